@@ -0,0 +1,10 @@
+// Package api holds the OpenAPI description of the Aureus API, which the
+// TypeScript client is generated from
+package api
+
+import _ "embed"
+
+// OpenAPISpec is openapi.yaml
+//
+//go:embed openapi.yaml
+var OpenAPISpec []byte
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// runCreateAdmin implements `api create-admin`, which sets up an admin on an
+// instance whose registration is invite-only or closed. The password is read
+// from the first line of stdin so it stays out of the shell history; an
+// existing user with the email is promoted and keeps their password.
+func runCreateAdmin(args []string) {
+	flags := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email of the admin")
+	tenantSlug := flags.String("tenant", config.String("DEFAULT_TENANT", "default"), "tenant of a new admin in multi-tenant mode")
+	flags.Parse(args)
+
+	if *email = strings.TrimSpace(*email); *email == "" {
+		log.Fatal("create-admin needs -email")
+	}
+
+	var existing models.User
+	err := database.GetDB().Where("LOWER(email) = ?", strings.ToLower(*email)).First(&existing).Error
+	if err == nil {
+		if err := database.GetDB().Model(&existing).Update("is_admin", true).Error; err != nil {
+			log.Fatal("Failed to promote admin: ", err)
+		}
+		log.Printf("✓ %s is now an admin", existing.Email)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Fatal("Failed to look up user: ", err)
+	}
+
+	var tenantID *uuid.UUID
+	if middleware.MultiTenant() {
+		var tenant models.Tenant
+		if err := database.GetDB().Where("slug = ?", *tenantSlug).First(&tenant).Error; err != nil {
+			log.Fatalf("Tenant %q not found", *tenantSlug)
+		}
+		tenantID = &tenant.ID
+	}
+
+	log.Printf("Password for %s:", *email)
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatal("Failed to read password: ", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if err := auth.CurrentPasswordPolicy().Validate(password); err != nil {
+		log.Fatal("Password refused: ", err)
+	}
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		log.Fatal("Failed to hash password: ", err)
+	}
+
+	user := models.User{TenantID: tenantID, Email: *email, Password: hashed, IsAdmin: true}
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		log.Fatal("Failed to create admin: ", err)
+	}
+	log.Printf("✓ Created admin %s", user.Email)
+}
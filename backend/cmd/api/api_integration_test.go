@@ -0,0 +1,1940 @@
+//go:build integration
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/clientip"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/demo"
+	"github.com/evansminotwood/aureus/internal/enrichment"
+	"github.com/evansminotwood/aureus/internal/handlers"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/oauth"
+	"github.com/evansminotwood/aureus/internal/sessions"
+	"github.com/evansminotwood/aureus/internal/testutil"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(testutil.Main(m))
+}
+
+func newRouter() *gin.Engine {
+	r := gin.New()
+	registerRoutes(r.Group("/api/v1", middleware.APIVersion("v1")))
+	return r
+}
+
+// request sends a JSON request through the router as the token's user and
+// decodes the response into out, if given
+func request(t *testing.T, r *gin.Engine, method, path, token string, body, out interface{}) int {
+	t.Helper()
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if out != nil && w.Code < 300 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode %s: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// waitForJob polls a job until it has finished, decodes its result into
+// out, if given, and returns it
+func waitForJob(t *testing.T, r *gin.Engine, token string, jobID uuid.UUID, out interface{}) models.Job {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job models.Job
+		if code := request(t, r, http.MethodGet, "/api/v1/jobs/"+jobID.String(), token, nil, &job); code != http.StatusOK {
+			t.Fatalf("GET job = %d", code)
+		}
+		if job.Status == jobs.StatusSucceeded || job.Status == jobs.StatusFailed {
+			if out != nil {
+				data, _ := json.Marshal(job.Result)
+				if err := json.Unmarshal(data, out); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s still %s", job.ID, job.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRequiresAuthentication(t *testing.T) {
+	if code := request(t, newRouter(), http.MethodGet, "/api/v1/portfolios", "", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /portfolios without a token = %d, want 401", code)
+	}
+}
+
+func TestCreatePortfolioAndCoin(t *testing.T) {
+	r := newRouter()
+	_, token := testutil.SeedUser(t)
+
+	var portfolio models.Portfolio
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolios", token, gin.H{"name": "Silver stack"}, &portfolio); code != http.StatusCreated {
+		t.Fatalf("create portfolio = %d", code)
+	}
+
+	var coin models.Coin
+	code := request(t, r, http.MethodPost, "/api/v1/coins", token, gin.H{
+		"portfolio_id":   portfolio.ID.String(),
+		"coin_type":      "Morgan Dollar",
+		"year":           1921,
+		"purchase_price": 40,
+		"quantity":       2,
+	}, &coin)
+	if code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if coin.MetalType != "silver" || coin.MetalPurity != 90 {
+		t.Errorf("coin composition = %s %.0f%%, want it filled in from the catalog", coin.MetalType, coin.MetalPurity)
+	}
+
+	var coins []models.Coin
+	if code := request(t, r, http.MethodGet, "/api/v1/portfolios/"+portfolio.ID.String()+"/coins", token, nil, &coins); code != http.StatusOK {
+		t.Fatalf("list coins = %d", code)
+	}
+	if len(coins) != 1 || coins[0].ID != coin.ID {
+		t.Errorf("portfolio coins = %+v, want the new coin", coins)
+	}
+}
+
+func TestNewCoinsInheritPortfolioDefaults(t *testing.T) {
+	r := newRouter()
+	_, token := testutil.SeedUser(t)
+
+	var portfolio models.Portfolio
+	code := request(t, r, http.MethodPost, "/api/v1/portfolios", token, gin.H{
+		"name":                     "Sovereigns",
+		"default_face_currency":    "gbp",
+		"default_storage_location": "Bank box 12",
+		"default_auto_sync":        false,
+	}, &portfolio)
+	if code != http.StatusCreated {
+		t.Fatalf("create portfolio = %d", code)
+	}
+	if portfolio.DefaultFaceCurrency != "GBP" || portfolio.DefaultAutoSync == nil || *portfolio.DefaultAutoSync {
+		t.Fatalf("portfolio defaults = %q, %v", portfolio.DefaultFaceCurrency, portfolio.DefaultAutoSync)
+	}
+
+	var inherited models.Coin
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, gin.H{
+		"portfolio_id": portfolio.ID.String(),
+		"coin_type":    "Half Sovereign",
+	}, &inherited); code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if inherited.FaceCurrency != "GBP" || inherited.StorageLocation != "Bank box 12" || inherited.AutoSync == nil || *inherited.AutoSync {
+		t.Errorf("coin = %q, %q, %v; want the portfolio's defaults", inherited.FaceCurrency, inherited.StorageLocation, inherited.AutoSync)
+	}
+
+	var own models.Coin
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, gin.H{
+		"portfolio_id":     portfolio.ID.String(),
+		"coin_type":        "Morgan Dollar",
+		"year":             1921,
+		"storage_location": "Home safe",
+		"auto_sync":        true,
+	}, &own); code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if own.FaceCurrency != "USD" || own.StorageLocation != "Home safe" || own.AutoSync == nil || !*own.AutoSync {
+		t.Errorf("coin = %q, %q, %v; want its own settings and its series' currency", own.FaceCurrency, own.StorageLocation, own.AutoSync)
+	}
+}
+
+func TestOtherUsersPortfoliosAreHidden(t *testing.T) {
+	r := newRouter()
+	owner, _ := testutil.SeedUser(t)
+	_, token := testutil.SeedUser(t)
+
+	portfolio := testutil.SeedPortfolio(t, owner.ID, "Not yours")
+	testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Peace Dollar", PurchasePrice: 35})
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+	}{
+		{http.MethodGet, "/api/v1/portfolios/" + portfolio.ID.String(), nil},
+		{http.MethodGet, "/api/v1/portfolios/" + portfolio.ID.String() + "/coins", nil},
+		{http.MethodDelete, "/api/v1/portfolios/" + portfolio.ID.String(), nil},
+		{http.MethodPost, "/api/v1/coins", gin.H{"portfolio_id": portfolio.ID.String(), "coin_type": "Morgan Dollar"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			code := request(t, r, tc.method, tc.path, token, tc.body, nil)
+			if code < 400 {
+				t.Errorf("%s %s as another user = %d, want an error", tc.method, tc.path, code)
+			}
+		})
+	}
+}
+
+func TestReadOnlyTokenCantModifyInventory(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Silver stack")
+
+	var scoped struct {
+		Token string `json:"token"`
+	}
+	body := gin.H{"scopes": []string{"coins:read", "reports:read"}}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/tokens", token, body, &scoped); code != http.StatusCreated {
+		t.Fatalf("create scoped token = %d", code)
+	}
+
+	path := "/api/v1/portfolios/" + portfolio.ID.String()
+	if code := request(t, r, http.MethodGet, path, scoped.Token, nil, nil); code != http.StatusOK {
+		t.Errorf("GET portfolio with coins:read = %d, want 200", code)
+	}
+	if code := request(t, r, http.MethodPut, path, scoped.Token, gin.H{"name": "Renamed"}, nil); code != http.StatusForbidden {
+		t.Errorf("PUT portfolio with coins:read = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/tokens", scoped.Token, body, nil); code != http.StatusForbidden {
+		t.Errorf("issuing a token from a scoped token = %d, want 403", code)
+	}
+}
+
+func TestAPIKeyScopesAndRevocation(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Scripted")
+
+	var key struct {
+		models.APIKey
+		Key string `json:"key"`
+	}
+	body := gin.H{"name": "Nightly import", "scopes": []string{"coins:read"}}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/api-keys", token, body, &key); code != http.StatusCreated {
+		t.Fatalf("create API key = %d", code)
+	}
+	if !strings.HasPrefix(key.Key, key.Prefix) {
+		t.Errorf("key %q doesn't start with its prefix %q", key.Key, key.Prefix)
+	}
+
+	withKey := func(method, path string, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	path := "/api/v1/portfolios/" + portfolio.ID.String()
+	if code := withKey(http.MethodGet, path, ""); code != http.StatusOK {
+		t.Errorf("GET portfolio with a read-only key = %d, want 200", code)
+	}
+	if code := withKey(http.MethodPut, path, `{"name":"Renamed"}`); code != http.StatusForbidden {
+		t.Errorf("PUT portfolio with a read-only key = %d, want 403", code)
+	}
+	if code := withKey(http.MethodPost, "/api/v1/auth/api-keys", `{"name":"More","scopes":["coins:write"]}`); code != http.StatusForbidden {
+		t.Errorf("creating a key with a key = %d, want 403", code)
+	}
+
+	if code := request(t, r, http.MethodDelete, "/api/v1/auth/api-keys/"+key.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Fatalf("revoke API key = %d", code)
+	}
+	if code := withKey(http.MethodGet, path, ""); code != http.StatusUnauthorized {
+		t.Errorf("GET portfolio with a revoked key = %d, want 401", code)
+	}
+}
+
+func TestDisplayTokenOnlySeesItsPortfolioTotal(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Shop window")
+	other := testutil.SeedPortfolio(t, user.ID, "Vault")
+	coin := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Morgan Dollar", Year: 1881, CurrentValue: 60, Quantity: 2})
+
+	var key struct {
+		models.APIKey
+		Key string `json:"key"`
+	}
+	body := gin.H{"name": "Counter screen", "portfolio_id": portfolio.ID}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/display-tokens", token, body, &key); code != http.StatusCreated {
+		t.Fatalf("create display token = %d", code)
+	}
+
+	withKey := func(path string, out interface{}) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", key.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if out != nil && w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code
+	}
+
+	var view struct {
+		PortfolioName string  `json:"portfolio_name"`
+		TotalValue    float64 `json:"total_value"`
+	}
+	// The portfolio it was issued for, whatever it asks for
+	if code := withKey("/api/v1/display?portfolio_id="+other.ID.String(), &view); code != http.StatusOK {
+		t.Fatalf("display = %d", code)
+	}
+	if view.PortfolioName != portfolio.Name || view.TotalValue != 120 {
+		t.Errorf("display = %+v, want %s worth 120", view, portfolio.Name)
+	}
+	for _, path := range []string{
+		"/api/v1/portfolios/" + portfolio.ID.String(),
+		"/api/v1/portfolios/" + portfolio.ID.String() + "/stats",
+		"/api/v1/coins/" + coin.ID.String(),
+		"/api/v1/metals/spot-prices",
+		"/api/v1/auth/me",
+		"/api/v1/settings",
+	} {
+		if code := withKey(path, nil); code != http.StatusForbidden {
+			t.Errorf("GET %s with a display token = %d, want 403", path, code)
+		}
+	}
+	if code := request(t, r, http.MethodPut, "/api/v1/auth/api-keys/"+key.ID.String(), token, gin.H{"scopes": []string{"coins:read"}}, nil); code != http.StatusBadRequest {
+		t.Errorf("widening a display token = %d, want 400", code)
+	}
+}
+
+func TestCurrencyHedgingSplitsMetalAndFXMoves(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Maples")
+	testutil.SeedCoin(t, portfolio.ID, models.Coin{
+		CoinType: "Gold Maple Leaf", Year: 2020, Quantity: 2, FaceValue: 50, FaceCurrency: "CAD",
+		MetalType: "gold", MetalWeight: 1, MetalPurity: 100,
+	})
+
+	// Gold went from $1900 to the mock $2000 while the dollar went from 1.30
+	// to 1.35 CAD
+	now := time.Now()
+	db := database.GetDB()
+	if err := db.Create(&models.SpotPriceHistory{Gold: 1900, Silver: 24, Platinum: 950, Palladium: 950, RecordedAt: now.AddDate(0, 0, -8)}).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, rate := range []models.FXRate{
+		{Currency: "CAD", Rate: 1.30, RecordedAt: now.AddDate(0, 0, -8)},
+		{Currency: "CAD", Rate: 1.35, RecordedAt: now.Add(-time.Hour)},
+	} {
+		if err := db.Create(&rate).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var report struct {
+		Currencies []struct {
+			Currency    string  `json:"currency"`
+			StartValue  float64 `json:"start_value"`
+			EndValue    float64 `json:"end_value"`
+			MetalEffect float64 `json:"metal_effect"`
+			FXEffect    float64 `json:"fx_effect"`
+		} `json:"currencies"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/reports/currency-hedging?period=7d", token, nil, &report); code != http.StatusOK {
+		t.Fatalf("currency hedging = %d", code)
+	}
+	if len(report.Currencies) != 1 || report.Currencies[0].Currency != "CAD" {
+		t.Fatalf("currencies = %+v, want the Maple Leafs in CAD", report.Currencies)
+	}
+	cad := report.Currencies[0]
+	near := func(got, want float64) bool { return got > want-0.01 && got < want+0.01 }
+	if !near(cad.StartValue, 2*1900*1.30) || !near(cad.EndValue, 2*2000*1.35) {
+		t.Errorf("value went from %.2f to %.2f, want %.2f to %.2f", cad.StartValue, cad.EndValue, 2*1900*1.30, 2*2000*1.35)
+	}
+	if !near(cad.MetalEffect, 2*100*1.30) || !near(cad.FXEffect, 2*2000*0.05) {
+		t.Errorf("metal effect %.2f and fx effect %.2f, want %.2f and %.2f", cad.MetalEffect, cad.FXEffect, 2*100*1.30, 2*2000*0.05)
+	}
+
+	if code := request(t, r, http.MethodGet, "/api/v1/reports/currency-hedging?currency=XAU", token, nil, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("report in a currency without rates = %d, want 422", code)
+	}
+}
+
+func TestTransferCoinWithoutCostBasis(t *testing.T) {
+	r := newRouter()
+	sender, senderToken := testutil.SeedUser(t)
+	recipient, recipientToken := testutil.SeedUser(t)
+	coin := testutil.SeedCoin(t, testutil.SeedPortfolio(t, sender.ID, "Morgans").ID, models.Coin{
+		CoinType: "Morgan Dollar", Year: 1921, PurchasePrice: 40,
+	})
+	portfolio := testutil.SeedPortfolio(t, recipient.ID, "Gifts")
+
+	var transfer models.CoinTransfer
+	body := gin.H{"to_email": recipient.Email, "keep_cost_basis": false}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins/"+coin.ID.String()+"/transfer", senderToken, body, &transfer); code != http.StatusCreated {
+		t.Fatalf("offer transfer = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.ID.String(), recipientToken, nil, nil); code == http.StatusOK {
+		t.Error("recipient can see the coin before accepting")
+	}
+
+	var accepted struct {
+		Coin models.Coin `json:"coin"`
+	}
+	path := "/api/v1/transfers/" + transfer.ID.String() + "/accept"
+	if code := request(t, r, http.MethodPost, path, recipientToken, gin.H{"portfolio_id": portfolio.ID.String()}, &accepted); code != http.StatusOK {
+		t.Fatalf("accept transfer = %d", code)
+	}
+	if accepted.Coin.PortfolioID != portfolio.ID || accepted.Coin.PurchasePrice != 0 {
+		t.Errorf("transferred coin in %s costing %.2f, want in %s costing 0", accepted.Coin.PortfolioID, accepted.Coin.PurchasePrice, portfolio.ID)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.ID.String(), senderToken, nil, nil); code == http.StatusOK {
+		t.Error("sender can still see the coin after the transfer")
+	}
+}
+
+func TestSplitAndMergeKeepCostBasis(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	coin := testutil.SeedCoin(t, testutil.SeedPortfolio(t, user.ID, "Rolls").ID, models.Coin{
+		CoinType: "Morgan Dollar", Year: 1921, Quantity: 4, PurchasePrice: 40, BuyersPremium: 10,
+	})
+
+	var split struct {
+		Coin     models.Coin   `json:"coin"`
+		NewCoins []models.Coin `json:"new_coins"`
+	}
+	path := "/api/v1/coins/" + coin.ID.String()
+	if code := request(t, r, http.MethodPost, path+"/split", token, gin.H{"quantities": []int{1}}, &split); code != http.StatusCreated {
+		t.Fatalf("split = %d", code)
+	}
+	if len(split.NewCoins) != 1 || split.Coin.Quantity != 3 || split.NewCoins[0].Quantity != 1 {
+		t.Fatalf("split into %d and %v, want 3 and [1]", split.Coin.Quantity, split.NewCoins)
+	}
+	if split.Coin.BuyersPremium != 7.5 || split.NewCoins[0].BuyersPremium != 2.5 {
+		t.Errorf("premium split %.2f / %.2f, want 7.50 / 2.50", split.Coin.BuyersPremium, split.NewCoins[0].BuyersPremium)
+	}
+
+	var merged models.Coin
+	body := gin.H{"coin_ids": []string{split.NewCoins[0].ID.String()}}
+	if code := request(t, r, http.MethodPost, path+"/merge", token, body, &merged); code != http.StatusOK {
+		t.Fatalf("merge = %d", code)
+	}
+	if merged.Quantity != 4 || merged.PurchasePrice != 40 || merged.BuyersPremium != 10 {
+		t.Errorf("merged coin = %d at %.2f plus %.2f, want 4 at 40 plus 10", merged.Quantity, merged.PurchasePrice, merged.BuyersPremium)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+split.NewCoins[0].ID.String(), token, nil, nil); code != http.StatusNotFound {
+		t.Errorf("merged row still found: %d", code)
+	}
+}
+
+func TestCoinReferencesKeepOwnedLinks(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Morgans")
+
+	book := gin.H{"type": "literature", "url": "https://example.com/vam/1881-s", "title": "VAM catalog"}
+	var coin models.Coin
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, gin.H{
+		"portfolio_id": portfolio.ID.String(),
+		"coin_type":    "Morgan Dollar",
+		"year":         1881,
+		"mint_mark":    "S",
+		"references":   []gin.H{book},
+	}, &coin); code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if len(coin.References) < 2 || coin.References[0].URL != book["url"] || coin.References[0].Auto || !coin.References[1].Auto {
+		t.Fatalf("references = %+v, want the catalog link then lookups", coin.References)
+	}
+
+	var updated models.Coin
+	path := "/api/v1/coins/" + coin.ID.String()
+	if code := request(t, r, http.MethodPut, path, token, gin.H{"year": 1882, "references": []gin.H{}}, &updated); code != http.StatusOK {
+		t.Fatalf("update coin = %d", code)
+	}
+	for _, ref := range updated.References {
+		if !ref.Auto || !strings.Contains(ref.URL, "1882") {
+			t.Errorf("reference %+v after clearing owned links and changing the year", ref)
+		}
+	}
+}
+
+func TestUpgradeKeepsCostBasisAndHistory(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	coin := testutil.SeedCoin(t, testutil.SeedPortfolio(t, user.ID, "Slabs").ID, models.Coin{
+		CoinType: "Morgan Dollar", Year: 1881, MintMark: "S", Quantity: 1, PurchasePrice: 60, PCGSCertNumber: "12345678",
+	})
+	if err := database.GetDB().Create(&models.PriceHistory{CoinID: coin.ID, NumismaticValue: 65, RecordedAt: time.Now().AddDate(0, -1, 0)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var upgraded struct {
+		Coin     models.Coin         `json:"coin"`
+		Previous models.ArchivedCoin `json:"previous"`
+	}
+	body := gin.H{"pcgs_cert_number": "87654321", "numismatic_value": 150}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins/"+coin.ID.String()+"/upgrade", token, body, &upgraded); code != http.StatusCreated {
+		t.Fatalf("upgrade = %d", code)
+	}
+	if upgraded.Coin.UpgradedFromID == nil || *upgraded.Coin.UpgradedFromID != coin.ID || upgraded.Coin.PurchasePrice != 60 {
+		t.Errorf("replacement = %+v, want upgraded from %s at 60", upgraded.Coin, coin.ID)
+	}
+	if upgraded.Previous.Disposition != "regraded" || upgraded.Previous.PCGSCertNumber != "12345678" {
+		t.Errorf("archived %q under %q, want regraded under the old cert", upgraded.Previous.Disposition, upgraded.Previous.PCGSCertNumber)
+	}
+
+	var history []models.PriceHistory
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+upgraded.Coin.ID.String()+"/price-history", token, nil, &history); code != http.StatusOK {
+		t.Fatalf("price history = %d", code)
+	}
+	if len(history) < 2 {
+		t.Errorf("replacement has %d snapshots, want the old one and the regrade's", len(history))
+	}
+
+	var predecessors []models.ArchivedCoin
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+upgraded.Coin.ID.String()+"/upgrades", token, nil, &predecessors); code != http.StatusOK {
+		t.Fatalf("upgrades = %d", code)
+	}
+	if len(predecessors) != 1 || predecessors[0].ID != coin.ID {
+		t.Errorf("upgrades = %v, want the old record", predecessors)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/archived-coins/"+coin.ID.String()+"/restore", token, nil, nil); code != http.StatusConflict {
+		t.Errorf("restore regraded coin = %d, want 409", code)
+	}
+}
+
+func TestEmergencyAccessWaitsAfterApproval(t *testing.T) {
+	r := newRouter()
+	_, ownerToken := testutil.SeedUser(t)
+	contact, contactToken := testutil.SeedUser(t)
+	admin, adminToken := testutil.SeedUser(t)
+	if err := database.GetDB().Model(&admin).Update("is_admin", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var designated models.EmergencyContact
+	body := gin.H{"email": contact.Email, "waiting_days": 7}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/me/emergency-contacts", ownerToken, body, &designated); code != http.StatusCreated {
+		t.Fatalf("designate contact = %d", code)
+	}
+	base := "/api/v1/emergency-access/" + designated.ID.String()
+	if code := request(t, r, http.MethodPost, base+"/token", contactToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("token before a request = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodPost, base+"/request", contactToken, gin.H{"reason": "Executor of the estate"}, nil); code != http.StatusOK {
+		t.Fatalf("request access = %d", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/admin/emergency-access/"+designated.ID.String()+"/approve", adminToken, nil, nil); code != http.StatusOK {
+		t.Fatalf("approve access = %d", code)
+	}
+	if code := request(t, r, http.MethodPost, base+"/token", contactToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("token during the waiting period = %d, want 403", code)
+	}
+}
+
+func TestDisposedCoinLeavesListingsButNotReports(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Morgans")
+	coin := testutil.SeedCoin(t, portfolio.ID, models.Coin{
+		CoinType: "Morgan Dollar", Year: 1921, Quantity: 1, PurchasePrice: 40,
+	})
+
+	body := gin.H{"disposition": "sold", "sale_price": 75, "sale_fees": 5}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins/"+coin.ID.String()+"/dispose", token, body, nil); code != http.StatusOK {
+		t.Fatalf("dispose coin = %d", code)
+	}
+
+	var coins []models.Coin
+	if code := request(t, r, http.MethodGet, "/api/v1/portfolios/"+portfolio.ID.String()+"/coins", token, nil, &coins); code != http.StatusOK {
+		t.Fatalf("list coins = %d", code)
+	}
+	if len(coins) != 0 {
+		t.Errorf("portfolio lists %d coins after the sale, want 0", len(coins))
+	}
+
+	var archived []models.ArchivedCoin
+	if code := request(t, r, http.MethodGet, "/api/v1/portfolios/"+portfolio.ID.String()+"/archived-coins", token, nil, &archived); code != http.StatusOK {
+		t.Fatalf("list archived coins = %d", code)
+	}
+	if len(archived) != 1 || archived[0].ID != coin.ID {
+		t.Fatalf("archived coins = %v, want the sold coin", archived)
+	}
+
+	var report struct {
+		Totals struct {
+			Proceeds float64 `json:"proceeds"`
+		} `json:"totals"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/reports/realized-gains", token, nil, &report); code != http.StatusOK {
+		t.Fatalf("realized gains = %d", code)
+	}
+	if report.Totals.Proceeds != 70 {
+		t.Errorf("realized proceeds = %.2f, want 70", report.Totals.Proceeds)
+	}
+}
+
+func TestRefreshTokenRotationAndLogoutEverywhere(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	_, first, err := sessions.Issue(user.ID, "test", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var refreshed struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/refresh", "", gin.H{"refresh_token": first}, &refreshed); code != http.StatusOK {
+		t.Fatalf("refresh = %d", code)
+	}
+	if refreshed.Token == "" || refreshed.RefreshToken == "" || refreshed.RefreshToken == first {
+		t.Fatalf("refresh should return a new access token and a rotated refresh token")
+	}
+
+	// Replaying a used token revokes the session it was rotated into
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/refresh", "", gin.H{"refresh_token": first}, nil); code != http.StatusUnauthorized {
+		t.Errorf("reusing a refresh token = %d, want 401", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/refresh", "", gin.H{"refresh_token": refreshed.RefreshToken}, nil); code != http.StatusUnauthorized {
+		t.Errorf("refreshing after reuse was detected = %d, want 401", code)
+	}
+
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/logout-everywhere", token, nil, nil); code != http.StatusOK {
+		t.Fatalf("logout everywhere = %d", code)
+	}
+	for _, old := range []string{token, refreshed.Token} {
+		if code := request(t, r, http.MethodGet, "/api/v1/auth/me", old, nil, nil); code != http.StatusUnauthorized {
+			t.Errorf("access token issued before logging out everywhere = %d, want 401", code)
+		}
+	}
+}
+
+func TestExportAndDeleteAccount(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	database.GetDB().Model(&user).Update("password", hash)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Estate")
+	coin := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Morgan Dollar", Year: 1881, PurchasePrice: 50})
+
+	var export struct {
+		User       models.User        `json:"user"`
+		Portfolios []models.Portfolio `json:"portfolios"`
+		Coins      []models.Coin      `json:"coins"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me/export", token, nil, &export); code != http.StatusOK {
+		t.Fatalf("export = %d", code)
+	}
+	if export.User.ID != user.ID || len(export.Portfolios) != 1 || len(export.Coins) != 1 || export.Coins[0].ID != coin.ID {
+		t.Errorf("export = %+v, want the user's portfolio and coin", export)
+	}
+
+	if code := request(t, r, http.MethodDelete, "/api/v1/auth/me", token, gin.H{"password": "wrong"}, nil); code != http.StatusUnauthorized {
+		t.Errorf("delete with the wrong password = %d, want 401", code)
+	}
+	if code := request(t, r, http.MethodDelete, "/api/v1/auth/me", token, gin.H{"password": "correct horse"}, nil); code != http.StatusOK {
+		t.Fatalf("delete account = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", token, nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("token of a deleted account = %d, want 401", code)
+	}
+	var left int64
+	database.GetDB().Model(&models.Coin{}).Where("id = ?", coin.ID).Count(&left)
+	if left != 0 {
+		t.Errorf("coin of a deleted account is still stored")
+	}
+}
+
+func TestRevokingSessionSignsDeviceOut(t *testing.T) {
+	r := newRouter()
+	user, _ := testutil.SeedUser(t)
+	signIn := func(userAgent string) string {
+		refresh, _, err := sessions.Issue(user.ID, userAgent, "192.0.2.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, _, err := auth.GenerateSessionToken(user.ID, user.Email, nil, user.TokenVersion, refresh.FamilyID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	laptop := signIn("Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+	phone := signIn("Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1")
+
+	var list []struct {
+		ID      string `json:"id"`
+		Device  string `json:"device"`
+		Current bool   `json:"current"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/sessions", laptop, nil, &list); code != http.StatusOK {
+		t.Fatalf("list sessions = %d", code)
+	}
+	if len(list) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(list))
+	}
+	var phoneID string
+	for _, s := range list {
+		if s.Device == "Safari on iPhone" {
+			phoneID = s.ID
+			if s.Current {
+				t.Error("the phone's session is marked as the laptop's")
+			}
+		} else if !s.Current {
+			t.Errorf("the laptop's session (%s) isn't marked current", s.Device)
+		}
+	}
+	if phoneID == "" {
+		t.Fatalf("sessions = %+v, want one on Safari on iPhone", list)
+	}
+
+	if code := request(t, r, http.MethodDelete, "/api/v1/auth/sessions/"+phoneID, laptop, nil, nil); code != http.StatusOK {
+		t.Fatalf("revoke session = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", phone, nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("access token of a revoked session = %d, want 401", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", laptop, nil, nil); code != http.StatusOK {
+		t.Errorf("access token of another session = %d, want 200", code)
+	}
+}
+
+func TestRepeatedFailedLoginsLockTheEmailOut(t *testing.T) {
+	t.Setenv("LOGIN_MAX_ATTEMPTS", "3")
+	r := newRouter()
+	user, _ := testutil.SeedUser(t)
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.GetDB().Model(&user).Update("password", hash).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := map[string]string{"email": user.Email, "password": "wrong"}
+	for i := 0; i < 3; i++ {
+		if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", wrong, nil); code != http.StatusUnauthorized {
+			t.Fatalf("failed login %d = %d, want 401", i+1, code)
+		}
+	}
+	// Locked out even with the right password, and with the email in capitals
+	right := map[string]string{"email": strings.ToUpper(user.Email), "password": "correct horse"}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", right, nil); code != http.StatusTooManyRequests {
+		t.Fatalf("login while locked out = %d, want 429", code)
+	}
+
+	var failures int64
+	database.GetDB().Model(&models.LoginFailure{}).Where("email = ? AND action = ?", user.Email, "login").Count(&failures)
+	if failures != 3 {
+		t.Errorf("%d failed logins recorded, want 3", failures)
+	}
+
+	// Once the lockout passes, the right password works and clears the count
+	database.GetDB().Model(&models.LoginThrottle{}).Where("key IN ?", []string{"email:" + user.Email, "ip:192.0.2.1"}).
+		Update("locked_until", time.Now().Add(-time.Second))
+	right["email"] = user.Email
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", right, nil); code != http.StatusOK {
+		t.Fatalf("login after the lockout = %d, want 200", code)
+	}
+	var left int64
+	database.GetDB().Model(&models.LoginThrottle{}).Where("key = ?", "email:"+user.Email).Count(&left)
+	if left != 0 {
+		t.Error("signing in didn't clear the email's failures")
+	}
+}
+
+type capturedMail []mail.Message
+
+func (m *capturedMail) Send(msg mail.Message) error {
+	*m = append(*m, msg)
+	return nil
+}
+
+func TestRegisterEnforcesPasswordPolicy(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_CLASSES", "2")
+	r := newRouter()
+	email := "policy-" + time.Now().Format("150405.000000") + "@example.com"
+
+	for password, want := range map[string]string{
+		"short1":        "password_too_short",
+		"onlylowercase": "password_too_simple",
+		"Password123":   "password_breached",
+	} {
+		payload, _ := json.Marshal(gin.H{"email": email, "password": password})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/register", bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusBadRequest || body.Code != want {
+			t.Errorf("register with %q = %d %q, want 400 %q", password, w.Code, body.Code, want)
+		}
+	}
+
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/register", "", gin.H{"email": email, "password": "a long passphrase"}, nil); code != http.StatusCreated {
+		t.Errorf("register with a good password = %d, want 201", code)
+	}
+}
+
+// register signs up from its own address, so the 403s the registration
+// tests expect don't count towards the lockout of the other tests' address
+func register(t *testing.T, r *gin.Engine, path string, body gin.H) (int, string) {
+	t.Helper()
+
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "198.51.100.30:1234"
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var resp struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp.Code
+}
+
+// seedInviteCode creates a single-use invite code for a tenant, or for the
+// instance if tenantID is nil
+func seedInviteCode(t *testing.T, tenantID *uuid.UUID) string {
+	t.Helper()
+
+	admin, _ := testutil.SeedUser(t)
+	code := strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", ""))
+	code = code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+	if err := database.GetDB().Create(&models.InviteCode{TenantID: tenantID, Code: code, CreatedBy: admin.ID, MaxUses: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestRegistrationModesApplyToAdminEmailsToo(t *testing.T) {
+	r := newRouter()
+	newEmail := func(prefix string) string {
+		return prefix + "-" + uuid.NewString()[:8] + "@example.com"
+	}
+	admin := newEmail("admin")
+	t.Setenv("ADMIN_EMAILS", admin)
+
+	t.Setenv("REGISTRATION_MODE", "open")
+	if code, _ := register(t, r, "/api/v1/auth/register", gin.H{"email": newEmail("open"), "password": "a long passphrase"}); code != http.StatusCreated {
+		t.Errorf("open register = %d, want 201", code)
+	}
+
+	t.Setenv("REGISTRATION_MODE", "disabled")
+	for _, email := range []string{newEmail("closed"), admin} {
+		if code, reason := register(t, r, "/api/v1/auth/register", gin.H{"email": email, "password": "a long passphrase"}); code != http.StatusForbidden || reason != "registration_disabled" {
+			t.Errorf("disabled register of %s = %d %q, want 403 registration_disabled", email, code, reason)
+		}
+	}
+
+	t.Setenv("REGISTRATION_MODE", "invite")
+	if code, reason := register(t, r, "/api/v1/auth/register", gin.H{"email": admin, "password": "a long passphrase"}); code != http.StatusForbidden || reason != "invite_required" {
+		t.Errorf("invite-only register without a code = %d %q, want 403 invite_required", code, reason)
+	}
+	invite := seedInviteCode(t, nil)
+	if code, _ := register(t, r, "/api/v1/auth/register", gin.H{"email": admin, "password": "a long passphrase", "invite_code": strings.ToLower(invite)}); code != http.StatusCreated {
+		t.Fatalf("register with an invite = %d, want 201", code)
+	}
+	if code, reason := register(t, r, "/api/v1/auth/register", gin.H{"email": newEmail("late"), "password": "a long passphrase", "invite_code": invite}); code != http.StatusForbidden || reason != "invalid_invite" {
+		t.Errorf("register with a used invite = %d %q, want 403 invalid_invite", code, reason)
+	}
+
+	var user models.User
+	if err := database.GetDB().Where("email = ?", admin).First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.IsAdmin {
+		t.Error("registering with an ADMIN_EMAILS address made the user an admin")
+	}
+}
+
+func TestInviteCodeOnlyWorksInItsTenant(t *testing.T) {
+	t.Setenv("MULTI_TENANT", "true")
+	t.Setenv("REGISTRATION_MODE", "invite")
+	r := newRouter()
+
+	club, other := "club-"+uuid.NewString()[:8], "other-"+uuid.NewString()[:8]
+	for _, slug := range []string{club, other} {
+		if err := database.EnsureTenant(slug, slug); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var tenant models.Tenant
+	if err := database.GetDB().Where("slug = ?", club).First(&tenant).Error; err != nil {
+		t.Fatal(err)
+	}
+	invite := seedInviteCode(t, &tenant.ID)
+
+	body := gin.H{"email": "member-" + uuid.NewString()[:8] + "@example.com", "password": "a long passphrase", "invite_code": invite}
+	if code, reason := register(t, r, "/api/v1/auth/register?tenant="+other, body); code != http.StatusForbidden || reason != "invalid_invite" {
+		t.Errorf("register with another tenant's invite = %d %q, want 403 invalid_invite", code, reason)
+	}
+	if code, _ := register(t, r, "/api/v1/auth/register?tenant="+club, body); code != http.StatusCreated {
+		t.Errorf("register with the tenant's own invite = %d, want 201", code)
+	}
+}
+
+func TestPasswordResetLinkWorksOnce(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	var sent capturedMail
+	mail.SetMailer(&sent)
+	defer mail.SetMailer(nil)
+
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": "nobody@example.com"}, nil); code != http.StatusAccepted {
+		t.Errorf("forgot password for an unknown email = %d, want 202 all the same", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/forgot-password", "", gin.H{"email": user.Email}, nil); code != http.StatusAccepted {
+		t.Fatalf("forgot password = %d", code)
+	}
+	if len(sent) != 1 || sent[0].To != user.Email {
+		t.Fatalf("sent %d emails, want one reset link to %s", len(sent), user.Email)
+	}
+	link := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(sent[0].Text)
+	if link == nil {
+		t.Fatalf("reset email has no link: %s", sent[0].Text)
+	}
+
+	body := gin.H{"token": link[1], "password": "new-password"}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/reset-password", "", body, nil); code != http.StatusOK {
+		t.Fatalf("reset password = %d", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/reset-password", "", body, nil); code != http.StatusBadRequest {
+		t.Errorf("reusing a reset link = %d, want 400", code)
+	}
+
+	var updated models.User
+	if err := database.GetDB().First(&updated, "id = ?", user.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !auth.CheckPasswordHash("new-password", updated.Password) {
+		t.Error("password wasn't changed")
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", token, nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("token from before the reset = %d, want 401", code)
+	}
+}
+
+func TestReimportSkipsOrUpdatesKnownCerts(t *testing.T) {
+	r := newRouter()
+	_, token := testutil.SeedUser(t)
+
+	var portfolio models.Portfolio
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolios", token, gin.H{"name": "Slabs"}, &portfolio); code != http.StatusCreated {
+		t.Fatalf("create portfolio = %d", code)
+	}
+	importCSV := func(csv, onDuplicate string) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/portfolios/"+portfolio.ID.String()+"/import?on_duplicate="+onDuplicate, strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("import = %d: %s", w.Code, w.Body.String())
+		}
+		var job models.Job
+		if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+			t.Fatal(err)
+		}
+		var out map[string]interface{}
+		if job = waitForJob(t, r, token, job.ID, &out); job.Status != jobs.StatusSucceeded {
+			t.Fatalf("import job %s: %s", job.Status, job.Error)
+		}
+		return out
+	}
+
+	first := importCSV("coin_type,year,cert,notes\nMorgan Dollar,1921,10000001,first\nPeace Dollar,1922,,\n", "skip")
+	if first["created"] != float64(2) {
+		t.Fatalf("first import = %v, want 2 created", first)
+	}
+
+	again := importCSV("coin_type,year,cert,notes\nMorgan Dollar,1921,10000001,regraded\nMorgan Dollar,1921,10000001,\n", "skip")
+	if again["created"] != float64(0) || again["skipped"] != float64(2) {
+		t.Errorf("re-import with skip = %v, want both rows skipped", again)
+	}
+
+	updated := importCSV("coin_type,year,cert,notes\nMorgan Dollar,1921,10000001,regraded\n", "update")
+	if updated["updated"] != float64(1) {
+		t.Fatalf("re-import with update = %v, want 1 updated", updated)
+	}
+
+	var coins []models.Coin
+	request(t, r, http.MethodGet, "/api/v1/portfolios/"+portfolio.ID.String()+"/coins", token, nil, &coins)
+	if len(coins) != 2 {
+		t.Fatalf("portfolio has %d coins, want re-imports not to double-enter", len(coins))
+	}
+	for _, coin := range coins {
+		if coin.PCGSCertNumber == "10000001" && coin.Notes != "regraded" {
+			t.Errorf("updated coin notes = %q, want regraded", coin.Notes)
+		}
+	}
+}
+
+func TestPreviewedMappingDrivesImport(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Spreadsheet")
+	csv := "Description,Yr,Paid\nMorgan Dollar,1881,$40\n"
+
+	post := func(path string, out interface{}) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if w.Code == http.StatusAccepted {
+			var job models.Job
+			if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+				t.Fatal(err)
+			}
+			waitForJob(t, r, token, job.ID, out)
+		}
+		return w.Code
+	}
+
+	var preview struct {
+		Mapping map[string]string `json:"mapping"`
+	}
+	if code := post("/api/v1/import/preview", &preview); code != http.StatusOK {
+		t.Fatalf("preview = %d", code)
+	}
+	if preview.Mapping["Yr"] != "year" || preview.Mapping["Paid"] != "purchase_price" {
+		t.Errorf("suggested mapping = %v", preview.Mapping)
+	}
+
+	// The user says which column is the coin
+	preview.Mapping["Description"] = "coin_type"
+	mapping, _ := json.Marshal(preview.Mapping)
+	var result struct {
+		Created int `json:"created"`
+	}
+	path := "/api/v1/portfolios/" + portfolio.ID.String() + "/import?mapping=" + url.QueryEscape(string(mapping))
+	if code := post(path, &result); code != http.StatusAccepted || result.Created != 1 {
+		t.Fatalf("mapped import = %d, created %d", code, result.Created)
+	}
+	var coin models.Coin
+	database.GetDB().Where("portfolio_id = ?", portfolio.ID).First(&coin)
+	if coin.CoinType != "Morgan Dollar" || coin.Year != 1881 || coin.PurchasePrice != 40 {
+		t.Errorf("imported coin = %s %d at %.2f", coin.CoinType, coin.Year, coin.PurchasePrice)
+	}
+}
+
+func TestImportRunsAsAJobAndOneAtATime(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Slabs")
+	path := "/api/v1/portfolios/" + portfolio.ID.String() + "/import"
+	csv := "coin_type,year\nMorgan Dollar,1921\nPeace Dollar,1922\n"
+
+	post := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(csv))
+		req.Header.Set("Content-Type", "text/csv")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post()
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("import = %d, want 202", w.Code)
+	}
+	var job models.Job
+	json.Unmarshal(w.Body.Bytes(), &job)
+	if w.Header().Get("Location") != "/api/v1/jobs/"+job.ID.String() || job.Kind != jobs.KindImport {
+		t.Errorf("started %q job at %q", job.Kind, w.Header().Get("Location"))
+	}
+	var result struct {
+		Created int `json:"created"`
+	}
+	job = waitForJob(t, r, token, job.ID, &result)
+	if job.Status != jobs.StatusSucceeded || job.Done != 2 || job.Total != 2 || result.Created != 2 {
+		t.Errorf("finished job = %s, %d of %d, created %d", job.Status, job.Done, job.Total, result.Created)
+	}
+
+	// A second import waits for the one still running
+	running := models.Job{UserID: user.ID, Kind: jobs.KindImport, Status: jobs.StatusRunning}
+	database.GetDB().Create(&running)
+	w = post()
+	var conflict struct {
+		Code string     `json:"code"`
+		Job  models.Job `json:"job"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &conflict)
+	if w.Code != http.StatusConflict || conflict.Code != "job_running" || conflict.Job.ID != running.ID {
+		t.Errorf("import while one runs = %d %s for job %s", w.Code, conflict.Code, conflict.Job.ID)
+	}
+
+	var listed []models.Job
+	request(t, r, http.MethodGet, "/api/v1/jobs?status=running", token, nil, &listed)
+	if len(listed) != 1 || listed[0].ID != running.ID {
+		t.Errorf("running jobs = %v", listed)
+	}
+	_, other := testutil.SeedUser(t)
+	if code := request(t, r, http.MethodGet, "/api/v1/jobs/"+job.ID.String(), other, nil, nil); code != http.StatusNotFound {
+		t.Errorf("another user's job = %d, want 404", code)
+	}
+}
+
+func TestOAuthSignInLinksExistingUserByEmail(t *testing.T) {
+	r := newRouter()
+	// Mock sign-in only reaches accounts it could have created itself
+	user := models.User{Email: "linked-" + uuid.NewString()[:8] + "@" + oauth.MockEmailDomain}
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	follow := func(target string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("GET %s = %d, want a redirect", target, w.Code)
+		}
+		return w
+	}
+
+	// The mock provider skips Google's sign-in page and comes straight back
+	start := follow("/api/v1/auth/oauth/google/start?login_hint="+url.QueryEscape(user.Email), nil)
+	callback, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the nonce cookie the callback isn't accepted
+	if location := follow(callback.RequestURI(), nil).Header().Get("Location"); !strings.Contains(location, "oauth_error=invalid_state") {
+		t.Errorf("callback from another browser redirected to %s", location)
+	}
+
+	done, err := url.Parse(follow(callback.RequestURI(), start.Result().Cookies()).Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := done.Query().Get("code")
+	if done.Path != "/oauth/callback" || code == "" {
+		t.Fatalf("callback redirected to %s, want the frontend with a login code", done)
+	}
+
+	var session struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	if status := request(t, r, http.MethodPost, "/api/v1/auth/oauth/exchange", "", gin.H{"code": code}, &session); status != http.StatusOK {
+		t.Fatalf("exchange = %d", status)
+	}
+	if session.Token == "" || session.User.ID != user.ID {
+		t.Errorf("signed in as %s, want the existing user with the same email", session.User.ID)
+	}
+	if status := request(t, r, http.MethodPost, "/api/v1/auth/oauth/exchange", "", gin.H{"code": code}, nil); status != http.StatusBadRequest {
+		t.Errorf("reusing a login code = %d, want 400", status)
+	}
+
+	var identities []models.OAuthIdentity
+	request(t, r, http.MethodGet, "/api/v1/auth/oauth/identities", session.Token, nil, &identities)
+	if len(identities) != 1 || identities[0].Provider != "google" {
+		t.Errorf("linked identities = %+v, want google", identities)
+	}
+}
+
+func TestMockSignInNeverReachesRealAccounts(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+
+	signIn := func(hint string) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/google/start?login_hint="+url.QueryEscape(hint), nil)
+		start := httptest.NewRecorder()
+		r.ServeHTTP(start, req)
+		callback, err := url.Parse(start.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req = httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+		for _, cookie := range start.Result().Cookies() {
+			req.AddCookie(cookie)
+		}
+		done := httptest.NewRecorder()
+		r.ServeHTTP(done, req)
+		return done.Header().Get("Location")
+	}
+
+	// A real address is refused outright
+	if location := signIn(user.Email); !strings.Contains(location, "oauth_error=") {
+		t.Errorf("mock sign-in as %s redirected to %s, want an error", user.Email, location)
+	}
+
+	// An account at the mock domain with a password wasn't made by mock
+	// sign-in, so it isn't taken over either
+	registered := models.User{Email: "registered-" + uuid.NewString()[:8] + "@" + oauth.MockEmailDomain, Password: "not-a-real-hash"}
+	if err := database.GetDB().Create(&registered).Error; err != nil {
+		t.Fatal(err)
+	}
+	if location := signIn(registered.Email); !strings.Contains(location, "oauth_error=mock_identity") {
+		t.Errorf("mock sign-in as an account with a password redirected to %s", location)
+	}
+
+	// Nor is a mock identity linked to a real account
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/oauth/google/link", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var link struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &link)
+	start, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, start.RequestURI()+"&login_hint="+url.QueryEscape("someone@"+oauth.MockEmailDomain), nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	startW := httptest.NewRecorder()
+	r.ServeHTTP(startW, req)
+	callback, err := url.Parse(startW.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range startW.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	done := httptest.NewRecorder()
+	r.ServeHTTP(done, req)
+	if location := done.Header().Get("Location"); !strings.Contains(location, "oauth_error=mock_identity") {
+		t.Errorf("linking a mock identity to a real account redirected to %s", location)
+	}
+	var linked int64
+	database.GetDB().Model(&models.OAuthIdentity{}).Where("user_id = ?", user.ID).Count(&linked)
+	if linked != 0 {
+		t.Errorf("%d identities linked to the real account", linked)
+	}
+}
+
+func TestOAuthLinkTicketOnlyWorksInTheRequestersBrowser(t *testing.T) {
+	r := newRouter()
+	_, token := testutil.SeedUser(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/oauth/google/link", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("link = %d", w.Code)
+	}
+	var link struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &link)
+	start, err := url.Parse(link.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	open := func(cookies []*http.Cookie) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, start.RequestURI(), nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusFound {
+			t.Fatalf("start = %d, want a redirect", w.Code)
+		}
+		return w.Header().Get("Location")
+	}
+
+	// A ticket sent to someone else, e.g. to link their Google account to
+	// the requester's, is refused in their browser
+	if location := open(nil); !strings.Contains(location, "oauth_error=invalid_state") {
+		t.Errorf("ticket opened in another browser redirected to %s", location)
+	}
+	if location := open(w.Result().Cookies()); strings.Contains(location, "oauth_error") {
+		t.Errorf("ticket opened in the requester's browser redirected to %s", location)
+	}
+}
+
+func TestSingleSignOnOnlyRefusesPasswords(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "https://sso.example.com")
+	t.Setenv("PASSWORD_LOGIN", "false")
+	r := newRouter()
+	email := "sso-" + time.Now().Format("150405.000000") + "@" + oauth.MockEmailDomain
+
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", gin.H{"email": email, "password": "a long passphrase"}, nil); code != http.StatusForbidden {
+		t.Errorf("password login = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/register", "", gin.H{"email": email, "password": "a long passphrase"}, nil); code != http.StatusForbidden {
+		t.Errorf("password registration = %d, want 403", code)
+	}
+	admin, _ := testutil.SeedUser(t)
+	t.Setenv("ADMIN_EMAILS", admin.Email)
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", gin.H{"email": admin.Email, "password": "a long passphrase"}, nil); code != http.StatusForbidden {
+		t.Errorf("password login of an admin = %d, want 403", code)
+	}
+
+	var providers struct {
+		Providers []string          `json:"providers"`
+		Labels    map[string]string `json:"labels"`
+	}
+	request(t, r, http.MethodGet, "/api/v1/auth/oauth/providers", "", nil, &providers)
+	if providers.Labels["oidc"] != "Single sign-on" {
+		t.Fatalf("providers = %+v, want oidc offered as Single sign-on", providers)
+	}
+
+	// The mock provider skips the provider's sign-in page and comes straight back
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/oidc/start?login_hint="+url.QueryEscape(email), nil)
+	start := httptest.NewRecorder()
+	r.ServeHTTP(start, req)
+	callback, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req = httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, cookie := range start.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	done := httptest.NewRecorder()
+	r.ServeHTTP(done, req)
+	location, err := url.Parse(done.Header().Get("Location"))
+	if err != nil || location.Query().Get("code") == "" {
+		t.Fatalf("single sign-on redirected to %s, want the frontend with a login code", done.Header().Get("Location"))
+	}
+
+	var session struct {
+		User models.User `json:"user"`
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/oauth/exchange", "", gin.H{"code": location.Query().Get("code")}, &session); code != http.StatusOK {
+		t.Fatalf("exchange = %d", code)
+	}
+	var identity models.OAuthIdentity
+	if err := database.GetDB().Where("user_id = ? AND provider = ?", session.User.ID, "oidc").First(&identity).Error; err != nil {
+		t.Errorf("the new user isn't mapped to the provider's subject: %v", err)
+	}
+}
+
+func TestAdminAllowlistOnlyTrustsConfiguredProxies(t *testing.T) {
+	t.Setenv("ADMIN_IP_ALLOWLIST", "10.0.0.0/8")
+	admin, token := testutil.SeedUser(t)
+	if err := database.GetDB().Model(&admin).Update("is_admin", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// httptest requests come from 192.0.2.1
+	stats := func(r *gin.Engine) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/instance-stats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Forwarded-For", "10.1.2.3")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Code
+	}
+
+	r := newRouter()
+	if err := clientip.Configure(r); err != nil {
+		t.Fatal(err)
+	}
+	if code, errCode := stats(r); code != http.StatusForbidden || errCode != "ip_not_allowed" {
+		t.Errorf("forwarded address from an untrusted proxy = %d %q, want 403 ip_not_allowed", code, errCode)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "192.0.2.0/24")
+	r = newRouter()
+	if err := clientip.Configure(r); err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := stats(r); code != http.StatusOK {
+		t.Errorf("forwarded address from a trusted proxy = %d, want 200", code)
+	}
+}
+
+func TestAuditLogRecordsSignInsAndChanges(t *testing.T) {
+	t.Setenv("BCRYPT_COST", "4")
+	r := newRouter()
+	user, _ := testutil.SeedUser(t)
+	hash, err := auth.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.GetDB().Model(&user).Update("password", hash).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var session struct {
+		Token string `json:"token"`
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/login", "", gin.H{"email": user.Email, "password": "correct horse"}, &session); code != http.StatusOK {
+		t.Fatalf("login = %d", code)
+	}
+	token := session.Token
+
+	var portfolio models.Portfolio
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolios", token, gin.H{"name": "Audited"}, &portfolio); code != http.StatusCreated {
+		t.Fatalf("create portfolio = %d", code)
+	}
+	var coin models.Coin
+	body := gin.H{"portfolio_id": portfolio.ID.String(), "coin_type": "Morgan Dollar", "year": 1921}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, body, &coin); code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if code := request(t, r, http.MethodDelete, "/api/v1/coins/"+coin.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Fatalf("delete coin = %d", code)
+	}
+	// Failures change nothing and aren't recorded
+	request(t, r, http.MethodDelete, "/api/v1/coins/"+coin.ID.String(), token, nil, nil)
+
+	var entries []models.AuditLog
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log", token, nil, &entries); code != http.StatusOK {
+		t.Fatalf("audit log = %d", code)
+	}
+	want := []string{"coin.deleted", "coin.created", "portfolio.created", "login"}
+	if len(entries) != len(want) {
+		t.Fatalf("audit log = %+v, want %v", entries, want)
+	}
+	for i, entry := range entries {
+		if entry.Action != want[i] || entry.IPAddress != "192.0.2.1" {
+			t.Errorf("entry %d = %s from %s, want %s from 192.0.2.1", i, entry.Action, entry.IPAddress, want[i])
+		}
+	}
+	if entries[1].TargetID == nil || *entries[1].TargetID != coin.ID {
+		t.Errorf("coin.created target = %v, want %s", entries[1].TargetID, coin.ID)
+	}
+
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log?action=portfolio.created", token, nil, &entries); code != http.StatusOK || len(entries) != 1 {
+		t.Errorf("filtered by action = %d with %d entries, want 1", code, len(entries))
+	}
+	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log?from="+tomorrow, token, nil, &entries); code != http.StatusOK || len(entries) != 0 {
+		t.Errorf("filtered from tomorrow = %d with %d entries, want none", code, len(entries))
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log?action=coin.melted", token, nil, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown action = %d, want 400", code)
+	}
+
+	// Pages run from the newest back
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/audit-log?limit=3", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	cursor := w.Header().Get("X-Next-Cursor")
+	if cursor == "" {
+		t.Fatal("a full page sent no X-Next-Cursor")
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log?limit=3&after="+cursor, token, nil, &entries); code != http.StatusOK || len(entries) != 1 || entries[0].Action != "login" {
+		t.Errorf("second page = %d %+v, want the login", code, entries)
+	}
+
+	_, other := testutil.SeedUser(t)
+	if code := request(t, r, http.MethodGet, "/api/v1/audit-log", other, nil, &entries); code != http.StatusOK || len(entries) != 0 {
+		t.Errorf("another user's audit log = %d with %d entries, want none", code, len(entries))
+	}
+}
+
+func TestNewCoinIsEnrichedFromItsCert(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Slabs")
+
+	var coin models.Coin
+	body := gin.H{"portfolio_id": portfolio.ID.String(), "coin_type": "Morgan Dollar", "year": 1921, "pcgs_cert_number": "10000001"}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, body, &coin); code != http.StatusCreated {
+		t.Fatalf("create coin = %d", code)
+	}
+	if coin.EnrichmentStatus != enrichment.StatusPending || coin.NumismaticValue != 0 {
+		t.Fatalf("created coin is %q valued at %.2f, want pending with no price guide value yet", coin.EnrichmentStatus, coin.NumismaticValue)
+	}
+
+	if err := enrichment.Enrich(coin.ID); err != nil {
+		t.Fatal(err)
+	}
+	var enriched models.Coin
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.ID.String(), token, nil, &enriched); code != http.StatusOK {
+		t.Fatalf("get coin = %d", code)
+	}
+	if enriched.EnrichmentStatus != enrichment.StatusDone || enriched.Series != "Morgan Dollar" || enriched.Mintage != 44690000 || enriched.NumismaticValue != 185 {
+		t.Errorf("enriched coin is %q, series %q, mintage %d, valued at %.2f", enriched.EnrichmentStatus, enriched.Series, enriched.Mintage, enriched.NumismaticValue)
+	}
+
+	// Coins without a cert have nothing to look up
+	body = gin.H{"portfolio_id": portfolio.ID.String(), "coin_type": "Peace Dollar", "year": 1922}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, body, &coin); code != http.StatusCreated || coin.EnrichmentStatus != "" {
+		t.Errorf("create coin without a cert = %d, status %q", code, coin.EnrichmentStatus)
+	}
+}
+
+func TestSettingsRestateAmountsWeightsAndDates(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Stack")
+	db := database.GetDB()
+	if err := db.Create(&models.FXRate{Currency: "CHF", Rate: 0.5, RecordedAt: time.Now().Add(-time.Minute)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if code := request(t, r, http.MethodPut, "/api/v1/settings", token, gin.H{"currency": "XYZ"}, nil); code != http.StatusBadRequest {
+		t.Errorf("currency without a rate = %d, want 400", code)
+	}
+	body := gin.H{"currency": "chf", "weight_unit": "g", "date_format": "eu", "default_portfolio_id": portfolio.ID.String()}
+	var prefs models.UserSettings
+	if code := request(t, r, http.MethodPut, "/api/v1/settings", token, body, &prefs); code != http.StatusOK {
+		t.Fatalf("update settings = %d", code)
+	}
+	if prefs.Currency != "CHF" || prefs.DefaultPortfolioID == nil || *prefs.DefaultPortfolioID != portfolio.ID {
+		t.Errorf("settings = %+v", prefs)
+	}
+
+	// Sent in francs and grams, stored in dollars and troy ounces
+	var coin models.Coin
+	body = gin.H{"coin_type": "Silver Eagle", "year": 2020, "purchase_price": 15, "metal_type": "silver", "metal_weight": 31.1034768, "metal_purity": 99.9}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, body, &coin); code != http.StatusCreated {
+		t.Fatalf("create coin in the default portfolio = %d", code)
+	}
+	if coin.PortfolioID != portfolio.ID || coin.PurchasePrice != 15 || math.Abs(coin.MetalWeight-31.1034768) > 1e-6 {
+		t.Errorf("created coin in %s at %.2f weighing %v, want the default portfolio at 15 weighing 31.1 grams", coin.PortfolioID, coin.PurchasePrice, coin.MetalWeight)
+	}
+	var stored models.Coin
+	if err := db.First(&stored, "id = ?", coin.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.PurchasePrice != 30 || math.Abs(stored.MetalWeight-1) > 1e-6 {
+		t.Errorf("stored at %.2f weighing %v, want 30 dollars and 1 troy ounce", stored.PurchasePrice, stored.MetalWeight)
+	}
+
+	recordedAt := time.Now().UTC().AddDate(0, 0, -1).Truncate(time.Minute)
+	if err := db.Create(&models.PriceHistory{CoinID: coin.ID, MeltValue: 25, RecordedAt: recordedAt}).Error; err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/coins/"+coin.ID.String()+"/price-history/export", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if want := recordedAt.Format("02/01/2006 15:04") + "," + coin.ID.String() + ",Silver Eagle,2020,12.50,"; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("export = %s, want a row starting %s", w.Body.String(), want)
+	}
+}
+
+func TestDemoLoginProvisionsAnExpiringSampleAccount(t *testing.T) {
+	r := newRouter()
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/demo", "", nil, nil); code != http.StatusNotFound {
+		t.Fatalf("demo login while off = %d, want 404", code)
+	}
+
+	t.Setenv("DEMO_MODE", "true")
+	var session struct {
+		Token string      `json:"token"`
+		User  models.User `json:"user"`
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/demo", "", nil, &session); code != http.StatusCreated {
+		t.Fatalf("demo login = %d, want 201", code)
+	}
+	if session.User.DemoExpiresAt == nil || !strings.HasSuffix(session.User.Email, "@"+demo.EmailDomain) {
+		t.Fatalf("demo user = %+v", session.User)
+	}
+	var stored models.User
+	if err := database.GetDB().First(&stored, "id = ?", session.User.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != "" {
+		t.Error("the demo account has a password")
+	}
+
+	// One address can only hold so many at a time
+	t.Setenv("DEMO_ACCOUNTS_PER_IP", "1")
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/demo", "", nil, nil); code != http.StatusTooManyRequests {
+		t.Errorf("second demo login from the same address = %d, want 429", code)
+	}
+
+	var portfolios []models.Portfolio
+	if code := request(t, r, http.MethodGet, "/api/v1/portfolios", session.Token, nil, &portfolios); code != http.StatusOK {
+		t.Fatalf("GET /portfolios = %d", code)
+	}
+	if len(portfolios) != 1 || portfolios[0].Name != demo.PortfolioName {
+		t.Fatalf("demo portfolios = %+v", portfolios)
+	}
+	var coins int64
+	database.GetDB().Model(&models.Coin{}).Where("portfolio_id = ?", portfolios[0].ID).Count(&coins)
+	if coins == 0 {
+		t.Fatal("the demo portfolio is empty")
+	}
+
+	// Not expired yet: purging leaves it; once it has, it's gone
+	if err := demo.Purge(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", session.Token, nil, nil); code != http.StatusOK {
+		t.Fatalf("GET /auth/me before expiry = %d, want 200", code)
+	}
+	if err := demo.Purge(session.User.DemoExpiresAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	database.GetDB().Model(&models.Coin{}).Where("portfolio_id = ?", portfolios[0].ID).Count(&coins)
+	if coins != 0 {
+		t.Errorf("%d coins left after the demo account expired", coins)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/auth/me", session.Token, nil, nil); code == http.StatusOK {
+		t.Error("the expired demo account can still sign in")
+	}
+}
+
+func TestCoinStoryRendersSafelyAndJoinsStatements(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Heirlooms")
+
+	story := "## From Grandpa\nBought at the **1962** ANA show.\n\n<script>alert(1)</script>"
+	var coin models.Coin
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", token, gin.H{
+		"portfolio_id": portfolio.ID, "coin_type": "Morgan Dollar", "year": 1881, "story": story,
+	}, &coin); code != http.StatusCreated || coin.Story != story {
+		t.Fatalf("create = %d with story %q", code, coin.Story)
+	}
+
+	// Edits that leave the story out keep it
+	request(t, r, http.MethodPut, "/api/v1/coins/"+coin.ID.String(), token, gin.H{"notes": "In the album"}, nil)
+	var rendered struct {
+		Story string `json:"story"`
+		HTML  string `json:"html"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.ID.String()+"/story", token, nil, &rendered); code != http.StatusOK {
+		t.Fatalf("story = %d", code)
+	}
+	if rendered.Story != story || !strings.Contains(rendered.HTML, "<h2>From Grandpa</h2>") ||
+		!strings.Contains(rendered.HTML, "<strong>1962</strong>") || strings.Contains(rendered.HTML, "<script>") {
+		t.Errorf("story = %q rendered as %s", rendered.Story, rendered.HTML)
+	}
+	_, other := testutil.SeedUser(t)
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.ID.String()+"/story", other, nil, nil); code != http.StatusForbidden {
+		t.Errorf("another user's coin story = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodPut, "/api/v1/coins/"+coin.ID.String(), token, gin.H{"story": strings.Repeat("x", 20001)}, nil); code != http.StatusBadRequest {
+		t.Errorf("overlong story = %d, want 400", code)
+	}
+
+	statementPath := "/api/v1/portfolios/" + portfolio.ID.String() + "/statement?month=" + time.Now().UTC().Format("2006-01")
+	var statement struct {
+		Acquisitions []struct {
+			Story string `json:"story"`
+		} `json:"acquisitions"`
+	}
+	request(t, r, http.MethodGet, statementPath, token, nil, &statement)
+	if len(statement.Acquisitions) != 1 || statement.Acquisitions[0].Story != "" {
+		t.Fatalf("statement without stories = %+v", statement.Acquisitions)
+	}
+	request(t, r, http.MethodPut, "/api/v1/portfolios/"+portfolio.ID.String(), token, gin.H{"name": "Heirlooms", "statement_stories": true}, nil)
+	request(t, r, http.MethodGet, statementPath, token, nil, &statement)
+	if len(statement.Acquisitions) != 1 || statement.Acquisitions[0].Story != story {
+		t.Errorf("statement with stories = %+v", statement.Acquisitions)
+	}
+}
+
+func TestSharedPortfolioRespectsMemberRoles(t *testing.T) {
+	r := newRouter()
+	owner, ownerToken := testutil.SeedUser(t)
+	editor, editorToken := testutil.SeedUser(t)
+	viewer, viewerToken := testutil.SeedUser(t)
+	_, strangerToken := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, owner.ID, "Family collection")
+	coin := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Morgan Dollar", Year: 1881, Quantity: 1, PurchasePrice: 60})
+	membersPath := "/api/v1/portfolios/" + portfolio.ID.String() + "/members"
+	coinPath := "/api/v1/coins/" + coin.ID.String()
+
+	join := func(user models.User, token, role string) models.PortfolioMember {
+		t.Helper()
+		var invited models.PortfolioMember
+		if code := request(t, r, http.MethodPost, membersPath, ownerToken, gin.H{"email": user.Email, "role": role}, &invited); code != http.StatusCreated {
+			t.Fatalf("invite %s = %d", role, code)
+		}
+		if code := request(t, r, http.MethodGet, coinPath, token, nil, nil); code != http.StatusForbidden {
+			t.Errorf("%s reading before accepting = %d, want 403", role, code)
+		}
+		if code := request(t, r, http.MethodPost, "/api/v1/portfolio-invitations/"+invited.ID.String()+"/accept", token, nil, nil); code != http.StatusOK {
+			t.Fatalf("accept %s invitation = %d", role, code)
+		}
+		return invited
+	}
+	join(editor, editorToken, "editor")
+	viewerMember := join(viewer, viewerToken, "viewer")
+
+	if code := request(t, r, http.MethodPost, membersPath, ownerToken, gin.H{"email": viewer.Email, "role": "editor"}, nil); code != http.StatusConflict {
+		t.Errorf("inviting a member again = %d, want 409", code)
+	}
+
+	var portfolios []struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}
+	request(t, r, http.MethodGet, "/api/v1/portfolios", viewerToken, nil, &portfolios)
+	if len(portfolios) != 1 || portfolios[0].ID != portfolio.ID || portfolios[0].Role != "viewer" {
+		t.Errorf("viewer's portfolio list = %+v, want the shared portfolio as viewer", portfolios)
+	}
+
+	if code := request(t, r, http.MethodGet, coinPath, viewerToken, nil, nil); code != http.StatusOK {
+		t.Errorf("viewer reading a coin = %d, want 200", code)
+	}
+	if code := request(t, r, http.MethodPut, coinPath, viewerToken, gin.H{"notes": "mine now"}, nil); code != http.StatusForbidden {
+		t.Errorf("viewer updating a coin = %d, want 403", code)
+	}
+	newCoin := gin.H{"portfolio_id": portfolio.ID.String(), "coin_type": "Peace Dollar", "year": 1922}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", viewerToken, newCoin, nil); code != http.StatusForbidden {
+		t.Errorf("viewer adding a coin = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins", editorToken, newCoin, nil); code != http.StatusCreated {
+		t.Errorf("editor adding a coin = %d, want 201", code)
+	}
+	if code := request(t, r, http.MethodPut, "/api/v1/portfolios/"+portfolio.ID.String(), editorToken, gin.H{"name": "Renamed"}, nil); code != http.StatusForbidden {
+		t.Errorf("editor renaming the portfolio = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodGet, coinPath, strangerToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("non-member reading a coin = %d, want 403", code)
+	}
+	if code := request(t, r, http.MethodGet, membersPath, strangerToken, nil, nil); code != http.StatusNotFound {
+		t.Errorf("non-member listing members = %d, want 404", code)
+	}
+
+	if code := request(t, r, http.MethodDelete, membersPath+"/"+viewerMember.ID.String(), ownerToken, nil, nil); code != http.StatusOK {
+		t.Fatalf("remove viewer = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, coinPath, viewerToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("removed viewer reading a coin = %d, want 403", code)
+	}
+}
+
+func TestCoOwnerTransfersACoinInTheCreatorsName(t *testing.T) {
+	r := newRouter()
+	creator, creatorToken := testutil.SeedUser(t)
+	coOwner, coOwnerToken := testutil.SeedUser(t)
+	recipient, recipientToken := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, creator.ID, "Family collection")
+	coin := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Morgan Dollar", Year: 1881, Quantity: 1, PurchasePrice: 60})
+	gifts := testutil.SeedPortfolio(t, recipient.ID, "Gifts")
+
+	var invited models.PortfolioMember
+	membersPath := "/api/v1/portfolios/" + portfolio.ID.String() + "/members"
+	if code := request(t, r, http.MethodPost, membersPath, creatorToken, gin.H{"email": coOwner.Email, "role": "owner"}, &invited); code != http.StatusCreated {
+		t.Fatalf("invite owner = %d", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolio-invitations/"+invited.ID.String()+"/accept", coOwnerToken, nil, nil); code != http.StatusOK {
+		t.Fatalf("accept invitation = %d", code)
+	}
+
+	body := gin.H{"name": "Counter screen", "portfolio_id": portfolio.ID}
+	if code := request(t, r, http.MethodPost, "/api/v1/auth/display-tokens", coOwnerToken, body, nil); code != http.StatusForbidden {
+		t.Errorf("co-owner creating a display token = %d, want 403", code)
+	}
+
+	var transfer models.CoinTransfer
+	path := "/api/v1/coins/" + coin.ID.String() + "/transfer"
+	if code := request(t, r, http.MethodPost, path, coOwnerToken, gin.H{"to_email": creator.Email}, nil); code != http.StatusBadRequest {
+		t.Errorf("transfer to the creator = %d, want 400", code)
+	}
+	if code := request(t, r, http.MethodPost, path, coOwnerToken, gin.H{"to_email": recipient.Email}, &transfer); code != http.StatusCreated {
+		t.Fatalf("co-owner offering the coin = %d", code)
+	}
+	if transfer.FromUserID != creator.ID || transfer.InitiatedByID == nil || *transfer.InitiatedByID != coOwner.ID {
+		t.Errorf("transfer from %s initiated by %v, want from %s initiated by %s", transfer.FromUserID, transfer.InitiatedByID, creator.ID, coOwner.ID)
+	}
+
+	var outgoing struct {
+		Outgoing []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"outgoing"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/transfers", coOwnerToken, nil, &outgoing); code != http.StatusOK {
+		t.Fatalf("GET /transfers = %d", code)
+	}
+	if len(outgoing.Outgoing) != 1 || outgoing.Outgoing[0].ID != transfer.ID {
+		t.Errorf("co-owner's outgoing transfers = %+v, want the offer", outgoing.Outgoing)
+	}
+
+	var accepted struct {
+		Coin models.Coin `json:"coin"`
+	}
+	acceptPath := "/api/v1/transfers/" + transfer.ID.String() + "/accept"
+	if code := request(t, r, http.MethodPost, acceptPath, recipientToken, gin.H{"portfolio_id": gifts.ID.String()}, &accepted); code != http.StatusOK {
+		t.Fatalf("accept transfer = %d", code)
+	}
+	if accepted.Coin.PortfolioID != gifts.ID {
+		t.Errorf("transferred coin in %s, want %s", accepted.Coin.PortfolioID, gifts.ID)
+	}
+}
+
+func TestCoinCodeWorksInPlaceOfItsID(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	coin := testutil.SeedCoin(t, testutil.SeedPortfolio(t, user.ID, "Labelled").ID, models.Coin{
+		CoinType: "Morgan Dollar", Year: 1881, Quantity: 1,
+	})
+	if !strings.HasPrefix(coin.Code, "AU-") {
+		t.Fatalf("new coin's code = %q, want AU-...", coin.Code)
+	}
+
+	var fetched models.Coin
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+strings.ToLower(coin.Code), token, nil, &fetched); code != http.StatusOK {
+		t.Fatalf("get coin by code = %d", code)
+	}
+	if fetched.ID != coin.ID || fetched.Code != coin.Code {
+		t.Errorf("code %s fetched %s (%s), want %s", coin.Code, fetched.ID, fetched.Code, coin.ID)
+	}
+	_, otherToken := testutil.SeedUser(t)
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+coin.Code, otherToken, nil, nil); code == http.StatusOK {
+		t.Error("another user fetched the coin by its code")
+	}
+
+	body := gin.H{"disposition": "gifted"}
+	if code := request(t, r, http.MethodPost, "/api/v1/coins/"+coin.Code+"/dispose", token, body, nil); code != http.StatusOK {
+		t.Fatalf("dispose by code = %d", code)
+	}
+	var archived models.ArchivedCoin
+	if code := request(t, r, http.MethodGet, "/api/v1/archived-coins/"+coin.Code, token, nil, &archived); code != http.StatusOK {
+		t.Fatalf("get archived coin by code = %d", code)
+	}
+	if archived.ID != coin.ID || archived.Code != coin.Code {
+		t.Errorf("archived coin %s (%s), want %s (%s)", archived.ID, archived.Code, coin.ID, coin.Code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/AU-999999999", token, nil, nil); code != http.StatusNotFound {
+		t.Errorf("unknown code = %d, want 404", code)
+	}
+}
+
+func TestDeletedPortfolioCanBeRestoredFromTrash(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Binned")
+	kept := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Morgan Dollar", Year: 1881, Quantity: 1})
+	loose := testutil.SeedCoin(t, portfolio.ID, models.Coin{CoinType: "Peace Dollar", Year: 1922, Quantity: 1})
+
+	if code := request(t, r, http.MethodDelete, "/api/v1/coins/"+loose.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Fatalf("delete coin = %d", code)
+	}
+	if code := request(t, r, http.MethodDelete, "/api/v1/portfolios/"+portfolio.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Fatalf("delete portfolio = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+kept.ID.String(), token, nil, nil); code != http.StatusNotFound {
+		t.Errorf("coin of a trashed portfolio = %d, want 404", code)
+	}
+
+	var trash struct {
+		Portfolios []handlers.TrashedPortfolio `json:"portfolios"`
+		Coins      []handlers.TrashedCoin      `json:"coins"`
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/trash", token, nil, &trash); code != http.StatusOK {
+		t.Fatalf("get trash = %d", code)
+	}
+	if len(trash.Portfolios) != 1 || trash.Portfolios[0].ID != portfolio.ID || trash.Portfolios[0].CoinCount != 1 {
+		t.Fatalf("trashed portfolios = %+v, want %s with 1 coin", trash.Portfolios, portfolio.ID)
+	}
+	if len(trash.Coins) != 1 || trash.Coins[0].ID != loose.ID || trash.Coins[0].Code != loose.Code {
+		t.Fatalf("trashed coins = %+v, want %s", trash.Coins, loose.ID)
+	}
+
+	if code := request(t, r, http.MethodPost, "/api/v1/trash/coins/"+loose.Code+"/restore", token, nil, nil); code != http.StatusConflict {
+		t.Errorf("restore coin of a trashed portfolio = %d, want 409", code)
+	}
+	_, otherToken := testutil.SeedUser(t)
+	if code := request(t, r, http.MethodPost, "/api/v1/trash/portfolios/"+portfolio.ID.String()+"/restore", otherToken, nil, nil); code != http.StatusNotFound {
+		t.Errorf("another user restored the portfolio: %d", code)
+	}
+	if code := request(t, r, http.MethodPost, "/api/v1/trash/portfolios/"+portfolio.ID.String()+"/restore", token, nil, nil); code != http.StatusOK {
+		t.Fatalf("restore portfolio = %d", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+kept.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Errorf("coin of a restored portfolio = %d, want 200", code)
+	}
+	if code := request(t, r, http.MethodGet, "/api/v1/coins/"+loose.ID.String(), token, nil, nil); code != http.StatusNotFound {
+		t.Errorf("coin deleted on its own came back with its portfolio: %d", code)
+	}
+
+	if code := request(t, r, http.MethodDelete, "/api/v1/trash/coins/"+loose.ID.String(), token, nil, nil); code != http.StatusOK {
+		t.Fatalf("purge coin = %d", code)
+	}
+	var count int64
+	database.GetDB().Unscoped().Model(&models.Coin{}).Where("id = ?", loose.ID).Count(&count)
+	if count != 0 {
+		t.Error("purged coin is still stored")
+	}
+}
+
+func TestArchivedPortfoliosAreListedSeparately(t *testing.T) {
+	r := newRouter()
+	user, token := testutil.SeedUser(t)
+	portfolio := testutil.SeedPortfolio(t, user.ID, "Sold off")
+
+	var archived models.Portfolio
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolios/"+portfolio.ID.String()+"/archive", token, nil, &archived); code != http.StatusOK {
+		t.Fatalf("archive = %d", code)
+	}
+	if !archived.Archived || archived.ArchivedAt == nil {
+		t.Errorf("archived portfolio = %+v", archived)
+	}
+
+	var listed []models.Portfolio
+	request(t, r, http.MethodGet, "/api/v1/portfolios", token, nil, &listed)
+	if len(listed) != 0 {
+		t.Errorf("portfolio list has %d portfolios, want the archived one left out", len(listed))
+	}
+	request(t, r, http.MethodGet, "/api/v1/portfolios?archived=true", token, nil, &listed)
+	if len(listed) != 1 || listed[0].ID != portfolio.ID {
+		t.Errorf("archived list = %+v, want %s", listed, portfolio.ID)
+	}
+
+	if code := request(t, r, http.MethodPost, "/api/v1/portfolios/"+portfolio.ID.String()+"/unarchive", token, nil, &archived); code != http.StatusOK || archived.Archived {
+		t.Errorf("unarchive = %d, archived %v", code, archived.Archived)
+	}
+}
@@ -1,19 +1,47 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"time"
 
+	"github.com/evansminotwood/aureus/internal/alerts"
+	"github.com/evansminotwood/aureus/internal/apikeys"
+	"github.com/evansminotwood/aureus/internal/archive"
+	"github.com/evansminotwood/aureus/internal/certimages"
+	"github.com/evansminotwood/aureus/internal/clientip"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/crypto"
 	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/enrichment"
 	"github.com/evansminotwood/aureus/internal/handlers"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
 	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/notifications"
+	"github.com/evansminotwood/aureus/internal/oauth"
+	"github.com/evansminotwood/aureus/internal/registry"
+	"github.com/evansminotwood/aureus/internal/scheduler"
+	"github.com/evansminotwood/aureus/internal/security"
+	"github.com/evansminotwood/aureus/internal/settings"
+	"github.com/evansminotwood/aureus/internal/snapshots"
+	"github.com/evansminotwood/aureus/internal/spothistory"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/evansminotwood/aureus/internal/valuation"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
+const defaultLegacySunset = "2027-06-30"
+
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML settings file; environment variables and *_FILE secrets take precedence")
+	flag.Parse()
+
 	// Try multiple .env locations
 	if err := godotenv.Load("../../../.env"); err != nil {
 		if err := godotenv.Load("../../.env"); err != nil {
@@ -29,14 +57,36 @@ func main() {
 		log.Println("✓ Loaded .env from ../../../.env")
 	}
 
+	if *configFile != "" {
+		if err := config.LoadFile(*configFile); err != nil {
+			log.Fatal("Failed to load config: ", err)
+		}
+		log.Printf("✓ Loaded settings from %s", *configFile)
+	}
+
 	// Debug: Check if PCGS_API_KEY is loaded
-	pcgsKey := os.Getenv("PCGS_API_KEY")
+	pcgsKey := config.String("PCGS_API_KEY", "")
 	if pcgsKey != "" {
 		log.Printf("✓ PCGS_API_KEY loaded (length: %d characters)", len(pcgsKey))
 	} else {
 		log.Println("⚠️  PCGS_API_KEY not found in environment")
 	}
 
+	if config.MockMode() {
+		log.Println("⚠️  MOCK_EXTERNAL_APIS enabled - PCGS and spot prices are served from local fixtures")
+	}
+	if config.Bool("MOCK_OAUTH", false) {
+		if !config.Development() {
+			log.Fatal("MOCK_OAUTH signs anyone in without credentials and is only allowed with APP_ENV=development")
+		}
+		log.Printf("⚠️  MOCK_OAUTH enabled - sign-in providers are faked for addresses at %s", oauth.MockEmailDomain)
+	}
+
+	crypto.ConfigureFromEnv()
+	if !crypto.Enabled() {
+		log.Println("⚠️  SECRETS_KEY not configured - storing user API keys and tokens is disabled")
+	}
+
 	if err := database.Connect(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -45,17 +95,86 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	if middleware.MultiTenant() {
+		defaultTenant := config.String("DEFAULT_TENANT", "default")
+		if err := database.EnsureTenant(defaultTenant, defaultTenant); err != nil {
+			log.Fatal("Failed to create default tenant:", err)
+		}
+		log.Printf("✓ Multi-tenant mode enabled (tenant header %s, default tenant %q)", middleware.TenantHeader(), defaultTenant)
+	}
+
+	if err := database.PromoteAdmins(); err != nil {
+		log.Println("Failed to promote admin users:", err)
+	}
+
+	var overrides []models.CompositionOverride
+	if err := database.GetDB().Find(&overrides).Error; err != nil {
+		log.Println("Failed to load composition overrides:", err)
+	} else if len(overrides) > 0 {
+		metals.LoadOverrides(overrides)
+		log.Printf("✓ Loaded %d composition override(s)", len(overrides))
+	}
+
+	var fallbacks []models.FallbackPrice
+	if err := database.GetDB().Find(&fallbacks).Error; err != nil {
+		log.Println("Failed to load fallback spot prices:", err)
+	} else if len(fallbacks) > 0 {
+		metals.LoadFallbackPrices(fallbacks)
+		log.Printf("✓ Loaded %d fallback spot price(s)", len(fallbacks))
+	}
+
+	if args := flag.Args(); len(args) > 0 && args[0] == "seed" {
+		runSeed(args[1:])
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "create-admin" {
+		runCreateAdmin(args[1:])
+		return
+	}
+
+	// Wire event subscribers before anything can publish
+	alerts.Subscribe()
+	snapshots.Subscribe()
+	notifications.Subscribe()
+	certimages.Subscribe()
+	spothistory.Subscribe()
+	registry.Subscribe()
+	archive.Subscribe()
+	valuation.Subscribe()
+	apikeys.Subscribe()
+	enrichment.Subscribe()
+	settings.Subscribe()
+	members.Subscribe()
+
+	scheduler.Start(context.Background(), scheduler.DefaultJobs())
+
 	r := gin.Default()
+	if err := clientip.Configure(r); err != nil {
+		log.Fatal("Invalid proxy configuration: ", err)
+	}
+	if _, err := clientip.ParseList(config.String("ADMIN_IP_ALLOWLIST", "")); err != nil {
+		log.Fatal("ADMIN_IP_ALLOWLIST: ", err)
+	}
+	if value, ok := config.Lookup("COOKIE_SAMESITE"); ok {
+		if _, err := security.ParseSameSite(value); err != nil {
+			log.Fatal("COOKIE_SAMESITE: ", err)
+		}
+	}
 
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:3000"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", apikeys.Header, middleware.TenantHeader()},
+		ExposeHeaders:    []string{"Content-Length", "X-API-Version", "Deprecation", "Sunset", "Link", "X-Total-Count", "X-Next-Cursor"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.Use(middleware.SecurityHeaders())
+	r.Use(middleware.BodySizeLimit())
+	r.Use(middleware.DebugLog())
+	r.Use(middleware.RoundValues())
+
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status":  "healthy",
@@ -64,74 +183,44 @@ func main() {
 		})
 	})
 
-	api := r.Group("/api")
-	{
-		auth := api.Group("/auth")
-		{
-			auth.POST("/register", handlers.Register)
-			auth.POST("/login", handlers.Login)
-		}
+	// External API usage for Prometheus
+	r.GET("/metrics", handlers.GetMetrics)
 
-		protected := api.Group("")
-		protected.Use(middleware.AuthRequired())
-		{
-			protected.GET("/auth/me", handlers.GetCurrentUser)
-
-			portfolios := protected.Group("/portfolios")
-			{
-				portfolios.GET("", handlers.GetPortfolios)
-				portfolios.POST("", handlers.CreatePortfolio)
-				portfolios.GET("/:id", handlers.GetPortfolio)
-				portfolios.PUT("/:id", handlers.UpdatePortfolio)
-				portfolios.DELETE("/:id", handlers.DeletePortfolio)
-				portfolios.GET("/:id/stats", handlers.GetPortfolioStats)
-				portfolios.GET("/:id/coins", handlers.GetPortfolioCoins)
-			}
+	// Serve uploaded images from local storage
+	r.Static("/uploads", storage.NewLocalStorage().BaseDir)
 
-			coins := protected.Group("/coins")
-			{
-				coins.POST("", handlers.CreateCoin)
-				coins.GET("/:id", handlers.GetCoin)
-				coins.PUT("/:id", handlers.UpdateCoin)
-				coins.DELETE("/:id", handlers.DeleteCoin)
-				coins.GET("/:id/price-history", handlers.GetCoinPriceHistory)
-				coins.POST("/:id/price-snapshot", handlers.RecordPriceSnapshot)
-				coins.POST("/sync-pcgs-values", handlers.SyncPCGSValues)
-			}
+	// Versioned API. Breaking response changes ship under a new version
+	// (e.g. /api/v2) while older versions keep their existing behavior.
+	registerRoutes(r.Group("/api/v1", middleware.APIVersion("v1")))
 
-			pcgs := protected.Group("/pcgs")
-			{
-				pcgs.GET("/price", handlers.GetPCGSPrice)
-			pcgs.GET("/images", handlers.GetPCGSImages)
-			}
+	// Unversioned routes are a deprecated alias of v1 kept for older clients
+	legacy := r.Group("/api",
+		middleware.Deprecated(middleware.Deprecation{
+			Sunset:    legacySunset(),
+			Successor: middleware.ReplacePrefix("/api/", "/api/v1/"),
+		}),
+		middleware.APIVersion("v1"),
+	)
+	registerRoutes(legacy)
 
-			metals := protected.Group("/metals")
-			{
-				metals.GET("/spot-prices", handlers.GetSpotPrices)
-				metals.GET("/compositions", handlers.GetMetalCompositions)
-				metals.GET("/composition", handlers.GetCoinComposition)
-				metals.POST("/melt-value", handlers.CalculateMeltValue)
-				metals.POST("/backfill-composition", handlers.BackfillMetalComposition)
-			}
-
-			priceHistory := protected.Group("/price-history")
-			{
-				priceHistory.POST("/backfill", handlers.BackfillPriceHistory)
-			}
-		}
-	}
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	port := config.String("PORT", "8080")
 
 	log.Printf("🚀 Server starting on port %s", port)
-	log.Printf("📊 API documentation: http://localhost:%s/health", port)
-	log.Printf("🔐 Auth endpoints: http://localhost:%s/api/auth/...", port)
-	log.Printf("💼 Portfolio endpoints: http://localhost:%s/api/portfolios/...", port)
+	log.Printf("📊 API documentation: http://localhost:%s/api/v1/openapi.yaml", port)
+	log.Printf("🔐 Auth endpoints: http://localhost:%s/api/v1/auth/...", port)
+	log.Printf("💼 Portfolio endpoints: http://localhost:%s/api/v1/portfolios/...", port)
 
 	if err := r.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// legacySunset is when the unversioned /api alias is removed (API_LEGACY_SUNSET, YYYY-MM-DD)
+func legacySunset() time.Time {
+	sunset, err := time.Parse("2006-01-02", config.String("API_LEGACY_SUNSET", defaultLegacySunset))
+	if err != nil {
+		log.Printf("Invalid API_LEGACY_SUNSET, using %s", defaultLegacySunset)
+		sunset, _ = time.Parse("2006-01-02", defaultLegacySunset)
+	}
+	return sunset
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"net/http"
+
+	spec "github.com/evansminotwood/aureus/api"
+	"github.com/evansminotwood/aureus/internal/audit"
+	authscopes "github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/handlers"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// registerRoutes mounts every API route on api. It is called once per API
+// version prefix; handlers that need version-specific behavior check
+// middleware.APIVersionFrom.
+func registerRoutes(api *gin.RouterGroup) {
+	api.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/yaml", spec.OpenAPISpec)
+	})
+
+	// Providers send the browser back without the tenant header; the signed
+	// state names the tenant instead
+	api.GET("/auth/oauth/:provider/callback", handlers.OAuthCallback)
+	api.POST("/auth/oauth/:provider/callback", handlers.OAuthCallback)
+
+	// Everything below is scoped to a tenant in multi-tenant mode
+	api.Use(middleware.ResolveTenant())
+
+	auth := api.Group("/auth")
+	{
+		auth.POST("/register", middleware.LoginGuard("register"), middleware.Audit(audit.ActionRegister), handlers.Register)
+		auth.POST("/login", middleware.LoginGuard("login"), middleware.Audit(audit.ActionLogin), handlers.Login)
+		auth.POST("/demo", middleware.Audit(audit.ActionLogin), handlers.DemoLogin)
+		auth.POST("/refresh", handlers.RefreshSession)
+		auth.POST("/logout", handlers.Logout)
+		auth.GET("/registration", handlers.GetRegistrationMode)
+		auth.POST("/change-email/confirm", handlers.ConfirmEmailChange)
+		auth.POST("/forgot-password", handlers.ForgotPassword)
+		auth.POST("/reset-password", handlers.ResetPassword)
+		auth.GET("/oauth/providers", handlers.GetOAuthProviders)
+		auth.GET("/oauth/:provider/start", handlers.StartOAuth)
+		auth.POST("/oauth/exchange", middleware.Audit(audit.ActionLogin), handlers.ExchangeOAuthCode)
+	}
+
+	// Public, read-only; off unless PUBLIC_REGISTRY is set
+	api.GET("/registry/leaderboard", handlers.GetRegistryLeaderboard)
+
+	protected := api.Group("")
+	protected.Use(middleware.AuthRequired())
+	{
+		protected.GET("/auth/me", middleware.NoDisplayTokens(), handlers.GetCurrentUser)
+		protected.GET("/audit-log", middleware.FullAccessRequired(), handlers.GetAuditLog)
+		protected.GET("/settings", middleware.NoDisplayTokens(), handlers.GetSettings)
+		protected.PUT("/settings", middleware.FullAccessRequired(), handlers.UpdateSettings)
+		protected.POST("/upload", middleware.RequireScope(authscopes.ScopeCoinsWrite), handlers.UploadImage)
+
+		// Account settings and token issuing need a full access login
+		account := protected.Group("/auth")
+		account.Use(middleware.FullAccessRequired())
+		{
+			account.POST("/tokens", handlers.CreateScopedToken)
+			account.GET("/api-keys", handlers.GetAPIKeys)
+			account.POST("/api-keys", handlers.CreateAPIKey)
+			account.PUT("/api-keys/:id", handlers.UpdateAPIKey)
+			account.DELETE("/api-keys/:id", handlers.DeleteAPIKey)
+			account.POST("/display-tokens", handlers.CreateDisplayToken)
+			account.POST("/logout-everywhere", handlers.LogoutEverywhere)
+			account.GET("/sessions", handlers.GetSessions)
+			account.DELETE("/sessions/:id", handlers.RevokeSession)
+			account.POST("/change-email", handlers.ChangeEmail)
+			account.GET("/oauth/identities", handlers.GetOAuthIdentities)
+			account.POST("/oauth/:provider/link", handlers.LinkOAuth)
+			account.DELETE("/oauth/:provider", handlers.UnlinkOAuth)
+			account.DELETE("/me", handlers.DeleteAccount)
+			account.GET("/me/export", handlers.ExportAccount)
+			account.GET("/me/pcgs-key", handlers.GetPCGSKey)
+			account.PUT("/me/pcgs-key", handlers.SetPCGSKey)
+			account.DELETE("/me/pcgs-key", handlers.DeletePCGSKey)
+			account.GET("/me/pcgs-sync", handlers.GetPCGSSyncSchedule)
+			account.PUT("/me/pcgs-sync", handlers.SetPCGSSyncSchedule)
+			account.GET("/me/emergency-contacts", handlers.GetEmergencyContacts)
+			account.POST("/me/emergency-contacts", handlers.DesignateEmergencyContact)
+			account.POST("/me/emergency-contacts/:id/deny", handlers.DenyEmergencyAccess)
+			account.DELETE("/me/emergency-contacts/:id", handlers.RemoveEmergencyContact)
+		}
+
+		portfolios := protected.Group("/portfolios")
+		portfolios.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite, "/stats-batch", "/statement/send", "/what-if"), middleware.Localize())
+		{
+			portfolios.GET("", handlers.GetPortfolios)
+			portfolios.POST("", middleware.Audit(audit.ActionPortfolioCreated), handlers.CreatePortfolio)
+			portfolios.POST("/stats-batch", handlers.GetPortfolioStatsBatch)
+			portfolios.POST("/reorder", handlers.ReorderPortfolios)
+			portfolios.GET("/:id", handlers.GetPortfolio)
+			portfolios.PUT("/:id", middleware.Audit(audit.ActionPortfolioUpdated), handlers.UpdatePortfolio)
+			portfolios.DELETE("/:id", middleware.Audit(audit.ActionPortfolioDeleted), handlers.DeletePortfolio)
+			portfolios.POST("/:id/archive", middleware.Audit(audit.ActionPortfolioUpdated), handlers.ArchivePortfolio)
+			portfolios.POST("/:id/unarchive", middleware.Audit(audit.ActionPortfolioUpdated), handlers.UnarchivePortfolio)
+			portfolios.GET("/:id/stats", handlers.GetPortfolioStats)
+			portfolios.GET("/:id/coins", handlers.GetPortfolioCoins)
+			portfolios.POST("/:id/import", middleware.Audit(audit.ActionCoinsImported), handlers.ImportCoins)
+			portfolios.GET("/:id/price-history/export", handlers.ExportPortfolioPriceHistory)
+			portfolios.GET("/:id/performance/chart", handlers.GetPortfolioPerformanceChart)
+			portfolios.GET("/:id/heatmap", handlers.GetPortfolioHeatMap)
+			portfolios.GET("/:id/statement", handlers.GetPortfolioStatement)
+			portfolios.POST("/:id/statement/send", handlers.SendPortfolioStatement)
+			portfolios.POST("/:id/what-if", handlers.PortfolioWhatIf)
+			portfolios.GET("/:id/alerts", handlers.GetPortfolioAlerts)
+			portfolios.POST("/:id/alerts", handlers.CreatePortfolioAlert)
+			portfolios.GET("/:id/registry", handlers.GetPortfolioRegistrySet)
+			portfolios.PUT("/:id/registry", handlers.SavePortfolioRegistrySet)
+			portfolios.DELETE("/:id/registry", handlers.DeletePortfolioRegistrySet)
+			portfolios.GET("/:id/archived-coins", handlers.GetPortfolioArchivedCoins)
+			portfolios.GET("/:id/members", handlers.GetPortfolioMembers)
+			portfolios.POST("/:id/members", middleware.FullAccessRequired(), handlers.InvitePortfolioMember)
+			portfolios.PUT("/:id/members/:memberId", middleware.FullAccessRequired(), handlers.UpdatePortfolioMember)
+			portfolios.DELETE("/:id/members/:memberId", middleware.FullAccessRequired(), handlers.RemovePortfolioMember)
+		}
+
+		// Invitations to portfolios other users share
+		invitations := protected.Group("/portfolio-invitations")
+		invitations.Use(middleware.FullAccessRequired())
+		{
+			invitations.GET("", handlers.GetPortfolioInvitations)
+			invitations.POST("/:id/accept", handlers.AcceptPortfolioInvitation)
+			invitations.POST("/:id/decline", handlers.DeclinePortfolioInvitation)
+		}
+
+		// Long operations answer 202 with a job to poll here
+		jobs := protected.Group("/jobs")
+		jobs.Use(middleware.RequireScope(authscopes.ScopeCoinsRead))
+		{
+			jobs.GET("", handlers.GetJobs)
+			jobs.GET("/:id", handlers.GetJob)
+		}
+
+		// Only reads the file; the import itself is on the portfolio
+		protected.POST("/import/preview", middleware.RequireScope(authscopes.ScopeCoinsRead), handlers.PreviewImport)
+
+		alerts := protected.Group("/alerts")
+		alerts.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite))
+		{
+			alerts.PUT("/:id", handlers.UpdatePortfolioAlert)
+			alerts.DELETE("/:id", handlers.DeletePortfolioAlert)
+		}
+
+		coinAlerts := protected.Group("/coin-alerts")
+		coinAlerts.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite))
+		{
+			coinAlerts.PUT("/:id", handlers.UpdateCoinAlert)
+			coinAlerts.DELETE("/:id", handlers.DeleteCoinAlert)
+		}
+
+		spotAlerts := protected.Group("/spot-alerts")
+		spotAlerts.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite))
+		{
+			spotAlerts.GET("", handlers.GetSpotAlerts)
+			spotAlerts.POST("", handlers.CreateSpotAlert)
+			spotAlerts.PUT("/:id", handlers.UpdateSpotAlert)
+			spotAlerts.DELETE("/:id", handlers.DeleteSpotAlert)
+		}
+
+		coins := protected.Group("/coins")
+		coins.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite, "/listing-draft"), middleware.Localize(), middleware.CoinCode())
+		{
+			coins.POST("", middleware.Audit(audit.ActionCoinCreated), handlers.CreateCoin)
+			coins.GET("/:id", handlers.GetCoin)
+			coins.PUT("/:id", middleware.Audit(audit.ActionCoinUpdated), handlers.UpdateCoin)
+			coins.DELETE("/:id", middleware.Audit(audit.ActionCoinDeleted), handlers.DeleteCoin)
+			coins.GET("/:id/price-history", handlers.GetCoinPriceHistory)
+			coins.GET("/:id/price-history/export", handlers.ExportCoinPriceHistory)
+			coins.GET("/:id/price-history/chart", handlers.GetCoinPriceChart)
+			coins.GET("/:id/valuation-explain", handlers.ExplainCoinValuation)
+			coins.GET("/:id/story", handlers.GetCoinStory)
+			coins.GET("/:id/comps", handlers.GetCoinComps)
+			coins.POST("/:id/listing-draft", handlers.CreateListingDraft)
+			coins.POST("/:id/price-snapshot", handlers.RecordPriceSnapshot)
+			coins.POST("/:id/revalue", handlers.RevalueCoin)
+			coins.POST("/sync-pcgs-values", middleware.Audit(audit.ActionPCGSSync), handlers.SyncPCGSValues)
+			coins.GET("/composition-review", handlers.GetCompositionReviewQueue)
+			coins.POST("/:id/composition-review", handlers.ReviewCoinComposition)
+			coins.GET("/cert-review", handlers.GetCertReviewQueue)
+			coins.GET("/watched", handlers.GetWatchedCoins)
+			coins.GET("/:id/alerts", handlers.GetCoinAlerts)
+			coins.POST("/:id/alerts", handlers.CreateCoinAlert)
+			coins.POST("/:id/cert-review", handlers.VerifyCoinCert)
+			coins.POST("/:id/transfer", handlers.TransferCoin)
+			coins.POST("/:id/dispose", handlers.DisposeCoin)
+			coins.POST("/:id/split", handlers.SplitCoin)
+			coins.POST("/:id/merge", handlers.MergeCoins)
+			coins.POST("/:id/upgrade", handlers.UpgradeCoin)
+			coins.GET("/:id/upgrades", handlers.GetCoinUpgrades)
+		}
+
+		archivedCoins := protected.Group("/archived-coins")
+		archivedCoins.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite), middleware.Localize(), middleware.ArchivedCoinCode())
+		{
+			archivedCoins.GET("/:id", handlers.GetArchivedCoin)
+			archivedCoins.POST("/:id/restore", handlers.RestoreArchivedCoin)
+			archivedCoins.DELETE("/:id", handlers.DeleteArchivedCoin)
+		}
+
+		// Deleted portfolios and coins, until they're purged
+		trash := protected.Group("/trash")
+		trash.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite), middleware.Localize())
+		{
+			trash.GET("", handlers.GetTrash)
+			trash.POST("/portfolios/:id/restore", middleware.Audit(audit.ActionPortfolioUpdated), handlers.RestoreTrashedPortfolio)
+			trash.DELETE("/portfolios/:id", middleware.Audit(audit.ActionPortfolioDeleted), handlers.PurgeTrashedPortfolio)
+			trash.POST("/coins/:id/restore", middleware.TrashedCoinCode(), middleware.Audit(audit.ActionCoinUpdated), handlers.RestoreTrashedCoin)
+			trash.DELETE("/coins/:id", middleware.TrashedCoinCode(), middleware.Audit(audit.ActionCoinDeleted), handlers.PurgeTrashedCoin)
+		}
+
+		transfers := protected.Group("/transfers")
+		transfers.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite), middleware.Localize())
+		{
+			transfers.GET("", handlers.GetTransfers)
+			transfers.POST("/:id/accept", handlers.AcceptTransfer)
+			transfers.POST("/:id/decline", handlers.DeclineTransfer)
+			transfers.DELETE("/:id", handlers.CancelTransfer)
+		}
+
+		// Emergency contacts act on their own login, never a scoped token
+		emergencyAccess := protected.Group("/emergency-access")
+		emergencyAccess.Use(middleware.FullAccessRequired())
+		{
+			emergencyAccess.GET("", handlers.GetEmergencyAccess)
+			emergencyAccess.POST("/:id/request", handlers.RequestEmergencyAccess)
+			emergencyAccess.POST("/:id/token", handlers.CreateEmergencyAccessToken)
+		}
+
+		notifications := protected.Group("/notifications")
+		notifications.Use(middleware.FullAccessRequired())
+		{
+			notifications.GET("", handlers.GetNotifications)
+			notifications.POST("/read-all", handlers.MarkAllNotificationsRead)
+			notifications.POST("/:id/read", handlers.MarkNotificationRead)
+			notifications.GET("/settings", handlers.GetNotificationSettings)
+			notifications.PUT("/settings", handlers.UpdateNotificationSettings)
+			notifications.PUT("/push-subscription", handlers.SetPushSubscription)
+			notifications.DELETE("/push-subscription", handlers.DeletePushSubscription)
+		}
+
+		// Display tokens can read this and nothing else
+		protected.GET("/display", middleware.RequireScope(authscopes.ScopeDisplay), middleware.Localize(), handlers.GetDisplay)
+
+		pcgs := protected.Group("/pcgs")
+		pcgs.Use(middleware.RequireScope(authscopes.ScopeCoinsRead))
+		{
+			pcgs.GET("/price", handlers.GetPCGSPrice)
+			pcgs.GET("/images", handlers.GetPCGSImages)
+		}
+
+		catalog := protected.Group("/catalog")
+		catalog.Use(middleware.RequireScope(authscopes.ScopeCoinsRead))
+		{
+			catalog.GET("/suggest", handlers.SuggestCoinTypes)
+		}
+
+		metals := protected.Group("/metals")
+		metals.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite, "/melt-value"))
+		{
+			metals.GET("/spot-prices", handlers.GetSpotPrices)
+			metals.GET("/indicators", handlers.GetMarketIndicators)
+			metals.GET("/compositions", handlers.GetMetalCompositions)
+			metals.GET("/composition", handlers.GetCoinComposition)
+			metals.GET("/resolve", handlers.ResolveCoinType)
+			metals.POST("/melt-value", handlers.CalculateMeltValue)
+			metals.POST("/backfill-composition", handlers.BackfillMetalComposition)
+		}
+
+		lots := protected.Group("/lots")
+		lots.Use(middleware.ScopeByMethod(authscopes.ScopeCoinsRead, authscopes.ScopeCoinsWrite), middleware.Localize())
+		{
+			lots.GET("", handlers.GetLots)
+			lots.POST("", handlers.CreateLot)
+			lots.GET("/:id", handlers.GetLot)
+			lots.PUT("/:id", handlers.UpdateLot)
+			lots.DELETE("/:id", handlers.DeleteLot)
+		}
+
+		reports := protected.Group("/reports")
+		reports.Use(middleware.ScopeByMethod(authscopes.ScopeReportsRead, authscopes.ScopeCoinsWrite), middleware.Localize())
+		{
+			reports.GET("/stale-values", handlers.GetStaleValuesReport)
+			reports.POST("/stale-values/refresh", handlers.RefreshStaleValues)
+			reports.GET("/scheduled-items", handlers.GetScheduledItemsReport)
+			reports.GET("/realized-gains", handlers.GetRealizedGainsReport)
+			reports.GET("/currency-hedging", handlers.GetCurrencyHedgingReport)
+		}
+
+		priceHistory := protected.Group("/price-history")
+		priceHistory.Use(middleware.RequireScope(authscopes.ScopeCoinsWrite))
+		{
+			priceHistory.POST("/backfill", handlers.BackfillPriceHistory)
+		}
+
+		admin := protected.Group("/admin")
+		admin.Use(middleware.IPAllowlist("ADMIN_IP_ALLOWLIST"), middleware.RequireScope(authscopes.ScopeAdmin), middleware.AdminRequired())
+		{
+			admin.GET("/instance-stats", handlers.GetInstanceStats)
+			admin.GET("/tenants", handlers.ListTenants)
+			admin.POST("/tenants", handlers.CreateTenant)
+			admin.GET("/invites", handlers.ListInvites)
+			admin.POST("/invites", handlers.CreateInvite)
+			admin.DELETE("/invites/:id", handlers.DeleteInvite)
+			admin.GET("/compositions", handlers.ListCompositionOverrides)
+			admin.PUT("/compositions", handlers.SaveCompositionOverride)
+			admin.DELETE("/compositions/:id", handlers.DeleteCompositionOverride)
+			admin.GET("/fallback-prices", handlers.ListFallbackPrices)
+			admin.PUT("/fallback-prices/:metal", handlers.SaveFallbackPrice)
+			admin.DELETE("/fallback-prices/:metal", handlers.DeleteFallbackPrice)
+			admin.GET("/cert-watchlist", handlers.ListCertWatchlist)
+			admin.POST("/cert-watchlist", handlers.CreateCertWatchEntry)
+			admin.DELETE("/cert-watchlist/:id", handlers.DeleteCertWatchEntry)
+			admin.GET("/debug-log", handlers.GetDebugLog)
+			admin.DELETE("/debug-log", handlers.ClearDebugLog)
+			admin.GET("/emergency-access", handlers.ListEmergencyAccessRequests)
+			admin.POST("/emergency-access/:id/approve", handlers.ApproveEmergencyAccess)
+			admin.POST("/emergency-access/:id/reject", handlers.RejectEmergencyAccess)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/seed"
+)
+
+// runSeed implements `api seed`, which generates fake collections for load
+// testing instead of starting the server
+func runSeed(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	opts := seed.Options{}
+	flags.IntVar(&opts.Users, "users", 10, "number of users to generate")
+	flags.IntVar(&opts.CoinsPerUser, "coins", 5000, "coins per user")
+	flags.IntVar(&opts.PortfoliosPerUser, "portfolios", 3, "portfolios per user")
+	flags.IntVar(&opts.SnapshotsPerCoin, "history", 0, "monthly price history entries per coin")
+	flags.Int64Var(&opts.RandomSeed, "seed", 1, "random seed; the same seed generates the same collections")
+	flags.BoolVar(&opts.Clean, "clean", false, "remove previously seeded users and their data first")
+	flags.Parse(args)
+
+	start := time.Now()
+	result, err := seed.Run(opts)
+	if err != nil {
+		log.Fatal("Failed to seed database: ", err)
+	}
+	log.Printf("✓ Seeded %d users, %d portfolios, %d coins and %d price snapshots in %s",
+		result.Users, result.Portfolios, result.Coins, result.Snapshots, time.Since(start).Round(time.Millisecond))
+	log.Printf("  Log in as seed-1@%s with password %q", seed.EmailDomain, seed.Password)
+}
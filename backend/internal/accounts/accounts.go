@@ -0,0 +1,216 @@
+// Package accounts acts on a user's whole account at once: exporting
+// everything they own as one JSON archive, and deleting it all when they
+// close their account
+package accounts
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExportVersion is bumped when the archive's layout changes incompatibly
+const ExportVersion = 1
+
+// exportBatchSize is how many rows of a section are read at a time, so
+// years of price history never sit in memory at once
+const exportBatchSize = 1000
+
+// owned are the queries selecting what a user owns, directly or through
+// their portfolios and coins
+type owned struct {
+	portfolios *gorm.DB
+	coins      *gorm.DB
+	archived   *gorm.DB
+}
+
+func ownedBy(db *gorm.DB, userID uuid.UUID) owned {
+	portfolios := db.Model(&models.Portfolio{}).Select("id").Where("user_id = ?", userID)
+	return owned{
+		portfolios: portfolios,
+		coins:      db.Model(&models.Coin{}).Select("id").Where("portfolio_id IN (?)", portfolios),
+		archived:   db.Model(&models.ArchivedCoin{}).Select("id").Where("portfolio_id IN (?)", portfolios),
+	}
+}
+
+// section is one list in the export archive
+type section struct {
+	name  string
+	query func(db *gorm.DB, userID uuid.UUID, o owned) *gorm.DB
+	write func(w *bufio.Writer, query *gorm.DB) error
+}
+
+// rows writes every row of a query as a JSON array, a batch at a time
+func rows[T any](w *bufio.Writer, query *gorm.DB) error {
+	w.WriteByte('[')
+	first := true
+	var batch []T
+	err := query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, row := range batch {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				w.WriteByte(',')
+			}
+			first = false
+			w.Write(data)
+		}
+		return nil
+	}).Error
+	w.WriteByte(']')
+	return err
+}
+
+func byUser(db *gorm.DB, userID uuid.UUID, _ owned) *gorm.DB {
+	return db.Where("user_id = ?", userID)
+}
+
+func ofCoins(db *gorm.DB, _ uuid.UUID, o owned) *gorm.DB {
+	return db.Where("coin_id IN (?) OR coin_id IN (?)", o.coins, o.archived)
+}
+
+var sections = []section{
+	{"portfolios", byUser, rows[models.Portfolio]},
+	{"portfolio_members", func(db *gorm.DB, userID uuid.UUID, o owned) *gorm.DB {
+		return db.Where("user_id = ? OR portfolio_id IN (?)", userID, o.portfolios)
+	}, rows[models.PortfolioMember]},
+	{"coins", func(db *gorm.DB, _ uuid.UUID, o owned) *gorm.DB {
+		return db.Where("portfolio_id IN (?)", o.portfolios)
+	}, rows[models.Coin]},
+	{"archived_coins", func(db *gorm.DB, _ uuid.UUID, o owned) *gorm.DB {
+		return db.Where("portfolio_id IN (?)", o.portfolios)
+	}, rows[models.ArchivedCoin]},
+	{"price_history", ofCoins, rows[models.PriceHistory]},
+	{"coin_images", ofCoins, rows[models.CoinImage]},
+	{"lots", byUser, rows[models.Lot]},
+	{"portfolio_alerts", byUser, rows[models.PortfolioAlert]},
+	{"coin_alerts", byUser, rows[models.CoinAlert]},
+	{"spot_alerts", byUser, rows[models.SpotAlert]},
+	{"registry_sets", byUser, rows[models.RegistrySet]},
+	{"transfers", func(db *gorm.DB, userID uuid.UUID, _ owned) *gorm.DB {
+		return db.Where("from_user_id = ? OR to_user_id = ? OR initiated_by_id = ?", userID, userID, userID)
+	}, rows[models.CoinTransfer]},
+	{"emergency_contacts", func(db *gorm.DB, userID uuid.UUID, _ owned) *gorm.DB {
+		return db.Where("user_id = ? OR contact_user_id = ?", userID, userID)
+	}, rows[models.EmergencyContact]},
+	{"notifications", byUser, rows[models.Notification]},
+	{"notification_settings", byUser, rows[models.NotificationSettings]},
+	{"settings", byUser, rows[models.UserSettings]},
+	{"jobs", byUser, rows[models.Job]},
+	{"oauth_identities", byUser, rows[models.OAuthIdentity]},
+	{"api_keys", byUser, rows[models.APIKey]},
+	{"sessions", byUser, rows[models.Session]},
+	{"audit_log", byUser, rows[models.AuditLog]},
+}
+
+// Export writes everything the user owns to w as one JSON object: the
+// account itself and a list per kind of record. Secrets such as password
+// and key hashes are left out, as they are from every API response. Once
+// writing has started an error can only cut the archive short, so callers
+// streaming it should treat invalid JSON as a failed export.
+func Export(userID uuid.UUID, w io.Writer) error {
+	db := database.GetReadDB()
+
+	var user models.User
+	if err := db.First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+	header, err := json.Marshal(struct {
+		Version    int         `json:"format_version"`
+		ExportedAt time.Time   `json:"exported_at"`
+		User       models.User `json:"user"`
+	}{ExportVersion, time.Now(), user})
+	if err != nil {
+		return err
+	}
+
+	buf := bufio.NewWriter(w)
+	buf.Write(header[:len(header)-1]) // left open for the sections
+	o := ownedBy(db, userID)
+	for _, s := range sections {
+		fmt.Fprintf(buf, ",%q:", s.name)
+		if err := s.write(buf, s.query(db, userID, o)); err != nil {
+			buf.Flush()
+			return fmt.Errorf("exporting %s: %w", s.name, err)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Flush()
+}
+
+// Delete closes a user's account: their portfolios, coins (held and
+// archived) with their price history and images, and every other record
+// of theirs go in one transaction, then their stored files. Transfers,
+// emergency access and portfolio memberships they were party to go too, on
+// both sides.
+func Delete(userID uuid.UUID) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Trashed portfolios and coins go too
+		tx = tx.Unscoped().Session(&gorm.Session{})
+		o := ownedBy(tx, userID)
+
+		steps := []struct {
+			model any
+			where string
+			args  []any
+		}{
+			// Coins and their records first, while the portfolios still
+			// select them
+			{&models.PriceHistory{}, "coin_id IN (?) OR coin_id IN (?)", []any{o.coins, o.archived}},
+			{&models.CoinImage{}, "coin_id IN (?) OR coin_id IN (?)", []any{o.coins, o.archived}},
+			{&models.CoinAlert{}, "user_id = ?", []any{userID}},
+			{&models.CoinTransfer{}, "from_user_id = ? OR to_user_id = ? OR initiated_by_id = ?", []any{userID, userID, userID}},
+			{&models.Coin{}, "portfolio_id IN (?)", []any{o.portfolios}},
+			{&models.ArchivedCoin{}, "portfolio_id IN (?)", []any{o.portfolios}},
+			{&models.PortfolioAlert{}, "user_id = ?", []any{userID}},
+			{&models.RegistrySet{}, "user_id = ?", []any{userID}},
+			{&models.PortfolioMember{}, "user_id = ? OR portfolio_id IN (?)", []any{userID, o.portfolios}},
+			{&models.Portfolio{}, "user_id = ?", []any{userID}},
+			{&models.Lot{}, "user_id = ?", []any{userID}},
+			{&models.SpotAlert{}, "user_id = ?", []any{userID}},
+			{&models.Notification{}, "user_id = ?", []any{userID}},
+			{&models.NotificationSettings{}, "user_id = ?", []any{userID}},
+			{&models.UserSettings{}, "user_id = ?", []any{userID}},
+			{&models.Job{}, "user_id = ?", []any{userID}},
+			{&models.EmergencyContact{}, "user_id = ? OR contact_user_id = ?", []any{userID, userID}},
+			{&models.APIKey{}, "user_id = ?", []any{userID}},
+			{&models.RefreshToken{}, "user_id = ?", []any{userID}},
+			{&models.Session{}, "user_id = ?", []any{userID}},
+			{&models.AuditLog{}, "user_id = ?", []any{userID}},
+			{&models.OAuthIdentity{}, "user_id = ?", []any{userID}},
+			{&models.OAuthLoginCode{}, "user_id = ?", []any{userID}},
+			{&models.EmailChangeRequest{}, "user_id = ?", []any{userID}},
+			{&models.PasswordResetToken{}, "user_id = ?", []any{userID}},
+			{&models.InviteCode{}, "created_by = ?", []any{userID}},
+		}
+		for _, step := range steps {
+			if err := tx.Where(step.where, step.args...).Delete(step.model).Error; err != nil {
+				return err
+			}
+		}
+		// Watchlist entries the user reported stay for everyone else
+		if err := tx.Model(&models.CertWatchEntry{}).Where("created_by = ?", userID).Update("created_by", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, "id = ?", userID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := storage.NewLocalStorage().DeleteUser(userID); err != nil {
+		log.Printf("Failed to remove stored files of deleted user %s: %v", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package alerts
+
+import (
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+const (
+	ConditionAbove = "above"
+	ConditionBelow = "below"
+)
+
+// ValidCondition reports whether condition is a supported alert condition
+func ValidCondition(condition string) bool {
+	return condition == ConditionAbove || condition == ConditionBelow
+}
+
+// conditionMet reports whether value satisfies the alert's threshold
+func conditionMet(alert models.PortfolioAlert, value float64) bool {
+	switch alert.Condition {
+	case ConditionAbove:
+		return value >= alert.Threshold
+	case ConditionBelow:
+		return value <= alert.Threshold
+	}
+	return false
+}
+
+// EvaluatePortfolioAlerts checks every enabled alert against the portfolio's
+// current melt value. An alert fires once when its condition starts holding
+// and re-arms when the value moves back across the threshold.
+func EvaluatePortfolioAlerts() error {
+	db := database.GetDB()
+
+	// Portfolios in the trash would read as empty until they're restored
+	var portfolioAlerts []models.PortfolioAlert
+	if err := db.Where("enabled = ? AND portfolio_id IN (?)", true, db.Model(&models.Portfolio{}).Select("id")).
+		Find(&portfolioAlerts).Error; err != nil {
+		return err
+	}
+
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		return err
+	}
+
+	// Several alerts often share a portfolio, so only total each one once
+	values := make(map[uuid.UUID]float64)
+	now := time.Now()
+	fired := 0
+
+	for _, alert := range portfolioAlerts {
+		value, ok := values[alert.PortfolioID]
+		if !ok {
+			var err error
+			value, err = valuation.PortfolioMeltValue(alert.PortfolioID, calc)
+			if err != nil {
+				log.Printf("Alert %s: failed to compute melt value: %v", alert.ID, err)
+				continue
+			}
+			values[alert.PortfolioID] = value
+		}
+
+		met := conditionMet(alert, value)
+		if met && !alert.Triggered {
+			alert.LastTriggeredAt = &now
+			fired++
+			log.Printf("🔔 Alert %s: portfolio %s melt value $%.2f is %s $%.2f",
+				alert.ID, alert.PortfolioID, value, alert.Condition, alert.Threshold)
+			events.Publish(events.AlertFired{
+				UserID:      alert.UserID,
+				PortfolioID: alert.PortfolioID,
+				AlertID:     alert.ID,
+				Condition:   alert.Condition,
+				Threshold:   alert.Threshold,
+				Value:       value,
+				Channels:    alert.Channels,
+			})
+		}
+
+		alert.Triggered = met
+		alert.LastValue = value
+		alert.LastEvaluatedAt = &now
+
+		if err := db.Save(&alert).Error; err != nil {
+			log.Printf("Alert %s: failed to save evaluation: %v", alert.ID, err)
+		}
+	}
+
+	if fired > 0 {
+		log.Printf("Evaluated %d portfolio alerts, %d fired", len(portfolioAlerts), fired)
+	}
+	return nil
+}
+
+// Subscribe evaluates portfolio, coin and spot alerts whenever spot prices
+// are refreshed, and a coin's alerts whenever it's revalued, and removes
+// alerts for deleted portfolios and coins
+func Subscribe() {
+	events.Subscribe(events.TypeSpotPricesRefreshed, func(e events.Event) {
+		if err := EvaluatePortfolioAlerts(); err != nil {
+			log.Printf("Failed to evaluate portfolio alerts: %v", err)
+		}
+		if err := EvaluateCoinAlerts(); err != nil {
+			log.Printf("Failed to evaluate coin alerts: %v", err)
+		}
+		evaluateSpotRefresh(e.(events.SpotPricesRefreshed))
+	})
+
+	events.Subscribe(events.TypeCoinValued, func(e events.Event) {
+		evaluateCoinValued(e.(events.CoinValued))
+	})
+
+	events.Subscribe(events.TypeCoinDeleted, func(e events.Event) {
+		deleted := e.(events.CoinDeleted)
+		if err := database.GetDB().Where("coin_id = ?", deleted.Coin.ID).Delete(&models.CoinAlert{}).Error; err != nil {
+			log.Printf("Failed to remove alerts for coin %s: %v", deleted.Coin.ID, err)
+		}
+	})
+
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		// Alerts on a deleted portfolio would otherwise keep firing against a $0 total
+		if err := database.GetDB().Where("portfolio_id = ?", updated.PortfolioID).Delete(&models.PortfolioAlert{}).Error; err != nil {
+			log.Printf("Failed to remove alerts for portfolio %s: %v", updated.PortfolioID, err)
+		}
+		// The portfolio's coins went with it, so their alerts are the user's
+		// coin alerts on coins that no longer exist, held, trashed or archived
+		db := database.GetDB()
+		if err := db.Where("user_id = ? AND coin_id NOT IN (?) AND coin_id NOT IN (?)", updated.UserID,
+			db.Unscoped().Model(&models.Coin{}).Select("id"), db.Model(&models.ArchivedCoin{}).Select("id")).
+			Delete(&models.CoinAlert{}).Error; err != nil {
+			log.Printf("Failed to remove coin alerts for portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+}
@@ -0,0 +1,201 @@
+package alerts
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+// ConditionPCGSChange fires a coin alert whenever the coin's PCGS guide
+// value changes, in either direction
+const ConditionPCGSChange = "pcgs_change"
+
+// Values a coin alert's threshold can watch, per coin
+const (
+	MetricMeltValue    = "melt_value"
+	MetricCurrentValue = "current_value"
+)
+
+// pcgsSources are the CoinValued sources whose numismatic value comes from
+// the PCGS guide; other changes, like a manual edit, don't fire pcgs_change
+var pcgsSources = []string{"pcgs", "revalue", "refresh"}
+
+// ValidCoinCondition reports whether condition is a supported coin alert condition
+func ValidCoinCondition(condition string) bool {
+	return ValidCondition(condition) || condition == ConditionPCGSChange
+}
+
+// ValidCoinMetric reports whether metric is a supported coin alert metric
+func ValidCoinMetric(metric string) bool {
+	return metric == MetricMeltValue || metric == MetricCurrentValue
+}
+
+// coinConditionMet reports whether a threshold coin alert's condition holds
+func coinConditionMet(alert models.CoinAlert, value float64) bool {
+	switch alert.Condition {
+	case ConditionAbove:
+		return value >= alert.Threshold
+	case ConditionBelow:
+		return value <= alert.Threshold
+	}
+	return false
+}
+
+// coinMetricValue returns the value a threshold alert watches. Melt values
+// are recomputed at calc's prices, and the current value follows them on
+// the portfolio's basis, as it does when the coin is fetched.
+func coinMetricValue(alert models.CoinAlert, coin models.Coin, basis string, calc *metals.Calculator) float64 {
+	if calc != nil {
+		if meltValue := valuation.CoinMeltValue(coin, calc); meltValue > 0 {
+			valuation.ApplyMeltValue(&coin, meltValue, basis)
+		}
+	}
+	if alert.Metric == MetricCurrentValue {
+		return coin.CurrentValue
+	}
+	return coin.MeltValue
+}
+
+// watchedCoinAlerts loads the enabled alerts of watched coins, with those
+// coins and their portfolios' bases. where narrows the alerts.
+func watchedCoinAlerts(where string, args ...interface{}) ([]models.CoinAlert, map[uuid.UUID]models.Coin, map[uuid.UUID]string, error) {
+	db := database.GetDB()
+
+	var coinAlerts []models.CoinAlert
+	if err := db.Where("enabled = ?", true).
+		Where("coin_id IN (?)", db.Model(&models.Coin{}).Select("id").Where("watched = ?", true)).
+		Where(where, args...).
+		Find(&coinAlerts).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	if len(coinAlerts) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	coinIDs := make([]uuid.UUID, 0, len(coinAlerts))
+	for _, alert := range coinAlerts {
+		coinIDs = append(coinIDs, alert.CoinID)
+	}
+	var coins []models.Coin
+	if err := db.Where("id IN ?", coinIDs).Find(&coins).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	byID := make(map[uuid.UUID]models.Coin, len(coins))
+	portfolioIDs := make([]uuid.UUID, 0, len(coins))
+	for _, coin := range coins {
+		byID[coin.ID] = coin
+		portfolioIDs = append(portfolioIDs, coin.PortfolioID)
+	}
+	bases, err := valuation.Bases(portfolioIDs)
+	return coinAlerts, byID, bases, err
+}
+
+// fireCoinAlert records that a coin alert fired and publishes it
+func fireCoinAlert(alert *models.CoinAlert, coin models.Coin, value, previous float64, now time.Time) {
+	alert.LastTriggeredAt = &now
+	log.Printf("🔔 Coin alert %s: coin %s %s %.2f %s %.2f", alert.ID, coin.ID, alert.Metric, value, alert.Condition, alert.Threshold)
+	events.Publish(events.CoinAlertFired{
+		UserID:    alert.UserID,
+		AlertID:   alert.ID,
+		Coin:      coin,
+		Metric:    alert.Metric,
+		Condition: alert.Condition,
+		Threshold: alert.Threshold,
+		Value:     value,
+		Previous:  previous,
+		Channels:  alert.Channels,
+	})
+}
+
+// EvaluateCoinAlerts checks the threshold alerts of every watched coin at
+// current spot prices. Like portfolio alerts, one fires once when its
+// condition starts holding and re-arms when it stops.
+func EvaluateCoinAlerts() error {
+	coinAlerts, coins, bases, err := watchedCoinAlerts("condition IN ?", []string{ConditionAbove, ConditionBelow})
+	if err != nil || len(coinAlerts) == 0 {
+		return err
+	}
+
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fired := 0
+	for _, alert := range coinAlerts {
+		coin, ok := coins[alert.CoinID]
+		if !ok {
+			continue
+		}
+		if evaluateCoinThreshold(&alert, coin, bases[coin.PortfolioID], calc, now) {
+			fired++
+		}
+	}
+
+	if fired > 0 {
+		log.Printf("Evaluated %d coin alerts, %d fired", len(coinAlerts), fired)
+	}
+	return nil
+}
+
+// evaluateCoinThreshold evaluates and saves one threshold alert, reporting
+// whether it fired
+func evaluateCoinThreshold(alert *models.CoinAlert, coin models.Coin, basis string, calc *metals.Calculator, now time.Time) bool {
+	value := coinMetricValue(*alert, coin, basis, calc)
+	met := coinConditionMet(*alert, value)
+	fired := met && !alert.Triggered
+	if fired {
+		fireCoinAlert(alert, coin, value, alert.LastValue, now)
+	}
+
+	alert.Triggered = met
+	alert.LastValue = value
+	alert.LastEvaluatedAt = &now
+	if err := database.GetDB().Save(alert).Error; err != nil {
+		log.Printf("Coin alert %s: failed to save evaluation: %v", alert.ID, err)
+	}
+	return fired
+}
+
+// evaluateCoinValued evaluates a revalued coin's alerts: its threshold
+// alerts against the new values, and its pcgs_change alerts when the PCGS
+// guide value moved
+func evaluateCoinValued(valued events.CoinValued) {
+	coinAlerts, coins, bases, err := watchedCoinAlerts("coin_id = ?", valued.CoinID)
+	if err != nil {
+		log.Printf("Failed to evaluate alerts of coin %s: %v", valued.CoinID, err)
+		return
+	}
+	coin, ok := coins[valued.CoinID]
+	if !ok {
+		return
+	}
+
+	pcgsChanged := slices.Contains(pcgsSources, valued.Source) && valued.NewNumismaticValue != valued.OldNumismaticValue
+	now := time.Now()
+	for _, alert := range coinAlerts {
+		if alert.Condition != ConditionPCGSChange {
+			// The stored values were just updated, so they're used as is
+			evaluateCoinThreshold(&alert, coin, bases[coin.PortfolioID], nil, now)
+			continue
+		}
+		if !pcgsChanged {
+			continue
+		}
+		fireCoinAlert(&alert, coin, valued.NewNumismaticValue, valued.OldNumismaticValue, now)
+		alert.LastValue = valued.NewNumismaticValue
+		alert.LastEvaluatedAt = &now
+		if err := database.GetDB().Save(&alert).Error; err != nil {
+			log.Printf("Coin alert %s: failed to save evaluation: %v", alert.ID, err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestCoinConditionMet(t *testing.T) {
+	tests := []struct {
+		condition string
+		threshold float64
+		value     float64
+		want      bool
+	}{
+		{ConditionBelow, 30, 29.5, true},
+		{ConditionBelow, 30, 30, true},
+		{ConditionBelow, 30, 31, false},
+		{ConditionAbove, 1500, 1600, true},
+		{ConditionAbove, 1500, 1400, false},
+		{ConditionPCGSChange, 0, 1400, false},
+	}
+
+	for _, tt := range tests {
+		alert := models.CoinAlert{Condition: tt.condition, Threshold: tt.threshold}
+		if got := coinConditionMet(alert, tt.value); got != tt.want {
+			t.Errorf("%s %.1f at value %.1f = %v, want %v", tt.condition, tt.threshold, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCoinMetricValueUsesStoredValuesWithoutPrices(t *testing.T) {
+	coin := models.Coin{MeltValue: 28, NumismaticValue: 120, CurrentValue: 120}
+
+	if got := coinMetricValue(models.CoinAlert{Metric: MetricMeltValue}, coin, "max", nil); got != 28 {
+		t.Errorf("melt_value = %.2f, want the stored 28", got)
+	}
+	if got := coinMetricValue(models.CoinAlert{Metric: MetricCurrentValue}, coin, "max", nil); got != 120 {
+		t.Errorf("current_value = %.2f, want the stored 120", got)
+	}
+}
+
+func TestValidCoinCondition(t *testing.T) {
+	for condition, want := range map[string]bool{
+		ConditionAbove:      true,
+		ConditionBelow:      true,
+		ConditionPCGSChange: true,
+		ConditionRises:      false,
+		"":                  false,
+	} {
+		if got := ValidCoinCondition(condition); got != want {
+			t.Errorf("ValidCoinCondition(%q) = %v, want %v", condition, got, want)
+		}
+	}
+}
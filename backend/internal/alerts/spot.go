@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/spothistory"
+)
+
+// Spot alert metrics besides the metals themselves
+const (
+	MetricGoldSilverRatio   = spothistory.MetricGoldSilverRatio
+	MetricPlatinumGoldRatio = spothistory.MetricPlatinumGoldRatio
+)
+
+// Spot alert conditions measured as a percent change over the last day.
+// Above and below compare the value itself.
+const (
+	ConditionRises = "rises"
+	ConditionFalls = "falls"
+	ConditionMoves = "moves" // either direction
+)
+
+const spotChangeWindow = 24 * time.Hour
+
+var spotMetrics = []string{"gold", "silver", "platinum", "palladium", MetricGoldSilverRatio, MetricPlatinumGoldRatio}
+
+// SpotMetrics lists the values a spot alert can watch
+func SpotMetrics() []string {
+	return spotMetrics
+}
+
+// ValidSpotMetric reports whether metric is a supported spot alert metric
+func ValidSpotMetric(metric string) bool {
+	for _, m := range spotMetrics {
+		if m == metric {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidSpotCondition reports whether condition is a supported spot alert condition
+func ValidSpotCondition(condition string) bool {
+	switch condition {
+	case ConditionAbove, ConditionBelow, ConditionRises, ConditionFalls, ConditionMoves:
+		return true
+	}
+	return false
+}
+
+// IsPercentCondition reports whether a spot condition's threshold is a percentage
+func IsPercentCondition(condition string) bool {
+	return condition == ConditionRises || condition == ConditionFalls || condition == ConditionMoves
+}
+
+// spotConditionMet reports whether a spot alert's condition holds. change is
+// the percent change over the last day; percentage conditions never hold
+// without one.
+func spotConditionMet(alert models.SpotAlert, value float64, change *float64) bool {
+	switch alert.Condition {
+	case ConditionAbove:
+		return value >= alert.Threshold
+	case ConditionBelow:
+		return value <= alert.Threshold
+	}
+	if change == nil {
+		return false
+	}
+	switch alert.Condition {
+	case ConditionRises:
+		return *change >= alert.Threshold
+	case ConditionFalls:
+		return *change <= -alert.Threshold
+	case ConditionMoves:
+		return *change >= alert.Threshold || *change <= -alert.Threshold
+	}
+	return false
+}
+
+// spotBaseline returns the prices a day before at. Until a day of history
+// has been recorded, the oldest refresh is used.
+func spotBaseline(at time.Time) (map[string]float64, bool) {
+	record, err := spothistory.At(at.Add(-spotChangeWindow))
+	if err != nil {
+		record, err = spothistory.Oldest(at)
+	}
+	if err != nil {
+		return nil, false
+	}
+	return spothistory.Prices(record), true
+}
+
+// EvaluateSpotAlerts checks every enabled spot alert against freshly
+// refreshed prices. Like portfolio alerts, a spot alert fires once when its
+// condition starts holding and re-arms when it stops.
+func EvaluateSpotAlerts(prices map[string]float64, at time.Time) error {
+	db := database.GetDB()
+
+	var spotAlerts []models.SpotAlert
+	if err := db.Where("enabled = ?", true).Find(&spotAlerts).Error; err != nil {
+		return err
+	}
+	if len(spotAlerts) == 0 {
+		return nil
+	}
+
+	baseline, hasBaseline := spotBaseline(at)
+	now := time.Now()
+	fired := 0
+
+	for _, alert := range spotAlerts {
+		value, ok := spothistory.Value(alert.Metric, prices)
+		if !ok {
+			continue
+		}
+
+		var change *float64
+		if hasBaseline {
+			if pct, ok := spothistory.Change(alert.Metric, baseline, prices); ok {
+				change = &pct
+			}
+		}
+
+		met := spotConditionMet(alert, value, change)
+		if met && !alert.Triggered {
+			alert.LastTriggeredAt = &now
+			fired++
+			event := events.SpotAlertFired{
+				UserID:    alert.UserID,
+				AlertID:   alert.ID,
+				Metric:    alert.Metric,
+				Condition: alert.Condition,
+				Threshold: alert.Threshold,
+				Value:     value,
+				Channels:  alert.Channels,
+			}
+			if change != nil {
+				event.Change = *change
+			}
+			log.Printf("🔔 Spot alert %s: %s at %.2f %s %.2f", alert.ID, alert.Metric, value, alert.Condition, alert.Threshold)
+			events.Publish(event)
+		}
+
+		alert.Triggered = met
+		alert.LastValue = value
+		alert.LastChange = 0
+		if change != nil {
+			alert.LastChange = *change
+		}
+		alert.LastEvaluatedAt = &now
+
+		if err := db.Save(&alert).Error; err != nil {
+			log.Printf("Spot alert %s: failed to save evaluation: %v", alert.ID, err)
+		}
+	}
+
+	if fired > 0 {
+		log.Printf("Evaluated %d spot alerts, %d fired", len(spotAlerts), fired)
+	}
+	return nil
+}
+
+// evaluateSpotRefresh evaluates spot alerts against a live refresh. Fallback
+// prices are hard-coded, so they're ignored.
+func evaluateSpotRefresh(refreshed events.SpotPricesRefreshed) {
+	if refreshed.Fallback {
+		return
+	}
+	at := refreshed.RefreshedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	if err := EvaluateSpotAlerts(refreshed.Prices, at); err != nil {
+		log.Printf("Failed to evaluate spot alerts: %v", err)
+	}
+}
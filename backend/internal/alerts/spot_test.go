@@ -0,0 +1,36 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestSpotConditionMet(t *testing.T) {
+	pct := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		condition string
+		threshold float64
+		value     float64
+		change    *float64
+		want      bool
+	}{
+		{ConditionAbove, 40, 40.5, nil, true},
+		{ConditionAbove, 40, 39.9, pct(10), false},
+		{ConditionBelow, 80, 79, nil, true},
+		{ConditionRises, 3, 2700, pct(3.2), true},
+		{ConditionRises, 3, 2700, pct(-3.2), false},
+		{ConditionFalls, 3, 2500, pct(-3.2), true},
+		{ConditionMoves, 3, 2500, pct(-3.2), true},
+		{ConditionMoves, 3, 2600, pct(2.9), false},
+		{ConditionMoves, 3, 2600, nil, false},
+	}
+
+	for _, tt := range tests {
+		alert := models.SpotAlert{Condition: tt.condition, Threshold: tt.threshold}
+		if got := spotConditionMet(alert, tt.value, tt.change); got != tt.want {
+			t.Errorf("%s %.1f at value %.1f = %v, want %v", tt.condition, tt.threshold, tt.value, got, tt.want)
+		}
+	}
+}
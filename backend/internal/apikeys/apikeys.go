@@ -0,0 +1,126 @@
+// Package apikeys issues and checks the long-lived keys scripts use to call
+// the API with an X-API-Key header, e.g. to automate imports without
+// juggling access and refresh tokens. A key acts for the user who created it,
+// limited to the scopes it was given, until it expires or is revoked.
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Header is the request header a key is sent in
+const Header = "X-API-Key"
+
+// keyPrefix starts every key, so leaked keys are easy to spot in code and logs
+const keyPrefix = "aur_"
+
+// shownPrefixLength is how much of a key is kept in the clear to tell keys apart
+const shownPrefixLength = len(keyPrefix) + 8
+
+// lastUsedPrecision is how stale a key's last_used_at may get, so busy
+// scripts don't write to the database on every request
+const lastUsedPrecision = time.Minute
+
+// ErrInvalidKey is returned for a key that is unknown or expired
+var ErrInvalidKey = errors.New("invalid or expired API key")
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(buf), nil
+}
+
+// Create stores a new key for userID and returns it with its plaintext,
+// which is only ever shown to the user this once. A zero ttl never expires.
+func Create(userID uuid.UUID, name string, scopes []string, ttl time.Duration) (models.APIKey, string, error) {
+	return create(models.APIKey{UserID: userID, Name: name, Scopes: scopes}, ttl)
+}
+
+// CreateDisplay stores a display token for userID: a key that can only read
+// the total value of portfolioID and spot prices
+func CreateDisplay(userID uuid.UUID, name string, portfolioID uuid.UUID, ttl time.Duration) (models.APIKey, string, error) {
+	return create(models.APIKey{UserID: userID, Name: name, Scopes: []string{auth.ScopeDisplay}, PortfolioID: &portfolioID}, ttl)
+}
+
+func create(key models.APIKey, ttl time.Duration) (models.APIKey, string, error) {
+	plain, err := newKey()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key.Prefix = plain[:shownPrefixLength]
+	key.KeyHash = hashKey(plain)
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	if err := database.GetDB().Create(&key).Error; err != nil {
+		return models.APIKey{}, "", err
+	}
+	return key, plain, nil
+}
+
+// Authenticate returns the key with plaintext plain and the user it acts
+// for, and records that it was used
+func Authenticate(plain string) (models.APIKey, models.User, error) {
+	var key models.APIKey
+	var user models.User
+
+	plain = strings.TrimSpace(plain)
+	if !strings.HasPrefix(plain, keyPrefix) {
+		return key, user, ErrInvalidKey
+	}
+	db := database.GetDB()
+	err := db.Where("key_hash = ?", hashKey(plain)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, user, ErrInvalidKey
+	}
+	if err != nil {
+		return key, user, err
+	}
+	now := time.Now()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return key, user, ErrInvalidKey
+	}
+	if err := db.First(&user, "id = ?", key.UserID).Error; err != nil {
+		return key, user, err
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedPrecision {
+		key.LastUsedAt = &now
+		db.Model(&key).UpdateColumn("last_used_at", now)
+	}
+	return key, user, nil
+}
+
+// Subscribe revokes the display tokens of deleted portfolios
+func Subscribe() {
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Where("portfolio_id = ?", updated.PortfolioID).Delete(&models.APIKey{}).Error; err != nil {
+			log.Printf("Failed to revoke display tokens of portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+}
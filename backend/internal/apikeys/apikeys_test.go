@@ -0,0 +1,33 @@
+package apikeys
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewKey(t *testing.T) {
+	a, err := newKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := newKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(a, keyPrefix) || len(a) != len(keyPrefix)+64 {
+		t.Errorf("newKey() = %q, want %s and 64 hex digits", a, keyPrefix)
+	}
+	if a == b {
+		t.Error("newKey returned the same key twice")
+	}
+	if hashKey(a) != hashKey(a) || hashKey(a) == hashKey(b) {
+		t.Error("hashKey isn't a stable, distinct hash of the key")
+	}
+}
+
+func TestAuthenticateRejectsForeignKeys(t *testing.T) {
+	// Rejected before the database is consulted
+	if _, _, err := Authenticate("Bearer abc"); err != ErrInvalidKey {
+		t.Errorf("Authenticate(no prefix) = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Package archive moves sold and otherwise disposed coins out of the coins
+// table into archived_coins, keeping the table behind every list and stats
+// query down to the coins users still hold. Historical reports read the
+// archive alongside it.
+package archive
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DispositionRegraded is how a coin leaves the collection when it is
+// regraded: it lives on as the replacement holding its new cert
+const DispositionRegraded = "regraded"
+
+// Dispositions are the ways a coin can leave a collection
+var Dispositions = []string{"sold", "gifted", "melted", "lost", DispositionRegraded, "other"}
+
+// realizing are the dispositions that can bring in proceeds, and so count
+// towards realized gains
+var realizing = []string{"sold", "melted"}
+
+// ValidDisposition reports whether d is one of Dispositions
+func ValidDisposition(d string) bool {
+	return slices.Contains(Dispositions, d)
+}
+
+// Disposal describes how and when a coin left the collection
+type Disposal struct {
+	Disposition string
+	DisposedAt  time.Time
+	SalePrice   float64 // total proceeds
+	SaleFees    float64
+	Notes       string
+}
+
+// Archive moves a coin into the archive
+func Archive(coin models.Coin, d Disposal) (models.ArchivedCoin, error) {
+	archived := models.ArchivedCoin{
+		Coin:          coin,
+		Disposition:   d.Disposition,
+		DisposedAt:    d.DisposedAt,
+		SalePrice:     d.SalePrice,
+		SaleFees:      d.SaleFees,
+		DisposalNotes: d.Notes,
+		ArchivedAt:    time.Now(),
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&archived).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Coin{}, "id = ?", coin.ID).Error
+	})
+	return archived, err
+}
+
+// Regrade archives coin as regraded and creates replacement, the same coin
+// under a new cert, in its place. The coin's price history and alerts move
+// to the replacement so its value history carries on across the cert
+// change; its archived cert images stay with the old record.
+func Regrade(coin models.Coin, replacement *models.Coin, at time.Time, notes string) (models.ArchivedCoin, error) {
+	archived := models.ArchivedCoin{
+		Coin:          coin,
+		Disposition:   DispositionRegraded,
+		DisposedAt:    at,
+		DisposalNotes: notes,
+		ArchivedAt:    time.Now(),
+	}
+	replacement.UpgradedFromID = &coin.ID
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(&models.Coin{}, "id = ?", coin.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		archived.ReplacedByID = &replacement.ID
+		if err := tx.Create(&archived).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PriceHistory{}).Where("coin_id = ?", coin.ID).Update("coin_id", replacement.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.CoinAlert{}).Where("coin_id = ?", coin.ID).Update("coin_id", replacement.ID).Error
+	})
+	return archived, err
+}
+
+// Predecessors returns the archived records a coin was regraded from,
+// most recent first
+func Predecessors(coin models.Coin) ([]models.ArchivedCoin, error) {
+	predecessors := []models.ArchivedCoin{}
+	seen := map[uuid.UUID]bool{coin.ID: true}
+	for next := coin.UpgradedFromID; next != nil && !seen[*next]; {
+		seen[*next] = true
+		var archived models.ArchivedCoin
+		if err := database.GetReadDB().First(&archived, "id = ?", *next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				break
+			}
+			return nil, err
+		}
+		predecessors = append(predecessors, archived)
+		next = archived.UpgradedFromID
+	}
+	return predecessors, nil
+}
+
+// Restore moves an archived coin back into its portfolio, undoing its
+// disposal
+func Restore(archived models.ArchivedCoin) (models.Coin, error) {
+	coin := archived.Coin
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&coin).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.ArchivedCoin{}, "id = ?", archived.ID).Error
+	})
+	return coin, err
+}
+
+// HeldDuring returns a portfolio's archived coins that were still held at
+// some point between from and to, for reports over that period
+func HeldDuring(portfolioID uuid.UUID, from, to time.Time) ([]models.ArchivedCoin, error) {
+	var archived []models.ArchivedCoin
+	err := database.GetReadDB().
+		Where("portfolio_id = ? AND created_at < ? AND disposed_at >= ?", portfolioID, to, from).
+		Find(&archived).Error
+	return archived, err
+}
+
+// RealizedGain is the gain or loss on a coin that was sold or melted
+type RealizedGain struct {
+	CoinID      uuid.UUID `json:"coin_id"`
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	CoinType    string    `json:"coin_type"`
+	Year        int       `json:"year"`
+	Quantity    int       `json:"quantity"`
+	Disposition string    `json:"disposition"`
+	AcquiredAt  time.Time `json:"acquired_at"`
+	DisposedAt  time.Time `json:"disposed_at"`
+	Proceeds    float64   `json:"proceeds"`   // sale price less selling fees
+	CostBasis   float64   `json:"cost_basis"` // all-in purchase cost
+	Gain        float64   `json:"gain"`
+	LongTerm    bool      `json:"long_term"` // held for more than a year
+}
+
+// Realized returns the realized gain of an archived coin, or false when it
+// wasn't sold or melted
+func Realized(archived models.ArchivedCoin) (RealizedGain, bool) {
+	if !slices.Contains(realizing, archived.Disposition) {
+		return RealizedGain{}, false
+	}
+	acquired := valuation.AcquiredAt(archived.Coin)
+	gain := RealizedGain{
+		CoinID:      archived.ID,
+		PortfolioID: archived.PortfolioID,
+		CoinType:    archived.CoinType,
+		Year:        archived.Year,
+		Quantity:    archived.Quantity,
+		Disposition: archived.Disposition,
+		AcquiredAt:  acquired,
+		DisposedAt:  archived.DisposedAt,
+		Proceeds:    archived.SalePrice - archived.SaleFees,
+		CostBasis:   valuation.AllInCost(archived.Coin),
+		LongTerm:    archived.DisposedAt.After(acquired.AddDate(1, 0, 0)),
+	}
+	gain.Gain = gain.Proceeds - gain.CostBasis
+	return gain, true
+}
+
+// Subscribe removes the archived coins of deleted portfolios
+func Subscribe() {
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Unscoped().Where("portfolio_id = ?", updated.PortfolioID).Delete(&models.ArchivedCoin{}).Error; err != nil {
+			log.Printf("Failed to remove archived coins of portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package archive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestRealized(t *testing.T) {
+	bought := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	coin := models.Coin{PurchasePrice: 40, Quantity: 2, ShippingCost: 6, PurchaseDate: &bought}
+
+	sold := models.ArchivedCoin{Coin: coin, Disposition: "sold", DisposedAt: bought.AddDate(1, 1, 0), SalePrice: 120, SaleFees: 12}
+	gain, ok := Realized(sold)
+	if !ok {
+		t.Fatal("sold coin has no realized gain")
+	}
+	if gain.Proceeds != 108 || gain.CostBasis != 86 || gain.Gain != 22 || !gain.LongTerm {
+		t.Errorf("Realized = %+v, want proceeds 108, cost 86, gain 22, long term", gain)
+	}
+
+	sold.DisposedAt = bought.AddDate(0, 11, 0)
+	if gain, _ := Realized(sold); gain.LongTerm {
+		t.Error("held under a year but long term")
+	}
+
+	if _, ok := Realized(models.ArchivedCoin{Coin: coin, Disposition: "gifted", DisposedAt: bought.AddDate(2, 0, 0)}); ok {
+		t.Error("gifted coin has a realized gain")
+	}
+}
@@ -0,0 +1,81 @@
+package auctions
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+// Auction houses results are fetched from
+const (
+	SourceHeritage         = "heritage"
+	SourceGreatCollections = "greatcollections"
+)
+
+// Query describes the coin comparables are wanted for. Empty fields match
+// anything.
+type Query struct {
+	CoinType string
+	Year     int
+	MintMark string
+	Grade    string // e.g. "MS65"
+	Limit    int
+}
+
+// Result is one sold lot
+type Result struct {
+	Source   string    `json:"source"`
+	LotID    string    `json:"lot_id"`
+	Title    string    `json:"title"`
+	Year     int       `json:"year"`
+	MintMark string    `json:"mint_mark"`
+	Grade    string    `json:"grade"`
+	Service  string    `json:"service"` // grading service, e.g. "PCGS"
+	Price    float64   `json:"price"`   // hammer price plus buyer's premium, USD
+	SoldAt   time.Time `json:"sold_at"`
+	URL      string    `json:"url"`
+}
+
+// Source fetches sold lots from one auction house
+type Source interface {
+	Name() string
+	Configured() bool
+	Search(q Query) ([]Result, error)
+}
+
+// Sources returns every auction source, configured or not
+func Sources() []Source {
+	return []Source{
+		newFeed(SourceHeritage, "HERITAGE", usage.ServiceHeritage),
+		newFeed(SourceGreatCollections, "GREATCOLLECTIONS", usage.ServiceGreatCollections),
+	}
+}
+
+// Search queries every configured source. A failing source is logged and
+// skipped so the others still return comparables. Results are newest first.
+func Search(q Query) (results []Result, searched []string) {
+	for _, source := range Sources() {
+		if !source.Configured() {
+			continue
+		}
+		found, err := source.Search(q)
+		if err != nil {
+			log.Printf("Auction search on %s failed: %v", source.Name(), err)
+			continue
+		}
+		searched = append(searched, source.Name())
+		results = append(results, found...)
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].SoldAt.After(results[j].SoldAt) })
+	return results, searched
+}
+
+// NormalizeGrade uppercases a grade and drops spaces and dashes, so "MS-65"
+// and "ms 65" both become "MS65"
+func NormalizeGrade(grade string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.ToUpper(strings.TrimSpace(grade)))
+}
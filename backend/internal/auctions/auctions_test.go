@@ -0,0 +1,28 @@
+package auctions
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestNormalizeGrade(t *testing.T) {
+	for in, want := range map[string]string{"MS-65": "MS65", " ms 65 ": "MS65", "PR69DCAM": "PR69DCAM", "": ""} {
+		if got := NormalizeGrade(in); got != want {
+			t.Errorf("NormalizeGrade(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	comps := []models.AuctionComparable{{Price: 192}, {Price: 176}, {Price: 180}, {Price: 61}}
+	got := Summarize(comps)
+	want := Summary{Count: 4, Low: 61, High: 192, Median: 178, Average: 152.25}
+	if got != want {
+		t.Errorf("Summarize = %+v, want %+v", got, want)
+	}
+
+	if got := Summarize(nil); got != (Summary{}) {
+		t.Errorf("Summarize(nil) = %+v, want zero", got)
+	}
+}
@@ -0,0 +1,105 @@
+package auctions
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+const defaultSearchLimit = 25
+
+// feed is a client for an auction house results feed. Neither house has an
+// open API, so each is configured with the URL and key of a licensed feed
+// (<PREFIX>_API_URL, <PREFIX>_API_KEY) that answers
+//
+//	GET <url>?q=<coin type>&year=&mint_mark=&grade=&limit=
+//
+// with {"results": [...]} in the Result JSON shape.
+type feed struct {
+	name    string
+	prefix  string
+	service string
+	client  *http.Client
+}
+
+func newFeed(name, prefix, service string) *feed {
+	client := &http.Client{Timeout: 20 * time.Second}
+	if config.MockMode() {
+		client = &http.Client{Transport: fixtureTransport{source: name}}
+	}
+	return &feed{name: name, prefix: prefix, service: service, client: client}
+}
+
+func (f *feed) Name() string { return f.name }
+
+func (f *feed) baseURL() string {
+	if config.MockMode() {
+		return "https://fixtures.invalid/" + f.name
+	}
+	return config.String(f.prefix+"_API_URL", "")
+}
+
+func (f *feed) Configured() bool {
+	return f.baseURL() != ""
+}
+
+func (f *feed) Search(q Query) ([]Result, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	params := url.Values{"q": {q.CoinType}, "limit": {strconv.Itoa(limit)}}
+	if q.Year > 0 {
+		params.Set("year", strconv.Itoa(q.Year))
+	}
+	if q.MintMark != "" {
+		params.Set("mint_mark", q.MintMark)
+	}
+	if q.Grade != "" {
+		params.Set("grade", q.Grade)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, f.baseURL()+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if key := config.String(f.prefix+"_API_KEY", ""); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		err = fmt.Errorf("%s returned status %d", f.name, resp.StatusCode)
+	}
+	usage.RecordCall(f.service, err)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Results []Result `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid %s response: %w", f.name, err)
+	}
+
+	results := make([]Result, 0, len(body.Results))
+	for _, result := range body.Results {
+		if result.LotID == "" || result.Price <= 0 {
+			continue
+		}
+		result.Source = f.name
+		result.Grade = NormalizeGrade(result.Grade)
+		results = append(results, result)
+	}
+	return results, nil
+}
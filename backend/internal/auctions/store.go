@@ -0,0 +1,124 @@
+package auctions
+
+import (
+	"sort"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Stored comparables are refetched once the newest is this old
+	cacheDuration = 24 * time.Hour
+	// Comparables older than this aren't shown
+	lookback = 365 * 24 * time.Hour
+)
+
+// Summary describes the spread of comparable prices
+type Summary struct {
+	Count   int     `json:"count"`
+	Low     float64 `json:"low"`
+	High    float64 `json:"high"`
+	Median  float64 `json:"median"`
+	Average float64 `json:"average"`
+}
+
+// Comparables returns stored sold lots matching q from the past year,
+// fetching fresh results from the configured sources first when the stored
+// ones are stale or refresh is set
+func Comparables(q Query, refresh bool) ([]models.AuctionComparable, error) {
+	if refresh || stale(q) {
+		if err := fetch(q); err != nil {
+			return nil, err
+		}
+	}
+
+	query := database.GetReadDB().
+		Where("LOWER(coin_type) = LOWER(?) AND mint_mark = ? AND sold_at >= ?", q.CoinType, q.MintMark, time.Now().Add(-lookback))
+	if q.Year > 0 {
+		query = query.Where("year = ?", q.Year)
+	}
+	if q.Grade != "" {
+		query = query.Where("grade = ?", NormalizeGrade(q.Grade))
+	}
+
+	var comparables []models.AuctionComparable
+	err := query.Order("sold_at DESC").Find(&comparables).Error
+	return comparables, err
+}
+
+// stale reports whether comparables for q were last fetched too long ago
+func stale(q Query) bool {
+	var latest models.AuctionComparable
+	query := database.GetReadDB().Where("LOWER(coin_type) = LOWER(?)", q.CoinType)
+	if q.Year > 0 {
+		query = query.Where("year = ?", q.Year)
+	}
+	if err := query.Order("fetched_at DESC").Limit(1).Find(&latest).Error; err != nil || latest.ID == uuid.Nil {
+		return true
+	}
+	return time.Since(latest.FetchedAt) > cacheDuration
+}
+
+// fetch searches every configured source for q's coin type and year and
+// stores what it finds, updating lots already stored
+func fetch(q Query) error {
+	results, _ := Search(Query{CoinType: q.CoinType, Year: q.Year})
+	if len(results) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]models.AuctionComparable, len(results))
+	for i, result := range results {
+		rows[i] = models.AuctionComparable{
+			Source:    result.Source,
+			LotID:     result.LotID,
+			CoinType:  q.CoinType,
+			Title:     result.Title,
+			Year:      result.Year,
+			MintMark:  result.MintMark,
+			Grade:     result.Grade,
+			Service:   result.Service,
+			Price:     result.Price,
+			SoldAt:    result.SoldAt,
+			URL:       result.URL,
+			FetchedAt: now,
+		}
+	}
+
+	return database.GetDB().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "lot_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "grade", "price", "sold_at", "url", "fetched_at"}),
+	}).Create(&rows).Error
+}
+
+// Summarize computes the price spread of comparables
+func Summarize(comparables []models.AuctionComparable) Summary {
+	if len(comparables) == 0 {
+		return Summary{}
+	}
+
+	prices := make([]float64, len(comparables))
+	total := 0.0
+	for i, comparable := range comparables {
+		prices[i] = comparable.Price
+		total += comparable.Price
+	}
+	sort.Float64s(prices)
+
+	median := prices[len(prices)/2]
+	if len(prices)%2 == 0 {
+		median = (prices[len(prices)/2-1] + prices[len(prices)/2]) / 2
+	}
+	return Summary{
+		Count:   len(prices),
+		Low:     prices[0],
+		High:    prices[len(prices)-1],
+		Median:  median,
+		Average: total / float64(len(prices)),
+	}
+}
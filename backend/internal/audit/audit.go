@@ -0,0 +1,74 @@
+// Package audit keeps the record of what was done to each account, for its
+// owner to review: sign-ins, coins and portfolios created, changed or
+// deleted, and PCGS syncs. Entries are written by middleware.Audit on the
+// routes that take these actions.
+package audit
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Actions recorded
+const (
+	ActionLogin            = "login"
+	ActionRegister         = "register"
+	ActionCoinCreated      = "coin.created"
+	ActionCoinUpdated      = "coin.updated"
+	ActionCoinDeleted      = "coin.deleted"
+	ActionCoinsImported    = "coins.imported"
+	ActionPortfolioCreated = "portfolio.created"
+	ActionPortfolioUpdated = "portfolio.updated"
+	ActionPortfolioDeleted = "portfolio.deleted"
+	ActionPCGSSync         = "pcgs.sync"
+)
+
+// Actions lists every action, for filtering by
+var Actions = []string{
+	ActionLogin,
+	ActionRegister,
+	ActionCoinCreated,
+	ActionCoinUpdated,
+	ActionCoinDeleted,
+	ActionCoinsImported,
+	ActionPortfolioCreated,
+	ActionPortfolioUpdated,
+	ActionPortfolioDeleted,
+	ActionPCGSSync,
+}
+
+// Known reports whether action is one of Actions
+func Known(action string) bool {
+	return slices.Contains(Actions, action)
+}
+
+// retention is how long entries are kept (AUDIT_LOG_RETENTION, default
+// 365 days)
+func retention() time.Duration {
+	return config.Duration("AUDIT_LOG_RETENTION", 365*24*time.Hour)
+}
+
+// Record saves an entry. The action has already happened, so a failure is
+// logged rather than returned to the client.
+func Record(entry models.AuditLog) {
+	if err := database.GetDB().Create(&entry).Error; err != nil {
+		log.Printf("Audit: failed to record %s for %s: %v", entry.Action, entry.UserID, err)
+	}
+}
+
+// Purge deletes entries older than the retention
+func Purge(now time.Time) error {
+	result := database.GetDB().Where("created_at < ?", now.Add(-retention())).Delete(&models.AuditLog{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d audit log entries", result.RowsAffected)
+	}
+	return nil
+}
@@ -1,33 +1,82 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/evansminotwood/aureus/internal/config"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var jwtSecret []byte
+// jwtSecret is read on first use so that it can come from a --config file
+var jwtSecret = sync.OnceValue(func() []byte {
+	return []byte(config.String("JWT_SECRET", "dev-secret-key"))
+})
 
-func init() {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "dev-secret-key"
-	}
-	jwtSecret = []byte(secret)
-}
+// stateSecret signs the state of round trips through third parties. It is
+// derived from the JWT secret but differs from it, so a state can never be
+// accepted as an access token.
+var stateSecret = sync.OnceValue(func() []byte {
+	mac := hmac.New(sha256.New, jwtSecret())
+	mac.Write([]byte("aureus state"))
+	return mac.Sum(nil)
+})
 
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	UserID   uuid.UUID  `json:"user_id"`
+	Email    string     `json:"email"`
+	TenantID *uuid.UUID `json:"tenant_id,omitempty"`
+	// Scopes limits what the token can do; none is full access
+	Scopes []string `json:"scopes,omitempty"`
+	// EmergencyAccessID is the emergency contact grant a token was issued
+	// under, for tokens used by someone other than the account's owner
+	EmergencyAccessID *uuid.UUID `json:"emergency_access_id,omitempty"`
+	// TokenVersion is the user's token version when the token was issued;
+	// it stops being accepted once the user logs out everywhere
+	TokenVersion int `json:"ver,omitempty"`
+	// SessionID is the login session an access token was issued in; the
+	// token stops being accepted once the session is revoked
+	SessionID *uuid.UUID `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsAdminEmail reports whether email is listed in ADMIN_EMAILS (comma-separated)
+func IsAdminEmail(email string) bool {
+	for _, admin := range strings.Split(config.String("ADMIN_EMAILS", ""), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration modes (REGISTRATION_MODE)
+const (
+	RegistrationOpen     = "open"     // anyone can register
+	RegistrationInvite   = "invite"   // registering requires an invite code
+	RegistrationDisabled = "disabled" // no new accounts
+)
+
+// RegistrationMode returns the configured registration mode. Unknown values
+// fall back to invite-only rather than leaving the instance open.
+func RegistrationMode() string {
+	switch mode := strings.ToLower(config.String("REGISTRATION_MODE", RegistrationOpen)); mode {
+	case RegistrationOpen, RegistrationInvite, RegistrationDisabled:
+		return mode
+	default:
+		return RegistrationInvite
+	}
+}
+
+// HashPassword hashes a password at BcryptCost
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost())
 	return string(bytes), err
 }
 
@@ -36,23 +85,58 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken(userID uuid.UUID, email string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-		},
+// AccessTokenTTL is how long a login's access token lasts (ACCESS_TOKEN_TTL,
+// default 24 hours). Clients with a refresh token can get a new one after.
+func AccessTokenTTL() time.Duration {
+	return config.Duration("ACCESS_TOKEN_TTL", 24*time.Hour)
+}
+
+// GenerateToken issues an access token for a user. tenantID is the user's
+// tenant in multi-tenant mode; the token is only accepted for that tenant.
+// version is the user's current token version.
+func GenerateToken(userID uuid.UUID, email string, tenantID *uuid.UUID, version int) (string, time.Time, error) {
+	return signExpiring(Claims{UserID: userID, Email: email, TenantID: tenantID, TokenVersion: version}, AccessTokenTTL())
+}
+
+// GenerateSessionToken issues an access token in session sessionID, which
+// stops working when the session is revoked
+func GenerateSessionToken(userID uuid.UUID, email string, tenantID *uuid.UUID, version int, sessionID uuid.UUID) (string, time.Time, error) {
+	return signExpiring(Claims{UserID: userID, Email: email, TenantID: tenantID, TokenVersion: version, SessionID: &sessionID}, AccessTokenTTL())
+}
+
+// GenerateScopedToken issues a token limited to scopes that expires after ttl,
+// e.g. a read-only login for an accountant
+func GenerateScopedToken(userID uuid.UUID, email string, tenantID *uuid.UUID, version int, scopes []string, ttl time.Duration) (string, time.Time, error) {
+	return signExpiring(Claims{UserID: userID, Email: email, TenantID: tenantID, TokenVersion: version, Scopes: scopes}, ttl)
+}
+
+// GenerateEmergencyToken issues an emergency contact a read-only token for
+// the account of userID. It names the grant it was issued under, so it stops
+// working as soon as that access is revoked.
+func GenerateEmergencyToken(userID uuid.UUID, email string, tenantID *uuid.UUID, accessID uuid.UUID, ttl time.Duration) (string, time.Time, error) {
+	return signExpiring(Claims{
+		UserID:            userID,
+		Email:             email,
+		TenantID:          tenantID,
+		Scopes:            []string{ScopeCoinsRead, ScopeReportsRead},
+		EmergencyAccessID: &accessID,
+	}, ttl)
+}
+
+func signExpiring(claims Claims, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret())
+	return token, expiresAt, err
 }
 
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		return jwtSecret(), nil
 	})
 
 	if err != nil {
@@ -65,3 +149,17 @@ func ValidateToken(tokenString string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// SignState signs claims carried through a third party and back, such as
+// the state of an OAuth login. claims should carry an expiry.
+func SignState(claims jwt.Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(stateSecret())
+}
+
+// ParseState verifies a state signed by SignState and decodes it into claims
+func ParseState(state string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		return stateSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	return err
+}
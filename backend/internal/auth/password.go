@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultBcryptCost is the cost passwords were always hashed at
+const defaultBcryptCost = 14
+
+// maxPasswordBytes is as much of a password as bcrypt uses
+const maxPasswordBytes = 72
+
+// defaultBreachRangeURL is the HaveIBeenPwned range API, which is sent the
+// first five hex digits of a password's SHA-1 and answers with the suffixes
+// of every breached password sharing them
+const defaultBreachRangeURL = "https://api.pwnedpasswords.com/range/"
+
+// Codes of the ways a password falls short of the policy
+const (
+	PasswordTooShort  = "password_too_short"
+	PasswordTooLong   = "password_too_long"
+	PasswordTooSimple = "password_too_simple"
+	PasswordBreached  = "password_breached"
+)
+
+// commonPasswords are refused even when the breach check is off or
+// unreachable
+var commonPasswords = []string{
+	"password", "password1", "password123", "123456", "12345678", "123456789",
+	"1234567890", "qwerty", "qwerty123", "qwertyuiop", "abc123", "111111",
+	"iloveyou", "letmein", "welcome", "welcome1", "monkey", "dragon",
+	"sunshine", "football", "baseball", "admin123", "changeme", "passw0rd",
+}
+
+// BcryptCost is the cost new password hashes are made at (BCRYPT_COST,
+// default 14), kept within what bcrypt accepts. Existing hashes keep the
+// cost they were made at.
+func BcryptCost() int {
+	return min(max(int(config.Int64("BCRYPT_COST", defaultBcryptCost)), bcrypt.MinCost), bcrypt.MaxCost)
+}
+
+// PasswordLogin reports whether users can sign in and register with a
+// password (PASSWORD_LOGIN, default true). Instances on single sign-on turn
+// it off for everyone, admins included.
+func PasswordLogin() bool {
+	return config.Bool("PASSWORD_LOGIN", true)
+}
+
+// PasswordPolicy is what a new password has to satisfy
+type PasswordPolicy struct {
+	MinLength int `json:"min_length"`
+	// MinClasses is how many of lowercase letters, uppercase letters, digits
+	// and symbols it has to mix
+	MinClasses int `json:"min_classes"`
+	// BreachCheck refuses passwords known from data breaches
+	BreachCheck bool `json:"breach_check"`
+}
+
+// CurrentPasswordPolicy returns the configured policy: PASSWORD_MIN_LENGTH
+// (default 8), PASSWORD_MIN_CLASSES (default 1) and PASSWORD_BREACH_CHECK
+// (default true)
+func CurrentPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:   int(config.Int64("PASSWORD_MIN_LENGTH", 8)),
+		MinClasses:  min(int(config.Int64("PASSWORD_MIN_CLASSES", 1)), 4),
+		BreachCheck: config.Bool("PASSWORD_BREACH_CHECK", true),
+	}
+}
+
+// PasswordError is a password the policy refuses
+type PasswordError struct {
+	Code    string
+	Message string
+}
+
+func (e *PasswordError) Error() string {
+	return e.Message
+}
+
+// characterClasses counts which of lowercase, uppercase, digits and symbols
+// a password uses
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return lower + upper + digit + symbol
+}
+
+// Validate returns a *PasswordError if password falls short of the policy.
+// The breach check fails open: if the range API can't be reached, only the
+// built-in list of common passwords is checked.
+func (p PasswordPolicy) Validate(password string) error {
+	if length := len([]rune(password)); length < p.MinLength {
+		return &PasswordError{PasswordTooShort, fmt.Sprintf("Password must be at least %d characters", p.MinLength)}
+	}
+	if len(password) > maxPasswordBytes {
+		return &PasswordError{PasswordTooLong, fmt.Sprintf("Password must be at most %d bytes", maxPasswordBytes)}
+	}
+	if characterClasses(password) < p.MinClasses {
+		return &PasswordError{PasswordTooSimple, fmt.Sprintf("Password must mix at least %d of lowercase letters, uppercase letters, digits and symbols", p.MinClasses)}
+	}
+
+	breached := slices.Contains(commonPasswords, strings.ToLower(password))
+	if !breached && p.BreachCheck && !config.MockMode() {
+		count, err := breachCount(password)
+		usage.RecordCall(usage.ServiceHIBP, err)
+		if err != nil {
+			log.Printf("Password breach check failed, allowing the password: %v", err)
+		}
+		breached = count > 0
+	}
+	if breached {
+		return &PasswordError{PasswordBreached, "This password has appeared in a data breach; choose another"}
+	}
+	return nil
+}
+
+// breachCount asks the range API how often password appears in breaches.
+// Only the first five hex digits of its SHA-1 leave the server.
+func breachCount(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	req, err := http.NewRequest(http.MethodGet, config.String("PASSWORD_BREACH_RANGE_URL", defaultBreachRangeURL)+digest[:5], nil)
+	if err != nil {
+		return 0, err
+	}
+	// Padding hides how many suffixes share the prefix from onlookers
+	req.Header.Set("Add-Padding", "true")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("breach range API returned status %d", resp.StatusCode)
+	}
+	return rangeCount(resp.Body, digest[5:])
+}
+
+// rangeCount finds suffix in a range response, lines of SUFFIX:COUNT, and
+// returns its count. Padding lines have a count of 0.
+func rangeCount(body io.Reader, suffix string) (int, error) {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && strings.EqualFold(line, suffix) {
+			return strconv.Atoi(count)
+		}
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 10, MinClasses: 3}
+	tests := []struct {
+		password string
+		code     string
+	}{
+		{"Short1!", PasswordTooShort},
+		{"alllowercaseletters", PasswordTooSimple},
+		{"correct horse battery", PasswordTooSimple},
+		{"correct horse 42", ""}, // spaces count as symbols
+		{"Tr0ubadorAndCo", ""},
+		{strings.Repeat("aB3", 25), PasswordTooLong},
+	}
+	for _, tt := range tests {
+		err := policy.Validate(tt.password)
+		var perr *PasswordError
+		switch {
+		case tt.code == "" && err != nil:
+			t.Errorf("Validate(%q) = %v, want it accepted", tt.password, err)
+		case tt.code != "" && (!errors.As(err, &perr) || perr.Code != tt.code):
+			t.Errorf("Validate(%q) = %v, want %s", tt.password, err, tt.code)
+		}
+	}
+}
+
+func TestCommonPasswordsAreRefusedWithoutTheBreachCheck(t *testing.T) {
+	err := PasswordPolicy{MinLength: 8}.Validate("Password123")
+	var perr *PasswordError
+	if !errors.As(err, &perr) || perr.Code != PasswordBreached {
+		t.Errorf("Validate(Password123) = %v, want %s", err, PasswordBreached)
+	}
+}
+
+func TestRangeCount(t *testing.T) {
+	body := strings.NewReader("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:2\r\n011053FD0102E94D6AE2F8B83D76FAF94F6:0\r\n")
+	if count, err := rangeCount(body, "00d4f6e8fa6eecad2a3aa415eec418d38ec"); err != nil || count != 2 {
+		t.Errorf("rangeCount = %d, %v, want 2", count, err)
+	}
+	if count, _ := rangeCount(strings.NewReader("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"), "FFFF"); count != 0 {
+		t.Errorf("rangeCount of a missing suffix = %d, want 0", count)
+	}
+}
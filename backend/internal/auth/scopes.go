@@ -0,0 +1,50 @@
+package auth
+
+import "slices"
+
+// Permission scopes a token can be limited to. Tokens from a normal login
+// carry no scopes and have full access; scoped tokens can only use the routes
+// their scopes cover and can't manage the account.
+const (
+	ScopeCoinsRead   = "coins:read"   // view portfolios, coins, lots and alerts
+	ScopeCoinsWrite  = "coins:write"  // change them; implies coins:read
+	ScopeReportsRead = "reports:read" // run reports
+	ScopeAdmin       = "admin"        // admin endpoints, for admin users only
+
+	// ScopeDisplay only reads one portfolio's total value and spot prices,
+	// for a wall display. It is given to display tokens alone, which are
+	// bound to the portfolio, so it isn't among the Scopes users can grant.
+	ScopeDisplay = "display"
+)
+
+// Scopes lists every scope, in display order
+var Scopes = []string{ScopeCoinsRead, ScopeCoinsWrite, ScopeReportsRead, ScopeAdmin}
+
+// implied lists the scopes each scope also grants
+var implied = map[string][]string{
+	ScopeCoinsWrite: {ScopeCoinsRead},
+}
+
+// ValidScope reports whether scope is a known scope
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// FullAccess reports whether granted scopes are unrestricted
+func FullAccess(granted []string) bool {
+	return len(granted) == 0
+}
+
+// HasScope reports whether granted scopes allow scope. No scopes at all is
+// full access.
+func HasScope(granted []string, scope string) bool {
+	if FullAccess(granted) {
+		return true
+	}
+	for _, g := range granted {
+		if g == scope || slices.Contains(implied[g], scope) {
+			return true
+		}
+	}
+	return false
+}
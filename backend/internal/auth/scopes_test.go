@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		name    string
+		granted []string
+		scope   string
+		want    bool
+	}{
+		{"full access", nil, ScopeAdmin, true},
+		{"granted", []string{ScopeReportsRead}, ScopeReportsRead, true},
+		{"write implies read", []string{ScopeCoinsWrite}, ScopeCoinsRead, true},
+		{"read doesn't imply write", []string{ScopeCoinsRead, ScopeReportsRead}, ScopeCoinsWrite, false},
+		{"not granted", []string{ScopeCoinsWrite}, ScopeReportsRead, false},
+		{"admin implies nothing else", []string{ScopeAdmin}, ScopeCoinsRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasScope(tt.granted, tt.scope); got != tt.want {
+				t.Errorf("HasScope(%v, %q) = %v, want %v", tt.granted, tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopedTokenCarriesScopes(t *testing.T) {
+	scopes := []string{ScopeCoinsRead, ScopeReportsRead}
+	token, expiresAt, err := GenerateScopedToken(uuid.New(), "accountant@example.com", nil, 0, scopes, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Until(expiresAt) > time.Hour {
+		t.Errorf("expires at %v, more than an hour from now", expiresAt)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claims.Scopes) != 2 || claims.Scopes[0] != ScopeCoinsRead || claims.Scopes[1] != ScopeReportsRead {
+		t.Errorf("scopes = %v, want %v", claims.Scopes, scopes)
+	}
+}
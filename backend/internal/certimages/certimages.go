@@ -0,0 +1,168 @@
+package certimages
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/google/uuid"
+)
+
+const maxImageBytes = 20 << 20 // 20MB
+
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Enabled reports whether certification images are archived. It is on
+// unless PCGS_IMAGE_ARCHIVE is false, and off in mock mode, whose fixture
+// image URLs don't resolve.
+func Enabled() bool {
+	return config.Bool("PCGS_IMAGE_ARCHIVE", true) && !config.MockMode()
+}
+
+// Archive records a coin's certification image variants, replacing those
+// recorded for an earlier cert, and downloads them into image storage when
+// archiving is enabled. The coin's image and thumbnail URLs are pointed at
+// the stored copies when they still hotlink the originals. Images that fail
+// to download, or that would exceed the user's storage quota, stay hotlinked.
+func Archive(userID uuid.UUID, coin models.Coin, images []pcgs.ImageDetail) error {
+	if len(images) == 0 {
+		return nil
+	}
+
+	store := storage.NewLocalStorage()
+	if err := Remove(userID, coin.ID); err != nil {
+		return err
+	}
+
+	enabled := Enabled()
+	var used int64
+	quota := storage.UserQuota()
+	if enabled && quota > 0 {
+		var err error
+		if used, err = store.UserUsage(userID); err != nil {
+			return err
+		}
+	}
+
+	archived := make(map[string]string) // source URL -> stored URL
+	for i, image := range images {
+		variant := pcgs.Classify(image)
+		record := models.CoinImage{
+			CoinID:      coin.ID,
+			Position:    i,
+			Description: image.Description,
+			Resolution:  image.Resolution,
+			Side:        variant.Side,
+			TrueView:    variant.TrueView,
+			SourceURL:   image.URL,
+			URL:         image.URL,
+		}
+
+		if enabled {
+			if data, ext, err := download(image.URL); err != nil {
+				log.Printf("Failed to archive image %s for coin %s: %v", image.URL, coin.ID, err)
+			} else if quota > 0 && used+int64(len(data)) > quota {
+				log.Printf("Skipping image archive for coin %s: storage quota exceeded", coin.ID)
+				enabled = false
+			} else {
+				name := fmt.Sprintf("%s_cert_%d%s", coin.ID, i, ext)
+				url, err := store.Save(userID, name, data)
+				if err != nil {
+					return err
+				}
+				used += int64(len(data))
+				record.URL = url
+				record.FileName = name
+				record.Size = int64(len(data))
+				archived[image.URL] = url
+			}
+		}
+
+		if err := database.GetDB().Create(&record).Error; err != nil {
+			return err
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if url, ok := archived[coin.ImageURL]; ok {
+		updates["image_url"] = url
+	}
+	if url, ok := archived[coin.ThumbnailURL]; ok {
+		updates["thumbnail_url"] = url
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return database.GetDB().Model(&models.Coin{}).Where("id = ?", coin.ID).Updates(updates).Error
+}
+
+// download fetches an image, rejecting anything that isn't a supported
+// image type or is too large
+func download(url string) ([]byte, string, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxImageBytes {
+		return nil, "", fmt.Errorf("image larger than %d bytes", maxImageBytes)
+	}
+
+	ext, ok := imageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported image type %s", http.DetectContentType(data))
+	}
+	return data, ext, nil
+}
+
+// Remove deletes a coin's archived images and their files
+func Remove(userID, coinID uuid.UUID) error {
+	var images []models.CoinImage
+	if err := database.GetDB().Where("coin_id = ?", coinID).Find(&images).Error; err != nil {
+		return err
+	}
+
+	store := storage.NewLocalStorage()
+	for _, image := range images {
+		if image.FileName == "" {
+			continue
+		}
+		if err := store.Delete(userID, image.FileName); err != nil {
+			log.Printf("Failed to delete archived image %s: %v", image.FileName, err)
+		}
+	}
+	return database.GetDB().Where("coin_id = ?", coinID).Delete(&models.CoinImage{}).Error
+}
+
+// Subscribe removes archived images when their coin is deleted
+func Subscribe() {
+	events.Subscribe(events.TypeCoinDeleted, func(e events.Event) {
+		deleted := e.(events.CoinDeleted)
+		if err := Remove(deleted.UserID, deleted.Coin.ID); err != nil {
+			log.Printf("Failed to remove archived images for coin %s: %v", deleted.Coin.ID, err)
+		}
+	})
+}
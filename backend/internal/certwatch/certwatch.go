@@ -0,0 +1,140 @@
+// Package certwatch checks PCGS cert numbers against a watchlist of numbers
+// known to be on counterfeit slabs, and for other signs of a fake holder.
+// The check is advisory: a suspicious coin is saved as usual and flagged for
+// its owner to verify against the slab and the grading service's own
+// verification page.
+package certwatch
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// Cert statuses
+const (
+	StatusSuspicious = "suspicious"
+	StatusVerified   = "verified"
+)
+
+// PCGS cert numbers are 7 to 9 digits
+const (
+	minCertDigits = 7
+	maxCertDigits = 9
+)
+
+// ParseCert returns a cert number as a number, or false when it isn't one
+func ParseCert(cert string) (int64, bool) {
+	cert = strings.TrimSpace(cert)
+	if cert == "" || len(cert) > 18 {
+		return 0, false
+	}
+	for _, r := range cert {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(cert, 10, 64)
+	return n, err == nil
+}
+
+// formatFlags returns why cert doesn't look like a PCGS cert number
+func formatFlags(cert string) []string {
+	cert = strings.TrimSpace(cert)
+	if _, ok := ParseCert(cert); !ok {
+		return []string{"Cert number isn't numeric, unlike PCGS cert numbers"}
+	}
+	if len(cert) < minCertDigits || len(cert) > maxCertDigits {
+		return []string{fmt.Sprintf("Cert number has %d digits; PCGS cert numbers have %d to %d", len(cert), minCertDigits, maxCertDigits)}
+	}
+	return nil
+}
+
+// watchlistFlags returns the reasons of the watchlist entries covering cert
+func watchlistFlags(cert int64, entries []models.CertWatchEntry) []string {
+	var flags []string
+	for _, e := range entries {
+		if cert >= e.RangeStart && cert <= e.RangeEnd {
+			flags = append(flags, "On the counterfeit watchlist: "+e.Reason)
+		}
+	}
+	return flags
+}
+
+// Check returns why a coin's cert number looks suspicious, or nothing when
+// it doesn't. Besides the format and the watchlist, a cert already recorded
+// for another coin is flagged: counterfeit slabs often copy a genuine cert.
+func Check(cert string, coinID uuid.UUID) []string {
+	cert = strings.TrimSpace(cert)
+	if cert == "" {
+		return nil
+	}
+	flags := formatFlags(cert)
+
+	if n, ok := ParseCert(cert); ok {
+		var entries []models.CertWatchEntry
+		if err := database.GetDB().Where("range_start <= ? AND range_end >= ?", n, n).Find(&entries).Error; err != nil {
+			log.Printf("Failed to check cert %s against the watchlist: %v", cert, err)
+		}
+		flags = append(flags, watchlistFlags(n, entries)...)
+	}
+
+	var duplicates int64
+	database.GetDB().Model(&models.Coin{}).Where("pcgs_cert_number = ? AND id <> ?", cert, coinID).Count(&duplicates)
+	if duplicates > 0 {
+		flags = append(flags, "Cert number is already recorded for another coin")
+	}
+	return flags
+}
+
+// Apply checks a coin's cert number and sets its cert status, clearing it
+// when the cert looks fine. It reports whether the coin is suspicious.
+func Apply(coin *models.Coin) bool {
+	flags := Check(coin.PCGSCertNumber, coin.ID)
+	if len(flags) == 0 {
+		coin.CertStatus, coin.CertFlags = "", nil
+		return false
+	}
+	coin.CertStatus, coin.CertFlags = StatusSuspicious, flags
+	return true
+}
+
+// FlagWatched flags the coins whose cert numbers fall in a new watchlist
+// entry, leaving coins their owners already verified alone. It returns how
+// many coins were flagged.
+func FlagWatched(entry models.CertWatchEntry) (int, error) {
+	type row struct {
+		models.Coin
+		UserID uuid.UUID
+	}
+	var rows []row
+	// Cast only the cert numbers that are numeric, and short enough for a bigint
+	if err := database.GetDB().Table("coins").
+		Select("coins.*, portfolios.user_id").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("CASE WHEN coins.pcgs_cert_number ~ '^[0-9]{1,18}$' THEN coins.pcgs_cert_number::bigint END BETWEEN ? AND ?", entry.RangeStart, entry.RangeEnd).
+		Where("coins.cert_status IS NULL OR coins.cert_status <> ?", StatusVerified).
+		Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	flagged := 0
+	for _, r := range rows {
+		coin := r.Coin
+		if !Apply(&coin) {
+			continue
+		}
+		if err := database.GetDB().Model(&coin).Select("cert_status", "cert_flags").Updates(&coin).Error; err != nil {
+			return flagged, err
+		}
+		flagged++
+		events.Publish(events.CertFlagged{UserID: r.UserID, Coin: coin})
+	}
+	return flagged, nil
+}
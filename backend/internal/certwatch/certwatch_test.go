@@ -0,0 +1,59 @@
+package certwatch
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestParseCert(t *testing.T) {
+	tests := []struct {
+		cert string
+		want int64
+		ok   bool
+	}{
+		{"12345678", 12345678, true},
+		{" 01234567 ", 1234567, true},
+		{"1234-5678", 0, false},
+		{"", 0, false},
+		{"1234567890123456789", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCert(tt.cert)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCert(%q) = %d, %v, want %d, %v", tt.cert, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatFlags(t *testing.T) {
+	for cert, flagged := range map[string]bool{
+		"1234567":    false,
+		"12345678":   false,
+		"123456789":  false,
+		"123456":     true,
+		"1234567890": true,
+		"12A45678":   true,
+	} {
+		if got := len(formatFlags(cert)) > 0; got != flagged {
+			t.Errorf("formatFlags(%q) flagged = %v, want %v", cert, got, flagged)
+		}
+	}
+}
+
+func TestWatchlistFlags(t *testing.T) {
+	entries := []models.CertWatchEntry{
+		{RangeStart: 40000000, RangeEnd: 40000999, Reason: "Chinese counterfeit Morgan slabs"},
+		{RangeStart: 40000500, RangeEnd: 40000500, Reason: "Reported stolen"},
+	}
+
+	if flags := watchlistFlags(40000500, entries); len(flags) != 2 {
+		t.Errorf("cert in both entries got %d flags, want 2: %v", len(flags), flags)
+	}
+	if flags := watchlistFlags(40000000, entries); len(flags) != 1 {
+		t.Errorf("cert at the start of a range got %d flags, want 1", len(flags))
+	}
+	if flags := watchlistFlags(40001000, entries); len(flags) != 0 {
+		t.Errorf("cert past every range got flags %v", flags)
+	}
+}
@@ -0,0 +1,203 @@
+package charts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Bin units, from finest to coarsest
+const (
+	UnitDay     = "day"
+	UnitWeek    = "week"
+	UnitMonth   = "month"
+	UnitQuarter = "quarter"
+	UnitYear    = "year"
+)
+
+const (
+	DefaultMaxPoints = 100
+	MinMaxPoints     = 2
+	MaxMaxPoints     = 1000
+)
+
+// Sample is one observation of a value over time
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series is one line of a chart. Data has one entry per label; null entries
+// are bins before the series' first sample.
+type Series struct {
+	Name string     `json:"name"`
+	Data []*float64 `json:"data"`
+}
+
+// Chart is a chart-ready payload: labels for the x axis and series values
+// aligned with them
+type Chart struct {
+	Labels  []string `json:"labels"`
+	Series  []Series `json:"series"`
+	Bin     string   `json:"bin"`      // unit of each bin
+	BinStep int      `json:"bin_step"` // number of units per bin
+}
+
+// Binning splits a time range into calendar-aligned bins
+type Binning struct {
+	Unit   string
+	Step   int
+	Starts []time.Time
+}
+
+var units = []string{UnitDay, UnitWeek, UnitMonth, UnitQuarter, UnitYear}
+
+// ClampMaxPoints keeps a requested point count within the supported range,
+// using the default for zero or negative values
+func ClampMaxPoints(n int) int {
+	switch {
+	case n <= 0:
+		return DefaultMaxPoints
+	case n < MinMaxPoints:
+		return MinMaxPoints
+	case n > MaxMaxPoints:
+		return MaxMaxPoints
+	}
+	return n
+}
+
+// truncate returns the start of the bin of the given unit containing t
+func truncate(t time.Time, unit string, step int) time.Time {
+	t = t.UTC()
+	y, m, d := t.Date()
+	switch unit {
+	case UnitWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)) // weeks start on Monday
+	case UnitMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case UnitQuarter:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case UnitYear:
+		return time.Date(y-y%step, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the bin after start
+func next(start time.Time, unit string, step int) time.Time {
+	switch unit {
+	case UnitWeek:
+		return start.AddDate(0, 0, 7)
+	case UnitMonth:
+		return start.AddDate(0, 1, 0)
+	case UnitQuarter:
+		return start.AddDate(0, 3, 0)
+	case UnitYear:
+		return start.AddDate(step, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// binStarts lists bin starts covering [from, to], or nil if there would be
+// more than maxPoints of them
+func binStarts(from, to time.Time, unit string, step, maxPoints int) []time.Time {
+	var starts []time.Time
+	for start := truncate(from, unit, step); !start.After(to); start = next(start, unit, step) {
+		if len(starts) == maxPoints {
+			return nil
+		}
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// NewBinning picks the finest calendar unit that covers [from, to] in at
+// most maxPoints bins. Spans too long even for yearly bins use multi-year bins.
+func NewBinning(from, to time.Time, maxPoints int) Binning {
+	maxPoints = ClampMaxPoints(maxPoints)
+	from, to = from.UTC(), to.UTC()
+	for _, unit := range units {
+		if starts := binStarts(from, to, unit, 1, maxPoints); starts != nil {
+			return Binning{Unit: unit, Step: 1, Starts: starts}
+		}
+	}
+
+	years := to.Year() - from.Year() + 1
+	step := (years + maxPoints - 2) / (maxPoints - 1) // one spare bin for alignment
+	return Binning{Unit: UnitYear, Step: step, Starts: binStarts(from, to, UnitYear, step, maxPoints)}
+}
+
+// Labels formats the bin starts for display on an x axis
+func (b Binning) Labels() []string {
+	labels := make([]string, len(b.Starts))
+	for i, start := range b.Starts {
+		switch b.Unit {
+		case UnitMonth:
+			labels[i] = start.Format("2006-01")
+		case UnitQuarter:
+			labels[i] = fmt.Sprintf("%d-Q%d", start.Year(), (int(start.Month())-1)/3+1)
+		case UnitYear:
+			labels[i] = start.Format("2006")
+		default:
+			labels[i] = start.Format("2006-01-02")
+		}
+	}
+	return labels
+}
+
+// Close returns the last value seen by the end of each bin. Empty bins carry
+// the previous value forward; bins before the first sample are nil.
+func (b Binning) Close(samples []Sample) []*float64 {
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	data := make([]*float64, len(b.Starts))
+	var last *float64
+	i := 0
+	for bin := range b.Starts {
+		var end time.Time
+		if bin+1 < len(b.Starts) {
+			end = b.Starts[bin+1]
+		}
+		for i < len(sorted) && (end.IsZero() || sorted[i].Time.Before(end)) {
+			value := sorted[i].Value
+			last = &value
+			i++
+		}
+		data[bin] = last
+	}
+	return data
+}
+
+// Chart wraps series already aligned with the binning into a chart payload
+func (b Binning) Chart(series ...Series) Chart {
+	if series == nil {
+		series = []Series{}
+	}
+	return Chart{
+		Labels:  b.Labels(),
+		Series:  series,
+		Bin:     b.Unit,
+		BinStep: b.Step,
+	}
+}
+
+// Sum adds series of n bins element-wise, treating nil as zero. The result
+// is nil only where every input is nil.
+func Sum(n int, series ...[]*float64) []*float64 {
+	total := make([]*float64, n)
+	for _, data := range series {
+		for i, value := range data {
+			if value == nil {
+				continue
+			}
+			if total[i] == nil {
+				total[i] = new(float64)
+			}
+			*total[i] += *value
+		}
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package charts
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestNewBinningPicksFinestUnit(t *testing.T) {
+	tests := []struct {
+		from, to  time.Time
+		maxPoints int
+		unit      string
+		step      int
+		bins      int
+	}{
+		{date(2024, 1, 1), date(2024, 1, 10), 100, UnitDay, 1, 10},
+		{date(2024, 1, 1), date(2024, 6, 30), 100, UnitWeek, 1, 26},
+		{date(2020, 1, 1), date(2024, 12, 31), 100, UnitMonth, 1, 60},
+		{date(2020, 1, 1), date(2024, 12, 31), 20, UnitQuarter, 1, 20},
+		{date(2000, 3, 1), date(2024, 12, 31), 20, UnitYear, 2, 13},
+	}
+
+	for _, tt := range tests {
+		b := NewBinning(tt.from, tt.to, tt.maxPoints)
+		if b.Unit != tt.unit || b.Step != tt.step || len(b.Starts) != tt.bins {
+			t.Errorf("NewBinning(%s, %s, %d) = %s×%d with %d bins, want %s×%d with %d bins",
+				tt.from.Format("2006-01-02"), tt.to.Format("2006-01-02"), tt.maxPoints,
+				b.Unit, b.Step, len(b.Starts), tt.unit, tt.step, tt.bins)
+		}
+		if len(b.Starts) > tt.maxPoints {
+			t.Errorf("NewBinning returned %d bins, more than max %d", len(b.Starts), tt.maxPoints)
+		}
+	}
+}
+
+func TestCloseCarriesLastValueForward(t *testing.T) {
+	b := NewBinning(date(2024, 1, 1), date(2024, 4, 15), 4)
+	if b.Unit != UnitMonth {
+		t.Fatalf("unit = %s, want month", b.Unit)
+	}
+
+	data := b.Close([]Sample{
+		{Time: date(2024, 3, 20), Value: 30},
+		{Time: date(2024, 2, 5), Value: 10},
+		{Time: date(2024, 2, 25), Value: 20},
+	})
+
+	want := []*float64{nil, ptr(20), ptr(30), ptr(30)}
+	for i := range want {
+		if (data[i] == nil) != (want[i] == nil) || (data[i] != nil && *data[i] != *want[i]) {
+			t.Errorf("bin %d (%s) = %v, want %v", i, b.Labels()[i], deref(data[i]), deref(want[i]))
+		}
+	}
+
+	total := Sum(len(data), data, []*float64{ptr(1), nil, ptr(1), nil})
+	if *total[0] != 1 || *total[1] != 20 || *total[2] != 31 {
+		t.Errorf("Sum = %v, %v, %v", deref(total[0]), deref(total[1]), deref(total[2]))
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func deref(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -0,0 +1,119 @@
+package charts
+
+import "sort"
+
+// HeatMapCell totals the coins acquired in one year from one issue decade.
+// Decade is nil for coins without an issue year, such as undated bullion.
+type HeatMapCell struct {
+	AcquisitionYear int     `json:"acquisition_year"`
+	Decade          *int    `json:"decade"`
+	TotalValue      float64 `json:"total_value"`
+	Count           int64   `json:"count"`
+}
+
+// HeatMapTotal totals one row or column of a heat map
+type HeatMapTotal struct {
+	Key        *int    `json:"key"`
+	TotalValue float64 `json:"total_value"`
+	Count      int64   `json:"count"`
+}
+
+// HeatMap is a heat-map-ready payload: contiguous axes of acquisition years
+// and issue decades (with null last for undated coins), the nonzero cells,
+// and the totals of each row and column
+type HeatMap struct {
+	AcquisitionYears  []int          `json:"acquisition_years"`
+	Decades           []*int         `json:"decades"`
+	Cells             []HeatMapCell  `json:"cells"`
+	ByAcquisitionYear []HeatMapTotal `json:"by_acquisition_year"`
+	ByDecade          []HeatMapTotal `json:"by_decade"`
+	TotalValue        float64        `json:"total_value"`
+	Count             int64          `json:"count"`
+}
+
+// NewHeatMap lays out grouped totals as a heat map. Cells may come in any
+// order but are expected to be unique per year and decade.
+func NewHeatMap(cells []HeatMapCell) HeatMap {
+	m := HeatMap{AcquisitionYears: []int{}, Decades: []*int{}, Cells: []HeatMapCell{}, ByAcquisitionYear: []HeatMapTotal{}, ByDecade: []HeatMapTotal{}}
+	if len(cells) == 0 {
+		return m
+	}
+
+	byYear := map[int]*HeatMapTotal{}
+	byDecade := map[int]*HeatMapTotal{}
+	var undated *HeatMapTotal
+	minYear, maxYear := cells[0].AcquisitionYear, cells[0].AcquisitionYear
+	minDecade, maxDecade, dated := 0, 0, false
+
+	for _, cell := range cells {
+		m.Cells = append(m.Cells, cell)
+		m.TotalValue += cell.TotalValue
+		m.Count += cell.Count
+
+		minYear, maxYear = min(minYear, cell.AcquisitionYear), max(maxYear, cell.AcquisitionYear)
+		if byYear[cell.AcquisitionYear] == nil {
+			year := cell.AcquisitionYear
+			byYear[year] = &HeatMapTotal{Key: &year}
+		}
+		byYear[cell.AcquisitionYear].TotalValue += cell.TotalValue
+		byYear[cell.AcquisitionYear].Count += cell.Count
+
+		var total *HeatMapTotal
+		if cell.Decade == nil {
+			if undated == nil {
+				undated = &HeatMapTotal{}
+			}
+			total = undated
+		} else {
+			decade := *cell.Decade
+			if !dated {
+				minDecade, maxDecade, dated = decade, decade, true
+			}
+			minDecade, maxDecade = min(minDecade, decade), max(maxDecade, decade)
+			if byDecade[decade] == nil {
+				byDecade[decade] = &HeatMapTotal{Key: &decade}
+			}
+			total = byDecade[decade]
+		}
+		total.TotalValue += cell.TotalValue
+		total.Count += cell.Count
+	}
+
+	for year := minYear; year <= maxYear; year++ {
+		m.AcquisitionYears = append(m.AcquisitionYears, year)
+		if total, ok := byYear[year]; ok {
+			m.ByAcquisitionYear = append(m.ByAcquisitionYear, *total)
+		} else {
+			y := year
+			m.ByAcquisitionYear = append(m.ByAcquisitionYear, HeatMapTotal{Key: &y})
+		}
+	}
+	if dated {
+		for decade := minDecade; decade <= maxDecade; decade += 10 {
+			d := decade
+			m.Decades = append(m.Decades, &d)
+			if total, ok := byDecade[decade]; ok {
+				m.ByDecade = append(m.ByDecade, *total)
+			} else {
+				m.ByDecade = append(m.ByDecade, HeatMapTotal{Key: &d})
+			}
+		}
+	}
+	if undated != nil {
+		m.Decades = append(m.Decades, nil)
+		m.ByDecade = append(m.ByDecade, *undated)
+	}
+
+	sort.Slice(m.Cells, func(i, j int) bool {
+		a, b := m.Cells[i], m.Cells[j]
+		if a.AcquisitionYear != b.AcquisitionYear {
+			return a.AcquisitionYear < b.AcquisitionYear
+		}
+		// Undated coins sort after every decade, as on the axis
+		if a.Decade == nil || b.Decade == nil {
+			return b.Decade == nil && a.Decade != nil
+		}
+		return *a.Decade < *b.Decade
+	})
+	return m
+}
@@ -0,0 +1,43 @@
+package charts
+
+import "testing"
+
+func decade(d int) *int { return &d }
+
+func TestNewHeatMapFillsAxesAndTotals(t *testing.T) {
+	m := NewHeatMap([]HeatMapCell{
+		{AcquisitionYear: 2022, Decade: decade(1920), TotalValue: 300, Count: 2},
+		{AcquisitionYear: 2020, Decade: nil, TotalValue: 50, Count: 1},
+		{AcquisitionYear: 2020, Decade: decade(1880), TotalValue: 100, Count: 3},
+		{AcquisitionYear: 2022, Decade: decade(1880), TotalValue: 40, Count: 1},
+	})
+
+	if got, want := m.AcquisitionYears, []int{2020, 2021, 2022}; len(got) != len(want) || got[1] != 2021 {
+		t.Errorf("acquisition years = %v, want %v", got, want)
+	}
+	if len(m.Decades) != 6 || *m.Decades[0] != 1880 || *m.Decades[4] != 1920 || m.Decades[5] != nil {
+		t.Errorf("decades should run 1880-1920 then null, got %d entries", len(m.Decades))
+	}
+	if m.TotalValue != 490 || m.Count != 7 {
+		t.Errorf("totals = %.2f, %d, want 490, 7", m.TotalValue, m.Count)
+	}
+
+	if len(m.ByAcquisitionYear) != 3 || m.ByAcquisitionYear[0].TotalValue != 150 || m.ByAcquisitionYear[1].Count != 0 || m.ByAcquisitionYear[2].TotalValue != 340 {
+		t.Errorf("by acquisition year = %+v", m.ByAcquisitionYear)
+	}
+	if m.ByDecade[0].TotalValue != 140 || m.ByDecade[0].Count != 4 || m.ByDecade[5].Key != nil || m.ByDecade[5].TotalValue != 50 {
+		t.Errorf("by decade = %+v", m.ByDecade)
+	}
+
+	first, second := m.Cells[0], m.Cells[1]
+	if first.AcquisitionYear != 2020 || first.Decade == nil || *first.Decade != 1880 || second.Decade != nil {
+		t.Errorf("cells should sort by year, then decade with undated last: %+v, %+v", first, second)
+	}
+}
+
+func TestNewHeatMapEmpty(t *testing.T) {
+	m := NewHeatMap(nil)
+	if m.Cells == nil || m.AcquisitionYears == nil || m.Decades == nil || m.Count != 0 {
+		t.Errorf("empty heat map should have empty, non-nil axes: %+v", m)
+	}
+}
@@ -0,0 +1,132 @@
+// Package clientip decides which address a request came from. Behind a
+// reverse proxy the connection's address is the proxy's, and the client's is
+// in a header the proxy sets; that header is only believed when the
+// connection comes from a proxy listed in TRUSTED_PROXIES, since anyone can
+// send it.
+package clientip
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// defaultHeaders are the headers read for the client's address when the
+// connection comes from a trusted proxy, first one set wins
+const defaultHeaders = "X-Forwarded-For,X-Real-IP"
+
+// platforms are the TRUSTED_PLATFORM shorthands for hosts that put the
+// client's address in a header of their own
+var platforms = map[string]string{
+	"cloudflare": gin.PlatformCloudflare,
+	"google":     gin.PlatformGoogleAppEngine,
+	"flyio":      gin.PlatformFlyIO,
+}
+
+// ParseList parses a comma-separated list of IP addresses and CIDR ranges,
+// e.g. "10.0.0.0/8, 192.168.1.5, ::1". A plain address is a range of one.
+func ParseList(value string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an IP address or CIDR range", entry)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// Contains reports whether ip is in any of the ranges. IPv4 addresses
+// written as IPv6 (::ffff:10.0.0.1) match IPv4 ranges.
+func Contains(prefixes []netip.Prefix, ip string) bool {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedProxies returns the TRUSTED_PROXIES ranges. Unset trusts no
+// proxy, so the client's address is always the connection's.
+func TrustedProxies() ([]netip.Prefix, error) {
+	prefixes, err := ParseList(config.String("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	return prefixes, nil
+}
+
+// Platform returns the header TRUSTED_PLATFORM names, by shorthand
+// (cloudflare, google, flyio) or as the header itself. The header is
+// believed from any connection, so set it only when the host strips it from
+// what clients send.
+func Platform() string {
+	value := config.String("TRUSTED_PLATFORM", "")
+	if header, ok := platforms[strings.ToLower(value)]; ok {
+		return header
+	}
+	return value
+}
+
+// Headers returns the CLIENT_IP_HEADERS read from trusted proxies
+func Headers() []string {
+	headers := []string{}
+	for _, header := range strings.Split(config.String("CLIENT_IP_HEADERS", defaultHeaders), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// Configure sets how the router finds each request's client address, which
+// c.ClientIP() then returns everywhere
+func Configure(r *gin.Engine) error {
+	prefixes, err := TrustedProxies()
+	if err != nil {
+		return err
+	}
+	proxies := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		proxies[i] = prefix.String()
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	r.RemoteIPHeaders = Headers()
+	r.TrustedPlatform = Platform()
+	return nil
+}
+
+// FromTrustedProxy reports whether the request's connection comes from one
+// of TRUSTED_PROXIES, so headers such as X-Forwarded-Proto can be believed
+func FromTrustedProxy(c *gin.Context) bool {
+	prefixes, err := TrustedProxies()
+	if err != nil {
+		return false
+	}
+	return Contains(prefixes, c.RemoteIP())
+}
@@ -0,0 +1,53 @@
+package clientip
+
+import "testing"
+
+func TestParseList(t *testing.T) {
+	prefixes, err := ParseList(" 10.0.0.0/8, 192.168.1.5 ,, ::1, 172.16.5.4/12 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.5/32", "::1/128", "172.16.0.0/12"}
+	if len(prefixes) != len(want) {
+		t.Fatalf("got %v, want %v", prefixes, want)
+	}
+	for i, prefix := range prefixes {
+		if prefix.String() != want[i] {
+			t.Errorf("prefix %d = %s, want %s", i, prefix, want[i])
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "example.com", "10.0.0"} {
+		if _, err := ParseList(bad); err == nil {
+			t.Errorf("ParseList(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	prefixes, err := ParseList("10.0.0.0/8,192.168.1.5,2001:db8::/32")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"192.168.1.5", true},
+		{"192.168.1.6", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"", false},
+		{"not an ip", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(prefixes, tt.ip); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+	if Contains(nil, "10.1.2.3") {
+		t.Error("an empty list contains nothing")
+	}
+}
@@ -0,0 +1,45 @@
+// Package coincode gives coins short, human-friendly codes such as
+// AU-000123 for labels and physical inventory. A code is the coin's number,
+// taken from a database sequence when the coin is created, so it never
+// changes and isn't reused.
+package coincode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Prefix starts every code
+const Prefix = "AU"
+
+// Sequence is the database sequence coin numbers are taken from
+const Sequence = "coin_numbers"
+
+// Format returns the code of a coin's number, zero-padded to six digits.
+// A coin without a number has no code.
+func Format(number int64) string {
+	if number <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s-%06d", Prefix, number)
+}
+
+// Parse returns the number a code stands for. It ignores case, surrounding
+// space and the hyphen, so "au-123" and "AU000123" are both number 123.
+func Parse(code string) (int64, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	rest, ok := strings.CutPrefix(code, Prefix)
+	if !ok {
+		return 0, false
+	}
+	rest = strings.TrimPrefix(rest, "-")
+	if rest == "" || strings.ContainsAny(rest, "+-") {
+		return 0, false
+	}
+	number, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil || number <= 0 {
+		return 0, false
+	}
+	return number, true
+}
@@ -0,0 +1,24 @@
+package coincode
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	for number, want := range map[int64]string{0: "", 7: "AU-000007", 123: "AU-000123", 1234567: "AU-1234567"} {
+		if got := Format(number); got != want {
+			t.Errorf("Format(%d) = %q, want %q", number, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for code, want := range map[string]int64{"AU-000123": 123, "au-123": 123, " AU000123 ": 123, "AU-1234567": 1234567} {
+		if got, ok := Parse(code); !ok || got != want {
+			t.Errorf("Parse(%q) = %d, %v, want %d", code, got, ok, want)
+		}
+	}
+	for _, code := range []string{"", "AU-", "AU-0", "AU--12", "AU-+12", "AU-12x", "AG-000123", "123", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"} {
+		if got, ok := Parse(code); ok {
+			t.Errorf("Parse(%q) = %d, want no code", code, got)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Package coinrows splits a coin row holding several coins into separate
+// rows, e.g. to send one off for grading or sell part of a roll, and merges
+// identical rows back into one. Cost basis moves in proportion to quantity
+// and each row keeps its price history.
+package coinrows
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/lots"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/references"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrNothingToSplit is returned when a row holds a single coin
+var ErrNothingToSplit = errors.New("only a coin with a quantity above 1 can be split")
+
+// Split takes quantities off coin into new rows, one per quantity, leaving
+// the rest on coin. The purchase price is per coin and stays; the purchase's
+// fees are divided by quantity to the cent. New rows are copies of the coin
+// under codes of their own, without its cert number, since a slab holds a
+// single coin, and without its alerts.
+func Split(coin *models.Coin, quantities []int) ([]models.Coin, error) {
+	if coin.Quantity < 2 {
+		return nil, ErrNothingToSplit
+	}
+	if len(quantities) == 0 {
+		return nil, errors.New("quantities is required")
+	}
+	taken := 0
+	for _, quantity := range quantities {
+		if quantity < 1 {
+			return nil, errors.New("quantities must be at least 1")
+		}
+		taken += quantity
+	}
+	if taken >= coin.Quantity {
+		return nil, fmt.Errorf("quantities add up to %d, the coin only has %d and one must stay", taken, coin.Quantity)
+	}
+
+	// The coin's own share is the first weight
+	weights := make([]float64, 0, len(quantities)+1)
+	weights = append(weights, float64(coin.Quantity-taken))
+	for _, quantity := range quantities {
+		weights = append(weights, float64(quantity))
+	}
+	premium := lots.SplitAmount(coin.BuyersPremium, weights)
+	shipping := lots.SplitAmount(coin.ShippingCost, weights)
+	tax := lots.SplitAmount(coin.SalesTax, weights)
+
+	parts := make([]models.Coin, len(quantities))
+	for i, quantity := range quantities {
+		part := *coin
+		part.ID = uuid.Nil
+		part.Number, part.Code = 0, ""
+		part.Quantity = quantity
+		part.BuyersPremium, part.ShippingCost, part.SalesTax = premium[i+1], shipping[i+1], tax[i+1]
+		part.Problems = slices.Clone(coin.Problems)
+		part.Toning = slices.Clone(coin.Toning)
+		part.PCGSCertNumber = ""
+		part.CertStatus = ""
+		part.CertFlags = nil
+		if coin.PCGSCertNumber != "" {
+			// The photos are of the slabbed coin
+			part.ImageURL = ""
+			part.ThumbnailURL = ""
+		}
+		// Links to the slab's cert and CoinFacts page stay with the coin
+		references.Refresh(&part, "")
+		part.Watched = false
+		part.CreatedAt = time.Time{}
+		part.UpdatedAt = time.Time{}
+		parts[i] = part
+	}
+
+	coin.Quantity -= taken
+	coin.BuyersPremium, coin.ShippingCost, coin.SalesTax = premium[0], shipping[0], tax[0]
+	return parts, nil
+}
+
+// Mergeable reports why other can't be merged into coin, or nil if they
+// describe the same kind of coin in the same portfolio and purchase lot.
+// Certified coins are never merged, as each slab is its own coin.
+func Mergeable(coin, other models.Coin) error {
+	if coin.ID == other.ID {
+		return errors.New("a coin can't be merged with itself")
+	}
+	if coin.PCGSCertNumber != "" || other.PCGSCertNumber != "" {
+		return errors.New("certified coins can't be merged")
+	}
+
+	type field struct {
+		name string
+		same bool
+	}
+	for _, f := range []field{
+		{"portfolio", coin.PortfolioID == other.PortfolioID},
+		{"lot", sameLot(coin, other)},
+		{"coin type", strings.EqualFold(coin.CoinType, other.CoinType)},
+		{"year", coin.Year == other.Year},
+		{"mint mark", strings.EqualFold(coin.MintMark, other.MintMark)},
+		{"strike type", coin.StrikeType == other.StrikeType},
+		{"denomination", coin.Denomination == other.Denomination},
+		{"face value", coin.FaceValue == other.FaceValue && coin.FaceCurrency == other.FaceCurrency},
+		{"metal", coin.MetalType == other.MetalType && coin.MetalWeight == other.MetalWeight && coin.MetalPurity == other.MetalPurity},
+		{"condition", slices.Equal(coin.Problems, other.Problems) && coin.EyeAppeal == other.EyeAppeal && sameToning(coin.Toning, other.Toning)},
+	} {
+		if !f.same {
+			return fmt.Errorf("coins differ in %s", f.name)
+		}
+	}
+	return nil
+}
+
+func sameLot(coin, other models.Coin) bool {
+	if coin.LotID == nil || other.LotID == nil {
+		return coin.LotID == nil && other.LotID == nil
+	}
+	return *coin.LotID == *other.LotID
+}
+
+// sameToning compares toning descriptors regardless of order
+func sameToning(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// Merge folds others into coin. Quantities and fees add up and the purchase
+// price becomes the average per coin, so the total cost basis is unchanged.
+// Per-coin values are averaged by quantity; callers revalue the result on
+// the portfolio's basis. The purchase date is the earliest, and notes and
+// stories are joined. Photos and storage location come from coin when it
+// has them.
+func Merge(coin *models.Coin, others []models.Coin) {
+	quantity := coin.Quantity
+	hammer := coin.PurchasePrice * float64(coin.Quantity)
+	current := coin.CurrentValue * float64(coin.Quantity)
+	melt := coin.MeltValue * float64(coin.Quantity)
+	numismatic := coin.NumismaticValue * float64(coin.Quantity)
+	insured := coin.InsuredValue * float64(coin.Quantity)
+	notes, stories := []string{}, []string{}
+	if note := strings.TrimSpace(coin.Notes); note != "" {
+		notes = append(notes, note)
+	}
+	if story := strings.TrimSpace(coin.Story); story != "" {
+		stories = append(stories, story)
+	}
+
+	for _, other := range others {
+		quantity += other.Quantity
+		hammer += other.PurchasePrice * float64(other.Quantity)
+		current += other.CurrentValue * float64(other.Quantity)
+		melt += other.MeltValue * float64(other.Quantity)
+		numismatic += other.NumismaticValue * float64(other.Quantity)
+		insured += other.InsuredValue * float64(other.Quantity)
+		coin.BuyersPremium += other.BuyersPremium
+		coin.ShippingCost += other.ShippingCost
+		coin.SalesTax += other.SalesTax
+
+		if other.PurchaseDate != nil && (coin.PurchaseDate == nil || other.PurchaseDate.Before(*coin.PurchaseDate)) {
+			coin.PurchaseDate = other.PurchaseDate
+		}
+		if other.LastPriceUpdate != nil && (coin.LastPriceUpdate == nil || other.LastPriceUpdate.After(*coin.LastPriceUpdate)) {
+			coin.LastPriceUpdate = other.LastPriceUpdate
+		}
+		if coin.ImageURL == "" && other.ImageURL != "" {
+			coin.ImageURL, coin.ThumbnailURL = other.ImageURL, other.ThumbnailURL
+		}
+		if coin.StorageLocation == "" {
+			coin.StorageLocation = other.StorageLocation
+		}
+		if note := strings.TrimSpace(other.Notes); note != "" && !slices.Contains(notes, note) {
+			notes = append(notes, note)
+		}
+		if story := strings.TrimSpace(other.Story); story != "" && !slices.Contains(stories, story) {
+			stories = append(stories, story)
+		}
+	}
+
+	coin.Quantity = quantity
+	coin.PurchasePrice = hammer / float64(quantity)
+	coin.CurrentValue = current / float64(quantity)
+	coin.MeltValue = melt / float64(quantity)
+	coin.NumismaticValue = numismatic / float64(quantity)
+	coin.InsuredValue = insured / float64(quantity)
+	coin.Notes = strings.Join(notes, "\n\n")
+	coin.Story = strings.Join(stories, "\n\n")
+}
+
+// SaveSplit stores a split: the coin's new quantity and fees, and its new
+// rows, each with a copy of the coin's price history
+func SaveSplit(coin *models.Coin, parts []models.Coin) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(coin).Error; err != nil {
+			return err
+		}
+		for i := range parts {
+			if err := tx.Create(&parts[i]).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(`INSERT INTO price_histories (id, coin_id, melt_value, numismatic_value, pcgs_value, recorded_at, created_at)
+				SELECT gen_random_uuid(), ?, melt_value, numismatic_value, pcgs_value, recorded_at, created_at
+				FROM price_histories WHERE coin_id = ?`, parts[i].ID, coin.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveMerge stores a merge: the merged coin, and the merged rows removed.
+// The coins are alike, so one price history serves them all: the one that
+// goes back furthest, so the chart starts at the earliest purchase.
+func SaveMerge(coin *models.Coin, others []models.Coin) error {
+	ids := []uuid.UUID{coin.ID}
+	for _, other := range others {
+		ids = append(ids, other.ID)
+	}
+
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var earliest models.PriceHistory
+		err := tx.Where("coin_id IN ?", ids).Order("recorded_at ASC").First(&earliest).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && earliest.CoinID != coin.ID {
+			if err := tx.Where("coin_id = ?", coin.ID).Delete(&models.PriceHistory{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.PriceHistory{}).Where("coin_id = ?", earliest.CoinID).
+				Update("coin_id", coin.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("coin_id IN ?", ids[1:]).Delete(&models.PriceHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids[1:]).Delete(&models.Coin{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(coin).Error
+	})
+}
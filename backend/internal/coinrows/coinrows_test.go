@@ -0,0 +1,138 @@
+package coinrows
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+func TestSplit(t *testing.T) {
+	coin := models.Coin{
+		ID:             uuid.New(),
+		Number:         12,
+		Quantity:       3,
+		PurchasePrice:  30,
+		BuyersPremium:  10,
+		ShippingCost:   1,
+		SalesTax:       0.02,
+		PCGSCertNumber: "12345678",
+		ImageURL:       "/uploads/slab.jpg",
+		Watched:        true,
+	}
+	before := valuation.AllInCost(coin)
+
+	parts, err := Split(&coin, []int{1})
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(parts) != 1 || coin.Quantity != 2 || parts[0].Quantity != 1 {
+		t.Fatalf("quantities = %d + %v, want 2 + [1]", coin.Quantity, parts)
+	}
+	part := parts[0]
+	if part.ID != uuid.Nil || part.Number != 0 || part.PCGSCertNumber != "" || part.ImageURL != "" || part.Watched {
+		t.Errorf("new row kept the coin's identity, cert, photos or alerts: %+v", part)
+	}
+	if part.PurchasePrice != 30 {
+		t.Errorf("new row's purchase price = %.2f, want 30 per coin", part.PurchasePrice)
+	}
+	if coin.BuyersPremium != 6.67 || part.BuyersPremium != 3.33 {
+		t.Errorf("premium split %.2f / %.2f, want 6.67 / 3.33", coin.BuyersPremium, part.BuyersPremium)
+	}
+	if after := valuation.AllInCost(coin) + valuation.AllInCost(part); math.Abs(after-before) > 0.001 {
+		t.Errorf("all-in cost after split = %.2f, want %.2f", after, before)
+	}
+}
+
+func TestSplitRejects(t *testing.T) {
+	tests := []struct {
+		quantity   int
+		quantities []int
+	}{
+		{1, []int{1}},
+		{3, nil},
+		{3, []int{0}},
+		{3, []int{3}},
+		{3, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		coin := models.Coin{Quantity: tt.quantity}
+		if _, err := Split(&coin, tt.quantities); err == nil {
+			t.Errorf("Split(quantity %d, %v) succeeded, want an error", tt.quantity, tt.quantities)
+		}
+		if coin.Quantity != tt.quantity {
+			t.Errorf("rejected split changed the quantity to %d", coin.Quantity)
+		}
+	}
+}
+
+func TestMergeable(t *testing.T) {
+	portfolio := uuid.New()
+	coin := models.Coin{ID: uuid.New(), PortfolioID: portfolio, CoinType: "Morgan Dollar", Year: 1921, Toning: []string{"rainbow", "crescent"}}
+	same := coin
+	same.ID = uuid.New()
+	same.CoinType = "morgan dollar"
+	same.Toning = []string{"crescent", "rainbow"}
+	if err := Mergeable(coin, same); err != nil {
+		t.Errorf("Mergeable(identical) = %v, want nil", err)
+	}
+
+	if err := Mergeable(coin, coin); err == nil {
+		t.Error("a coin was mergeable with itself")
+	}
+	otherYear := same
+	otherYear.Year = 1922
+	if err := Mergeable(coin, otherYear); err == nil {
+		t.Error("coins of different years were mergeable")
+	}
+	certified := same
+	certified.PCGSCertNumber = "12345678"
+	if err := Mergeable(coin, certified); err == nil {
+		t.Error("a certified coin was mergeable")
+	}
+	lot := uuid.New()
+	inLot := same
+	inLot.LotID = &lot
+	if err := Mergeable(coin, inLot); err == nil {
+		t.Error("coins from different lots were mergeable")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	early := time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	coin := models.Coin{Quantity: 1, PurchasePrice: 40, ShippingCost: 5, NumismaticValue: 60, PurchaseDate: &late, Notes: "From the show", Story: "Bought from *Bob*"}
+	others := []models.Coin{
+		{Quantity: 3, PurchasePrice: 20, ShippingCost: 7, NumismaticValue: 50, PurchaseDate: &early, ImageURL: "/uploads/roll.jpg", Notes: "Roll find", Story: "Bought from *Bob*"},
+	}
+	before := valuation.AllInCost(coin) + valuation.AllInCost(others[0])
+
+	Merge(&coin, others)
+	if coin.Quantity != 4 {
+		t.Errorf("quantity = %d, want 4", coin.Quantity)
+	}
+	if coin.PurchasePrice != 25 || coin.ShippingCost != 12 {
+		t.Errorf("purchase price %.2f, shipping %.2f, want 25 and 12", coin.PurchasePrice, coin.ShippingCost)
+	}
+	if after := valuation.AllInCost(coin); math.Abs(after-before) > 0.001 {
+		t.Errorf("all-in cost after merge = %.2f, want %.2f", after, before)
+	}
+	if coin.NumismaticValue != 52.5 {
+		t.Errorf("numismatic value = %.2f, want 52.50", coin.NumismaticValue)
+	}
+	if !coin.PurchaseDate.Equal(early) {
+		t.Errorf("purchase date = %v, want the earliest", coin.PurchaseDate)
+	}
+	if coin.ImageURL != "/uploads/roll.jpg" {
+		t.Errorf("image = %q, want the merged row's photo", coin.ImageURL)
+	}
+	if coin.Notes != "From the show\n\nRoll find" {
+		t.Errorf("notes = %q", coin.Notes)
+	}
+	if coin.Story != "Bought from *Bob*" {
+		t.Errorf("story = %q, want the shared story once", coin.Story)
+	}
+}
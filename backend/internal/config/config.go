@@ -0,0 +1,90 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// String returns the value of key, or def when unset
+func String(key, def string) string {
+	if value, ok := Lookup(key); ok {
+		return value
+	}
+	return def
+}
+
+// Int64 returns key parsed as an integer, or def when unset or invalid
+func Int64(key string, def int64) int64 {
+	if value, err := strconv.ParseInt(String(key, ""), 10, 64); err == nil {
+		return value
+	}
+	return def
+}
+
+// Bool returns key parsed as a boolean, or def when unset or invalid
+func Bool(key string, def bool) bool {
+	if value, err := strconv.ParseBool(String(key, "")); err == nil {
+		return value
+	}
+	return def
+}
+
+// Duration returns key parsed with time.ParseDuration, or def when unset or invalid
+func Duration(key string, def time.Duration) time.Duration {
+	if value, err := time.ParseDuration(String(key, "")); err == nil && value > 0 {
+		return value
+	}
+	return def
+}
+
+// Bytes returns key parsed as a size such as "512KB", "10MB" or "1GB"
+// (binary multiples), or def when unset or invalid
+func Bytes(key string, def int64) int64 {
+	value := strings.ToUpper(String(key, ""))
+	if value == "" {
+		return def
+	}
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.size
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return def
+	}
+	return n * multiplier
+}
+
+// MockMode reports whether MOCK_EXTERNAL_APIS is set, in which case external
+// services (PCGS, spot price providers) are replaced by local fixtures
+func MockMode() bool {
+	return Bool("MOCK_EXTERNAL_APIS", false)
+}
+
+// Development reports whether APP_ENV is development
+func Development() bool {
+	return String("APP_ENV", "production") == "development"
+}
+
+// MockOAuth reports whether MOCK_OAUTH is set, in which case the sign-in
+// providers are replaced by a fake one that asks for no credentials. It is
+// only honoured in development; the server refuses to start with it
+// elsewhere.
+func MockOAuth() bool {
+	return Bool("MOCK_OAUTH", false) && Development()
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Settings are looked up in order from:
+//
+//  1. the environment variable itself, e.g. JWT_SECRET
+//  2. a file named by the variable with a _FILE suffix, e.g.
+//     JWT_SECRET_FILE=/run/secrets/jwt_secret (Kubernetes and Docker secrets)
+//  3. the YAML file loaded with LoadFile, e.g. --config /etc/aureus.yaml
+var (
+	mu          sync.RWMutex
+	fileValues  = map[string]string{}
+	secretFiles = map[string]string{} // contents of *_FILE secrets by path
+)
+
+// Lookup returns the value of key and whether it is set in any source
+func Lookup(key string) (string, bool) {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value, true
+	}
+
+	if path := strings.TrimSpace(os.Getenv(key + "_FILE")); path != "" {
+		if value, err := readSecretFile(path); err == nil && value != "" {
+			return value, true
+		}
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	value, ok := fileValues[key]
+	return value, ok && value != ""
+}
+
+// readSecretFile reads a mounted secret once; trailing newlines written by
+// editors and `echo` are trimmed
+func readSecretFile(path string) (string, error) {
+	mu.RLock()
+	value, ok := secretFiles[path]
+	mu.RUnlock()
+	if ok {
+		return value, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value = strings.TrimSpace(string(data))
+
+	mu.Lock()
+	secretFiles[path] = value
+	mu.Unlock()
+	return value, nil
+}
+
+// LoadFile reads settings from a YAML file of environment variable names to
+// values. Keys are case-insensitive and may be nested, in which case the path
+// is joined with underscores:
+//
+//	database_url: postgres://...
+//	pcgs:
+//	  api_key: ...          # PCGS_API_KEY
+//	max_json_body_size: 1MB
+//
+// Environment variables and *_FILE secrets take precedence over the file.
+func LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", doc, values)
+
+	mu.Lock()
+	fileValues = values
+	mu.Unlock()
+	return nil
+}
+
+func flatten(prefix string, doc map[string]any, values map[string]string) {
+	for k, v := range doc {
+		key := strings.ToUpper(k)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, values)
+		case []any:
+			// Lists such as admin_emails become comma-separated values
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+}
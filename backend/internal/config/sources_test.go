@@ -0,0 +1,43 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookupPrecedence(t *testing.T) {
+	dir := t.TempDir()
+
+	configPath := filepath.Join(dir, "aureus.yaml")
+	os.WriteFile(configPath, []byte("jwt_secret: from-config\npcgs:\n  api_key: pcgs-from-config\nadmin_emails: [a@example.com, b@example.com]\nmax_json_body_size: 2MB\n"), 0o600)
+	if err := LoadFile(configPath); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fileValues = map[string]string{} })
+
+	secretPath := filepath.Join(dir, "jwt_secret")
+	os.WriteFile(secretPath, []byte("from-file\n"), 0o600)
+
+	if got := String("PCGS_API_KEY", ""); got != "pcgs-from-config" {
+		t.Errorf("nested config key = %q", got)
+	}
+	if got := String("ADMIN_EMAILS", ""); got != "a@example.com,b@example.com" {
+		t.Errorf("list config key = %q", got)
+	}
+	if got := Bytes("MAX_JSON_BODY_SIZE", 0); got != 2<<20 {
+		t.Errorf("MAX_JSON_BODY_SIZE = %d", got)
+	}
+
+	if got := String("JWT_SECRET", ""); got != "from-config" {
+		t.Errorf("config only: JWT_SECRET = %q", got)
+	}
+	t.Setenv("JWT_SECRET_FILE", secretPath)
+	if got := String("JWT_SECRET", ""); got != "from-file" {
+		t.Errorf("_FILE over config: JWT_SECRET = %q", got)
+	}
+	t.Setenv("JWT_SECRET", "from-env")
+	if got := String("JWT_SECRET", ""); got != "from-env" {
+		t.Errorf("env over _FILE: JWT_SECRET = %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ciphertextPrefix marks values written by Encrypt; the key ID follows so
+// values encrypted under a previous key can still be opened after rotation
+const ciphertextPrefix = "enc:v1:"
+
+var (
+	ErrKeyMissing = errors.New("SECRETS_KEY is not configured")
+	ErrInvalidKey = errors.New("encryption key must be 32 bytes, base64 encoded")
+	ErrUnknownKey = errors.New("ciphertext was encrypted with an unknown key")
+	ErrCiphertext = errors.New("malformed ciphertext")
+)
+
+var (
+	providerMu     sync.RWMutex
+	activeProvider KeyProvider = NewEnvKeyProvider()
+)
+
+// KeyProvider supplies the current encryption key and any previous keys
+// that are still accepted for decryption
+type KeyProvider interface {
+	CurrentKey() ([]byte, error)
+	PreviousKeys() ([][]byte, error)
+}
+
+// SetKeyProvider replaces the provider used by Encrypt and Decrypt
+func SetKeyProvider(p KeyProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	activeProvider = p
+}
+
+func provider() KeyProvider {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return activeProvider
+}
+
+// Enabled reports whether an encryption key is available
+func Enabled() bool {
+	_, err := provider().CurrentKey()
+	return err == nil
+}
+
+// keyID is a short, non-secret fingerprint of a key
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext with AES-256-GCM under the current key
+func Encrypt(plaintext string) (string, error) {
+	key, err := provider().CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return ciphertextPrefix + keyID(key) + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt. Values without a key ID (written
+// before key IDs were recorded) are tried against every known key.
+func Decrypt(ciphertext string) (string, error) {
+	current, err := provider().CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	previous, err := provider().PreviousKeys()
+	if err != nil {
+		return "", err
+	}
+	keys := append([][]byte{current}, previous...)
+
+	encoded := ciphertext
+	if strings.HasPrefix(ciphertext, ciphertextPrefix) {
+		parts := strings.SplitN(strings.TrimPrefix(ciphertext, ciphertextPrefix), ":", 2)
+		if len(parts) != 2 {
+			return "", ErrCiphertext
+		}
+		var matched [][]byte
+		for _, key := range keys {
+			if keyID(key) == parts[0] {
+				matched = append(matched, key)
+			}
+		}
+		if len(matched) == 0 {
+			return "", ErrUnknownKey
+		}
+		keys, encoded = matched, parts[1]
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCiphertext
+	}
+
+	for _, key := range keys {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(data) < gcm.NonceSize() {
+			return "", ErrCiphertext
+		}
+		plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+		if err == nil {
+			return string(plaintext), nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to decrypt: %w", ErrUnknownKey)
+}
+
+// NeedsRotation reports whether a ciphertext was not written under the current key
+func NeedsRotation(ciphertext string) bool {
+	key, err := provider().CurrentKey()
+	if err != nil {
+		return false
+	}
+	return !strings.HasPrefix(ciphertext, ciphertextPrefix+keyID(key)+":")
+}
@@ -0,0 +1,166 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// useEnvKeys points Encrypt and Decrypt at SECRETS_KEY and
+// SECRETS_KEY_PREVIOUS for the test
+func useEnvKeys(t *testing.T, current, previous string) {
+	t.Helper()
+	t.Setenv("SECRETS_KEY", current)
+	t.Setenv("SECRETS_KEY_PREVIOUS", previous)
+	SetKeyProvider(NewEnvKeyProvider())
+	t.Cleanup(func() { SetKeyProvider(NewEnvKeyProvider()) })
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	useEnvKeys(t, newKey(t), "")
+
+	sealed, err := Encrypt("pcgs-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sealed, ciphertextPrefix) || strings.Contains(sealed, "pcgs-secret") {
+		t.Fatalf("sealed = %q", sealed)
+	}
+	again, _ := Encrypt("pcgs-secret")
+	if again == sealed {
+		t.Error("two encryptions of the same value are identical")
+	}
+	if got, err := Decrypt(sealed); err != nil || got != "pcgs-secret" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if NeedsRotation(sealed) {
+		t.Error("value under the current key needs rotation")
+	}
+}
+
+func TestDecryptAfterRotation(t *testing.T) {
+	old := newKey(t)
+	useEnvKeys(t, old, "")
+	sealed, err := Encrypt("pcgs-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	useEnvKeys(t, newKey(t), old)
+	if got, err := Decrypt(sealed); err != nil || got != "pcgs-secret" {
+		t.Errorf("Decrypt under a previous key = %q, %v", got, err)
+	}
+	if !NeedsRotation(sealed) {
+		t.Error("value under a previous key doesn't need rotation")
+	}
+
+	useEnvKeys(t, newKey(t), "")
+	if _, err := Decrypt(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt with the key dropped = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	useEnvKeys(t, newKey(t), "")
+	sealed, err := Encrypt("pcgs-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := strings.LastIndex(sealed, ":")
+	data, _ := base64.StdEncoding.DecodeString(sealed[i+1:])
+
+	for name, offset := range map[string]int{"nonce": 0, "ciphertext": len(data) - 1} {
+		tampered := bytes.Clone(data)
+		tampered[offset] ^= 1
+		value := sealed[:i+1] + base64.StdEncoding.EncodeToString(tampered)
+		if _, err := Decrypt(value); err == nil {
+			t.Errorf("tampered %s decrypted", name)
+		}
+	}
+	if _, err := Decrypt(sealed[:i+1] + "AAAA"); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("truncated ciphertext = %v, want ErrCiphertext", err)
+	}
+	if _, err := Decrypt(ciphertextPrefix + "no-key-id"); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("value without a key ID separator = %v, want ErrCiphertext", err)
+	}
+}
+
+func TestDecryptLegacyValues(t *testing.T) {
+	key := newKey(t)
+	useEnvKeys(t, key, "")
+	sealed, err := Encrypt("pcgs-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Values written before key IDs were recorded are the bare base64
+	legacy := sealed[strings.LastIndex(sealed, ":")+1:]
+	if got, err := Decrypt(legacy); err != nil || got != "pcgs-secret" {
+		t.Errorf("Decrypt legacy value = %q, %v", got, err)
+	}
+	if !NeedsRotation(legacy) {
+		t.Error("legacy value doesn't need rotation")
+	}
+
+	if _, err := Decrypt("plain-api-key"); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("Decrypt plaintext = %v, want ErrCiphertext", err)
+	}
+}
+
+func TestMissingAndInvalidKeys(t *testing.T) {
+	useEnvKeys(t, "", "")
+	if Enabled() {
+		t.Error("enabled without a key")
+	}
+	if _, err := Encrypt("x"); !errors.Is(err, ErrKeyMissing) {
+		t.Errorf("Encrypt without a key = %v, want ErrKeyMissing", err)
+	}
+
+	useEnvKeys(t, base64.StdEncoding.EncodeToString([]byte("too short")), "")
+	if _, err := Encrypt("x"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Encrypt with a short key = %v, want ErrInvalidKey", err)
+	}
+
+	useEnvKeys(t, newKey(t), "not base64!")
+	if _, err := Decrypt("AAAA"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Decrypt with a bad previous key = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestCommandKeyProvider(t *testing.T) {
+	key := newKey(t)
+	p := NewCommandKeyProvider("echo " + key)
+	got, err := p.CurrentKey()
+	if err != nil || base64.StdEncoding.EncodeToString(got) != key {
+		t.Errorf("CurrentKey = %x, %v", got, err)
+	}
+
+	failing := NewCommandKeyProvider("exit 3")
+	if _, err := failing.CurrentKey(); err == nil || !strings.Contains(err.Error(), "key command failed") {
+		t.Errorf("failing command = %v", err)
+	}
+	SetKeyProvider(failing)
+	t.Cleanup(func() { SetKeyProvider(NewEnvKeyProvider()) })
+	if Enabled() {
+		t.Error("enabled with a failing key command")
+	}
+	if _, err := Encrypt("x"); err == nil {
+		t.Error("Encrypt succeeded with a failing key command")
+	}
+
+	if _, err := NewCommandKeyProvider("echo not-a-key").CurrentKey(); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("command printing a bad key = %v, want ErrInvalidKey", err)
+	}
+}
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+func decodeKeyList(list string) ([][]byte, error) {
+	var keys [][]byte
+	for _, encoded := range strings.Split(list, ",") {
+		if strings.TrimSpace(encoded) == "" {
+			continue
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// EnvKeyProvider reads keys from SECRETS_KEY and SECRETS_KEY_PREVIOUS
+// (comma-separated) on every call, so rotating keys only needs a restart
+type EnvKeyProvider struct{}
+
+func NewEnvKeyProvider() *EnvKeyProvider {
+	return &EnvKeyProvider{}
+}
+
+func (p *EnvKeyProvider) CurrentKey() ([]byte, error) {
+	encoded := config.String("SECRETS_KEY", "")
+	if encoded == "" {
+		return nil, ErrKeyMissing
+	}
+	return decodeKey(encoded)
+}
+
+func (p *EnvKeyProvider) PreviousKeys() ([][]byte, error) {
+	return decodeKeyList(config.String("SECRETS_KEY_PREVIOUS", ""))
+}
+
+// CommandKeyProvider obtains the data key by running an external command,
+// such as a KMS or Vault CLI call that decrypts a wrapped key and prints it
+// base64 encoded. The result is cached for the life of the process.
+type CommandKeyProvider struct {
+	Command string
+
+	once sync.Once
+	key  []byte
+	err  error
+}
+
+func NewCommandKeyProvider(command string) *CommandKeyProvider {
+	return &CommandKeyProvider{Command: command}
+}
+
+func (p *CommandKeyProvider) CurrentKey() ([]byte, error) {
+	p.once.Do(func() {
+		output, err := exec.Command("sh", "-c", p.Command).Output()
+		if err != nil {
+			p.err = fmt.Errorf("key command failed: %w", err)
+			return
+		}
+		p.key, p.err = decodeKey(string(output))
+	})
+	return p.key, p.err
+}
+
+func (p *CommandKeyProvider) PreviousKeys() ([][]byte, error) {
+	return decodeKeyList(config.String("SECRETS_KEY_PREVIOUS", ""))
+}
+
+// ConfigureFromEnv selects the key provider: SECRETS_KEY_COMMAND (KMS) when
+// set, otherwise SECRETS_KEY
+func ConfigureFromEnv() {
+	if command := config.String("SECRETS_KEY_COMMAND", ""); command != "" {
+		SetKeyProvider(NewCommandKeyProvider(command))
+		return
+	}
+	SetKeyProvider(NewEnvKeyProvider())
+}
@@ -2,18 +2,25 @@ package database
 
 import (
 	"log"
-	"os"
+	"strings"
+	"time"
 
+	"github.com/evansminotwood/aureus/internal/coincode"
+	"github.com/evansminotwood/aureus/internal/config"
 	"github.com/evansminotwood/aureus/internal/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 var DB *gorm.DB
 
+// hasReplicas is set when DATABASE_REPLICA_URLS configured read replicas
+var hasReplicas bool
+
 func Connect() error {
-	dsn := os.Getenv("DATABASE_URL")
+	dsn := config.String("DATABASE_URL", "")
 	if dsn == "" {
 		log.Fatal("DATABASE_URL not set in environment")
 	}
@@ -26,28 +33,159 @@ func Connect() error {
 		return err
 	}
 
+	if err := registerReplicas(); err != nil {
+		return err
+	}
+
 	log.Println("Database connected successfully")
 	return nil
 }
 
+// registerReplicas routes reads made through GetReadDB to the replicas in
+// DATABASE_REPLICA_URLS (comma-separated), picking one at random per query
+func registerReplicas() error {
+	var replicas []gorm.Dialector
+	for _, replicaDSN := range strings.Split(config.String("DATABASE_REPLICA_URLS", ""), ",") {
+		if replicaDSN = strings.TrimSpace(replicaDSN); replicaDSN != "" {
+			replicas = append(replicas, postgres.Open(replicaDSN))
+		}
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	}).
+		SetMaxOpenConns(int(config.Int64("DATABASE_REPLICA_MAX_OPEN_CONNS", 0))).
+		SetConnMaxLifetime(config.Duration("DATABASE_REPLICA_CONN_MAX_LIFETIME", time.Hour))
+	if err := DB.Use(resolver); err != nil {
+		return err
+	}
+
+	hasReplicas = true
+	log.Printf("Routing read-only queries to %d replica(s)", len(replicas))
+	return nil
+}
+
 func Migrate() error {
 	log.Println("Running database migrations...")
 
+	// Before valuation bases, current_value was melt except for classic gold
+	addingBasis := !DB.Migrator().HasColumn(&models.Portfolio{}, "valuation_basis")
+
+	// Coin numbers, for their short codes, are shared with archived coins
+	// so a coin keeps its code through disposal and restore
+	if err := DB.Exec("CREATE SEQUENCE IF NOT EXISTS " + coincode.Sequence).Error; err != nil {
+		return err
+	}
+
 	err := DB.AutoMigrate(
+		&models.Tenant{},
 		&models.User{},
+		&models.InviteCode{},
+		&models.EmailChangeRequest{},
 		&models.Portfolio{},
+		&models.PortfolioMember{},
 		&models.Coin{},
 		&models.PriceHistory{},
+		&models.PortfolioAlert{},
+		&models.Notification{},
+		&models.NotificationSettings{},
+		&models.UserSettings{},
+		&models.Job{},
+		&models.AuctionComparable{},
+		&models.CoinImage{},
+		&models.SpotAlert{},
+		&models.SpotPriceHistory{},
+		&models.SpotPriceClose{},
+		&models.FXRate{},
+		&models.Lot{},
+		&models.CompositionOverride{},
+		&models.FallbackPrice{},
+		&models.RegistrySet{},
+		&models.CoinTransfer{},
+		&models.EmergencyContact{},
+		&models.ArchivedCoin{},
+		&models.CertWatchEntry{},
+		&models.CoinAlert{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.LoginThrottle{},
+		&models.LoginFailure{},
+		&models.AuditLog{},
+		&models.PasswordResetToken{},
+		&models.OAuthIdentity{},
+		&models.OAuthLoginCode{},
+		&models.APIKey{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_coins_number ON coins (number)").Error; err != nil {
+		return err
+	}
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_archived_coins_number ON archived_coins (number)").Error; err != nil {
+		return err
+	}
+
+	if err := partitionPriceHistory(); err != nil {
+		return err
+	}
+	if err := EnsurePriceHistoryPartitions(time.Now()); err != nil {
+		return err
+	}
+
+	if addingBasis {
+		// Existing portfolios get the default "max" basis
+		if err := DB.Exec("UPDATE coins SET current_value = GREATEST(melt_value, numismatic_value) WHERE GREATEST(melt_value, numismatic_value) > 0").Error; err != nil {
+			return err
+		}
+	}
+
 	log.Println("Database migrations completed")
 	return nil
 }
 
+// PromoteAdmins grants admin rights to existing users listed in ADMIN_EMAILS
+func PromoteAdmins() error {
+	var emails []string
+	for _, email := range strings.Split(config.String("ADMIN_EMAILS", ""), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			emails = append(emails, strings.ToLower(email))
+		}
+	}
+	if len(emails) == 0 {
+		return nil
+	}
+
+	return DB.Model(&models.User{}).
+		Where("LOWER(email) IN ?", emails).
+		Update("is_admin", true).Error
+}
+
+// EnsureTenant creates the tenant with the given slug if it does not exist,
+// so a new multi-tenant deployment has somewhere for its operators to sign up
+func EnsureTenant(slug, name string) error {
+	tenant := models.Tenant{Slug: slug, Name: name}
+	return GetDB().Where(models.Tenant{Slug: slug}).FirstOrCreate(&tenant).Error
+}
+
+// GetDB returns the primary database. Use it for writes and for reads that
+// must see the request's own writes.
 func GetDB() *gorm.DB {
+	if !hasReplicas {
+		return DB
+	}
+	return DB.Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
+// GetReadDB returns a handle whose reads go to a replica when replicas are
+// configured. Use it for list, stats and history queries that can tolerate
+// replication lag; writes and transactions still go to the primary.
+func GetReadDB() *gorm.DB {
 	return DB
 }
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/models"
+	"gorm.io/gorm"
+)
+
+// price_histories is range partitioned by month on recorded_at. Each month
+// has its own partition, named price_histories_yYYYYmMM, and snapshots
+// outside every monthly partition, such as ones dated at a purchase years
+// ago, land in price_histories_default until their month is split out.
+const (
+	priceHistoryTable   = "price_histories"
+	priceHistoryDefault = "price_histories_default"
+)
+
+const defaultPartitionsAhead = 3
+
+// monthStart returns the first instant of t's month in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// partitionName returns the name of the partition holding month
+func partitionName(month time.Time) string {
+	return fmt.Sprintf("%s_y%04dm%02d", priceHistoryTable, month.Year(), month.Month())
+}
+
+// partitionMonth returns the month a partition named by partitionName holds,
+// or false for any other name
+func partitionMonth(name string) (time.Time, bool) {
+	var year, month int
+	if _, err := fmt.Sscanf(name, priceHistoryTable+"_y%4dm%2d", &year, &month); err != nil || month < 1 || month > 12 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// monthsBetween returns the start of every month from from's through to's
+func monthsBetween(from, to time.Time) []time.Time {
+	var months []time.Time
+	for month := monthStart(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		months = append(months, month)
+	}
+	return months
+}
+
+// isPartitioned reports whether price_histories is already partitioned
+func isPartitioned(db *gorm.DB) (bool, error) {
+	var count int64
+	err := db.Raw("SELECT count(*) FROM pg_partitioned_table WHERE partrelid = to_regclass(?)", priceHistoryTable).Scan(&count).Error
+	return count > 0, err
+}
+
+// partitionPriceHistory turns a plain price_histories table, as created by
+// AutoMigrate or left by an older version, into a partitioned one. The old
+// table becomes the default partition as is, so this doesn't copy any rows;
+// EnsurePriceHistoryPartitions then splits its months out.
+func partitionPriceHistory() error {
+	partitioned, err := isPartitioned(DB)
+	if err != nil || partitioned {
+		return err
+	}
+	log.Println("Partitioning price_histories by month...")
+
+	var indexes []string
+	if err := DB.Raw("SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ? AND indexname <> ?",
+		priceHistoryTable, priceHistoryTable+"_pkey").Scan(&indexes).Error; err != nil {
+		return err
+	}
+
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// Index names are unique per schema, so the old table's move aside
+		// for the parent's
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", priceHistoryTable, priceHistoryDefault),
+			fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s_pkey TO %s_pkey", priceHistoryDefault, priceHistoryTable, priceHistoryDefault),
+			fmt.Sprintf("ALTER TABLE %s ALTER COLUMN recorded_at SET NOT NULL", priceHistoryDefault),
+		}
+		for _, index := range indexes {
+			statements = append(statements, fmt.Sprintf("ALTER INDEX %q RENAME TO %q", index, index+"_default"))
+		}
+		statements = append(statements,
+			fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS) PARTITION BY RANGE (recorded_at)", priceHistoryTable, priceHistoryDefault),
+			// The partition key has to be part of the primary key
+			fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (id, recorded_at)", priceHistoryTable),
+			fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s DEFAULT", priceHistoryTable, priceHistoryDefault),
+		)
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Recreate the indexes on the parent; Postgres adopts the default
+	// partition's matching ones instead of building them again
+	return DB.AutoMigrate(&models.PriceHistory{})
+}
+
+// EnsurePriceHistoryPartitions creates the partitions of the current month
+// and the next PRICE_HISTORY_PARTITIONS_AHEAD (default 3), and moves every
+// month with snapshots in the default partition into its own
+func EnsurePriceHistoryPartitions(now time.Time) error {
+	db := GetDB()
+
+	var months []time.Time
+	if err := db.Raw(fmt.Sprintf("SELECT DISTINCT date_trunc('month', recorded_at AT TIME ZONE 'UTC') FROM %s", priceHistoryDefault)).
+		Scan(&months).Error; err != nil {
+		return err
+	}
+	ahead := int(config.Int64("PRICE_HISTORY_PARTITIONS_AHEAD", defaultPartitionsAhead))
+	months = append(months, monthsBetween(now, monthStart(now).AddDate(0, max(ahead, 0), 0))...)
+
+	var existing []string
+	if err := db.Raw("SELECT child.relname FROM pg_inherits JOIN pg_class child ON child.oid = pg_inherits.inhrelid WHERE pg_inherits.inhparent = to_regclass(?)",
+		priceHistoryTable).Scan(&existing).Error; err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		have[name] = true
+	}
+
+	for _, month := range months {
+		month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+		name := partitionName(month)
+		if have[name] {
+			continue
+		}
+		if err := createPartition(db, month); err != nil {
+			return fmt.Errorf("creating %s: %w", name, err)
+		}
+		have[name] = true
+	}
+	return nil
+}
+
+// createPartition adds month's partition, moving its snapshots out of the
+// default partition. Attaching a new partition fails while the default one
+// holds rows in its range, so the table is filled before it's attached.
+func createPartition(db *gorm.DB, month time.Time) error {
+	name := partitionName(month)
+	from, to := month.Format(time.RFC3339), month.AddDate(0, 1, 0).Format(time.RFC3339)
+	inRange := fmt.Sprintf("recorded_at >= '%s' AND recorded_at < '%s'", from, to)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS)", name, priceHistoryTable),
+			fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE %s", name, priceHistoryDefault, inRange),
+			fmt.Sprintf("DELETE FROM %s WHERE %s", priceHistoryDefault, inRange),
+			// Lets ATTACH skip scanning the new table
+			fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s_range CHECK (%s)", name, name, inRange),
+			fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')", priceHistoryTable, name, from, to),
+			fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s_range", name, name),
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PrunePriceHistory drops the monthly partitions that end on or before
+// before, and deletes older snapshots left in the default partition. It
+// returns how many partitions were dropped.
+func PrunePriceHistory(before time.Time) (int, error) {
+	db := GetDB()
+	cutoff := monthStart(before)
+
+	var existing []string
+	if err := db.Raw("SELECT child.relname FROM pg_inherits JOIN pg_class child ON child.oid = pg_inherits.inhrelid WHERE pg_inherits.inhparent = to_regclass(?) ORDER BY child.relname",
+		priceHistoryTable).Scan(&existing).Error; err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, name := range existing {
+		start, ok := partitionMonth(name)
+		if !ok || start.AddDate(0, 1, 0).After(cutoff) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf("DROP TABLE %s", name)).Error; err != nil {
+			return dropped, err
+		}
+		dropped++
+	}
+
+	err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE recorded_at < ?", priceHistoryDefault), cutoff).Error
+	return dropped, err
+}
+
+// MaintainPriceHistoryPartitions creates upcoming partitions and, when
+// PRICE_HISTORY_RETENTION_MONTHS is set, drops partitions older than that
+// many whole months
+func MaintainPriceHistoryPartitions(now time.Time) error {
+	if err := EnsurePriceHistoryPartitions(now); err != nil {
+		return err
+	}
+	retention := config.Int64("PRICE_HISTORY_RETENTION_MONTHS", 0)
+	if retention <= 0 {
+		return nil
+	}
+	dropped, err := PrunePriceHistory(monthStart(now).AddDate(0, -int(retention), 0))
+	if dropped > 0 {
+		log.Printf("Dropped %d price history partition(s) older than %d months", dropped, retention)
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionNameRoundTrips(t *testing.T) {
+	month := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	name := partitionName(month)
+	if name != "price_histories_y2026m03" {
+		t.Fatalf("partitionName = %q", name)
+	}
+	if got, ok := partitionMonth(name); !ok || !got.Equal(month) {
+		t.Errorf("partitionMonth(%q) = %v, %v", name, got, ok)
+	}
+	if _, ok := partitionMonth(priceHistoryDefault); ok {
+		t.Error("the default partition parsed as a month")
+	}
+}
+
+func TestMonthStartIsUTC(t *testing.T) {
+	// Still the 31st of March in New York, but April in UTC
+	ny := time.FixedZone("EDT", -4*60*60)
+	got := monthStart(time.Date(2026, time.March, 31, 22, 0, 0, 0, ny))
+	if want := time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("monthStart = %v, want %v", got, want)
+	}
+}
+
+func TestMonthsBetween(t *testing.T) {
+	from := time.Date(2026, time.November, 15, 0, 0, 0, 0, time.UTC)
+	months := monthsBetween(from, time.Date(2027, time.February, 1, 0, 0, 0, 0, time.UTC))
+	if len(months) != 4 || months[0].Month() != time.November || months[3].Month() != time.February {
+		t.Errorf("monthsBetween = %v, want November through February", months)
+	}
+}
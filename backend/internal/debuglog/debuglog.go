@@ -0,0 +1,115 @@
+// Package debuglog keeps the most recent API requests and outbound calls in
+// memory, with secrets and personal data redacted, so admins can debug an
+// instance without turning on verbose logging. It's off unless DEBUG_LOGGING
+// is set.
+package debuglog
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// Kinds of entries
+const (
+	KindRequest  = "request"  // an API request and its response
+	KindOutbound = "outbound" // a call to an external service
+	KindMessage  = "message"  // a debug message
+)
+
+const defaultSize = 200
+
+// MaxBodyBytes is how much of each body is kept; the rest is cut off
+const MaxBodyBytes = 4 << 10
+
+// Entry is one logged request, outbound call or message
+type Entry struct {
+	ID             int64             `json:"id"`
+	Kind           string            `json:"kind"`
+	Time           time.Time         `json:"time"`
+	Method         string            `json:"method,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Status         int               `json:"status,omitempty"`
+	DurationMS     float64           `json:"duration_ms,omitempty"`
+	UserID         string            `json:"user_id,omitempty"`
+	RequestHeaders map[string]string `json:"request_headers,omitempty"`
+	RequestBody    string            `json:"request_body,omitempty"`
+	ResponseBody   string            `json:"response_body,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	Error          string            `json:"error,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	entries []Entry // oldest first
+	lastID  int64
+)
+
+// Enabled reports whether debug logging is on (DEBUG_LOGGING)
+func Enabled() bool {
+	return config.Bool("DEBUG_LOGGING", false)
+}
+
+// Size is how many entries are kept (DEBUG_LOG_SIZE, default 200)
+func Size() int {
+	if size := config.Int64("DEBUG_LOG_SIZE", defaultSize); size > 0 {
+		return int(size)
+	}
+	return defaultSize
+}
+
+// Record adds an entry, dropping the oldest once Size are kept. It does
+// nothing while debug logging is off.
+func Record(e Entry) {
+	if !Enabled() {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	lastID++
+	e.ID = lastID
+
+	entries = append(entries, e)
+	if size := Size(); len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+}
+
+// Printf records a debug message, and logs it too
+func Printf(format string, args ...interface{}) {
+	if !Enabled() {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+	log.Print("[debug] " + message)
+	Record(Entry{Kind: KindMessage, Message: message})
+}
+
+// Entries returns up to limit entries, newest first; limit 0 returns all
+func Entries(limit int) []Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make([]Entry, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, entries[i])
+	}
+	return result
+}
+
+// Clear drops every entry
+func Clear() {
+	mu.Lock()
+	defer mu.Unlock()
+	entries = nil
+}
@@ -0,0 +1,75 @@
+package debuglog
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	body := `{"email":"jane.doe@example.com","password":"hunter2","user":{"pcgs_api_key":"abc","name":"Jane"},"tokens":[{"access_token":"xyz"}]}`
+	got := RedactBody([]byte(body), "application/json; charset=utf-8")
+
+	for _, secret := range []string{"hunter2", "abc", "xyz", "jane.doe"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("redacted body %s still contains %q", got, secret)
+		}
+	}
+	if !strings.Contains(got, `"j***@example.com"`) || !strings.Contains(got, `"Jane"`) {
+		t.Errorf("redacted body %s should keep masked emails and other fields", got)
+	}
+
+	if got := RedactBody([]byte{0xff, 0xd8, 0xff}, "image/jpeg"); got != "[3 bytes of image/jpeg]" {
+		t.Errorf("binary body = %q", got)
+	}
+}
+
+func TestRedactBodyHidesNewKeysAndLinkTickets(t *testing.T) {
+	// A created API key or display token
+	created := `{"id":"k1","name":"Shop display","scopes":["display"],"key":"aur_live_secret"}`
+	if got := RedactBody([]byte(created), "application/json"); strings.Contains(got, "aur_live_secret") || !strings.Contains(got, "Shop display") {
+		t.Errorf("redacted key %s", got)
+	}
+
+	// The URL that links a provider to the account
+	link := `{"url":"https://api.example.com/api/v1/auth/oauth/google/start?link=ticket123"}`
+	if got := RedactBody([]byte(link), "application/json"); strings.Contains(got, "ticket123") || !strings.Contains(got, "/auth/oauth/google/start") {
+		t.Errorf("redacted link %s", got)
+	}
+
+	u, _ := url.Parse("https://api.example.com/api/v1/auth/oauth/google/start?link=ticket123")
+	if got := RedactURL(u); strings.Contains(got, "ticket123") {
+		t.Errorf("url = %s", got)
+	}
+}
+
+func TestRedactHeadersAndURL(t *testing.T) {
+	headers := RedactHeaders(http.Header{"Authorization": {"Bearer abc"}, "Accept": {"application/json"}})
+	if headers["Authorization"] != Redacted || headers["Accept"] != "application/json" {
+		t.Errorf("headers = %v", headers)
+	}
+
+	u, _ := url.Parse("https://example.com/confirm?token=abc&page=2")
+	if got := RedactURL(u); strings.Contains(got, "abc") || !strings.Contains(got, "page=2") {
+		t.Errorf("url = %s", got)
+	}
+}
+
+func TestBufferKeepsNewest(t *testing.T) {
+	t.Setenv("DEBUG_LOGGING", "true")
+	t.Setenv("DEBUG_LOG_SIZE", "3")
+	Clear()
+	defer Clear()
+
+	for _, message := range []string{"a", "b", "c", "d"} {
+		Record(Entry{Kind: KindMessage, Message: message})
+	}
+	got := Entries(0)
+	if len(got) != 3 || got[0].Message != "d" || got[2].Message != "b" {
+		t.Errorf("entries = %+v, want d, c, b", got)
+	}
+	if len(Entries(1)) != 1 {
+		t.Error("limit should cap the entries returned")
+	}
+}
@@ -0,0 +1,137 @@
+package debuglog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values
+const Redacted = "[REDACTED]"
+
+// Field names whose values are always redacted, matched after lowercasing
+// and dropping "_" and "-". Names containing one of secretFragments are too.
+// "key" is a new API key or display token, and "link" an OAuth link ticket.
+var (
+	secretNames     = map[string]bool{"authorization": true, "cookie": true, "setcookie": true, "auth": true, "p256dh": true, "code": true, "phone": true, "phonenumber": true, "chatid": true, "key": true, "link": true}
+	secretFragments = []string{"password", "token", "secret", "apikey"}
+)
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+
+func secretName(name string) bool {
+	name = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(name))
+	if secretNames[name] {
+		return true
+	}
+	for _, fragment := range secretFragments {
+		if strings.Contains(name, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskEmails keeps the first letter and domain of email addresses in s,
+// e.g. "j***@example.com"
+func maskEmails(s string) string {
+	return emailPattern.ReplaceAllString(s, "$1***@$2")
+}
+
+// RedactHeaders flattens headers, redacting credentials
+func RedactHeaders(h http.Header) map[string]string {
+	result := make(map[string]string, len(h))
+	for name, values := range h {
+		if secretName(name) {
+			result[name] = Redacted
+			continue
+		}
+		result[name] = maskEmails(strings.Join(values, ", "))
+	}
+	return result
+}
+
+// RedactURL returns u with secret query parameters redacted and emails masked
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for name := range query {
+		if secretName(name) {
+			query[name] = []string{Redacted}
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return maskEmails(redacted.String())
+}
+
+// RedactBody returns a body as it should be logged: JSON and form bodies with
+// secret fields redacted and emails masked, other text as is and binary
+// bodies as their size only. Long bodies are cut off at MaxBodyBytes.
+func RedactBody(data []byte, contentType string) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	var body string
+	switch {
+	case strings.Contains(contentType, "json"):
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return fmt.Sprintf("[%d bytes of invalid JSON]", len(data))
+		}
+		redacted, _ := json.Marshal(redactValue(value))
+		body = string(redacted)
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			return fmt.Sprintf("[%d bytes of invalid form data]", len(data))
+		}
+		for name := range form {
+			if secretName(name) {
+				form[name] = []string{Redacted}
+			}
+		}
+		body = maskEmails(form.Encode())
+	case strings.HasPrefix(contentType, "text/"):
+		body = maskEmails(string(data))
+	default:
+		return fmt.Sprintf("[%d bytes of %s]", len(data), contentType)
+	}
+
+	if len(body) > MaxBodyBytes {
+		body = body[:MaxBodyBytes] + fmt.Sprintf("... [%d bytes cut]", len(body)-MaxBodyBytes)
+	}
+	return body
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if secretName(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(inner)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+		return v
+	case string:
+		// URLs in bodies, e.g. the one returned for linking a provider,
+		// can carry secrets in their query
+		if strings.HasPrefix(v, "http") && strings.Contains(v, "?") {
+			if u, err := url.Parse(v); err == nil && u.Host != "" {
+				return RedactURL(u)
+			}
+		}
+		return maskEmails(v)
+	}
+	return value
+}
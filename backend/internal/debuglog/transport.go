@@ -0,0 +1,60 @@
+package debuglog
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Transport records every outbound call made through it while debug logging
+// is on, e.g. to an external API
+type Transport struct {
+	Base http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !Enabled() {
+		return base.RoundTrip(req)
+	}
+
+	entry := Entry{
+		Kind:           KindOutbound,
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            RedactURL(req.URL),
+		RequestHeaders: RedactHeaders(req.Header),
+	}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(io.LimitReader(body, MaxBodyBytes*4))
+			entry.RequestBody = RedactBody(data, req.Header.Get("Content-Type"))
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	entry.DurationMS = float64(time.Since(entry.Time).Microseconds()) / 1000
+	if err != nil {
+		entry.Error = err.Error()
+		Record(entry)
+		return resp, err
+	}
+
+	// Read the body to log it, then hand the caller a copy
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	entry.Status = resp.StatusCode
+	if err != nil {
+		entry.Error = err.Error()
+		Record(entry)
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	entry.ResponseBody = RedactBody(data, resp.Header.Get("Content-Type"))
+	Record(entry)
+	return resp, nil
+}
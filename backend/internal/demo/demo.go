@@ -0,0 +1,203 @@
+// Package demo provisions throwaway accounts for trying the API without
+// registering. Each holds a sample collection of famous coins and is
+// deleted, with everything added to it, once it expires.
+package demo
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/accounts"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EmailDomain marks demo users, whose addresses can't receive mail
+const EmailDomain = "demo.aureus.test"
+
+// PortfolioName is the name of the sample portfolio
+const PortfolioName = "Demo Collection"
+
+const (
+	defaultTTL      = 24 * time.Hour
+	defaultMaxPerIP = 3
+)
+
+// Enabled reports whether demo sign-in is on (DEMO_MODE)
+func Enabled() bool {
+	return config.Bool("DEMO_MODE", false)
+}
+
+// TTL is how long a demo account lasts (DEMO_ACCOUNT_TTL)
+func TTL() time.Duration {
+	return config.Duration("DEMO_ACCOUNT_TTL", defaultTTL)
+}
+
+// MaxPerIP is how many live demo accounts one IP address may hold
+// (DEMO_ACCOUNTS_PER_IP); 0 is no limit
+func MaxPerIP() int64 {
+	return config.Int64("DEMO_ACCOUNTS_PER_IP", defaultMaxPerIP)
+}
+
+// LimitError is returned by Provision when the IP address already holds
+// MaxPerIP live demo accounts. RetryAfter is when the oldest expires.
+type LimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e LimitError) Error() string {
+	return fmt.Sprintf("too many demo accounts, retry in %s", e.RetryAfter)
+}
+
+// sample is a coin of the sample collection, bought monthsAgo
+type sample struct {
+	coinType        string
+	year            int
+	mintMark        string
+	strikeType      string
+	purchasePrice   float64
+	numismaticValue float64
+	monthsAgo       int
+	notes           string
+}
+
+// collection is what every demo account starts with: a few Morgan dollars,
+// two Saint-Gaudens double eagles and a Walking Liberty short set
+var collection = []sample{
+	{"Morgan Dollar", 1878, "CC", "business", 240, 285, 30, "First year of issue from Carson City"},
+	{"Morgan Dollar", 1881, "S", "business", 85, 95, 26, ""},
+	{"Morgan Dollar", 1884, "O", "business", 70, 78, 20, ""},
+	{"Morgan Dollar", 1921, "", "business", 48, 52, 8, "Last year of the series"},
+	{"$20 Saint Gaudens", 1924, "", "business", 2250, 2600, 36, ""},
+	{"$20 Saint Gaudens", 1927, "", "business", 2300, 2650, 14, ""},
+	{"Walking Liberty Half Dollar", 1941, "", "business", 24, 32, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1942, "", "business", 22, 30, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1943, "", "business", 22, 30, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1944, "", "business", 22, 30, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1945, "", "business", 22, 30, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1946, "", "business", 24, 33, 18, "Short set"},
+	{"Walking Liberty Half Dollar", 1947, "", "business", 26, 36, 18, "Short set"},
+}
+
+// SampleCoins returns the sample collection for a portfolio, valued at
+// calc's spot prices. A nil calc leaves melt values out.
+func SampleCoins(portfolioID uuid.UUID, calc *metals.Calculator, now time.Time) []models.Coin {
+	coins := make([]models.Coin, 0, len(collection))
+	for _, s := range collection {
+		purchased := now.AddDate(0, -s.monthsAgo, 0)
+		coin := models.Coin{
+			PortfolioID:     portfolioID,
+			CoinType:        s.coinType,
+			Year:            s.year,
+			MintMark:        s.mintMark,
+			StrikeType:      s.strikeType,
+			FaceCurrency:    metals.Currency,
+			PurchasePrice:   s.purchasePrice,
+			PurchaseDate:    &purchased,
+			NumismaticValue: s.numismaticValue,
+			LastPriceUpdate: &now,
+			Notes:           s.notes,
+			Quantity:        1,
+		}
+		if match, ok := metals.MatchComposition(coin.CoinType, coin.Year); ok {
+			coin.MetalType = match.Composition.MetalType
+			coin.MetalWeight = match.Composition.Weight
+			coin.MetalPurity = match.Composition.Purity
+			coin.CompositionSource = match.Method
+			coin.CompositionConfidence = match.Confidence
+			if calc != nil {
+				coin.MeltValue = valuation.CoinMeltValue(coin, calc)
+			}
+		}
+		valuation.ApplyBasis(&coin, valuation.DefaultBasis)
+		coins = append(coins, coin)
+	}
+	return coins
+}
+
+// Provision creates a demo user in tenantID holding the sample collection,
+// expiring TTL after now, for a client at ip. It has no password; the
+// session it's signed in with is the only way in. A LimitError means ip
+// already holds MaxPerIP live demo accounts.
+func Provision(tenantID *uuid.UUID, ip string, now time.Time) (models.User, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return models.User{}, err
+	}
+	expiresAt := now.Add(TTL())
+	user := models.User{
+		TenantID:      tenantID,
+		Email:         fmt.Sprintf("demo-%s@%s", hex.EncodeToString(id), EmailDomain),
+		DemoExpiresAt: &expiresAt,
+		DemoIPAddress: ip,
+	}
+
+	// Demo accounts are worth having without spot prices; they only lack
+	// melt values until the next price update
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		log.Printf("Demo: no spot prices for the sample collection: %v", err)
+	}
+
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Concurrent requests from one address take turns, so they can't
+		// all pass the count below
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "demo:"+ip).Error; err != nil {
+			return err
+		}
+		var live []models.User
+		if err := tx.Select("demo_expires_at").
+			Where("demo_ip_address = ? AND demo_expires_at >= ?", ip, now).
+			Order("demo_expires_at").
+			Find(&live).Error; err != nil {
+			return err
+		}
+		if limit := MaxPerIP(); limit > 0 && int64(len(live)) >= limit {
+			return LimitError{RetryAfter: live[0].DemoExpiresAt.Sub(now)}
+		}
+
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		portfolio := models.Portfolio{
+			UserID:         user.ID,
+			TenantID:       tenantID,
+			Name:           PortfolioName,
+			Description:    "A sample collection to try things out with. It's deleted when the demo account expires.",
+			ValuationBasis: valuation.DefaultBasis,
+		}
+		if err := tx.Create(&portfolio).Error; err != nil {
+			return err
+		}
+		return tx.Create(SampleCoins(portfolio.ID, calc, now)).Error
+	})
+	return user, err
+}
+
+// Purge deletes the demo accounts that expired before now, with everything
+// in them
+func Purge(now time.Time) error {
+	var expired []uuid.UUID
+	if err := database.GetDB().Model(&models.User{}).
+		Where("demo_expires_at < ?", now).
+		Pluck("id", &expired).Error; err != nil {
+		return err
+	}
+	for _, userID := range expired {
+		if err := accounts.Delete(userID); err != nil {
+			return fmt.Errorf("deleting demo account %s: %w", userID, err)
+		}
+	}
+	if len(expired) > 0 {
+		log.Printf("Purged %d expired demo accounts", len(expired))
+	}
+	return nil
+}
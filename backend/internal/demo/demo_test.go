@@ -0,0 +1,38 @@
+package demo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/google/uuid"
+)
+
+func TestSampleCoinsAreValued(t *testing.T) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	calc := metals.NewCalculator(metals.SpotPrices{Gold: 2000, Silver: 25})
+	portfolioID := uuid.New()
+
+	coins := SampleCoins(portfolioID, calc, now)
+	if len(coins) != len(collection) {
+		t.Fatalf("got %d coins, want %d", len(coins), len(collection))
+	}
+	for _, coin := range coins {
+		if coin.PortfolioID != portfolioID {
+			t.Errorf("%d %s is in portfolio %s", coin.Year, coin.CoinType, coin.PortfolioID)
+		}
+		if coin.MetalType == "" || coin.MeltValue <= 0 {
+			t.Errorf("%d %s has no composition or melt value: %+v", coin.Year, coin.CoinType, coin)
+		}
+		if coin.CurrentValue < coin.MeltValue || coin.CurrentValue <= 0 {
+			t.Errorf("%d %s is valued at %v", coin.Year, coin.CoinType, coin.CurrentValue)
+		}
+		if coin.PurchaseDate == nil || !coin.PurchaseDate.Before(now) {
+			t.Errorf("%d %s was bought %v", coin.Year, coin.CoinType, coin.PurchaseDate)
+		}
+	}
+
+	if coins := SampleCoins(portfolioID, nil, now); coins[0].MeltValue != 0 || coins[0].CurrentValue <= 0 {
+		t.Errorf("without spot prices got melt %v, value %v", coins[0].MeltValue, coins[0].CurrentValue)
+	}
+}
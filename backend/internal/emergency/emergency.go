@@ -0,0 +1,87 @@
+// Package emergency decides when an emergency contact, e.g. the executor of
+// an estate, may read the account they were designated for. Access goes
+// designated -> requested (by the contact) -> approved (by an admin), and
+// opens once the owner's waiting period has passed since the approval. The
+// owner can deny it, and revoke the contact, at any point.
+package emergency
+
+import (
+	"errors"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// Statuses of an emergency contact
+const (
+	StatusDesignated = "designated"
+	StatusRequested  = "requested"
+	StatusApproved   = "approved"
+)
+
+// Waiting periods owners can choose, in days
+const (
+	MinWaitingDays     = 7
+	MaxWaitingDays     = 365
+	DefaultWaitingDays = 30
+)
+
+// TokenTTL is how long a token issued to a contact with access lasts
+const TokenTTL = 24 * time.Hour
+
+// ErrWrongStatus is returned for a step that doesn't follow from the
+// contact's current status
+var ErrWrongStatus = errors.New("emergency access is not at that step")
+
+// Request records the contact asking for access
+func Request(contact *models.EmergencyContact, reason string, now time.Time) error {
+	if contact.Status != StatusDesignated {
+		return ErrWrongStatus
+	}
+	contact.Status = StatusRequested
+	contact.Reason = reason
+	contact.RequestedAt = &now
+	return nil
+}
+
+// Approve records an admin approving a request, which starts the waiting
+// period
+func Approve(contact *models.EmergencyContact, adminID uuid.UUID, now time.Time) error {
+	if contact.Status != StatusRequested {
+		return ErrWrongStatus
+	}
+	available := now.AddDate(0, 0, contact.WaitingDays)
+	contact.Status = StatusApproved
+	contact.ApprovedAt = &now
+	contact.ApprovedBy = &adminID
+	contact.AvailableAt = &available
+	return nil
+}
+
+// Reset puts the contact back to designated, when the owner denies a request
+// or an admin rejects it
+func Reset(contact *models.EmergencyContact) {
+	contact.Status = StatusDesignated
+	contact.Reason = ""
+	contact.RequestedAt = nil
+	contact.ApprovedAt = nil
+	contact.ApprovedBy = nil
+	contact.AvailableAt = nil
+}
+
+// Granted reports whether the contact can read the owner's account at now
+func Granted(contact models.EmergencyContact, now time.Time) bool {
+	return contact.Status == StatusApproved && contact.AvailableAt != nil && !now.Before(*contact.AvailableAt)
+}
+
+// Active reports whether a token issued under accessID for the account of
+// userID is still good, i.e. the access wasn't denied or revoked since
+func Active(accessID, userID uuid.UUID) bool {
+	var contact models.EmergencyContact
+	if err := database.GetDB().Where("id = ? AND user_id = ?", accessID, userID).First(&contact).Error; err != nil {
+		return false
+	}
+	return Granted(contact, time.Now())
+}
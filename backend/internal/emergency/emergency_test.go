@@ -0,0 +1,40 @@
+package emergency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestAccessOpensAfterApprovalAndWaitingPeriod(t *testing.T) {
+	contact := models.EmergencyContact{Status: StatusDesignated, WaitingDays: 30}
+	requested := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	if err := Approve(&contact, uuid.New(), requested); err != ErrWrongStatus {
+		t.Errorf("approving before a request: err = %v, want ErrWrongStatus", err)
+	}
+	if err := Request(&contact, "Executor of the estate", requested); err != nil {
+		t.Fatal(err)
+	}
+	if Granted(contact, requested.AddDate(1, 0, 0)) {
+		t.Error("granted without an admin's approval")
+	}
+
+	approved := requested.AddDate(0, 0, 2)
+	if err := Approve(&contact, uuid.New(), approved); err != nil {
+		t.Fatal(err)
+	}
+	if Granted(contact, approved.AddDate(0, 0, 29)) {
+		t.Error("granted during the waiting period")
+	}
+	if !Granted(contact, approved.AddDate(0, 0, 30)) {
+		t.Error("not granted once the waiting period passed")
+	}
+
+	Reset(&contact)
+	if Granted(contact, approved.AddDate(1, 0, 0)) || contact.Status != StatusDesignated {
+		t.Errorf("still granted after the owner denied it: %+v", contact)
+	}
+}
@@ -0,0 +1,295 @@
+// Package enrichment looks up new coins' certs at PCGS in the background,
+// so adding a coin doesn't wait on PCGS. A coin with a cert is saved as
+// pending; a small pool of workers then fills in what the owner left out
+// (series, mintage, denomination, images, composition and the price guide
+// value) from CoinFacts. Coins still pending after a restart or a failed
+// attempt are picked up again by Sweep.
+package enrichment
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evansminotwood/aureus/internal/certimages"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/pcgssync"
+	"github.com/evansminotwood/aureus/internal/references"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Enrichment statuses
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+// maxAttempts is how many times a coin is tried before it's marked failed
+const maxAttempts = 3
+
+// queueSize bounds the coins waiting for a worker; past it, coins stay
+// pending for the next sweep
+const queueSize = 1000
+
+var (
+	startOnce sync.Once
+	queue     chan uuid.UUID
+
+	queuedMu sync.Mutex
+	queued   = map[uuid.UUID]bool{}
+)
+
+// workers is how many coins are looked up at once (ENRICHMENT_WORKERS,
+// default 2)
+func workers() int {
+	return int(max(config.Int64("ENRICHMENT_WORKERS", 2), 1))
+}
+
+// Needed reports whether a new coin has anything to look up
+func Needed(coin models.Coin) bool {
+	return strings.TrimSpace(coin.PCGSCertNumber) != ""
+}
+
+// Enqueue queues a pending coin for a worker. A coin already queued isn't
+// queued twice; when the queue is full it returns false and the coin waits
+// for the next sweep.
+func Enqueue(coinID uuid.UUID) bool {
+	startOnce.Do(func() {
+		queue = make(chan uuid.UUID, queueSize)
+		for range workers() {
+			go work()
+		}
+	})
+
+	queuedMu.Lock()
+	defer queuedMu.Unlock()
+	if queued[coinID] {
+		return true
+	}
+	select {
+	case queue <- coinID:
+		queued[coinID] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Queued returns the number of coins waiting for or being looked up
+func Queued() int {
+	queuedMu.Lock()
+	defer queuedMu.Unlock()
+	return len(queued)
+}
+
+func work() {
+	for coinID := range queue {
+		if err := Enrich(coinID); err != nil {
+			log.Printf("Enrichment of coin %s failed: %v", coinID, err)
+		}
+		queuedMu.Lock()
+		delete(queued, coinID)
+		queuedMu.Unlock()
+	}
+}
+
+// ParseMintage reads a CoinFacts mintage such as "44,690,000"; 0 when
+// it's missing or not a number (e.g. "Unknown")
+func ParseMintage(value string) int64 {
+	value = strings.NewReplacer(",", "", " ", "").Replace(value)
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// Fill copies what CoinFacts and the cert's images know about a coin into
+// the fields its owner left empty, returning the columns it changed.
+// images may be nil.
+func Fill(coin *models.Coin, facts *pcgs.CoinFactsResponse, images *pcgs.PCGSImageData) []string {
+	changed := []string{}
+	if coin.Series == "" && facts.SeriesName != "" {
+		coin.Series = facts.SeriesName
+		changed = append(changed, "series")
+	}
+	if mintage := ParseMintage(facts.Mintage); coin.Mintage == 0 && mintage > 0 {
+		coin.Mintage = mintage
+		changed = append(changed, "mintage")
+	}
+	if coin.Denomination == "" && facts.Denomination != "" {
+		coin.Denomination = facts.Denomination
+		changed = append(changed, "denomination")
+	}
+	if coin.Year == 0 && facts.Year > 0 {
+		coin.Year = facts.Year
+		changed = append(changed, "year")
+	}
+	if coin.ImageURL == "" && images != nil && len(images.Images) > 0 {
+		coin.ImageURL = images.GetFrontImageURL()
+		changed = append(changed, "image_url")
+		if len(images.Images) > 1 && coin.ThumbnailURL == "" {
+			coin.ThumbnailURL = images.GetBackImageURL()
+			changed = append(changed, "thumbnail_url")
+		}
+	}
+	return changed
+}
+
+// fillComposition tries the catalog again with the series PCGS gave, for
+// coin types typed in a way the catalog didn't recognize
+func fillComposition(coin *models.Coin, series, basis string) []string {
+	if coin.MetalType != "" || series == "" {
+		return nil
+	}
+	match, ok := metals.MatchStrikeComposition(series, coin.Year, coin.StrikeType)
+	if !ok {
+		return nil
+	}
+	comp := match.Composition
+	coin.MetalType = comp.MetalType
+	coin.MetalWeight = comp.Weight
+	coin.MetalPurity = comp.Purity
+	coin.CompositionSource = match.Method
+	coin.CompositionConfidence = match.Confidence
+	changed := []string{"metal_type", "metal_weight", "metal_purity", "composition_source", "composition_confidence"}
+	if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
+		valuation.ApplyMeltValue(coin, meltValue, basis)
+		changed = append(changed, "melt_value", "current_value")
+	}
+	return changed
+}
+
+// Enrich looks up a pending coin's cert and fills it in. The coin is then
+// done, or after maxAttempts failures, failed; either way CoinEnriched is
+// published so its first snapshot gets recorded. While the PCGS quota is
+// nearly used up the coin is left pending for a later sweep.
+func Enrich(coinID uuid.UUID) error {
+	db := database.GetDB()
+	var coin models.Coin
+	if err := db.First(&coin, "id = ?", coinID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // deleted while it waited
+		}
+		return err
+	}
+	if coin.EnrichmentStatus != StatusPending {
+		return nil
+	}
+	var portfolio models.Portfolio
+	if err := db.Select("id", "user_id", "valuation_basis").First(&portfolio, "id = ?", coin.PortfolioID).Error; err != nil {
+		return err
+	}
+
+	client := pcgssync.ClientForUser(portfolio.UserID)
+	if usage.Throttled(client.UsageService) {
+		return nil
+	}
+
+	facts, lookupErr := client.GetCoinDataByCertNumber(coin.PCGSCertNumber)
+	if lookupErr != nil {
+		coin.EnrichmentAttempts++
+		status := StatusPending
+		if coin.EnrichmentAttempts >= maxAttempts {
+			status = StatusFailed
+		}
+		if err := db.Model(&coin).Updates(map[string]interface{}{
+			"enrichment_status":   status,
+			"enrichment_attempts": coin.EnrichmentAttempts,
+		}).Error; err != nil {
+			return err
+		}
+		if status == StatusFailed {
+			coin.EnrichmentStatus = status
+			events.Publish(events.CoinEnriched{UserID: portfolio.UserID, Coin: coin})
+		}
+		return lookupErr
+	}
+
+	changed := []string{"enrichment_status"}
+	coin.EnrichmentStatus = StatusDone
+	var images []pcgs.ImageDetail
+	var pcgsValue float64
+	oldCurrentValue, oldNumismaticValue := coin.CurrentValue, coin.NumismaticValue
+	// An unknown cert leaves nothing to fill in; the coin is still done
+	if facts.IsValidRequest {
+		var imageData *pcgs.PCGSImageData
+		if coin.ImageURL == "" {
+			if data, err := client.GetCoinImagesByCertNumber(coin.PCGSCertNumber); err == nil && data.IsValidRequest {
+				imageData = data
+				images = data.Images
+			}
+		}
+		changed = append(changed, Fill(&coin, facts, imageData)...)
+		changed = append(changed, fillComposition(&coin, facts.SeriesName, portfolio.ValuationBasis)...)
+
+		pcgsValue = facts.PriceGuideValue
+		if pcgsValue > 0 && coin.NumismaticValue == 0 {
+			valuation.ApplyNumismaticValue(&coin, pcgsValue, portfolio.ValuationBasis)
+			changed = append(changed, "numismatic_value", "current_value")
+		}
+		references.Refresh(&coin, facts.PCGSNo)
+		changed = append(changed, "references")
+	}
+
+	if err := db.Model(&coin).Select(changed).Updates(&coin).Error; err != nil {
+		return err
+	}
+	if err := certimages.Archive(portfolio.UserID, coin, images); err != nil {
+		log.Printf("Failed to archive certification images for coin %s: %v", coin.ID, err)
+	}
+
+	events.Publish(events.CoinEnriched{UserID: portfolio.UserID, Coin: coin, PCGSValue: pcgsValue})
+	if coin.NumismaticValue != oldNumismaticValue || coin.CurrentValue != oldCurrentValue {
+		events.Publish(events.CoinValued{
+			UserID:             portfolio.UserID,
+			CoinID:             coin.ID,
+			PortfolioID:        coin.PortfolioID,
+			Source:             "pcgs",
+			OldCurrentValue:    oldCurrentValue,
+			NewCurrentValue:    coin.CurrentValue,
+			OldNumismaticValue: oldNumismaticValue,
+			NewNumismaticValue: coin.NumismaticValue,
+		})
+	}
+	return nil
+}
+
+// Sweep queues every pending coin that isn't queued already, e.g. after a
+// restart or a failed attempt
+func Sweep() error {
+	var ids []uuid.UUID
+	if err := database.GetDB().Model(&models.Coin{}).
+		Where("enrichment_status = ?", StatusPending).
+		Order("created_at").Limit(queueSize).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if !Enqueue(id) {
+			break
+		}
+	}
+	return nil
+}
+
+// Subscribe queues each new coin saved as pending
+func Subscribe() {
+	events.Subscribe(events.TypeCoinCreated, func(e events.Event) {
+		created := e.(events.CoinCreated)
+		if created.Coin.EnrichmentStatus == StatusPending {
+			Enqueue(created.Coin.ID)
+		}
+	})
+}
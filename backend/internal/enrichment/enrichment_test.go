@@ -0,0 +1,72 @@
+package enrichment
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+)
+
+func TestParseMintage(t *testing.T) {
+	tests := map[string]int64{
+		"44,690,000": 44690000,
+		"1 000":      1000,
+		"2700":       2700,
+		"":           0,
+		"Unknown":    0,
+		"-5":         0,
+	}
+	for value, want := range tests {
+		if got := ParseMintage(value); got != want {
+			t.Errorf("ParseMintage(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
+
+func TestFillKeepsWhatTheOwnerEntered(t *testing.T) {
+	facts := &pcgs.CoinFactsResponse{
+		Year:         1921,
+		Denomination: "$1",
+		Mintage:      "44,690,000",
+		SeriesName:   "Morgan Dollar",
+	}
+	images := &pcgs.PCGSImageData{Images: []pcgs.ImageDetail{
+		{URL: "https://images.example/obverse.jpg", Description: "Obverse"},
+		{URL: "https://images.example/reverse.jpg", Description: "Reverse"},
+	}}
+
+	coin := models.Coin{Denomination: "Dollar", ImageURL: "mine.jpg"}
+	changed := Fill(&coin, facts, images)
+
+	if coin.Series != "Morgan Dollar" || coin.Mintage != 44690000 || coin.Year != 1921 {
+		t.Errorf("got series %q, mintage %d, year %d", coin.Series, coin.Mintage, coin.Year)
+	}
+	if coin.Denomination != "Dollar" || coin.ImageURL != "mine.jpg" || coin.ThumbnailURL != "" {
+		t.Errorf("overwrote the owner's denomination or image: %+v", coin)
+	}
+	want := []string{"series", "mintage", "year"}
+	if !slices.Equal(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+}
+
+func TestFillImages(t *testing.T) {
+	images := &pcgs.PCGSImageData{Images: []pcgs.ImageDetail{
+		{URL: "https://images.example/obverse.jpg", Description: "Obverse"},
+		{URL: "https://images.example/reverse.jpg", Description: "Reverse"},
+	}}
+	coin := models.Coin{}
+	changed := Fill(&coin, &pcgs.CoinFactsResponse{}, images)
+
+	if coin.ImageURL != "https://images.example/obverse.jpg" || coin.ThumbnailURL != "https://images.example/reverse.jpg" {
+		t.Errorf("got image %q, thumbnail %q", coin.ImageURL, coin.ThumbnailURL)
+	}
+	if !slices.Equal(changed, []string{"image_url", "thumbnail_url"}) {
+		t.Errorf("changed = %v", changed)
+	}
+
+	if changed := Fill(&models.Coin{}, &pcgs.CoinFactsResponse{}, nil); len(changed) != 0 {
+		t.Errorf("nothing to fill changed %v", changed)
+	}
+}
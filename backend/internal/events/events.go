@@ -0,0 +1,316 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	TypeCoinCreated         = "coin.created"
+	TypeCoinDeleted         = "coin.deleted"
+	TypeCoinEnriched        = "coin.enriched"
+	TypeCoinValued          = "coin.valued"
+	TypePortfolioUpdated    = "portfolio.updated"
+	TypeSpotPricesRefreshed = "spot_prices.refreshed"
+	TypeSpotPricesDegraded  = "spot_prices.degraded"
+	TypeAlertFired          = "alert.fired"
+	TypeSpotAlertFired      = "spot_alert.fired"
+	TypeCoinAlertFired      = "coin_alert.fired"
+	TypePCGSSyncCompleted   = "pcgs_sync.completed"
+	TypeStatementSent       = "statement.sent"
+	TypeJobFinished         = "job.finished"
+	TypePortfolioMember     = "portfolio_member.updated"
+	TypeCoinTransfer        = "coin_transfer.updated"
+	TypeEmergencyAccess     = "emergency_access.updated"
+	TypeCertFlagged         = "cert.flagged"
+)
+
+// Event is a domain event published by handlers and background jobs
+type Event interface {
+	Type() string
+}
+
+// CoinCreated is published after a coin is saved for the first time
+type CoinCreated struct {
+	UserID uuid.UUID
+	Coin   models.Coin
+}
+
+func (CoinCreated) Type() string { return TypeCoinCreated }
+
+// CoinDeleted is published after a coin is removed for good: deleted from
+// the archive, merged into another row or purged from the trash
+type CoinDeleted struct {
+	UserID uuid.UUID
+	Coin   models.Coin
+}
+
+func (CoinDeleted) Type() string { return TypeCoinDeleted }
+
+// CoinEnriched is published once a new coin's cert has been looked up in
+// the background, or given up on (its EnrichmentStatus says which)
+type CoinEnriched struct {
+	UserID    uuid.UUID
+	Coin      models.Coin
+	PCGSValue float64 // price guide value found for the cert, if any
+}
+
+func (CoinEnriched) Type() string { return TypeCoinEnriched }
+
+// CoinValued is published when a coin's current or numismatic value changes
+type CoinValued struct {
+	UserID             uuid.UUID
+	CoinID             uuid.UUID
+	PortfolioID        uuid.UUID
+	Source             string // e.g. "manual", "melt", "pcgs"
+	OldCurrentValue    float64
+	NewCurrentValue    float64
+	OldNumismaticValue float64
+	NewNumismaticValue float64
+}
+
+func (CoinValued) Type() string { return TypeCoinValued }
+
+// Portfolio actions. A deleted portfolio is trashed first and only
+// deleted, for good, when it's purged from the trash.
+const (
+	PortfolioCreated  = "created"
+	PortfolioChanged  = "updated"
+	PortfolioTrashed  = "trashed"
+	PortfolioRestored = "restored"
+	PortfolioDeleted  = "deleted"
+)
+
+// PortfolioUpdated is published when a portfolio is created, edited,
+// trashed, restored or deleted
+type PortfolioUpdated struct {
+	UserID      uuid.UUID
+	PortfolioID uuid.UUID
+	Action      string
+}
+
+func (PortfolioUpdated) Type() string { return TypePortfolioUpdated }
+
+// SpotPricesRefreshed is published whenever the spot price cache is refilled
+type SpotPricesRefreshed struct {
+	Prices   map[string]float64 // metal -> USD price
+	Moved    []string           // metals whose price changed since the last refresh
+	Fallback bool               // true when live sources failed and fallback prices were used
+	// FallbackMetals lists the metals priced from fallbacks, on live
+	// refreshes too when a source doesn't quote every metal
+	FallbackMetals []string
+	RefreshedAt    time.Time
+}
+
+func (SpotPricesRefreshed) Type() string { return TypeSpotPricesRefreshed }
+
+// SpotPricesDegraded is published when metals have been priced from fallback
+// prices for longer than admins should let pass unnoticed
+type SpotPricesDegraded struct {
+	Metals []string
+	Since  time.Time // when the earliest of them fell back
+}
+
+func (SpotPricesDegraded) Type() string { return TypeSpotPricesDegraded }
+
+// AlertFired is published when a portfolio alert's condition starts holding
+type AlertFired struct {
+	UserID      uuid.UUID
+	PortfolioID uuid.UUID
+	AlertID     uuid.UUID
+	Condition   string
+	Threshold   float64
+	Value       float64
+	Channels    []string // delivery channels besides in-app
+}
+
+func (AlertFired) Type() string { return TypeAlertFired }
+
+// SpotAlertFired is published when a spot alert's condition starts holding
+type SpotAlertFired struct {
+	UserID    uuid.UUID
+	AlertID   uuid.UUID
+	Metric    string
+	Condition string
+	Threshold float64
+	Value     float64
+	Change    float64 // percent change over the last day
+	Channels  []string
+}
+
+func (SpotAlertFired) Type() string { return TypeSpotAlertFired }
+
+// CoinAlertFired is published when a watched coin's alert fires. For
+// pcgs_change alerts, Value and Previous are the new and old guide values.
+type CoinAlertFired struct {
+	UserID    uuid.UUID
+	AlertID   uuid.UUID
+	Coin      models.Coin
+	Metric    string
+	Condition string
+	Threshold float64
+	Value     float64
+	Previous  float64
+	Channels  []string
+}
+
+func (CoinAlertFired) Type() string { return TypeCoinAlertFired }
+
+// PCGSSyncCompleted is published after a scheduled PCGS value sync
+type PCGSSyncCompleted struct {
+	UserID  uuid.UUID
+	Total   int
+	Updated int
+	Skipped int
+	Failed  int
+}
+
+func (PCGSSyncCompleted) Type() string { return TypePCGSSyncCompleted }
+
+// StatementSent is published after a monthly statement is emailed
+type StatementSent struct {
+	UserID        uuid.UUID
+	PortfolioID   uuid.UUID
+	PortfolioName string
+	Period        string // e.g. "September 2025"
+}
+
+func (StatementSent) Type() string { return TypeStatementSent }
+
+// JobFinished is published when a background job a user started succeeds
+// or fails
+type JobFinished struct {
+	Job models.Job
+}
+
+func (JobFinished) Type() string { return TypeJobFinished }
+
+// CoinTransferUpdated is published when a coin is offered to another user
+// and again when the offer is accepted, declined or cancelled
+type CoinTransferUpdated struct {
+	Transfer  models.CoinTransfer
+	CoinLabel string // e.g. "1921 Morgan Dollar"
+	FromEmail string
+	ToEmail   string
+}
+
+func (CoinTransferUpdated) Type() string { return TypeCoinTransfer }
+
+// Portfolio member actions
+const (
+	MemberInvited  = "invited"
+	MemberAccepted = "accepted"
+	MemberDeclined = "declined"
+	MemberChanged  = "changed" // given another role
+	MemberRemoved  = "removed" // by an owner, or the member leaving
+)
+
+// PortfolioMemberUpdated is published when someone is invited to share a
+// portfolio, answers the invitation or loses access
+type PortfolioMemberUpdated struct {
+	Member        models.PortfolioMember
+	Action        string
+	PortfolioName string
+	ByEmail       string // who invited or removed them
+	MemberEmail   string
+}
+
+func (PortfolioMemberUpdated) Type() string { return TypePortfolioMember }
+
+// Emergency access actions
+const (
+	EmergencyDesignated = "designated"
+	EmergencyRequested  = "requested"
+	EmergencyApproved   = "approved"
+	EmergencyDenied     = "denied" // by the owner or an admin
+	EmergencyRevoked    = "revoked"
+)
+
+// EmergencyAccessUpdated is published at each step of an emergency contact's
+// access to an account
+type EmergencyAccessUpdated struct {
+	Contact      models.EmergencyContact
+	Action       string
+	OwnerEmail   string
+	ContactEmail string
+}
+
+func (EmergencyAccessUpdated) Type() string { return TypeEmergencyAccess }
+
+// CertFlagged is published when a coin's cert number is flagged as possibly
+// counterfeit, with the reasons in the coin's CertFlags
+type CertFlagged struct {
+	UserID uuid.UUID
+	Coin   models.Coin
+}
+
+func (CertFlagged) Type() string { return TypeCertFlagged }
+
+// Handler receives published events
+type Handler func(Event)
+
+// Bus delivers events to subscribers. Each handler runs in its own goroutine
+// so slow subscribers never hold up the request that published the event.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	wg       sync.WaitGroup
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for events of the given type
+func (b *Bus) Subscribe(eventType string, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish delivers event to every subscriber of its type asynchronously
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers[event.Type()]
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		b.wg.Add(1)
+		go func(h Handler) {
+			defer b.wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Event handler for %s panicked: %v", event.Type(), r)
+				}
+			}()
+			h(event)
+		}(handler)
+	}
+}
+
+// Wait blocks until all in-flight handlers have finished
+func (b *Bus) Wait() {
+	b.wg.Wait()
+}
+
+var defaultBus = NewBus()
+
+// Subscribe registers handler on the default bus
+func Subscribe(eventType string, handler Handler) {
+	defaultBus.Subscribe(eventType, handler)
+}
+
+// Publish sends event on the default bus
+func Publish(event Event) {
+	defaultBus.Publish(event)
+}
+
+// Wait waits for in-flight handlers on the default bus
+func Wait() {
+	defaultBus.Wait()
+}
@@ -0,0 +1,75 @@
+package events
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublishDeliversToSubscribersOfTheType(t *testing.T) {
+	bus := NewBus()
+	var mu sync.Mutex
+	var got []uuid.UUID
+	bus.Subscribe(TypePortfolioUpdated, func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e.(PortfolioUpdated).PortfolioID)
+	})
+	var other atomic.Int32
+	bus.Subscribe(TypeCoinDeleted, func(Event) { other.Add(1) })
+
+	id := uuid.New()
+	bus.Publish(PortfolioUpdated{PortfolioID: id, Action: PortfolioCreated})
+	bus.Wait()
+
+	if len(got) != 1 || got[0] != id {
+		t.Errorf("delivered %v, want [%s]", got, id)
+	}
+	if other.Load() != 0 {
+		t.Error("event delivered to a subscriber of another type")
+	}
+}
+
+func TestPublishWithoutSubscribers(t *testing.T) {
+	bus := NewBus()
+	bus.Publish(CoinDeleted{})
+	bus.Wait()
+}
+
+func TestEverySubscriberReceivesTheEvent(t *testing.T) {
+	bus := NewBus()
+	var calls atomic.Int32
+	for range 3 {
+		bus.Subscribe(TypeCoinDeleted, func(Event) { calls.Add(1) })
+	}
+
+	bus.Publish(CoinDeleted{})
+	bus.Publish(CoinDeleted{})
+	bus.Wait()
+
+	if n := calls.Load(); n != 6 {
+		t.Errorf("handlers ran %d times, want 6", n)
+	}
+}
+
+func TestPanickingHandlerDoesNotStopOthers(t *testing.T) {
+	bus := NewBus()
+	var calls atomic.Int32
+	bus.Subscribe(TypeCoinDeleted, func(Event) { panic("subscriber bug") })
+	bus.Subscribe(TypeCoinDeleted, func(Event) { calls.Add(1) })
+
+	bus.Publish(CoinDeleted{})
+	bus.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("other handler ran %d times, want 1", n)
+	}
+
+	// The bus keeps delivering after a handler panicked
+	bus.Publish(CoinDeleted{})
+	bus.Wait()
+	if n := calls.Load(); n != 2 {
+		t.Errorf("other handler ran %d times after the panic, want 2", n)
+	}
+}
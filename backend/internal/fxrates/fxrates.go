@@ -0,0 +1,172 @@
+// Package fxrates records exchange rates against the US dollar, which every
+// amount in the API is in, so a value can be restated in another currency as
+// of a past date and its change split into what the metal did and what the
+// currency did.
+package fxrates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+// Retention is how long rates are kept; the same as spot price history,
+// since restating a past value needs both
+const Retention = 35 * 24 * time.Hour
+
+// defaultURL serves the day's rates against USD without an API key
+const defaultURL = "https://open.er-api.com/v6/latest/USD"
+
+// defaultCurrencies are recorded unless FX_CURRENCIES says otherwise:
+// the currencies of the major bullion coins and markets
+const defaultCurrencies = "AUD,CAD,CHF,CNY,EUR,GBP,JPY,MXN,ZAR"
+
+// ErrNoRate is returned when no rate was recorded for a currency by the
+// time asked about
+var ErrNoRate = errors.New("no exchange rate recorded")
+
+// Currencies returns the currencies whose rates are recorded: FX_CURRENCIES
+// and the face currencies of coins held
+func Currencies() []string {
+	currencies := []string{}
+	add := func(code string) {
+		if code, ok := metals.NormalizeCurrency(code); ok && code != metals.Currency && !slices.Contains(currencies, code) {
+			currencies = append(currencies, code)
+		}
+	}
+	for _, code := range strings.Split(config.String("FX_CURRENCIES", defaultCurrencies), ",") {
+		add(code)
+	}
+
+	var held []string
+	if err := database.GetReadDB().Model(&models.Coin{}).Distinct("face_currency").Pluck("face_currency", &held).Error; err != nil {
+		log.Printf("Failed to list face currencies for exchange rates: %v", err)
+	}
+	for _, code := range held {
+		add(code)
+	}
+	slices.Sort(currencies)
+	return currencies
+}
+
+// mockRates are fixed rates used when MOCK_EXTERNAL_APIS is enabled
+func mockRates() map[string]float64 {
+	return map[string]float64{
+		"AUD": 1.50, "CAD": 1.35, "CHF": 0.90, "CNY": 7.20, "EUR": 0.92,
+		"GBP": 0.79, "JPY": 150.0, "MXN": 17.0, "ZAR": 18.5,
+	}
+}
+
+// fetch gets the latest rates against USD from FX_RATES_URL, which answers
+// with a "rates" object of currency codes to rates
+func fetch() (map[string]float64, error) {
+	if config.MockMode() {
+		return mockRates(), nil
+	}
+
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(config.String("FX_RATES_URL", defaultURL))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("exchange rates returned status %d", resp.StatusCode)
+	}
+	var result struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if len(result.Rates) == 0 {
+		return nil, errors.New("no rates in exchange rate response")
+	}
+	return result.Rates, nil
+}
+
+// Refresh fetches the latest rates, records those of Currencies and prunes
+// rates older than Retention
+func Refresh(now time.Time) error {
+	rates, err := fetch()
+	usage.RecordCall(usage.ServiceFXRates, err)
+	if err != nil {
+		return err
+	}
+
+	records := []models.FXRate{}
+	for _, currency := range Currencies() {
+		if rate := rates[currency]; rate > 0 {
+			records = append(records, models.FXRate{Currency: currency, Rate: rate, RecordedAt: now})
+		}
+	}
+	db := database.GetDB()
+	if len(records) > 0 {
+		if err := db.Create(&records).Error; err != nil {
+			return err
+		}
+	}
+	return db.Where("recorded_at < ?", now.Add(-Retention)).Delete(&models.FXRate{}).Error
+}
+
+// At returns how many units of currency a US dollar bought at t, by the most
+// recent rate recorded at or before then. The dollar is always 1.
+func At(currency string, t time.Time) (float64, error) {
+	if currency == metals.Currency {
+		return 1, nil
+	}
+	var record models.FXRate
+	err := database.GetReadDB().Where("currency = ? AND recorded_at <= ?", currency, t).
+		Order("recorded_at DESC").First(&record).Error
+	if err != nil {
+		return 0, ErrNoRate
+	}
+	return record.Rate, nil
+}
+
+// Attribution splits the change in a value restated in another currency
+// between two dates
+type Attribution struct {
+	StartValue  float64 `json:"start_value"`
+	EndValue    float64 `json:"end_value"`
+	Change      float64 `json:"change"`
+	MetalEffect float64 `json:"metal_effect"` // from the dollar value moving, at the starting rate
+	FXEffect    float64 `json:"fx_effect"`    // from the rate moving, on the ending dollar value
+}
+
+// Attribute restates a dollar value that went from startUSD to endUSD while
+// the rate went from startRate to endRate. The metal and FX effects add up
+// to the change exactly.
+func Attribute(startUSD, endUSD, startRate, endRate float64) Attribution {
+	a := Attribution{
+		StartValue:  startUSD * startRate,
+		EndValue:    endUSD * endRate,
+		MetalEffect: (endUSD - startUSD) * startRate,
+		FXEffect:    endUSD * (endRate - startRate),
+	}
+	a.Change = a.EndValue - a.StartValue
+	return a
+}
+
+// Add sums two attributions in the same currency
+func (a Attribution) Add(b Attribution) Attribution {
+	return Attribution{
+		StartValue:  a.StartValue + b.StartValue,
+		EndValue:    a.EndValue + b.EndValue,
+		Change:      a.Change + b.Change,
+		MetalEffect: a.MetalEffect + b.MetalEffect,
+		FXEffect:    a.FXEffect + b.FXEffect,
+	}
+}
@@ -0,0 +1,33 @@
+package fxrates
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAttributeSplitsTheChange(t *testing.T) {
+	// An ounce of gold bought at $2000 when a dollar was 1.30 CAD, now
+	// $2100 with the dollar at 1.40 CAD
+	a := Attribute(2000, 2100, 1.30, 1.40)
+
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if !near(a.StartValue, 2600) || !near(a.EndValue, 2940) {
+		t.Errorf("values = %v to %v, want 2600 to 2940", a.StartValue, a.EndValue)
+	}
+	if !near(a.MetalEffect, 130) {
+		t.Errorf("metal effect = %v, want 130", a.MetalEffect)
+	}
+	if !near(a.FXEffect, 210) {
+		t.Errorf("fx effect = %v, want 210", a.FXEffect)
+	}
+	if !near(a.MetalEffect+a.FXEffect, a.Change) {
+		t.Errorf("effects %v + %v don't add up to the change %v", a.MetalEffect, a.FXEffect, a.Change)
+	}
+}
+
+func TestAttributeInDollarsHasNoFXEffect(t *testing.T) {
+	a := Attribute(25, 30, 1, 1)
+	if a.FXEffect != 0 || a.MetalEffect != 5 {
+		t.Errorf("in dollars got metal %v and fx %v, want 5 and 0", a.MetalEffect, a.FXEffect)
+	}
+}
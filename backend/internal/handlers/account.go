@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/accounts"
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/registry"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"` // required unless the account only signs in with a provider
+	Email    string `json:"email"`    // confirms the deletion for accounts without a password
+}
+
+// DeleteAccount closes the user's account for good, deleting their
+// portfolios, coins, price history, images and every other record of theirs.
+// The password confirms it; accounts that only sign in with Google or Apple
+// confirm with their email address instead.
+func DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Password != "" {
+		if !auth.CheckPasswordHash(req.Password, user.Password) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+			return
+		}
+	} else if !strings.EqualFold(strings.TrimSpace(req.Email), user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the account's email address to confirm the deletion", "code": "confirm_email"})
+		return
+	}
+
+	if err := accounts.Delete(user.ID); err != nil {
+		log.Printf("Failed to delete account %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	// Their published sets leave the leaderboard
+	registry.Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
+
+// ExportAccount streams everything the user owns as one JSON file: their
+// account, portfolios, coins held and archived, price history, images,
+// lots, alerts, transfers, notifications and sign-in methods
+func ExportAccount(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	filename := fmt.Sprintf("aureus-export-%s.json", time.Now().Format("2006-01-02"))
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// The status has gone out with the first byte, so a failure part way
+	// through leaves the file cut short
+	if err := accounts.Export(userID.(uuid.UUID), c.Writer); err != nil {
+		log.Printf("Failed to export account %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/debuglog"
+	"github.com/evansminotwood/aureus/internal/enrichment"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/scheduler"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// GetInstanceStats reports capacity and external quota usage for self-hosters
+func GetInstanceStats(c *gin.Context) {
+	db := database.GetReadDB()
+
+	var userCount, portfolioCount, coinRows, historyRows int64
+	var coinQuantity int64
+	db.Model(&models.User{}).Count(&userCount)
+	db.Model(&models.Portfolio{}).Count(&portfolioCount)
+	db.Model(&models.Coin{}).Count(&coinRows)
+	db.Model(&models.Coin{}).Select("COALESCE(SUM(quantity), 0)").Scan(&coinQuantity)
+	db.Model(&models.PriceHistory{}).Count(&historyRows)
+
+	var databaseBytes int64
+	db.Raw("SELECT pg_database_size(current_database())").Scan(&databaseBytes)
+
+	storageBytes, err := storage.NewLocalStorage().TotalUsage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":      userCount,
+		"portfolios": portfolioCount,
+		"coins": gin.H{
+			"records":        coinRows,
+			"total_quantity": coinQuantity,
+		},
+		"price_history_records": historyRows,
+		"storage": gin.H{
+			"uploads_bytes":  storageBytes,
+			"database_bytes": databaseBytes,
+		},
+		"external_apis": usage.Snapshot(),
+		"jobs": gin.H{
+			"queue_depth":      scheduler.QueueDepth(),
+			"enrichment_queue": enrichment.Queued(),
+			"scheduled":        scheduler.Status(),
+		},
+		"generated_at": time.Now().Format(time.RFC3339),
+	})
+}
+
+// GetMetrics serves external API usage and quotas in the Prometheus text
+// format. When METRICS_TOKEN is set, scrapers must send it as a bearer token.
+func GetMetrics(c *gin.Context) {
+	if token := config.String("METRICS_TOKEN", ""); token != "" {
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := usage.WritePrometheus(c.Writer); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// GetDebugLog lists the most recent requests, outbound calls and debug
+// messages, newest first. Narrow it with ?kind= and ?limit=.
+func GetDebugLog(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+		return
+	}
+	kind := c.Query("kind")
+
+	entries := debuglog.Entries(0)
+	if kind != "" {
+		filtered := entries[:0]
+		for _, entry := range entries {
+			if entry.Kind == kind {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = filtered
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled": debuglog.Enabled(),
+		"size":    debuglog.Size(),
+		"entries": entries,
+	})
+}
+
+// ClearDebugLog drops every debug log entry
+func ClearDebugLog(c *gin.Context) {
+	debuglog.Clear()
+	c.JSON(http.StatusOK, gin.H{"message": "Debug log cleared"})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/alerts"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/notifications"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreatePortfolioAlertRequest struct {
+	Condition string   `json:"condition" binding:"required"`
+	Threshold float64  `json:"threshold" binding:"required,gt=0"`
+	Channels  []string `json:"channels"`
+}
+
+type UpdatePortfolioAlertRequest struct {
+	Condition string    `json:"condition"`
+	Threshold float64   `json:"threshold"`
+	Enabled   *bool     `json:"enabled"`
+	Channels  *[]string `json:"channels"`
+}
+
+// validateChannels checks alert delivery channels, responding with 400 on an
+// unknown one
+func validateChannels(c *gin.Context, names []string) bool {
+	for _, name := range names {
+		if !notifications.ValidChannel(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown channel %q, must be one of %v", name, notifications.ChannelNames())})
+			return false
+		}
+	}
+	return true
+}
+
+// GetPortfolioAlerts lists the melt value alerts configured on a portfolio
+func GetPortfolioAlerts(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var portfolioAlerts []models.PortfolioAlert
+	if err := database.GetDB().Where("portfolio_id = ?", portfolio.ID).Order("created_at ASC").Find(&portfolioAlerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, portfolioAlerts)
+}
+
+// CreatePortfolioAlert adds a melt value threshold alert to a portfolio
+func CreatePortfolioAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req CreatePortfolioAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !alerts.ValidCondition(req.Condition) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "condition must be 'above' or 'below'"})
+		return
+	}
+	if !validateChannels(c, req.Channels) {
+		return
+	}
+
+	alert := models.PortfolioAlert{
+		PortfolioID: portfolio.ID,
+		UserID:      userID.(uuid.UUID),
+		Condition:   req.Condition,
+		Threshold:   req.Threshold,
+		Enabled:     true,
+		Channels:    req.Channels,
+	}
+
+	if err := database.GetDB().Create(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// UpdatePortfolioAlert changes an alert's condition, threshold or enabled state
+func UpdatePortfolioAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	var alert models.PortfolioAlert
+	if err := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).First(&alert).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	var req UpdatePortfolioAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Condition != "" {
+		if !alerts.ValidCondition(req.Condition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "condition must be 'above' or 'below'"})
+			return
+		}
+		alert.Condition = req.Condition
+	}
+	if req.Threshold > 0 {
+		alert.Threshold = req.Threshold
+	}
+	if req.Enabled != nil {
+		alert.Enabled = *req.Enabled
+	}
+	if req.Channels != nil {
+		if !validateChannels(c, *req.Channels) {
+			return
+		}
+		alert.Channels = *req.Channels
+	}
+
+	// Re-arm the alert so the new settings are evaluated from scratch
+	alert.Triggered = false
+
+	if err := database.GetDB().Save(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// DeletePortfolioAlert removes an alert
+func DeletePortfolioAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).Delete(&models.PortfolioAlert{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert deleted successfully"})
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/apikeys"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// maxAPIKeyNameLength caps an API key's name
+const maxAPIKeyNameLength = 100
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"gte=0,lte=3650"` // 0 never expires
+}
+
+type UpdateAPIKeyRequest struct {
+	Name   *string  `json:"name"`
+	Scopes []string `json:"scopes"` // replaces the key's scopes when given
+}
+
+// CreatedAPIKey is a new key along with its plaintext, which is only ever
+// returned here
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+func apiKeyName(c *gin.Context, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name must be 1 to 100 characters"})
+		return "", false
+	}
+	return name, true
+}
+
+// GetAPIKeys lists the user's API keys, newest first
+func GetAPIKeys(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	keys := []models.APIKey{}
+	if err := database.GetDB().Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey issues an API key for scripts, sent as X-API-Key in place of
+// a bearer token. Keys are always scoped: coins:read for a read-only key,
+// coins:write to change the inventory as well.
+func CreateAPIKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, ok := apiKeyName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	scopes, ok := grantableScopes(c, user, req.Scopes)
+	if !ok {
+		return
+	}
+
+	key, plain, err := apikeys.Create(user.ID, name, scopes, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	c.JSON(http.StatusCreated, CreatedAPIKey{APIKey: key, Key: plain})
+}
+
+// UpdateAPIKey renames a key or changes its scopes. The key itself stays
+// the same.
+func UpdateAPIKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var key models.APIKey
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&key).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+
+	var req UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		name, ok := apiKeyName(c, *req.Name)
+		if !ok {
+			return
+		}
+		key.Name = name
+	}
+	if req.Scopes != nil {
+		if key.PortfolioID != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A display token's scope can't be changed"})
+			return
+		}
+		var user models.User
+		if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		scopes, ok := grantableScopes(c, user, req.Scopes)
+		if !ok {
+			return
+		}
+		if len(scopes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An API key needs at least one scope"})
+			return
+		}
+		key.Scopes = scopes
+	}
+
+	if err := database.GetDB().Save(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update API key"})
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
+
+// DeleteAPIKey revokes a key; requests made with it are refused from then on
+func DeleteAPIKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
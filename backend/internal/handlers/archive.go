@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/archive"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DisposeCoinRequest struct {
+	Disposition string     `json:"disposition" binding:"required"`
+	DisposedAt  *time.Time `json:"disposed_at"` // defaults to now
+	SalePrice   float64    `json:"sale_price" binding:"gte=0"`
+	SaleFees    float64    `json:"sale_fees" binding:"gte=0"`
+	Notes       string     `json:"notes"`
+}
+
+// RealizedGainsTotals adds up a realized gains report
+type RealizedGainsTotals struct {
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"cost_basis"`
+	Gain      float64 `json:"gain"`
+	ShortTerm float64 `json:"short_term"`
+	LongTerm  float64 `json:"long_term"`
+}
+
+// findArchivedCoin loads an archived coin from a portfolio of the user or
+// shared with them, responding with 404 when there is none and 403 when
+// their role on the portfolio is below min
+func findArchivedCoin(c *gin.Context, userID interface{}, min string) (models.ArchivedCoin, bool) {
+	var archived models.ArchivedCoin
+	portfolios := members.Accessible(database.GetDB(), userID.(uuid.UUID))
+	if err := database.GetDB().Where("id = ? AND portfolio_id IN (?)", c.Param("id"), portfolios).First(&archived).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archived coin not found"})
+		return archived, false
+	}
+	_, ok := findPortfolio(c, archived.PortfolioID, min)
+	return archived, ok
+}
+
+// DisposeCoin records that a coin was sold or otherwise left the collection,
+// moving it to the archive. Its price history stays, so reports covering the
+// time it was held still include it.
+func DisposeCoin(c *gin.Context) {
+	var req DisposeCoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	disposition := strings.ToLower(strings.TrimSpace(req.Disposition))
+	if !archive.ValidDisposition(disposition) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "disposition must be one of " + strings.Join(archive.Dispositions, ", ")})
+		return
+	}
+	if disposition == archive.DispositionRegraded {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Record a regrade with POST /coins/:id/upgrade, which adds the coin under its new cert"})
+		return
+	}
+	disposedAt := time.Now()
+	if req.DisposedAt != nil {
+		if req.DisposedAt.After(disposedAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disposed_at can't be in the future"})
+			return
+		}
+		disposedAt = *req.DisposedAt
+	}
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleEditor); !ok {
+		return
+	}
+	if disposedAt.Before(coin.CreatedAt) && (coin.PurchaseDate == nil || disposedAt.Before(*coin.PurchaseDate)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "disposed_at can't be before the coin was acquired"})
+		return
+	}
+
+	archived, err := archive.Archive(coin, archive.Disposal{
+		Disposition: disposition,
+		DisposedAt:  disposedAt,
+		SalePrice:   req.SalePrice,
+		SaleFees:    req.SaleFees,
+		Notes:       strings.TrimSpace(req.Notes),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive coin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, archived)
+}
+
+// GetPortfolioArchivedCoins lists the coins that left a portfolio, most
+// recently disposed first. ?disposition= narrows it to one disposition.
+func GetPortfolioArchivedCoins(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	query := database.GetReadDB().Where("portfolio_id = ?", portfolio.ID)
+	if disposition := c.Query("disposition"); disposition != "" {
+		if !archive.ValidDisposition(disposition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disposition must be one of " + strings.Join(archive.Dispositions, ", ")})
+			return
+		}
+		query = query.Where("disposition = ?", disposition)
+	}
+
+	var archived []models.ArchivedCoin
+	if err := query.Order("disposed_at DESC").Find(&archived).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archived coins"})
+		return
+	}
+	c.JSON(http.StatusOK, archived)
+}
+
+// GetArchivedCoin returns one archived coin
+func GetArchivedCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	archived, ok := findArchivedCoin(c, userID, members.RoleViewer)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, archived)
+}
+
+// RestoreArchivedCoin moves an archived coin back into its portfolio, for
+// disposals recorded by mistake or sales that fell through
+func RestoreArchivedCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	archived, ok := findArchivedCoin(c, userID, members.RoleEditor)
+	if !ok {
+		return
+	}
+	// Its history went to the replacement, which is the coin now
+	if archived.Disposition == archive.DispositionRegraded {
+		c.JSON(http.StatusConflict, gin.H{"error": "A regraded coin lives on as its replacement and can't be restored", "replaced_by_id": archived.ReplacedByID})
+		return
+	}
+	coin, err := archive.Restore(archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore coin"})
+		return
+	}
+	valuation.Derive(&coin)
+	c.JSON(http.StatusOK, coin)
+}
+
+// DeleteArchivedCoin removes an archived coin for good, along with its place
+// in historical reports
+func DeleteArchivedCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	archived, ok := findArchivedCoin(c, userID, members.RoleEditor)
+	if !ok {
+		return
+	}
+	if err := database.GetDB().Unscoped().Delete(&archived).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete archived coin"})
+		return
+	}
+
+	events.Publish(events.CoinDeleted{UserID: userID.(uuid.UUID), Coin: archived.Coin})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Archived coin deleted successfully"})
+}
+
+// GetRealizedGainsReport lists the gains and losses on coins sold or melted
+// in a calendar year (?year=, default the current one), split into short and
+// long term holdings
+func GetRealizedGainsReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	year := time.Now().Year()
+	if raw := c.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+			return
+		}
+		year = parsed
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	portfolios := database.GetReadDB().Model(&models.Portfolio{}).Select("id").Where("user_id = ?", userID)
+	var archived []models.ArchivedCoin
+	if err := database.GetReadDB().
+		Where("portfolio_id IN (?) AND disposed_at >= ? AND disposed_at < ?", portfolios, from, to).
+		Find(&archived).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch archived coins"})
+		return
+	}
+
+	gains := []archive.RealizedGain{}
+	var totals RealizedGainsTotals
+	for _, a := range archived {
+		gain, ok := archive.Realized(a)
+		if !ok {
+			continue
+		}
+		gains = append(gains, gain)
+		totals.Proceeds += gain.Proceeds
+		totals.CostBasis += gain.CostBasis
+		totals.Gain += gain.Gain
+		if gain.LongTerm {
+			totals.LongTerm += gain.Gain
+		} else {
+			totals.ShortTerm += gain.Gain
+		}
+	}
+	sort.Slice(gains, func(i, j int) bool { return gains[i].DisposedAt.Before(gains[j].DisposedAt) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"year":   year,
+		"gains":  gains,
+		"totals": totals,
+	})
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/audit"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// parseAuditTime reads a from/to bound as an RFC 3339 time or a date; a
+// date bound covers the whole day, so to=2025-06-30 includes June 30th
+func parseAuditTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+// GetAuditLog lists what was done to the user's account, newest first:
+// sign-ins, coin and portfolio changes and PCGS syncs. ?action= and
+// ?from=/?to= (dates or RFC 3339 times) filter it; pages are cursor paged
+// with ?after= and ?limit=.
+func GetAuditLog(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	query := database.GetReadDB().Model(&models.AuditLog{}).Where("user_id = ?", userID)
+	if action := c.Query("action"); action != "" {
+		if !audit.Known(action) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown action", "actions": audit.Actions})
+			return
+		}
+		query = query.Where("action = ?", action)
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := parseAuditTime(value, false)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (YYYY-MM-DD) or an RFC 3339 time"})
+			return
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := parseAuditTime(value, true)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (YYYY-MM-DD) or an RFC 3339 time"})
+			return
+		}
+		query = query.Where("created_at < ?", to)
+	}
+
+	query, limit, ok := newestFirstPage(c, query, "created_at")
+	if !ok {
+		return
+	}
+	entries := []models.AuditLog{}
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	if len(entries) > 0 {
+		setNextCursor(c, limit, len(entries), entries[len(entries)-1].ID)
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -1,17 +1,25 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/evansminotwood/aureus/internal/auth"
 	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/demo"
+	"github.com/evansminotwood/aureus/internal/middleware"
 	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/sessions"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required"`
+	InviteCode string `json:"invite_code"`
 }
 
 type LoginRequest struct {
@@ -20,8 +28,61 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+	User             models.User `json:"user"`
+}
+
+// respondWithSession signs the user in: an access token plus a refresh
+// token to get the next one with. refresh continues an existing session
+// rather than starting one.
+func respondWithSession(c *gin.Context, status int, user models.User, refresh *models.RefreshToken, refreshPlain string) {
+	if refresh == nil {
+		issued, plain, err := sessions.Issue(user.ID, c.Request.UserAgent(), c.ClientIP())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+			return
+		}
+		refresh, refreshPlain = &issued, plain
+	}
+
+	token, expiresAt, err := auth.GenerateSessionToken(user.ID, user.Email, user.TenantID, user.TokenVersion, refresh.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	middleware.AuditUser(c, user.ID)
+	c.JSON(status, AuthResponse{
+		Token:            token,
+		ExpiresAt:        expiresAt,
+		RefreshToken:     refreshPlain,
+		RefreshExpiresAt: refresh.ExpiresAt,
+		User:             user,
+	})
+}
+
+// checkPassword answers 400 with the policy's code if a new password falls
+// short of it
+func checkPassword(c *gin.Context, password string) bool {
+	var weak *auth.PasswordError
+	if err := auth.CurrentPasswordPolicy().Validate(password); errors.As(err, &weak) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": weak.Message, "code": weak.Code})
+		return false
+	}
+	return true
+}
+
+// rejectPasswordLogin answers 403 if passwords can't be used because the
+// instance signs in with single sign-on
+func rejectPasswordLogin(c *gin.Context) bool {
+	if auth.PasswordLogin() {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Sign in with single sign-on instead of a password", "code": "password_login_disabled"})
+	return true
 }
 
 func Register(c *gin.Context) {
@@ -30,7 +91,27 @@ func Register(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if rejectPasswordLogin(c) {
+		return
+	}
 
+	// Emails in ADMIN_EMAILS register under the same rules as anyone else;
+	// the first admin of a closed instance comes from `api create-admin`
+	mode := auth.RegistrationMode()
+	if mode == auth.RegistrationDisabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration is disabled", "code": "registration_disabled"})
+		return
+	}
+	needsInvite := mode == auth.RegistrationInvite
+	if needsInvite && strings.TrimSpace(req.InviteCode) == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "An invite code is required to register", "code": "invite_required"})
+		return
+	}
+	if !checkPassword(c, req.Password) {
+		return
+	}
+
+	// Emails are unique across tenants, so an address can only join one tenant
 	var existingUser models.User
 	if err := database.GetDB().Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
@@ -44,25 +125,29 @@ func Register(c *gin.Context) {
 	}
 
 	user := models.User{
+		TenantID: middleware.TenantIDFrom(c),
 		Email:    req.Email,
 		Password: hashedPassword,
 	}
 
-	if err := database.GetDB().Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if needsInvite {
+			if err := redeemInviteCode(tx, req.InviteCode, user.TenantID); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&user).Error
+	})
+	if errors.Is(err, errInvalidInviteCode) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired invite code", "code": "invalid_invite"})
 		return
 	}
-
-	token, err := auth.GenerateToken(user.ID, user.Email)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	respondWithSession(c, http.StatusCreated, user, nil, "")
 }
 
 func Login(c *gin.Context) {
@@ -71,9 +156,17 @@ func Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if rejectPasswordLogin(c) {
+		return
+	}
+
+	query := database.GetDB().Where("email = ?", req.Email)
+	if tenantID := middleware.TenantIDFrom(c); tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
 
 	var user models.User
-	if err := database.GetDB().Where("email = ?", req.Email).First(&user).Error; err != nil {
+	if err := query.First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
@@ -83,16 +176,7 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	token, err := auth.GenerateToken(user.ID, user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
-		return
-	}
-
-	c.JSON(http.StatusOK, AuthResponse{
-		Token: token,
-		User:  user,
-	})
+	respondWithSession(c, http.StatusOK, user, nil, "")
 }
 
 func GetCurrentUser(c *gin.Context) {
@@ -106,3 +190,15 @@ func GetCurrentUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, user)
 }
+
+// GetRegistrationMode tells the signup page whether registration is open,
+// invite-only or disabled, whether passwords can be used at all, what a
+// password has to satisfy, and whether a demo account can be tried instead
+func GetRegistrationMode(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"mode":            auth.RegistrationMode(),
+		"password_login":  auth.PasswordLogin(),
+		"password_policy": auth.CurrentPasswordPolicy(),
+		"demo":            demo.Enabled(),
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// SuggestCoinTypes backs the add-coin type-ahead, ranking catalog coin types
+// by how well they match q and how often the user has used them
+func SuggestCoinTypes(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit := defaultSuggestLimit
+	if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+		limit = min(value, maxSuggestLimit)
+	}
+
+	var rows []struct {
+		CoinType string
+		Count    int
+	}
+	if err := database.GetDB().Table("coins").
+		Select("coins.coin_type, COUNT(*) AS count").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.deleted_at IS NULL", userID).
+		Group("coins.coin_type").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coin usage"})
+		return
+	}
+
+	// Count free-text variants ("walker", "1942 Walker MS65") under their catalog name
+	usage := map[string]int{}
+	for _, row := range rows {
+		if name, _, ok := metals.CanonicalCoinType(row.CoinType); ok {
+			usage[name] += row.Count
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":       c.Query("q"),
+		"suggestions": metals.SuggestCoinTypes(c.Query("q"), usage, limit),
+	})
+}
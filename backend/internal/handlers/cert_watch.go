@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/certwatch"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CertWatchEntryRequest struct {
+	RangeStart string `json:"range_start" binding:"required"`
+	RangeEnd   string `json:"range_end"` // defaults to range_start, for a single cert
+	Reason     string `json:"reason" binding:"required"`
+	Source     string `json:"source"`
+}
+
+// GetCertReviewQueue lists the user's coins whose cert numbers were flagged
+// as possibly counterfeit and haven't been verified
+func GetCertReviewQueue(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coins []models.Coin
+	if err := database.GetReadDB().Table("coins").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.cert_status = ?", userID, certwatch.StatusSuspicious).
+		Order("coins.created_at ASC").
+		Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	valuation.ApplyDerived(coins)
+
+	c.JSON(http.StatusOK, gin.H{
+		"coins": coins,
+		"count": len(coins),
+	})
+}
+
+// VerifyCoinCert records that the owner checked a flagged coin's slab by hand
+// and found it genuine, removing it from the review queue. It stays verified
+// until its cert number changes.
+func VerifyCoinCert(c *gin.Context) {
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleEditor); !ok {
+		return
+	}
+	if coin.CertStatus != certwatch.StatusSuspicious {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coin's cert isn't flagged"})
+		return
+	}
+
+	coin.CertStatus = certwatch.StatusVerified
+	if err := database.GetDB().Model(&coin).Update("cert_status", coin.CertStatus).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coin"})
+		return
+	}
+	valuation.Derive(&coin)
+	c.JSON(http.StatusOK, coin)
+}
+
+// ListCertWatchlist lists the instance's counterfeit watchlist
+func ListCertWatchlist(c *gin.Context) {
+	var entries []models.CertWatchEntry
+	if err := database.GetDB().Order("range_start ASC").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch watchlist"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateCertWatchEntry adds a cert number or range to the counterfeit
+// watchlist and flags the coins already recorded with a cert in it
+func CreateCertWatchEntry(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CertWatchEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.RangeEnd == "" {
+		req.RangeEnd = req.RangeStart
+	}
+	start, okStart := certwatch.ParseCert(req.RangeStart)
+	end, okEnd := certwatch.ParseCert(req.RangeEnd)
+	if !okStart || !okEnd {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range_start and range_end must be cert numbers"})
+		return
+	}
+	if end < start {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range_end can't be before range_start"})
+		return
+	}
+
+	createdBy := userID.(uuid.UUID)
+	entry := models.CertWatchEntry{
+		RangeStart: start,
+		RangeEnd:   end,
+		Reason:     strings.TrimSpace(req.Reason),
+		Source:     strings.TrimSpace(req.Source),
+		CreatedBy:  &createdBy,
+	}
+	if err := database.GetDB().Create(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save watchlist entry"})
+		return
+	}
+
+	flagged, err := certwatch.FlagWatched(entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Saved the entry but failed to flag existing coins"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"entry":         entry,
+		"coins_flagged": flagged,
+	})
+}
+
+// DeleteCertWatchEntry removes a watchlist entry. Coins it flagged stay
+// flagged until their owners verify them.
+func DeleteCertWatchEntry(c *gin.Context) {
+	var entry models.CertWatchEntry
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Watchlist entry not found"})
+		return
+	}
+	if err := database.GetDB().Delete(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete watchlist entry"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Watchlist entry removed"})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/archive"
+	"github.com/evansminotwood/aureus/internal/charts"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/inflation"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxPointsParam reads the max_points query parameter, clamped to the
+// supported range
+func maxPointsParam(c *gin.Context) int {
+	n, _ := strconv.Atoi(c.Query("max_points"))
+	return charts.ClampMaxPoints(n)
+}
+
+// realTerms reports whether ?real=true asked for values in today's dollars,
+// adjusted for inflation with the CPI
+func realTerms(c *gin.Context) bool {
+	enabled, _ := strconv.ParseBool(c.Query("real"))
+	return enabled
+}
+
+// historyRange returns the time span of price history records
+func historyRange(history []models.PriceHistory) (from, to time.Time) {
+	for i, record := range history {
+		if i == 0 || record.RecordedAt.Before(from) {
+			from = record.RecordedAt
+		}
+		if i == 0 || record.RecordedAt.After(to) {
+			to = record.RecordedAt
+		}
+	}
+	return from, to
+}
+
+// GetCoinPriceChart returns a coin's price history binned for charting, with
+// one value per series and bin (the last snapshot in the bin)
+func GetCoinPriceChart(c *gin.Context) {
+	coinID := c.Param("id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
+		return
+	}
+
+	var history []models.PriceHistory
+	if err := database.GetReadDB().Where("coin_id = ?", coin.ID).Find(&history).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+
+	from, to := historyRange(history)
+	binning := charts.NewBinning(from, to, maxPointsParam(c))
+	if len(history) == 0 {
+		binning.Starts = nil
+	}
+
+	melt := make([]charts.Sample, 0, len(history))
+	numismatic := make([]charts.Sample, 0, len(history))
+	pcgs := make([]charts.Sample, 0, len(history))
+	for _, record := range history {
+		melt = append(melt, charts.Sample{Time: record.RecordedAt, Value: record.MeltValue})
+		numismatic = append(numismatic, charts.Sample{Time: record.RecordedAt, Value: record.NumismaticValue})
+		pcgs = append(pcgs, charts.Sample{Time: record.RecordedAt, Value: record.PCGSValue})
+	}
+
+	c.JSON(http.StatusOK, binning.Chart(
+		charts.Series{Name: "melt_value", Data: binning.Close(melt)},
+		charts.Series{Name: "numismatic_value", Data: binning.Close(numismatic)},
+		charts.Series{Name: "pcgs_value", Data: binning.Close(pcgs)},
+	))
+}
+
+// GetPortfolioPerformanceChart returns a portfolio's total melt and
+// numismatic value over time, its value on the portfolio's valuation basis,
+// and its cost basis, binned for charting. Each
+// coin counts from its first snapshot with its latest value carried forward.
+// With ?real=true all series are in today's dollars.
+func GetPortfolioPerformanceChart(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var coins []models.Coin
+	if err := database.GetReadDB().Where("portfolio_id = ?", portfolio.ID).Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	// Coins that left the portfolio count until they were disposed of
+	var archived []models.ArchivedCoin
+	if err := database.GetReadDB().Where("portfolio_id = ?", portfolio.ID).Find(&archived).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	disposedAt := make(map[uuid.UUID]time.Time, len(archived))
+	for _, a := range archived {
+		// A regraded coin's replacement carries its history
+		if a.Disposition == archive.DispositionRegraded {
+			continue
+		}
+		coins = append(coins, a.Coin)
+		disposedAt[a.ID] = a.DisposedAt
+	}
+
+	coinIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		coinIDs[i] = coin.ID
+	}
+	var history []models.PriceHistory
+	if len(coinIDs) > 0 {
+		if err := database.GetReadDB().Where("coin_id IN ?", coinIDs).Find(&history).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+			return
+		}
+	}
+
+	from, to := historyRange(history)
+	for _, at := range disposedAt {
+		if len(history) > 0 && at.After(to) {
+			to = at
+		}
+	}
+	binning := charts.NewBinning(from, to, maxPointsParam(c))
+	if len(history) == 0 {
+		binning.Starts = nil
+	}
+
+	// In real terms every amount is restated in today's dollars
+	adjust := func(value float64, at time.Time) float64 { return value }
+	if realTerms(c) {
+		cpi, now := inflation.Current(), time.Now()
+		adjust = func(value float64, at time.Time) float64 { return cpi.Adjust(value, at, now) }
+	}
+
+	byCoin := make(map[uuid.UUID][]models.PriceHistory)
+	for _, record := range history {
+		byCoin[record.CoinID] = append(byCoin[record.CoinID], record)
+	}
+
+	var melt, numismatic, value, cost [][]*float64
+	for _, coin := range coins {
+		records := byCoin[coin.ID]
+		if len(records) == 0 {
+			continue
+		}
+		quantity := float64(coin.Quantity)
+
+		var meltSamples, numismaticSamples, valueSamples []charts.Sample
+		first := records[0].RecordedAt
+		for _, record := range records {
+			meltSamples = append(meltSamples, charts.Sample{Time: record.RecordedAt, Value: adjust(record.MeltValue*quantity, record.RecordedAt)})
+			numismaticSamples = append(numismaticSamples, charts.Sample{Time: record.RecordedAt, Value: adjust(record.NumismaticValue*quantity, record.RecordedAt)})
+			basisValue := valuation.Value(portfolio.ValuationBasis, record.MeltValue, valuation.AdjustedNumismaticValue(coin, record.NumismaticValue))
+			valueSamples = append(valueSamples, charts.Sample{Time: record.RecordedAt, Value: adjust(basisValue*quantity, record.RecordedAt)})
+			if record.RecordedAt.Before(first) {
+				first = record.RecordedAt
+			}
+		}
+		costBasis := adjust(valuation.AllInCost(coin), valuation.AcquiredAt(coin))
+		costSamples := []charts.Sample{{Time: first, Value: costBasis}}
+		if at, ok := disposedAt[coin.ID]; ok {
+			gone := charts.Sample{Time: at}
+			meltSamples = append(meltSamples, gone)
+			numismaticSamples = append(numismaticSamples, gone)
+			valueSamples = append(valueSamples, gone)
+			costSamples = append(costSamples, gone)
+		}
+		melt = append(melt, binning.Close(meltSamples))
+		numismatic = append(numismatic, binning.Close(numismaticSamples))
+		value = append(value, binning.Close(valueSamples))
+		cost = append(cost, binning.Close(costSamples))
+	}
+
+	n := len(binning.Starts)
+	c.JSON(http.StatusOK, binning.Chart(
+		charts.Series{Name: "melt_value", Data: charts.Sum(n, melt...)},
+		charts.Series{Name: "numismatic_value", Data: charts.Sum(n, numismatic...)},
+		charts.Series{Name: "value", Data: charts.Sum(n, value...)},
+		charts.Series{Name: "cost_basis", Data: charts.Sum(n, cost...)},
+	))
+}
+
+// GetPortfolioHeatMap totals a portfolio's coins by the year they were
+// acquired and the decade they were issued, laid out for a heat map
+func GetPortfolioHeatMap(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	// Coins are acquired at their purchase date, or when they were added
+	// without one, as everywhere else
+	var cells []charts.HeatMapCell
+	if err := database.GetReadDB().Model(&models.Coin{}).
+		Select(`EXTRACT(YEAR FROM COALESCE(purchase_date, created_at) AT TIME ZONE 'UTC')::int AS acquisition_year,
+			CASE WHEN year > 0 THEN year / 10 * 10 END AS decade,
+			COALESCE(SUM(current_value * quantity), 0) AS total_value,
+			COUNT(*) AS count`).
+		Where("portfolio_id = ?", portfolio.ID).
+		Group("acquisition_year, decade").
+		Scan(&cells).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate heat map"})
+		return
+	}
+
+	c.JSON(http.StatusOK, charts.NewHeatMap(cells))
+}
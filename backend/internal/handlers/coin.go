@@ -1,51 +1,117 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/evansminotwood/aureus/internal/certimages"
+	"github.com/evansminotwood/aureus/internal/certwatch"
 	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/enrichment"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/markdown"
+	"github.com/evansminotwood/aureus/internal/members"
 	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/middleware"
 	"github.com/evansminotwood/aureus/internal/models"
 	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/pcgssync"
+	"github.com/evansminotwood/aureus/internal/references"
+	"github.com/evansminotwood/aureus/internal/settings"
+	"github.com/evansminotwood/aureus/internal/trash"
+	"github.com/evansminotwood/aureus/internal/valuation"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type CreateCoinRequest struct {
-	PortfolioID     string  `json:"portfolio_id" binding:"required"`
-	CoinType        string  `json:"coin_type" binding:"required"`
-	Year            int     `json:"year"`
-	MintMark        string  `json:"mint_mark"`
-	Denomination    string  `json:"denomination"`
-	PCGSCertNumber  string  `json:"pcgs_cert_number"`
-	PurchasePrice   float64 `json:"purchase_price"`
-	CurrentValue    float64 `json:"current_value"`
-	NumismaticValue float64 `json:"numismatic_value"`
-	ImageURL        string  `json:"image_url"`
-	ThumbnailURL    string  `json:"thumbnail_url"`
-	Notes           string  `json:"notes"`
-	Quantity        int     `json:"quantity"`
-	MetalType       string  `json:"metal_type"`
-	MetalWeight     float64 `json:"metal_weight"`
-	MetalPurity     float64 `json:"metal_purity"`
+	PortfolioID     string     `json:"portfolio_id"` // defaults to the user's default portfolio
+	CoinType        string     `json:"coin_type" binding:"required"`
+	Year            int        `json:"year"`
+	MintMark        string     `json:"mint_mark"`
+	StrikeType      string     `json:"strike_type"`
+	Denomination    string     `json:"denomination"`
+	FaceValue       float64    `json:"face_value"`
+	FaceCurrency    string     `json:"face_currency"`
+	PCGSCertNumber  string     `json:"pcgs_cert_number"`
+	PurchasePrice   float64    `json:"purchase_price"`
+	BuyersPremium   float64    `json:"buyers_premium"`
+	ShippingCost    float64    `json:"shipping_cost"`
+	SalesTax        float64    `json:"sales_tax"`
+	PurchaseDate    *time.Time `json:"purchase_date"` // defaults to now
+	CurrentValue    float64    `json:"current_value"`
+	NumismaticValue float64    `json:"numismatic_value"`
+	InsuredValue    float64    `json:"insured_value"`
+	ImageURL        string     `json:"image_url"`
+	ThumbnailURL    string     `json:"thumbnail_url"`
+	Notes           string     `json:"notes"`
+	Story           string     `json:"story"` // Markdown
+	Quantity        int        `json:"quantity"`
+	MetalType       string     `json:"metal_type"`
+	MetalWeight     float64    `json:"metal_weight"`
+	MetalPurity     float64    `json:"metal_purity"`
+	Problems        []string   `json:"problems"`
+	EyeAppeal       string     `json:"eye_appeal"`
+	Toning          []string   `json:"toning"`
+	// Links added by hand; PCGS, NGC and auction archive links are filled in
+	References []models.CoinReference `json:"references"`
+	// Left out, these come from the portfolio's defaults
+	StorageLocation string `json:"storage_location"`
+	AutoSync        *bool  `json:"auto_sync"`
 }
 
 type UpdateCoinRequest struct {
-	PortfolioID     string  `json:"portfolio_id"`
-	CoinType        string  `json:"coin_type"`
-	Year            int     `json:"year"`
-	MintMark        string  `json:"mint_mark"`
-	Denomination    string  `json:"denomination"`
-	PCGSCertNumber  string  `json:"pcgs_cert_number"`
-	PurchasePrice   float64 `json:"purchase_price"`
-	CurrentValue    float64 `json:"current_value"`
-	NumismaticValue float64 `json:"numismatic_value"`
-	Notes           string  `json:"notes"`
-	Quantity        int     `json:"quantity"`
-	MetalType       string  `json:"metal_type"`
-	MetalWeight     float64 `json:"metal_weight"`
-	MetalPurity     float64 `json:"metal_purity"`
+	PortfolioID     string   `json:"portfolio_id"`
+	CoinType        string   `json:"coin_type"`
+	Year            int      `json:"year"`
+	MintMark        string   `json:"mint_mark"`
+	StrikeType      string   `json:"strike_type"`
+	Denomination    string   `json:"denomination"`
+	FaceValue       float64  `json:"face_value"`
+	FaceCurrency    string   `json:"face_currency"`
+	PCGSCertNumber  string   `json:"pcgs_cert_number"`
+	PurchasePrice   float64  `json:"purchase_price"`
+	BuyersPremium   *float64 `json:"buyers_premium"` // fees left out are unchanged; 0 clears them
+	ShippingCost    *float64 `json:"shipping_cost"`
+	SalesTax        *float64 `json:"sales_tax"`
+	CurrentValue    float64  `json:"current_value"`
+	NumismaticValue float64  `json:"numismatic_value"`
+	InsuredValue    *float64 `json:"insured_value"` // 0 clears it
+	Notes           string   `json:"notes"`
+	Story           *string  `json:"story"` // left out is unchanged; "" clears it
+	Quantity        int      `json:"quantity"`
+	MetalType       string   `json:"metal_type"`
+	MetalWeight     float64  `json:"metal_weight"`
+	MetalPurity     float64  `json:"metal_purity"`
+	Problems        []string `json:"problems"` // condition fields left out are unchanged; [] or "" clears them
+	EyeAppeal       *string  `json:"eye_appeal"`
+	Toning          []string `json:"toning"`
+	Watched         *bool    `json:"watched"` // left out is unchanged
+	StorageLocation *string  `json:"storage_location"`
+	AutoSync        *bool    `json:"auto_sync"`
+	// Replaces the links added by hand; left out is unchanged
+	References []models.CoinReference `json:"references"`
+}
+
+// maxCoinStoryLength caps a coin's story, like a portfolio's notes
+const maxCoinStoryLength = maxPortfolioNotesLength
+
+// validCoinStory responds with 400 when a story is too long
+func validCoinStory(c *gin.Context, story string) bool {
+	if utf8.RuneCountInString(story) > maxCoinStoryLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("story must be at most %d characters", maxCoinStoryLength)})
+		return false
+	}
+	return true
 }
 
 func CreateCoin(c *gin.Context) {
@@ -57,12 +123,50 @@ func CreateCoin(c *gin.Context) {
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", req.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+	if req.PortfolioID == "" {
+		prefs, err := settings.For(userID.(uuid.UUID))
+		if err != nil || prefs.DefaultPortfolioID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "portfolio_id is required unless a default portfolio is set"})
+			return
+		}
+		req.PortfolioID = prefs.DefaultPortfolioID.String()
+	}
+
+	portfolio, ok := findPortfolio(c, req.PortfolioID, members.RoleEditor)
+	if !ok {
 		return
 	}
 
+	if !metals.ValidStrikeType(req.StrikeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid strike type: " + req.StrikeType})
+		return
+	}
+	if !validCoinStory(c, req.Story) {
+		return
+	}
+
+	if err := metals.ValidateCoinIssue(req.CoinType, req.Year, req.Denomination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.BuyersPremium < 0 || req.ShippingCost < 0 || req.SalesTax < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Buyer's premium, shipping and sales tax can't be negative"})
+		return
+	}
+	if req.InsuredValue < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insured value can't be negative"})
+		return
+	}
+	if req.FaceCurrency != "" {
+		currency, ok := metals.NormalizeCurrency(req.FaceCurrency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid face currency: " + req.FaceCurrency})
+			return
+		}
+		req.FaceCurrency = currency
+	}
+
 	portfolioUUID, err := uuid.Parse(req.PortfolioID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
@@ -70,89 +174,103 @@ func CreateCoin(c *gin.Context) {
 	}
 
 	now := time.Now()
+	purchaseDate := now
+	if req.PurchaseDate != nil {
+		if req.PurchaseDate.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Purchase date can't be in the future"})
+			return
+		}
+		purchaseDate = *req.PurchaseDate
+	}
 	coin := models.Coin{
 		PortfolioID:     portfolioUUID,
 		CoinType:        req.CoinType,
 		Year:            req.Year,
 		MintMark:        req.MintMark,
+		StrikeType:      req.StrikeType,
 		Denomination:    req.Denomination,
+		FaceValue:       req.FaceValue,
+		FaceCurrency:    req.FaceCurrency,
 		PCGSCertNumber:  req.PCGSCertNumber,
 		PurchasePrice:   req.PurchasePrice,
-		PurchaseDate:    &now,
+		BuyersPremium:   req.BuyersPremium,
+		ShippingCost:    req.ShippingCost,
+		SalesTax:        req.SalesTax,
+		PurchaseDate:    &purchaseDate,
 		CurrentValue:    req.CurrentValue,
 		NumismaticValue: req.NumismaticValue,
+		InsuredValue:    req.InsuredValue,
 		LastPriceUpdate: &now,
 		ImageURL:        req.ImageURL,
 		ThumbnailURL:    req.ThumbnailURL,
 		Notes:           req.Notes,
+		Story:           req.Story,
 		Quantity:        req.Quantity,
 		MetalType:       req.MetalType,
 		MetalWeight:     req.MetalWeight,
 		MetalPurity:     req.MetalPurity,
+		Problems:        req.Problems,
+		EyeAppeal:       req.EyeAppeal,
+		Toning:          req.Toning,
+		StorageLocation: strings.TrimSpace(req.StorageLocation),
+		AutoSync:        req.AutoSync,
 	}
-
-	// Auto-fetch PCGS images if cert number is provided and no image URL is set
-	if req.PCGSCertNumber != "" && req.ImageURL == "" {
-		pcgsClient := pcgs.NewPCGSClient()
-		imageData, err := pcgsClient.GetCoinImagesByCertNumber(req.PCGSCertNumber)
-		if err == nil && imageData.IsValidRequest && len(imageData.Images) > 0 {
-			// Set the first image as the main image
-			coin.ImageURL = imageData.GetFrontImageURL()
-			// Set the second image as thumbnail if available
-			if len(imageData.Images) > 1 {
-				coin.ThumbnailURL = imageData.GetBackImageURL()
-			}
-		}
+	if err := valuation.NormalizeCondition(&coin); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	owned, err := references.Normalize(req.References)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	coin.References = owned
 
 	if coin.Quantity == 0 {
 		coin.Quantity = 1
 	}
 
-	// Auto-populate metal composition if not provided
-	// Use year-based lookup for accurate composition
-	if coin.MetalType == "" || coin.MetalWeight == 0 || coin.MetalPurity == 0 {
-		var comp metals.MetalComposition
-		var exists bool
-
-		// Try year-based composition first (more accurate)
-		if coin.Year > 0 {
-			comp, exists = metals.GetCompositionByYear(coin.CoinType, coin.Year)
-		} else {
-			// Fall back to static composition if no year provided
-			comp, exists = metals.GetComposition(coin.CoinType)
-		}
+	if coin.StrikeType == "" {
+		coin.StrikeType = metals.InferStrikeType(coin.CoinType)
+	}
+	fillSeriesReference(&coin)
+	applyPortfolioDefaults(&coin, portfolio)
 
-		if exists {
-			coin.MetalType = comp.MetalType
-			coin.MetalWeight = comp.Weight
-			coin.MetalPurity = comp.Purity
+	fillComposition(&coin, portfolio.ValuationBasis)
 
-			// Calculate melt value using composition (handles both precious and base metals)
-			if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
-				coin.CurrentValue = meltValue
-			}
-		}
+	// The cert is looked up at PCGS in the background, which fills in the
+	// images, series, mintage and price guide value the owner left out
+	if enrichment.Needed(coin) {
+		coin.EnrichmentStatus = enrichment.StatusPending
 	}
+	references.Refresh(&coin, "")
 
-	// Always calculate melt value if we have metal data but no current value
-	// This handles cases where composition lookup failed but we have metal data
-	if coin.CurrentValue == 0 && coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
-		if meltValue, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
-			coin.CurrentValue = meltValue
-		}
-	}
+	// Flag possibly counterfeit certs for the owner to verify; it doesn't
+	// stop the coin being saved
+	certSuspicious := certwatch.Apply(&coin)
 
 	if err := database.GetDB().Create(&coin).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coin"})
 		return
 	}
 
+	events.Publish(events.CoinCreated{UserID: userID.(uuid.UUID), Coin: coin})
+	if certSuspicious {
+		events.Publish(events.CertFlagged{UserID: userID.(uuid.UUID), Coin: coin})
+	}
+
+	valuation.Derive(&coin)
+	middleware.AuditTarget(c, coin.ID)
 	c.JSON(http.StatusCreated, coin)
 }
 
+// CoinDetail is a coin with every certification image variant recorded for it
+type CoinDetail struct {
+	models.Coin
+	Images []models.CoinImage `json:"images"`
+}
+
 func GetCoin(c *gin.Context) {
-	userID, _ := c.Get("user_id")
 	coinID := c.Param("id")
 
 	var coin models.Coin
@@ -161,13 +279,59 @@ func GetCoin(c *gin.Context) {
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", coin.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, coin)
+	coins := []models.Coin{coin}
+	valuation.RefreshMeltValues(coins)
+
+	detail := CoinDetail{Coin: coins[0], Images: []models.CoinImage{}}
+	database.GetReadDB().Where("coin_id = ?", coin.ID).Order("position ASC").Find(&detail.Images)
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// ExplainCoinValuation describes how a coin's current_value was derived
+func ExplainCoinValuation(c *gin.Context) {
+	coinID := c.Param("id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	portfolio, ok := coinPortfolio(c, coin, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spot prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, valuation.Explain(coin, calc, portfolio.ValuationBasis))
+}
+
+// GetCoinStory returns a coin's story as written and rendered as HTML that
+// is safe to embed in a page
+func GetCoinStory(c *gin.Context) {
+	coinID := c.Param("id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"story": coin.Story, "html": markdown.Render(coin.Story)})
 }
 
 func UpdateCoin(c *gin.Context) {
@@ -180,9 +344,8 @@ func UpdateCoin(c *gin.Context) {
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", coin.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	portfolio, ok := coinPortfolio(c, coin, members.RoleEditor)
+	if !ok {
 		return
 	}
 
@@ -192,11 +355,14 @@ func UpdateCoin(c *gin.Context) {
 		return
 	}
 
+	oldCurrentValue, oldNumismaticValue := coin.CurrentValue, coin.NumismaticValue
+
 	// Handle portfolio move if requested
 	if req.PortfolioID != "" && req.PortfolioID != coin.PortfolioID.String() {
-		// Validate that the destination portfolio exists and belongs to the user
-		var destPortfolio models.Portfolio
-		if err := database.GetDB().Where("id = ? AND user_id = ?", req.PortfolioID, userID).First(&destPortfolio).Error; err != nil {
+		// Validate that the destination portfolio exists and the user can
+		// add coins to it
+		destPortfolio, _, err := members.Find(userID.(uuid.UUID), req.PortfolioID, members.RoleEditor)
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Destination portfolio not found or access denied"})
 			return
 		}
@@ -208,8 +374,11 @@ func UpdateCoin(c *gin.Context) {
 			return
 		}
 		coin.PortfolioID = destPortfolioUUID
+		portfolio = destPortfolio
+		valuation.ApplyBasis(&coin, portfolio.ValuationBasis)
 	}
 
+	oldCoinType, oldYear, oldDenomination := coin.CoinType, coin.Year, coin.Denomination
 	if req.CoinType != "" {
 		coin.CoinType = req.CoinType
 	}
@@ -217,16 +386,55 @@ func UpdateCoin(c *gin.Context) {
 		coin.Year = req.Year
 	}
 	coin.MintMark = req.MintMark
+
+	if !metals.ValidStrikeType(req.StrikeType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid strike type: " + req.StrikeType})
+		return
+	}
+	strikeChanged := req.StrikeType != "" && req.StrikeType != coin.StrikeType
+	if strikeChanged {
+		coin.StrikeType = req.StrikeType
+	}
 	coin.Denomination = req.Denomination
+	if req.FaceValue != 0 {
+		coin.FaceValue = req.FaceValue
+	} else if coin.CoinType != oldCoinType {
+		// Re-derive the face value from the new coin type
+		coin.FaceValue, coin.FaceCurrency = 0, ""
+	}
+	if req.FaceCurrency != "" {
+		currency, ok := metals.NormalizeCurrency(req.FaceCurrency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid face currency: " + req.FaceCurrency})
+			return
+		}
+		coin.FaceCurrency = currency
+	}
+	fillSeriesReference(&coin)
+
+	// Only validate what changed so older records stay editable
+	if coin.CoinType != oldCoinType || coin.Year != oldYear || coin.Denomination != oldDenomination {
+		if err := metals.ValidateCoinIssue(coin.CoinType, coin.Year, coin.Denomination); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	// If PCGS cert number is being updated, fetch images
 	pcgsCertChanged := req.PCGSCertNumber != "" && req.PCGSCertNumber != coin.PCGSCertNumber
-	coin.PCGSCertNumber = req.PCGSCertNumber
+	certSuspicious := false
+	if req.PCGSCertNumber != coin.PCGSCertNumber {
+		coin.PCGSCertNumber = req.PCGSCertNumber
+		certSuspicious = certwatch.Apply(&coin)
+	}
 
+	var certImages []pcgs.ImageDetail
+	pcgsNumber := references.PCGSNumber(coin)
 	if pcgsCertChanged {
-		pcgsClient := pcgs.NewPCGSClient()
+		pcgsClient := pcgsClientForUser(c)
 		imageData, err := pcgsClient.GetCoinImagesByCertNumber(req.PCGSCertNumber)
 		if err == nil && imageData.IsValidRequest && len(imageData.Images) > 0 {
+			certImages = imageData.Images
 			// Set the first image as the main image
 			coin.ImageURL = imageData.GetFrontImageURL()
 			// Set the second image as thumbnail if available
@@ -234,23 +442,95 @@ func UpdateCoin(c *gin.Context) {
 				coin.ThumbnailURL = imageData.GetBackImageURL()
 			}
 		}
+		// CoinFacts pages are keyed by the PCGS number behind the new cert
+		pcgsNumber = ""
+		if coinData, err := pcgsClient.GetCoinDataByCertNumber(req.PCGSCertNumber); err == nil && coinData.IsValidRequest {
+			pcgsNumber = coinData.PCGSNo
+		}
+	} else if coin.PCGSCertNumber == "" {
+		pcgsNumber = ""
+	}
+	if req.References != nil {
+		owned, err := references.Normalize(req.References)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		coin.References = owned
 	}
+	references.Refresh(&coin, pcgsNumber)
 
 	if req.PurchasePrice != 0 {
 		coin.PurchasePrice = req.PurchasePrice
 	}
+	if req.BuyersPremium != nil {
+		coin.BuyersPremium = *req.BuyersPremium
+	}
+	if req.ShippingCost != nil {
+		coin.ShippingCost = *req.ShippingCost
+	}
+	if req.SalesTax != nil {
+		coin.SalesTax = *req.SalesTax
+	}
+	if coin.BuyersPremium < 0 || coin.ShippingCost < 0 || coin.SalesTax < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Buyer's premium, shipping and sales tax can't be negative"})
+		return
+	}
+	if req.InsuredValue != nil {
+		if *req.InsuredValue < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Insured value can't be negative"})
+			return
+		}
+		coin.InsuredValue = *req.InsuredValue
+	}
+	if req.Problems != nil || req.EyeAppeal != nil || req.Toning != nil {
+		if req.Problems != nil {
+			coin.Problems = req.Problems
+		}
+		if req.EyeAppeal != nil {
+			coin.EyeAppeal = *req.EyeAppeal
+		}
+		if req.Toning != nil {
+			coin.Toning = req.Toning
+		}
+		if err := valuation.NormalizeCondition(&coin); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		valuation.ApplyBasis(&coin, portfolio.ValuationBasis)
+	}
 	if req.CurrentValue != 0 {
 		coin.CurrentValue = req.CurrentValue
 		now := time.Now()
 		coin.LastPriceUpdate = &now
 	}
 	if req.NumismaticValue != 0 {
-		coin.NumismaticValue = req.NumismaticValue
+		valuation.ApplyNumismaticValue(&coin, req.NumismaticValue, portfolio.ValuationBasis)
 	}
 	if req.Quantity != 0 {
 		coin.Quantity = req.Quantity
 	}
 	coin.Notes = req.Notes
+	if req.Story != nil {
+		if !validCoinStory(c, *req.Story) {
+			return
+		}
+		coin.Story = *req.Story
+	}
+	if req.Watched != nil {
+		coin.Watched = *req.Watched
+	}
+	if req.StorageLocation != nil {
+		coin.StorageLocation = strings.TrimSpace(*req.StorageLocation)
+	}
+	if req.AutoSync != nil {
+		coin.AutoSync = req.AutoSync
+	}
+
+	// The edit form resends unchanged metal fields, so only differing values count as a manual edit
+	metalEdited := (req.MetalType != "" && req.MetalType != coin.MetalType) ||
+		(req.MetalWeight != 0 && req.MetalWeight != coin.MetalWeight) ||
+		(req.MetalPurity != 0 && req.MetalPurity != coin.MetalPurity)
 
 	if req.MetalType != "" {
 		coin.MetalType = req.MetalType
@@ -262,20 +542,32 @@ func UpdateCoin(c *gin.Context) {
 		coin.MetalPurity = req.MetalPurity
 	}
 
-	// Auto-populate metal composition if not provided and coin type or year changed
-	if (req.CoinType != "" || req.Year != 0) && (coin.MetalType == "" || coin.MetalWeight == 0 || coin.MetalPurity == 0) {
-		var comp metals.MetalComposition
-		var exists bool
+	// A new strike type can change the composition (e.g. silver proofs of clad series)
+	if strikeChanged && !metalEdited && coin.CompositionSource != metals.CompositionSourceManual {
+		if match, exists := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType); exists {
+			comp := match.Composition
+			coin.MetalType = comp.MetalType
+			coin.MetalWeight = comp.Weight
+			coin.MetalPurity = comp.Purity
+			coin.CompositionSource = match.Method
+			coin.CompositionConfidence = match.Confidence
 
-		// Try year-based composition first (more accurate)
-		if coin.Year > 0 {
-			comp, exists = metals.GetCompositionByYear(coin.CoinType, coin.Year)
-		} else {
-			// Fall back to static composition if no year provided
-			comp, exists = metals.GetComposition(coin.CoinType)
+			if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
+				valuation.ApplyMeltValue(&coin, meltValue, portfolio.ValuationBasis)
+				now := time.Now()
+				coin.LastPriceUpdate = &now
+			}
 		}
+	}
+
+	// Auto-populate metal composition if not provided and coin type or year changed
+	if (req.CoinType != "" || req.Year != 0) && (coin.MetalType == "" || coin.MetalWeight == 0 || coin.MetalPurity == 0) {
+		match, exists := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType)
 
 		if exists {
+			comp := match.Composition
+			coin.CompositionSource = match.Method
+			coin.CompositionConfidence = match.Confidence
 			if coin.MetalType == "" {
 				coin.MetalType = comp.MetalType
 			}
@@ -288,19 +580,24 @@ func UpdateCoin(c *gin.Context) {
 
 			// Calculate melt value using composition (handles both precious and base metals)
 			if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
-				coin.CurrentValue = meltValue
+				valuation.ApplyMeltValue(&coin, meltValue, portfolio.ValuationBasis)
 				now := time.Now()
 				coin.LastPriceUpdate = &now
 			}
 		}
 	}
 
+	if metalEdited {
+		coin.CompositionSource = metals.CompositionSourceManual
+		coin.CompositionConfidence = metals.ConfidenceHigh
+	}
+
 	// Always recalculate melt value if metal data changed
 	// This handles cases where composition lookup failed but we have metal data
 	if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 &&
 		(req.MetalType != "" || req.MetalWeight != 0 || req.MetalPurity != 0 || coin.CurrentValue == 0) {
 		if meltValue, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
-			coin.CurrentValue = meltValue
+			valuation.ApplyMeltValue(&coin, meltValue, portfolio.ValuationBasis)
 			now := time.Now()
 			coin.LastPriceUpdate = &now
 		}
@@ -311,11 +608,30 @@ func UpdateCoin(c *gin.Context) {
 		return
 	}
 
+	if coin.CurrentValue != oldCurrentValue || coin.NumismaticValue != oldNumismaticValue {
+		events.Publish(events.CoinValued{
+			UserID:             userID.(uuid.UUID),
+			CoinID:             coin.ID,
+			PortfolioID:        coin.PortfolioID,
+			Source:             "update",
+			OldCurrentValue:    oldCurrentValue,
+			NewCurrentValue:    coin.CurrentValue,
+			OldNumismaticValue: oldNumismaticValue,
+			NewNumismaticValue: coin.NumismaticValue,
+		})
+	}
+	if certSuspicious {
+		events.Publish(events.CertFlagged{UserID: userID.(uuid.UUID), Coin: coin})
+	}
+	archiveCertImages(userID.(uuid.UUID), coin, certImages)
+
+	valuation.Derive(&coin)
 	c.JSON(http.StatusOK, coin)
 }
 
+// DeleteCoin moves a coin to the trash, where it can be restored until it's
+// purged
 func DeleteCoin(c *gin.Context) {
-	userID, _ := c.Get("user_id")
 	coinID := c.Param("id")
 
 	var coin models.Coin
@@ -324,9 +640,7 @@ func DeleteCoin(c *gin.Context) {
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", coin.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if _, ok := coinPortfolio(c, coin, members.RoleEditor); !ok {
 		return
 	}
 
@@ -335,83 +649,224 @@ func DeleteCoin(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Coin deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Coin moved to the trash", "purge_at": trash.PurgeAt(time.Now())})
+}
+
+// maxCoinPageSize caps the limit of a paged coin listing
+const maxCoinPageSize = 500
+
+// conditionFilter narrows a coin query by condition: ?problem= (comma-separated,
+// coins with all of them), ?problem_free=true, ?eye_appeal= (comma-separated,
+// any of them) and ?toning= (a descriptor). It responds with 400 on an
+// unknown problem or eye appeal.
+func conditionFilter(c *gin.Context) (func(*gorm.DB) *gorm.DB, bool) {
+	var problems, eyeAppeals []string
+	if raw := c.Query("problem"); raw != "" {
+		for _, problem := range strings.Split(raw, ",") {
+			problem = strings.ToLower(strings.TrimSpace(problem))
+			if !slices.Contains(valuation.Problems, problem) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown problem %q, must be one of %v", problem, valuation.Problems)})
+				return nil, false
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if raw := c.Query("eye_appeal"); raw != "" {
+		for _, level := range strings.Split(raw, ",") {
+			level = strings.ToLower(strings.TrimSpace(level))
+			if !slices.Contains(valuation.EyeAppeals, level) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("eye_appeal must be one of %v", valuation.EyeAppeals)})
+				return nil, false
+			}
+			eyeAppeals = append(eyeAppeals, level)
+		}
+	}
+	problemFree := c.Query("problem_free") == "true"
+	toning := strings.ToLower(strings.TrimSpace(c.Query("toning")))
+
+	return func(db *gorm.DB) *gorm.DB {
+		if len(problems) > 0 {
+			encoded, _ := json.Marshal(problems)
+			db = db.Where("problems @> ?::jsonb", string(encoded))
+		}
+		if problemFree {
+			db = db.Where("(problems IS NULL OR problems = 'null'::jsonb OR problems = '[]'::jsonb)")
+		}
+		if len(eyeAppeals) > 0 {
+			db = db.Where("eye_appeal IN ?", eyeAppeals)
+		}
+		if toning != "" {
+			encoded, _ := json.Marshal([]string{toning})
+			db = db.Where("toning @> ?::jsonb", string(encoded))
+		}
+		return db
+	}, true
 }
 
 func GetPortfolioCoins(c *gin.Context) {
-	userID, _ := c.Get("user_id")
 	portfolioID := c.Param("id")
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", portfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+	if _, ok := findPortfolio(c, portfolioID, members.RoleViewer); !ok {
+		return
+	}
+
+	filter, ok := conditionFilter(c)
+	if !ok {
 		return
 	}
+	query := filter(database.GetReadDB().Where("portfolio_id = ?", portfolioID))
+
+	// Paging is opt-in: without limit every coin is returned
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil && limit > 0 {
+		var total int64
+		if err := filter(database.GetReadDB().Model(&models.Coin{}).Where("portfolio_id = ?", portfolioID)).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+			return
+		}
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+
+		offset, _ := strconv.Atoi(c.Query("offset"))
+		query = query.Order("created_at ASC, id ASC").Limit(min(limit, maxCoinPageSize)).Offset(max(offset, 0))
+	}
 
 	var coins []models.Coin
-	if err := database.GetDB().Where("portfolio_id = ?", portfolioID).Find(&coins).Error; err != nil {
+	if err := query.Find(&coins).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
 		return
 	}
 
+	valuation.RefreshMeltValues(coins)
+
 	c.JSON(http.StatusOK, coins)
 }
 
+// SyncPCGSValues refreshes numismatic values from PCGS for the user's coins
+// with a cert number, as a background job whose result counts the coins
+// updated, skipped and failed. It can be narrowed with ?portfolio_id= and
+// ?coin_ids= (comma-separated), and ?max_age_days= skips coins synced more
+// recently.
 func SyncPCGSValues(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	db := database.GetDB()
-
-	// Get all coins for this user that have PCGS cert numbers
-	var coins []models.Coin
-	if err := db.Table("coins").
-		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
-		Where("portfolios.user_id = ? AND coins.pcgs_cert_number != ''", userID).
-		Find(&coins).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch coins",
-		})
-		return
+	var opts pcgssync.Options
+	if portfolioID := c.Query("portfolio_id"); portfolioID != "" {
+		id, err := uuid.Parse(portfolioID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
+			return
+		}
+		opts.PortfolioID = &id
+	}
+	if coinIDs := c.Query("coin_ids"); coinIDs != "" {
+		for _, raw := range strings.Split(coinIDs, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(raw))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coin ID: " + raw})
+				return
+			}
+			opts.CoinIDs = append(opts.CoinIDs, id)
+		}
+	}
+	if maxAge := c.Query("max_age_days"); maxAge != "" {
+		days, err := strconv.Atoi(maxAge)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_age_days must be a non-negative integer"})
+			return
+		}
+		opts.MaxAge = time.Duration(days) * 24 * time.Hour
 	}
 
-	pcgsClient := pcgs.NewPCGSClient()
-	updated := 0
-	failed := 0
-	errors := []string{}
+	startJob(c, jobs.KindPCGSSync, func(p *jobs.Progress) (any, error) {
+		opts.Progress = p.Set
+		return pcgssync.Sync(userID.(uuid.UUID), opts)
+	})
+}
 
-	for _, coin := range coins {
-		// Fetch PCGS price data
-		priceData, err := pcgsClient.GetPriceData(coin.PCGSCertNumber)
-		if err != nil {
-			failed++
-			errors = append(errors, coin.PCGSCertNumber+": "+err.Error())
-			continue
+// archiveCertImages records certification image variants and copies them
+// into image storage in the background so saving the coin isn't held up by
+// the downloads
+func archiveCertImages(userID uuid.UUID, coin models.Coin, images []pcgs.ImageDetail) {
+	if len(images) == 0 {
+		return
+	}
+	go func() {
+		if err := certimages.Archive(userID, coin, images); err != nil {
+			log.Printf("Failed to archive certification images for coin %s: %v", coin.ID, err)
 		}
+	}()
+}
 
-		// Update numismatic value if we got a valid price
-		if priceData.Price > 0 {
-			coin.NumismaticValue = priceData.Price
+// fillComposition fills in a new coin's metal composition from the catalog
+// when it wasn't entered, and values it at current spot prices on basis
+func fillComposition(coin *models.Coin, basis string) {
+	// Auto-populate metal composition if not provided
+	// Use year-based lookup for accurate composition
+	if coin.MetalType == "" || coin.MetalWeight == 0 || coin.MetalPurity == 0 {
+		match, exists := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType)
+
+		if exists {
+			comp := match.Composition
+			coin.MetalType = comp.MetalType
+			coin.MetalWeight = comp.Weight
+			coin.MetalPurity = comp.Purity
+			coin.CompositionSource = match.Method
+			coin.CompositionConfidence = match.Confidence
 
-			// Save the updated coin
-			if err := db.Save(&coin).Error; err != nil {
-				failed++
-				errors = append(errors, coin.PCGSCertNumber+": failed to save")
-			} else {
-				updated++
+			// Calculate melt value using composition (handles both precious and base metals)
+			if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
+				valuation.ApplyMeltValue(coin, meltValue, basis)
 			}
 		}
 	}
 
-	response := gin.H{
-		"message":     "PCGS value sync complete",
-		"total_coins": len(coins),
-		"updated":     updated,
-		"failed":      failed,
+	if coin.CompositionSource == "" && coin.MetalType != "" {
+		coin.CompositionSource = metals.CompositionSourceManual
+		coin.CompositionConfidence = metals.ConfidenceHigh
+	}
+
+	// Always calculate melt value if we have metal data but no current value
+	// This handles cases where composition lookup failed but we have metal data
+	if coin.CurrentValue == 0 && coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
+		if meltValue, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
+			valuation.ApplyMeltValue(coin, meltValue, basis)
+		}
 	}
+}
 
-	if len(errors) > 0 {
-		response["errors"] = errors
+// applyPortfolioDefaults fills in what a new coin left out from its
+// portfolio's defaults. It runs after fillSeriesReference, so a known
+// series' own currency wins over the portfolio's.
+func applyPortfolioDefaults(coin *models.Coin, portfolio models.Portfolio) {
+	if coin.FaceCurrency == "" {
+		coin.FaceCurrency = portfolio.DefaultFaceCurrency
+	}
+	if coin.StorageLocation == "" {
+		coin.StorageLocation = portfolio.DefaultStorageLocation
+	}
+	if coin.AutoSync == nil && portfolio.DefaultAutoSync != nil {
+		autoSync := *portfolio.DefaultAutoSync
+		coin.AutoSync = &autoSync
 	}
+}
 
-	c.JSON(http.StatusOK, response)
+// fillSeriesReference fills in the series name, denomination and face value
+// of a known series when the user left them blank
+func fillSeriesReference(coin *models.Coin) {
+	name, info, ok := metals.LookupSeries(coin.CoinType)
+	if !ok {
+		return
+	}
+	if coin.Series == "" {
+		coin.Series = name
+	}
+	if coin.Denomination == "" {
+		coin.Denomination = info.Denomination
+	}
+	if coin.FaceValue == 0 {
+		coin.FaceValue = info.FaceValue
+		coin.FaceCurrency = info.Currency
+	}
+	if coin.FaceCurrency == "" {
+		coin.FaceCurrency = info.Currency
+	}
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/alerts"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateCoinAlertRequest struct {
+	Condition string   `json:"condition" binding:"required"`
+	Metric    string   `json:"metric"` // defaults to melt_value
+	Threshold float64  `json:"threshold" binding:"gte=0"`
+	Channels  []string `json:"channels"`
+}
+
+type UpdateCoinAlertRequest struct {
+	Condition string    `json:"condition"`
+	Metric    string    `json:"metric"`
+	Threshold float64   `json:"threshold"`
+	Enabled   *bool     `json:"enabled"`
+	Channels  *[]string `json:"channels"`
+}
+
+// WatchedCoin is a watched coin with its alerts
+type WatchedCoin struct {
+	models.Coin
+	Alerts []models.CoinAlert `json:"alerts"`
+}
+
+// validateCoinAlert checks a coin alert's condition, metric and threshold,
+// responding with 400 when they don't fit together
+func validateCoinAlert(c *gin.Context, alert *models.CoinAlert) bool {
+	if !alerts.ValidCoinCondition(alert.Condition) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "condition must be 'above', 'below' or 'pcgs_change'"})
+		return false
+	}
+	if alert.Condition == alerts.ConditionPCGSChange {
+		alert.Metric, alert.Threshold = "", 0
+		return true
+	}
+	if alert.Metric == "" {
+		alert.Metric = alerts.MetricMeltValue
+	}
+	if !alerts.ValidCoinMetric(alert.Metric) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric must be 'melt_value' or 'current_value'"})
+		return false
+	}
+	if alert.Threshold <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold is required for 'above' and 'below' alerts"})
+		return false
+	}
+	return true
+}
+
+// findOwnedCoin loads a coin from a portfolio of the user or shared with
+// them, responding with 404 when there is none and 403 when their role on
+// the portfolio is below min
+func findOwnedCoin(c *gin.Context, userID interface{}, min string) (models.Coin, bool) {
+	var coin models.Coin
+	portfolios := members.Accessible(database.GetDB(), userID.(uuid.UUID))
+	if err := database.GetDB().Where("id = ? AND portfolio_id IN (?)", c.Param("id"), portfolios).First(&coin).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return coin, false
+	}
+	_, ok := findPortfolio(c, coin.PortfolioID, min)
+	return coin, ok
+}
+
+// GetWatchedCoins lists the user's watched coins with their alerts
+func GetWatchedCoins(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coins []models.Coin
+	if err := database.GetReadDB().Table("coins").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.watched = ?", userID, true).
+		Order("coins.created_at ASC").
+		Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	valuation.ApplyDerived(coins)
+
+	coinIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		coinIDs[i] = coin.ID
+	}
+	var coinAlerts []models.CoinAlert
+	if len(coinIDs) > 0 {
+		if err := database.GetReadDB().Where("coin_id IN ?", coinIDs).Order("created_at ASC").Find(&coinAlerts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+			return
+		}
+	}
+	byCoin := map[uuid.UUID][]models.CoinAlert{}
+	for _, alert := range coinAlerts {
+		byCoin[alert.CoinID] = append(byCoin[alert.CoinID], alert)
+	}
+
+	watched := make([]WatchedCoin, len(coins))
+	for i, coin := range coins {
+		watched[i] = WatchedCoin{Coin: coin, Alerts: byCoin[coin.ID]}
+		if watched[i].Alerts == nil {
+			watched[i].Alerts = []models.CoinAlert{}
+		}
+	}
+	c.JSON(http.StatusOK, watched)
+}
+
+// GetCoinAlerts lists the alerts configured on a coin
+func GetCoinAlerts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	coin, ok := findOwnedCoin(c, userID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var coinAlerts []models.CoinAlert
+	if err := database.GetDB().Where("coin_id = ?", coin.ID).Order("created_at ASC").Find(&coinAlerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, coinAlerts)
+}
+
+// CreateCoinAlert adds an alert to a coin, watching it if it wasn't already
+func CreateCoinAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	coin, ok := findOwnedCoin(c, userID, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req CreateCoinAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert := models.CoinAlert{
+		CoinID:    coin.ID,
+		UserID:    userID.(uuid.UUID),
+		Condition: req.Condition,
+		Metric:    req.Metric,
+		Threshold: req.Threshold,
+		Enabled:   true,
+		Channels:  req.Channels,
+	}
+	if !validateCoinAlert(c, &alert) || !validateChannels(c, req.Channels) {
+		return
+	}
+
+	if err := database.GetDB().Create(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		return
+	}
+	if !coin.Watched {
+		if err := database.GetDB().Model(&coin).Update("watched", true).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to watch coin"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// UpdateCoinAlert changes a coin alert's condition, metric, threshold or
+// enabled state
+func UpdateCoinAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	var alert models.CoinAlert
+	if err := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).First(&alert).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	var req UpdateCoinAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Condition != "" {
+		alert.Condition = req.Condition
+	}
+	if req.Metric != "" {
+		alert.Metric = req.Metric
+	}
+	if req.Threshold > 0 {
+		alert.Threshold = req.Threshold
+	}
+	if !validateCoinAlert(c, &alert) {
+		return
+	}
+	if req.Enabled != nil {
+		alert.Enabled = *req.Enabled
+	}
+	if req.Channels != nil {
+		if !validateChannels(c, *req.Channels) {
+			return
+		}
+		alert.Channels = *req.Channels
+	}
+
+	// Re-arm the alert so the new settings are evaluated from scratch
+	alert.Triggered = false
+
+	if err := database.GetDB().Save(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// DeleteCoinAlert removes a coin alert. The coin stays watched.
+func DeleteCoinAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).Delete(&models.CoinAlert{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert deleted successfully"})
+}
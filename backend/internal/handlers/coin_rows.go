@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/coinrows"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/transfers"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxSplitRows caps how many rows one split can make
+const maxSplitRows = 100
+
+type SplitCoinRequest struct {
+	Quantities []int `json:"quantities" binding:"required"` // one new row per entry
+}
+
+type MergeCoinsRequest struct {
+	CoinIDs []uuid.UUID `json:"coin_ids" binding:"required"` // rows folded into this one
+}
+
+// SplitCoinResponse is a split coin and the rows split off it
+type SplitCoinResponse struct {
+	Coin     models.Coin   `json:"coin"`
+	NewCoins []models.Coin `json:"new_coins"`
+}
+
+// hasPendingTransfer reports whether any of the coins is on offer to
+// another user, which keeps it from being split or merged away
+func hasPendingTransfer(coinIDs ...uuid.UUID) bool {
+	var pending int64
+	database.GetDB().Model(&models.CoinTransfer{}).Where("coin_id IN ? AND status = ?", coinIDs, transfers.StatusPending).Count(&pending)
+	return pending > 0
+}
+
+// SplitCoin moves some of a coin's quantity into new rows, e.g. to send one
+// off for grading or sell part of a roll. Fees are divided by quantity and
+// each new row gets a copy of the coin's price history.
+func SplitCoin(c *gin.Context) {
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	if _, ok := coinPortfolio(c, coin, members.RoleEditor); !ok {
+		return
+	}
+
+	var req SplitCoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Quantities) > maxSplitRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A coin can be split into at most 100 new rows at once"})
+		return
+	}
+	if hasPendingTransfer(coin.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coin has a pending transfer"})
+		return
+	}
+
+	parts, err := coinrows.Split(&coin, req.Quantities)
+	if err != nil {
+		code := "invalid_split"
+		if errors.Is(err, coinrows.ErrNothingToSplit) {
+			code = "nothing_to_split"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": code})
+		return
+	}
+	if err := coinrows.SaveSplit(&coin, parts); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to split coin"})
+		return
+	}
+
+	coins := append([]models.Coin{coin}, parts...)
+	valuation.RefreshMeltValues(coins)
+	c.JSON(http.StatusCreated, SplitCoinResponse{Coin: coins[0], NewCoins: coins[1:]})
+}
+
+// MergeCoins folds identical rows of the same portfolio into this coin,
+// adding up their quantities and cost basis. The merged rows are deleted.
+func MergeCoins(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	portfolio, ok := coinPortfolio(c, coin, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req MergeCoinsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ids := map[uuid.UUID]bool{}
+	for _, id := range req.CoinIDs {
+		ids[id] = true
+	}
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "coin_ids is required"})
+		return
+	}
+
+	var others []models.Coin
+	if err := database.GetDB().Where("id IN ? AND portfolio_id IN (?)", req.CoinIDs, members.Accessible(database.GetDB(), userID.(uuid.UUID))).
+		Order("created_at ASC").Find(&others).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	if len(others) != len(ids) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	for _, other := range others {
+		if err := coinrows.Mergeable(coin, other); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "not_mergeable", "coin_id": other.ID})
+			return
+		}
+	}
+
+	otherIDs := make([]uuid.UUID, len(others))
+	for i, other := range others {
+		otherIDs[i] = other.ID
+	}
+	if hasPendingTransfer(append(otherIDs, coin.ID)...) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A coin has a pending transfer"})
+		return
+	}
+
+	coinrows.Merge(&coin, others)
+	valuation.ApplyBasis(&coin, portfolio.ValuationBasis)
+	if err := coinrows.SaveMerge(&coin, others); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge coins"})
+		return
+	}
+	for _, other := range others {
+		events.Publish(events.CoinDeleted{UserID: userID.(uuid.UUID), Coin: other})
+	}
+
+	coins := []models.Coin{coin}
+	valuation.RefreshMeltValues(coins)
+	c.JSON(http.StatusOK, coins[0])
+}
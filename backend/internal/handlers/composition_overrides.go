@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var overrideMetalTypes = []string{"gold", "silver", "platinum", "palladium", "copper"}
+
+type CompositionOverrideRequest struct {
+	CoinType      string  `json:"coin_type" binding:"required"`
+	MetalType     string  `json:"metal_type" binding:"required"`
+	Weight        float64 `json:"weight" binding:"gte=0"`
+	Purity        float64 `json:"purity" binding:"gte=0,lte=100"`
+	Description   string  `json:"description"`
+	IsBaseMetal   bool    `json:"is_base_metal"`
+	WeightGrams   float64 `json:"weight_grams" binding:"gte=0"`
+	CopperPercent float64 `json:"copper_percent" binding:"gte=0,lte=100"`
+	NickelPercent float64 `json:"nickel_percent" binding:"gte=0,lte=100"`
+}
+
+// CompositionOverrideSummary is an override alongside the built-in
+// composition it replaces, which is nil for coin types it adds
+type CompositionOverrideSummary struct {
+	models.CompositionOverride
+	Builtin *metals.MetalComposition `json:"builtin"`
+}
+
+func summarizeOverride(o models.CompositionOverride) CompositionOverrideSummary {
+	summary := CompositionOverrideSummary{CompositionOverride: o}
+	if builtin, ok := metals.BuiltinComposition(o.CoinType); ok {
+		summary.Builtin = &builtin
+	}
+	return summary
+}
+
+// ListCompositionOverrides lists the instance's composition overrides
+func ListCompositionOverrides(c *gin.Context) {
+	var stored []models.CompositionOverride
+	if err := database.GetDB().Order("coin_type ASC").Find(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch composition overrides"})
+		return
+	}
+
+	result := make([]CompositionOverrideSummary, len(stored))
+	for i, o := range stored {
+		result[i] = summarizeOverride(o)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// SaveCompositionOverride adds or replaces the composition of a coin type for
+// every user of the instance. Coin types already in the catalog are matched
+// case-insensitively so an override lands on the entry it corrects.
+func SaveCompositionOverride(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CompositionOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coinType := strings.Join(strings.Fields(req.CoinType), " ")
+	if name, how, ok := metals.CanonicalCoinType(coinType); ok && how == metals.MatchExact {
+		coinType = name
+	}
+	metalType := strings.ToLower(strings.TrimSpace(req.MetalType))
+	if !slices.Contains(overrideMetalTypes, metalType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metal_type must be one of " + strings.Join(overrideMetalTypes, ", ")})
+		return
+	}
+	if req.IsBaseMetal {
+		if req.WeightGrams <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight_grams is required for base metal coins"})
+			return
+		}
+		if req.CopperPercent+req.NickelPercent > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "copper_percent and nickel_percent can't add up to more than 100"})
+			return
+		}
+	} else if req.Weight <= 0 || req.Purity <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "weight and purity are required for precious metal coins"})
+		return
+	}
+
+	updatedBy := userID.(uuid.UUID)
+	var override models.CompositionOverride
+	database.GetDB().Where("coin_type = ?", coinType).First(&override)
+	override.CoinType = coinType
+	override.MetalType = metalType
+	override.Weight = req.Weight
+	override.Purity = req.Purity
+	override.Description = strings.TrimSpace(req.Description)
+	override.IsBaseMetal = req.IsBaseMetal
+	override.WeightGrams = req.WeightGrams
+	override.CopperPercent = req.CopperPercent
+	override.NickelPercent = req.NickelPercent
+	override.UpdatedBy = &updatedBy
+	if err := database.GetDB().Save(&override).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save composition override"})
+		return
+	}
+
+	metals.SetOverride(override)
+	c.JSON(http.StatusOK, summarizeOverride(override))
+}
+
+// DeleteCompositionOverride removes an override, restoring the built-in
+// composition of its coin type if there is one
+func DeleteCompositionOverride(c *gin.Context) {
+	var override models.CompositionOverride
+	if err := database.GetDB().Where("id = ?", c.Param("id")).First(&override).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Composition override not found"})
+		return
+	}
+	if err := database.GetDB().Delete(&override).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete composition override"})
+		return
+	}
+
+	metals.RemoveOverride(override.CoinType)
+	c.JSON(http.StatusOK, gin.H{"message": "Composition override removed"})
+}
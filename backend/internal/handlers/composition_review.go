@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CompositionReviewItem is a coin whose composition was guessed, along with
+// the catalog match that produced the guess
+type CompositionReviewItem struct {
+	Coin       models.Coin              `json:"coin"`
+	Suggestion *metals.CompositionMatch `json:"suggestion"`
+}
+
+// ReviewCompositionRequest confirms a guessed composition as-is, or corrects
+// it when any metal field is given
+type ReviewCompositionRequest struct {
+	MetalType   string  `json:"metal_type"`
+	MetalWeight float64 `json:"metal_weight" binding:"gte=0"`
+	MetalPurity float64 `json:"metal_purity" binding:"gte=0,lte=100"`
+}
+
+// GetCompositionReviewQueue lists the user's coins whose composition was
+// auto-populated with less than high confidence
+func GetCompositionReviewQueue(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coins []models.Coin
+	if err := database.GetReadDB().Table("coins").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.composition_confidence IN ?", userID, []string{metals.ConfidenceLow, metals.ConfidenceMedium}).
+		Order("coins.created_at ASC").
+		Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+	valuation.ApplyDerived(coins)
+
+	items := make([]CompositionReviewItem, 0, len(coins))
+	for _, coin := range coins {
+		item := CompositionReviewItem{Coin: coin}
+		if match, ok := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType); ok {
+			item.Suggestion = &match
+		}
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coins": items,
+		"count": len(items),
+	})
+}
+
+// ReviewCoinComposition confirms or corrects a coin's guessed composition and
+// removes it from the review queue
+func ReviewCoinComposition(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	coinID := c.Param("id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	portfolio, ok := coinPortfolio(c, coin, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// An empty body confirms the composition as-is
+	var req ReviewCompositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	oldCurrentValue := coin.CurrentValue
+	corrected := req.MetalType != "" || req.MetalWeight != 0 || req.MetalPurity != 0
+
+	if corrected {
+		if req.MetalType != "" {
+			coin.MetalType = req.MetalType
+		}
+		if req.MetalWeight != 0 {
+			coin.MetalWeight = req.MetalWeight
+		}
+		if req.MetalPurity != 0 {
+			coin.MetalPurity = req.MetalPurity
+		}
+		coin.CompositionSource = metals.CompositionSourceManual
+
+		if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
+			meltValue, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			valuation.ApplyMeltValue(&coin, meltValue, portfolio.ValuationBasis)
+			now := time.Now()
+			coin.LastPriceUpdate = &now
+		}
+	} else {
+		coin.CompositionSource = metals.CompositionSourceConfirmed
+	}
+	coin.CompositionConfidence = metals.ConfidenceHigh
+
+	if err := database.GetDB().Save(&coin).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coin"})
+		return
+	}
+
+	if coin.CurrentValue != oldCurrentValue {
+		events.Publish(events.CoinValued{
+			UserID:             userID.(uuid.UUID),
+			CoinID:             coin.ID,
+			PortfolioID:        coin.PortfolioID,
+			Source:             "update",
+			OldCurrentValue:    oldCurrentValue,
+			NewCurrentValue:    coin.CurrentValue,
+			OldNumismaticValue: coin.NumismaticValue,
+			NewNumismaticValue: coin.NumismaticValue,
+		})
+	}
+
+	valuation.Derive(&coin)
+	c.JSON(http.StatusOK, coin)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/auctions"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// GetCoinComps lists recent auction results for the coin's type, year and
+// mint mark. The grade comes from ?grade=, or from PCGS when the coin has a
+// cert number; ?refresh=true refetches from the auction sources.
+func GetCoinComps(c *gin.Context) {
+	coinID := c.Param("id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
+		return
+	}
+
+	grade := c.Query("grade")
+	if grade == "" && coin.PCGSCertNumber != "" {
+		if coinData, err := pcgsClientForUser(c).GetCoinDataByCertNumber(coin.PCGSCertNumber); err == nil && coinData.IsValidRequest {
+			grade = coinData.Grade
+		}
+	}
+
+	query := auctions.Query{
+		CoinType: coin.CoinType,
+		Year:     coin.Year,
+		MintMark: coin.MintMark,
+		Grade:    auctions.NormalizeGrade(grade),
+	}
+	comparables, err := auctions.Comparables(query, c.Query("refresh") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comparables"})
+		return
+	}
+
+	sources := []string{}
+	for _, source := range auctions.Sources() {
+		if source.Configured() {
+			sources = append(sources, source.Name())
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coin_id":     coin.ID,
+		"coin_type":   query.CoinType,
+		"year":        query.Year,
+		"mint_mark":   query.MintMark,
+		"grade":       query.Grade,
+		"sources":     sources,
+		"comparables": comparables,
+		"summary":     auctions.Summarize(comparables),
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/demo"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// DemoLogin signs in to a new throwaway account holding a sample
+// collection, so the API can be tried without registering. The account and
+// everything added to it are deleted after DEMO_ACCOUNT_TTL. Each IP address
+// can hold DEMO_ACCOUNTS_PER_IP of them at a time. Off unless DEMO_MODE is
+// set.
+func DemoLogin(c *gin.Context) {
+	if !demo.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Demo mode is disabled", "code": "demo_disabled"})
+		return
+	}
+
+	user, err := demo.Provision(middleware.TenantIDFrom(c), c.ClientIP(), time.Now())
+	var limited demo.LimitError
+	if errors.As(err, &limited) {
+		seconds := int(math.Ceil(limited.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many demo accounts from this address, try again later",
+			"code":        "too_many_demo_accounts",
+			"retry_after": seconds,
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to provision demo account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create demo account"})
+		return
+	}
+
+	respondWithSession(c, http.StatusCreated, user, nil, "")
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/apikeys"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateDisplayTokenRequest struct {
+	Name          string    `json:"name" binding:"required"`
+	PortfolioID   uuid.UUID `json:"portfolio_id" binding:"required"`
+	ExpiresInDays int       `json:"expires_in_days" binding:"gte=0,lte=3650"` // 0 never expires
+}
+
+// DisplayView is all a display token can see: one portfolio's name and
+// total value, and spot prices
+type DisplayView struct {
+	PortfolioName string             `json:"portfolio_name"`
+	Currency      string             `json:"currency"`
+	TotalValue    float64            `json:"total_value"`
+	SpotPrices    *metals.SpotPrices `json:"spot_prices"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// CreateDisplayToken issues a long-lived token for an always-on display,
+// e.g. a screen in a shop. It is an API key that can only read GET /display
+// for the one portfolio, so a stolen screen gives away the total and nothing
+// about the coins. It is listed and revoked with the other API keys. Only the
+// portfolio's creator can issue one, since the token acts as them.
+func CreateDisplayToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateDisplayTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, ok := apiKeyName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	portfolio, ok := findPortfolio(c, req.PortfolioID, members.RoleOwner)
+	if !ok {
+		return
+	}
+	if portfolio.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the portfolio's creator can create display tokens"})
+		return
+	}
+
+	key, plain, err := apikeys.CreateDisplay(portfolio.UserID, name, portfolio.ID, time.Duration(req.ExpiresInDays)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create display token"})
+		return
+	}
+	c.JSON(http.StatusCreated, CreatedAPIKey{APIKey: key, Key: plain})
+}
+
+// GetDisplay returns what a wall display shows. A display token gets its
+// own portfolio; the owner can preview any of theirs with ?portfolio_id=.
+func GetDisplay(c *gin.Context) {
+	portfolioID := c.Query("portfolio_id")
+	if bound, ok := c.Get("display_portfolio_id"); ok {
+		portfolioID = bound.(uuid.UUID).String()
+	}
+	if portfolioID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "portfolio_id is required"})
+		return
+	}
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	batch, err := portfolioStats([]uuid.UUID{portfolio.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate stats"})
+		return
+	}
+	stats := batch[portfolio.ID]
+
+	prices, err := metals.GetSpotPrices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spot prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, DisplayView{
+		PortfolioName: portfolio.Name,
+		Currency:      stats.Currency,
+		TotalValue:    stats.TotalValue,
+		SpotPrices:    prices,
+		UpdatedAt:     time.Now(),
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// emailChangeTTL is how long the verification link mailed to the new address stays valid
+const emailChangeTTL = 24 * time.Hour
+
+var errEmailTaken = errors.New("email already in use")
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChangeEmail starts an email change. The address isn't switched until the
+// link mailed to the new address is confirmed.
+func ChangeEmail(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.NewEmail = strings.TrimSpace(req.NewEmail)
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !auth.CheckPasswordHash(req.Password, user.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+
+	if strings.EqualFold(req.NewEmail, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email is the same as the current one"})
+		return
+	}
+
+	var existingUser models.User
+	if err := database.GetDB().Where("email = ?", req.NewEmail).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use", "code": "email_taken"})
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create verification token"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	request := models.EmailChangeRequest{
+		UserID:    user.ID,
+		NewEmail:  req.NewEmail,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(emailChangeTTL),
+	}
+
+	// Starting a new change replaces any pending one, so older links stop working
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.EmailChangeRequest{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&request).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start email change"})
+		return
+	}
+
+	link := mail.AppURL() + "/verify-email?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Someone asked to change the email address of an Aureus account to this address.\n\n"+
+		"To confirm the change, open this link within %d hours:\n\n%s\n\n"+
+		"If you didn't ask for this, you can ignore this email.\n", int(emailChangeTTL.Hours()), link)
+	if err := mail.Send(request.NewEmail, "Confirm your new Aureus email address", body); err != nil {
+		log.Printf("Email change for user %s: %v", user.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":    "Verification email sent",
+		"new_email":  request.NewEmail,
+		"expires_at": request.ExpiresAt,
+	})
+}
+
+// ConfirmEmailChange switches the user's email to the verified address and
+// notifies the old one. It is public, since the link is usually opened
+// from the new mailbox rather than a signed-in browser.
+func ConfirmEmailChange(c *gin.Context) {
+	var req ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	var request models.EmailChangeRequest
+	var oldEmail string
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND expires_at > ?", hashToken(strings.TrimSpace(req.Token)), time.Now()).
+			First(&request).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&user, "id = ?", request.UserID).Error; err != nil {
+			return err
+		}
+
+		// The address may have been registered since the change was started
+		var count int64
+		if err := tx.Model(&models.User{}).Where("email = ? AND id <> ?", request.NewEmail, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errEmailTaken
+		}
+
+		oldEmail = user.Email
+		user.Email = request.NewEmail
+		if err := tx.Model(&user).Update("email", user.Email).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&request).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link", "code": "invalid_token"})
+		return
+	}
+	if errors.Is(err, errEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email is already in use", "code": "email_taken"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change email"})
+		return
+	}
+
+	body := fmt.Sprintf("The email address of your Aureus account was changed from %s to %s.\n\n"+
+		"If you didn't make this change, contact your administrator right away.\n", oldEmail, user.Email)
+	if err := mail.Send(oldEmail, "Your Aureus email address was changed", body); err != nil {
+		log.Printf("Email change notice for user %s: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/emergency"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxEmergencyReasonLength = 1000
+
+type DesignateEmergencyContactRequest struct {
+	Email       string `json:"email" binding:"required,email"`
+	WaitingDays int    `json:"waiting_days"`
+}
+
+type RequestEmergencyAccessRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// EmergencyContactSummary is an emergency contact with both accounts' emails
+type EmergencyContactSummary struct {
+	models.EmergencyContact
+	OwnerEmail   string `json:"owner_email"`
+	ContactEmail string `json:"contact_email"`
+	Granted      bool   `json:"granted"`
+}
+
+func summarizeEmergencyContacts(contacts []models.EmergencyContact) ([]EmergencyContactSummary, error) {
+	var userIDs []uuid.UUID
+	for _, contact := range contacts {
+		userIDs = append(userIDs, contact.UserID, contact.ContactUserID)
+	}
+	emails := map[uuid.UUID]string{}
+	if len(userIDs) > 0 {
+		var users []models.User
+		if err := database.GetDB().Select("id", "email").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			emails[user.ID] = user.Email
+		}
+	}
+
+	now := time.Now()
+	result := make([]EmergencyContactSummary, len(contacts))
+	for i, contact := range contacts {
+		result[i] = EmergencyContactSummary{
+			EmergencyContact: contact,
+			OwnerEmail:       emails[contact.UserID],
+			ContactEmail:     emails[contact.ContactUserID],
+			Granted:          emergency.Granted(contact, now),
+		}
+	}
+	return result, nil
+}
+
+func listEmergencyContacts(c *gin.Context, query *gorm.DB) {
+	var contacts []models.EmergencyContact
+	if err := query.Order("created_at DESC").Find(&contacts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emergency contacts"})
+		return
+	}
+	summaries, err := summarizeEmergencyContacts(contacts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch emergency contacts"})
+		return
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// saveEmergencyContact stores a step of the access process and publishes it
+func saveEmergencyContact(c *gin.Context, contact models.EmergencyContact, action string) {
+	if err := database.GetDB().Save(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update emergency contact"})
+		return
+	}
+	summaries, err := summarizeEmergencyContacts([]models.EmergencyContact{contact})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update emergency contact"})
+		return
+	}
+
+	summary := summaries[0]
+	events.Publish(events.EmergencyAccessUpdated{
+		Contact:      contact,
+		Action:       action,
+		OwnerEmail:   summary.OwnerEmail,
+		ContactEmail: summary.ContactEmail,
+	})
+	c.JSON(http.StatusOK, summary)
+}
+
+// findEmergencyContact loads an emergency contact by id where the user is the
+// given party ("user_id" or "contact_user_id"), responding 404 when missing
+func findEmergencyContact(c *gin.Context, party string) (models.EmergencyContact, bool) {
+	userID, _ := c.Get("user_id")
+
+	var contact models.EmergencyContact
+	if err := database.GetDB().Where("id = ? AND "+party+" = ?", c.Param("id"), userID).First(&contact).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Emergency contact not found"})
+		return contact, false
+	}
+	return contact, true
+}
+
+// GetEmergencyContacts lists the accounts the user has designated
+func GetEmergencyContacts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	listEmergencyContacts(c, database.GetDB().Where("user_id = ?", userID))
+}
+
+// DesignateEmergencyContact names another account on the instance that can
+// ask for read-only access to the user's portfolios, and how many days must
+// pass after an admin approves before it opens
+func DesignateEmergencyContact(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req DesignateEmergencyContactRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	waitingDays := req.WaitingDays
+	if waitingDays == 0 {
+		waitingDays = emergency.DefaultWaitingDays
+	}
+	if waitingDays < emergency.MinWaitingDays || waitingDays > emergency.MaxWaitingDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("waiting_days must be between %d and %d", emergency.MinWaitingDays, emergency.MaxWaitingDays)})
+		return
+	}
+
+	var owner models.User
+	if err := database.GetDB().First(&owner, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	var contactUser models.User
+	if err := database.GetDB().Where("email = ?", strings.TrimSpace(req.Email)).First(&contactUser).Error; err != nil || !middleware.SameTenant(owner.TenantID, contactUser.TenantID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with that email on this instance"})
+		return
+	}
+	if contactUser.ID == owner.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't be your own emergency contact"})
+		return
+	}
+
+	var existing int64
+	database.GetDB().Model(&models.EmergencyContact{}).Where("user_id = ? AND contact_user_id = ?", owner.ID, contactUser.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "That user is already an emergency contact"})
+		return
+	}
+
+	contact := models.EmergencyContact{
+		UserID:        owner.ID,
+		ContactUserID: contactUser.ID,
+		WaitingDays:   waitingDays,
+		Status:        emergency.StatusDesignated,
+	}
+	if err := database.GetDB().Create(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add emergency contact"})
+		return
+	}
+
+	events.Publish(events.EmergencyAccessUpdated{Contact: contact, Action: events.EmergencyDesignated, OwnerEmail: owner.Email, ContactEmail: contactUser.Email})
+	c.JSON(http.StatusCreated, EmergencyContactSummary{EmergencyContact: contact, OwnerEmail: owner.Email, ContactEmail: contactUser.Email})
+}
+
+// DenyEmergencyAccess turns down a pending or approved request, including
+// access that is already open. The contact stays designated.
+func DenyEmergencyAccess(c *gin.Context) {
+	contact, ok := findEmergencyContact(c, "user_id")
+	if !ok {
+		return
+	}
+	if contact.Status == emergency.StatusDesignated {
+		c.JSON(http.StatusConflict, gin.H{"error": "No emergency access has been requested"})
+		return
+	}
+
+	emergency.Reset(&contact)
+	saveEmergencyContact(c, contact, events.EmergencyDenied)
+}
+
+// RemoveEmergencyContact revokes a contact, ending any access they have
+func RemoveEmergencyContact(c *gin.Context) {
+	contact, ok := findEmergencyContact(c, "user_id")
+	if !ok {
+		return
+	}
+	if err := database.GetDB().Delete(&contact).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove emergency contact"})
+		return
+	}
+
+	events.Publish(events.EmergencyAccessUpdated{Contact: contact, Action: events.EmergencyRevoked})
+	c.JSON(http.StatusOK, gin.H{"message": "Emergency contact removed"})
+}
+
+// GetEmergencyAccess lists the accounts the user is an emergency contact for
+func GetEmergencyAccess(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	listEmergencyContacts(c, database.GetDB().Where("contact_user_id = ?", userID))
+}
+
+// RequestEmergencyAccess asks for read-only access to an account the user is
+// the emergency contact for. An admin has to approve it, and the owner is
+// told by email so they can deny it.
+func RequestEmergencyAccess(c *gin.Context) {
+	contact, ok := findEmergencyContact(c, "contact_user_id")
+	if !ok {
+		return
+	}
+
+	var req RequestEmergencyAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" || len(reason) > maxEmergencyReasonLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required and can be at most 1000 characters"})
+		return
+	}
+
+	if err := emergency.Request(&contact, reason, time.Now()); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Emergency access was already requested"})
+		return
+	}
+	saveEmergencyContact(c, contact, events.EmergencyRequested)
+}
+
+// CreateEmergencyAccessToken issues the contact a read-only token for the
+// owner's account once access is open. It stops working if the owner denies
+// access or removes the contact.
+func CreateEmergencyAccessToken(c *gin.Context) {
+	contact, ok := findEmergencyContact(c, "contact_user_id")
+	if !ok {
+		return
+	}
+	if !emergency.Granted(contact, time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Emergency access is not open"})
+		return
+	}
+
+	var owner models.User
+	if err := database.GetDB().First(&owner, "id = ?", contact.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	token, expiresAt, err := auth.GenerateEmergencyToken(owner.ID, owner.Email, owner.TenantID, contact.ID, emergency.TokenTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ScopedTokenResponse{
+		Token:     token,
+		Scopes:    []string{auth.ScopeCoinsRead, auth.ScopeReportsRead},
+		ExpiresAt: expiresAt,
+	})
+}
+
+// adminEmergencyContacts scopes a query to the admin's tenant
+func adminEmergencyContacts(c *gin.Context) *gorm.DB {
+	query := database.GetDB()
+	if tenantID := middleware.TenantIDFrom(c); tenantID != nil {
+		query = query.Where("user_id IN (?)", database.GetDB().Model(&models.User{}).Select("id").Where("tenant_id = ?", *tenantID))
+	}
+	return query
+}
+
+// ListEmergencyAccessRequests lists requested and approved emergency access,
+// or only ?status= when given
+func ListEmergencyAccessRequests(c *gin.Context) {
+	query := adminEmergencyContacts(c)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	} else {
+		query = query.Where("status IN ?", []string{emergency.StatusRequested, emergency.StatusApproved})
+	}
+	listEmergencyContacts(c, query)
+}
+
+func adminEmergencyContact(c *gin.Context) (models.EmergencyContact, bool) {
+	var contact models.EmergencyContact
+	if err := adminEmergencyContacts(c).Where("id = ?", c.Param("id")).First(&contact).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Emergency contact not found"})
+		return contact, false
+	}
+	return contact, true
+}
+
+// ApproveEmergencyAccess approves a request, starting the owner's waiting
+// period
+func ApproveEmergencyAccess(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	contact, ok := adminEmergencyContact(c)
+	if !ok {
+		return
+	}
+	if contact.ContactUserID == userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Another admin has to approve your own request"})
+		return
+	}
+	if err := emergency.Approve(&contact, userID.(uuid.UUID), time.Now()); errors.Is(err, emergency.ErrWrongStatus) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only requested access can be approved"})
+		return
+	}
+	saveEmergencyContact(c, contact, events.EmergencyApproved)
+}
+
+// RejectEmergencyAccess turns down a request or withdraws an approval
+func RejectEmergencyAccess(c *gin.Context) {
+	contact, ok := adminEmergencyContact(c)
+	if !ok {
+		return
+	}
+	if contact.Status == emergency.StatusDesignated {
+		c.JSON(http.StatusConflict, gin.H{"error": "No emergency access has been requested"})
+		return
+	}
+
+	emergency.Reset(&contact)
+	saveEmergencyContact(c, contact, events.EmergencyDenied)
+}
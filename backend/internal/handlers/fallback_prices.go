@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type FallbackPriceRequest struct {
+	Price      float64    `json:"price" binding:"required,gt=0"`
+	ReviewedAt *time.Time `json:"reviewed_at"` // defaults to now
+}
+
+// ListFallbackPrices lists the price each metal falls back to, where it
+// comes from and when it was last reviewed
+func ListFallbackPrices(c *gin.Context) {
+	c.JSON(http.StatusOK, metals.FallbackPrices())
+}
+
+// SaveFallbackPrice sets the price a metal falls back to for every user of
+// the instance. Saving the current price again records that it was reviewed.
+func SaveFallbackPrice(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	metal := strings.ToLower(c.Param("metal"))
+	if !metals.IsMetal(metal) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "metal must be one of " + strings.Join(metals.Metals, ", ")})
+		return
+	}
+
+	var req FallbackPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reviewedAt := time.Now()
+	if req.ReviewedAt != nil {
+		if req.ReviewedAt.After(reviewedAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reviewed_at can't be in the future"})
+			return
+		}
+		reviewedAt = *req.ReviewedAt
+	}
+
+	reviewedBy := userID.(uuid.UUID)
+	var stored models.FallbackPrice
+	database.GetDB().Where("metal = ?", metal).First(&stored)
+	stored.Metal = metal
+	stored.Price = req.Price
+	stored.ReviewedAt = reviewedAt
+	stored.ReviewedBy = &reviewedBy
+	if err := database.GetDB().Save(&stored).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save fallback price"})
+		return
+	}
+
+	metals.SetFallbackPrice(stored)
+	c.JSON(http.StatusOK, metals.FallbackPriceFor(metal))
+}
+
+// DeleteFallbackPrice removes an admin-set fallback price, restoring the
+// configured or built-in one
+func DeleteFallbackPrice(c *gin.Context) {
+	metal := strings.ToLower(c.Param("metal"))
+	result := database.GetDB().Where("metal = ?", metal).Delete(&models.FallbackPrice{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete fallback price"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Fallback price not found"})
+		return
+	}
+
+	metals.RemoveFallbackPrice(metal)
+	c.JSON(http.StatusOK, metals.FallbackPriceFor(metal))
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/fxrates"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/spothistory"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// HedgingCoin is one coin's melt value restated in a currency at the start
+// and end of the period, with its change split between metal and FX
+type HedgingCoin struct {
+	CoinID      uuid.UUID `json:"coin_id"`
+	PortfolioID uuid.UUID `json:"portfolio_id"`
+	CoinType    string    `json:"coin_type"`
+	Year        int       `json:"year"`
+	MetalType   string    `json:"metal_type"`
+	Quantity    int       `json:"quantity"`
+	fxrates.Attribution
+}
+
+// HedgingCurrency totals the coins valued in one currency
+type HedgingCurrency struct {
+	Currency  string  `json:"currency"`
+	StartRate float64 `json:"start_rate"` // units of the currency per USD
+	EndRate   float64 `json:"end_rate"`
+	fxrates.Attribution
+	Coins []HedgingCoin `json:"coins"`
+}
+
+// GetCurrencyHedgingReport splits the change in value of the user's bullion
+// over ?period= (default 30d) into what the metal did and what the exchange
+// rate did, from recorded spot prices and exchange rates. Coins are valued in
+// their face currency, so a Maple Leaf's value is in CAD, or all in
+// ?currency= when given. Only melt value is covered; coins are counted as
+// held now for the whole period. Optionally for one ?portfolio_id=.
+func GetCurrencyHedgingReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	middleware.KeepCurrency(c)
+
+	period, err := parseAge(c.Query("period"))
+	if err != nil || period <= 0 || period > fxrates.Retention {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period must be like 30d, 2w or 36h, and at most 35d"})
+		return
+	}
+	reportCurrency := ""
+	if code := c.Query("currency"); code != "" {
+		normalized, ok := metals.NormalizeCurrency(code)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "currency must be a three-letter currency code like CAD"})
+			return
+		}
+		reportCurrency = normalized
+	}
+
+	to := time.Now()
+	from := to.Add(-period)
+	startSpot, err := spothistory.At(from)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No spot prices were recorded by the start of the period", "code": "no_spot_history"})
+		return
+	}
+	endSpot, err := metals.GetSpotPrices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spot prices"})
+		return
+	}
+	startCalc := metals.NewCalculator(metals.SpotPrices{
+		Gold:      startSpot.Gold,
+		Silver:    startSpot.Silver,
+		Platinum:  startSpot.Platinum,
+		Palladium: startSpot.Palladium,
+	})
+	endCalc := metals.NewCalculator(*endSpot)
+
+	query := database.GetReadDB().
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.metal_weight > 0 AND coins.metal_purity > 0", userID)
+	if portfolioID := c.Query("portfolio_id"); portfolioID != "" {
+		if _, err := uuid.Parse(portfolioID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
+			return
+		}
+		query = query.Where("coins.portfolio_id = ?", portfolioID)
+	}
+	var coins []models.Coin
+	if err := query.Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+
+	groups := map[string]*HedgingCurrency{}
+	missing := []string{}
+	for _, coin := range coins {
+		// Spot history only covers the precious metals
+		startMelt := valuation.CoinMeltValue(coin, startCalc) * float64(coin.Quantity)
+		endMelt := valuation.CoinMeltValue(coin, endCalc) * float64(coin.Quantity)
+		if startMelt <= 0 || endMelt <= 0 {
+			continue
+		}
+
+		currency := reportCurrency
+		if currency == "" {
+			currency = metals.Currency
+			if code, ok := metals.NormalizeCurrency(coin.FaceCurrency); ok {
+				currency = code
+			}
+		}
+		group, ok := groups[currency]
+		if !ok {
+			startRate, err := fxrates.At(currency, from)
+			if err != nil {
+				missing = append(missing, currency)
+				groups[currency] = nil
+				continue
+			}
+			endRate, err := fxrates.At(currency, to)
+			if err != nil {
+				missing = append(missing, currency)
+				groups[currency] = nil
+				continue
+			}
+			group = &HedgingCurrency{Currency: currency, StartRate: startRate, EndRate: endRate, Coins: []HedgingCoin{}}
+			groups[currency] = group
+		}
+		if group == nil {
+			continue
+		}
+
+		attribution := fxrates.Attribute(startMelt, endMelt, group.StartRate, group.EndRate)
+		group.Attribution = group.Attribution.Add(attribution)
+		group.Coins = append(group.Coins, HedgingCoin{
+			CoinID:      coin.ID,
+			PortfolioID: coin.PortfolioID,
+			CoinType:    coin.CoinType,
+			Year:        coin.Year,
+			MetalType:   coin.MetalType,
+			Quantity:    coin.Quantity,
+			Attribution: attribution,
+		})
+	}
+
+	if reportCurrency != "" && len(missing) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No exchange rates for " + reportCurrency + " were recorded by the start of the period", "code": "no_fx_history"})
+		return
+	}
+
+	currencies := []HedgingCurrency{}
+	for _, group := range groups {
+		if group != nil {
+			sort.Slice(group.Coins, func(i, j int) bool { return group.Coins[i].EndValue > group.Coins[j].EndValue })
+			currencies = append(currencies, *group)
+		}
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Currency < currencies[j].Currency })
+	sort.Strings(missing)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":       from,
+		"to":         to,
+		"spot_from":  startSpot.RecordedAt,
+		"currencies": currencies,
+		// Face currencies whose coins were left out for want of rates
+		"missing_rates": missing,
+	})
+}
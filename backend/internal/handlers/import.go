@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/certwatch"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/imports"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/references"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// What an import did with a row
+const (
+	importCreated = "created"
+	importUpdated = "updated"
+	importSkipped = "skipped"
+	importFailed  = "failed"
+)
+
+// ImportRowResult reports what an import did with one row. A row whose cert
+// number is already in the collection names the coin holding it, or the
+// earlier row of the same file.
+type ImportRowResult struct {
+	Line            int        `json:"line"`
+	Status          string     `json:"status"`
+	CoinID          *uuid.UUID `json:"coin_id,omitempty"`
+	PCGSCertNumber  string     `json:"pcgs_cert_number,omitempty"`
+	DuplicateOf     *uuid.UUID `json:"duplicate_of,omitempty"`
+	DuplicateOfLine int        `json:"duplicate_of_line,omitempty"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// importTarget is a coin a later row with the same cert number refers to
+type importTarget struct {
+	coin  *models.Coin
+	basis string
+	line  int // the row that added it, 0 for coins already in the collection
+}
+
+// readImportFile returns the uploaded CSV, sent either as the "file" field
+// of a multipart form or as a text/csv body
+func readImportFile(c *gin.Context) ([]byte, bool) {
+	var r io.Reader
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				middleware.PayloadTooLarge(c, "file_too_large", "File too large", gin.H{"limit_bytes": middleware.MaxUploadBytes()})
+				return nil, false
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return nil, false
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+			return nil, false
+		}
+		defer file.Close()
+		r = file
+	} else {
+		r = c.Request.Body
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.PayloadTooLarge(c, "file_too_large", "File too large", gin.H{"limit_bytes": middleware.MaxUploadBytes()})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return nil, false
+	}
+	return data, true
+}
+
+// importMapping reads the columns the user chose for an import, a JSON object
+// from header to field sent as the "mapping" form field or query parameter.
+// It is nil when none was sent.
+func importMapping(c *gin.Context) (map[string]string, bool) {
+	raw := c.Query("mapping")
+	if raw == "" && strings.HasPrefix(c.ContentType(), "multipart/") {
+		raw = c.PostForm("mapping")
+	}
+	if raw == "" {
+		return nil, true
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object from header to field"})
+		return nil, false
+	}
+	if err := imports.ValidateMapping(mapping); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return mapping, true
+}
+
+// PreviewImport reads a CSV without importing it and suggests which field
+// each column holds, with how confident the guess is and sample rows, so
+// the user can check the columns before importing. The suggestions, as
+// corrected, go back to the import as its mapping. ?sample_rows sets how
+// many rows are shown.
+func PreviewImport(c *gin.Context) {
+	samples := imports.DefaultSampleRows
+	if raw := c.Query("sample_rows"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > imports.MaxSampleRows {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sample_rows must be between 1 and %d", imports.MaxSampleRows)})
+			return
+		}
+		samples = n
+	}
+
+	data, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	preview, err := imports.PreviewCSV(bytes.NewReader(data), samples)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// ImportCoins adds the coins of a CSV spreadsheet to a portfolio. Rows whose
+// cert number is already in any of the user's portfolios, or on an earlier
+// row, are handled by ?on_duplicate: skip (the default), update the existing
+// coin from the row, or duplicate to add them anyway. The rows are imported
+// by a background job whose result is a per-row report; with ?dry_run=true
+// the report is made without saving anything. A mapping from PreviewImport
+// picks the columns instead of their headers.
+func ImportCoins(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	dryRun := c.Query("dry_run") == "true"
+	strategy := c.DefaultQuery("on_duplicate", imports.OnDuplicateSkip)
+	if !imports.ValidStrategy(strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "on_duplicate must be 'skip', 'update' or 'duplicate'"})
+		return
+	}
+
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	data, ok := readImportFile(c)
+	if !ok {
+		return
+	}
+	mapping, ok := importMapping(c)
+	if !ok {
+		return
+	}
+	rows, ignored, err := imports.ParseMapped(bytes.NewReader(data), mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	startJob(c, jobs.KindImport, func(p *jobs.Progress) (any, error) {
+		return importRows(userID.(uuid.UUID), portfolio, rows, ignored, strategy, dryRun, p)
+	})
+}
+
+// importRows imports parsed rows into a portfolio, as ImportCoins describes
+func importRows(userID uuid.UUID, portfolio models.Portfolio, rows []imports.Row, ignored []string, strategy string, dryRun bool, p *jobs.Progress) (gin.H, error) {
+	targets, err := existingCertTargets(userID, rows)
+	if err != nil {
+		return nil, err
+	}
+	p.SetTotal(len(rows))
+
+	now := time.Now()
+	results := make([]ImportRowResult, 0, len(rows))
+	counts := map[string]int{}
+	for _, row := range rows {
+		p.Add(1)
+		result := ImportRowResult{Line: row.Line, PCGSCertNumber: row.CertNumber()}
+		if row.Error != "" {
+			result.Status, result.Error = importFailed, row.Error
+			results = append(results, result)
+			counts[importFailed]++
+			continue
+		}
+
+		target := targets[row.CertNumber()]
+		if row.CertNumber() == "" {
+			target = nil
+		}
+		if target != nil {
+			if target.line == 0 {
+				result.DuplicateOf = &target.coin.ID
+			} else {
+				result.DuplicateOfLine = target.line
+			}
+		}
+
+		switch {
+		case target != nil && strategy == imports.OnDuplicateSkip:
+			result.Status = importSkipped
+		case target != nil && strategy == imports.OnDuplicateUpdate:
+			if err := updateImportedCoin(userID, target, row, dryRun, now); err != nil {
+				result.Status, result.Error = importFailed, err.Error()
+				break
+			}
+			result.Status = importUpdated
+			if target.coin.ID != uuid.Nil {
+				result.CoinID = &target.coin.ID
+			}
+		default:
+			coin, err := createImportedCoin(userID, portfolio, row, dryRun, now)
+			if err != nil {
+				result.Status, result.Error = importFailed, err.Error()
+				break
+			}
+			result.Status = importCreated
+			if !dryRun {
+				result.CoinID = &coin.ID
+			}
+			if row.CertNumber() != "" && target == nil {
+				targets[row.CertNumber()] = &importTarget{coin: coin, basis: portfolio.ValuationBasis, line: row.Line}
+			}
+		}
+		results = append(results, result)
+		counts[result.Status]++
+	}
+
+	return gin.H{
+		"on_duplicate":    strategy,
+		"dry_run":         dryRun,
+		"created":         counts[importCreated],
+		"updated":         counts[importUpdated],
+		"skipped":         counts[importSkipped],
+		"failed":          counts[importFailed],
+		"ignored_columns": ignored,
+		"rows":            results,
+	}, nil
+}
+
+// existingCertTargets finds the coins already holding the rows' cert
+// numbers in any of the user's portfolios, the oldest one per cert
+func existingCertTargets(userID interface{}, rows []imports.Row) (map[string]*importTarget, error) {
+	targets := map[string]*importTarget{}
+	var certs []string
+	for _, row := range rows {
+		if cert := row.CertNumber(); cert != "" {
+			certs = append(certs, cert)
+		}
+	}
+	if len(certs) == 0 {
+		return targets, nil
+	}
+
+	var coins []models.Coin
+	portfolios := database.GetDB().Model(&models.Portfolio{}).Select("id").Where("user_id = ?", userID)
+	if err := database.GetDB().Where("portfolio_id IN (?) AND pcgs_cert_number IN ?", portfolios, certs).
+		Order("created_at ASC").Find(&coins).Error; err != nil {
+		return nil, err
+	}
+	portfolioIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		portfolioIDs[i] = coin.PortfolioID
+	}
+	bases, err := valuation.Bases(portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range coins {
+		cert := imports.NormalizeCert(coins[i].PCGSCertNumber)
+		if _, ok := targets[cert]; !ok {
+			targets[cert] = &importTarget{coin: &coins[i], basis: bases[coins[i].PortfolioID]}
+		}
+	}
+	return targets, nil
+}
+
+// createImportedCoin adds a row as a new coin, valued like one entered by
+// hand. Guide values aren't looked up per row; a PCGS sync fills them in.
+func createImportedCoin(userID uuid.UUID, portfolio models.Portfolio, row imports.Row, dryRun bool, now time.Time) (*models.Coin, error) {
+	coin := row.Coin
+	coin.PortfolioID = portfolio.ID
+
+	if !metals.ValidStrikeType(coin.StrikeType) {
+		return nil, errors.New("invalid strike type: " + coin.StrikeType)
+	}
+	if err := metals.ValidateCoinIssue(coin.CoinType, coin.Year, coin.Denomination); err != nil {
+		return nil, err
+	}
+	if coin.PurchaseDate == nil {
+		coin.PurchaseDate = &now
+	} else if coin.PurchaseDate.After(now) {
+		return nil, errors.New("purchase date can't be in the future")
+	}
+	coin.LastPriceUpdate = &now
+	if coin.Quantity == 0 {
+		coin.Quantity = 1
+	}
+	if coin.StrikeType == "" {
+		coin.StrikeType = metals.InferStrikeType(coin.CoinType)
+	}
+	fillSeriesReference(&coin)
+	applyPortfolioDefaults(&coin, portfolio)
+	fillComposition(&coin, portfolio.ValuationBasis)
+	references.Refresh(&coin, "")
+	certSuspicious := certwatch.Apply(&coin)
+
+	if dryRun {
+		return &coin, nil
+	}
+	if err := database.GetDB().Create(&coin).Error; err != nil {
+		return nil, errors.New("failed to create coin")
+	}
+	events.Publish(events.CoinCreated{UserID: userID, Coin: coin})
+	if certSuspicious {
+		events.Publish(events.CertFlagged{UserID: userID, Coin: coin})
+	}
+	return &coin, nil
+}
+
+// updateImportedCoin updates a coin from the columns a row fills in, as a
+// PUT /coins/:id with those fields would. Its cert number and portfolio stay.
+func updateImportedCoin(userID uuid.UUID, target *importTarget, row imports.Row, dryRun bool, now time.Time) error {
+	coin := *target.coin
+	oldCoinType, oldYear, oldDenomination := coin.CoinType, coin.Year, coin.Denomination
+	oldCurrentValue, oldNumismaticValue := coin.CurrentValue, coin.NumismaticValue
+
+	imports.Merge(&coin, row)
+	references.Refresh(&coin, references.PCGSNumber(coin))
+	if !metals.ValidStrikeType(coin.StrikeType) {
+		return errors.New("invalid strike type: " + coin.StrikeType)
+	}
+	// Only validate what changed so older records stay importable
+	if coin.CoinType != oldCoinType || coin.Year != oldYear || coin.Denomination != oldDenomination {
+		if err := metals.ValidateCoinIssue(coin.CoinType, coin.Year, coin.Denomination); err != nil {
+			return err
+		}
+	}
+	if coin.PurchaseDate != nil && coin.PurchaseDate.After(now) {
+		return errors.New("purchase date can't be in the future")
+	}
+	fillSeriesReference(&coin)
+
+	if row.Has("current_value") {
+		coin.LastPriceUpdate = &now
+	}
+	if row.Has("numismatic_value") {
+		valuation.ApplyNumismaticValue(&coin, coin.NumismaticValue, target.basis)
+	}
+	if row.MetalEdited() {
+		coin.CompositionSource = metals.CompositionSourceManual
+		coin.CompositionConfidence = metals.ConfidenceHigh
+		if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
+			if meltValue, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
+				valuation.ApplyMeltValue(&coin, meltValue, target.basis)
+				coin.LastPriceUpdate = &now
+			}
+		}
+	}
+
+	if !dryRun && coin.ID != uuid.Nil {
+		if err := database.GetDB().Save(&coin).Error; err != nil {
+			return errors.New("failed to update coin")
+		}
+		if coin.CurrentValue != oldCurrentValue || coin.NumismaticValue != oldNumismaticValue {
+			events.Publish(events.CoinValued{
+				UserID:             userID,
+				CoinID:             coin.ID,
+				PortfolioID:        coin.PortfolioID,
+				Source:             "import",
+				OldCurrentValue:    oldCurrentValue,
+				NewCurrentValue:    coin.CurrentValue,
+				OldNumismaticValue: oldNumismaticValue,
+				NewNumismaticValue: coin.NumismaticValue,
+			})
+		}
+	}
+	*target.coin = coin
+	return nil
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errInvalidInviteCode = errors.New("invalid or expired invite code")
+
+type CreateInviteRequest struct {
+	Note           string `json:"note"`
+	MaxUses        int    `json:"max_uses" binding:"gte=0,lte=1000"`         // default 1
+	ExpiresInHours int    `json:"expires_in_hours" binding:"gte=0,lte=8760"` // 0 never expires
+}
+
+// newInviteCode returns a random code such as "K7QF-2M9X-PA4T-HW3C"
+func newInviteCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	raw := base32.StdEncoding.EncodeToString(buf)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+// normalizeInviteCode accepts codes typed in lower case or without dashes
+func normalizeInviteCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 16 {
+		return code
+	}
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16]
+}
+
+// redeemInviteCode uses up one use of an invite code of the given tenant. It
+// returns errInvalidInviteCode if the code is unknown, used up or expired.
+func redeemInviteCode(tx *gorm.DB, code string, tenantID *uuid.UUID) error {
+	query := tx.Model(&models.InviteCode{}).
+		Where("code = ? AND uses < max_uses AND (expires_at IS NULL OR expires_at > ?)", normalizeInviteCode(code), time.Now())
+	if tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	} else {
+		query = query.Where("tenant_id IS NULL")
+	}
+
+	// A single conditional update so concurrent signups can't overuse a code
+	result := query.Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidInviteCode
+	}
+	return nil
+}
+
+// ListInvites lists the invite codes of the admin's tenant, newest first
+func ListInvites(c *gin.Context) {
+	query := database.GetDB().Order("created_at DESC")
+	if tenantID := middleware.TenantIDFrom(c); tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+
+	var invites []models.InviteCode
+	if err := query.Find(&invites).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invite codes"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+// CreateInvite generates an invite code for the admin's tenant
+func CreateInvite(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, err := newInviteCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invite code"})
+		return
+	}
+
+	invite := models.InviteCode{
+		TenantID:  middleware.TenantIDFrom(c),
+		Code:      code,
+		Note:      req.Note,
+		CreatedBy: userID.(uuid.UUID),
+		MaxUses:   max(req.MaxUses, 1),
+	}
+	if req.ExpiresInHours > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	if err := database.GetDB().Create(&invite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+// DeleteInvite revokes an invite code
+func DeleteInvite(c *gin.Context) {
+	query := database.GetDB().Where("id = ?", c.Param("id"))
+	if tenantID := middleware.TenantIDFrom(c); tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+
+	result := query.Delete(&models.InviteCode{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete invite code"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite code not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invite code revoked"})
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// jobURL is where a job is polled, under the version the request used
+func jobURL(c *gin.Context, jobID uuid.UUID) string {
+	prefix := "/api/" + middleware.APIVersionFrom(c)
+	if !strings.HasPrefix(c.Request.URL.Path, prefix+"/") {
+		prefix = "/api"
+	}
+	return prefix + "/jobs/" + jobID.String()
+}
+
+// startJob runs work in the background as a job of kind and answers 202
+// with the job, and its URL in Location. While the user has one of that
+// kind unfinished, it answers 409 with that job instead.
+func startJob(c *gin.Context, kind string, work jobs.Func) {
+	userID, _ := c.Get("user_id")
+
+	job, err := jobs.Start(userID.(uuid.UUID), kind, work)
+	if errors.Is(err, jobs.ErrRunning) {
+		c.Header("Location", jobURL(c, job.ID))
+		c.JSON(http.StatusConflict, gin.H{"error": "A job of this kind is already running", "code": "job_running", "job": job})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start job"})
+		return
+	}
+
+	c.Header("Location", jobURL(c, job.ID))
+	c.Header("Retry-After", "2")
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJobs lists the user's background jobs, newest first, optionally of
+// one ?kind= or ?status=; pages are cursor paged with ?after= and ?limit=
+func GetJobs(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	query := database.GetDB().Model(&models.Job{}).Where("user_id = ?", userID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	query, limit, ok := newestFirstPage(c, query, "created_at")
+	if !ok {
+		return
+	}
+	list := []models.Job{}
+	if err := query.Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch jobs"})
+		return
+	}
+
+	if len(list) > 0 {
+		setNextCursor(c, limit, len(list), list[len(list)-1].ID)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// GetJob returns a background job: its status and progress while it runs,
+// then its result. Unfinished jobs say when to poll again in Retry-After.
+func GetJob(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	job, err := jobs.Get(userID.(uuid.UUID), jobID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	if job.FinishedAt == nil {
+		c.Header("Retry-After", "2")
+	}
+	c.JSON(http.StatusOK, job)
+}
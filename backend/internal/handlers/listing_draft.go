@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/listings"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type ListingDraftRequest struct {
+	Marketplace string `json:"marketplace"` // "ebay" (default) or "greatcollections"
+}
+
+// CreateListingDraft formats a coin's stored data into a marketplace listing
+// title, description and image set for the user to paste into a listing.
+// Certified coins get their grade from PCGS.
+func CreateListingDraft(c *gin.Context) {
+	coinID := c.Param("id")
+
+	var req ListingDraftRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Marketplace == "" {
+		req.Marketplace = listings.MarketplaceEbay
+	}
+	if !listings.ValidMarketplace(req.Marketplace) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "marketplace must be 'ebay' or 'greatcollections'"})
+		return
+	}
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
+		return
+	}
+
+	in := listings.Input{Coin: coin}
+	for _, url := range []string{coin.ImageURL, coin.ThumbnailURL} {
+		if url != "" {
+			in.Images = append(in.Images, url)
+		}
+	}
+
+	if coin.PCGSCertNumber != "" {
+		pcgsClient := pcgsClientForUser(c)
+		if coinData, err := pcgsClient.GetCoinDataByCertNumber(coin.PCGSCertNumber); err == nil && coinData.IsValidRequest {
+			in.Grade = coinData.Grade
+			in.Designation = coinData.Designation
+		}
+		if len(in.Images) == 0 {
+			if imageData, err := pcgsClient.GetCoinImagesByCertNumber(coin.PCGSCertNumber); err == nil && imageData.IsValidRequest {
+				for _, image := range imageData.Images {
+					in.Images = append(in.Images, image.URL)
+				}
+			}
+		}
+	}
+
+	draft, err := listings.Build(in, req.Marketplace)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build listing draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, draft)
+}
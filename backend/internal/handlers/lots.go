@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/lots"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CreateLotRequest struct {
+	Name          string             `json:"name" binding:"required"`
+	Seller        string             `json:"seller"`
+	InvoiceNumber string             `json:"invoice_number"`
+	PurchaseDate  *time.Time         `json:"purchase_date"`
+	TotalPrice    float64            `json:"total_price"`
+	BuyersPremium float64            `json:"buyers_premium"`
+	ShippingCost  float64            `json:"shipping_cost"`
+	SalesTax      float64            `json:"sales_tax"`
+	Allocation    string             `json:"allocation"` // defaults to melt_weight
+	Notes         string             `json:"notes"`
+	CoinIDs       []string           `json:"coin_ids"`
+	Amounts       map[string]float64 `json:"amounts"` // coin ID -> hammer price share, for manual allocation
+}
+
+type UpdateLotRequest struct {
+	Name          string             `json:"name"`
+	Seller        *string            `json:"seller"`
+	InvoiceNumber *string            `json:"invoice_number"`
+	PurchaseDate  *time.Time         `json:"purchase_date"`
+	TotalPrice    *float64           `json:"total_price"`
+	BuyersPremium *float64           `json:"buyers_premium"`
+	ShippingCost  *float64           `json:"shipping_cost"`
+	SalesTax      *float64           `json:"sales_tax"`
+	Allocation    string             `json:"allocation"`
+	Notes         *string            `json:"notes"`
+	CoinIDs       *[]string          `json:"coin_ids"` // replaces the lot's coins
+	Amounts       map[string]float64 `json:"amounts"`
+}
+
+// LotSummary is a lot with how many coins it holds and whether they carry
+// its invoice totals
+type LotSummary struct {
+	models.Lot
+	CoinCount      int                 `json:"coin_count"`
+	Reconciliation lots.Reconciliation `json:"reconciliation"`
+}
+
+// LotDetail is a lot with its coins
+type LotDetail struct {
+	LotSummary
+	Coins []models.Coin `json:"coins"`
+}
+
+func lotDetail(lot models.Lot, coins []models.Coin) LotDetail {
+	valuation.ApplyDerived(coins)
+	return LotDetail{
+		LotSummary: LotSummary{Lot: lot, CoinCount: len(coins), Reconciliation: lots.Reconcile(lot, coins)},
+		Coins:      coins,
+	}
+}
+
+// userCoins loads the user's coins with the given IDs, responding with 400
+// when any of them isn't found
+func userCoins(c *gin.Context, userID interface{}, coinIDs []string) ([]models.Coin, bool) {
+	coins := []models.Coin{}
+	if len(coinIDs) == 0 {
+		return coins, true
+	}
+
+	ids := make([]uuid.UUID, 0, len(coinIDs))
+	for _, raw := range coinIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coin ID: " + raw})
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+
+	if err := database.GetDB().
+		Where("id IN ? AND portfolio_id IN (?)", ids, database.GetDB().Model(&models.Portfolio{}).Select("id").Where("user_id = ?", userID)).
+		Order("created_at ASC").
+		Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return nil, false
+	}
+	if len(coins) != len(uniqueIDs(ids)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some coins were not found"})
+		return nil, false
+	}
+	return coins, true
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]bool {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	return seen
+}
+
+// lotAmounts parses manual allocation amounts. Coins without an entry keep
+// their current hammer price, so fees can be re-split without re-entering
+// every amount.
+func lotAmounts(c *gin.Context, coins []models.Coin, raw map[string]float64) (map[uuid.UUID]float64, bool) {
+	amounts := make(map[uuid.UUID]float64, len(coins))
+	for _, coin := range coins {
+		amounts[coin.ID] = coin.PurchasePrice * float64(max(coin.Quantity, 1))
+	}
+	for key, amount := range raw {
+		id, err := uuid.Parse(key)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coin ID: " + key})
+			return nil, false
+		}
+		if _, ok := amounts[id]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Coin " + key + " is not in the lot"})
+			return nil, false
+		}
+		amounts[id] = amount
+	}
+	return amounts, true
+}
+
+// saveLot allocates the lot's totals across coins and saves the lot and its
+// coins, unlinking coins that were in the lot but no longer are
+func saveLot(c *gin.Context, lot *models.Lot, coins []models.Coin, rawAmounts map[string]float64) bool {
+	amounts, ok := lotAmounts(c, coins, rawAmounts)
+	if !ok {
+		return false
+	}
+	if err := lots.Allocate(*lot, coins, amounts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	coinIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		coinIDs[i] = coin.ID
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(lot).Error; err != nil {
+			return err
+		}
+		unlink := tx.Model(&models.Coin{}).Where("lot_id = ?", lot.ID)
+		if len(coinIDs) > 0 {
+			unlink = unlink.Where("id NOT IN ?", coinIDs)
+		}
+		if err := unlink.Update("lot_id", nil).Error; err != nil {
+			return err
+		}
+		for i := range coins {
+			if err := tx.Save(&coins[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save lot"})
+		return false
+	}
+	return true
+}
+
+// GetLots lists the user's lots, newest purchase first
+func GetLots(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var userLots []models.Lot
+	if err := database.GetDB().Where("user_id = ?", userID).Order("purchase_date DESC NULLS LAST, created_at DESC").Find(&userLots).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lots"})
+		return
+	}
+
+	lotIDs := make([]uuid.UUID, len(userLots))
+	for i, lot := range userLots {
+		lotIDs[i] = lot.ID
+	}
+	var coins []models.Coin
+	if len(lotIDs) > 0 {
+		if err := database.GetDB().Where("lot_id IN ?", lotIDs).Find(&coins).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+			return
+		}
+	}
+	byLot := make(map[uuid.UUID][]models.Coin)
+	for _, coin := range coins {
+		byLot[*coin.LotID] = append(byLot[*coin.LotID], coin)
+	}
+
+	summaries := make([]LotSummary, len(userLots))
+	for i, lot := range userLots {
+		summaries[i] = lotDetail(lot, byLot[lot.ID]).LotSummary
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// GetLot returns a lot with its coins and reconciliation
+func GetLot(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var lot models.Lot
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&lot).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+		return
+	}
+
+	coins := []models.Coin{}
+	if err := database.GetDB().Where("lot_id = ?", lot.ID).Order("created_at ASC").Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, lotDetail(lot, coins))
+}
+
+// CreateLot records a group purchase and splits its cost across the given coins
+func CreateLot(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateLotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Allocation == "" {
+		req.Allocation = lots.AllocationMeltWeight
+	}
+	if !lots.ValidAllocation(req.Allocation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "allocation must be 'melt_weight' or 'manual'"})
+		return
+	}
+	if req.TotalPrice < 0 || req.BuyersPremium < 0 || req.ShippingCost < 0 || req.SalesTax < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Lot price and fees can't be negative"})
+		return
+	}
+
+	coins, ok := userCoins(c, userID, req.CoinIDs)
+	if !ok {
+		return
+	}
+
+	lot := models.Lot{
+		ID:            uuid.New(),
+		UserID:        userID.(uuid.UUID),
+		Name:          req.Name,
+		Seller:        req.Seller,
+		InvoiceNumber: req.InvoiceNumber,
+		PurchaseDate:  req.PurchaseDate,
+		TotalPrice:    req.TotalPrice,
+		BuyersPremium: req.BuyersPremium,
+		ShippingCost:  req.ShippingCost,
+		SalesTax:      req.SalesTax,
+		Allocation:    req.Allocation,
+		Notes:         req.Notes,
+	}
+	if !saveLot(c, &lot, coins, req.Amounts) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, lotDetail(lot, coins))
+}
+
+// UpdateLot changes a lot's details, totals or coins and re-splits its cost
+func UpdateLot(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var lot models.Lot
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&lot).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+		return
+	}
+
+	var req UpdateLotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name != "" {
+		lot.Name = req.Name
+	}
+	if req.Seller != nil {
+		lot.Seller = *req.Seller
+	}
+	if req.InvoiceNumber != nil {
+		lot.InvoiceNumber = *req.InvoiceNumber
+	}
+	if req.PurchaseDate != nil {
+		lot.PurchaseDate = req.PurchaseDate
+	}
+	if req.Notes != nil {
+		lot.Notes = *req.Notes
+	}
+	if req.Allocation != "" {
+		if !lots.ValidAllocation(req.Allocation) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "allocation must be 'melt_weight' or 'manual'"})
+			return
+		}
+		lot.Allocation = req.Allocation
+	}
+	for _, amount := range []struct {
+		field *float64
+		value *float64
+	}{
+		{&lot.TotalPrice, req.TotalPrice},
+		{&lot.BuyersPremium, req.BuyersPremium},
+		{&lot.ShippingCost, req.ShippingCost},
+		{&lot.SalesTax, req.SalesTax},
+	} {
+		if amount.value == nil {
+			continue
+		}
+		if *amount.value < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Lot price and fees can't be negative"})
+			return
+		}
+		*amount.field = *amount.value
+	}
+
+	var coins []models.Coin
+	if req.CoinIDs != nil {
+		var ok bool
+		if coins, ok = userCoins(c, userID, *req.CoinIDs); !ok {
+			return
+		}
+	} else if err := database.GetDB().Where("lot_id = ?", lot.ID).Order("created_at ASC").Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+
+	if !saveLot(c, &lot, coins, req.Amounts) {
+		return
+	}
+
+	c.JSON(http.StatusOK, lotDetail(lot, coins))
+}
+
+// DeleteLot removes a lot. Its coins keep their allocated costs.
+func DeleteLot(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var lot models.Lot
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&lot).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Lot not found"})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Coin{}).Where("lot_id = ?", lot.ID).Update("lot_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&lot).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lot"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lot deleted successfully"})
+}
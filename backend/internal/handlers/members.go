@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InvitePortfolioMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdatePortfolioMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// PortfolioMemberSummary is a portfolio member with the emails and portfolio
+// name the UI shows
+type PortfolioMemberSummary struct {
+	models.PortfolioMember
+	PortfolioName  string `json:"portfolio_name"`
+	Email          string `json:"email"`
+	InvitedByEmail string `json:"invited_by_email"`
+}
+
+// findPortfolio loads a portfolio the user has at least the min role on,
+// responding 404 when they can't see it and 403 when their role is too low
+func findPortfolio(c *gin.Context, portfolioID any, min string) (models.Portfolio, bool) {
+	userID, _ := c.Get("user_id")
+
+	portfolio, _, err := members.Find(userID.(uuid.UUID), portfolioID, min)
+	switch {
+	case errors.Is(err, members.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+		return portfolio, false
+	case errors.Is(err, members.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role on this portfolio doesn't allow that"})
+		return portfolio, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolio"})
+		return portfolio, false
+	}
+	return portfolio, true
+}
+
+// coinPortfolio loads the portfolio of a coin the user has at least the min
+// role on, responding 403 when they don't
+func coinPortfolio(c *gin.Context, coin models.Coin, min string) (models.Portfolio, bool) {
+	userID, _ := c.Get("user_id")
+
+	portfolio, _, err := members.Find(userID.(uuid.UUID), coin.PortfolioID, min)
+	switch {
+	case errors.Is(err, members.ErrNotFound):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return portfolio, false
+	case errors.Is(err, members.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your role on this portfolio doesn't allow that"})
+		return portfolio, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolio"})
+		return portfolio, false
+	}
+	return portfolio, true
+}
+
+func summarizeMembers(list []models.PortfolioMember) ([]PortfolioMemberSummary, error) {
+	var userIDs, portfolioIDs []uuid.UUID
+	for _, m := range list {
+		userIDs = append(userIDs, m.UserID, m.InvitedBy)
+		portfolioIDs = append(portfolioIDs, m.PortfolioID)
+	}
+	emails := map[uuid.UUID]string{}
+	names := map[uuid.UUID]string{}
+	if len(list) > 0 {
+		var users []models.User
+		if err := database.GetDB().Select("id", "email").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			emails[user.ID] = user.Email
+		}
+		var portfolios []models.Portfolio
+		if err := database.GetDB().Select("id", "name").Where("id IN ?", portfolioIDs).Find(&portfolios).Error; err != nil {
+			return nil, err
+		}
+		for _, portfolio := range portfolios {
+			names[portfolio.ID] = portfolio.Name
+		}
+	}
+
+	result := make([]PortfolioMemberSummary, len(list))
+	for i, m := range list {
+		result[i] = PortfolioMemberSummary{
+			PortfolioMember: m,
+			PortfolioName:   names[m.PortfolioID],
+			Email:           emails[m.UserID],
+			InvitedByEmail:  emails[m.InvitedBy],
+		}
+	}
+	return result, nil
+}
+
+func listMembers(c *gin.Context, query *gorm.DB) {
+	var list []models.PortfolioMember
+	if err := query.Order("created_at").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	summaries, err := summarizeMembers(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	c.JSON(http.StatusOK, summaries)
+}
+
+// publishMember publishes a step of a membership with the emails the
+// notifications need. byEmail is whoever acted, when not the member.
+func publishMember(member models.PortfolioMember, action, byEmail string) (PortfolioMemberSummary, error) {
+	summaries, err := summarizeMembers([]models.PortfolioMember{member})
+	if err != nil {
+		return PortfolioMemberSummary{}, err
+	}
+	summary := summaries[0]
+	events.Publish(events.PortfolioMemberUpdated{
+		Member:        member,
+		Action:        action,
+		PortfolioName: summary.PortfolioName,
+		ByEmail:       byEmail,
+		MemberEmail:   summary.Email,
+	})
+	return summary, nil
+}
+
+// findMember loads the member in :memberId of the portfolio in :id
+func findMember(c *gin.Context) (models.PortfolioMember, bool) {
+	var member models.PortfolioMember
+	if err := database.GetDB().Where("id = ? AND portfolio_id = ?", c.Param("memberId"), c.Param("id")).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return member, false
+	}
+	return member, true
+}
+
+// userEmail looks up the email of the signed in user
+func userEmail(c *gin.Context) string {
+	userID, _ := c.Get("user_id")
+	var user models.User
+	database.GetDB().Select("email").First(&user, "id = ?", userID)
+	return user.Email
+}
+
+// GetPortfolioMembers lists everyone a portfolio is shared with, including
+// pending invitations
+func GetPortfolioMembers(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleViewer)
+	if !ok {
+		return
+	}
+	listMembers(c, database.GetDB().Where("portfolio_id = ?", portfolio.ID))
+}
+
+// InvitePortfolioMember invites another user on the instance to a portfolio
+// as an owner, editor or viewer. They get access once they accept.
+func InvitePortfolioMember(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var req InvitePortfolioMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !members.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor or viewer"})
+		return
+	}
+
+	var inviter models.User
+	if err := database.GetDB().First(&inviter, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	var invitee models.User
+	if err := database.GetDB().Where("email = ?", strings.TrimSpace(req.Email)).First(&invitee).Error; err != nil || !middleware.SameTenant(inviter.TenantID, invitee.TenantID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with that email on this instance"})
+		return
+	}
+	if invitee.ID == inviter.ID || invitee.ID == portfolio.UserID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "That user already owns this portfolio"})
+		return
+	}
+
+	var existing int64
+	database.GetDB().Model(&models.PortfolioMember{}).Where("portfolio_id = ? AND user_id = ?", portfolio.ID, invitee.ID).Count(&existing)
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "That user is already a member or invited"})
+		return
+	}
+
+	member := models.PortfolioMember{
+		PortfolioID: portfolio.ID,
+		UserID:      invitee.ID,
+		Role:        req.Role,
+		InvitedBy:   inviter.ID,
+	}
+	if err := database.GetDB().Create(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite member"})
+		return
+	}
+
+	events.Publish(events.PortfolioMemberUpdated{Member: member, Action: events.MemberInvited, PortfolioName: portfolio.Name, ByEmail: inviter.Email, MemberEmail: invitee.Email})
+	c.JSON(http.StatusCreated, PortfolioMemberSummary{PortfolioMember: member, PortfolioName: portfolio.Name, Email: invitee.Email, InvitedByEmail: inviter.Email})
+}
+
+// UpdatePortfolioMember gives a member, or a pending invitation, another role
+func UpdatePortfolioMember(c *gin.Context) {
+	if _, ok := findPortfolio(c, c.Param("id"), members.RoleOwner); !ok {
+		return
+	}
+	member, ok := findMember(c)
+	if !ok {
+		return
+	}
+
+	var req UpdatePortfolioMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !members.ValidRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be owner, editor or viewer"})
+		return
+	}
+
+	member.Role = req.Role
+	if err := database.GetDB().Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	summary, err := publishMember(member, events.MemberChanged, userEmail(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// RemovePortfolioMember ends a membership or withdraws an invitation. Owners
+// can remove anyone; other members can only remove themselves, to leave.
+func RemovePortfolioMember(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	member, ok := findMember(c)
+	if !ok {
+		return
+	}
+	leaving := member.UserID == userID.(uuid.UUID)
+	if !leaving {
+		if _, ok := findPortfolio(c, member.PortfolioID, members.RoleOwner); !ok {
+			return
+		}
+	}
+
+	if err := database.GetDB().Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	byEmail := ""
+	if !leaving {
+		byEmail = userEmail(c)
+	}
+	publishMember(member, events.MemberRemoved, byEmail)
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// GetPortfolioInvitations lists the invitations the user hasn't answered
+func GetPortfolioInvitations(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	listMembers(c, database.GetDB().Where("user_id = ? AND accepted_at IS NULL", userID))
+}
+
+// pendingInvitation loads an unanswered invitation to the user, responding
+// 404 when there isn't one
+func pendingInvitation(c *gin.Context) (models.PortfolioMember, bool) {
+	userID, _ := c.Get("user_id")
+
+	var member models.PortfolioMember
+	if err := database.GetDB().Where("id = ? AND user_id = ? AND accepted_at IS NULL", c.Param("id"), userID).First(&member).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return member, false
+	}
+	return member, true
+}
+
+// AcceptPortfolioInvitation joins a portfolio with the role invited as
+func AcceptPortfolioInvitation(c *gin.Context) {
+	member, ok := pendingInvitation(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	member.AcceptedAt = &now
+	if err := database.GetDB().Save(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	summary, err := publishMember(member, events.MemberAccepted, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// DeclinePortfolioInvitation turns down an invitation, which is removed
+func DeclinePortfolioInvitation(c *gin.Context) {
+	member, ok := pendingInvitation(c)
+	if !ok {
+		return
+	}
+	if err := database.GetDB().Delete(&member).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+		return
+	}
+
+	publishMember(member, events.MemberDeclined, "")
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
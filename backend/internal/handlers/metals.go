@@ -2,13 +2,28 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/market"
 	"github.com/evansminotwood/aureus/internal/metals"
 	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/spothistory"
+	"github.com/evansminotwood/aureus/internal/valuation"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// SpotPricesResponse is the spot prices with whether the market is open
+type SpotPricesResponse struct {
+	*metals.SpotPrices
+	Market market.Status `json:"market"`
+}
+
 func GetSpotPrices(c *gin.Context) {
 	prices, err := metals.GetSpotPrices()
 	if err != nil {
@@ -18,7 +33,49 @@ func GetSpotPrices(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, prices)
+	c.JSON(http.StatusOK, SpotPricesResponse{SpotPrices: prices, Market: market.Default().Status(time.Now())})
+}
+
+// GetMarketIndicators returns current spot prices and ratios with their
+// daily, weekly and monthly changes, for the dashboard header. The daily
+// change runs from one market close to the next: while the market is open,
+// from the last close; while it's closed, over the last session, so a
+// weekend doesn't show a change of its own.
+func GetMarketIndicators(c *gin.Context) {
+	prices, err := metals.GetSpotPrices()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spot prices"})
+		return
+	}
+
+	current := map[string]float64{
+		"gold":      prices.Gold,
+		"silver":    prices.Silver,
+		"platinum":  prices.Platinum,
+		"palladium": prices.Palladium,
+	}
+	at := prices.UpdatedAt
+
+	now := time.Now()
+	calendar := market.Default()
+	status := calendar.Status(now)
+	if !status.Open {
+		if closing := spothistory.CloseAt(status.LastClose); closing != nil {
+			current = closing
+		}
+	}
+	dayFrom := calendar.PreviousClose(now)
+
+	c.JSON(http.StatusOK, gin.H{
+		"indicators": spothistory.Indicators(current,
+			spothistory.CloseAt(dayFrom),
+			spothistory.PricesAt(at.AddDate(0, 0, -7)),
+			spothistory.PricesAt(at.AddDate(0, -1, 0)),
+		),
+		"updated_at":      at,
+		"market":          status,
+		"day_change_from": dayFrom,
+	})
 }
 
 func GetMetalCompositions(c *gin.Context) {
@@ -46,6 +103,39 @@ func GetCoinComposition(c *gin.Context) {
 	c.JSON(http.StatusOK, composition)
 }
 
+// ResolveCoinType maps free text (nicknames, abbreviations, PCGS-style names)
+// to a catalog coin type for the add-coin autocomplete
+func ResolveCoinType(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "q query parameter is required",
+		})
+		return
+	}
+
+	year, _ := strconv.Atoi(c.Query("year"))
+
+	name, nameMatch, ok := metals.CanonicalCoinType(query)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No coin type matches this name",
+		})
+		return
+	}
+
+	match, _ := metals.MatchComposition(query, year)
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":       query,
+		"coin_type":   name,
+		"name_match":  nameMatch,
+		"composition": match.Composition,
+		"method":      match.Method,
+		"confidence":  match.Confidence,
+	})
+}
+
 func CalculateMeltValue(c *gin.Context) {
 	var req struct {
 		MetalType string  `json:"metal_type" binding:"required"`
@@ -70,67 +160,162 @@ func CalculateMeltValue(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"melt_value": meltValue,
+		"currency":   metals.Currency,
 		"metal_type": req.MetalType,
 		"weight":     req.Weight,
+		"unit":       metals.UnitTroyOunce,
 		"purity":     req.Purity,
 	})
 }
 
+// BackfillCoinReport is what a composition backfill did, or would do, to one coin
+type BackfillCoinReport struct {
+	CoinID   uuid.UUID       `json:"coin_id"`
+	CoinType string          `json:"coin_type"`
+	Year     int             `json:"year"`
+	Status   string          `json:"status"` // "updated", "unchanged", "skipped", "no_match" or "failed"
+	Reason   string          `json:"reason,omitempty"`
+	Changes  []RevalueChange `json:"changes,omitempty"`
+}
+
+// BackfillMetalComposition fills in metal composition and melt value from the
+// catalog. It can be narrowed with ?portfolio_id= and ?coin_type=; coins that
+// already have a composition are skipped unless ?overwrite=true, and
+// compositions entered or confirmed by the user are never replaced. It runs
+// as a background job whose result is a per-coin report; with
+// ?dry_run=true the report is made without saving anything.
 func BackfillMetalComposition(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	dryRun := c.Query("dry_run") == "true"
+	overwrite := c.Query("overwrite") == "true"
 
-	// Import required packages
 	db := database.GetDB()
 
-	// Get all coins for this user
-	var coins []models.Coin
-	if err := db.Table("coins").
+	query := db.Table("coins").
 		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
-		Where("portfolios.user_id = ?", userID).
-		Find(&coins).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to fetch coins",
-		})
-		return
+		Where("portfolios.user_id = ?", userID)
+	if portfolioID := c.Query("portfolio_id"); portfolioID != "" {
+		if _, err := uuid.Parse(portfolioID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
+			return
+		}
+		query = query.Where("coins.portfolio_id = ?", portfolioID)
 	}
+	if coinType := c.Query("coin_type"); coinType != "" {
+		query = query.Where("LOWER(coins.coin_type) = LOWER(?)", coinType)
+	}
+
+	startJob(c, jobs.KindCompositionBackfill, func(p *jobs.Progress) (any, error) {
+		return backfillComposition(userID.(uuid.UUID), query, dryRun, overwrite, p)
+	})
+}
 
-	updated := 0
+// backfillComposition fills in the composition of the coins query selects,
+// as BackfillMetalComposition describes
+func backfillComposition(userID uuid.UUID, query *gorm.DB, dryRun, overwrite bool, p *jobs.Progress) (gin.H, error) {
+	db := database.GetDB()
+
+	var coins []models.Coin
+	if err := query.Select("coins.*").Find(&coins).Error; err != nil {
+		return nil, err
+	}
+	p.SetTotal(len(coins))
+
+	portfolioIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		portfolioIDs[i] = coin.PortfolioID
+	}
+	bases, err := valuation.Bases(portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]BackfillCoinReport, 0, len(coins))
+	updated, failed := 0, 0
 	for _, coin := range coins {
-		// Skip if already has metal composition
-		if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
+		p.Add(1)
+		entry := BackfillCoinReport{CoinID: coin.ID, CoinType: coin.CoinType, Year: coin.Year}
+
+		switch {
+		case coin.CompositionSource == metals.CompositionSourceManual || coin.CompositionSource == metals.CompositionSourceConfirmed:
+			entry.Status, entry.Reason = "skipped", "composition set by user"
+			report = append(report, entry)
+			continue
+		case !overwrite && coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0:
+			entry.Status, entry.Reason = "skipped", "already has a composition"
+			report = append(report, entry)
 			continue
 		}
 
 		// Try to get composition (year-based for accuracy)
-		var comp metals.MetalComposition
-		var exists bool
+		match, exists := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType)
+		if !exists {
+			entry.Status = "no_match"
+			report = append(report, entry)
+			continue
+		}
+
+		before := coin
+		comp := match.Composition
+		coin.MetalType = comp.MetalType
+		coin.MetalWeight = comp.Weight
+		coin.MetalPurity = comp.Purity
+		coin.CompositionSource = match.Method
+		coin.CompositionConfidence = match.Confidence
+
+		// Calculate melt value using new function that handles both precious and base metals
+		if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
+			valuation.ApplyMeltValue(&coin, meltValue, bases[coin.PortfolioID])
+		}
 
-		if coin.Year > 0 {
-			comp, exists = metals.GetCompositionByYear(coin.CoinType, coin.Year)
-		} else {
-			comp, exists = metals.GetComposition(coin.CoinType)
+		entry.Changes = revalueChanges(before, coin)
+		if len(entry.Changes) == 0 {
+			entry.Status = "unchanged"
+			report = append(report, entry)
+			continue
 		}
 
-		if exists {
-			coin.MetalType = comp.MetalType
-			coin.MetalWeight = comp.Weight
-			coin.MetalPurity = comp.Purity
+		entry.Status = "updated"
+		if dryRun {
+			updated++
+			report = append(report, entry)
+			continue
+		}
 
-			// Calculate melt value using new function that handles both precious and base metals
-			if meltValue, err := metals.CalculateMeltValueFromComposition(comp); err == nil {
-				coin.CurrentValue = meltValue
-			}
+		// Save the updated coin
+		if err := db.Save(&coin).Error; err != nil {
+			failed++
+			entry.Status, entry.Reason, entry.Changes = "failed", "failed to save", nil
+			report = append(report, entry)
+			continue
+		}
+		updated++
+		report = append(report, entry)
 
-			// Save the updated coin
-			if err := db.Save(&coin).Error; err == nil {
-				updated++
-			}
+		if coin.CurrentValue != before.CurrentValue {
+			events.Publish(events.CoinValued{
+				UserID:             userID,
+				CoinID:             coin.ID,
+				PortfolioID:        coin.PortfolioID,
+				Source:             "melt",
+				OldCurrentValue:    before.CurrentValue,
+				NewCurrentValue:    coin.CurrentValue,
+				OldNumismaticValue: coin.NumismaticValue,
+				NewNumismaticValue: coin.NumismaticValue,
+			})
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Metal composition backfill complete",
+	message := "Metal composition backfill complete"
+	if dryRun {
+		message = "Metal composition backfill dry run - no changes saved"
+	}
+	return gin.H{
+		"message":     message,
+		"dry_run":     dryRun,
 		"total_coins": len(coins),
-		"updated": updated,
-	})
+		"updated":     updated,
+		"failed":      failed,
+		"coins":       report,
+	}, nil
 }
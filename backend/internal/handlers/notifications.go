@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/notifications"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const defaultNotificationLimit = 50
+
+// GetNotifications lists the user's notifications, newest first. ?unread=true
+// limits it to unread ones; ?limit= caps the count (default 50).
+func GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit := defaultNotificationLimit
+	if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+		limit = min(value, maxCursorPageSize)
+	}
+
+	query := database.GetReadDB().Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var list []models.Notification
+	if err := query.Order("created_at DESC").Limit(limit).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	var unread int64
+	if err := database.GetReadDB().Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": list,
+		"unread":        unread,
+	})
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var notification models.Notification
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := database.GetDB().Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	result := database.GetDB().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked_read": result.RowsAffected})
+}
+
+type UpdateNotificationSettingsRequest struct {
+	Phone          *string `json:"phone"`
+	TelegramChatID *string `json:"telegram_chat_id"`
+}
+
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys"`
+}
+
+func notificationSettingsResponse(settings models.NotificationSettings) gin.H {
+	return gin.H{
+		"phone":            settings.Phone,
+		"telegram_chat_id": settings.TelegramChatID,
+		"push_subscribed":  settings.PushEndpoint != "",
+		"channels":         notifications.Available(),
+		"vapid_public_key": notifications.VAPIDPublicKey(),
+	}
+}
+
+// GetNotificationSettings returns where the user can be reached and which
+// channels the instance supports
+func GetNotificationSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	settings, err := notifications.Settings(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationSettingsResponse(settings))
+}
+
+// UpdateNotificationSettings sets the user's phone number and Telegram chat.
+// An empty string clears a field.
+func UpdateNotificationSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req UpdateNotificationSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := notifications.Settings(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	if req.Phone != nil {
+		phone := strings.ReplaceAll(strings.TrimSpace(*req.Phone), " ", "")
+		if phone != "" && !notifications.ValidPhone(phone) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "phone must be in international format, e.g. +15555550123"})
+			return
+		}
+		settings.Phone = phone
+	}
+	if req.TelegramChatID != nil {
+		settings.TelegramChatID = strings.TrimSpace(*req.TelegramChatID)
+	}
+
+	if err := database.GetDB().Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationSettingsResponse(settings))
+}
+
+// SetPushSubscription stores the browser's Web Push subscription, replacing
+// any earlier one
+func SetPushSubscription(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := notifications.Settings(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	settings.PushEndpoint = req.Endpoint
+	settings.PushP256dh = req.Keys.P256dh
+	settings.PushAuth = req.Keys.Auth
+	if err := database.GetDB().Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save push subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notificationSettingsResponse(settings))
+}
+
+// DeletePushSubscription removes the user's Web Push subscription
+func DeletePushSubscription(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := database.GetDB().Model(&models.NotificationSettings{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"push_endpoint": "", "push_p256dh": "", "push_auth": ""}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove push subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"push_subscribed": false})
+}
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/clientip"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/oauth"
+	"github.com/evansminotwood/aureus/internal/security"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// oauthNonceCookie keeps the nonce of a sign-in in progress, binding its
+// callback to the browser that started it
+const oauthNonceCookie = "aureus_oauth_nonce"
+
+// oauthLinkCookie keeps the nonce of a link ticket, binding the ticket to
+// the browser of the user who asked for it
+const oauthLinkCookie = "aureus_oauth_link"
+
+// oauthLoginCodeTTL is how long the frontend has to trade a login code
+const oauthLoginCodeTTL = time.Minute
+
+// errOAuth is a failed sign-in, reported to the frontend by its code
+type errOAuth struct {
+	code string
+}
+
+func (e errOAuth) Error() string { return "oauth: " + e.code }
+
+type OAuthExchangeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// oauthCallbackURL is where a provider sends the browser back to: under
+// API_URL, the API's public URL, or else the host the sign-in started on.
+// X-Forwarded-Proto is only believed from TRUSTED_PROXIES.
+func oauthCallbackURL(c *gin.Context, provider string) string {
+	base := strings.TrimRight(config.String("API_URL", ""), "/")
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || (clientip.FromTrustedProxy(c) && c.GetHeader("X-Forwarded-Proto") == "https") {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + "/api/v1/auth/oauth/" + provider + "/callback"
+}
+
+// redirectOAuthError sends the browser back to the frontend's login page
+// with why signing in failed
+func redirectOAuthError(c *gin.Context, code string) {
+	c.Redirect(http.StatusFound, mail.AppURL()+"/login?oauth_error="+url.QueryEscape(code))
+}
+
+// GetOAuthProviders lists the providers users can sign in with, and what
+// to call each on its button
+func GetOAuthProviders(c *gin.Context) {
+	names := []string{}
+	labels := map[string]string{}
+	for _, p := range oauth.Enabled() {
+		names = append(names, p.Name())
+		labels[p.Name()] = oauth.Label(p.Name())
+	}
+	c.JSON(http.StatusOK, gin.H{"providers": names, "labels": labels})
+}
+
+// StartOAuth sends the browser to a provider's sign-in page. invite_code
+// is kept for creating an account on an invite-only instance, and a
+// ticket from LinkOAuth links the provider to that user instead.
+func StartOAuth(c *gin.Context) {
+	provider, ok := oauth.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown or disabled sign-in provider"})
+		return
+	}
+
+	state := oauth.State{
+		Provider:   provider.Name(),
+		TenantID:   middleware.TenantIDFrom(c),
+		InviteCode: strings.TrimSpace(c.Query("invite_code")),
+	}
+	if ticket := c.Query("link"); ticket != "" {
+		nonce, _ := c.Cookie(oauthLinkCookie)
+		userID, err := oauth.ParseLinkTicket(ticket, nonce)
+		if err != nil {
+			redirectOAuthError(c, "invalid_state")
+			return
+		}
+		security.ClearCookie(c.Writer, oauthLinkCookie, "/", http.SameSiteDefaultMode)
+		state.LinkUserID = &userID
+	}
+
+	signed, nonce, err := state.Sign()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	// Apple posts the callback from its own site, so the cookie has to be
+	// sent on cross-site requests
+	security.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthNonceCookie,
+		Value:    nonce,
+		Path:     "/",
+		MaxAge:   int(oauth.StateTTL.Seconds()),
+		SameSite: http.SameSiteNoneMode,
+	})
+	authURL := provider.AuthURL(signed, oauthCallbackURL(c, provider.Name()), c.Query("login_hint"))
+	if authURL == "" {
+		redirectOAuthError(c, "provider_unavailable")
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback finishes signing in with a provider. A linking user's
+// account gets the identity; otherwise the user it belongs to, the user
+// with its verified email, or a new user is signed in, and the browser is
+// sent to the frontend with a login code to trade for a session.
+func OAuthCallback(c *gin.Context) {
+	provider, ok := oauth.Get(c.Param("provider"))
+	if !ok {
+		redirectOAuthError(c, "unknown_provider")
+		return
+	}
+	// Apple posts the callback as a form; others redirect with a query
+	param := func(key string) string {
+		if value := c.PostForm(key); value != "" {
+			return value
+		}
+		return c.Query(key)
+	}
+
+	nonce, _ := c.Cookie(oauthNonceCookie)
+	security.ClearCookie(c.Writer, oauthNonceCookie, "/", http.SameSiteNoneMode)
+
+	if param("error") != "" {
+		redirectOAuthError(c, "cancelled")
+		return
+	}
+	state, err := oauth.ParseState(param("state"), nonce, provider.Name())
+	if err != nil {
+		redirectOAuthError(c, "invalid_state")
+		return
+	}
+
+	identity, err := provider.Exchange(c.Request.Context(), param("code"), oauthCallbackURL(c, provider.Name()))
+	if err != nil {
+		log.Printf("Sign-in with %s failed: %v", provider.Name(), err)
+		redirectOAuthError(c, "provider_error")
+		return
+	}
+
+	if state.LinkUserID != nil {
+		if err := linkOAuthIdentity(*state.LinkUserID, provider.Name(), identity); err != nil {
+			var failed errOAuth
+			if errors.As(err, &failed) {
+				c.Redirect(http.StatusFound, mail.AppURL()+"/dashboard?oauth_error="+url.QueryEscape(failed.code))
+				return
+			}
+			log.Printf("Linking %s to user %s failed: %v", provider.Name(), *state.LinkUserID, err)
+			c.Redirect(http.StatusFound, mail.AppURL()+"/dashboard?oauth_error=server_error")
+			return
+		}
+		c.Redirect(http.StatusFound, mail.AppURL()+"/dashboard?oauth_linked="+url.QueryEscape(provider.Name()))
+		return
+	}
+
+	user, err := oauthUser(provider.Name(), identity, state)
+	if err != nil {
+		var failed errOAuth
+		if errors.As(err, &failed) {
+			redirectOAuthError(c, failed.code)
+			return
+		}
+		log.Printf("Sign-in with %s failed: %v", provider.Name(), err)
+		redirectOAuthError(c, "server_error")
+		return
+	}
+
+	code, err := issueOAuthLoginCode(user.ID)
+	if err != nil {
+		log.Printf("Sign-in with %s failed: %v", provider.Name(), err)
+		redirectOAuthError(c, "server_error")
+		return
+	}
+	c.Redirect(http.StatusFound, mail.AppURL()+"/oauth/callback?code="+url.QueryEscape(code))
+}
+
+// mockAccount reports whether user could only have come from mock sign-in:
+// an address at the mock domain without a password. Mock identities sign in
+// to nothing else.
+func mockAccount(user models.User) bool {
+	return user.Password == "" && oauth.IsMockEmail(user.Email)
+}
+
+// oauthUser finds or creates the user signing in with identity
+func oauthUser(provider string, identity oauth.Identity, state oauth.State) (models.User, error) {
+	db := database.GetDB()
+	var user models.User
+
+	var linked models.OAuthIdentity
+	err := db.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&linked).Error
+	if err == nil {
+		if err := db.First(&user, "id = ?", linked.UserID).Error; err != nil {
+			return user, err
+		}
+		if identity.Mock && !mockAccount(user) {
+			return user, errOAuth{"mock_identity"}
+		}
+		if !sameTenant(user.TenantID, state.TenantID) {
+			return user, errOAuth{"wrong_tenant"}
+		}
+		if identity.Email != "" && identity.Email != linked.Email {
+			db.Model(&linked).Update("email", identity.Email)
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	if identity.Email == "" {
+		return user, errOAuth{"email_required"}
+	}
+	// Only a verified email proves the account is the same person's
+	err = db.Where("LOWER(email) = LOWER(?)", identity.Email).First(&user).Error
+	if err == nil {
+		if !identity.EmailVerified {
+			return user, errOAuth{"email_unverified"}
+		}
+		if identity.Mock && !mockAccount(user) {
+			return user, errOAuth{"mock_identity"}
+		}
+		if !sameTenant(user.TenantID, state.TenantID) {
+			return user, errOAuth{"wrong_tenant"}
+		}
+		return user, db.Create(&models.OAuthIdentity{UserID: user.ID, Provider: provider, Subject: identity.Subject, Email: identity.Email}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// A new account, under the same rules as registering with a password
+	mode := auth.RegistrationMode()
+	if mode == auth.RegistrationDisabled {
+		return user, errOAuth{"registration_disabled"}
+	}
+	needsInvite := mode == auth.RegistrationInvite
+	if needsInvite && state.InviteCode == "" {
+		return user, errOAuth{"invite_required"}
+	}
+
+	isAdmin := auth.IsAdminEmail(identity.Email) && identity.EmailVerified && !identity.Mock
+	user = models.User{TenantID: state.TenantID, Email: identity.Email, IsAdmin: isAdmin}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if needsInvite {
+			if err := redeemInviteCode(tx, state.InviteCode, user.TenantID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.OAuthIdentity{UserID: user.ID, Provider: provider, Subject: identity.Subject, Email: identity.Email}).Error
+	})
+	if errors.Is(err, errInvalidInviteCode) {
+		return user, errOAuth{"invalid_invite"}
+	}
+	return user, err
+}
+
+// linkOAuthIdentity links identity to userID, unless it already belongs to
+// someone else or the user has linked another account at the provider
+func linkOAuthIdentity(userID uuid.UUID, provider string, identity oauth.Identity) error {
+	db := database.GetDB()
+	if identity.Mock {
+		var user models.User
+		if err := db.First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		if !mockAccount(user) {
+			return errOAuth{"mock_identity"}
+		}
+	}
+	var existing models.OAuthIdentity
+	err := db.Where("provider = ? AND subject = ?", provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return errOAuth{"already_linked"}
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var count int64
+	if err := db.Model(&models.OAuthIdentity{}).Where("user_id = ? AND provider = ?", userID, provider).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errOAuth{"provider_already_linked"}
+	}
+	return db.Create(&models.OAuthIdentity{UserID: userID, Provider: provider, Subject: identity.Subject, Email: identity.Email}).Error
+}
+
+func sameTenant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// issueOAuthLoginCode stores a single-use login code for userID, clearing
+// out expired ones
+func issueOAuthLoginCode(userID uuid.UUID) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+
+	now := time.Now()
+	db := database.GetDB()
+	if err := db.Where("expires_at < ?", now).Delete(&models.OAuthLoginCode{}).Error; err != nil {
+		log.Printf("Failed to clear expired OAuth login codes: %v", err)
+	}
+	err := db.Create(&models.OAuthLoginCode{UserID: userID, CodeHash: hashToken(code), ExpiresAt: now.Add(oauthLoginCodeTTL)}).Error
+	return code, err
+}
+
+// ExchangeOAuthCode trades the login code from a provider sign-in for a
+// session, once
+func ExchangeOAuthCode(c *gin.Context) {
+	var req OAuthExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var code models.OAuthLoginCode
+		if err := tx.Where("code_hash = ? AND expires_at > ?", hashToken(strings.TrimSpace(req.Code)), time.Now()).
+			First(&code).Error; err != nil {
+			return err
+		}
+		// Deleting it is what makes the code single use
+		result := tx.Delete(&code)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&user, "id = ?", code.UserID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login code", "code": "invalid_code"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	respondWithSession(c, http.StatusOK, user, nil, "")
+}
+
+// GetOAuthIdentities lists the providers linked to the user's account
+func GetOAuthIdentities(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	identities := []models.OAuthIdentity{}
+	if err := database.GetDB().Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch linked accounts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// LinkOAuth returns the URL to send the browser to for linking a provider
+// to the user's account. The URL only works in the browser that asked for
+// it, which gets the ticket's nonce in a cookie; the request has to be made
+// with credentials for the browser to keep it.
+func LinkOAuth(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	provider, ok := oauth.Get(c.Param("provider"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown or disabled sign-in provider"})
+		return
+	}
+	ticket, nonce, err := oauth.NewLinkTicket(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start linking"})
+		return
+	}
+	security.SetCookie(c.Writer, &http.Cookie{
+		Name:   oauthLinkCookie,
+		Value:  nonce,
+		Path:   "/",
+		MaxAge: int(oauth.LinkTicketTTL.Seconds()),
+	})
+
+	start := strings.TrimSuffix(oauthCallbackURL(c, provider.Name()), "/callback") + "/start?link=" + url.QueryEscape(ticket)
+	c.JSON(http.StatusOK, gin.H{"url": start})
+}
+
+// UnlinkOAuth removes a provider from the user's account. The last way to
+// sign in can't be removed: a user without a password needs to set one
+// first, e.g. through a password reset.
+func UnlinkOAuth(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	provider := c.Param("provider")
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.OAuthIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		var remaining int64
+		if err := tx.Model(&models.OAuthIdentity{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+			return err
+		}
+		// A password is no way in while passwords are turned off
+		if remaining == 0 && (user.Password == "" || !auth.PasswordLogin()) {
+			return errOAuth{"last_sign_in_method"}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provider not linked"})
+		return
+	}
+	var failed errOAuth
+	if errors.As(err, &failed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Set a password before unlinking your only sign-in method", "code": failed.code})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink provider"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Provider unlinked"})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCursorPageSize = 100
+	maxCursorPageSize     = 1000
+)
+
+// cursorPage applies keyset paging (?after=<id>&limit=N) to a query over an
+// append-only table ordered by (timeColumn, id). The query must set its
+// Model and be scoped to what the caller may see. Paging is opt-in: with
+// neither parameter the query is returned unchanged and limit is 0. On a bad
+// cursor it responds with 400 and returns ok false.
+//
+// Unlike offsets, the cursor seeks straight to the next row, so deep pages
+// of long histories cost the same as the first.
+func cursorPage(c *gin.Context, query *gorm.DB, timeColumn string) (paged *gorm.DB, limit int, ok bool) {
+	if limit, _ := strconv.Atoi(c.Query("limit")); c.Query("after") == "" && limit <= 0 {
+		return query, 0, true
+	}
+	return keysetPage(c, query, timeColumn, false)
+}
+
+// newestFirstPage is cursorPage for feeds read from the newest row back,
+// e.g. the audit log. Paging is always on: without ?limit= a page holds
+// defaultCursorPageSize rows.
+func newestFirstPage(c *gin.Context, query *gorm.DB, timeColumn string) (paged *gorm.DB, limit int, ok bool) {
+	return keysetPage(c, query, timeColumn, true)
+}
+
+// keysetPage orders query by (timeColumn, id), ascending or descending,
+// and seeks past ?after=
+func keysetPage(c *gin.Context, query *gorm.DB, timeColumn string, descending bool) (paged *gorm.DB, limit int, ok bool) {
+	after := c.Query("after")
+	limit, _ = strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = defaultCursorPageSize
+	}
+	limit = min(limit, maxCursorPageSize)
+
+	order, seek := " ASC", ">"
+	if descending {
+		order, seek = " DESC", "<"
+	}
+	paged = query.Order(timeColumn + order + ", id" + order).Limit(limit)
+	if after == "" {
+		return paged, limit, true
+	}
+
+	if _, err := uuid.Parse(after); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return nil, 0, false
+	}
+
+	var anchor time.Time
+	err := query.Session(&gorm.Session{}).Select(timeColumn).Where("id = ?", after).Limit(1).Row().Scan(&anchor)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown cursor"})
+		return nil, 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve cursor"})
+		return nil, 0, false
+	}
+
+	return paged.Where("("+timeColumn+", id) "+seek+" (?, ?)", anchor, after), limit, true
+}
+
+// setNextCursor tells the client where the next page starts. A short page is
+// the last one, so no cursor is sent.
+func setNextCursor(c *gin.Context, limit, count int, lastID uuid.UUID) {
+	if limit > 0 && count == limit {
+		c.Header("X-Next-Cursor", lastID.String())
+	}
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/sessions"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// passwordResetTTL is how long a password reset link stays valid
+const passwordResetTTL = time.Hour
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ForgotPassword mails a password reset link to the account with the given
+// email. It answers the same whether or not there is one, so it can't be
+// used to find out who has an account.
+func ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if rejectPasswordLogin(c) {
+		return
+	}
+
+	accepted := gin.H{"message": "If an account exists for that email, a reset link is on its way"}
+
+	query := database.GetDB().Where("email = ?", strings.TrimSpace(req.Email))
+	if tenantID := middleware.TenantIDFrom(c); tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	}
+	var user models.User
+	if err := query.First(&user).Error; err != nil {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reset token"})
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	reset := models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+
+	// A new link replaces any earlier one
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&reset).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start password reset"})
+		return
+	}
+
+	link := mail.AppURL() + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Someone asked to reset the password of the Aureus account for this address.\n\n"+
+		"To choose a new password, open this link within %d minutes:\n\n%s\n\n"+
+		"If you didn't ask for this, you can ignore this email; your password stays the same.\n", int(passwordResetTTL.Minutes()), link)
+	if err := mail.Send(user.Email, "Reset your Aureus password", body); err != nil {
+		// Still accepted, so a failing mailer doesn't reveal the account
+		log.Printf("Password reset for user %s: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// ResetPassword sets a new password with the token from a reset link. The
+// link works once, and every existing session is logged out, since whoever
+// knew the old password shouldn't stay signed in.
+func ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !checkPassword(c, req.Password) {
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	var user models.User
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var reset models.PasswordResetToken
+		if err := tx.Where("token_hash = ? AND expires_at > ?", hashToken(strings.TrimSpace(req.Token)), time.Now()).
+			First(&reset).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&user, "id = ?", reset.UserID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&user).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&reset).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link", "code": "invalid_token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := sessions.RevokeAll(user.ID); err != nil {
+		log.Printf("Password reset for user %s: failed to log out sessions: %v", user.ID, err)
+	}
+
+	body := "The password of your Aureus account was just changed with a reset link, and every device was signed out.\n\n" +
+		"If you didn't do this, reset your password again and contact your administrator right away.\n"
+	if err := mail.Send(user.Email, "Your Aureus password was changed", body); err != nil {
+		log.Printf("Password reset notice for user %s: %v", user.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; sign in with the new password"})
+}
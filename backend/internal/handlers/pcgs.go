@@ -3,7 +3,6 @@ package handlers
 import (
 	"net/http"
 
-	"github.com/evansminotwood/aureus/internal/pcgs"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,7 +15,7 @@ func GetPCGSPrice(c *gin.Context) {
 		return
 	}
 
-	client := pcgs.NewPCGSClient()
+	client := pcgsClientForUser(c)
 
 	priceData, err := client.GetPriceData(certNumber)
 	if err != nil {
@@ -47,7 +46,7 @@ func GetPCGSImages(c *gin.Context) {
 		return
 	}
 
-	client := pcgs.NewPCGSClient()
+	client := pcgsClientForUser(c)
 
 	imageData, err := client.GetCoinImagesByCertNumber(certNumber)
 	if err != nil {
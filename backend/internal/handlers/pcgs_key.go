@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/crypto"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/pcgssync"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SetPCGSKeyRequest struct {
+	APIKey string `json:"api_key" binding:"required"`
+}
+
+type SetPCGSSyncScheduleRequest struct {
+	IntervalDays *int `json:"interval_days" binding:"required"`
+}
+
+// pcgsClientForUser returns a PCGS client using the user's own API key when one
+// is stored, falling back to the instance-wide PCGS_API_KEY
+func pcgsClientForUser(c *gin.Context) *pcgs.PCGSClient {
+	userID, _ := c.Get("user_id")
+	return pcgssync.ClientForUser(userID.(uuid.UUID))
+}
+
+func maskKey(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
+
+// GetPCGSKey reports whether the user has stored their own PCGS API key
+func GetPCGSKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.PCGSAPIKey == "" {
+		c.JSON(http.StatusOK, gin.H{"configured": false})
+		return
+	}
+
+	apiKey, err := crypto.Decrypt(user.PCGSAPIKey)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"configured": true, "readable": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"configured": true, "readable": true, "key": maskKey(apiKey)})
+}
+
+// SetPCGSKey stores the user's own PCGS API key, encrypted at rest
+func SetPCGSKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req SetPCGSKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sealed, err := crypto.Encrypt(req.APIKey)
+	if err != nil {
+		if errors.Is(err, crypto.ErrKeyMissing) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Per-user API keys are not enabled on this instance"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encrypt API key"})
+		return
+	}
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", userID).Update("pcgs_api_key", sealed).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"configured": true, "readable": true, "key": maskKey(req.APIKey)})
+}
+
+// DeletePCGSKey removes the user's own PCGS API key
+func DeletePCGSKey(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := database.GetDB().Model(&models.User{}).Where("id = ?", userID).Update("pcgs_api_key", "").Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove API key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"configured": false})
+}
+
+// GetPCGSSyncSchedule returns the user's automatic PCGS sync schedule
+func GetPCGSSyncSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"interval_days": user.PCGSSyncIntervalDays,
+		"last_synced":   user.PCGSSyncedAt,
+		"options":       pcgssync.Intervals,
+	})
+}
+
+// SetPCGSSyncSchedule sets how often the user's coins are synced with PCGS
+// automatically; 0 turns automatic syncs off
+func SetPCGSSyncSchedule(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req SetPCGSSyncScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !pcgssync.ValidInterval(*req.IntervalDays) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("interval_days must be one of %v", pcgssync.Intervals)})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err := database.GetDB().Model(&user).Update("pcgs_sync_interval_days", *req.IntervalDays).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save sync schedule"})
+		return
+	}
+	user.PCGSSyncIntervalDays = *req.IntervalDays
+
+	c.JSON(http.StatusOK, gin.H{
+		"interval_days": user.PCGSSyncIntervalDays,
+		"last_synced":   user.PCGSSyncedAt,
+		"options":       pcgssync.Intervals,
+	})
+}
@@ -1,51 +1,160 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/inflation"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/middleware"
 	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/trash"
+	"github.com/evansminotwood/aureus/internal/valuation"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type CreatePortfolioRequest struct {
-	Name        string `json:"name" binding:"required"`
-	Description string `json:"description"`
+	Name           string `json:"name" binding:"required"`
+	Description    string `json:"description"`
+	ValuationBasis string `json:"valuation_basis"`
+	CoverImageURL  string `json:"cover_image_url"`
+	Color          string `json:"color"`
+	Icon           string `json:"icon"`
+	Notes          string `json:"notes"`
+	// Defaults for new coins; auto-sync is on unless set to false
+	DefaultFaceCurrency    string `json:"default_face_currency"`
+	DefaultStorageLocation string `json:"default_storage_location"`
+	DefaultAutoSync        *bool  `json:"default_auto_sync"`
 }
 
 type UpdatePortfolioRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name             string  `json:"name"`
+	Description      string  `json:"description"`
+	MonthlyStatement *bool   `json:"monthly_statement"`
+	StatementStories *bool   `json:"statement_stories"`
+	ValuationBasis   string  `json:"valuation_basis"`
+	CoverImageURL    *string `json:"cover_image_url"` // fields left out are unchanged; "" clears them
+	Color            *string `json:"color"`
+	Icon             *string `json:"icon"`
+	Notes            *string `json:"notes"`
+	// Changing the defaults only affects coins added afterwards
+	DefaultFaceCurrency    *string `json:"default_face_currency"`
+	DefaultStorageLocation *string `json:"default_storage_location"`
+	DefaultAutoSync        *bool   `json:"default_auto_sync"`
 }
 
+type ReorderPortfoliosRequest struct {
+	PortfolioIDs []string `json:"portfolio_ids" binding:"required"`
+}
+
+const (
+	maxPortfolioIconLength  = 32
+	maxPortfolioNotesLength = 20000
+)
+
+var portfolioColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// validatePortfolioAppearance checks a portfolio's cover image, color, icon
+// and notes, responding with 400 when one is invalid
+func validatePortfolioAppearance(c *gin.Context, coverImageURL, color, icon, notes string) bool {
+	switch {
+	case coverImageURL != "" && !strings.HasPrefix(coverImageURL, "/") && !strings.HasPrefix(coverImageURL, "https://") && !strings.HasPrefix(coverImageURL, "http://"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cover_image_url must be an http(s) URL or an uploaded image path"})
+	case color != "" && !portfolioColorPattern.MatchString(color):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "color must be a hex color like #c9a227"})
+	case len(icon) > maxPortfolioIconLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("icon must be at most %d characters", maxPortfolioIconLength)})
+	case len(notes) > maxPortfolioNotesLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("notes must be at most %d characters", maxPortfolioNotesLength)})
+	default:
+		return true
+	}
+	return false
+}
+
+// normalizeDefaultCurrency validates a portfolio's default face currency,
+// which may be blank
+func normalizeDefaultCurrency(c *gin.Context, code string) (string, bool) {
+	if strings.TrimSpace(code) == "" {
+		return "", true
+	}
+	currency, ok := metals.NormalizeCurrency(code)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "default_face_currency must be a three-letter currency code like USD"})
+		return "", false
+	}
+	return currency, true
+}
+
+// PortfolioWithCount is a portfolio in the list with its coin count, total
+// value and the user's role on it
+type PortfolioWithCount struct {
+	models.Portfolio
+	CoinCount  int     `json:"coin_count"`
+	TotalValue float64 `json:"total_value"`
+	Role       string  `json:"role"`
+}
+
+// PortfolioDetail is a portfolio with its coins and the user's role on it
+type PortfolioDetail struct {
+	models.Portfolio
+	Role string `json:"role"`
+}
+
+// GetPortfolios lists the user's portfolios in their order, followed by the
+// portfolios shared with them by name. Archived portfolios are listed
+// instead with ?archived=true.
 func GetPortfolios(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+	archived := c.Query("archived") == "true"
 
 	var portfolios []models.Portfolio
-	if err := database.GetDB().Where("user_id = ?", userID).Find(&portfolios).Error; err != nil {
+	if err := database.GetReadDB().Where("user_id = ? AND archived = ?", userID, archived).Order("sort_order ASC, created_at ASC").Find(&portfolios).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolios"})
 		return
 	}
-
-	type PortfolioWithCount struct {
-		models.Portfolio
-		CoinCount  int     `json:"coin_count"`
-		TotalValue float64 `json:"total_value"`
+	var shared []models.Portfolio
+	if err := database.GetReadDB().Where("id IN (?) AND user_id <> ? AND archived = ?", members.Accessible(database.GetReadDB(), userID.(uuid.UUID)), userID, archived).
+		Order("name ASC").Find(&shared).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolios"})
+		return
+	}
+	sharedIDs := make([]uuid.UUID, len(shared))
+	for i, p := range shared {
+		sharedIDs[i] = p.ID
 	}
+	roles, err := members.Roles(userID.(uuid.UUID), sharedIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolios"})
+		return
+	}
+	portfolios = append(portfolios, shared...)
 
 	result := make([]PortfolioWithCount, len(portfolios))
 	for i, p := range portfolios {
 		var count int64
 		var totalValue float64
 
-		database.GetDB().Model(&models.Coin{}).Where("portfolio_id = ?", p.ID).Count(&count)
-		database.GetDB().Model(&models.Coin{}).Where("portfolio_id = ?", p.ID).Select("COALESCE(SUM(current_value * quantity), 0)").Scan(&totalValue)
+		database.GetReadDB().Model(&models.Coin{}).Where("portfolio_id = ?", p.ID).Count(&count)
+		database.GetReadDB().Model(&models.Coin{}).Where("portfolio_id = ?", p.ID).Select("COALESCE(SUM(current_value * quantity), 0)").Scan(&totalValue)
 
+		role := members.RoleOwner
+		if r, ok := roles[p.ID]; ok {
+			role = r
+		}
 		result[i] = PortfolioWithCount{
 			Portfolio:  p,
 			CoinCount:  int(count),
 			TotalValue: totalValue,
+			Role:       role,
 		}
 	}
 
@@ -56,16 +165,17 @@ func GetPortfolio(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	portfolioID := c.Param("id")
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().
-		Preload("Coins").
-		Where("id = ? AND user_id = ?", portfolioID, userID).
-		First(&portfolio).Error; err != nil {
+	portfolio, role, err := members.Find(userID.(uuid.UUID), portfolioID, members.RoleViewer)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
 		return
 	}
+	if err := database.GetDB().Where("portfolio_id = ?", portfolio.ID).Find(&portfolio.Coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
 
-	c.JSON(http.StatusOK, portfolio)
+	c.JSON(http.StatusOK, PortfolioDetail{Portfolio: portfolio, Role: role})
 }
 
 func CreatePortfolio(c *gin.Context) {
@@ -77,10 +187,40 @@ func CreatePortfolio(c *gin.Context) {
 		return
 	}
 
+	if req.ValuationBasis == "" {
+		req.ValuationBasis = valuation.DefaultBasis
+	}
+	if !valuation.ValidBasis(req.ValuationBasis) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valuation basis: " + req.ValuationBasis})
+		return
+	}
+	if !validatePortfolioAppearance(c, req.CoverImageURL, req.Color, req.Icon, req.Notes) {
+		return
+	}
+	defaultCurrency, ok := normalizeDefaultCurrency(c, req.DefaultFaceCurrency)
+	if !ok {
+		return
+	}
+
+	// New portfolios go to the end of the user's ordering
+	var lastOrder int
+	database.GetDB().Model(&models.Portfolio{}).Where("user_id = ?", userID).Select("COALESCE(MAX(sort_order), -1)").Scan(&lastOrder)
+
 	portfolio := models.Portfolio{
-		UserID:      userID.(uuid.UUID),
-		Name:        req.Name,
-		Description: req.Description,
+		UserID:         userID.(uuid.UUID),
+		TenantID:       middleware.TenantIDFrom(c),
+		Name:           req.Name,
+		Description:    req.Description,
+		ValuationBasis: req.ValuationBasis,
+		CoverImageURL:  req.CoverImageURL,
+		Color:          req.Color,
+		Icon:           req.Icon,
+		Notes:          req.Notes,
+		SortOrder:      lastOrder + 1,
+
+		DefaultFaceCurrency:    defaultCurrency,
+		DefaultStorageLocation: strings.TrimSpace(req.DefaultStorageLocation),
+		DefaultAutoSync:        req.DefaultAutoSync,
 	}
 
 	if err := database.GetDB().Create(&portfolio).Error; err != nil {
@@ -88,16 +228,17 @@ func CreatePortfolio(c *gin.Context) {
 		return
 	}
 
+	events.Publish(events.PortfolioUpdated{UserID: portfolio.UserID, PortfolioID: portfolio.ID, Action: events.PortfolioCreated})
+
+	middleware.AuditTarget(c, portfolio.ID)
 	c.JSON(http.StatusCreated, portfolio)
 }
 
 func UpdatePortfolio(c *gin.Context) {
-	userID, _ := c.Get("user_id")
 	portfolioID := c.Param("id")
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", portfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleOwner)
+	if !ok {
 		return
 	}
 
@@ -111,61 +252,319 @@ func UpdatePortfolio(c *gin.Context) {
 		portfolio.Name = req.Name
 	}
 	portfolio.Description = req.Description
+	if req.MonthlyStatement != nil {
+		portfolio.MonthlyStatement = *req.MonthlyStatement
+	}
+	if req.StatementStories != nil {
+		portfolio.StatementStories = *req.StatementStories
+	}
+	if req.ValuationBasis != "" && !valuation.ValidBasis(req.ValuationBasis) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid valuation basis: " + req.ValuationBasis})
+		return
+	}
+	basisChanged := req.ValuationBasis != "" && req.ValuationBasis != portfolio.ValuationBasis
+	if req.CoverImageURL != nil {
+		portfolio.CoverImageURL = *req.CoverImageURL
+	}
+	if req.Color != nil {
+		portfolio.Color = *req.Color
+	}
+	if req.Icon != nil {
+		portfolio.Icon = *req.Icon
+	}
+	if req.Notes != nil {
+		portfolio.Notes = *req.Notes
+	}
+	if !validatePortfolioAppearance(c, portfolio.CoverImageURL, portfolio.Color, portfolio.Icon, portfolio.Notes) {
+		return
+	}
+	if req.DefaultFaceCurrency != nil {
+		currency, ok := normalizeDefaultCurrency(c, *req.DefaultFaceCurrency)
+		if !ok {
+			return
+		}
+		portfolio.DefaultFaceCurrency = currency
+	}
+	if req.DefaultStorageLocation != nil {
+		portfolio.DefaultStorageLocation = strings.TrimSpace(*req.DefaultStorageLocation)
+	}
+	if req.DefaultAutoSync != nil {
+		portfolio.DefaultAutoSync = req.DefaultAutoSync
+	}
+	if basisChanged {
+		portfolio.ValuationBasis = req.ValuationBasis
+	}
 
 	if err := database.GetDB().Save(&portfolio).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update portfolio"})
 		return
 	}
 
+	// Stats and reports total current_value, so it has to follow the new basis
+	if basisChanged {
+		if _, err := valuation.Rebase(portfolio.ID, portfolio.ValuationBasis); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revalue coins"})
+			return
+		}
+	}
+
+	events.Publish(events.PortfolioUpdated{UserID: portfolio.UserID, PortfolioID: portfolio.ID, Action: events.PortfolioChanged})
+
 	c.JSON(http.StatusOK, portfolio)
 }
 
-func DeletePortfolio(c *gin.Context) {
+// ArchivePortfolio takes a portfolio the user is done with out of the
+// portfolio list and stops its statements. Its coins stay as they are.
+func ArchivePortfolio(c *gin.Context) {
+	setPortfolioArchived(c, true)
+}
+
+// UnarchivePortfolio puts an archived portfolio back in the portfolio list
+func UnarchivePortfolio(c *gin.Context) {
+	setPortfolioArchived(c, false)
+}
+
+func setPortfolioArchived(c *gin.Context, archived bool) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	portfolio.Archived, portfolio.ArchivedAt = archived, nil
+	if archived {
+		now := time.Now()
+		portfolio.ArchivedAt = &now
+	}
+	if err := database.GetDB().Model(&portfolio).Select("archived", "archived_at").Updates(&portfolio).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update portfolio"})
+		return
+	}
+
+	events.Publish(events.PortfolioUpdated{UserID: portfolio.UserID, PortfolioID: portfolio.ID, Action: events.PortfolioChanged})
+
+	c.JSON(http.StatusOK, portfolio)
+}
+
+// ReorderPortfolios sets the order of the user's portfolio list. Portfolios
+// left out keep their relative order after the listed ones.
+func ReorderPortfolios(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	portfolioID := c.Param("id")
 
-	result := database.GetDB().Where("id = ? AND user_id = ?", portfolioID, userID).Delete(&models.Portfolio{})
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete portfolio"})
+	var req ReorderPortfoliosRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
+	var portfolios []models.Portfolio
+	if err := database.GetDB().Where("user_id = ?", userID).Order("sort_order ASC, created_at ASC").Find(&portfolios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolios"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Portfolio deleted successfully"})
+	byID := make(map[string]models.Portfolio, len(portfolios))
+	for _, p := range portfolios {
+		byID[p.ID.String()] = p
+	}
+
+	ordered := make([]models.Portfolio, 0, len(portfolios))
+	listed := make(map[uuid.UUID]bool, len(req.PortfolioIDs))
+	for _, id := range req.PortfolioIDs {
+		p, ok := byID[strings.ToLower(id)]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found: " + id})
+			return
+		}
+		if listed[p.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Portfolio listed twice: " + id})
+			return
+		}
+		listed[p.ID] = true
+		ordered = append(ordered, p)
+	}
+	for _, p := range portfolios {
+		if !listed[p.ID] {
+			ordered = append(ordered, p)
+		}
+	}
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for i := range ordered {
+			if ordered[i].SortOrder == i {
+				continue
+			}
+			ordered[i].SortOrder = i
+			if err := tx.Model(&ordered[i]).Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder portfolios"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ordered)
 }
 
-func GetPortfolioStats(c *gin.Context) {
+// DeletePortfolio moves a portfolio and its coins to the trash, where they
+// can be restored until they're purged
+func DeletePortfolio(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	portfolioID := c.Param("id")
 
 	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", portfolioID, userID).First(&portfolio).Error; err != nil {
+	if err := database.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&portfolio).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found"})
 		return
 	}
 
-	var stats models.PortfolioStats
+	now := time.Now()
+	if err := trash.TrashPortfolio(portfolio, now); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete portfolio"})
+		return
+	}
+
+	events.Publish(events.PortfolioUpdated{
+		UserID:      userID.(uuid.UUID),
+		PortfolioID: portfolio.ID,
+		Action:      events.PortfolioTrashed,
+	})
 
-	database.GetDB().Model(&models.Coin{}).Where("portfolio_id = ?", portfolioID).Count((*int64)(&stats.TotalCoins))
+	c.JSON(http.StatusOK, gin.H{"message": "Portfolio moved to the trash", "purge_at": trash.PurgeAt(now)})
+}
 
-	database.GetDB().Model(&models.Coin{}).
-		Where("portfolio_id = ?", portfolioID).
-		Select("COALESCE(SUM(current_value * quantity), 0)").
-		Scan(&stats.TotalValue)
+func GetPortfolioStats(c *gin.Context) {
+	portfolioID := c.Param("id")
 
-	database.GetDB().Model(&models.Coin{}).
-		Where("portfolio_id = ?", portfolioID).
-		Select("COALESCE(SUM(purchase_price * quantity), 0)").
-		Scan(&stats.TotalPurchaseCost)
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
 
-	stats.TotalGainLoss = stats.TotalValue - stats.TotalPurchaseCost
-	if stats.TotalPurchaseCost > 0 {
-		stats.GainLossPercent = (stats.TotalGainLoss / stats.TotalPurchaseCost) * 100
+	batch, err := portfolioStats([]uuid.UUID{portfolio.ID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate stats"})
+		return
+	}
+	stats := batch[portfolio.ID]
+
+	if realTerms(c) {
+		var coins []models.Coin
+		if err := database.GetReadDB().Where("portfolio_id = ?", portfolioID).Find(&coins).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+			return
+		}
+
+		cpi := inflation.Current()
+		now := time.Now()
+		adjusted := models.RealPerformance{CPISource: cpi.Source, CPIPeriod: cpi.LatestPeriod()}
+		for _, coin := range coins {
+			adjusted.PurchaseCost += cpi.Adjust(valuation.AllInCost(coin), valuation.AcquiredAt(coin), now)
+		}
+		adjusted.GainLoss = stats.TotalValue - adjusted.PurchaseCost
+		if adjusted.PurchaseCost > 0 {
+			adjusted.GainLossPercent = (adjusted.GainLoss / adjusted.PurchaseCost) * 100
+		}
+		stats.InflationAdjusted = &adjusted
 	}
 
 	c.JSON(http.StatusOK, stats)
 }
+
+// StatsBatchRequest lists up to 100 portfolios to total
+type StatsBatchRequest struct {
+	PortfolioIDs []uuid.UUID `json:"portfolio_ids" binding:"required,min=1,max=100"`
+}
+
+// portfolioStats totals the coins of several portfolios in one grouped
+// query. Portfolios without coins get zero stats.
+func portfolioStats(portfolioIDs []uuid.UUID) (map[uuid.UUID]models.PortfolioStats, error) {
+	var rows []struct {
+		PortfolioID         uuid.UUID
+		TotalCoins          int64
+		TotalValue          float64
+		TotalHammerPrice    float64
+		TotalFees           float64
+		TotalFaceValue      float64
+		JunkSilverFaceValue float64
+	}
+	if err := database.GetReadDB().Model(&models.Coin{}).
+		Select(`portfolio_id,
+			COUNT(*) AS total_coins,
+			COALESCE(SUM(current_value * quantity), 0) AS total_value,
+			COALESCE(SUM(purchase_price * quantity), 0) AS total_hammer_price,
+			COALESCE(SUM(buyers_premium + shipping_cost + sales_tax), 0) AS total_fees,
+			COALESCE(SUM(CASE WHEN face_currency IN ('USD', '') THEN face_value * quantity ELSE 0 END), 0) AS total_face_value,
+			COALESCE(SUM(CASE WHEN face_currency IN ('USD', '') AND metal_type = 'silver' AND metal_purity < 99 THEN face_value * quantity ELSE 0 END), 0) AS junk_silver_face_value`).
+		Where("portfolio_id IN ?", portfolioIDs).
+		Group("portfolio_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	stats := make(map[uuid.UUID]models.PortfolioStats, len(portfolioIDs))
+	for _, id := range portfolioIDs {
+		stats[id] = models.PortfolioStats{Currency: metals.Currency}
+	}
+	for _, row := range rows {
+		s := models.PortfolioStats{
+			Currency:             metals.Currency,
+			TotalCoins:           row.TotalCoins,
+			TotalValue:           row.TotalValue,
+			TotalHammerPrice:     row.TotalHammerPrice,
+			TotalAcquisitionFees: row.TotalFees,
+			TotalPurchaseCost:    row.TotalHammerPrice + row.TotalFees,
+			TotalFaceValue:       row.TotalFaceValue,
+			JunkSilverFaceValue:  row.JunkSilverFaceValue,
+		}
+		s.TotalGainLoss = s.TotalValue - s.TotalPurchaseCost
+		if s.TotalPurchaseCost > 0 {
+			s.GainLossPercent = (s.TotalGainLoss / s.TotalPurchaseCost) * 100
+		}
+		stats[row.PortfolioID] = s
+	}
+	return stats, nil
+}
+
+// GetPortfolioStatsBatch returns stats for several of the user's portfolios,
+// or those shared with them, at once, keyed by portfolio ID. IDs of other
+// portfolios are listed in not_found.
+func GetPortfolioStatsBatch(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req StatsBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var owned []uuid.UUID
+	if err := database.GetDB().Model(&models.Portfolio{}).
+		Where("id IN ? AND id IN (?)", req.PortfolioIDs, members.Accessible(database.GetDB(), userID.(uuid.UUID))).
+		Pluck("id", &owned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch portfolios"})
+		return
+	}
+
+	stats := map[uuid.UUID]models.PortfolioStats{}
+	if len(owned) > 0 {
+		var err error
+		if stats, err = portfolioStats(owned); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate stats"})
+			return
+		}
+	}
+
+	notFound := []uuid.UUID{}
+	for _, id := range req.PortfolioIDs {
+		if _, ok := stats[id]; !ok {
+			notFound = append(notFound, id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stats":     stats,
+		"not_found": notFound,
+	})
+}
@@ -1,19 +1,26 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/evansminotwood/aureus/internal/database"
-	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/members"
 	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/settings"
+	"github.com/evansminotwood/aureus/internal/snapshots"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // GetCoinPriceHistory returns the price history for a specific coin
 func GetCoinPriceHistory(c *gin.Context) {
-	userID, _ := c.Get("user_id")
 	coinID := c.Param("id")
 
 	// Verify coin belongs to user
@@ -23,68 +30,164 @@ func GetCoinPriceHistory(c *gin.Context) {
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", coin.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
 		return
 	}
 
+	query := database.GetReadDB().Model(&models.PriceHistory{}).Where("coin_id = ?", coin.ID)
+	query, limit, ok := cursorPage(c, query, "recorded_at")
+	if !ok {
+		return
+	}
+	if limit == 0 {
+		query = query.Order("recorded_at ASC")
+	}
+
 	// Fetch price history
 	var history []models.PriceHistory
-	if err := database.GetDB().
-		Where("coin_id = ?", coinID).
-		Order("recorded_at ASC").
-		Find(&history).Error; err != nil {
+	if err := query.Find(&history).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
 		return
 	}
 
+	if len(history) > 0 {
+		setNextCursor(c, limit, len(history), history[len(history)-1].ID)
+	}
 	c.JSON(http.StatusOK, history)
 }
 
-// RecordPriceSnapshot creates a new price history record for a coin
-func RecordPriceSnapshot(c *gin.Context) {
+// priceHistoryExportRow is a price history record with the coin it belongs to
+type priceHistoryExportRow struct {
+	RecordedAt      time.Time
+	CoinID          string
+	CoinType        string
+	Year            int
+	MeltValue       float64
+	NumismaticValue float64
+	PCGSValue       float64
+}
+
+var priceHistoryCSVHeader = []string{"recorded_at", "coin_id", "coin_type", "year", "melt_value", "numismatic_value", "pcgs_value"}
+
+// exportFormat checks the format query parameter. CSV is the only format
+// and the default.
+func exportFormat(c *gin.Context) bool {
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format %q", format)})
+		return false
+	}
+	return true
+}
+
+// writePriceHistoryCSV streams price history rows as a CSV attachment, with
+// dates and amounts as the user's settings show them
+func writePriceHistoryCSV(c *gin.Context, filename string, rows []priceHistoryExportRow) {
 	userID, _ := c.Get("user_id")
+	prefs, err := settings.For(userID.(uuid.UUID))
+	if err != nil {
+		log.Printf("Failed to load settings for user %v: %v", userID, err)
+	}
+	localizer, err := settings.NewLocalizer(prefs)
+	if err != nil {
+		log.Printf("No exchange rate for user %v's currency %s, exporting dollars: %v", userID, prefs.Currency, err)
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(priceHistoryCSVHeader)
+	for _, row := range rows {
+		w.Write([]string{
+			settings.FormatTime(row.RecordedAt, prefs.DateFormat),
+			row.CoinID,
+			row.CoinType,
+			strconv.Itoa(row.Year),
+			strconv.FormatFloat(localizer.Amount(row.MeltValue), 'f', 2, 64),
+			strconv.FormatFloat(localizer.Amount(row.NumismaticValue), 'f', 2, 64),
+			strconv.FormatFloat(localizer.Amount(row.PCGSValue), 'f', 2, 64),
+		})
+	}
+	w.Flush()
+}
+
+// priceHistoryExportQuery selects price history joined with its coin, oldest first
+func priceHistoryExportQuery() *gorm.DB {
+	return database.GetReadDB().Table("price_histories").
+		Select("price_histories.recorded_at, price_histories.coin_id, coins.coin_type, coins.year, " +
+			"price_histories.melt_value, price_histories.numismatic_value, price_histories.pcgs_value").
+		Joins("JOIN coins ON price_histories.coin_id = coins.id AND coins.deleted_at IS NULL").
+		Order("price_histories.recorded_at ASC, coins.coin_type ASC")
+}
+
+// ExportCoinPriceHistory downloads a coin's price history as CSV
+func ExportCoinPriceHistory(c *gin.Context) {
 	coinID := c.Param("id")
 
-	// Verify coin belongs to user
 	var coin models.Coin
 	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
 		return
 	}
 
-	var portfolio models.Portfolio
-	if err := database.GetDB().Where("id = ? AND user_id = ?", coin.PortfolioID, userID).First(&portfolio).Error; err != nil {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
 		return
 	}
 
-	coinUUID, err := uuid.Parse(coinID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid coin ID"})
+	if !exportFormat(c) {
 		return
 	}
 
-	// Calculate current melt value
-	var meltValue float64
-	if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
-		if mv, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
-			meltValue = mv
-		}
+	var rows []priceHistoryExportRow
+	if err := priceHistoryExportQuery().Where("price_histories.coin_id = ?", coin.ID).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
 	}
 
-	// Create price history record
-	now := time.Now()
-	history := models.PriceHistory{
-		CoinID:          coinUUID,
-		MeltValue:       meltValue,
-		NumismaticValue: coin.NumismaticValue,
-		PCGSValue:       0, // TODO: Fetch from PCGS API if cert number exists
-		RecordedAt:      now,
+	writePriceHistoryCSV(c, fmt.Sprintf("coin-%s-price-history.csv", coin.ID), rows)
+}
+
+// ExportPortfolioPriceHistory downloads the price history of every coin in
+// a portfolio as one CSV
+func ExportPortfolioPriceHistory(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
 	}
 
-	if err := database.GetDB().Create(&history).Error; err != nil {
+	if !exportFormat(c) {
+		return
+	}
+
+	var rows []priceHistoryExportRow
+	if err := priceHistoryExportQuery().Where("coins.portfolio_id = ?", portfolio.ID).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch price history"})
+		return
+	}
+
+	writePriceHistoryCSV(c, fmt.Sprintf("portfolio-%s-price-history.csv", portfolio.ID), rows)
+}
+
+// RecordPriceSnapshot creates a new price history record for a coin
+func RecordPriceSnapshot(c *gin.Context) {
+	coinID := c.Param("id")
+
+	// Verify coin belongs to user
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	if _, ok := coinPortfolio(c, coin, members.RoleEditor); !ok {
+		return
+	}
+
+	history, err := snapshots.Record(coin, time.Now())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record price snapshot"})
 		return
 	}
@@ -92,9 +195,17 @@ func RecordPriceSnapshot(c *gin.Context) {
 	c.JSON(http.StatusCreated, history)
 }
 
-// BackfillPriceHistory creates initial price history records for all user's coins
+// BackfillPriceHistory creates initial price history records for all user's
+// coins, as a background job whose result counts the records created
 func BackfillPriceHistory(c *gin.Context) {
 	userID, _ := c.Get("user_id")
+
+	startJob(c, jobs.KindPriceHistoryBackfill, func(p *jobs.Progress) (any, error) {
+		return backfillPriceHistory(userID, p)
+	})
+}
+
+func backfillPriceHistory(userID any, p *jobs.Progress) (gin.H, error) {
 	db := database.GetDB()
 
 	// Get all coins for this user
@@ -103,14 +214,16 @@ func BackfillPriceHistory(c *gin.Context) {
 		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
 		Where("portfolios.user_id = ?", userID).
 		Find(&coins).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
-		return
+		return nil, err
 	}
+	p.SetTotal(len(coins))
 
 	created := 0
 	now := time.Now()
 
 	for _, coin := range coins {
+		p.Add(1)
+
 		// Check if history already exists
 		var count int64
 		if err := db.Model(&models.PriceHistory{}).Where("coin_id = ?", coin.ID).Count(&count).Error; err != nil {
@@ -122,31 +235,15 @@ func BackfillPriceHistory(c *gin.Context) {
 			continue
 		}
 
-		// Calculate melt value
-		var meltValue float64
-		if coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0 {
-			if mv, err := metals.CalculateMeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity); err == nil {
-				meltValue = mv
-			}
-		}
-
 		// Create initial history record
-		history := models.PriceHistory{
-			CoinID:          coin.ID,
-			MeltValue:       meltValue,
-			NumismaticValue: coin.NumismaticValue,
-			PCGSValue:       0,
-			RecordedAt:      now,
-		}
-
-		if err := db.Create(&history).Error; err == nil {
+		if _, err := snapshots.Record(coin, now); err == nil {
 			created++
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	return gin.H{
 		"message":     "Price history backfill complete",
 		"total_coins": len(coins),
 		"created":     created,
-	})
+	}, nil
 }
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/registry"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxRegistryTextLength   = 80
+	defaultLeaderboardLimit = 100
+	maxLeaderboardLimit     = 500
+)
+
+type SaveRegistrySetRequest struct {
+	Title       string `json:"title"`
+	DisplayName string `json:"display_name" binding:"required"`
+	CoinType    string `json:"coin_type" binding:"required"`
+	StartYear   int    `json:"start_year" binding:"required,gte=1"`
+	EndYear     int    `json:"end_year" binding:"required,gte=1"`
+	Published   bool   `json:"published"`
+}
+
+// RegistrySetResponse is a portfolio's registry set with its current score
+type RegistrySetResponse struct {
+	models.RegistrySet
+	Score registry.Score `json:"score"`
+}
+
+func registrySetResponse(c *gin.Context, set models.RegistrySet) {
+	var coins []models.Coin
+	if err := database.GetDB().Where("portfolio_id = ?", set.PortfolioID).Find(&coins).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to score registry set"})
+		return
+	}
+	c.JSON(http.StatusOK, RegistrySetResponse{RegistrySet: set, Score: registry.ScoreSet(set, coins)})
+}
+
+// GetPortfolioRegistrySet returns the registry set of a portfolio and how it
+// scores, whether or not it's published
+func GetPortfolioRegistrySet(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var set models.RegistrySet
+	if err := database.GetDB().Where("portfolio_id = ?", portfolio.ID).First(&set).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio has no registry set"})
+		return
+	}
+	registrySetResponse(c, set)
+}
+
+// SavePortfolioRegistrySet makes a portfolio a registry set of a coin type's
+// years, and publishes it to the public leaderboard when published is set
+func SavePortfolioRegistrySet(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var req SaveRegistrySetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = portfolio.Name
+	}
+	displayName := strings.TrimSpace(req.DisplayName)
+	coinType := strings.Join(strings.Fields(req.CoinType), " ")
+	switch {
+	case len(title) > maxRegistryTextLength || len(displayName) > maxRegistryTextLength || len(coinType) > maxRegistryTextLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("title, display_name and coin_type can be at most %d characters", maxRegistryTextLength)})
+		return
+	case displayName == "" || coinType == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "display_name and coin_type can't be blank"})
+		return
+	case req.EndYear < req.StartYear || req.EndYear > time.Now().Year():
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_year must be between start_year and this year"})
+		return
+	case req.EndYear-req.StartYear+1 > registry.MaxSlots:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("a set can span at most %d years", registry.MaxSlots)})
+		return
+	}
+
+	var set models.RegistrySet
+	database.GetDB().Where("portfolio_id = ?", portfolio.ID).First(&set)
+	set.PortfolioID = portfolio.ID
+	set.UserID = portfolio.UserID
+	set.TenantID = portfolio.TenantID
+	set.Title = title
+	set.DisplayName = displayName
+	set.CoinType = coinType
+	set.StartYear = req.StartYear
+	set.EndYear = req.EndYear
+	set.Published = req.Published
+	if err := database.GetDB().Save(&set).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save registry set"})
+		return
+	}
+
+	registry.Invalidate()
+	registrySetResponse(c, set)
+}
+
+// DeletePortfolioRegistrySet takes a portfolio off the registry
+func DeletePortfolioRegistrySet(c *gin.Context) {
+	portfolio, ok := findPortfolio(c, c.Param("id"), members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	result := database.GetDB().Where("portfolio_id = ?", portfolio.ID).Delete(&models.RegistrySet{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete registry set"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio has no registry set"})
+		return
+	}
+
+	registry.Invalidate()
+	c.JSON(http.StatusOK, gin.H{"message": "Registry set removed"})
+}
+
+// GetRegistryLeaderboard is the public, unauthenticated club leaderboard of
+// published sets, optionally for one ?coin_type=. It 404s unless
+// PUBLIC_REGISTRY is on.
+func GetRegistryLeaderboard(c *gin.Context) {
+	if !registry.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The public registry is not enabled on this instance"})
+		return
+	}
+
+	limit := defaultLeaderboardLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLeaderboardLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, computedAt, err := registry.Leaderboard(middleware.TenantIDFrom(c), c.Query("coin_type"))
+	if errors.Is(err, registry.ErrUnknownCoinType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "coin_type is not a coin type in the catalog"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute leaderboard"})
+		return
+	}
+	total := len(entries)
+	entries = entries[:min(limit, total)]
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(registry.CacheTTL().Seconds())))
+	c.JSON(http.StatusOK, gin.H{
+		"entries":     entries,
+		"total":       total,
+		"computed_at": computedAt,
+	})
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/archive"
+	"github.com/evansminotwood/aureus/internal/certwatch"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/references"
+	"github.com/evansminotwood/aureus/internal/snapshots"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type UpgradeCoinRequest struct {
+	PCGSCertNumber  string     `json:"pcgs_cert_number" binding:"required"`
+	NumismaticValue float64    `json:"numismatic_value" binding:"gte=0"` // looked up by the new cert when 0
+	RegradedAt      *time.Time `json:"regraded_at"`                      // defaults to now
+	Notes           string     `json:"notes"`
+}
+
+// UpgradeCoinResponse is the coin under its new cert and the archived
+// record of it under the old one
+type UpgradeCoinResponse struct {
+	Coin     models.Coin         `json:"coin"`
+	Previous models.ArchivedCoin `json:"previous"`
+}
+
+// UpgradeCoin records that a coin was regraded or crossed over into a new
+// slab. The coin is archived as regraded and a replacement with the new cert
+// takes its place, keeping its cost basis, purchase date, alerts and price
+// history, so its charts carry on across the cert change.
+func UpgradeCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	portfolio, ok := coinPortfolio(c, coin, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req UpgradeCoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cert := strings.TrimSpace(req.PCGSCertNumber)
+	if cert == "" || cert == coin.PCGSCertNumber {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "pcgs_cert_number must be the coin's new cert number"})
+		return
+	}
+	if coin.Quantity != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only a single coin can be regraded; split it off its row first", "code": "split_first"})
+		return
+	}
+	regradedAt := time.Now()
+	if req.RegradedAt != nil {
+		if req.RegradedAt.After(regradedAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "regraded_at can't be in the future"})
+			return
+		}
+		if req.RegradedAt.Before(valuation.AcquiredAt(coin)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "regraded_at can't be before the coin was acquired"})
+			return
+		}
+		regradedAt = *req.RegradedAt
+	}
+	if hasPendingTransfer(coin.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coin has a pending transfer"})
+		return
+	}
+
+	replacement := coin
+	replacement.ID = uuid.Nil
+	replacement.PCGSCertNumber = cert
+	replacement.UpdatedAt = time.Time{}
+	if coin.PCGSCertNumber != "" {
+		// The photos are of the old slab
+		replacement.ImageURL, replacement.ThumbnailURL = "", ""
+	}
+
+	client := pcgsClientForUser(c)
+	var certImages []pcgs.ImageDetail
+	if imageData, err := client.GetCoinImagesByCertNumber(cert); err == nil && imageData.IsValidRequest && len(imageData.Images) > 0 {
+		certImages = imageData.Images
+		replacement.ImageURL = imageData.GetFrontImageURL()
+		if len(imageData.Images) > 1 {
+			replacement.ThumbnailURL = imageData.GetBackImageURL()
+		}
+	}
+	var pcgsValue float64
+	var pcgsNumber string
+	if priceData, err := client.GetPriceData(cert); err == nil {
+		pcgsValue, pcgsNumber = priceData.Price, priceData.PCGSNumber
+	}
+	references.Refresh(&replacement, pcgsNumber)
+	switch {
+	case req.NumismaticValue > 0:
+		valuation.ApplyNumismaticValue(&replacement, req.NumismaticValue, portfolio.ValuationBasis)
+	case pcgsValue > 0:
+		valuation.ApplyNumismaticValue(&replacement, pcgsValue, portfolio.ValuationBasis)
+	default:
+		// A graded coin takes no condition haircut
+		valuation.ApplyBasis(&replacement, portfolio.ValuationBasis)
+	}
+	now := time.Now()
+	replacement.LastPriceUpdate = &now
+	certSuspicious := certwatch.Apply(&replacement)
+
+	previous, err := archive.Regrade(coin, &replacement, regradedAt, strings.TrimSpace(req.Notes))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the regrade"})
+		return
+	}
+
+	// The history carries on with the new grade's value
+	if _, err := snapshots.RecordPCGS(replacement, pcgsValue, regradedAt); err != nil {
+		log.Printf("Failed to record snapshot for regraded coin %s: %v", replacement.ID, err)
+	}
+	if certSuspicious {
+		events.Publish(events.CertFlagged{UserID: userID.(uuid.UUID), Coin: replacement})
+	}
+	archiveCertImages(userID.(uuid.UUID), replacement, certImages)
+
+	valuation.Derive(&replacement)
+	c.JSON(http.StatusCreated, UpgradeCoinResponse{Coin: replacement, Previous: previous})
+}
+
+// GetCoinUpgrades lists the archived records a coin was regraded from, most
+// recent first, e.g. its raw record and each earlier slab
+func GetCoinUpgrades(c *gin.Context) {
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	if _, ok := coinPortfolio(c, coin, members.RoleViewer); !ok {
+		return
+	}
+
+	predecessors, err := archive.Predecessors(coin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the coin's earlier records"})
+		return
+	}
+	c.JSON(http.StatusOK, predecessors)
+}
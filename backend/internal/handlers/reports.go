@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/coincode"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgssync"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const defaultStaleAge = 30 * 24 * time.Hour
+
+// StaleCoin is a coin whose current or numismatic value is out of date
+type StaleCoin struct {
+	CoinID          uuid.UUID  `json:"coin_id"`
+	PortfolioID     uuid.UUID  `json:"portfolio_id"`
+	PortfolioName   string     `json:"portfolio_name"`
+	CoinType        string     `json:"coin_type"`
+	Year            int        `json:"year"`
+	PCGSCertNumber  string     `json:"pcgs_cert_number,omitempty"`
+	CurrentValue    float64    `json:"current_value"`
+	NumismaticValue float64    `json:"numismatic_value"`
+	LastPriceUpdate *time.Time `json:"last_price_update"`
+	PCGSSyncedAt    *time.Time `json:"pcgs_synced_at"`
+	Stale           []string   `json:"stale"` // "current_value" and/or "numismatic_value"
+}
+
+// parseAge reads an age such as "30d", "2w" or a Go duration like "36h"
+func parseAge(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultStaleAge, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q", value)
+	}
+	return age, nil
+}
+
+// staleCoins lists the user's coins not valued since cutoff: current_value
+// by last_price_update, and for coins with a cert number numismatic_value by
+// the last PCGS sync
+func staleCoins(c *gin.Context) ([]StaleCoin, bool) {
+	userID, _ := c.Get("user_id")
+
+	age, err := parseAge(c.Query("older_than"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than must be like 30d, 2w or 36h"})
+		return nil, false
+	}
+	cutoff := time.Now().Add(-age)
+
+	query := database.GetReadDB().Table("coins").
+		Select("coins.*, portfolios.name AS portfolio_name").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.deleted_at IS NULL", userID).
+		Where(database.GetReadDB().
+			Where("coins.last_price_update IS NULL OR coins.last_price_update < ?", cutoff).
+			Or("coins.pcgs_cert_number != '' AND (coins.pcgs_synced_at IS NULL OR coins.pcgs_synced_at < ?)", cutoff))
+	if portfolioID := c.Query("portfolio_id"); portfolioID != "" {
+		if _, err := uuid.Parse(portfolioID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
+			return nil, false
+		}
+		query = query.Where("coins.portfolio_id = ?", portfolioID)
+	}
+
+	var rows []struct {
+		models.Coin   `gorm:"embedded"`
+		PortfolioName string
+	}
+	if err := query.Order("coins.last_price_update ASC NULLS FIRST").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return nil, false
+	}
+
+	stale := make([]StaleCoin, len(rows))
+	for i, row := range rows {
+		entry := StaleCoin{
+			CoinID:          row.ID,
+			PortfolioID:     row.PortfolioID,
+			PortfolioName:   row.PortfolioName,
+			CoinType:        row.CoinType,
+			Year:            row.Year,
+			PCGSCertNumber:  row.PCGSCertNumber,
+			CurrentValue:    row.CurrentValue,
+			NumismaticValue: row.NumismaticValue,
+			LastPriceUpdate: row.LastPriceUpdate,
+			PCGSSyncedAt:    row.PCGSSyncedAt,
+			Stale:           []string{},
+		}
+		if row.LastPriceUpdate == nil || row.LastPriceUpdate.Before(cutoff) {
+			entry.Stale = append(entry.Stale, "current_value")
+		}
+		if row.PCGSCertNumber != "" && (row.PCGSSyncedAt == nil || row.PCGSSyncedAt.Before(cutoff)) {
+			entry.Stale = append(entry.Stale, "numismatic_value")
+		}
+		stale[i] = entry
+	}
+	return stale, true
+}
+
+// GetStaleValuesReport lists the user's coins whose values haven't been
+// updated within ?older_than= (default 30d), optionally in one ?portfolio_id=
+func GetStaleValuesReport(c *gin.Context) {
+	stale, ok := staleCoins(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total": len(stale),
+		"coins": stale,
+	})
+}
+
+// RefreshStaleValues revalues every coin the stale values report lists for
+// the same filters, as a background job
+func RefreshStaleValues(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	stale, ok := staleCoins(c)
+	if !ok {
+		return
+	}
+	if len(stale) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "No stale values to refresh", "queued": 0})
+		return
+	}
+
+	var meltIDs, pcgsIDs []uuid.UUID
+	for _, coin := range stale {
+		for _, field := range coin.Stale {
+			if field == "current_value" {
+				meltIDs = append(meltIDs, coin.CoinID)
+			} else {
+				pcgsIDs = append(pcgsIDs, coin.CoinID)
+			}
+		}
+	}
+
+	startJob(c, jobs.KindStaleValueRefresh, func(p *jobs.Progress) (any, error) {
+		return refreshStaleValues(userID.(uuid.UUID), meltIDs, pcgsIDs, p)
+	})
+}
+
+// refreshStaleValues recomputes melt-based values at current spot prices,
+// then syncs numismatic values from PCGS
+func refreshStaleValues(userID uuid.UUID, meltIDs, pcgsIDs []uuid.UUID, p *jobs.Progress) (gin.H, error) {
+	total := len(meltIDs) + len(pcgsIDs)
+	p.SetTotal(total)
+	result := gin.H{"melt_refreshed": 0}
+	if len(meltIDs) > 0 {
+		if err := refreshMeltValues(userID, meltIDs); err != nil {
+			return result, err
+		}
+		result["melt_refreshed"] = len(meltIDs)
+		p.Set(len(meltIDs), total)
+	}
+	if len(pcgsIDs) > 0 {
+		synced, err := pcgssync.Sync(userID, pcgssync.Options{
+			CoinIDs:    pcgsIDs,
+			Background: true,
+			Progress:   func(done, _ int) { p.Set(len(meltIDs)+done, total) },
+		})
+		if err != nil {
+			return result, err
+		}
+		result["pcgs"] = synced
+		if synced.Throttled {
+			return result, fmt.Errorf("PCGS quota nearly used up, %d coins were left for tomorrow", synced.Skipped)
+		}
+		if synced.Failed > 0 {
+			return result, fmt.Errorf("%d of %d PCGS lookups failed", synced.Failed, synced.TotalCoins)
+		}
+	}
+	return result, nil
+}
+
+func refreshMeltValues(userID uuid.UUID, coinIDs []uuid.UUID) error {
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		return err
+	}
+
+	var coins []models.Coin
+	if err := database.GetDB().Where("id IN ?", coinIDs).Find(&coins).Error; err != nil {
+		return err
+	}
+	portfolioIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		portfolioIDs[i] = coin.PortfolioID
+	}
+	bases, err := valuation.Bases(portfolioIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, coin := range coins {
+		// Values of coins without metal content were entered manually and
+		// stay stale until the user updates them
+		meltValue := valuation.CoinMeltValue(coin, calc)
+		if meltValue <= 0 {
+			continue
+		}
+
+		before := coin
+		valuation.ApplyMeltValue(&coin, meltValue, bases[coin.PortfolioID])
+		if err := database.GetDB().Model(&coin).Updates(map[string]interface{}{
+			"melt_value":        coin.MeltValue,
+			"current_value":     coin.CurrentValue,
+			"last_price_update": now,
+		}).Error; err != nil {
+			log.Printf("Failed to refresh coin %s: %v", coin.ID, err)
+			continue
+		}
+
+		if coin.CurrentValue != before.CurrentValue {
+			events.Publish(events.CoinValued{
+				UserID:             userID,
+				CoinID:             coin.ID,
+				PortfolioID:        coin.PortfolioID,
+				Source:             "refresh",
+				OldCurrentValue:    before.CurrentValue,
+				NewCurrentValue:    coin.CurrentValue,
+				OldNumismaticValue: coin.NumismaticValue,
+				NewNumismaticValue: coin.NumismaticValue,
+			})
+		}
+	}
+	return nil
+}
+
+// ScheduledItem is a coin valuable enough to be listed on an insurance
+// schedule rather than covered by a collection's blanket limit
+type ScheduledItem struct {
+	CoinID         uuid.UUID `json:"coin_id"`
+	Code           string    `json:"code"`
+	PortfolioID    uuid.UUID `json:"portfolio_id"`
+	PortfolioName  string    `json:"portfolio_name"`
+	CoinType       string    `json:"coin_type"`
+	Year           int       `json:"year"`
+	MintMark       string    `json:"mint_mark"`
+	PCGSCertNumber string    `json:"pcgs_cert_number,omitempty"`
+	Quantity       int       `json:"quantity"`
+	InsuredValue   float64   `json:"insured_value"` // per coin
+	TotalValue     float64   `json:"total_value"`
+	// Estimated is set when no insured value was entered and current_value
+	// stands in for it
+	Estimated bool `json:"estimated"`
+	// References back up the valuation, e.g. the coin's CoinFacts page
+	References []models.CoinReference `json:"references"`
+}
+
+var scheduledItemsCSVHeader = []string{"coin_id", "code", "portfolio", "coin_type", "year", "mint_mark", "pcgs_cert_number", "quantity", "insured_value", "total_value", "estimated", "references"}
+
+// referenceURLs lists a coin's reference links for a CSV cell, one per line
+func referenceURLs(refs []models.CoinReference) string {
+	urls := make([]string, len(refs))
+	for i, ref := range refs {
+		urls[i] = ref.URL
+	}
+	return strings.Join(urls, "\n")
+}
+
+// GetScheduledItemsReport lists the user's coins whose insured value per coin
+// is at least ?threshold= (default INSURANCE_SCHEDULE_THRESHOLD, $1000), with
+// the coins below it totalled as unscheduled. Coins without an insured value
+// use their current_value. With ?format=csv the scheduled items are
+// downloaded for an insurer.
+func GetScheduledItemsReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	threshold := float64(config.Int64("INSURANCE_SCHEDULE_THRESHOLD", 1000))
+	if value := c.Query("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be a non-negative number"})
+			return
+		}
+		threshold = parsed
+	}
+	format := c.Query("format")
+	if format != "" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported report format %q", format)})
+		return
+	}
+
+	query := database.GetReadDB().Table("coins").
+		Select("coins.*, portfolios.name AS portfolio_name").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.deleted_at IS NULL", userID)
+	if portfolioID := c.Query("portfolio_id"); portfolioID != "" {
+		if _, err := uuid.Parse(portfolioID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid portfolio ID"})
+			return
+		}
+		query = query.Where("coins.portfolio_id = ?", portfolioID)
+	}
+
+	var rows []struct {
+		models.Coin   `gorm:"embedded"`
+		PortfolioName string
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coins"})
+		return
+	}
+
+	scheduled := []ScheduledItem{}
+	var scheduledTotal, unscheduledTotal float64
+	unscheduledCount := 0
+	for _, row := range rows {
+		item := ScheduledItem{
+			CoinID:         row.ID,
+			Code:           coincode.Format(row.Number),
+			PortfolioID:    row.PortfolioID,
+			PortfolioName:  row.PortfolioName,
+			CoinType:       row.CoinType,
+			Year:           row.Year,
+			MintMark:       row.MintMark,
+			PCGSCertNumber: row.PCGSCertNumber,
+			Quantity:       row.Quantity,
+			InsuredValue:   row.InsuredValue,
+			References:     row.References,
+		}
+		if item.InsuredValue == 0 {
+			item.InsuredValue, item.Estimated = row.CurrentValue, true
+		}
+		item.TotalValue = item.InsuredValue * float64(row.Quantity)
+
+		if item.InsuredValue > 0 && item.InsuredValue >= threshold {
+			scheduled = append(scheduled, item)
+			scheduledTotal += item.TotalValue
+		} else {
+			unscheduledCount++
+			unscheduledTotal += item.TotalValue
+		}
+	}
+	sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].InsuredValue > scheduled[j].InsuredValue })
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "scheduled-items.csv"))
+		c.Status(http.StatusOK)
+
+		w := csv.NewWriter(c.Writer)
+		w.Write(scheduledItemsCSVHeader)
+		for _, item := range scheduled {
+			w.Write([]string{
+				item.CoinID.String(),
+				item.Code,
+				item.PortfolioName,
+				item.CoinType,
+				strconv.Itoa(item.Year),
+				item.MintMark,
+				item.PCGSCertNumber,
+				strconv.Itoa(item.Quantity),
+				strconv.FormatFloat(item.InsuredValue, 'f', 2, 64),
+				strconv.FormatFloat(item.TotalValue, 'f', 2, 64),
+				strconv.FormatBool(item.Estimated),
+				referenceURLs(item.References),
+			})
+		}
+		w.Flush()
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold":         threshold,
+		"scheduled":         scheduled,
+		"scheduled_count":   len(scheduled),
+		"scheduled_total":   scheduledTotal,
+		"unscheduled_count": unscheduledCount,
+		"unscheduled_total": unscheduledTotal,
+		"total":             scheduledTotal + unscheduledTotal,
+	})
+}
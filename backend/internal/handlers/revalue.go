@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RevalueChange is one coin field a revalue changed, or would change
+type RevalueChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// revalueCoin recomputes a coin's composition, melt value and PCGS value in
+// place, valuing it on basis. Compositions entered or confirmed by the user
+// are kept. Steps that can't run (no spot prices, PCGS lookup failed) are
+// reported as warnings.
+func revalueCoin(c *gin.Context, coin *models.Coin, basis string) []string {
+	warnings := []string{}
+
+	if coin.CompositionSource != metals.CompositionSourceManual && coin.CompositionSource != metals.CompositionSourceConfirmed {
+		if match, ok := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType); ok {
+			coin.MetalType = match.Composition.MetalType
+			coin.MetalWeight = match.Composition.Weight
+			coin.MetalPurity = match.Composition.Purity
+			coin.CompositionSource = match.Method
+			coin.CompositionConfidence = match.Confidence
+		} else if coin.MetalType == "" {
+			warnings = append(warnings, "No catalog composition matches this coin type")
+		}
+	}
+
+	if coin.MetalType != "" {
+		calc, err := metals.CurrentCalculator()
+		if err != nil {
+			warnings = append(warnings, "Spot prices unavailable: "+err.Error())
+		} else if meltValue := valuation.CoinMeltValue(*coin, calc); meltValue > 0 {
+			valuation.ApplyMeltValue(coin, meltValue, basis)
+		}
+	}
+
+	if coin.PCGSCertNumber != "" {
+		priceData, err := pcgsClientForUser(c).GetPriceData(coin.PCGSCertNumber)
+		if err != nil {
+			warnings = append(warnings, "PCGS lookup failed: "+err.Error())
+		} else if priceData.Price > 0 {
+			valuation.ApplyNumismaticValue(coin, priceData.Price, basis)
+		}
+	}
+
+	return warnings
+}
+
+// revalueChanges lists the valuation fields that differ between two
+// versions of a coin
+func revalueChanges(before, after models.Coin) []RevalueChange {
+	changes := []RevalueChange{}
+	add := func(field string, from, to interface{}) {
+		if from != to {
+			changes = append(changes, RevalueChange{Field: field, Old: from, New: to})
+		}
+	}
+	add("metal_type", before.MetalType, after.MetalType)
+	add("metal_weight", before.MetalWeight, after.MetalWeight)
+	add("metal_purity", before.MetalPurity, after.MetalPurity)
+	add("composition_source", before.CompositionSource, after.CompositionSource)
+	add("composition_confidence", before.CompositionConfidence, after.CompositionConfidence)
+	add("melt_value", before.MeltValue, after.MeltValue)
+	add("numismatic_value", before.NumismaticValue, after.NumismaticValue)
+	add("current_value", before.CurrentValue, after.CurrentValue)
+	return changes
+}
+
+// RevalueCoin recomputes a single coin's composition, melt value and PCGS
+// value. With ?dry_run=true the changes are returned without being saved.
+func RevalueCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	coinID := c.Param("id")
+	dryRun := c.Query("dry_run") == "true"
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", coinID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+
+	portfolio, ok := coinPortfolio(c, coin, members.RoleEditor)
+	if !ok {
+		return
+	}
+
+	before := coin
+	warnings := revalueCoin(c, &coin, portfolio.ValuationBasis)
+	changes := revalueChanges(before, coin)
+
+	if !dryRun && len(changes) > 0 {
+		now := time.Now()
+		coin.LastPriceUpdate = &now
+		if err := database.GetDB().Save(&coin).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coin"})
+			return
+		}
+
+		if coin.CurrentValue != before.CurrentValue || coin.NumismaticValue != before.NumismaticValue {
+			events.Publish(events.CoinValued{
+				UserID:             userID.(uuid.UUID),
+				CoinID:             coin.ID,
+				PortfolioID:        coin.PortfolioID,
+				Source:             "revalue",
+				OldCurrentValue:    before.CurrentValue,
+				NewCurrentValue:    coin.CurrentValue,
+				OldNumismaticValue: before.NumismaticValue,
+				NewNumismaticValue: coin.NumismaticValue,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":  dryRun,
+		"applied":  !dryRun && len(changes) > 0,
+		"changes":  changes,
+		"warnings": warnings,
+		"coin":     coin,
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshSession trades a refresh token for a new access token and a new
+// refresh token; the one sent can't be used again
+func RefreshSession(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, refresh, plain, err := sessions.Rotate(strings.TrimSpace(req.RefreshToken), c.Request.UserAgent(), c.ClientIP())
+	if errors.Is(err, sessions.ErrInvalidToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token", "code": "invalid_refresh_token"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to rotate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	if middleware.MultiTenant() && !middleware.SameTenant(user.TenantID, middleware.TenantIDFrom(c)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token", "code": "invalid_refresh_token"})
+		return
+	}
+
+	respondWithSession(c, http.StatusOK, user, &refresh, plain)
+}
+
+// Logout ends the session of a refresh token, along with the access tokens
+// issued in it. Scoped tokens aren't part of a session; use
+// logout-everywhere to cut those off too.
+func Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := sessions.Revoke(strings.TrimSpace(req.RefreshToken)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutEverywhere ends every session of the user and revokes every token
+// issued to them so far, including this request's and scoped tokens
+func LogoutEverywhere(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := sessions.RevokeAll(userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out everywhere"})
+}
+
+// SessionView is a session in the device list; Current marks the one the
+// request was made in
+type SessionView struct {
+	models.Session
+	Current bool `json:"current"`
+}
+
+// GetSessions lists the devices the user is signed in on, most recently
+// seen first
+func GetSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	list, err := sessions.List(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+	current, _ := c.Get("session_id")
+	views := make([]SessionView, len(list))
+	for i, session := range list {
+		views[i] = SessionView{Session: session, Current: session.ID == current}
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// RevokeSession signs one device out, e.g. a lost phone or a token that may
+// have been stolen. Its refresh token and access tokens stop working at
+// once.
+func RevokeSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	err = sessions.RevokeSession(userID.(uuid.UUID), id)
+	if errors.Is(err, sessions.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
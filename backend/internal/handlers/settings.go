@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/settings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UpdateSettingsRequest changes the fields given; left out is unchanged
+type UpdateSettingsRequest struct {
+	Currency           *string `json:"currency"`
+	WeightUnit         *string `json:"weight_unit"`
+	DateFormat         *string `json:"date_format"`
+	DefaultPortfolioID *string `json:"default_portfolio_id"` // "" clears it
+}
+
+// GetSettings returns the user's display settings and default portfolio
+func GetSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	prefs, err := settings.For(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateSettings changes the user's settings. A currency needs an exchange
+// rate recorded for it, and the default portfolio must be the user's own.
+func UpdateSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prefs, err := settings.For(userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	if req.Currency != nil {
+		currency, err := settings.NormalizeCurrency(*req.Currency)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "unsupported_currency"})
+			return
+		}
+		prefs.Currency = currency
+	}
+	if req.WeightUnit != nil {
+		unit := strings.ToLower(strings.TrimSpace(*req.WeightUnit))
+		if !settings.ValidWeightUnit(unit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "weight_unit must be one of: " + strings.Join(settings.WeightUnits, ", ")})
+			return
+		}
+		prefs.WeightUnit = unit
+	}
+	if req.DateFormat != nil {
+		format := strings.ToLower(strings.TrimSpace(*req.DateFormat))
+		if !settings.ValidDateFormat(format) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date_format must be one of: " + strings.Join(settings.DateFormats, ", ")})
+			return
+		}
+		prefs.DateFormat = format
+	}
+	if req.DefaultPortfolioID != nil {
+		prefs.DefaultPortfolioID = nil
+		if *req.DefaultPortfolioID != "" {
+			portfolio, ok := findPortfolio(c, *req.DefaultPortfolioID, members.RoleEditor)
+			if !ok {
+				return
+			}
+			prefs.DefaultPortfolioID = &portfolio.ID
+		}
+	}
+
+	if err := database.GetDB().Save(&prefs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save settings"})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
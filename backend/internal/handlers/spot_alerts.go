@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/alerts"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateSpotAlertRequest struct {
+	Metric    string   `json:"metric" binding:"required"`
+	Condition string   `json:"condition" binding:"required"`
+	Threshold float64  `json:"threshold" binding:"required,gt=0"`
+	Channels  []string `json:"channels"`
+}
+
+type UpdateSpotAlertRequest struct {
+	Metric    string    `json:"metric"`
+	Condition string    `json:"condition"`
+	Threshold float64   `json:"threshold"`
+	Enabled   *bool     `json:"enabled"`
+	Channels  *[]string `json:"channels"`
+}
+
+// validateSpotAlert checks a spot alert's metric and condition, responding
+// with 400 when either is unknown
+func validateSpotAlert(c *gin.Context, alert models.SpotAlert) bool {
+	if !alerts.ValidSpotMetric(alert.Metric) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("metric must be one of %v", alerts.SpotMetrics())})
+		return false
+	}
+	if !alerts.ValidSpotCondition(alert.Condition) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "condition must be 'above', 'below', 'rises', 'falls' or 'moves'"})
+		return false
+	}
+	return true
+}
+
+// GetSpotAlerts lists the user's spot price alerts
+func GetSpotAlerts(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var spotAlerts []models.SpotAlert
+	if err := database.GetDB().Where("user_id = ?", userID).Order("created_at ASC").Find(&spotAlerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, spotAlerts)
+}
+
+// CreateSpotAlert adds an alert on a spot price or the gold/silver ratio
+func CreateSpotAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateSpotAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alert := models.SpotAlert{
+		UserID:    userID.(uuid.UUID),
+		Metric:    req.Metric,
+		Condition: req.Condition,
+		Threshold: req.Threshold,
+		Enabled:   true,
+		Channels:  req.Channels,
+	}
+	if !validateSpotAlert(c, alert) || !validateChannels(c, req.Channels) {
+		return
+	}
+
+	if err := database.GetDB().Create(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+// UpdateSpotAlert changes a spot alert's metric, condition, threshold or
+// enabled state
+func UpdateSpotAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	var alert models.SpotAlert
+	if err := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).First(&alert).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	var req UpdateSpotAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Metric != "" {
+		alert.Metric = req.Metric
+	}
+	if req.Condition != "" {
+		alert.Condition = req.Condition
+	}
+	if !validateSpotAlert(c, alert) {
+		return
+	}
+	if req.Threshold > 0 {
+		alert.Threshold = req.Threshold
+	}
+	if req.Enabled != nil {
+		alert.Enabled = *req.Enabled
+	}
+	if req.Channels != nil {
+		if !validateChannels(c, *req.Channels) {
+			return
+		}
+		alert.Channels = *req.Channels
+	}
+
+	// Re-arm the alert so the new settings are evaluated from scratch
+	alert.Triggered = false
+
+	if err := database.GetDB().Save(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update alert"})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// DeleteSpotAlert removes a spot alert
+func DeleteSpotAlert(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	alertID := c.Param("id")
+
+	result := database.GetDB().Where("id = ? AND user_id = ?", alertID, userID).Delete(&models.SpotAlert{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert deleted successfully"})
+}
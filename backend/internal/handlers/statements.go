@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/statements"
+	"github.com/gin-gonic/gin"
+)
+
+// statementMonth reads the month query parameter ("2025-09"), defaulting to
+// last month
+func statementMonth(c *gin.Context) (time.Time, bool) {
+	month := c.Query("month")
+	if month == "" {
+		return statements.MonthStart(time.Now()).AddDate(0, -1, 0), true
+	}
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be formatted as YYYY-MM"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// GetPortfolioStatement previews a monthly statement as JSON, or as the HTML
+// email with ?format=html
+func GetPortfolioStatement(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	month, ok := statementMonth(c)
+	if !ok {
+		return
+	}
+
+	st, err := statements.Build(portfolio, month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build statement"})
+		return
+	}
+
+	if c.Query("format") == "html" {
+		html, err := statements.RenderHTML(st)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render statement"})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+		return
+	}
+
+	c.JSON(http.StatusOK, st)
+}
+
+// SendPortfolioStatement emails a monthly statement to the user right away,
+// rendering and sending it in a background job
+func SendPortfolioStatement(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	month, ok := statementMonth(c)
+	if !ok {
+		return
+	}
+
+	startJob(c, jobs.KindStatement, func(*jobs.Progress) (any, error) {
+		if err := statements.Send(portfolio, month); err != nil {
+			log.Printf("Statement for portfolio %s: %v", portfolio.ID, err)
+			return nil, errors.New("failed to send statement")
+		}
+		return gin.H{"message": "Statement sent"}, nil
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTenantRequest struct {
+	Slug string `json:"slug" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+// TenantSummary is a tenant with its user count
+type TenantSummary struct {
+	models.Tenant
+	Users int64 `json:"users"`
+}
+
+// ListTenants lists the tenants of a multi-tenant deployment
+func ListTenants(c *gin.Context) {
+	var tenants []models.Tenant
+	if err := database.GetDB().Order("slug ASC").Find(&tenants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenants"})
+		return
+	}
+
+	result := make([]TenantSummary, len(tenants))
+	for i, tenant := range tenants {
+		result[i] = TenantSummary{Tenant: tenant}
+		database.GetDB().Model(&models.User{}).Where("tenant_id = ?", tenant.ID).Count(&result[i].Users)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CreateTenant adds a tenant. Its users sign up through its subdomain or by
+// sending its slug in the tenant header.
+func CreateTenant(c *gin.Context) {
+	var req CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !middleware.ValidTenantSlug(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slug must be 1-63 lowercase letters, digits or hyphens"})
+		return
+	}
+
+	var existing models.Tenant
+	if err := database.GetDB().Where("slug = ?", slug).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant already exists"})
+		return
+	}
+
+	tenant := models.Tenant{Slug: slug, Name: strings.TrimSpace(req.Name)}
+	if err := database.GetDB().Create(&tenant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tenant"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tenant)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultScopedTokenDays = 30
+
+type CreateScopedTokenRequest struct {
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"gte=0,lte=365"`
+}
+
+type ScopedTokenResponse struct {
+	Token     string    `json:"token"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// grantableScopes normalizes the scopes user asked to hand out, responding
+// with an error if one is unknown or, for non-admins, the admin scope
+func grantableScopes(c *gin.Context, user models.User, requested []string) ([]string, bool) {
+	var scopes []string
+	for _, scope := range requested {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !auth.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scopes must be among " + strings.Join(auth.Scopes, ", ")})
+			return nil, false
+		}
+		if scope == auth.ScopeAdmin && !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can grant the admin scope"})
+			return nil, false
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true
+}
+
+// CreateScopedToken issues a token for the current user limited to the
+// requested scopes, e.g. coins:read and reports:read for an accountant who
+// shouldn't be able to change the inventory. Only admins can ask for the
+// admin scope.
+func CreateScopedToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req CreateScopedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	scopes, ok := grantableScopes(c, user, req.Scopes)
+	if !ok {
+		return
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultScopedTokenDays
+	}
+	token, expiresAt, err := auth.GenerateScopedToken(user.ID, user.Email, user.TenantID, user.TokenVersion, scopes, time.Duration(days)*24*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, ScopedTokenResponse{Token: token, Scopes: scopes, ExpiresAt: expiresAt})
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/transfers"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxTransferMessageLength = 1000
+
+type TransferCoinRequest struct {
+	ToEmail        string `json:"to_email" binding:"required,email"`
+	KeepCostBasis  *bool  `json:"keep_cost_basis"` // options left out default to true
+	IncludeHistory *bool  `json:"include_history"`
+	IncludeImages  *bool  `json:"include_images"`
+	Message        string `json:"message"`
+}
+
+type AcceptTransferRequest struct {
+	PortfolioID string `json:"portfolio_id" binding:"required"`
+}
+
+// TransferSummary is a transfer with enough about the coin and the other
+// party for the recipient to decide, since they can't see the coin itself yet
+type TransferSummary struct {
+	models.CoinTransfer
+	CoinLabel      string `json:"coin_label"`
+	PCGSCertNumber string `json:"pcgs_cert_number,omitempty"`
+	ImageURL       string `json:"image_url,omitempty"`
+	FromEmail      string `json:"from_email"`
+	ToEmail        string `json:"to_email"`
+	// InitiatedByEmail is the co-owner who made the offer, if not the sender
+	InitiatedByEmail string `json:"initiated_by_email,omitempty"`
+}
+
+func optionOrTrue(option *bool) bool {
+	return option == nil || *option
+}
+
+// summarizeTransfers loads the coins and users of transfers for display
+func summarizeTransfers(list []models.CoinTransfer) ([]TransferSummary, error) {
+	var coinIDs, userIDs []uuid.UUID
+	for _, t := range list {
+		coinIDs = append(coinIDs, t.CoinID)
+		userIDs = append(userIDs, t.FromUserID, t.ToUserID)
+		if t.InitiatedByID != nil {
+			userIDs = append(userIDs, *t.InitiatedByID)
+		}
+	}
+
+	coins := map[uuid.UUID]models.Coin{}
+	emails := map[uuid.UUID]string{}
+	if len(list) > 0 {
+		var found []models.Coin
+		if err := database.GetDB().Where("id IN ?", coinIDs).Find(&found).Error; err != nil {
+			return nil, err
+		}
+		for _, coin := range found {
+			coins[coin.ID] = coin
+		}
+		var users []models.User
+		if err := database.GetDB().Select("id", "email").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			emails[user.ID] = user.Email
+		}
+	}
+
+	result := make([]TransferSummary, len(list))
+	for i, t := range list {
+		coin := coins[t.CoinID]
+		result[i] = TransferSummary{
+			CoinTransfer:   t,
+			CoinLabel:      transfers.Label(coin),
+			PCGSCertNumber: coin.PCGSCertNumber,
+			ImageURL:       coin.ImageURL,
+			FromEmail:      emails[t.FromUserID],
+			ToEmail:        emails[t.ToUserID],
+		}
+		if t.InitiatedByID != nil {
+			result[i].InitiatedByEmail = emails[*t.InitiatedByID]
+		}
+	}
+	return result, nil
+}
+
+func publishTransfer(t models.CoinTransfer) {
+	summaries, err := summarizeTransfers([]models.CoinTransfer{t})
+	if err != nil || len(summaries) == 0 {
+		return
+	}
+	s := summaries[0]
+	events.Publish(events.CoinTransferUpdated{Transfer: t, CoinLabel: s.CoinLabel, FromEmail: s.FromEmail, ToEmail: s.ToEmail})
+}
+
+// TransferCoin offers a coin to another user on the instance. It stays with
+// the sender until the recipient accepts; keep_cost_basis, include_history
+// and include_images choose what goes with it. The sender is the creator of
+// the coin's portfolio, which holds its images, even when a co-owner makes
+// the offer.
+func TransferCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var coin models.Coin
+	if err := database.GetDB().First(&coin, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found"})
+		return
+	}
+	portfolio, ok := coinPortfolio(c, coin, members.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var req TransferCoinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if len(message) > maxTransferMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message can be at most 1000 characters"})
+		return
+	}
+
+	var sender models.User
+	if err := database.GetDB().First(&sender, "id = ?", portfolio.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	var recipient models.User
+	if err := database.GetDB().Where("email = ?", strings.TrimSpace(req.ToEmail)).First(&recipient).Error; err != nil || !middleware.SameTenant(sender.TenantID, recipient.TenantID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No user with that email on this instance"})
+		return
+	}
+	if recipient.ID == sender.ID {
+		reason := "Can't transfer a coin to yourself"
+		if sender.ID != userID.(uuid.UUID) {
+			reason = "The coin already belongs to that user"
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": reason})
+		return
+	}
+
+	var pending int64
+	database.GetDB().Model(&models.CoinTransfer{}).Where("coin_id = ? AND status = ?", coin.ID, transfers.StatusPending).Count(&pending)
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coin already has a pending transfer"})
+		return
+	}
+
+	transfer := models.CoinTransfer{
+		CoinID:         coin.ID,
+		FromUserID:     sender.ID,
+		ToUserID:       recipient.ID,
+		Status:         transfers.StatusPending,
+		KeepCostBasis:  optionOrTrue(req.KeepCostBasis),
+		IncludeHistory: optionOrTrue(req.IncludeHistory),
+		IncludeImages:  optionOrTrue(req.IncludeImages),
+		Message:        message,
+	}
+	if initiator := userID.(uuid.UUID); initiator != sender.ID {
+		transfer.InitiatedByID = &initiator
+	}
+	if err := database.GetDB().Create(&transfer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+
+	publishTransfer(transfer)
+	c.JSON(http.StatusCreated, transfer)
+}
+
+// GetTransfers lists the user's incoming and outgoing transfers, newest
+// first, optionally only those with ?status=. Outgoing ones include those
+// the user offered as a co-owner.
+func GetTransfers(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	query := database.GetDB().Where("from_user_id = ? OR to_user_id = ? OR initiated_by_id = ?", userID, userID, userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var list []models.CoinTransfer
+	if err := query.Order("created_at DESC").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+
+	summaries, err := summarizeTransfers(list)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+	incoming, outgoing := []TransferSummary{}, []TransferSummary{}
+	for _, s := range summaries {
+		if s.ToUserID == userID.(uuid.UUID) {
+			incoming = append(incoming, s)
+		} else {
+			outgoing = append(outgoing, s)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"incoming": incoming, "outgoing": outgoing})
+}
+
+// pendingTransfer loads a pending transfer where the user is one of the
+// given parties ("to_user_id", "from_user_id" or "initiated_by_id"),
+// responding with 404 or 409
+func pendingTransfer(c *gin.Context, parties ...string) (models.CoinTransfer, bool) {
+	userID, _ := c.Get("user_id")
+
+	conditions := make([]string, len(parties))
+	args := []any{c.Param("id")}
+	for i, column := range parties {
+		conditions[i] = column + " = ?"
+		args = append(args, userID)
+	}
+	var transfer models.CoinTransfer
+	if err := database.GetDB().Where("id = ? AND ("+strings.Join(conditions, " OR ")+")", args...).First(&transfer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return transfer, false
+	}
+	if transfer.Status != transfers.StatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Transfer is already " + transfer.Status})
+		return transfer, false
+	}
+	return transfer, true
+}
+
+// respond closes a pending transfer without moving the coin
+func respond(c *gin.Context, transfer models.CoinTransfer, status string) {
+	now := time.Now()
+	transfer.Status = status
+	transfer.RespondedAt = &now
+	if err := database.GetDB().Save(&transfer).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer"})
+		return
+	}
+
+	publishTransfer(transfer)
+	c.JSON(http.StatusOK, transfer)
+}
+
+// AcceptTransfer moves an offered coin into one of the recipient's
+// portfolios
+func AcceptTransfer(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	transfer, ok := pendingTransfer(c, "to_user_id")
+	if !ok {
+		return
+	}
+
+	var req AcceptTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	portfolio, _, err := members.Find(userID.(uuid.UUID), req.PortfolioID, members.RoleEditor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Destination portfolio not found or access denied"})
+		return
+	}
+
+	coin, err := transfers.Complete(&transfer, portfolio)
+	if errors.Is(err, transfers.ErrCoinGone) {
+		now := time.Now()
+		transfer.Status = transfers.StatusCancelled
+		transfer.RespondedAt = &now
+		if database.GetDB().Save(&transfer).Error == nil {
+			publishTransfer(transfer)
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "The sender no longer has this coin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer coin"})
+		return
+	}
+
+	publishTransfer(transfer)
+	valuation.Derive(&coin)
+	c.JSON(http.StatusOK, gin.H{"transfer": transfer, "coin": coin})
+}
+
+// DeclineTransfer turns down an offered coin, which stays with the sender
+func DeclineTransfer(c *gin.Context) {
+	if transfer, ok := pendingTransfer(c, "to_user_id"); ok {
+		respond(c, transfer, transfers.StatusDeclined)
+	}
+}
+
+// CancelTransfer withdraws a pending offer, by its sender or the co-owner
+// who made it
+func CancelTransfer(c *gin.Context) {
+	if transfer, ok := pendingTransfer(c, "from_user_id", "initiated_by_id"); ok {
+		respond(c, transfer, transfers.StatusCancelled)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/coincode"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/trash"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TrashedPortfolio is a portfolio in the trash with the number of coins
+// that went with it
+type TrashedPortfolio struct {
+	models.Portfolio
+	CoinCount int64     `json:"coin_count"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedCoin is a coin deleted on its own, with the portfolio it was in
+type TrashedCoin struct {
+	models.Coin
+	PortfolioName string    `json:"portfolio_name"`
+	DeletedAt     time.Time `json:"deleted_at"`
+	PurgeAt       time.Time `json:"purge_at"`
+}
+
+// GetTrash lists the deleted portfolios of the user's and the coins deleted
+// from their portfolios, most recently deleted first. Coins trashed with
+// their portfolio are counted under it rather than listed.
+func GetTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var portfolios []models.Portfolio
+	if err := database.GetReadDB().Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").Find(&portfolios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the trash"})
+		return
+	}
+	trashedPortfolios := make([]TrashedPortfolio, len(portfolios))
+	for i, p := range portfolios {
+		var count int64
+		database.GetReadDB().Unscoped().Model(&models.Coin{}).
+			Where("portfolio_id = ? AND deleted_at = ?", p.ID, p.DeletedAt.Time).Count(&count)
+		trashedPortfolios[i] = TrashedPortfolio{Portfolio: p, CoinCount: count, DeletedAt: p.DeletedAt.Time, PurgeAt: trash.PurgeAt(p.DeletedAt.Time)}
+	}
+
+	var rows []struct {
+		models.Coin   `gorm:"embedded"`
+		PortfolioName string
+	}
+	if err := database.GetReadDB().Table("coins").
+		Select("coins.*, portfolios.name AS portfolio_name").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.deleted_at IS NOT NULL", userID).
+		Where("portfolios.deleted_at IS NULL OR coins.deleted_at <> portfolios.deleted_at").
+		Order("coins.deleted_at DESC").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the trash"})
+		return
+	}
+	trashedCoins := make([]TrashedCoin, len(rows))
+	for i, row := range rows {
+		row.Coin.Code = coincode.Format(row.Number)
+		deletedAt := row.Coin.DeletedAt.Time
+		trashedCoins[i] = TrashedCoin{Coin: row.Coin, PortfolioName: row.PortfolioName, DeletedAt: deletedAt, PurgeAt: trash.PurgeAt(deletedAt)}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"portfolios": trashedPortfolios,
+		"coins":      trashedCoins,
+		"retention":  trash.Retention().String(),
+	})
+}
+
+// findTrashedPortfolio loads a portfolio of the user's in the trash,
+// answering 404 when there isn't one
+func findTrashedPortfolio(c *gin.Context) (models.Portfolio, bool) {
+	userID, _ := c.Get("user_id")
+
+	var portfolio models.Portfolio
+	if err := database.GetDB().Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", c.Param("id"), userID).
+		First(&portfolio).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Portfolio not found in the trash"})
+		return portfolio, false
+	}
+	return portfolio, true
+}
+
+// findTrashedCoin loads a coin in the trash from one of the user's
+// portfolios, answering 404 when there isn't one
+func findTrashedCoin(c *gin.Context) (models.Coin, bool) {
+	userID, _ := c.Get("user_id")
+
+	var coin models.Coin
+	owned := database.GetDB().Unscoped().Model(&models.Portfolio{}).Select("id").Where("user_id = ?", userID)
+	if err := database.GetDB().Unscoped().Where("id = ? AND portfolio_id IN (?) AND deleted_at IS NOT NULL", c.Param("id"), owned).
+		First(&coin).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coin not found in the trash"})
+		return coin, false
+	}
+	return coin, true
+}
+
+// RestoreTrashedPortfolio takes a portfolio out of the trash with the coins
+// deleted along with it
+func RestoreTrashedPortfolio(c *gin.Context) {
+	portfolio, ok := findTrashedPortfolio(c)
+	if !ok {
+		return
+	}
+	if err := trash.RestorePortfolio(portfolio); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore portfolio"})
+		return
+	}
+
+	events.Publish(events.PortfolioUpdated{UserID: portfolio.UserID, PortfolioID: portfolio.ID, Action: events.PortfolioRestored})
+
+	portfolio.DeletedAt.Valid = false
+	c.JSON(http.StatusOK, portfolio)
+}
+
+// PurgeTrashedPortfolio deletes a portfolio in the trash and its coins for
+// good
+func PurgeTrashedPortfolio(c *gin.Context) {
+	portfolio, ok := findTrashedPortfolio(c)
+	if !ok {
+		return
+	}
+	if err := trash.PurgePortfolio(portfolio); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete portfolio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Portfolio deleted for good"})
+}
+
+// RestoreTrashedCoin puts a deleted coin back in its portfolio. A coin
+// whose portfolio is in the trash too comes back with the portfolio.
+func RestoreTrashedCoin(c *gin.Context) {
+	coin, ok := findTrashedCoin(c)
+	if !ok {
+		return
+	}
+	if err := trash.RestoreCoin(coin); err != nil {
+		if errors.Is(err, trash.ErrPortfolioTrashed) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The coin's portfolio is in the trash; restore the portfolio first", "portfolio_id": coin.PortfolioID})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore coin"})
+		return
+	}
+
+	coin.DeletedAt.Valid = false
+	coins := []models.Coin{coin}
+	valuation.RefreshMeltValues(coins)
+	c.JSON(http.StatusOK, coins[0])
+}
+
+// PurgeTrashedCoin deletes a coin in the trash for good
+func PurgeTrashedCoin(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	coin, ok := findTrashedCoin(c)
+	if !ok {
+		return
+	}
+	if err := trash.PurgeCoin(coin, userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coin deleted for good"})
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/evansminotwood/aureus/internal/imageproc"
+	"github.com/evansminotwood/aureus/internal/middleware"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// UploadImage stores an uploaded coin photo. When auto-crop is enabled (via the
+// auto_crop form field or IMAGE_AUTO_CROP), the image service crops the coin and
+// normalizes the background, and the processed image and thumbnail are returned
+// instead of the original.
+func UploadImage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			middleware.PayloadTooLarge(c, "file_too_large", "File too large", gin.H{"limit_bytes": middleware.MaxUploadBytes()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	if fileHeader.Size > middleware.MaxUploadBytes() {
+		middleware.PayloadTooLarge(c, "file_too_large", "File too large", gin.H{"limit_bytes": middleware.MaxUploadBytes()})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported image type: " + contentType})
+		return
+	}
+
+	autoCrop := imageproc.AutoCropEnabled()
+	if value := c.PostForm("auto_crop"); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			autoCrop = parsed
+		}
+	}
+
+	store := storage.NewLocalStorage()
+	uid := userID.(uuid.UUID)
+	baseName := uuid.New().String()
+
+	// USER_STORAGE_QUOTA of 0 disables the per-user quota
+	if quota := storage.UserQuota(); quota > 0 {
+		used, err := store.UserUsage(uid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check storage usage"})
+			return
+		}
+		if used+int64(len(data)) > quota {
+			middleware.PayloadTooLarge(c, "storage_quota_exceeded", "Storage quota exceeded", gin.H{
+				"quota_bytes": quota,
+				"used_bytes":  used,
+			})
+			return
+		}
+	}
+
+	originalURL, err := store.Save(uid, baseName+ext, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+		return
+	}
+
+	response := gin.H{
+		"image_url":     originalURL,
+		"thumbnail_url": originalURL,
+		"original_url":  originalURL,
+		"processed":     false,
+	}
+
+	if autoCrop {
+		processed, err := imageproc.NewClient().Process(fileHeader.Filename, data, imageproc.DefaultOptions())
+		if err != nil {
+			// Auto-crop is best effort - keep the original if the service is unavailable
+			log.Printf("Image auto-crop failed, keeping original: %v", err)
+		} else if processed.Detected {
+			imageURL, err := store.Save(uid, baseName+"_processed.png", processed.Image)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store processed image"})
+				return
+			}
+			thumbnailURL, err := store.Save(uid, baseName+"_thumb.png", processed.Thumbnail)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store thumbnail"})
+				return
+			}
+
+			response["image_url"] = imageURL
+			response["thumbnail_url"] = thumbnailURL
+			response["processed"] = true
+		}
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/members"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/gin-gonic/gin"
+)
+
+// WhatIfRequest holds hypothetical spot prices. Omitted metals keep their
+// current price.
+type WhatIfRequest struct {
+	Gold      *float64 `json:"gold" binding:"omitempty,gte=0"`
+	Silver    *float64 `json:"silver" binding:"omitempty,gte=0"`
+	Platinum  *float64 `json:"platinum" binding:"omitempty,gte=0"`
+	Palladium *float64 `json:"palladium" binding:"omitempty,gte=0"`
+	Copper    *float64 `json:"copper" binding:"omitempty,gte=0"`
+	Nickel    *float64 `json:"nickel" binding:"omitempty,gte=0"`
+}
+
+type WhatIfResponse struct {
+	CurrentPrices  metals.SpotPrices `json:"current_prices"`
+	ScenarioPrices metals.SpotPrices `json:"scenario_prices"`
+	CurrentMelt    float64           `json:"current_melt_value"`
+	ScenarioMelt   float64           `json:"scenario_melt_value"`
+	Change         float64           `json:"change"`
+	ChangePercent  float64           `json:"change_percent"`
+}
+
+// PortfolioWhatIf compares a portfolio's melt value at current spot prices
+// with its melt value at hypothetical prices
+func PortfolioWhatIf(c *gin.Context) {
+	portfolioID := c.Param("id")
+
+	portfolio, ok := findPortfolio(c, portfolioID, members.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var req WhatIfRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := metals.CurrentCalculator()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spot prices"})
+		return
+	}
+
+	scenarioPrices := current.Prices
+	override := func(price *float64, dst *float64) {
+		if price != nil {
+			*dst = *price
+		}
+	}
+	override(req.Gold, &scenarioPrices.Gold)
+	override(req.Silver, &scenarioPrices.Silver)
+	override(req.Platinum, &scenarioPrices.Platinum)
+	override(req.Palladium, &scenarioPrices.Palladium)
+	override(req.Copper, &scenarioPrices.Copper)
+	override(req.Nickel, &scenarioPrices.Nickel)
+
+	currentMelt, err := valuation.PortfolioMeltValue(portfolio.ID, current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate melt value"})
+		return
+	}
+	scenarioMelt, err := valuation.PortfolioMeltValue(portfolio.ID, metals.NewCalculator(scenarioPrices))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate melt value"})
+		return
+	}
+
+	response := WhatIfResponse{
+		CurrentPrices:  current.Prices,
+		ScenarioPrices: scenarioPrices,
+		CurrentMelt:    currentMelt,
+		ScenarioMelt:   scenarioMelt,
+		Change:         scenarioMelt - currentMelt,
+	}
+	if currentMelt > 0 {
+		response.ChangePercent = (response.Change / currentMelt) * 100
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,137 @@
+package imageproc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+const defaultImageServiceURL = "http://localhost:8001"
+
+// Client talks to the Python image service
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Options controls the output of the auto-crop step
+type Options struct {
+	Size          int    // Edge length of the normalized square image in pixels
+	ThumbnailSize int    // Edge length of the thumbnail in pixels
+	Background    string // Background color as RRGGBB hex
+}
+
+// DefaultOptions returns the sizes used for gallery images
+func DefaultOptions() Options {
+	return Options{
+		Size:          1024,
+		ThumbnailSize: 256,
+		Background:    "ffffff",
+	}
+}
+
+// ProcessedImage is the decoded result of an auto-crop
+type ProcessedImage struct {
+	Detected    bool
+	ContentType string
+	Image       []byte
+	Thumbnail   []byte
+}
+
+type processResponse struct {
+	Success     bool   `json:"success"`
+	Detected    bool   `json:"detected"`
+	ContentType string `json:"content_type"`
+	Image       string `json:"image"`
+	Thumbnail   string `json:"thumbnail"`
+}
+
+// NewClient creates an image service client from IMAGE_SERVICE_URL
+func NewClient() *Client {
+	return &Client{
+		BaseURL:    config.String("IMAGE_SERVICE_URL", defaultImageServiceURL),
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// AutoCropEnabled reports whether uploads should be auto-cropped by default
+func AutoCropEnabled() bool {
+	return config.Bool("IMAGE_AUTO_CROP", false)
+}
+
+// Process sends an image to the service to detect the coin, crop it and
+// normalize the background. If no coin is detected, Detected is false and
+// no image data is returned.
+func (c *Client) Process(filename string, data []byte, opts Options) (*ProcessedImage, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize form: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("size", strconv.Itoa(opts.Size))
+	query.Set("thumbnail_size", strconv.Itoa(opts.ThumbnailSize))
+	query.Set("background", opts.Background)
+	endpoint := fmt.Sprintf("%s/process?%s", c.BaseURL, query.Encode())
+
+	req, err := http.NewRequest("POST", endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.HTTPClient.Do(req)
+	usage.RecordCall(usage.ServiceImageService, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("image service failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result processResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !result.Detected {
+		return &ProcessedImage{Detected: false}, nil
+	}
+
+	image, err := base64.StdEncoding.DecodeString(result.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode processed image: %w", err)
+	}
+	thumbnail, err := base64.StdEncoding.DecodeString(result.Thumbnail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+
+	return &ProcessedImage{
+		Detected:    true,
+		ContentType: result.ContentType,
+		Image:       image,
+		Thumbnail:   thumbnail,
+	}, nil
+}
@@ -0,0 +1,365 @@
+// Package imports reads coins from CSV spreadsheets and decides what to do
+// with rows whose cert number is already in the collection, so re-importing
+// an updated spreadsheet doesn't enter the same slabbed coins twice.
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// What to do with a row whose cert number is already in the collection
+const (
+	OnDuplicateSkip      = "skip"      // leave the existing coin alone
+	OnDuplicateUpdate    = "update"    // update the existing coin from the row
+	OnDuplicateDuplicate = "duplicate" // add the row as another coin anyway
+)
+
+// ValidStrategy reports whether strategy is a supported duplicate strategy
+func ValidStrategy(strategy string) bool {
+	return strategy == OnDuplicateSkip || strategy == OnDuplicateUpdate || strategy == OnDuplicateDuplicate
+}
+
+// MaxRows is the most data rows one import takes
+const MaxRows = 5000
+
+// Columns an import understands, in the order they're documented
+var Columns = []string{
+	"coin_type", "year", "mint_mark", "strike_type", "denomination", "face_value",
+	"pcgs_cert_number", "quantity", "purchase_price", "buyers_premium", "shipping_cost",
+	"sales_tax", "purchase_date", "current_value", "numismatic_value", "insured_value",
+	"metal_type", "metal_weight", "metal_purity", "notes", "face_currency",
+	"storage_location",
+}
+
+// columnAliases maps other common spreadsheet headers to a column
+var columnAliases = map[string]string{
+	"type":        "coin_type",
+	"coin":        "coin_type",
+	"mint":        "mint_mark",
+	"strike":      "strike_type",
+	"cert":        "pcgs_cert_number",
+	"cert_number": "pcgs_cert_number",
+	"pcgs_cert":   "pcgs_cert_number",
+	"qty":         "quantity",
+	"price":       "purchase_price",
+	"cost":        "purchase_price",
+	"date":        "purchase_date",
+	"purchased":   "purchase_date",
+	"value":       "current_value",
+	"metal":       "metal_type",
+	"weight":      "metal_weight",
+	"purity":      "metal_purity",
+	"currency":    "face_currency",
+	"location":    "storage_location",
+	"storage":     "storage_location",
+}
+
+// dateLayouts are the purchase date formats accepted, tried in order
+var dateLayouts = []string{"2006-01-02", time.RFC3339, "01/02/2006", "1/2/2006"}
+
+// ErrNoCoinType is returned for a CSV without a coin_type column
+var ErrNoCoinType = errors.New("the CSV needs a coin_type column")
+
+// Row is one data row of an import
+type Row struct {
+	Line  int         // line number in the file, counting the header as 1
+	Coin  models.Coin // the fields the row sets; the portfolio isn't set
+	Set   map[string]bool
+	Error string // why the row can't be imported, if it can't
+}
+
+// Has reports whether the row gave a value for column
+func (r Row) Has(column string) bool {
+	return r.Set[column]
+}
+
+// CertNumber is the row's cert number, normalized
+func (r Row) CertNumber() string {
+	return NormalizeCert(r.Coin.PCGSCertNumber)
+}
+
+// NormalizeCert makes cert numbers typed in different ways compare equal
+func NormalizeCert(cert string) string {
+	return strings.TrimPrefix(strings.TrimSpace(cert), "#")
+}
+
+// headerName is a header cell in the form of a column name, e.g.
+// "purchase_price" for "Purchase Price"
+func headerName(header string) string {
+	name := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(header, "#", "")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// normalizeHeader turns a header cell into a column name, reporting false
+// for headers that don't name one
+func normalizeHeader(header string) (string, bool) {
+	name := headerName(header)
+	if alias, ok := columnAliases[name]; ok {
+		return alias, true
+	}
+	for _, column := range Columns {
+		if column == name {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Parse reads a CSV with a header row. It returns every data row, with the
+// rows that can't be imported carrying an Error, and the headers that were
+// ignored because they don't name a column.
+func Parse(r io.Reader) ([]Row, []string, error) {
+	return ParseMapped(r, nil)
+}
+
+// ParseMapped is Parse with the columns chosen by mapping, from header to
+// column or "" to ignore it, e.g. after a preview. Headers mapping leaves
+// out are matched by name as usual. mapping must pass ValidateMapping.
+func ParseMapped(r io.Reader, mapping map[string]string) ([]Row, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("the CSV is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// Spreadsheet exports often start with a byte order mark
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns := make([]string, len(header))
+	ignored := []string{}
+	hasCoinType := false
+	for i, cell := range header {
+		column, ok := normalizeHeader(cell)
+		if field, chosen := mapping[strings.TrimSpace(cell)]; chosen {
+			column, ok = field, field != ""
+		}
+		if !ok {
+			if strings.TrimSpace(cell) != "" {
+				ignored = append(ignored, cell)
+			}
+			continue
+		}
+		columns[i] = column
+		hasCoinType = hasCoinType || column == "coin_type"
+	}
+	if !hasCoinType {
+		return nil, ignored, ErrNoCoinType
+	}
+
+	rows := []Row{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ignored, err
+		}
+		line, _ := reader.FieldPos(0)
+		if blank(record) {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, ignored, fmt.Errorf("an import takes at most %d rows", MaxRows)
+		}
+		rows = append(rows, parseRow(line, columns, record))
+	}
+	return rows, ignored, nil
+}
+
+func blank(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseRow(line int, columns []string, record []string) Row {
+	row := Row{Line: line, Set: map[string]bool{}}
+	var errs []string
+	for i, cell := range record {
+		if i >= len(columns) || columns[i] == "" {
+			continue
+		}
+		value := strings.TrimSpace(cell)
+		if value == "" {
+			continue
+		}
+		if err := setField(&row.Coin, columns[i], value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", columns[i], err))
+			continue
+		}
+		row.Set[columns[i]] = true
+	}
+	if !row.Has("coin_type") {
+		errs = append([]string{"coin_type is required"}, errs...)
+	}
+	row.Error = strings.Join(errs, "; ")
+	return row
+}
+
+func setField(coin *models.Coin, column, value string) error {
+	var err error
+	switch column {
+	case "coin_type":
+		coin.CoinType = value
+	case "year":
+		coin.Year, err = parseWhole(value)
+	case "mint_mark":
+		coin.MintMark = value
+	case "strike_type":
+		coin.StrikeType = value
+	case "denomination":
+		coin.Denomination = value
+	case "face_value":
+		coin.FaceValue, err = parseAmount(value)
+	case "pcgs_cert_number":
+		coin.PCGSCertNumber = NormalizeCert(value)
+	case "quantity":
+		coin.Quantity, err = parseWhole(value)
+		if err == nil && coin.Quantity <= 0 {
+			err = errors.New("must be at least 1")
+		}
+	case "purchase_price":
+		coin.PurchasePrice, err = parseAmount(value)
+	case "buyers_premium":
+		coin.BuyersPremium, err = parseAmount(value)
+	case "shipping_cost":
+		coin.ShippingCost, err = parseAmount(value)
+	case "sales_tax":
+		coin.SalesTax, err = parseAmount(value)
+	case "purchase_date":
+		var date time.Time
+		if date, err = parseDate(value); err == nil {
+			coin.PurchaseDate = &date
+		}
+	case "current_value":
+		coin.CurrentValue, err = parseAmount(value)
+	case "numismatic_value":
+		coin.NumismaticValue, err = parseAmount(value)
+	case "insured_value":
+		coin.InsuredValue, err = parseAmount(value)
+	case "metal_type":
+		coin.MetalType = strings.ToLower(value)
+	case "metal_weight":
+		coin.MetalWeight, err = parseAmount(value)
+	case "metal_purity":
+		coin.MetalPurity, err = parseAmount(strings.TrimSuffix(value, "%"))
+	case "notes":
+		coin.Notes = value
+	case "face_currency":
+		var ok bool
+		if coin.FaceCurrency, ok = metals.NormalizeCurrency(value); !ok {
+			err = errors.New("not a three-letter currency code")
+		}
+	case "storage_location":
+		coin.StorageLocation = value
+	}
+	return err
+}
+
+// parseAmount reads a non-negative number as spreadsheets format money,
+// e.g. "$1,234.50"
+func parseAmount(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimPrefix(value, "$"), ",", "")
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, errors.New("not a number")
+	}
+	if amount < 0 {
+		return 0, errors.New("can't be negative")
+	}
+	return amount, nil
+}
+
+func parseWhole(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("not a whole number")
+	}
+	return n, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, errors.New("use YYYY-MM-DD")
+}
+
+// Merge copies the fields a row sets onto an existing coin, for the update
+// strategy. Fields the row leaves blank keep their current values.
+func Merge(coin *models.Coin, row Row) {
+	for column := range row.Set {
+		switch column {
+		case "coin_type":
+			coin.CoinType = row.Coin.CoinType
+		case "year":
+			coin.Year = row.Coin.Year
+		case "mint_mark":
+			coin.MintMark = row.Coin.MintMark
+		case "strike_type":
+			coin.StrikeType = row.Coin.StrikeType
+		case "denomination":
+			coin.Denomination = row.Coin.Denomination
+		case "face_value":
+			coin.FaceValue = row.Coin.FaceValue
+		case "quantity":
+			coin.Quantity = row.Coin.Quantity
+		case "purchase_price":
+			coin.PurchasePrice = row.Coin.PurchasePrice
+		case "buyers_premium":
+			coin.BuyersPremium = row.Coin.BuyersPremium
+		case "shipping_cost":
+			coin.ShippingCost = row.Coin.ShippingCost
+		case "sales_tax":
+			coin.SalesTax = row.Coin.SalesTax
+		case "purchase_date":
+			coin.PurchaseDate = row.Coin.PurchaseDate
+		case "current_value":
+			coin.CurrentValue = row.Coin.CurrentValue
+		case "numismatic_value":
+			coin.NumismaticValue = row.Coin.NumismaticValue
+		case "insured_value":
+			coin.InsuredValue = row.Coin.InsuredValue
+		case "metal_type":
+			coin.MetalType = row.Coin.MetalType
+		case "metal_weight":
+			coin.MetalWeight = row.Coin.MetalWeight
+		case "metal_purity":
+			coin.MetalPurity = row.Coin.MetalPurity
+		case "notes":
+			coin.Notes = row.Coin.Notes
+		case "face_currency":
+			coin.FaceCurrency = row.Coin.FaceCurrency
+		case "storage_location":
+			coin.StorageLocation = row.Coin.StorageLocation
+		}
+	}
+}
+
+// MetalEdited reports whether a row sets any metal field, which makes the
+// composition manual
+func (r Row) MetalEdited() bool {
+	return r.Has("metal_type") || r.Has("metal_weight") || r.Has("metal_purity")
+}
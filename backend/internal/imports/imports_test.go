@@ -0,0 +1,67 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestParseMapsHeadersAndValues(t *testing.T) {
+	csv := "\ufeffCoin Type,Year,Cert #,Qty,Price,Date,Purity,Currency,Location,Grader\n" +
+		"Morgan Dollar,1881,#12345678,2,\"$1,250.50\",2023-04-01,90%,usd,Home safe,PCGS\n" +
+		",,,,,,,,,\n" +
+		"Peace Dollar,19x2,,0,,,,US$,,\n"
+
+	rows, ignored, err := Parse(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ignored) != 1 || ignored[0] != "Grader" {
+		t.Errorf("ignored = %v, want Grader", ignored)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want the blank one left out", len(rows))
+	}
+
+	first := rows[0]
+	if first.Error != "" || first.Line != 2 {
+		t.Fatalf("first row = line %d, error %q", first.Line, first.Error)
+	}
+	coin := first.Coin
+	if coin.CoinType != "Morgan Dollar" || coin.Year != 1881 || first.CertNumber() != "12345678" || coin.Quantity != 2 {
+		t.Errorf("coin = %+v", coin)
+	}
+	if coin.PurchasePrice != 1250.5 || coin.MetalPurity != 90 || coin.PurchaseDate == nil || coin.PurchaseDate.Year() != 2023 {
+		t.Errorf("amounts and date = %.2f, %.2f, %v", coin.PurchasePrice, coin.MetalPurity, coin.PurchaseDate)
+	}
+	if coin.FaceCurrency != "USD" || coin.StorageLocation != "Home safe" {
+		t.Errorf("currency and location = %q, %q", coin.FaceCurrency, coin.StorageLocation)
+	}
+	if first.Has("metal_weight") || !first.MetalEdited() {
+		t.Error("only the columns with values should be set")
+	}
+
+	if second := rows[1]; second.Line != 4 || !strings.Contains(second.Error, "year") || !strings.Contains(second.Error, "quantity") || !strings.Contains(second.Error, "face_currency") {
+		t.Errorf("second row = line %d, error %q", second.Line, second.Error)
+	}
+}
+
+func TestParseNeedsCoinType(t *testing.T) {
+	if _, _, err := Parse(strings.NewReader("year,notes\n1921,x\n")); err != ErrNoCoinType {
+		t.Errorf("err = %v, want ErrNoCoinType", err)
+	}
+}
+
+func TestMergeKeepsBlankFields(t *testing.T) {
+	coin := models.Coin{CoinType: "Morgan Dollar", Year: 1881, Notes: "old", PurchasePrice: 40}
+	rows, _, err := Parse(strings.NewReader("coin_type,notes,purchase_price\nMorgan Dollar,new,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Merge(&coin, rows[0])
+	if coin.Notes != "new" || coin.PurchasePrice != 40 || coin.Year != 1881 {
+		t.Errorf("merged coin = %+v", coin)
+	}
+}
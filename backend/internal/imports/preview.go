@@ -0,0 +1,256 @@
+package imports
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultSampleRows is how many data rows a preview shows unless asked
+const DefaultSampleRows = 5
+
+// MaxSampleRows caps the data rows a preview shows
+const MaxSampleRows = 20
+
+// minConfidence is the least confidence a suggestion needs to be made
+const minConfidence = 0.3
+
+// How a column's suggested field was found
+const (
+	MatchHeader  = "header"  // the header is the field's name
+	MatchAlias   = "alias"   // the header is a common name for the field
+	MatchSimilar = "similar" // the header shares words with the field's name
+	MatchContent = "content" // the column's values look like the field's
+)
+
+// PreviewColumn is one column of a CSV with the field it most likely holds.
+// SuggestedField is empty when no field fits well enough.
+type PreviewColumn struct {
+	Index          int      `json:"index"`
+	Header         string   `json:"header"`
+	Samples        []string `json:"samples"`
+	SuggestedField string   `json:"suggested_field"`
+	Confidence     float64  `json:"confidence"` // 0 to 1
+	Match          string   `json:"match,omitempty"`
+}
+
+// Preview is what a CSV holds, for choosing which column is which field
+// before importing it. Mapping is the suggestions as ParseMapped takes
+// them: header to field, "" to leave a column out.
+type Preview struct {
+	Columns        []PreviewColumn   `json:"columns"`
+	SampleRows     [][]string        `json:"sample_rows"`
+	RowCount       int               `json:"row_count"`
+	Fields         []string          `json:"fields"`
+	RequiredFields []string          `json:"required_fields"`
+	Mapping        map[string]string `json:"mapping"`
+}
+
+var (
+	wordSplit   = regexp.MustCompile(`[^a-z0-9]+`)
+	certPattern = regexp.MustCompile(`^#?\d{7,10}$`)
+	yearPattern = regexp.MustCompile(`^(1[5-9]|20)\d\d$`)
+	mintPattern = regexp.MustCompile(`^(?i)(P|D|S|O|W|CC|C|M)$`)
+	money       = regexp.MustCompile(`^\$\s?[\d,]+(\.\d{1,2})?$`)
+)
+
+// words splits a header into lowercase words, e.g. "Purchase Price (USD)"
+// into purchase, price and usd
+func words(header string) []string {
+	return strings.Fields(wordSplit.ReplaceAllString(strings.ToLower(header), " "))
+}
+
+// similarity is how many words two headers share, over the words of both
+func similarity(a, b []string) float64 {
+	shared := 0
+	for _, w := range a {
+		if slices.Contains(b, w) {
+			shared++
+		}
+	}
+	if shared == 0 {
+		return 0
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// suggestByHeader finds the field a header names, exactly, by a common
+// alias, or by sharing words with a field or alias
+func suggestByHeader(header string) (string, float64, string) {
+	name := headerName(header)
+	if slices.Contains(Columns, name) {
+		return name, 1, MatchHeader
+	}
+	if column, ok := columnAliases[name]; ok {
+		return column, 0.9, MatchAlias
+	}
+
+	headerWords := words(header)
+	best, bestScore := "", 0.0
+	score := func(name, column string) {
+		if s := similarity(headerWords, strings.Split(name, "_")); s > bestScore {
+			best, bestScore = column, s
+		}
+	}
+	for _, column := range Columns {
+		score(column, column)
+	}
+	for alias, column := range columnAliases {
+		score(alias, column)
+	}
+	return best, 0.8 * bestScore, MatchSimilar
+}
+
+// suggestByContent guesses a field from a column's values, for headers that
+// say nothing useful
+func suggestByContent(samples []string) (string, float64) {
+	values := []string{}
+	for _, sample := range samples {
+		if v := strings.TrimSpace(sample); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return "", 0
+	}
+	all := func(match func(string) bool) bool {
+		for _, v := range values {
+			if !match(v) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(certPattern.MatchString):
+		return "pcgs_cert_number", 0.6
+	case all(yearPattern.MatchString):
+		return "year", 0.6
+	case all(func(v string) bool { _, err := parseDate(v); return err == nil }):
+		return "purchase_date", 0.6
+	case all(mintPattern.MatchString):
+		return "mint_mark", 0.5
+	case all(money.MatchString):
+		return "purchase_price", 0.4
+	}
+	return "", 0
+}
+
+// PreviewCSV reads a CSV with a header row and suggests a field for each
+// column, with the first samples data rows. Each field is suggested for at
+// most one column, the one it fits best.
+func PreviewCSV(r io.Reader, samples int) (Preview, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return Preview{}, errors.New("the CSV is empty")
+	}
+	if err != nil {
+		return Preview{}, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	preview := Preview{
+		SampleRows:     [][]string{},
+		Fields:         Columns,
+		RequiredFields: []string{"coin_type"},
+		Mapping:        map[string]string{},
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Preview{}, err
+		}
+		if blank(record) {
+			continue
+		}
+		preview.RowCount++
+		if len(preview.SampleRows) < samples {
+			preview.SampleRows = append(preview.SampleRows, record)
+		}
+	}
+
+	type candidate struct {
+		column     int
+		field      string
+		confidence float64
+		match      string
+	}
+	candidates := []candidate{}
+	preview.Columns = make([]PreviewColumn, len(header))
+	for i, cell := range header {
+		column := PreviewColumn{Index: i, Header: strings.TrimSpace(cell), Samples: []string{}}
+		for _, row := range preview.SampleRows {
+			if i < len(row) {
+				column.Samples = append(column.Samples, row[i])
+			}
+		}
+		preview.Columns[i] = column
+
+		field, confidence, match := suggestByHeader(cell)
+		if confidence < minConfidence {
+			field, confidence = suggestByContent(column.Samples)
+			match = MatchContent
+		}
+		if confidence >= minConfidence {
+			candidates = append(candidates, candidate{i, field, confidence, match})
+		}
+	}
+
+	// Best fits first, so a field goes to the column it fits best
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].confidence > candidates[b].confidence
+	})
+	taken := map[string]bool{}
+	for _, c := range candidates {
+		if taken[c.field] {
+			continue
+		}
+		taken[c.field] = true
+		column := &preview.Columns[c.column]
+		column.SuggestedField = c.field
+		column.Confidence = math.Round(c.confidence*100) / 100
+		column.Match = c.match
+	}
+	for _, column := range preview.Columns {
+		if column.Header != "" {
+			preview.Mapping[column.Header] = column.SuggestedField
+		}
+	}
+	return preview, nil
+}
+
+// ValidateMapping checks a header-to-field mapping chosen for an import:
+// every field is one of Columns or "" to leave the column out, and no field
+// is mapped twice
+func ValidateMapping(mapping map[string]string) error {
+	mapped := map[string]string{}
+	for header, field := range mapping {
+		if field == "" {
+			continue
+		}
+		if !slices.Contains(Columns, field) {
+			return fmt.Errorf("mapping: %q is not a field an import takes", field)
+		}
+		if other, ok := mapped[field]; ok {
+			return fmt.Errorf("mapping: %q and %q are both mapped to %s", other, header, field)
+		}
+		mapped[field] = header
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package imports
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewSuggestsFields(t *testing.T) {
+	csv := "Coin Type,Yr,Cert #,Purchase Price (USD),Paid,Grader,\n" +
+		"Morgan Dollar,1881,12345678,\"$1,250.50\",$40,PCGS,S\n" +
+		"Peace Dollar,1922,87654321,$45,$45,NGC,P\n" +
+		",,,,,,\n" +
+		"Walking Liberty Half,1943,11223344,$20,$20,,D\n"
+
+	preview, err := PreviewCSV(strings.NewReader(csv), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.RowCount != 3 || len(preview.SampleRows) != 2 {
+		t.Errorf("rows = %d with %d samples, want 3 with 2", preview.RowCount, len(preview.SampleRows))
+	}
+
+	want := []struct {
+		field string
+		match string
+	}{
+		{"coin_type", MatchHeader},
+		{"year", MatchContent},
+		{"pcgs_cert_number", MatchAlias},
+		{"purchase_price", MatchSimilar},
+		{"", ""}, // purchase_price went to the better match
+		{"", ""},
+		{"mint_mark", MatchContent},
+	}
+	if len(preview.Columns) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(preview.Columns), len(want))
+	}
+	for i, w := range want {
+		column := preview.Columns[i]
+		if column.SuggestedField != w.field || column.Match != w.match {
+			t.Errorf("column %q = %q by %q, want %q by %q", column.Header, column.SuggestedField, column.Match, w.field, w.match)
+		}
+	}
+	if c := preview.Columns[0]; c.Confidence != 1 || len(c.Samples) != 2 || c.Samples[1] != "Peace Dollar" {
+		t.Errorf("coin type column = %+v", c)
+	}
+	if preview.Mapping["Paid"] != "" || preview.Mapping["Yr"] != "year" {
+		t.Errorf("mapping = %v", preview.Mapping)
+	}
+}
+
+func TestParseMappedUsesChosenColumns(t *testing.T) {
+	mapping := map[string]string{"Description": "coin_type", "Value": "", "Paid": "purchase_price"}
+	if err := ValidateMapping(mapping); err != nil {
+		t.Fatal(err)
+	}
+	rows, ignored, err := ParseMapped(strings.NewReader("Description,Value,Paid,Year\nMorgan Dollar,99,40,1881\n"), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coin := rows[0].Coin
+	if coin.CoinType != "Morgan Dollar" || coin.CurrentValue != 0 || coin.PurchasePrice != 40 || coin.Year != 1881 {
+		t.Errorf("coin = %+v", coin)
+	}
+	if len(ignored) != 1 || ignored[0] != "Value" {
+		t.Errorf("ignored = %v, want Value", ignored)
+	}
+
+	for _, bad := range []map[string]string{
+		{"A": "grade"},
+		{"A": "coin_type", "B": "coin_type"},
+	} {
+		if ValidateMapping(bad) == nil {
+			t.Errorf("ValidateMapping(%v) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package inflation
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+// Sources of the CPI series
+const (
+	SourceAnnual = "bls-annual" // built-in annual averages
+	SourceFRED   = "fred"       // monthly CPIAUCSL from FRED
+)
+
+const (
+	cacheDuration = 24 * time.Hour
+	fredURL       = "https://api.stlouisfed.org/fred/series/observations"
+)
+
+// Series is a consumer price index. Months without a monthly observation
+// use the annual average of their year.
+type Series struct {
+	Source  string
+	monthly map[string]float64 // keyed by "2006-01"
+	latest  string             // latest month with an observation
+}
+
+var (
+	firstYear, lastYear = yearRange()
+
+	mu        sync.Mutex
+	cached    *Series
+	fetchedAt time.Time
+)
+
+func yearRange() (first, last int) {
+	for year := range annualCPI {
+		if first == 0 || year < first {
+			first = year
+		}
+		if year > last {
+			last = year
+		}
+	}
+	return first, last
+}
+
+// Index returns the CPI level at t. Dates before the series use its first
+// value and dates after it its latest.
+func (s *Series) Index(t time.Time) float64 {
+	if value, ok := s.monthly[t.UTC().Format("2006-01")]; ok {
+		return value
+	}
+	if s.latest != "" && t.UTC().Format("2006-01") > s.latest {
+		return s.monthly[s.latest]
+	}
+	year := min(max(t.UTC().Year(), firstYear), lastYear)
+	return annualCPI[year]
+}
+
+// Adjust converts an amount in dollars of from into dollars of to
+func (s *Series) Adjust(value float64, from, to time.Time) float64 {
+	base := s.Index(from)
+	if base == 0 {
+		return value
+	}
+	return value * s.Index(to) / base
+}
+
+// LatestPeriod is the most recent period with CPI data, e.g. "2024" for the
+// annual series or "2025-08" for monthly data
+func (s *Series) LatestPeriod() string {
+	if s.latest != "" {
+		return s.latest
+	}
+	return strconv.Itoa(lastYear)
+}
+
+// Current returns the CPI series, refreshed daily from FRED when
+// FRED_API_KEY is set. Without a key, in mock mode or when FRED is
+// unreachable, the built-in annual series is used.
+func Current() *Series {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if cached != nil && time.Since(fetchedAt) < cacheDuration {
+		return cached
+	}
+
+	cached = &Series{Source: SourceAnnual}
+	if apiKey := config.String("FRED_API_KEY", ""); apiKey != "" && !config.MockMode() {
+		series, err := fetchFRED(apiKey)
+		usage.RecordCall(usage.ServiceFRED, err)
+		if err != nil {
+			log.Printf("⚠ Using built-in annual CPI (FRED fetch failed: %v)", err)
+		} else {
+			cached = series
+		}
+	}
+	fetchedAt = time.Now()
+	return cached
+}
+
+func fetchFRED(apiKey string) (*Series, error) {
+	query := url.Values{
+		"series_id": {"CPIAUCSL"},
+		"api_key":   {apiKey},
+		"file_type": {"json"},
+	}
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(fredURL + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("FRED returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Observations []struct {
+			Date  string `json:"date"`
+			Value string `json:"value"`
+		} `json:"observations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	series := &Series{Source: SourceFRED, monthly: make(map[string]float64)}
+	for _, obs := range result.Observations {
+		value, err := strconv.ParseFloat(obs.Value, 64) // missing values are "."
+		if err != nil || value <= 0 || len(obs.Date) < 7 {
+			continue
+		}
+		month := obs.Date[:7]
+		series.monthly[month] = value
+		if month > series.latest {
+			series.latest = month
+		}
+	}
+	if len(series.monthly) == 0 {
+		return nil, fmt.Errorf("no CPI observations in FRED response")
+	}
+	return series, nil
+}
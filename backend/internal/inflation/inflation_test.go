@@ -0,0 +1,38 @@
+package inflation
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAdjustUsesAnnualAverages(t *testing.T) {
+	s := &Series{Source: SourceAnnual}
+	from := time.Date(2000, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	got := s.Adjust(100, from, to)
+	want := 100 * 258.811 / 172.2
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("Adjust(100, 2000, 2020) = %.4f, want %.4f", got, want)
+	}
+
+	// Dates past the series use its latest year
+	if s.Index(time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)) != annualCPI[lastYear] {
+		t.Errorf("Index after the series should use %d", lastYear)
+	}
+}
+
+func TestIndexPrefersMonthlyData(t *testing.T) {
+	s := &Series{Source: SourceFRED, monthly: map[string]float64{"2024-01": 308.4, "2024-02": 310.3}, latest: "2024-02"}
+
+	if got := s.Index(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)); got != 308.4 {
+		t.Errorf("Index(2024-01) = %v, want 308.4", got)
+	}
+	if got := s.Index(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)); got != 310.3 {
+		t.Errorf("Index(2025-05) = %v, want latest monthly 310.3", got)
+	}
+	if got := s.Index(time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)); got != annualCPI[1990] {
+		t.Errorf("Index(1990-05) = %v, want annual %v", got, annualCPI[1990])
+	}
+}
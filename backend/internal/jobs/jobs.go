@@ -0,0 +1,252 @@
+// Package jobs runs the long operations users start, such as PCGS syncs,
+// backfills and imports, in the background. Starting one answers at once
+// with a job, which clients poll for progress; once it has finished, its
+// result holds what the operation reports. Jobs are stored, so any instance
+// can answer a poll, and one whose instance stopped before it finished is
+// marked failed by Sweep.
+package jobs
+
+import (
+	"errors"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// Job statuses
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Kinds of job
+const (
+	KindPCGSSync             = "pcgs_sync"
+	KindImport               = "import"
+	KindPriceHistoryBackfill = "price_history_backfill"
+	KindCompositionBackfill  = "composition_backfill"
+	KindStaleValueRefresh    = "stale_value_refresh"
+	KindStatement            = "statement"
+)
+
+const (
+	// A running job touches its row this often, and one not heard from
+	// for staleAfter died with its instance
+	heartbeatInterval = time.Minute
+	staleAfter        = 5 * time.Minute
+	// Progress is written at most this often
+	progressInterval = time.Second
+	defaultRetention = 7 * 24 * time.Hour
+)
+
+// ErrRunning is returned by Start while the user already has a job of the
+// same kind queued or running
+var ErrRunning = errors.New("a job of this kind is already running")
+
+// Func does a job's work, reporting how far it has got to p, and returns
+// what the job's result should say. A result returned with an error is
+// kept too, e.g. what was done before it failed.
+type Func func(p *Progress) (any, error)
+
+// Progress reports how far a running job has got. A nil Progress ignores
+// everything, so work can also be run without a job.
+type Progress struct {
+	jobID   uuid.UUID
+	mu      sync.Mutex
+	done    int
+	total   int
+	savedAt time.Time
+}
+
+// SetTotal says how many items the job has to get through
+func (p *Progress) SetTotal(total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.total = total
+	p.mu.Unlock()
+	p.save(true)
+}
+
+// Set records done of total items done
+func (p *Progress) Set(done, total int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.done, p.total = done, total
+	p.mu.Unlock()
+	p.save(false)
+}
+
+// Add counts n more items done
+func (p *Progress) Add(n int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	p.done += n
+	p.mu.Unlock()
+	p.save(false)
+}
+
+func (p *Progress) counts() (done, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done, p.total
+}
+
+// save writes the progress to the job, unless it was written less than
+// progressInterval ago and force isn't set
+func (p *Progress) save(force bool) {
+	p.mu.Lock()
+	if !force && time.Since(p.savedAt) < progressInterval {
+		p.mu.Unlock()
+		return
+	}
+	p.savedAt = time.Now()
+	done, total := p.done, p.total
+	p.mu.Unlock()
+
+	if err := database.GetDB().Model(&models.Job{}).Where("id = ?", p.jobID).
+		Updates(map[string]any{"done": done, "total": total}).Error; err != nil {
+		log.Printf("Failed to save progress of job %s: %v", p.jobID, err)
+	}
+}
+
+// startMu keeps two requests on this instance from starting the same job
+var startMu sync.Mutex
+
+// Start queues run as a job of kind for the user and returns it at once.
+// While the user has a job of that kind unfinished, it returns that job
+// with ErrRunning instead.
+func Start(userID uuid.UUID, kind string, run Func) (models.Job, error) {
+	startMu.Lock()
+	defer startMu.Unlock()
+
+	db := database.GetDB()
+	var running models.Job
+	if err := db.Where("user_id = ? AND kind = ? AND status IN ?", userID, kind, []string{StatusQueued, StatusRunning}).
+		Limit(1).Find(&running).Error; err != nil {
+		return running, err
+	}
+	if running.ID != uuid.Nil {
+		return running, ErrRunning
+	}
+
+	job := models.Job{UserID: userID, Kind: kind, Status: StatusQueued}
+	if err := db.Create(&job).Error; err != nil {
+		return job, err
+	}
+	go execute(job, run)
+	return job, nil
+}
+
+// execute runs a job, keeping its heartbeat going, and records how it ended
+func execute(job models.Job, run Func) {
+	db := database.GetDB()
+	started := time.Now()
+	job.Status, job.StartedAt = StatusRunning, &started
+	if err := db.Model(&job).Updates(map[string]any{"status": StatusRunning, "started_at": started}).Error; err != nil {
+		log.Printf("Failed to start job %s: %v", job.ID, err)
+	}
+
+	stop := make(chan struct{})
+	go heartbeat(job.ID, stop)
+	progress := &Progress{jobID: job.ID}
+	result, err := safeRun(run, progress)
+	close(stop)
+
+	finished := time.Now()
+	job.Done, job.Total = progress.counts()
+	job.Result, job.FinishedAt = result, &finished
+	job.Status = StatusSucceeded
+	if err != nil {
+		job.Status, job.Error = StatusFailed, err.Error()
+		log.Printf("Job %s (%s) failed: %v", job.ID, job.Kind, err)
+	}
+	if err := db.Model(&job).Select("status", "done", "total", "result", "error", "finished_at").Updates(&job).Error; err != nil {
+		log.Printf("Failed to record the end of job %s: %v", job.ID, err)
+	}
+	events.Publish(events.JobFinished{Job: job})
+}
+
+// safeRun runs a job's work, turning a panic into a failure rather than
+// taking the server down with it
+func safeRun(run Func, p *Progress) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v\n%s", p.jobID, r, debug.Stack())
+			err = errors.New("the job stopped unexpectedly")
+		}
+	}()
+	return run(p)
+}
+
+func heartbeat(jobID uuid.UUID, stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := database.GetDB().Model(&models.Job{}).Where("id = ?", jobID).
+				Update("updated_at", time.Now()).Error; err != nil {
+				log.Printf("Failed to touch job %s: %v", jobID, err)
+			}
+		}
+	}
+}
+
+// Get returns one of the user's jobs
+func Get(userID, jobID uuid.UUID) (models.Job, error) {
+	var job models.Job
+	err := database.GetDB().Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error
+	return job, err
+}
+
+// retention is how long finished jobs are kept (JOB_RETENTION)
+func retention() time.Duration {
+	return config.Duration("JOB_RETENTION", defaultRetention)
+}
+
+// Sweep fails the jobs whose instance stopped before they finished, and
+// deletes those that finished longer than JOB_RETENTION ago
+func Sweep(now time.Time) error {
+	db := database.GetDB()
+	var stale []models.Job
+	if err := db.Where("status IN ? AND updated_at < ?", []string{StatusQueued, StatusRunning}, now.Add(-staleAfter)).
+		Find(&stale).Error; err != nil {
+		return err
+	}
+	for _, job := range stale {
+		job.Status, job.Error, job.FinishedAt = StatusFailed, "interrupted: the server running it stopped; start it again", &now
+		if err := db.Model(&job).Select("status", "error", "finished_at").Updates(&job).Error; err != nil {
+			return err
+		}
+		events.Publish(events.JobFinished{Job: job})
+	}
+	if len(stale) > 0 {
+		log.Printf("Marked %d interrupted jobs failed", len(stale))
+	}
+
+	result := db.Where("finished_at < ?", now.Add(-retention())).Delete(&models.Job{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d finished jobs", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// Package jsonrewrite edits the values in a JSON document by the key they're
+// under, token by token, so a response can be adjusted after a handler has
+// written it without reordering its keys or touching anything else.
+package jsonrewrite
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// Rewriter says how values under each key change. Either function may be
+// nil; one that returns false leaves the value as it was written. Values in
+// arrays are under no key and are never changed.
+type Rewriter struct {
+	Number func(key string, value float64) (float64, bool)
+	String func(key, value string) (string, bool)
+}
+
+// Rewrite applies r to every number and string value in body, wherever
+// they're nested
+func Rewrite(body []byte, r Rewriter) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	type frame struct {
+		object    bool
+		count     int
+		expectKey bool
+		key       string
+	}
+	var (
+		out   bytes.Buffer
+		stack []*frame
+	)
+	top := func() *frame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	// Values in arrays and keys in objects are separated by commas; values
+	// in objects follow their key's colon
+	separate := func() {
+		if f := top(); f != nil && f.count > 0 && (!f.object || f.expectKey) {
+			out.WriteByte(',')
+		}
+	}
+	valueDone := func() {
+		if f := top(); f != nil {
+			f.count++
+			f.expectKey = f.object
+		}
+	}
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch v := token.(type) {
+		case json.Delim:
+			if v == '{' || v == '[' {
+				separate()
+				out.WriteByte(byte(v))
+				stack = append(stack, &frame{object: v == '{', expectKey: v == '{'})
+				continue
+			}
+			out.WriteByte(byte(v))
+			stack = stack[:len(stack)-1]
+			valueDone()
+		case string:
+			separate()
+			f := top()
+			if f != nil && f.object && f.expectKey {
+				encoded, _ := json.Marshal(v)
+				out.Write(encoded)
+				out.WriteByte(':')
+				f.key, f.expectKey = v, false
+				continue
+			}
+			if f != nil && f.object && r.String != nil {
+				if changed, ok := r.String(f.key, v); ok {
+					v = changed
+				}
+			}
+			encoded, _ := json.Marshal(v)
+			out.Write(encoded)
+			valueDone()
+		case json.Number:
+			separate()
+			text := v.String()
+			if f := top(); f != nil && f.object && r.Number != nil {
+				if value, err := v.Float64(); err == nil {
+					if changed, ok := r.Number(f.key, value); ok {
+						text = strconv.FormatFloat(changed, 'f', -1, 64)
+					}
+				}
+			}
+			out.WriteString(text)
+			valueDone()
+		case bool:
+			separate()
+			out.WriteString(strconv.FormatBool(v))
+			valueDone()
+		case nil:
+			separate()
+			out.WriteString("null")
+			valueDone()
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,175 @@
+package listings
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Marketplaces drafts can be formatted for
+const (
+	MarketplaceEbay             = "ebay"
+	MarketplaceGreatCollections = "greatcollections"
+)
+
+// titleLimits are each marketplace's maximum title length
+var titleLimits = map[string]int{
+	MarketplaceEbay:             80,
+	MarketplaceGreatCollections: 100,
+}
+
+var strikeLabels = map[string]string{
+	metals.StrikeProof:              "Proof",
+	metals.StrikeSMS:                "SMS",
+	metals.StrikeSilverProof:        "Silver Proof",
+	metals.StrikeSilverUncirculated: "Silver Uncirculated",
+}
+
+var preciousMetals = map[string]bool{"gold": true, "silver": true, "platinum": true, "palladium": true}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// Input is everything a draft is built from
+type Input struct {
+	Coin        models.Coin
+	Grade       string // e.g. "MS65", from PCGS when the coin is certified
+	Designation string // e.g. "DCAM"
+	Images      []string
+}
+
+// Spec is one line of the item specifics
+type Spec struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Draft is a listing ready to paste into a marketplace
+type Draft struct {
+	Marketplace string   `json:"marketplace"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Specs       []Spec   `json:"specs"`
+	Images      []string `json:"images"`
+	CertURL     string   `json:"cert_url,omitempty"`
+}
+
+// ValidMarketplace reports whether marketplace has a template
+func ValidMarketplace(marketplace string) bool {
+	_, ok := titleLimits[marketplace]
+	return ok
+}
+
+// CertURL links to the PCGS cert verification page
+func CertURL(certNumber string) string {
+	if certNumber == "" {
+		return ""
+	}
+	return "https://www.pcgs.com/cert/" + certNumber
+}
+
+// Title formats a listing title like "1921-S Peace Dollar PCGS MS63",
+// trimmed at a word boundary to the marketplace's limit
+func Title(in Input, marketplace string) string {
+	coin := in.Coin
+	var parts []string
+
+	if coin.Year > 0 && !strings.Contains(coin.CoinType, strconv.Itoa(coin.Year)) {
+		date := strconv.Itoa(coin.Year)
+		if coin.MintMark != "" {
+			date += "-" + coin.MintMark
+		}
+		parts = append(parts, date)
+	}
+	parts = append(parts, coin.CoinType)
+	if label, ok := strikeLabels[coin.StrikeType]; ok && !strings.Contains(strings.ToLower(coin.CoinType), strings.ToLower(label)) {
+		parts = append(parts, label)
+	}
+	if in.Grade != "" {
+		grade := in.Grade
+		if in.Designation != "" {
+			grade += " " + in.Designation
+		}
+		if coin.PCGSCertNumber != "" {
+			grade = "PCGS " + grade
+		}
+		parts = append(parts, grade)
+	}
+
+	title := strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	limit := titleLimits[marketplace]
+	if limit > 0 && len(title) > limit {
+		cut := strings.LastIndex(title[:limit+1], " ")
+		if cut <= 0 {
+			cut = limit
+		}
+		title = title[:cut]
+	}
+	return title
+}
+
+// Specs lists the item specifics known for the coin
+func Specs(in Input) []Spec {
+	coin := in.Coin
+	specs := []Spec{}
+	add := func(label, value string) {
+		if value != "" {
+			specs = append(specs, Spec{Label: label, Value: value})
+		}
+	}
+
+	add("Coin", coin.CoinType)
+	if coin.Year > 0 {
+		add("Year", strconv.Itoa(coin.Year))
+	}
+	add("Mint Mark", coin.MintMark)
+	add("Denomination", coin.Denomination)
+	add("Strike Type", strikeLabels[coin.StrikeType])
+	if in.Grade != "" {
+		add("Grade", strings.TrimSpace(in.Grade+" "+in.Designation))
+	}
+	if coin.PCGSCertNumber != "" {
+		add("Certification", "PCGS")
+		add("Certification Number", coin.PCGSCertNumber)
+	}
+	if coin.MetalType != "" && coin.MetalPurity > 0 {
+		add("Composition", fmt.Sprintf("%s%% %s", strconv.FormatFloat(coin.MetalPurity, 'f', -1, 64), coin.MetalType))
+	}
+	if coin.MetalWeight > 0 && preciousMetals[coin.MetalType] {
+		add("Precious Metal Content", fmt.Sprintf("%.4f troy oz", coin.MetalWeight*coin.MetalPurity/100))
+	}
+	return specs
+}
+
+// Build drafts a listing for marketplace from the coin's stored data
+func Build(in Input, marketplace string) (Draft, error) {
+	if !ValidMarketplace(marketplace) {
+		return Draft{}, fmt.Errorf("unknown marketplace %q", marketplace)
+	}
+
+	draft := Draft{
+		Marketplace: marketplace,
+		Title:       Title(in, marketplace),
+		Specs:       Specs(in),
+		Images:      in.Images,
+		CertURL:     CertURL(in.Coin.PCGSCertNumber),
+	}
+	if draft.Images == nil {
+		draft.Images = []string{}
+	}
+
+	var description bytes.Buffer
+	if err := templates.ExecuteTemplate(&description, marketplace+".tmpl", draft); err != nil {
+		return Draft{}, err
+	}
+	draft.Description = strings.TrimSpace(description.String())
+	return draft, nil
+}
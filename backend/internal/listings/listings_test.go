@@ -0,0 +1,55 @@
+package listings
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		in   Input
+		want string
+	}{
+		{Input{Coin: models.Coin{CoinType: "Peace Dollar", Year: 1921, MintMark: "S", PCGSCertNumber: "123"}, Grade: "MS63"}, "1921-S Peace Dollar PCGS MS63"},
+		{Input{Coin: models.Coin{CoinType: "Kennedy Half Dollar", Year: 1992, MintMark: "S", StrikeType: "silver_proof"}}, "1992-S Kennedy Half Dollar Silver Proof"},
+		{Input{Coin: models.Coin{CoinType: "1986 American Silver Eagle", Year: 1986, PCGSCertNumber: "1"}, Grade: "PR70", Designation: "DCAM"}, "1986 American Silver Eagle PCGS PR70 DCAM"},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.in, MarketplaceEbay); got != tt.want {
+			t.Errorf("Title = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestTitleTrimsToLimit(t *testing.T) {
+	in := Input{Coin: models.Coin{CoinType: strings.Repeat("Very Long Coin Name ", 6), Year: 1900}}
+	got := Title(in, MarketplaceEbay)
+	if len(got) > titleLimits[MarketplaceEbay] || strings.HasSuffix(got, " ") {
+		t.Errorf("Title = %q (%d chars), want at most %d without trailing space", got, len(got), titleLimits[MarketplaceEbay])
+	}
+}
+
+func TestBuild(t *testing.T) {
+	in := Input{
+		Coin:   models.Coin{CoinType: "Morgan Dollar", Year: 1921, PCGSCertNumber: "10000001", MetalType: "silver", MetalWeight: 0.8594, MetalPurity: 90},
+		Grade:  "MS65",
+		Images: []string{"https://example.com/front.jpg"},
+	}
+	for _, marketplace := range []string{MarketplaceEbay, MarketplaceGreatCollections} {
+		draft, err := Build(in, marketplace)
+		if err != nil {
+			t.Fatalf("Build(%s): %v", marketplace, err)
+		}
+		for _, want := range []string{"1921 Morgan Dollar PCGS MS65", "https://www.pcgs.com/cert/10000001", "Composition: 90% silver", "0.7735 troy oz"} {
+			if !strings.Contains(draft.Description, want) {
+				t.Errorf("%s description missing %q:\n%s", marketplace, want, draft.Description)
+			}
+		}
+	}
+
+	if _, err := Build(in, "etsy"); err == nil {
+		t.Error("Build accepted an unknown marketplace")
+	}
+}
@@ -0,0 +1,197 @@
+// Package loginguard slows down password guessing on sign-in and
+// registration. Failures are counted per IP address and per email address;
+// once a key has failed too often it is locked out for a while, twice as long
+// for each further failure. The counts live in the database so a restart or
+// a second instance doesn't reset them, and every failure is kept as an
+// audit record.
+package loginguard
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Attempt is a failed sign-in or registration
+type Attempt struct {
+	Action    string // login or register
+	Email     string
+	IPAddress string
+	UserAgent string
+	Status    int
+}
+
+// IPKey is the throttle key for an IP address
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
+
+// EmailKey is the throttle key for an email address
+func EmailKey(email string) string {
+	return "email:" + normalizeEmail(email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// maxAttempts is how many failures a key has before it is locked out.
+// An IP address gets more than an email address, since an office or a
+// phone network puts many people behind one.
+func maxAttempts(key string) int {
+	if strings.HasPrefix(key, "ip:") {
+		return int(config.Int64("LOGIN_MAX_ATTEMPTS_PER_IP", 20))
+	}
+	return int(config.Int64("LOGIN_MAX_ATTEMPTS", 5))
+}
+
+// lockout is the first lockout's length (LOGIN_LOCKOUT, default 1 minute)
+func lockout() time.Duration {
+	return config.Duration("LOGIN_LOCKOUT", time.Minute)
+}
+
+// maxLockout caps a lockout's length (LOGIN_MAX_LOCKOUT, default 1 hour)
+func maxLockout() time.Duration {
+	return config.Duration("LOGIN_MAX_LOCKOUT", time.Hour)
+}
+
+// failureWindow is how long a key must go without failing for its count to
+// start again (LOGIN_FAILURE_WINDOW, default 24 hours)
+func failureWindow() time.Duration {
+	return config.Duration("LOGIN_FAILURE_WINDOW", 24*time.Hour)
+}
+
+// auditRetention is how long failed attempts are kept (LOGIN_AUDIT_RETENTION,
+// default 90 days)
+func auditRetention() time.Duration {
+	return config.Duration("LOGIN_AUDIT_RETENTION", 90*24*time.Hour)
+}
+
+// Backoff is how long a key is locked out after its failures'th failure:
+// nothing until it reaches max, then base, doubling with each failure after
+// that up to limit
+func Backoff(failures, max int, base, limit time.Duration) time.Duration {
+	if failures < max || base <= 0 {
+		return 0
+	}
+	d := base
+	for i := max; i < failures; i++ {
+		d *= 2
+		if d >= limit {
+			return limit
+		}
+	}
+	return min(d, limit)
+}
+
+// Locked returns how much longer the most locked of keys stays locked out,
+// or zero if none of them is
+func Locked(now time.Time, keys ...string) (time.Duration, error) {
+	var throttles []models.LoginThrottle
+	if err := database.GetDB().Where("key IN ? AND locked_until > ?", keys, now).Find(&throttles).Error; err != nil {
+		return 0, err
+	}
+	var wait time.Duration
+	for _, t := range throttles {
+		wait = max(wait, t.LockedUntil.Sub(now))
+	}
+	return wait, nil
+}
+
+// Fail counts a failed attempt against its IP and email addresses, locking
+// either out if it has now failed too often, and records it for the audit
+// log
+func Fail(attempt Attempt, now time.Time) error {
+	attempt.Email = normalizeEmail(attempt.Email)
+	keys := []string{IPKey(attempt.IPAddress)}
+	if attempt.Email != "" {
+		keys = append(keys, EmailKey(attempt.Email))
+	}
+
+	locked := false
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		for _, key := range keys {
+			until, err := fail(tx, key, now)
+			if err != nil {
+				return err
+			}
+			if until != nil {
+				locked = true
+				log.Printf("Login guard: %s locked out until %s", key, until.Format(time.RFC3339))
+			}
+		}
+		return tx.Create(&models.LoginFailure{
+			Action:    attempt.Action,
+			Email:     attempt.Email,
+			IPAddress: attempt.IPAddress,
+			UserAgent: attempt.UserAgent,
+			Status:    attempt.Status,
+			Locked:    locked,
+			CreatedAt: now,
+		}).Error
+	})
+	log.Printf("Login guard: failed %s for %q from %s (status %d)", attempt.Action, attempt.Email, attempt.IPAddress, attempt.Status)
+	return err
+}
+
+// fail counts one failure against key and returns when it is now locked
+// out until, if it is
+func fail(tx *gorm.DB, key string, now time.Time) (*time.Time, error) {
+	// Concurrent failures for a new key would otherwise both insert it
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginThrottle{Key: key, LastFailureAt: now}).Error; err != nil {
+		return nil, err
+	}
+	var throttle models.LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&throttle, "key = ?", key).Error; err != nil {
+		return nil, err
+	}
+
+	if now.Sub(throttle.LastFailureAt) > failureWindow() {
+		throttle.Failures = 0
+	}
+	throttle.Failures++
+	throttle.LastFailureAt = now
+	throttle.LockedUntil = nil
+	if d := Backoff(throttle.Failures, maxAttempts(key), lockout(), maxLockout()); d > 0 {
+		until := now.Add(d)
+		throttle.LockedUntil = &until
+	}
+	if err := tx.Save(&throttle).Error; err != nil {
+		return nil, err
+	}
+	return throttle.LockedUntil, nil
+}
+
+// Succeed clears an email address's failures once it signs in. The IP
+// address's failures stand, so one good account doesn't cover for guessing
+// at others.
+func Succeed(email string) error {
+	if normalizeEmail(email) == "" {
+		return nil
+	}
+	return database.GetDB().Delete(&models.LoginThrottle{}, "key = ?", EmailKey(email)).Error
+}
+
+// Purge deletes throttles that have gone unused for the failure window and
+// failed attempts older than the audit retention
+func Purge(now time.Time) error {
+	db := database.GetDB()
+	if err := db.Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-failureWindow()), now).
+		Delete(&models.LoginThrottle{}).Error; err != nil {
+		return err
+	}
+	result := db.Where("created_at < ?", now.Add(-auditRetention())).Delete(&models.LoginFailure{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d failed login attempts", result.RowsAffected)
+	}
+	return nil
+}
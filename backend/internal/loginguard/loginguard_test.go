@@ -0,0 +1,31 @@
+package loginguard
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{4, 0},
+		{5, time.Minute},
+		{6, 2 * time.Minute},
+		{8, 8 * time.Minute},
+		{11, time.Hour}, // 64 minutes, capped
+		{1000, time.Hour},
+	} {
+		if got := Backoff(tt.failures, 5, time.Minute, time.Hour); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestEmailKeyIgnoresCaseAndSpace(t *testing.T) {
+	if a, b := EmailKey(" Ann@Example.com"), EmailKey("ann@example.com"); a != b {
+		t.Errorf("EmailKey gave %q and %q for the same address", a, b)
+	}
+}
@@ -0,0 +1,160 @@
+package lots
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+// How a lot's totals are split across its coins
+const (
+	AllocationMeltWeight = "melt_weight" // by fine metal weight
+	AllocationManual     = "manual"      // by hammer price amounts entered per coin
+)
+
+// ValidAllocation reports whether method is a supported allocation method
+func ValidAllocation(method string) bool {
+	return method == AllocationMeltWeight || method == AllocationManual
+}
+
+// ErrNoMetalContent is returned when a lot can't be split by melt weight
+// because none of its coins have a known metal content
+var ErrNoMetalContent = errors.New("no coin in the lot has a known metal content, allocate it manually")
+
+// Reconciliation compares a lot's invoice totals with what its coins carry
+type Reconciliation struct {
+	LotTotal   float64 `json:"lot_total"` // total price plus fees
+	Allocated  float64 `json:"allocated"` // all-in cost of the lot's coins
+	Difference float64 `json:"difference"`
+	Balanced   bool    `json:"balanced"`
+}
+
+// LotTotal is what the whole lot cost: its hammer price plus fees
+func LotTotal(lot models.Lot) float64 {
+	return lot.TotalPrice + lot.BuyersPremium + lot.ShippingCost + lot.SalesTax
+}
+
+// Reconcile compares the lot's totals with its coins' all-in costs, to the cent
+func Reconcile(lot models.Lot, coins []models.Coin) Reconciliation {
+	r := Reconciliation{LotTotal: LotTotal(lot)}
+	for _, coin := range coins {
+		r.Allocated += valuation.AllInCost(coin)
+	}
+	r.Difference = math.Round((r.LotTotal-r.Allocated)*100) / 100
+	r.Balanced = r.Difference == 0
+	return r
+}
+
+// FineWeight is a coin's pure metal content in troy ounces, across its quantity
+func FineWeight(coin models.Coin) float64 {
+	if coin.MetalWeight <= 0 || coin.MetalPurity <= 0 {
+		return 0
+	}
+	return coin.MetalWeight * coin.MetalPurity / 100 * float64(coin.Quantity)
+}
+
+// SplitAmount divides total across weights in whole cents. Leftover cents go
+// to the largest remainders so the parts add up to total exactly.
+func SplitAmount(total float64, weights []float64) []float64 {
+	parts := make([]float64, len(weights))
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 || total == 0 {
+		return parts
+	}
+
+	cents := int64(math.Round(total * 100))
+	type share struct {
+		index     int
+		remainder float64
+	}
+	shares := make([]share, len(weights))
+	var assigned int64
+	for i, w := range weights {
+		exact := float64(cents) * w / sum
+		whole := int64(math.Floor(exact))
+		parts[i] = float64(whole)
+		assigned += whole
+		shares[i] = share{i, exact - float64(whole)}
+	}
+
+	sort.SliceStable(shares, func(a, b int) bool { return shares[a].remainder > shares[b].remainder })
+	for i := int64(0); i < cents-assigned; i++ {
+		parts[shares[i%int64(len(shares))].index]++
+	}
+
+	for i := range parts {
+		parts[i] /= 100
+	}
+	return parts
+}
+
+// Allocate splits the lot's hammer price and fees across coins in place,
+// setting each coin's purchase price (per coin), fees and purchase date.
+// Manual allocation takes each coin's share of the hammer price from amounts,
+// which must add up to the lot's total price; fees follow the same shares.
+func Allocate(lot models.Lot, coins []models.Coin, amounts map[uuid.UUID]float64) error {
+	if len(coins) == 0 {
+		return nil
+	}
+
+	weights := make([]float64, len(coins))
+	switch lot.Allocation {
+	case AllocationManual:
+		var sum float64
+		for i, coin := range coins {
+			amount, ok := amounts[coin.ID]
+			if !ok {
+				return fmt.Errorf("no amount given for coin %s", coin.ID)
+			}
+			if amount < 0 {
+				return fmt.Errorf("amount for coin %s can't be negative", coin.ID)
+			}
+			weights[i] = amount
+			sum += amount
+		}
+		if math.Round(sum*100) != math.Round(lot.TotalPrice*100) {
+			return fmt.Errorf("amounts add up to %.2f, not the lot's total price of %.2f", sum, lot.TotalPrice)
+		}
+	default:
+		var sum float64
+		for i, coin := range coins {
+			weights[i] = FineWeight(coin)
+			sum += weights[i]
+		}
+		if sum == 0 {
+			return ErrNoMetalContent
+		}
+	}
+
+	hammer := SplitAmount(lot.TotalPrice, weights)
+	premium := SplitAmount(lot.BuyersPremium, weights)
+	shipping := SplitAmount(lot.ShippingCost, weights)
+	tax := SplitAmount(lot.SalesTax, weights)
+
+	for i := range coins {
+		coin := &coins[i]
+		quantity := coin.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+		lotID := lot.ID
+		coin.LotID = &lotID
+		coin.PurchasePrice = hammer[i] / float64(quantity)
+		coin.BuyersPremium = premium[i]
+		coin.ShippingCost = shipping[i]
+		coin.SalesTax = tax[i]
+		if lot.PurchaseDate != nil {
+			purchaseDate := *lot.PurchaseDate
+			coin.PurchaseDate = &purchaseDate
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package lots
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestSplitAddsUpToTheCent(t *testing.T) {
+	parts := SplitAmount(100, []float64{1, 1, 1})
+
+	var sum float64
+	for _, p := range parts {
+		sum += p
+	}
+	if parts[0] != 33.34 || parts[1] != 33.33 || parts[2] != 33.33 || int(sum*100+0.5) != 10000 {
+		t.Errorf("SplitAmount(100, 1:1:1) = %v (sum %.2f)", parts, sum)
+	}
+}
+
+func TestAllocateByMeltWeight(t *testing.T) {
+	lot := models.Lot{ID: uuid.New(), TotalPrice: 300, ShippingCost: 15, Allocation: AllocationMeltWeight}
+	coins := []models.Coin{
+		{ID: uuid.New(), Quantity: 2, MetalWeight: 1, MetalPurity: 100},   // 2 oz
+		{ID: uuid.New(), Quantity: 1, MetalWeight: 1.25, MetalPurity: 80}, // 1 oz
+	}
+
+	if err := Allocate(lot, coins, nil); err != nil {
+		t.Fatal(err)
+	}
+	if coins[0].PurchasePrice != 100 || coins[0].ShippingCost != 10 {
+		t.Errorf("first coin = %.2f each + %.2f shipping, want 100 + 10", coins[0].PurchasePrice, coins[0].ShippingCost)
+	}
+	if coins[1].LotID == nil || *coins[1].LotID != lot.ID {
+		t.Error("coins should be linked to the lot")
+	}
+	if r := Reconcile(lot, coins); !r.Balanced {
+		t.Errorf("reconciliation = %+v, want balanced", r)
+	}
+}
+
+func TestAllocateManualMustMatchTotal(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	lot := models.Lot{TotalPrice: 500, SalesTax: 40, Allocation: AllocationManual}
+	coins := []models.Coin{{ID: a, Quantity: 1}, {ID: b, Quantity: 1}}
+
+	if err := Allocate(lot, coins, map[uuid.UUID]float64{a: 300, b: 150}); err == nil {
+		t.Error("amounts short of the total price should be rejected")
+	}
+	if err := Allocate(lot, coins, map[uuid.UUID]float64{a: 400, b: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if coins[0].SalesTax != 32 || coins[1].SalesTax != 8 {
+		t.Errorf("sales tax = %.2f / %.2f, want 32 / 8", coins[0].SalesTax, coins[1].SalesTax)
+	}
+}
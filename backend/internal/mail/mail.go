@@ -0,0 +1,136 @@
+// Package mail sends email through a pluggable Mailer. SMTP and SendGrid
+// are built in and picked with MAIL_PROVIDER; self-hosters can wire any
+// other provider with SetMailer.
+package mail
+
+import (
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// Message is an email to a single recipient. HTML is optional; Text is
+// always sent, as the whole body or the plain-text alternative.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers email through some provider
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Providers MAIL_PROVIDER can name
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSendGrid = "sendgrid"
+	ProviderLog      = "log"
+)
+
+var (
+	mu     sync.RWMutex
+	custom Mailer
+)
+
+// SetMailer makes every email go through m instead of the configured
+// provider, e.g. a provider that isn't built in. nil restores the default.
+func SetMailer(m Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	custom = m
+}
+
+// Provider returns the provider email goes through: MAIL_PROVIDER, or when
+// unset, SMTP if SMTP_HOST is set, SendGrid if SENDGRID_API_KEY is, and the
+// log otherwise. Mock mode always logs.
+func Provider() string {
+	if config.MockMode() {
+		return ProviderLog
+	}
+	switch provider := strings.ToLower(config.String("MAIL_PROVIDER", "")); provider {
+	case ProviderSMTP, ProviderSendGrid, ProviderLog:
+		return provider
+	}
+	switch {
+	case config.String("SMTP_HOST", "") != "":
+		return ProviderSMTP
+	case config.String("SENDGRID_API_KEY", "") != "":
+		return ProviderSendGrid
+	}
+	return ProviderLog
+}
+
+// current returns the Mailer for the configured provider
+func current() Mailer {
+	mu.RLock()
+	defer mu.RUnlock()
+	if custom != nil {
+		return custom
+	}
+	switch Provider() {
+	case ProviderSMTP:
+		return SMTPMailer{}
+	case ProviderSendGrid:
+		return SendGridMailer{}
+	}
+	return LogMailer{}
+}
+
+// Enabled reports whether email is actually delivered. Without a provider,
+// or in mock mode, messages are written to the log instead of sent.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return custom != nil || Provider() != ProviderLog
+}
+
+// Send delivers a plain-text email to a single recipient
+func Send(to, subject, body string) error {
+	return current().Send(Message{To: to, Subject: subject, Text: body})
+}
+
+// SendHTML delivers an HTML email with a plain-text alternative
+func SendHTML(to, subject, text, html string) error {
+	return current().Send(Message{To: to, Subject: subject, Text: text, HTML: html})
+}
+
+// From is the sender of every email (MAIL_FROM)
+func From() string {
+	return config.String("MAIL_FROM", "Aureus <no-reply@localhost>")
+}
+
+// LogMailer writes messages to the log instead of sending them
+type LogMailer struct{}
+
+func (LogMailer) Send(msg Message) error {
+	log.Printf("mail: to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// envelopeAddress extracts the bare address from "Name <addr>"
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
+
+// displayName extracts the name from "Name <addr>", if any
+func displayName(from string) string {
+	if start := strings.LastIndex(from, "<"); start > 0 {
+		return strings.Trim(strings.TrimSpace(from[:start]), `"`)
+	}
+	return ""
+}
+
+// AppURL is the frontend's base URL, used to build links in emails
+func AppURL() string {
+	return strings.TrimRight(config.String("APP_URL", "http://localhost:3000"), "/")
+}
@@ -0,0 +1,55 @@
+package mail
+
+import "testing"
+
+func TestProvider(t *testing.T) {
+	t.Setenv("MOCK_EXTERNAL_APIS", "false")
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, ProviderLog},
+		{map[string]string{"SMTP_HOST": "smtp.example.com"}, ProviderSMTP},
+		{map[string]string{"SENDGRID_API_KEY": "SG.key"}, ProviderSendGrid},
+		{map[string]string{"SMTP_HOST": "smtp.example.com", "MAIL_PROVIDER": "sendgrid"}, ProviderSendGrid},
+		{map[string]string{"SMTP_HOST": "smtp.example.com", "MAIL_PROVIDER": "log"}, ProviderLog},
+	}
+	for _, tt := range tests {
+		for _, key := range []string{"SMTP_HOST", "SENDGRID_API_KEY", "MAIL_PROVIDER"} {
+			t.Setenv(key, tt.env[key])
+		}
+		if got := Provider(); got != tt.want {
+			t.Errorf("Provider() with %v = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestSetMailerOverridesProvider(t *testing.T) {
+	var sent []Message
+	SetMailer(mailerFunc(func(m Message) error { sent = append(sent, m); return nil }))
+	defer SetMailer(nil)
+
+	if err := SendHTML("me@example.com", "Hi", "text", "<p>html</p>"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].To != "me@example.com" || sent[0].HTML != "<p>html</p>" {
+		t.Errorf("custom mailer got %+v", sent)
+	}
+	if !Enabled() {
+		t.Error("a custom mailer should count as enabled")
+	}
+}
+
+func TestSendGridRequest(t *testing.T) {
+	payload := sendGridRequest(Message{To: "me@example.com", Subject: "Hi", Text: "text", HTML: "<p>html</p>"}, `"Aureus" <no-reply@example.com>`)
+	if payload.From.Email != "no-reply@example.com" || payload.From.Name != "Aureus" {
+		t.Errorf("from = %+v", payload.From)
+	}
+	if len(payload.Content) != 2 || payload.Content[0].Type != "text/plain" || payload.Content[1].Type != "text/html" {
+		t.Errorf("content should be plain text then HTML: %+v", payload.Content)
+	}
+}
+
+type mailerFunc func(Message) error
+
+func (f mailerFunc) Send(m Message) error { return f(m) }
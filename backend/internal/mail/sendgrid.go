@@ -0,0 +1,93 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// SendGridMailer sends through the SendGrid v3 API with SENDGRID_API_KEY
+type SendGridMailer struct{}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridPayload struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// sendGridRequest builds the API payload for m. SendGrid wants the plain
+// text part before the HTML one.
+func sendGridRequest(m Message, from string) sendGridPayload {
+	payload := sendGridPayload{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: m.To}}}},
+		From:             sendGridAddress{Email: envelopeAddress(from), Name: displayName(from)},
+		Subject:          m.Subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: m.Text}},
+	}
+	if m.HTML != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: m.HTML})
+	}
+	return payload
+}
+
+func (SendGridMailer) Send(m Message) error {
+	body, err := json.Marshal(sendGridRequest(m, From()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sendGridURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+config.String("SENDGRID_API_KEY", ""))
+	req.Header.Set("Content-Type", "application/json")
+
+	err = post(req)
+	usage.RecordCall(usage.ServiceSendGrid, err)
+	if err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", m.To, err)
+	}
+	return nil
+}
+
+// post sends req and turns non-2xx responses into errors
+func post(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
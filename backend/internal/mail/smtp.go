@@ -0,0 +1,53 @@
+package mail
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// SMTPMailer sends through the SMTP server at SMTP_HOST:SMTP_PORT, logging
+// in with SMTP_USERNAME and SMTP_PASSWORD when a username is set
+type SMTPMailer struct{}
+
+func (SMTPMailer) Send(m Message) error {
+	from := From()
+	host := config.String("SMTP_HOST", "")
+	addr := net.JoinHostPort(host, config.String("SMTP_PORT", "587"))
+
+	var auth smtp.Auth
+	if username := config.String("SMTP_USERNAME", ""); username != "" {
+		auth = smtp.PlainAuth("", username, config.String("SMTP_PASSWORD", ""), host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", m.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	if m.HTML == "" {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		msg.WriteString(crlf(m.Text))
+	} else {
+		boundary := fmt.Sprintf("aureus-%d", time.Now().UnixNano())
+		fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+		fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(m.Text))
+		fmt.Fprintf(&msg, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(m.HTML))
+		fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+	}
+
+	if err := smtp.SendMail(addr, auth, envelopeAddress(from), []string{m.To}, []byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", m.To, err)
+	}
+	return nil
+}
+
+// crlf converts line endings to the CRLF required by SMTP
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
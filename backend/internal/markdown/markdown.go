@@ -0,0 +1,157 @@
+// Package markdown renders the Markdown users write, such as coin stories,
+// as HTML that is safe to show to anyone. It supports a small subset:
+// headings, paragraphs, bulleted and numbered lists, block quotes, rules,
+// bold, italics, inline code and links. Raw HTML is escaped rather than
+// passed through, and links only keep http(s) and mailto URLs.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// Link targets may hold balanced parentheses one level deep, as in
+// Wikipedia's https://en.wikipedia.org/wiki/Morgan_dollar_(coin)
+var (
+	headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	bulletPattern  = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	numberPattern  = regexp.MustCompile(`^\s*\d{1,9}[.)]\s+(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	linkPattern    = regexp.MustCompile(`\[([^\]]+)\]\(((?:[^()\s]|\([^()\s]*\))+)\)`)
+	strongPattern  = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emPattern      = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_\s][^_]*)_\b`)
+)
+
+// Render renders src as safe HTML
+func Render(src string) template.HTML {
+	var b strings.Builder
+	var paragraph []string
+	list := "" // "ul" or "ol" while in a list
+	quote := false
+
+	flushParagraph := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		if quote {
+			b.WriteString("<blockquote>")
+		}
+		b.WriteString("<p>" + inline(strings.Join(paragraph, "\n")) + "</p>")
+		if quote {
+			b.WriteString("</blockquote>")
+		}
+		paragraph, quote = nil, false
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">")
+			list = ""
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			flushParagraph()
+			closeList()
+			continue
+		}
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			flushParagraph()
+			closeList()
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">" + inline(m[2]) + "</h" + level + ">")
+			continue
+		}
+		if rulePattern.MatchString(line) {
+			flushParagraph()
+			closeList()
+			b.WriteString("<hr>")
+			continue
+		}
+		item, kind := "", ""
+		if m := bulletPattern.FindStringSubmatch(line); m != nil {
+			item, kind = m[1], "ul"
+		} else if m := numberPattern.FindStringSubmatch(line); m != nil {
+			item, kind = m[1], "ol"
+		}
+		if kind != "" {
+			flushParagraph()
+			if list != kind {
+				closeList()
+				b.WriteString("<" + kind + ">")
+				list = kind
+			}
+			b.WriteString("<li>" + inline(item) + "</li>")
+			continue
+		}
+		closeList()
+		if rest, ok := strings.CutPrefix(strings.TrimLeft(line, " "), ">"); ok {
+			if !quote {
+				flushParagraph()
+				quote = true
+			}
+			paragraph = append(paragraph, strings.TrimSpace(rest))
+			continue
+		}
+		if quote {
+			flushParagraph()
+		}
+		paragraph = append(paragraph, strings.TrimSpace(line))
+	}
+	flushParagraph()
+	closeList()
+	return template.HTML(b.String())
+}
+
+// inline renders the spans of a block: code, links and emphasis
+func inline(text string) string {
+	var b strings.Builder
+	// Odd parts between backticks are code and left as they are
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		case i%2 == 1:
+			// An unmatched backtick is just a backtick
+			b.WriteString("`" + links(part))
+		default:
+			b.WriteString(links(part))
+		}
+	}
+	return strings.ReplaceAll(b.String(), "\n", "<br>")
+}
+
+// links renders the links of text, escaping and emphasizing the rest
+func links(text string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		b.WriteString(emphasis(text[last:m[0]]))
+		label, target := text[m[2]:m[3]], text[m[4]:m[5]]
+		if safeURL(target) {
+			b.WriteString(`<a href="` + html.EscapeString(target) + `" rel="nofollow noopener noreferrer">` + emphasis(label) + "</a>")
+		} else {
+			b.WriteString(emphasis(label))
+		}
+		last = m[1]
+	}
+	b.WriteString(emphasis(text[last:]))
+	return b.String()
+}
+
+// emphasis escapes text and renders its bold and italics. Escaping leaves
+// asterisks and underscores alone, so they can be matched afterwards.
+func emphasis(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = strongPattern.ReplaceAllString(escaped, "<strong>$1$2</strong>")
+	return emPattern.ReplaceAllString(escaped, "<em>$1$2</em>")
+}
+
+// safeURL reports whether a link may point at target
+func safeURL(target string) bool {
+	lower := strings.ToLower(target)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "mailto:")
+}
@@ -0,0 +1,27 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name, src, want string
+	}{
+		{"paragraphs", "Bought at the\n1985 show.\n\nFrom Grandpa.", "<p>Bought at the<br>1985 show.</p><p>From Grandpa.</p>"},
+		{"heading", "## Provenance ##", "<h2>Provenance</h2>"},
+		{"emphasis", "A **superb** gem, *fully* struck, _not_ a snake_case_word", "<p>A <strong>superb</strong> gem, <em>fully</em> struck, <em>not</em> a snake_case_word</p>"},
+		{"lists", "- one\n- two\n1. first", "<ul><li>one</li><li>two</li></ul><ol><li>first</li></ol>"},
+		{"quote", "> Nice coin\n> said the dealer\nI agreed", "<blockquote><p>Nice coin<br>said the dealer</p></blockquote><p>I agreed</p>"},
+		{"rule", "above\n\n---", "<p>above</p><hr>"},
+		{"code", "Cert `12345 <b>`", "<p>Cert <code>12345 &lt;b&gt;</code></p>"},
+		{"link", "See [the auction](https://example.com/lot?a=1&b=2)", `<p>See <a href="https://example.com/lot?a=1&amp;b=2" rel="nofollow noopener noreferrer">the auction</a></p>`},
+		{"parens in link", "[Morgan](https://en.wikipedia.org/wiki/Morgan_dollar_(coin)).", `<p><a href="https://en.wikipedia.org/wiki/Morgan_dollar_(coin)" rel="nofollow noopener noreferrer">Morgan</a>.</p>`},
+		{"unsafe link", "[click](javascript:alert(1))", "<p>click</p>"},
+		{"raw html", `<script>alert("x")</script> <img src=x onerror=alert(1)>`, "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; &lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"empty", "  \n\n", ""},
+	}
+	for _, tc := range cases {
+		if got := string(Render(tc.src)); got != tc.want {
+			t.Errorf("%s: Render(%q) =\n%s\nwant\n%s", tc.name, tc.src, got, tc.want)
+		}
+	}
+}
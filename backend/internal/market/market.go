@@ -0,0 +1,175 @@
+// Package market knows when the precious metals market trades, so spot
+// price changes can be measured from one close to the next instead of over
+// weekends and holidays. It follows the New York (COMEX) session by default:
+// each trading day closes at 17:00 New York time and the next session opens
+// an hour later, from Sunday evening to Friday's close, with New Year's Day,
+// Good Friday and Christmas off.
+package market
+
+import (
+	"sync"
+	"time"
+	// Embedded so the market's timezone loads on hosts without zoneinfo
+	_ "time/tzdata"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// SessionBreak is how long the market pauses between a close and the next
+// session
+const SessionBreak = time.Hour
+
+const (
+	defaultTimezone = "America/New_York"
+	defaultClose    = "17:00"
+)
+
+// Calendar is a market's trading days and daily close
+type Calendar struct {
+	Location    *time.Location
+	CloseHour   int
+	CloseMinute int
+}
+
+// Default returns the calendar configured with MARKET_TIMEZONE (an IANA
+// name, default America/New_York) and MARKET_CLOSE (HH:MM in it, default
+// 17:00). Invalid settings fall back to the defaults.
+var Default = sync.OnceValue(func() Calendar {
+	location, err := time.LoadLocation(config.String("MARKET_TIMEZONE", defaultTimezone))
+	if err != nil {
+		location, _ = time.LoadLocation(defaultTimezone)
+	}
+	at, err := time.Parse("15:04", config.String("MARKET_CLOSE", defaultClose))
+	if err != nil {
+		at, _ = time.Parse("15:04", defaultClose)
+	}
+	return Calendar{Location: location, CloseHour: at.Hour(), CloseMinute: at.Minute()}
+})
+
+// Status is whether the market is open at a moment, with its last close and
+// when it next opens (while closed) or closes (while open)
+type Status struct {
+	Open      bool       `json:"open"`
+	Timezone  string     `json:"timezone"`
+	LastClose time.Time  `json:"last_close"`
+	NextOpen  *time.Time `json:"next_open,omitempty"`
+	NextClose *time.Time `json:"next_close,omitempty"`
+}
+
+// Status reports the market's state at t
+func (cal Calendar) Status(t time.Time) Status {
+	status := Status{Open: cal.Open(t), Timezone: cal.Location.String(), LastClose: cal.LastClose(t)}
+	if status.Open {
+		next := cal.NextClose(t)
+		status.NextClose = &next
+	} else {
+		next := cal.NextOpen(t)
+		status.NextOpen = &next
+	}
+	return status
+}
+
+// date returns midnight of t's day in the market's timezone, offset by days
+func (cal Calendar) date(t time.Time, days int) time.Time {
+	local := t.In(cal.Location)
+	return time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, cal.Location)
+}
+
+// closeOn returns the close on date's day
+func (cal Calendar) closeOn(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), cal.CloseHour, cal.CloseMinute, 0, 0, cal.Location)
+}
+
+// TradingDay reports whether the market trades on date's day in its timezone
+func (cal Calendar) TradingDay(date time.Time) bool {
+	date = cal.date(date, 0)
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return !holiday(date)
+}
+
+// Open reports whether the market is trading at t. A trading day's session
+// starts SessionBreak after the previous day's close.
+func (cal Calendar) Open(t time.Time) bool {
+	day := cal.date(t, 0)
+	if !t.Before(cal.closeOn(day)) {
+		day = cal.date(t, 1)
+	}
+	previous := cal.closeOn(cal.date(day, -1))
+	return cal.TradingDay(day) && !t.Before(previous.Add(SessionBreak))
+}
+
+// LastClose returns the most recent close at or before t
+func (cal Calendar) LastClose(t time.Time) time.Time {
+	for days := 0; ; days-- {
+		day := cal.date(t, days)
+		if closing := cal.closeOn(day); cal.TradingDay(day) && !closing.After(t) {
+			return closing
+		}
+	}
+}
+
+// NextClose returns the first close after t
+func (cal Calendar) NextClose(t time.Time) time.Time {
+	for days := 0; ; days++ {
+		day := cal.date(t, days)
+		if closing := cal.closeOn(day); cal.TradingDay(day) && closing.After(t) {
+			return closing
+		}
+	}
+}
+
+// NextOpen returns when the first session starting after t opens
+func (cal Calendar) NextOpen(t time.Time) time.Time {
+	for days := 0; ; days++ {
+		day := cal.date(t, days)
+		open := cal.closeOn(cal.date(day, -1)).Add(SessionBreak)
+		if cal.TradingDay(day) && open.After(t) {
+			return open
+		}
+	}
+}
+
+// PreviousClose returns the close a day's change at t is measured from:
+// while the market is open, the last close; while it's closed, the close
+// before that, so the change is that of the last session rather than zero
+func (cal Calendar) PreviousClose(t time.Time) time.Time {
+	last := cal.LastClose(t)
+	if cal.Open(t) {
+		return last
+	}
+	return cal.LastClose(last.Add(-time.Nanosecond))
+}
+
+// holiday reports whether the market is closed for a holiday on date: New
+// Year's Day (or the Monday after when it's a Sunday), Good Friday, and
+// Christmas (or the nearest weekday when it's on a weekend)
+func holiday(date time.Time) bool {
+	year, month, day := date.Date()
+	weekday := date.Weekday()
+	switch {
+	case month == time.January && (day == 1 || day == 2 && weekday == time.Monday):
+		return true
+	case month == time.December && (day == 25 || day == 24 && weekday == time.Friday || day == 26 && weekday == time.Monday):
+		return true
+	}
+	goodFriday := easter(year, date.Location()).AddDate(0, 0, -2)
+	return month == goodFriday.Month() && day == goodFriday.Day()
+}
+
+// easter returns Easter Sunday of year, by the anonymous Gregorian algorithm
+func easter(year int, location *time.Location) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, location)
+}
@@ -0,0 +1,85 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+func newYork(t *testing.T) Calendar {
+	t.Helper()
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Calendar{Location: location, CloseHour: 17}
+}
+
+func TestOpen(t *testing.T) {
+	cal := newYork(t)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, cal.Location)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"Friday before the close", at(2026, time.October, 16, 16, 59), true},
+		{"Friday at the close", at(2026, time.October, 16, 17, 0), false},
+		{"Friday evening", at(2026, time.October, 16, 18, 30), false},
+		{"Saturday", at(2026, time.October, 17, 12, 0), false},
+		{"Sunday before the open", at(2026, time.October, 18, 17, 59), false},
+		{"Sunday evening", at(2026, time.October, 18, 18, 0), true},
+		{"daily break", at(2026, time.October, 20, 17, 30), false},
+		{"Tuesday evening", at(2026, time.October, 20, 18, 0), true},
+		{"Good Friday", at(2026, time.April, 3, 10, 0), false},
+		{"evening before Good Friday", at(2026, time.April, 2, 19, 0), false},
+		{"Christmas Eve when Christmas is a Saturday", at(2027, time.December, 24, 10, 0), false},
+		{"New Year's Day", at(2027, time.January, 1, 10, 0), false},
+	}
+	for _, tc := range tests {
+		if got := cal.Open(tc.at); got != tc.want {
+			t.Errorf("%s: Open(%v) = %v, want %v", tc.name, tc.at, got, tc.want)
+		}
+	}
+}
+
+func TestCloses(t *testing.T) {
+	cal := newYork(t)
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, cal.Location)
+	}
+
+	saturday := at(time.October, 17, 12)
+	if got, want := cal.LastClose(saturday), at(time.October, 16, 17); !got.Equal(want) {
+		t.Errorf("LastClose(Saturday) = %v, want Friday's close %v", got, want)
+	}
+	if got, want := cal.PreviousClose(saturday), at(time.October, 15, 17); !got.Equal(want) {
+		t.Errorf("PreviousClose(Saturday) = %v, want Thursday's close %v", got, want)
+	}
+	if got, want := cal.NextOpen(saturday), at(time.October, 18, 18); !got.Equal(want) {
+		t.Errorf("NextOpen(Saturday) = %v, want Sunday evening %v", got, want)
+	}
+
+	monday := at(time.October, 19, 10)
+	if got, want := cal.PreviousClose(monday), at(time.October, 16, 17); !got.Equal(want) {
+		t.Errorf("PreviousClose(Monday) = %v, want Friday's close %v", got, want)
+	}
+	if got, want := cal.NextClose(monday), at(time.October, 19, 17); !got.Equal(want) {
+		t.Errorf("NextClose(Monday) = %v, want %v", got, want)
+	}
+
+	// Good Friday 2026 is April 3, so the Monday after is measured from Thursday
+	if got, want := cal.PreviousClose(at(time.April, 6, 10)), at(time.April, 2, 17); !got.Equal(want) {
+		t.Errorf("PreviousClose after Good Friday = %v, want %v", got, want)
+	}
+}
+
+func TestEaster(t *testing.T) {
+	for year, want := range map[int]string{2024: "2024-03-31", 2025: "2025-04-20", 2026: "2026-04-05", 2038: "2038-04-25"} {
+		if got := easter(year, time.UTC).Format("2006-01-02"); got != want {
+			t.Errorf("easter(%d) = %s, want %s", year, got, want)
+		}
+	}
+}
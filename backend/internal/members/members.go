@@ -0,0 +1,114 @@
+// Package members decides what a user may do with a portfolio shared with
+// them. The account that created a portfolio owns it; members are invited
+// as owners too, as editors, who manage its coins, or as viewers, who only
+// read them. An invitation grants nothing until it is accepted.
+package members
+
+import (
+	"errors"
+	"log"
+	"slices"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles, from least to most access
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleOwner  = "owner"
+)
+
+var roles = []string{RoleViewer, RoleEditor, RoleOwner}
+
+var (
+	// ErrNotFound is returned for a portfolio the user can't see at all
+	ErrNotFound = errors.New("portfolio not found")
+	// ErrForbidden is returned when the user's role doesn't allow the action
+	ErrForbidden = errors.New("your role on this portfolio doesn't allow that")
+)
+
+// ValidRole reports whether role is one members can be given
+func ValidRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+// Allows reports whether role grants at least min
+func Allows(role, min string) bool {
+	return slices.Index(roles, role) >= slices.Index(roles, min) && slices.Index(roles, min) >= 0
+}
+
+// memberships selects the portfolios the user has accepted an invitation to
+func memberships(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.PortfolioMember{}).Select("portfolio_id").
+		Where("user_id = ? AND accepted_at IS NOT NULL", userID)
+}
+
+// Accessible selects the IDs of the portfolios the user owns or is a
+// member of, for use as a subquery
+func Accessible(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Model(&models.Portfolio{}).Select("id").
+		Where("user_id = ? OR id IN (?)", userID, memberships(db, userID))
+}
+
+// Find loads a portfolio the user has at least the min role on, and their
+// role. It returns ErrNotFound when they have no access, and the portfolio
+// with ErrForbidden when their role is too low.
+func Find(userID uuid.UUID, portfolioID any, min string) (models.Portfolio, string, error) {
+	db := database.GetDB()
+	var portfolio models.Portfolio
+	if err := db.Where("id = ? AND (user_id = ? OR id IN (?))", portfolioID, userID, memberships(db, userID)).
+		First(&portfolio).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return portfolio, "", ErrNotFound
+		}
+		return portfolio, "", err
+	}
+
+	role := RoleOwner
+	if portfolio.UserID != userID {
+		var member models.PortfolioMember
+		if err := db.Where("portfolio_id = ? AND user_id = ?", portfolio.ID, userID).First(&member).Error; err != nil {
+			return portfolio, "", err
+		}
+		role = member.Role
+	}
+	if !Allows(role, min) {
+		return portfolio, role, ErrForbidden
+	}
+	return portfolio, role, nil
+}
+
+// Roles returns the user's role on each of portfolios they don't own
+func Roles(userID uuid.UUID, portfolioIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	result := map[uuid.UUID]string{}
+	if len(portfolioIDs) == 0 {
+		return result, nil
+	}
+	var rows []models.PortfolioMember
+	if err := database.GetDB().Where("user_id = ? AND portfolio_id IN ? AND accepted_at IS NOT NULL", userID, portfolioIDs).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.PortfolioID] = row.Role
+	}
+	return result, nil
+}
+
+// Subscribe removes the members of deleted portfolios
+func Subscribe() {
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Where("portfolio_id = ?", updated.PortfolioID).Delete(&models.PortfolioMember{}).Error; err != nil {
+			log.Printf("Failed to remove the members of portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+}
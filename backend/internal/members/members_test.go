@@ -0,0 +1,27 @@
+package members
+
+import "testing"
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		role, min string
+		want      bool
+	}{
+		{RoleOwner, RoleOwner, true},
+		{RoleOwner, RoleViewer, true},
+		{RoleEditor, RoleEditor, true},
+		{RoleEditor, RoleOwner, false},
+		{RoleViewer, RoleViewer, true},
+		{RoleViewer, RoleEditor, false},
+		{"", RoleViewer, false},
+		{RoleOwner, "admin", false},
+	}
+	for _, tc := range cases {
+		if got := Allows(tc.role, tc.min); got != tc.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tc.role, tc.min, got, tc.want)
+		}
+	}
+	if ValidRole("admin") || !ValidRole(RoleEditor) {
+		t.Error("ValidRole accepts the wrong roles")
+	}
+}
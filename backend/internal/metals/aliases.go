@@ -0,0 +1,201 @@
+package metals
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SeriesAliases maps common collector nicknames and abbreviations (lowercase)
+// to the catalog name they refer to
+var SeriesAliases = map[string]string{
+	// Dollars
+	"morgan":        "Morgan Dollar",
+	"peace":         "Peace Dollar",
+	"ike":           "Eisenhower Dollar",
+	"ike dollar":    "Eisenhower Dollar",
+	"sba":           "Susan B. Anthony Dollar",
+	"sba dollar":    "Susan B. Anthony Dollar",
+	"sac":           "Sacagawea Dollar",
+	"sacagawea":     "Sacagawea Dollar",
+	"golden dollar": "Sacagawea Dollar",
+	"trade":         "Trade Dollar",
+	"seated dollar": "Seated Liberty Dollar",
+
+	// Halves
+	"walker":               "Walking Liberty Half Dollar",
+	"walking liberty":      "Walking Liberty Half Dollar",
+	"walking liberty half": "Walking Liberty Half Dollar",
+	"franklin":             "Franklin Half Dollar",
+	"franklin half":        "Franklin Half Dollar",
+	"kennedy":              "Kennedy Half Dollar",
+	"kennedy half":         "Kennedy Half Dollar",
+	"jfk half":             "Kennedy Half Dollar",
+	"barber half":          "Barber Half Dollar",
+	"seated half":          "Seated Liberty Half Dollar",
+
+	// Quarters
+	"washington":       "Washington Quarter",
+	"slq":              "Standing Liberty Quarter",
+	"standing liberty": "Standing Liberty Quarter",
+	"barber quarter":   "Barber Quarter",
+	"seated quarter":   "Seated Liberty Quarter",
+
+	// Dimes
+	"merc":                "Mercury Dime",
+	"mercury":             "Mercury Dime",
+	"winged liberty dime": "Mercury Dime",
+	"rosie":               "Roosevelt Dime",
+	"roosevelt":           "Roosevelt Dime",
+	"barber":              "Barber Dime",
+
+	// Nickels and cents
+	"war nickel":         "Jefferson Nickel (Wartime Silver)",
+	"wartime nickel":     "Jefferson Nickel (Wartime Silver)",
+	"buffalo":            "Buffalo Nickel",
+	"indian head nickel": "Buffalo Nickel",
+	"v nickel":           "Liberty Nickel",
+	"jefferson":          "Jefferson Nickel",
+	"wheatie":            "Wheat Penny",
+	"wheat cent":         "Wheat Penny",
+	"steelie":            "Steel Penny",
+	"steel cent":         "Steel Penny",
+	"indian cent":        "Indian Head Cent",
+	"lincoln":            "Lincoln Cent",
+
+	// Gold
+	"saint":                 "$20 Saint Gaudens",
+	"saint gaudens":         "$20 Saint Gaudens",
+	"st gaudens":            "$20 Saint Gaudens",
+	"st. gaudens":           "$20 Saint Gaudens",
+	"$20 lib":               "$20 Liberty",
+	"age":                   "American Gold Eagle (1 oz)",
+	"gold eagle":            "American Gold Eagle (1 oz)",
+	"gold buffalo":          "American Buffalo (Gold)",
+	"half oz buffalo":       "American Buffalo (Gold 1/2 oz)",
+	"quarter oz buffalo":    "American Buffalo (Gold 1/4 oz)",
+	"tenth oz buffalo":      "American Buffalo (Gold 1/10 oz)",
+	"first spouse":          "First Spouse Gold",
+	"first spouse $10 gold": "First Spouse Gold",
+	"spouse gold":           "First Spouse Gold",
+	"american liberty":      "American Liberty High Relief Gold",
+	"liberty high relief":   "American Liberty High Relief Gold",
+	"krug":                  "Krugerrand",
+	"gold maple":            "Canadian Maple Leaf (Gold)",
+	"gold maple leaf":       "Canadian Maple Leaf (Gold)",
+	"gold philharmonic":     "Vienna Philharmonic (Gold)",
+	"gold britannia":        "Britannia (Gold)",
+
+	// Palladium
+	"palladium eagle": "American Palladium Eagle",
+	"ape":             "American Palladium Eagle",
+
+	// Silver bullion
+	"ase":               "American Silver Eagle",
+	"silver eagle":      "American Silver Eagle",
+	"silver maple":      "Canadian Maple Leaf (Silver)",
+	"silver maple leaf": "Canadian Maple Leaf (Silver)",
+	"silver britannia":  "Britannia (Silver)",
+
+	// Commemoratives
+	"commem dollar":              "Commemorative Silver Dollar",
+	"commemorative dollar":       "Commemorative Silver Dollar",
+	"silver commem":              "Commemorative Silver Dollar",
+	"commem half":                "Commemorative Clad Half Dollar",
+	"commemorative half dollar":  "Commemorative Clad Half Dollar",
+	"silver commem half":         "Commemorative Silver Half Dollar",
+	"$5 commem":                  "Commemorative $5 Gold",
+	"gold commem":                "Commemorative $5 Gold",
+	"$10 commem":                 "Commemorative $10 Gold",
+	"classic commem":             "Classic Commemorative Half Dollar",
+	"classic commem half":        "Classic Commemorative Half Dollar",
+	"isabella":                   "Isabella Quarter",
+	"classic commem gold dollar": "Classic Commemorative Gold Dollar",
+	"pan-pac $50":                "Panama-Pacific $50",
+}
+
+var (
+	leadingYearPattern    = regexp.MustCompile(`(?i)^\d{4}[-\s]?[A-Z]?\s+`)
+	trailingGradePattern  = regexp.MustCompile(`(?i)\s+[A-Z]{2}\d+[A-Z+]*$`)
+	repeatedSpacesPattern = regexp.MustCompile(`\s+`)
+)
+
+// normalizeCoinType attempts to extract the base coin name from PCGS-style names
+// e.g., "1921-S Peace Dollar MS67" -> "Peace Dollar",
+// "1999-S Silver Proof Washington Quarter PR69DCAM" -> "Washington Quarter"
+func normalizeCoinType(coinType string) string {
+	normalized := repeatedSpacesPattern.ReplaceAllString(strings.TrimSpace(coinType), " ")
+	// Remove leading year patterns like "1921 " or "1921-S "
+	normalized = leadingYearPattern.ReplaceAllString(normalized, "")
+	// Remove strike descriptors like "Silver Proof" or "SMS"
+	normalized = stripStrikeWords(normalized)
+	// Remove trailing grade patterns like " MS67" or " PR70DCAM"
+	normalized = trailingGradePattern.ReplaceAllString(normalized, "")
+	return normalized
+}
+
+// builtinNames indexes every built-in catalog coin type by its lowercase name
+var builtinNames = sync.OnceValue(func() map[string]string {
+	names := make(map[string]string, len(CommonCompositions)+len(YearBasedCompositions))
+	for name := range CommonCompositions {
+		names[strings.ToLower(name)] = name
+	}
+	for _, ybc := range YearBasedCompositions {
+		names[strings.ToLower(ybc.CoinType)] = ybc.CoinType
+	}
+	return names
+})
+
+// catalogNames indexes every catalog coin type, including those only added by
+// an override, by its lowercase name
+func catalogNames() map[string]string {
+	added := overrideNames()
+	if len(added) == 0 {
+		return builtinNames()
+	}
+	names := make(map[string]string, len(builtinNames())+len(added))
+	for lower, name := range builtinNames() {
+		names[lower] = name
+	}
+	for lower, name := range added {
+		names[lower] = name
+	}
+	return names
+}
+
+// CanonicalCoinType resolves free text to a catalog coin type, matching
+// case-insensitively and through SeriesAliases, first on the text as given
+// and then with the year and grade stripped. The second return value is how
+// it matched: MatchExact, MatchAlias or MatchNormalized.
+func CanonicalCoinType(coinType string) (string, string, bool) {
+	if _, ok := catalogComposition(coinType); ok {
+		return coinType, MatchExact, true
+	}
+	if _, ok := yearBasedRule(coinType); ok {
+		return coinType, MatchExact, true
+	}
+
+	cleaned := strings.ToLower(repeatedSpacesPattern.ReplaceAllString(strings.TrimSpace(coinType), " "))
+	if cleaned == "" {
+		return "", "", false
+	}
+	if name, ok := catalogNames()[cleaned]; ok {
+		return name, MatchExact, true
+	}
+	if name, ok := SeriesAliases[cleaned]; ok {
+		return name, MatchAlias, true
+	}
+
+	normalized := strings.ToLower(normalizeCoinType(coinType))
+	if normalized == cleaned || normalized == "" {
+		return "", "", false
+	}
+	if name, ok := catalogNames()[normalized]; ok {
+		return name, MatchNormalized, true
+	}
+	if name, ok := SeriesAliases[normalized]; ok {
+		return name, MatchNormalized, true
+	}
+
+	return "", "", false
+}
@@ -0,0 +1,147 @@
+package metals
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/debuglog"
+	"github.com/evansminotwood/aureus/internal/usage"
+)
+
+// Neither precious metals source quotes copper or nickel. They come from a
+// base metals feed configured with BASE_METALS_API_URL (and an optional
+// BASE_METALS_API_KEY bearer token) that answers GET <url> with
+//
+//	{"copper": 9850, "nickel": 17200, "unit": "t"}
+//
+// in USD per unit: "lb" (the default), "kg" or "t" (metric tonne, as the LME
+// quotes them).
+const defaultBaseMetalsInterval = time.Hour
+
+// Pounds per unit a base metals feed may quote in
+var poundsPerUnit = map[string]float64{
+	"lb": 1,
+	"kg": 2.20462,
+	"t":  2204.62,
+}
+
+// BaseMetalQuote is copper and nickel in USD per pound
+type BaseMetalQuote struct {
+	Copper    float64
+	Nickel    float64
+	FetchedAt time.Time
+}
+
+var (
+	baseMetalsMu   sync.Mutex
+	lastBaseQuote  *BaseMetalQuote
+	baseMetalsHTTP = &http.Client{Timeout: 15 * time.Second, Transport: debuglog.Transport{}}
+)
+
+// BaseMetalsConfigured reports whether a base metals feed is set up
+func BaseMetalsConfigured() bool {
+	return config.String("BASE_METALS_API_URL", "") != ""
+}
+
+// parseBaseMetals reads a feed response, converting its prices to USD per
+// pound
+func parseBaseMetals(body []byte) (BaseMetalQuote, error) {
+	var response struct {
+		Copper float64 `json:"copper"`
+		Nickel float64 `json:"nickel"`
+		Unit   string  `json:"unit"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return BaseMetalQuote{}, fmt.Errorf("invalid base metals response: %w", err)
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(response.Unit))
+	if unit == "" {
+		unit = "lb"
+	}
+	pounds, ok := poundsPerUnit[unit]
+	if !ok {
+		return BaseMetalQuote{}, fmt.Errorf("unknown base metals unit %q", response.Unit)
+	}
+	if response.Copper <= 0 && response.Nickel <= 0 {
+		return BaseMetalQuote{}, fmt.Errorf("no copper or nickel price in base metals response")
+	}
+	return BaseMetalQuote{Copper: response.Copper / pounds, Nickel: response.Nickel / pounds}, nil
+}
+
+// baseMetalPrices returns the latest copper and nickel quote, asking the feed
+// at most every BASE_METALS_REFRESH_INTERVAL (default 1h) since base metals
+// are quoted daily. A failed fetch keeps the previous quote, if it's no older
+// than a day.
+func baseMetalPrices(now time.Time) (*BaseMetalQuote, error) {
+	baseMetalsMu.Lock()
+	defer baseMetalsMu.Unlock()
+
+	interval := config.Duration("BASE_METALS_REFRESH_INTERVAL", defaultBaseMetalsInterval)
+	if lastBaseQuote != nil && now.Sub(lastBaseQuote.FetchedAt) < interval {
+		return lastBaseQuote, nil
+	}
+
+	quote, err := fetchBaseMetals()
+	usage.RecordCall(usage.ServiceBaseMetals, err)
+	if err != nil {
+		if lastBaseQuote != nil && now.Sub(lastBaseQuote.FetchedAt) < 24*time.Hour {
+			return lastBaseQuote, nil
+		}
+		return nil, err
+	}
+	quote.FetchedAt = now
+	lastBaseQuote = &quote
+	return lastBaseQuote, nil
+}
+
+func fetchBaseMetals() (BaseMetalQuote, error) {
+	req, err := http.NewRequest(http.MethodGet, config.String("BASE_METALS_API_URL", ""), nil)
+	if err != nil {
+		return BaseMetalQuote{}, err
+	}
+	if key := config.String("BASE_METALS_API_KEY", ""); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := baseMetalsHTTP.Do(req)
+	if err != nil {
+		return BaseMetalQuote{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return BaseMetalQuote{}, fmt.Errorf("base metals feed returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return BaseMetalQuote{}, err
+	}
+	return parseBaseMetals(body)
+}
+
+// addBaseMetals prices copper and nickel from the base metals feed when one
+// is configured and prices doesn't have them yet
+func addBaseMetals(prices *SpotPrices) {
+	if config.MockMode() || !BaseMetalsConfigured() || (prices.Copper > 0 && prices.Nickel > 0) {
+		return
+	}
+	quote, err := baseMetalPrices(time.Now())
+	if err != nil {
+		fmt.Printf("⚠ Base metals feed failed: %v\n", err)
+		return
+	}
+	if prices.Copper <= 0 {
+		prices.Copper = quote.Copper
+	}
+	if prices.Nickel <= 0 {
+		prices.Nickel = quote.Nickel
+	}
+}
@@ -0,0 +1,53 @@
+package metals
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseBaseMetalsConvertsToPounds(t *testing.T) {
+	quote, err := parseBaseMetals([]byte(`{"copper": 9850, "nickel": 17200, "unit": "t"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(quote.Copper-4.468) > 0.001 || math.Abs(quote.Nickel-7.802) > 0.001 {
+		t.Errorf("copper = %.3f, nickel = %.3f per pound; want 4.468 and 7.802", quote.Copper, quote.Nickel)
+	}
+
+	if quote, _ := parseBaseMetals([]byte(`{"copper": 4.5}`)); quote.Copper != 4.5 || quote.Nickel != 0 {
+		t.Errorf("per-pound quote = %+v", quote)
+	}
+	if _, err := parseBaseMetals([]byte(`{"copper": 4.5, "unit": "oz"}`)); err == nil {
+		t.Error("an unknown unit should be rejected")
+	}
+}
+
+func TestBaseMetalPricesAreCachedForTheInterval(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("Authorization") != "Bearer feed-key" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"copper": 4.25, "nickel": 7.5}`))
+	}))
+	defer server.Close()
+	t.Setenv("BASE_METALS_API_URL", server.URL)
+	t.Setenv("BASE_METALS_API_KEY", "feed-key")
+	t.Setenv("BASE_METALS_REFRESH_INTERVAL", "1h")
+	t.Cleanup(func() { lastBaseQuote = nil })
+
+	now := time.Now()
+	for _, at := range []time.Time{now, now.Add(30 * time.Minute), now.Add(2 * time.Hour)} {
+		quote, err := baseMetalPrices(at)
+		if err != nil || quote.Copper != 4.25 {
+			t.Fatalf("quote = %+v, %v", quote, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("feed called %d times over 2h with a 1h interval, want 2", calls)
+	}
+}
@@ -0,0 +1,75 @@
+package metals
+
+import "fmt"
+
+// GramsPerPound converts base metal coin weights to the per-pound spot unit
+const GramsPerPound = 453.592
+
+// Calculator computes melt values against a fixed set of spot prices. It has
+// no dependency on the live price cache, so results are deterministic and
+// hypothetical prices can be plugged in (e.g. for what-if scenarios).
+type Calculator struct {
+	Prices SpotPrices
+}
+
+// NewCalculator creates a calculator for the given spot prices
+func NewCalculator(prices SpotPrices) *Calculator {
+	return &Calculator{Prices: prices}
+}
+
+// CurrentCalculator returns a calculator using the current (cached) spot prices
+func CurrentCalculator() (*Calculator, error) {
+	prices, err := GetSpotPrices()
+	if err != nil {
+		return nil, err
+	}
+	return NewCalculator(*prices), nil
+}
+
+// MeltValue returns the melt value of a precious metal coin.
+// weight is in troy ounces and purity is a percentage (e.g. 90).
+func (c *Calculator) MeltValue(metalType string, weight float64, purity float64) (float64, error) {
+	var pricePerOz float64
+	switch metalType {
+	case "gold":
+		pricePerOz = c.Prices.Gold
+	case "silver":
+		pricePerOz = c.Prices.Silver
+	case "platinum":
+		pricePerOz = c.Prices.Platinum
+	case "palladium":
+		pricePerOz = c.Prices.Palladium
+	case "copper", "nickel":
+		// Base metals are priced per pound, but weight is in troy ounces
+		// For base metal coins, we need to return 0 since the weight stored is troy oz of precious metal
+		// Base metal calculations need to be handled separately with gram weights
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unsupported metal type: %s", metalType)
+	}
+
+	pureWeight := weight * (purity / 100.0)
+	return pureWeight * pricePerOz, nil
+}
+
+// BaseMeltValue returns the melt value of a copper/nickel coin
+// weightGrams: total weight of coin in grams
+// copperPercent: percentage of copper (0-100)
+// nickelPercent: percentage of nickel (0-100)
+func (c *Calculator) BaseMeltValue(weightGrams float64, copperPercent float64, nickelPercent float64) float64 {
+	weightPounds := weightGrams / GramsPerPound
+
+	copperValue := weightPounds * (copperPercent / 100.0) * c.Prices.Copper
+	nickelValue := weightPounds * (nickelPercent / 100.0) * c.Prices.Nickel
+
+	return copperValue + nickelValue
+}
+
+// CompositionMeltValue returns the melt value for a composition, handling
+// both precious metals (troy oz) and base metals (grams)
+func (c *Calculator) CompositionMeltValue(comp MetalComposition) (float64, error) {
+	if comp.IsBaseMetal {
+		return c.BaseMeltValue(comp.WeightGrams, comp.CopperPercent, comp.NickelPercent), nil
+	}
+	return c.MeltValue(comp.MetalType, comp.Weight, comp.Purity)
+}
@@ -0,0 +1,231 @@
+package metals
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+)
+
+type goldenDataset struct {
+	Prices struct {
+		Gold      float64 `json:"gold"`
+		Silver    float64 `json:"silver"`
+		Platinum  float64 `json:"platinum"`
+		Palladium float64 `json:"palladium"`
+		Copper    float64 `json:"copper"`
+		Nickel    float64 `json:"nickel"`
+	} `json:"prices"`
+	Coins []struct {
+		CoinType  string  `json:"coin_type"`
+		Year      int     `json:"year"`
+		MetalType string  `json:"metal_type"`
+		MeltValue float64 `json:"melt_value"`
+	} `json:"coins"`
+}
+
+func loadGolden(t *testing.T) goldenDataset {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/golden_melt.json")
+	if err != nil {
+		t.Fatalf("failed to read golden dataset: %v", err)
+	}
+
+	var golden goldenDataset
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("failed to parse golden dataset: %v", err)
+	}
+	return golden
+}
+
+func TestGoldenMeltValues(t *testing.T) {
+	golden := loadGolden(t)
+	calc := NewCalculator(SpotPrices{
+		Gold:      golden.Prices.Gold,
+		Silver:    golden.Prices.Silver,
+		Platinum:  golden.Prices.Platinum,
+		Palladium: golden.Prices.Palladium,
+		Copper:    golden.Prices.Copper,
+		Nickel:    golden.Prices.Nickel,
+	})
+
+	for _, tc := range golden.Coins {
+		t.Run(fmt.Sprintf("%s/%d", tc.CoinType, tc.Year), func(t *testing.T) {
+			comp, ok := ResolveComposition(tc.CoinType, tc.Year)
+			if !ok {
+				t.Fatalf("no composition found")
+			}
+			if comp.MetalType != tc.MetalType {
+				t.Errorf("metal type = %q, want %q", comp.MetalType, tc.MetalType)
+			}
+
+			got, err := calc.CompositionMeltValue(comp)
+			if err != nil {
+				t.Fatalf("CompositionMeltValue: %v", err)
+			}
+			if math.Abs(got-tc.MeltValue) > 0.0001 {
+				t.Errorf("melt value = %.4f, want %.4f", got, tc.MeltValue)
+			}
+		})
+	}
+}
+
+func TestCalculatorUsesGivenPrices(t *testing.T) {
+	low := NewCalculator(SpotPrices{Silver: 20})
+	high := NewCalculator(SpotPrices{Silver: 40})
+
+	lowValue, _ := low.MeltValue("silver", 1, 100)
+	highValue, _ := high.MeltValue("silver", 1, 100)
+
+	if lowValue != 20 || highValue != 40 {
+		t.Errorf("got %.2f and %.2f, want 20.00 and 40.00", lowValue, highValue)
+	}
+}
+
+func TestCalculatorUnsupportedMetal(t *testing.T) {
+	calc := NewCalculator(SpotPrices{})
+	if _, err := calc.MeltValue("unobtainium", 1, 100); err == nil {
+		t.Error("expected error for unsupported metal")
+	}
+}
+
+func TestResolveCompositionUnknown(t *testing.T) {
+	if _, ok := ResolveComposition("Not A Real Coin", 1900); ok {
+		t.Error("expected unknown coin type to not resolve")
+	}
+}
+
+func TestCanonicalCoinType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		how   string
+	}{
+		{"Morgan Dollar", "Morgan Dollar", MatchExact},
+		{"morgan dollar", "Morgan Dollar", MatchExact},
+		{"  Walking   Liberty Half Dollar ", "Walking Liberty Half Dollar", MatchExact},
+		{"Walker", "Walking Liberty Half Dollar", MatchAlias},
+		{"ASE", "American Silver Eagle", MatchAlias},
+		{"Saint", "$20 Saint Gaudens", MatchAlias},
+		{"Merc", "Mercury Dime", MatchAlias},
+		{"1921 Peace Dollar", "Peace Dollar", MatchNormalized},
+		{"1921-S Peace Dollar MS67", "Peace Dollar", MatchNormalized},
+		{"1942 Walker MS65", "Walking Liberty Half Dollar", MatchNormalized},
+		{"sacagawea dollar", "Sacagawea Dollar", MatchExact},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			got, how, ok := CanonicalCoinType(tc.input)
+			if !ok {
+				t.Fatalf("CanonicalCoinType(%q) did not resolve", tc.input)
+			}
+			if got != tc.want || how != tc.how {
+				t.Errorf("CanonicalCoinType(%q) = %q (%s), want %q (%s)", tc.input, got, how, tc.want, tc.how)
+			}
+		})
+	}
+}
+
+func TestEveryCatalogTypeHasSeriesReference(t *testing.T) {
+	for _, name := range catalogNames() {
+		if _, ok := SeriesReference[name]; !ok {
+			t.Errorf("%q has no entry in SeriesReference", name)
+		}
+	}
+}
+
+func TestValidateCoinIssue(t *testing.T) {
+	tests := []struct {
+		coinType     string
+		year         int
+		denomination string
+		valid        bool
+	}{
+		{"Morgan Dollar", 1921, "$1", true},
+		{"Morgan Dollar", 1910, "", false},
+		{"Morgan Dollar", 1921, "50C", false},
+		{"Mercury Dime", 1942, "10¢", true},
+		{"Mercury Dime", 1942, "Dime", true},
+		{"1964 Kennedy Half Dollar MS65", 1964, "Half Dollar", true},
+		{"Roosevelt Dime", 2024, "", true},
+		{"Steel Penny", 1944, "", false},
+		{"Krugerrand", 1975, "", true},
+		{"Isabella Quarter", 1894, "", false},
+		{"Commemorative $5 Gold", 1988, "$5", true},
+		{"American Buffalo (Gold 1/4 oz)", 2009, "", false},
+		{"First Spouse Gold", 2012, "$10", true},
+		{"Not A Real Coin", 1066, "$3", true},
+	}
+
+	for _, tc := range tests {
+		err := ValidateCoinIssue(tc.coinType, tc.year, tc.denomination)
+		if (err == nil) != tc.valid {
+			t.Errorf("ValidateCoinIssue(%q, %d, %q) = %v, want valid=%v", tc.coinType, tc.year, tc.denomination, err, tc.valid)
+		}
+	}
+}
+
+func TestStrikeVariants(t *testing.T) {
+	tests := []struct {
+		coinType   string
+		year       int
+		strikeType string
+		metalType  string
+		purity     float64
+	}{
+		{"Washington Quarter", 1999, StrikeSilverProof, "silver", 90},
+		{"Washington Quarter", 1999, StrikeProof, "copper", 0},
+		{"Washington Quarter", 2021, StrikeSilverProof, "silver", 99.9},
+		{"Washington Quarter", 1976, StrikeSilverUncirculated, "silver", 40},
+		{"Kennedy Half Dollar", 1966, StrikeSMS, "silver", 40},
+		{"Roosevelt Dime", 1960, StrikeProof, "silver", 90},
+		{"Eisenhower Dollar", 1974, StrikeBusiness, "copper", 0},
+		{"Eisenhower Dollar", 1974, StrikeSilverProof, "silver", 40},
+		{"1999-S Silver Proof Washington Quarter PR69DCAM", 1999, StrikeSilverProof, "silver", 90},
+	}
+
+	for _, tc := range tests {
+		match, ok := MatchStrikeComposition(tc.coinType, tc.year, tc.strikeType)
+		if !ok {
+			t.Errorf("%s %d %s did not resolve", tc.coinType, tc.year, tc.strikeType)
+			continue
+		}
+		if match.Composition.MetalType != tc.metalType || match.Composition.Purity != tc.purity {
+			t.Errorf("%s %d %s = %s %.1f%%, want %s %.1f%%", tc.coinType, tc.year, tc.strikeType,
+				match.Composition.MetalType, match.Composition.Purity, tc.metalType, tc.purity)
+		}
+	}
+}
+
+func TestInferStrikeType(t *testing.T) {
+	tests := map[string]string{
+		"1999-S Silver Proof Washington Quarter PR69DCAM": StrikeSilverProof,
+		"1966 SMS Kennedy Half Dollar MS65":               StrikeSMS,
+		"1975-S Roosevelt Dime PR69DCAM":                  StrikeProof,
+		"1921 Morgan Dollar MS63":                         "",
+	}
+	for input, want := range tests {
+		if got := InferStrikeType(input); got != want {
+			t.Errorf("InferStrikeType(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestIsClassicGold(t *testing.T) {
+	tests := map[string]bool{
+		"$20 Saint Gaudens":          true,
+		"1924 Saint MS65":            true,
+		"$5 Indian":                  true,
+		"American Gold Eagle (1 oz)": false,
+		"Morgan Dollar":              false,
+		"Not A Real Coin":            false,
+	}
+	for coinType, want := range tests {
+		if got := IsClassicGold(coinType); got != want {
+			t.Errorf("IsClassicGold(%q) = %v, want %v", coinType, got, want)
+		}
+	}
+}
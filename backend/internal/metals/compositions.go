@@ -1,7 +1,5 @@
 package metals
 
-import "regexp"
-
 type MetalComposition struct {
 	Name           string  // Coin type name
 	MetalType      string  // Primary metal: "silver", "gold", "copper", etc.
@@ -416,6 +414,57 @@ var CommonCompositions = map[string]MetalComposition{
 		Purity:      99.99,
 		Description: "Contains 1 troy oz of pure gold (99.99% gold - 24 karat)",
 	},
+	"American Buffalo (Gold 1/2 oz)": {
+		Name:        "American Gold Buffalo (1/2 oz)",
+		MetalType:   "gold",
+		Weight:      0.5,
+		Purity:      99.99,
+		Description: "2008 only: Contains 0.5 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+	"American Buffalo (Gold 1/4 oz)": {
+		Name:        "American Gold Buffalo (1/4 oz)",
+		MetalType:   "gold",
+		Weight:      0.25,
+		Purity:      99.99,
+		Description: "2008 only: Contains 0.25 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+	"American Buffalo (Gold 1/10 oz)": {
+		Name:        "American Gold Buffalo (1/10 oz)",
+		MetalType:   "gold",
+		Weight:      0.1,
+		Purity:      99.99,
+		Description: "2008 only: Contains 0.1 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+	"First Spouse Gold": {
+		Name:        "First Spouse $10 Gold (1/2 oz)",
+		MetalType:   "gold",
+		Weight:      0.5,
+		Purity:      99.99,
+		Description: "Contains 0.5 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+	"American Liberty High Relief Gold": {
+		Name:        "American Liberty High Relief Gold (1 oz)",
+		MetalType:   "gold",
+		Weight:      1.0,
+		Purity:      99.99,
+		Description: "$100 high relief: Contains 1 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+	"American Liberty Gold (1/10 oz)": {
+		Name:        "American Liberty Gold (1/10 oz)",
+		MetalType:   "gold",
+		Weight:      0.1,
+		Purity:      99.99,
+		Description: "$10 issue: Contains 0.1 troy oz of pure gold (99.99% gold - 24 karat)",
+	},
+
+	// Palladium Coins
+	"American Palladium Eagle": {
+		Name:        "American Palladium Eagle (1 oz)",
+		MetalType:   "palladium",
+		Weight:      1.0,
+		Purity:      99.95,
+		Description: "Contains 1 troy oz of pure palladium (99.95% palladium)",
+	},
 	"Krugerrand": {
 		Name:        "South African Krugerrand (1 oz)",
 		MetalType:   "gold",
@@ -444,37 +493,109 @@ var CommonCompositions = map[string]MetalComposition{
 		Purity:      99.9,
 		Description: "Contains 1 troy oz of pure silver (99.9% silver)",
 	},
-}
-
-func GetComposition(coinType string) (MetalComposition, bool) {
-	// Try exact match first
-	comp, exists := CommonCompositions[coinType]
-	if exists {
-		return comp, true
-	}
 
-	// Try to normalize PCGS-style names
-	// e.g., "1921-S Peace Dollar MS67" -> "Peace Dollar"
-	normalized := normalizeCoinType(coinType)
-	if normalized != coinType {
-		comp, exists = CommonCompositions[normalized]
-		if exists {
-			return comp, true
-		}
-	}
+	// Modern Commemoratives (1982+)
+	"Commemorative Silver Dollar": {
+		Name:        "Modern Commemorative Silver Dollar",
+		MetalType:   "silver",
+		Weight:      0.77344,
+		Purity:      90,
+		Description: "26.73g, contains 0.77344 oz of silver (90% silver)",
+	},
+	"Commemorative Silver Half Dollar": {
+		Name:        "Modern Commemorative Silver Half Dollar",
+		MetalType:   "silver",
+		Weight:      0.36169,
+		Purity:      90,
+		Description: "1982 Washington and 1993 Bill of Rights halves: Contains 0.36169 oz of silver (90% silver)",
+	},
+	"Commemorative Clad Half Dollar": {
+		Name:          "Modern Commemorative Clad Half Dollar",
+		MetalType:     "copper",
+		Weight:        0.0,
+		Purity:        0,
+		Description:   "Copper-nickel clad (91.67% copper, 8.33% nickel). No precious metal content",
+		IsBaseMetal:   true,
+		WeightGrams:   11.34,
+		CopperPercent: 91.67,
+		NickelPercent: 8.33,
+	},
+	"Commemorative $5 Gold": {
+		Name:        "Modern Commemorative $5 Gold",
+		MetalType:   "gold",
+		Weight:      0.24187,
+		Purity:      90,
+		Description: "8.359g, contains 0.24187 oz of gold (90% gold)",
+	},
+	"Commemorative $10 Gold": {
+		Name:        "Modern Commemorative $10 Gold",
+		MetalType:   "gold",
+		Weight:      0.48375,
+		Purity:      90,
+		Description: "16.718g (1984 Olympic, 2003 First Flight), contains 0.48375 oz of gold (90% gold)",
+	},
 
-	return MetalComposition{}, false
+	// Classic Commemoratives (1892-1954)
+	"Classic Commemorative Half Dollar": {
+		Name:        "Classic Commemorative Half Dollar",
+		MetalType:   "silver",
+		Weight:      0.36169,
+		Purity:      90,
+		Description: "1892-1954 silver halves: Contains 0.36169 oz of silver (90% silver)",
+	},
+	"Isabella Quarter": {
+		Name:        "1893 Isabella Quarter",
+		MetalType:   "silver",
+		Weight:      0.18084,
+		Purity:      90,
+		Description: "Contains 0.18084 oz of silver (90% silver)",
+	},
+	"Lafayette Dollar": {
+		Name:        "1900 Lafayette Dollar",
+		MetalType:   "silver",
+		Weight:      0.77344,
+		Purity:      90,
+		Description: "Contains 0.77344 oz of silver (90% silver)",
+	},
+	"Classic Commemorative Gold Dollar": {
+		Name:        "Classic Commemorative Gold Dollar",
+		MetalType:   "gold",
+		Weight:      0.04837,
+		Purity:      90,
+		Description: "1903-1922 gold dollars: Contains 0.04837 oz of gold (90% gold)",
+	},
+	"Classic Commemorative $2.50": {
+		Name:        "Classic Commemorative $2.50 Gold",
+		MetalType:   "gold",
+		Weight:      0.12094,
+		Purity:      90,
+		Description: "1915 Panama-Pacific and 1926 Sesquicentennial: Contains 0.12094 oz of gold (90% gold)",
+	},
+	"Panama-Pacific $50": {
+		Name:        "1915 Panama-Pacific $50 Gold",
+		MetalType:   "gold",
+		Weight:      2.41875,
+		Purity:      90,
+		Description: "Round and octagonal: Contains 2.41875 oz of gold (90% gold)",
+	},
 }
 
-// normalizeCoinType attempts to extract the base coin name from PCGS-style names
-func normalizeCoinType(coinType string) string {
-	// Remove leading year patterns like "1921 " or "1921-S "
-	normalized := regexp.MustCompile(`^\d{4}[-\s]?[A-Z]?\s*`).ReplaceAllString(coinType, "")
-	// Remove trailing grade patterns like " MS67" or " PR70DCAM"
-	normalized = regexp.MustCompile(`\s+[A-Z]{2}\d+[A-Z]*$`).ReplaceAllString(normalized, "")
-	return normalized
+func GetComposition(coinType string) (MetalComposition, bool) {
+	// Resolves exact names, aliases and PCGS-style names
+	// e.g., "1921-S Peace Dollar MS67" -> "Peace Dollar"
+	return ResolveComposition(coinType, 0)
 }
 
+// GetAllCompositions returns the catalog with admin overrides applied
 func GetAllCompositions() map[string]MetalComposition {
-	return CommonCompositions
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	all := make(map[string]MetalComposition, len(CommonCompositions)+len(overrides))
+	for name, comp := range CommonCompositions {
+		all[name] = comp
+	}
+	for name, comp := range overrides {
+		all[name] = comp
+	}
+	return all
 }
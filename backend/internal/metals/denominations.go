@@ -0,0 +1,222 @@
+package metals
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// YearSpan is an inclusive range of mintage years; EndYear 0 means the series
+// is still being struck
+type YearSpan struct {
+	StartYear int
+	EndYear   int
+}
+
+// SeriesInfo is reference data for a coin series
+type SeriesInfo struct {
+	Denomination string     `json:"denomination"`
+	FaceValue    float64    `json:"face_value"`
+	Currency     string     `json:"currency"`
+	Years        []YearSpan `json:"years"`
+}
+
+// SeriesReference holds the denomination, face value and mintage years of
+// each catalog coin type
+var SeriesReference = map[string]SeriesInfo{
+	// Dollars
+	"Morgan Dollar":           usd("$1", 1, YearSpan{1878, 1904}, YearSpan{1921, 1921}, YearSpan{2021, 0}),
+	"Peace Dollar":            usd("$1", 1, YearSpan{1921, 1928}, YearSpan{1934, 1935}, YearSpan{2021, 0}),
+	"Eisenhower Dollar":       usd("$1", 1, YearSpan{1971, 1978}),
+	"Susan B. Anthony Dollar": usd("$1", 1, YearSpan{1979, 1981}, YearSpan{1999, 1999}),
+	"Sacagawea Dollar":        usd("$1", 1, YearSpan{2000, 0}),
+	"Seated Liberty Dollar":   usd("$1", 1, YearSpan{1840, 1873}),
+	"Trade Dollar":            usd("$1", 1, YearSpan{1873, 1885}),
+	"Bust Dollar":             usd("$1", 1, YearSpan{1794, 1804}),
+
+	// Half dollars
+	"Walking Liberty Half Dollar": usd("50C", 0.50, YearSpan{1916, 1947}),
+	"Franklin Half Dollar":        usd("50C", 0.50, YearSpan{1948, 1963}),
+	"Kennedy Half Dollar":         usd("50C", 0.50, YearSpan{1964, 0}),
+	"Barber Half Dollar":          usd("50C", 0.50, YearSpan{1892, 1915}),
+	"Seated Liberty Half Dollar":  usd("50C", 0.50, YearSpan{1839, 1891}),
+	"Capped Bust Half Dollar":     usd("50C", 0.50, YearSpan{1807, 1839}),
+	"Draped Bust Half Dollar":     usd("50C", 0.50, YearSpan{1796, 1807}),
+
+	// Quarters
+	"Washington Quarter":       usd("25C", 0.25, YearSpan{1932, 0}),
+	"Standing Liberty Quarter": usd("25C", 0.25, YearSpan{1916, 1930}),
+	"Barber Quarter":           usd("25C", 0.25, YearSpan{1892, 1916}),
+	"Seated Liberty Quarter":   usd("25C", 0.25, YearSpan{1838, 1891}),
+	"Draped Bust Quarter":      usd("25C", 0.25, YearSpan{1796, 1807}),
+	"Capped Bust Quarter":      usd("25C", 0.25, YearSpan{1815, 1838}),
+
+	// Dimes and half dimes
+	"Mercury Dime":             usd("10C", 0.10, YearSpan{1916, 1945}),
+	"Roosevelt Dime":           usd("10C", 0.10, YearSpan{1946, 0}),
+	"Barber Dime":              usd("10C", 0.10, YearSpan{1892, 1916}),
+	"Seated Liberty Half Dime": usd("H10C", 0.05, YearSpan{1837, 1873}),
+	"Bust Half Dime":           usd("H10C", 0.05, YearSpan{1794, 1837}),
+	"Three Cent Silver":        usd("3C", 0.03, YearSpan{1851, 1873}),
+
+	// Nickels and cents
+	"Buffalo Nickel":                    usd("5C", 0.05, YearSpan{1913, 1938}),
+	"Jefferson Nickel":                  usd("5C", 0.05, YearSpan{1938, 0}),
+	"Jefferson Nickel (Wartime Silver)": usd("5C", 0.05, YearSpan{1942, 1945}),
+	"Liberty Nickel":                    usd("5C", 0.05, YearSpan{1883, 1913}),
+	"Shield Nickel":                     usd("5C", 0.05, YearSpan{1866, 1883}),
+	"Indian Head Cent":                  usd("1C", 0.01, YearSpan{1859, 1909}),
+	"Lincoln Cent":                      usd("1C", 0.01, YearSpan{1909, 0}),
+	"Wheat Penny":                       usd("1C", 0.01, YearSpan{1909, 1958}),
+	"Steel Penny":                       usd("1C", 0.01, YearSpan{1943, 1943}),
+
+	// US gold
+	"$20 Liberty":                       usd("$20", 20, YearSpan{1850, 1907}),
+	"$20 Saint Gaudens":                 usd("$20", 20, YearSpan{1907, 1933}),
+	"$10 Liberty":                       usd("$10", 10, YearSpan{1838, 1907}),
+	"$10 Indian":                        usd("$10", 10, YearSpan{1907, 1933}),
+	"$5 Liberty":                        usd("$5", 5, YearSpan{1839, 1908}),
+	"$5 Indian":                         usd("$5", 5, YearSpan{1908, 1929}),
+	"$2.50 Liberty":                     usd("$2.50", 2.50, YearSpan{1840, 1907}),
+	"$2.50 Indian":                      usd("$2.50", 2.50, YearSpan{1908, 1929}),
+	"$1 Liberty":                        usd("G$1", 1, YearSpan{1849, 1889}),
+	"American Gold Eagle (1 oz)":        usd("$50", 50, YearSpan{1986, 0}),
+	"American Gold Eagle (1/2 oz)":      usd("$25", 25, YearSpan{1986, 0}),
+	"American Gold Eagle (1/4 oz)":      usd("$10", 10, YearSpan{1986, 0}),
+	"American Gold Eagle (1/10 oz)":     usd("$5", 5, YearSpan{1986, 0}),
+	"American Buffalo (Gold)":           usd("$50", 50, YearSpan{2006, 0}),
+	"American Buffalo (Gold 1/2 oz)":    usd("$25", 25, YearSpan{2008, 2008}),
+	"American Buffalo (Gold 1/4 oz)":    usd("$10", 10, YearSpan{2008, 2008}),
+	"American Buffalo (Gold 1/10 oz)":   usd("$5", 5, YearSpan{2008, 2008}),
+	"First Spouse Gold":                 usd("$10", 10, YearSpan{2007, 2016}, YearSpan{2020, 2020}),
+	"American Liberty High Relief Gold": usd("$100", 100, YearSpan{2015, 2015}, YearSpan{2017, 2017}, YearSpan{2019, 2019}, YearSpan{2021, 2021}),
+	"American Liberty Gold (1/10 oz)":   usd("$10", 10, YearSpan{2017, 0}),
+	"American Palladium Eagle":          usd("$25", 25, YearSpan{2017, 0}),
+
+	// Bullion
+	"American Silver Eagle":        usd("$1", 1, YearSpan{1986, 0}),
+	"Canadian Maple Leaf (Gold)":   {Denomination: "$50 CAD", FaceValue: 50, Currency: "CAD", Years: []YearSpan{{1979, 0}}},
+	"Canadian Maple Leaf (Silver)": {Denomination: "$5 CAD", FaceValue: 5, Currency: "CAD", Years: []YearSpan{{1988, 0}}},
+	"Krugerrand":                   {Denomination: "None", Currency: "ZAR", Years: []YearSpan{{1967, 0}}},
+	"Vienna Philharmonic (Gold)":   {Denomination: "€100", FaceValue: 100, Currency: "EUR", Years: []YearSpan{{1989, 0}}},
+	"Britannia (Gold)":             {Denomination: "£100", FaceValue: 100, Currency: "GBP", Years: []YearSpan{{1987, 0}}},
+	"Britannia (Silver)":           {Denomination: "£2", FaceValue: 2, Currency: "GBP", Years: []YearSpan{{1997, 0}}},
+	// Commemoratives
+	"Commemorative Silver Dollar":       usd("$1", 1, YearSpan{1983, 0}),
+	"Commemorative Silver Half Dollar":  usd("50C", 0.50, YearSpan{1982, 1982}, YearSpan{1993, 1993}),
+	"Commemorative Clad Half Dollar":    usd("50C", 0.50, YearSpan{1986, 0}),
+	"Commemorative $5 Gold":             usd("$5", 5, YearSpan{1986, 0}),
+	"Commemorative $10 Gold":            usd("$10", 10, YearSpan{1984, 1984}, YearSpan{2003, 2003}),
+	"Classic Commemorative Half Dollar": usd("50C", 0.50, YearSpan{1892, 1893}, YearSpan{1915, 1954}),
+	"Isabella Quarter":                  usd("25C", 0.25, YearSpan{1893, 1893}),
+	"Lafayette Dollar":                  usd("$1", 1, YearSpan{1900, 1900}),
+	"Classic Commemorative Gold Dollar": usd("G$1", 1, YearSpan{1903, 1905}, YearSpan{1915, 1917}, YearSpan{1922, 1922}),
+	"Classic Commemorative $2.50":       usd("$2.50", 2.50, YearSpan{1915, 1915}, YearSpan{1926, 1926}),
+	"Panama-Pacific $50":                usd("$50", 50, YearSpan{1915, 1915}),
+}
+
+// classicGoldSeries are the pre-1934 US gold series, which trade at
+// grade-driven premiums well above melt
+var classicGoldSeries = map[string]bool{
+	"$20 Liberty":       true,
+	"$20 Saint Gaudens": true,
+	"$10 Liberty":       true,
+	"$10 Indian":        true,
+	"$5 Liberty":        true,
+	"$5 Indian":         true,
+	"$2.50 Liberty":     true,
+	"$2.50 Indian":      true,
+	"$1 Liberty":        true,
+}
+
+// IsClassicGold reports whether a coin type is a pre-1934 US gold series
+func IsClassicGold(coinType string) bool {
+	name, _, ok := CanonicalCoinType(coinType)
+	return ok && classicGoldSeries[name]
+}
+
+func usd(denomination string, faceValue float64, years ...YearSpan) SeriesInfo {
+	return SeriesInfo{Denomination: denomination, FaceValue: faceValue, Currency: "USD", Years: years}
+}
+
+// LookupSeries returns reference data for a coin type, resolving aliases and
+// PCGS-style names like the composition lookup does
+func LookupSeries(coinType string) (string, SeriesInfo, bool) {
+	name, _, ok := CanonicalCoinType(coinType)
+	if !ok {
+		return "", SeriesInfo{}, false
+	}
+	info, ok := SeriesReference[name]
+	return name, info, ok
+}
+
+// Minted reports whether the series was struck in the given year
+func (s SeriesInfo) Minted(year int) bool {
+	for _, span := range s.Years {
+		if year >= span.StartYear && (span.EndYear == 0 || year <= span.EndYear) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	dollarPattern = regexp.MustCompile(`^[a-z]*\$\s*(\d+(?:\.\d+)?)`)
+	centPattern   = regexp.MustCompile(`^(\d+)\s*(?:c|¢|cents?)$`)
+)
+
+// namedDenominations maps spelled-out US denominations to face values
+var namedDenominations = map[string]float64{
+	"cent": 0.01, "penny": 0.01, "one cent": 0.01,
+	"three cent": 0.03, "three cents": 0.03,
+	"nickel": 0.05, "five cent": 0.05, "five cents": 0.05, "half dime": 0.05, "h10c": 0.05,
+	"dime": 0.10, "ten cent": 0.10, "ten cents": 0.10,
+	"quarter": 0.25, "quarter dollar": 0.25, "twenty-five cent": 0.25,
+	"half": 0.50, "half dollar": 0.50, "fifty cent": 0.50,
+	"dollar": 1, "one dollar": 1, "silver dollar": 1, "gold dollar": 1,
+	"quarter eagle": 2.50, "half eagle": 5, "eagle": 10, "double eagle": 20,
+}
+
+// ParseDenomination converts a US denomination such as "$1", "50C", "25¢" or
+// "Half Dollar" to its face value in dollars
+func ParseDenomination(denomination string) (float64, bool) {
+	value := strings.ToLower(strings.TrimSpace(denomination))
+	if value == "" {
+		return 0, false
+	}
+	if face, ok := namedDenominations[value]; ok {
+		return face, true
+	}
+	if matches := dollarPattern.FindStringSubmatch(value); matches != nil {
+		face, err := strconv.ParseFloat(matches[1], 64)
+		return face, err == nil
+	}
+	if matches := centPattern.FindStringSubmatch(value); matches != nil {
+		cents, err := strconv.Atoi(matches[1])
+		return float64(cents) / 100, err == nil
+	}
+	return 0, false
+}
+
+// ValidateCoinIssue checks that a known series was struck in the given year
+// and that a US denomination, when given, matches the series. Unknown coin
+// types and missing years are not validated.
+func ValidateCoinIssue(coinType string, year int, denomination string) error {
+	name, info, ok := LookupSeries(coinType)
+	if !ok {
+		return nil
+	}
+
+	if year > 0 && !info.Minted(year) {
+		return fmt.Errorf("%s was not struck in %d", name, year)
+	}
+
+	if info.Currency == "USD" && info.FaceValue > 0 {
+		if face, ok := ParseDenomination(denomination); ok && face != info.FaceValue {
+			return fmt.Errorf("%s has a face value of %s, not %s", name, info.Denomination, denomination)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package metals
+
+import (
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Metals are the metals spot prices are tracked for
+var Metals = []string{"gold", "silver", "platinum", "palladium", "copper", "nickel"}
+
+// Where a fallback price comes from, lowest precedence first
+const (
+	FallbackBuiltin = "builtin" // shipped with the release
+	FallbackEnv     = "env"     // FALLBACK_SPOT_PRICES
+	FallbackAdmin   = "admin"   // set through the admin API
+)
+
+// builtinFallbacks are the fallback prices shipped with the release: USD per
+// troy ounce, per pound for copper and nickel
+var builtinFallbacks = map[string]float64{
+	"gold":      2650.00,
+	"silver":    30.50,
+	"platinum":  950.00,
+	"palladium": 950.00,
+	"copper":    5.52,
+	"nickel":    6.96,
+}
+
+// builtinFallbacksReviewed is when builtinFallbacks were last checked
+// against the market
+var builtinFallbacksReviewed = time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+// FallbackPrice is what a metal is priced at when no live source quotes it
+type FallbackPrice struct {
+	Metal      string     `json:"metal"`
+	Price      float64    `json:"price"`
+	Unit       string     `json:"unit"` // "troy_oz" or "lb"
+	Source     string     `json:"source"`
+	ReviewedAt *time.Time `json:"reviewed_at"` // unknown for env prices without FALLBACK_SPOT_PRICES_REVIEWED
+}
+
+var (
+	fallbacksMu    sync.RWMutex
+	envFallbacks   map[string]FallbackPrice
+	envOnce        sync.Once
+	adminFallbacks = map[string]FallbackPrice{}
+)
+
+// IsMetal reports whether metal is one of Metals
+func IsMetal(metal string) bool {
+	return slices.Contains(Metals, metal)
+}
+
+// parseFallbackPrices reads FALLBACK_SPOT_PRICES, e.g. "gold=2650,silver=30.5",
+// with reviewed as the date they were checked (YYYY-MM-DD, optional)
+func parseFallbackPrices(spec, reviewed string) map[string]FallbackPrice {
+	var reviewedAt *time.Time
+	if reviewed = strings.TrimSpace(reviewed); reviewed != "" {
+		if t, err := time.Parse("2006-01-02", reviewed); err == nil {
+			reviewedAt = &t
+		} else {
+			log.Printf("⚠ Ignoring FALLBACK_SPOT_PRICES_REVIEWED %q, want YYYY-MM-DD", reviewed)
+		}
+	}
+
+	parsed := map[string]FallbackPrice{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		metal, value, ok := strings.Cut(entry, "=")
+		metal = strings.ToLower(strings.TrimSpace(metal))
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || !IsMetal(metal) || err != nil || price <= 0 {
+			log.Printf("⚠ Ignoring FALLBACK_SPOT_PRICES entry %q", entry)
+			continue
+		}
+		parsed[metal] = FallbackPrice{Metal: metal, Price: price, Unit: PriceUnit(metal), Source: FallbackEnv, ReviewedAt: reviewedAt}
+	}
+	return parsed
+}
+
+func envFallbackPrices() map[string]FallbackPrice {
+	envOnce.Do(func() {
+		envFallbacks = parseFallbackPrices(config.String("FALLBACK_SPOT_PRICES", ""), config.String("FALLBACK_SPOT_PRICES_REVIEWED", ""))
+	})
+	return envFallbacks
+}
+
+func fromStored(stored models.FallbackPrice) FallbackPrice {
+	reviewedAt := stored.ReviewedAt
+	return FallbackPrice{Metal: stored.Metal, Price: stored.Price, Unit: PriceUnit(stored.Metal), Source: FallbackAdmin, ReviewedAt: &reviewedAt}
+}
+
+// FallbackPriceFor returns a metal's fallback price: the admin's if set, else
+// FALLBACK_SPOT_PRICES', else the built-in one
+func FallbackPriceFor(metal string) FallbackPrice {
+	fallbacksMu.RLock()
+	admin, ok := adminFallbacks[metal]
+	fallbacksMu.RUnlock()
+	if ok {
+		return admin
+	}
+	if env, ok := envFallbackPrices()[metal]; ok {
+		return env
+	}
+	reviewed := builtinFallbacksReviewed
+	return FallbackPrice{Metal: metal, Price: builtinFallbacks[metal], Unit: PriceUnit(metal), Source: FallbackBuiltin, ReviewedAt: &reviewed}
+}
+
+// FallbackPrices returns the fallback price of every metal, in Metals order
+func FallbackPrices() []FallbackPrice {
+	result := make([]FallbackPrice, len(Metals))
+	for i, metal := range Metals {
+		result[i] = FallbackPriceFor(metal)
+	}
+	return result
+}
+
+// LoadFallbackPrices replaces the admin-set fallback prices, e.g. with the
+// ones stored in the database at startup
+func LoadFallbackPrices(stored []models.FallbackPrice) {
+	loaded := make(map[string]FallbackPrice, len(stored))
+	for _, p := range stored {
+		loaded[p.Metal] = fromStored(p)
+	}
+
+	fallbacksMu.Lock()
+	adminFallbacks = loaded
+	fallbacksMu.Unlock()
+	for _, metal := range Metals {
+		repriceCachedFallback(metal)
+	}
+}
+
+// SetFallbackPrice adds or replaces a metal's admin-set fallback price
+func SetFallbackPrice(stored models.FallbackPrice) {
+	fallbacksMu.Lock()
+	adminFallbacks[stored.Metal] = fromStored(stored)
+	fallbacksMu.Unlock()
+	repriceCachedFallback(stored.Metal)
+}
+
+// RemoveFallbackPrice drops a metal's admin-set fallback price, restoring the
+// FALLBACK_SPOT_PRICES or built-in one
+func RemoveFallbackPrice(metal string) {
+	fallbacksMu.Lock()
+	delete(adminFallbacks, metal)
+	fallbacksMu.Unlock()
+	repriceCachedFallback(metal)
+}
+
+// repriceCachedFallback updates the cached spot prices when they serve
+// metal from its fallback price, so a new one applies before the next refresh
+func repriceCachedFallback(metal string) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+
+	if cachedPrices == nil || !slices.Contains(cachedPrices.FallbackMetals, metal) {
+		return
+	}
+	// Callers may hold the old prices, so they're copied rather than changed
+	updated := *cachedPrices
+	*updated.field(metal) = FallbackPriceFor(metal).Price
+	cachedPrices = &updated
+}
@@ -0,0 +1,47 @@
+package metals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestParseFallbackPrices(t *testing.T) {
+	parsed := parseFallbackPrices("gold=2700, Silver=31.25, unobtainium=5, copper=-1", "2026-09-15")
+
+	if len(parsed) != 2 {
+		t.Fatalf("parsed %d prices, want gold and silver: %v", len(parsed), parsed)
+	}
+	silver := parsed["silver"]
+	if silver.Price != 31.25 || silver.Source != FallbackEnv || silver.Unit != UnitTroyOunce {
+		t.Errorf("silver = %+v", silver)
+	}
+	if silver.ReviewedAt == nil || !silver.ReviewedAt.Equal(time.Date(2026, time.September, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("reviewed at %v, want 2026-09-15", silver.ReviewedAt)
+	}
+	if parsed := parseFallbackPrices("gold=2700", ""); parsed["gold"].ReviewedAt != nil {
+		t.Error("prices without a review date should say so")
+	}
+}
+
+func TestAdminFallbackPriceTakesPrecedence(t *testing.T) {
+	t.Cleanup(func() { LoadFallbackPrices(nil) })
+
+	reviewed := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	SetFallbackPrice(models.FallbackPrice{Metal: "platinum", Price: 1010, ReviewedAt: reviewed})
+	if p := FallbackPriceFor("platinum"); p.Price != 1010 || p.Source != FallbackAdmin {
+		t.Errorf("platinum = %+v, want the admin's price", p)
+	}
+
+	prices := &SpotPrices{Gold: 2400, Silver: 29}
+	fillFallbacks(prices)
+	if prices.Platinum != 1010 {
+		t.Errorf("filled platinum = %.2f, want 1010", prices.Platinum)
+	}
+
+	RemoveFallbackPrice("platinum")
+	if p := FallbackPriceFor("platinum"); p.Source != FallbackBuiltin || p.Price != builtinFallbacks["platinum"] {
+		t.Errorf("after removal platinum = %+v, want the built-in price", p)
+	}
+}
@@ -0,0 +1,102 @@
+package metals
+
+// Ways a coin type can be matched to a composition
+const (
+	MatchYearRange   = "year_range"   // year-based rule, year within a range
+	MatchYearDefault = "year_default" // year-based rule, year outside every range
+	MatchExact       = "exact"        // coin type is a catalog name
+	MatchNormalized  = "normalized"   // catalog name after stripping year/grade
+	MatchAlias       = "alias"        // nickname or abbreviation from SeriesAliases
+	MatchStrike      = "strike"       // special-strike variant (e.g. silver proof)
+)
+
+// Other sources of a coin's stored composition
+const (
+	CompositionSourceManual    = "manual"    // metal fields entered by the user
+	CompositionSourceConfirmed = "confirmed" // guessed composition reviewed by the user
+)
+
+// How sure an auto-populated composition is; anything below high should be
+// reviewed by the user
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium" // composition varies by year but the year is unknown
+	ConfidenceLow    = "low"    // matched only after stripping year/grade from the name
+)
+
+// CompositionMatch records which catalog entry a coin type resolved to and how
+type CompositionMatch struct {
+	Composition MetalComposition `json:"composition"`
+	MatchedName string           `json:"matched_name"`
+	Method      string           `json:"method"`
+	Confidence  string           `json:"confidence"`
+}
+
+// ResolveComposition finds the composition for a coin type, using the
+// year-based rules when a year is known
+func ResolveComposition(coinType string, year int) (MetalComposition, bool) {
+	match, ok := MatchComposition(coinType, year)
+	return match.Composition, ok
+}
+
+// MatchComposition resolves a coin type like ResolveComposition, also
+// reporting the catalog entry and lookup method that produced the result
+func MatchComposition(coinType string, year int) (CompositionMatch, bool) {
+	return MatchStrikeComposition(coinType, year, "")
+}
+
+// MatchStrikeComposition is MatchComposition for a known strike type, so that
+// e.g. silver proofs of clad series resolve to their silver composition
+func MatchStrikeComposition(coinType string, year int, strikeType string) (CompositionMatch, bool) {
+	name, nameMatch, ok := CanonicalCoinType(coinType)
+	if !ok {
+		return CompositionMatch{}, false
+	}
+
+	match := CompositionMatch{MatchedName: name, Confidence: ConfidenceHigh}
+	ybc, yearBased := yearBasedRule(name)
+	if comp, ok := strikeVariant(name, year, strikeType); ok {
+		match.Composition, match.Method = comp, MatchStrike
+	} else if yearBased && year > 0 {
+		match.Composition, match.Method = ybc.DefaultComp, MatchYearDefault
+		if comp, ok := overrideFor(name); ok {
+			match.Composition = comp
+		}
+		for _, yr := range ybc.YearRanges {
+			if year >= yr.StartYear && year <= yr.EndYear {
+				match.Composition, match.Method = yr.Composition, MatchYearRange
+				break
+			}
+		}
+	} else {
+		comp, inCatalog := catalogComposition(name)
+		if !inCatalog {
+			// Series that only exist as year-based rules
+			comp = ybc.DefaultComp
+		}
+		match.Composition, match.Method = comp, MatchExact
+		if yearBased {
+			match.Confidence = ConfidenceMedium
+		}
+	}
+
+	switch nameMatch {
+	case MatchAlias:
+		if match.Method != MatchStrike {
+			match.Method = MatchAlias
+		}
+	case MatchNormalized:
+		match.Method, match.Confidence = MatchNormalized, ConfidenceLow
+	}
+
+	return match, true
+}
+
+func yearBasedRule(coinType string) (YearBasedComposition, bool) {
+	for _, ybc := range YearBasedCompositions {
+		if ybc.CoinType == coinType {
+			return ybc, true
+		}
+	}
+	return YearBasedComposition{}, false
+}
@@ -0,0 +1,42 @@
+package metals
+
+import "testing"
+
+func TestMatchComposition(t *testing.T) {
+	tests := []struct {
+		name       string
+		coinType   string
+		year       int
+		wantName   string
+		method     string
+		confidence string
+		purity     float64
+	}{
+		{"exact", "Morgan Dollar", 0, "Morgan Dollar", MatchExact, ConfidenceHigh, 90},
+		{"alias", "Walker", 0, "Walking Liberty Half Dollar", MatchAlias, ConfidenceHigh, 90},
+		{"normalized", "1921-S Peace Dollar MS67", 0, "Peace Dollar", MatchNormalized, ConfidenceLow, 90},
+		{"year in range", "Kennedy Half Dollar", 1964, "Kennedy Half Dollar", MatchYearRange, ConfidenceHigh, 90},
+		{"year in later range", "Kennedy Half Dollar", 1967, "Kennedy Half Dollar", MatchYearRange, ConfidenceHigh, 40},
+		{"year outside ranges", "Kennedy Half Dollar", 1990, "Kennedy Half Dollar", MatchYearDefault, ConfidenceHigh, 0},
+		{"year unknown", "Kennedy Half Dollar", 0, "Kennedy Half Dollar", MatchExact, ConfidenceMedium, -1},
+		{"wartime nickel", "Jefferson Nickel", 1943, "Jefferson Nickel", MatchYearRange, ConfidenceHigh, 35},
+		{"alias with year", "jfk half", 1964, "Kennedy Half Dollar", MatchAlias, ConfidenceHigh, 90},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			match, ok := MatchComposition(tc.coinType, tc.year)
+			if !ok {
+				t.Fatalf("MatchComposition(%q, %d) did not resolve", tc.coinType, tc.year)
+			}
+			if match.MatchedName != tc.wantName || match.Method != tc.method || match.Confidence != tc.confidence {
+				t.Errorf("MatchComposition(%q, %d) = %s by %s (%s), want %s by %s (%s)",
+					tc.coinType, tc.year, match.MatchedName, match.Method, match.Confidence,
+					tc.wantName, tc.method, tc.confidence)
+			}
+			if tc.purity >= 0 && match.Composition.Purity != tc.purity {
+				t.Errorf("purity = %v, want %v", match.Composition.Purity, tc.purity)
+			}
+		})
+	}
+}
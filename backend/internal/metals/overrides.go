@@ -0,0 +1,92 @@
+package metals
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Admin overrides layered over CommonCompositions, keyed by coin type. They
+// fix or add catalog entries without waiting for a release.
+var (
+	overridesMu sync.RWMutex
+	overrides   = map[string]MetalComposition{}
+)
+
+// FromOverride converts a stored override to a catalog composition
+func FromOverride(o models.CompositionOverride) MetalComposition {
+	return MetalComposition{
+		Name:          o.CoinType,
+		MetalType:     o.MetalType,
+		Weight:        o.Weight,
+		Purity:        o.Purity,
+		Description:   o.Description,
+		IsBaseMetal:   o.IsBaseMetal,
+		WeightGrams:   o.WeightGrams,
+		CopperPercent: o.CopperPercent,
+		NickelPercent: o.NickelPercent,
+	}
+}
+
+// LoadOverrides replaces every override, e.g. with the ones stored in the
+// database at startup
+func LoadOverrides(stored []models.CompositionOverride) {
+	loaded := make(map[string]MetalComposition, len(stored))
+	for _, o := range stored {
+		loaded[o.CoinType] = FromOverride(o)
+	}
+
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides = loaded
+}
+
+// SetOverride adds or replaces the override for a coin type
+func SetOverride(o models.CompositionOverride) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	overrides[o.CoinType] = FromOverride(o)
+}
+
+// RemoveOverride drops a coin type's override, restoring the built-in
+// composition if there is one
+func RemoveOverride(coinType string) {
+	overridesMu.Lock()
+	defer overridesMu.Unlock()
+	delete(overrides, coinType)
+}
+
+// BuiltinComposition returns the composition shipped with the release for a
+// catalog name, ignoring overrides
+func BuiltinComposition(coinType string) (MetalComposition, bool) {
+	comp, ok := CommonCompositions[coinType]
+	return comp, ok
+}
+
+func overrideFor(coinType string) (MetalComposition, bool) {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	comp, ok := overrides[coinType]
+	return comp, ok
+}
+
+// catalogComposition looks up a catalog name, preferring its override
+func catalogComposition(coinType string) (MetalComposition, bool) {
+	if comp, ok := overrideFor(coinType); ok {
+		return comp, true
+	}
+	comp, ok := CommonCompositions[coinType]
+	return comp, ok
+}
+
+// overrideNames indexes the coin types of every override by lowercase name
+func overrideNames() map[string]string {
+	overridesMu.RLock()
+	defer overridesMu.RUnlock()
+	names := make(map[string]string, len(overrides))
+	for name := range overrides {
+		names[strings.ToLower(name)] = name
+	}
+	return names
+}
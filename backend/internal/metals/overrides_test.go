@@ -0,0 +1,32 @@
+package metals
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestOverridesLayerOverCatalog(t *testing.T) {
+	t.Cleanup(func() { LoadOverrides(nil) })
+
+	builtin, _ := BuiltinComposition("Morgan Dollar")
+	LoadOverrides([]models.CompositionOverride{
+		{CoinType: "Morgan Dollar", MetalType: "silver", Weight: 0.7734, Purity: 90},
+		{CoinType: "Hawaii Dollar", MetalType: "silver", Weight: 0.7734, Purity: 90},
+	})
+
+	if comp, ok := GetComposition("1921-S Morgan Dollar MS63"); !ok || comp.Weight != 0.7734 {
+		t.Errorf("Morgan Dollar = %+v, want the override", comp)
+	}
+	if name, how, ok := CanonicalCoinType("hawaii dollar"); !ok || name != "Hawaii Dollar" || how != MatchExact {
+		t.Errorf("CanonicalCoinType(hawaii dollar) = %q, %q, %v", name, how, ok)
+	}
+	if len(GetAllCompositions()) != len(CommonCompositions)+1 {
+		t.Error("an override for a new coin type should add it to the catalog")
+	}
+
+	RemoveOverride("Morgan Dollar")
+	if comp, _ := GetComposition("Morgan Dollar"); comp != builtin {
+		t.Errorf("removing the override should restore %+v, got %+v", builtin, comp)
+	}
+}
@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/usage"
 )
 
 type SpotPrices struct {
@@ -13,61 +18,216 @@ type SpotPrices struct {
 	Silver    float64   `json:"silver"`
 	Platinum  float64   `json:"platinum"`
 	Palladium float64   `json:"palladium"`
-	Copper    float64   `json:"copper"`    // USD per pound
-	Nickel    float64   `json:"nickel"`    // USD per pound
+	Copper    float64   `json:"copper"` // USD per pound
+	Nickel    float64   `json:"nickel"` // USD per pound
 	UpdatedAt time.Time `json:"updated_at"`
+	// Degraded is set when some metals are priced from the built-in
+	// fallbacks rather than a live source; FallbackMetals lists them
+	Degraded       bool     `json:"degraded"`
+	FallbackMetals []string `json:"fallback_metals,omitempty"`
+	// Currency and Units say what the prices are in: USD per troy ounce, or
+	// per pound for copper and nickel
+	Currency string            `json:"currency"`
+	Units    map[string]string `json:"units"`
 }
 
-type MetalsAPIResponse struct {
-	Success   bool               `json:"success"`
-	Timestamp int64              `json:"timestamp"`
-	Base      string             `json:"base"`
-	Date      string             `json:"date"`
-	Rates     map[string]float64 `json:"rates"`
+// fillFallbacks prices the metals prices is missing from their fallback
+// prices and flags them. Every price set that's cached passes through here,
+// so it also labels their currency and units.
+func fillFallbacks(prices *SpotPrices) {
+	prices.Currency, prices.Units = Currency, priceUnits()
+	prices.FallbackMetals = nil
+	for _, metal := range Metals {
+		price := prices.field(metal)
+		if *price <= 0 {
+			*price = FallbackPriceFor(metal).Price
+			prices.FallbackMetals = append(prices.FallbackMetals, metal)
+		}
+	}
+	prices.Degraded = len(prices.FallbackMetals) > 0
+}
+
+// field returns a pointer to a metal's price
+func (p *SpotPrices) field(metal string) *float64 {
+	switch metal {
+	case "gold":
+		return &p.Gold
+	case "silver":
+		return &p.Silver
+	case "platinum":
+		return &p.Platinum
+	case "palladium":
+		return &p.Palladium
+	case "copper":
+		return &p.Copper
+	case "nickel":
+		return &p.Nickel
+	}
+	return nil
 }
 
 var cachedPrices *SpotPrices
 var lastFetchTime time.Time
 
+// fallbackSince is when each metal currently served from its fallback price
+// started to be, and fallbackAlerted the ones admins were warned about
+var (
+	fallbackSince   = map[string]time.Time{}
+	fallbackAlerted = map[string]bool{}
+)
+
+// priceMu guards the price cache, which is refreshed by both handlers and the scheduler
+var priceMu sync.Mutex
+
 const cacheDuration = 15 * time.Minute
 
+const defaultFallbackAlertAfter = 6 * time.Hour
+
 func GetSpotPrices() (*SpotPrices, error) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+
 	if cachedPrices != nil && time.Since(lastFetchTime) < cacheDuration {
 		return cachedPrices, nil
 	}
 
+	return refreshLocked()
+}
+
+// RefreshSpotPrices fetches new prices regardless of the cache age
+func RefreshSpotPrices() (*SpotPrices, error) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+
+	return refreshLocked()
+}
+
+// refreshLocked refills the cache and publishes SpotPricesRefreshed.
+// Callers must hold priceMu.
+func refreshLocked() (*SpotPrices, error) {
 	realPrices, err := fetchRealPrices()
 	if err == nil && realPrices != nil {
 		fmt.Printf("✓ Fetched live spot prices: Gold=$%.2f, Silver=$%.2f\n", realPrices.Gold, realPrices.Silver)
+		addBaseMetals(realPrices)
+		fillFallbacks(realPrices)
+		previous := cachedPrices
 		cachedPrices = realPrices
 		lastFetchTime = time.Now()
+		trackFallbacks(realPrices.FallbackMetals, lastFetchTime)
+		publishRefresh(realPrices, previous, false)
 		return realPrices, nil
 	}
 
 	fmt.Printf("⚠ Using fallback prices (live fetch failed: %v)\n", err)
-	prices := &SpotPrices{
-		Gold:      2650.00, // USD per troy ounce (updated Dec 2025)
-		Silver:    30.50,   // USD per troy ounce (updated Dec 2025)
-		Platinum:  950.00,
-		Palladium: 950.00,
-		Copper:    5.52,  // USD per pound (updated Dec 2025)
-		Nickel:    6.96,  // USD per pound (updated Dec 2025)
-		UpdatedAt: time.Now(),
-	}
+	prices := &SpotPrices{UpdatedAt: time.Now()}
+	addBaseMetals(prices)
+	fillFallbacks(prices)
 
+	previous := cachedPrices
 	cachedPrices = prices
 	lastFetchTime = time.Now()
+	trackFallbacks(prices.FallbackMetals, lastFetchTime)
+	publishRefresh(prices, previous, true)
 
 	return prices, nil
 }
 
+// trackFallbacks records which metals a refresh at now served from fallback
+// prices, and publishes SpotPricesDegraded once for the metals that have been
+// for longer than SPOT_FALLBACK_ALERT_AFTER (default 6h). A metal priced live
+// again can be alerted about anew. Callers must hold priceMu.
+func trackFallbacks(metals []string, now time.Time) {
+	served := make(map[string]bool, len(metals))
+	for _, metal := range metals {
+		served[metal] = true
+		if _, ok := fallbackSince[metal]; !ok {
+			fallbackSince[metal] = now
+		}
+	}
+	for metal := range fallbackSince {
+		if !served[metal] {
+			delete(fallbackSince, metal)
+			delete(fallbackAlerted, metal)
+		}
+	}
+
+	after := config.Duration("SPOT_FALLBACK_ALERT_AFTER", defaultFallbackAlertAfter)
+	var overdue []string
+	since := now
+	for _, metal := range metals {
+		if fallbackAlerted[metal] || now.Sub(fallbackSince[metal]) < after {
+			continue
+		}
+		fallbackAlerted[metal] = true
+		overdue = append(overdue, metal)
+		if fallbackSince[metal].Before(since) {
+			since = fallbackSince[metal]
+		}
+	}
+	if len(overdue) > 0 {
+		events.Publish(events.SpotPricesDegraded{Metals: overdue, Since: since})
+	}
+}
+
+// FallbackSince returns when each metal now served from fallback prices
+// started to be
+func FallbackSince() map[string]time.Time {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+
+	result := make(map[string]time.Time, len(fallbackSince))
+	for metal, since := range fallbackSince {
+		result[metal] = since
+	}
+	return result
+}
+
+// movedMetals lists the metals whose price differs between two price sets;
+// every priced metal has moved when there was no previous set
+func movedMetals(previous, prices *SpotPrices) []string {
+	var moved []string
+	for _, metal := range Metals {
+		price := *prices.field(metal)
+		if price <= 0 {
+			continue
+		}
+		if previous == nil || *previous.field(metal) != price {
+			moved = append(moved, metal)
+		}
+	}
+	return moved
+}
+
+func publishRefresh(prices, previous *SpotPrices, fallback bool) {
+	events.Publish(events.SpotPricesRefreshed{
+		Prices: map[string]float64{
+			"gold":      prices.Gold,
+			"silver":    prices.Silver,
+			"platinum":  prices.Platinum,
+			"palladium": prices.Palladium,
+			"copper":    prices.Copper,
+			"nickel":    prices.Nickel,
+		},
+		Moved:          movedMetals(previous, prices),
+		Fallback:       fallback,
+		FallbackMetals: prices.FallbackMetals,
+		RefreshedAt:    prices.UpdatedAt,
+	})
+}
+
 func fetchRealPrices() (*SpotPrices, error) {
+	if config.MockMode() {
+		return mockSpotPrices(), nil
+	}
+
 	goldPrice, err := fetchGoldPriceOrg()
+	usage.RecordCall(usage.ServiceGoldPrice, err)
 	if err == nil {
 		return goldPrice, nil
 	}
 
 	metalsLive, err := fetchMetalsLive()
+	usage.RecordCall(usage.ServiceMetalsLive, err)
 	if err == nil {
 		return metalsLive, nil
 	}
@@ -75,6 +235,20 @@ func fetchRealPrices() (*SpotPrices, error) {
 	return nil, fmt.Errorf("all price sources failed")
 }
 
+// mockSpotPrices returns fixed prices used when MOCK_EXTERNAL_APIS is enabled,
+// so melt values are deterministic in development and e2e tests
+func mockSpotPrices() *SpotPrices {
+	return &SpotPrices{
+		Gold:      2000.00,
+		Silver:    25.00,
+		Platinum:  1000.00,
+		Palladium: 1000.00,
+		Copper:    4.00,
+		Nickel:    8.00,
+		UpdatedAt: time.Now(),
+	}
+}
+
 func fetchGoldPriceOrg() (*SpotPrices, error) {
 	resp, err := http.Get("https://data-asg.goldprice.org/dbXRates/USD")
 	if err != nil {
@@ -109,13 +283,10 @@ func fetchGoldPriceOrg() (*SpotPrices, error) {
 		return nil, fmt.Errorf("invalid price data from goldprice.org")
 	}
 
+	// Other metals come from their fallback prices
 	return &SpotPrices{
 		Gold:      gold,
 		Silver:    silver,
-		Platinum:  950.00, // Fallback for less common metals
-		Palladium: 950.00,
-		Copper:    5.52,   // Fallback for base metals
-		Nickel:    6.96,   // Fallback for base metals
 		UpdatedAt: time.Now(),
 	}, nil
 }
@@ -166,75 +337,46 @@ func fetchMetalsLive() (*SpotPrices, error) {
 	return prices, nil
 }
 
+// CalculateMeltValue calculates melt value using the current spot prices
 func CalculateMeltValue(metalType string, weight float64, purity float64) (float64, error) {
-	prices, err := GetSpotPrices()
+	calc, err := CurrentCalculator()
 	if err != nil {
 		return 0, err
 	}
-
-	var pricePerOz float64
-	switch metalType {
-	case "gold":
-		pricePerOz = prices.Gold
-	case "silver":
-		pricePerOz = prices.Silver
-	case "platinum":
-		pricePerOz = prices.Platinum
-	case "palladium":
-		pricePerOz = prices.Palladium
-	case "copper", "nickel":
-		// Base metals are priced per pound, but weight is in troy ounces
-		// For base metal coins, we need to return 0 since the weight stored is troy oz of precious metal
-		// Base metal calculations need to be handled separately with gram weights
-		return 0, nil
-	default:
-		return 0, fmt.Errorf("unsupported metal type: %s", metalType)
-	}
-
-	pureWeight := weight * (purity / 100.0)
-	meltValue := pureWeight * pricePerOz
-
-	return meltValue, nil
+	return calc.MeltValue(metalType, weight, purity)
 }
 
 func UpdateSpotPricesManually(gold, silver, platinum, palladium float64) {
+	priceMu.Lock()
+	defer priceMu.Unlock()
+
 	cachedPrices = &SpotPrices{
 		Gold:      gold,
 		Silver:    silver,
 		Platinum:  platinum,
 		Palladium: palladium,
-		Copper:    5.52,
-		Nickel:    6.96,
 		UpdatedAt: time.Now(),
 	}
+	fillFallbacks(cachedPrices)
 	lastFetchTime = time.Now()
 }
 
 // CalculateBaseMeltValue calculates melt value for base metal coins using gram weight
-// weightGrams: total weight of coin in grams
-// copperPercent: percentage of copper (0-100)
-// nickelPercent: percentage of nickel (0-100)
+// and the current spot prices
 func CalculateBaseMeltValue(weightGrams float64, copperPercent float64, nickelPercent float64) (float64, error) {
-	prices, err := GetSpotPrices()
+	calc, err := CurrentCalculator()
 	if err != nil {
 		return 0, err
 	}
-
-	// Convert grams to pounds (1 pound = 453.592 grams)
-	weightPounds := weightGrams / 453.592
-
-	// Calculate value from each metal component
-	copperValue := weightPounds * (copperPercent / 100.0) * prices.Copper
-	nickelValue := weightPounds * (nickelPercent / 100.0) * prices.Nickel
-
-	return copperValue + nickelValue, nil
+	return calc.BaseMeltValue(weightGrams, copperPercent, nickelPercent), nil
 }
 
 // CalculateMeltValueFromComposition calculates melt value using a MetalComposition
 // This handles both precious metals (troy oz) and base metals (grams)
 func CalculateMeltValueFromComposition(comp MetalComposition) (float64, error) {
-	if comp.IsBaseMetal {
-		return CalculateBaseMeltValue(comp.WeightGrams, comp.CopperPercent, comp.NickelPercent)
+	calc, err := CurrentCalculator()
+	if err != nil {
+		return 0, err
 	}
-	return CalculateMeltValue(comp.MetalType, comp.Weight, comp.Purity)
+	return calc.CompositionMeltValue(comp)
 }
@@ -0,0 +1,59 @@
+package metals
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFillFallbacksFlagsMissingMetals(t *testing.T) {
+	prices := &SpotPrices{Gold: 2400, Silver: 29}
+	fillFallbacks(prices)
+
+	if !prices.Degraded || !slices.Equal(prices.FallbackMetals, []string{"platinum", "palladium", "copper", "nickel"}) {
+		t.Errorf("degraded = %v, fallback metals = %v", prices.Degraded, prices.FallbackMetals)
+	}
+	if prices.Gold != 2400 || prices.Platinum != FallbackPriceFor("platinum").Price {
+		t.Errorf("gold = %.2f, platinum = %.2f; want the live gold and the fallback platinum", prices.Gold, prices.Platinum)
+	}
+
+	live := &SpotPrices{Gold: 2400, Silver: 29, Platinum: 1000, Palladium: 1000, Copper: 4, Nickel: 8}
+	fillFallbacks(live)
+	if live.Degraded || live.FallbackMetals != nil {
+		t.Errorf("fully live prices flagged degraded: %v", live.FallbackMetals)
+	}
+}
+
+func TestTrackFallbacksAlertsOncePerEpisode(t *testing.T) {
+	t.Setenv("SPOT_FALLBACK_ALERT_AFTER", "6h")
+	t.Cleanup(func() {
+		fallbackSince = map[string]time.Time{}
+		fallbackAlerted = map[string]bool{}
+	})
+
+	start := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	trackFallbacks([]string{"platinum"}, start)
+	if fallbackAlerted["platinum"] {
+		t.Fatal("alerted as soon as platinum fell back")
+	}
+	trackFallbacks([]string{"platinum"}, start.Add(7*time.Hour))
+	if !fallbackAlerted["platinum"] || !fallbackSince["platinum"].Equal(start) {
+		t.Fatalf("after 7h: alerted = %v, since = %v", fallbackAlerted["platinum"], fallbackSince["platinum"])
+	}
+
+	trackFallbacks(nil, start.Add(8*time.Hour))
+	if _, ok := fallbackSince["platinum"]; ok || fallbackAlerted["platinum"] {
+		t.Error("a live price should end the episode")
+	}
+}
+
+func TestMovedMetals(t *testing.T) {
+	previous := &SpotPrices{Gold: 2400, Silver: 29, Platinum: 1000}
+	prices := &SpotPrices{Gold: 2410, Silver: 29, Platinum: 1000, Copper: 4}
+	if moved := movedMetals(previous, prices); !slices.Equal(moved, []string{"gold", "copper"}) {
+		t.Errorf("moved = %v, want gold and the newly priced copper", moved)
+	}
+	if moved := movedMetals(nil, prices); len(moved) != 4 {
+		t.Errorf("without previous prices every priced metal moved, got %v", moved)
+	}
+}
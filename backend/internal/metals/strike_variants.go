@@ -0,0 +1,211 @@
+package metals
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Strike types recorded on a coin. An empty strike type means unknown, and
+// the year-based composition for regular issues is used.
+const (
+	StrikeBusiness           = "business"            // regular circulation strike
+	StrikeProof              = "proof"               // proof in the regular (e.g. clad) composition
+	StrikeSMS                = "sms"                 // 1965-1967 Special Mint Set
+	StrikeSilverProof        = "silver_proof"        // proof struck in silver for collector sets
+	StrikeSilverUncirculated = "silver_uncirculated" // uncirculated collector issue struck in silver
+)
+
+// ValidStrikeType reports whether s is a known strike type (or empty)
+func ValidStrikeType(s string) bool {
+	switch s {
+	case "", StrikeBusiness, StrikeProof, StrikeSMS, StrikeSilverProof, StrikeSilverUncirculated:
+		return true
+	}
+	return false
+}
+
+// StrikeVariant is a composition that applies only to some strike types of a
+// series within a year range. EndYear 0 means the variant is still struck.
+type StrikeVariant struct {
+	StrikeTypes []string
+	StartYear   int
+	EndYear     int
+	Composition MetalComposition
+}
+
+// StrikeVariants lists special-strike compositions by catalog coin type.
+// Proofs and SMS coins of clad series are clad too, but the silver proof sets
+// (1992+) and 40% silver collector issues are not.
+var StrikeVariants = map[string][]StrikeVariant{
+	"Washington Quarter": {
+		{
+			StrikeTypes: []string{StrikeSilverProof, StrikeSilverUncirculated},
+			StartYear:   1976,
+			EndYear:     1976,
+			Composition: MetalComposition{
+				Name:        "Washington Quarter (1976-S Bicentennial Silver)",
+				MetalType:   "silver",
+				Weight:      0.07395,
+				Purity:      40,
+				Description: "1976-S Bicentennial collector issue: Contains 0.07395 oz of silver (40% silver)",
+			},
+		},
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   1992,
+			EndYear:     2018,
+			Composition: MetalComposition{
+				Name:        "Washington Quarter (1992-2018 Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.18084,
+				Purity:      90,
+				Description: "1992-2018 silver proof: Contains 0.18084 oz of silver (90% silver)",
+			},
+		},
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   2019,
+			Composition: MetalComposition{
+				Name:        "Washington Quarter (2019+ Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.20373,
+				Purity:      99.9,
+				Description: "2019+ silver proof: Contains 0.20373 oz of silver (99.9% silver)",
+			},
+		},
+	},
+	"Roosevelt Dime": {
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   1992,
+			EndYear:     2018,
+			Composition: MetalComposition{
+				Name:        "Roosevelt Dime (1992-2018 Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.07234,
+				Purity:      90,
+				Description: "1992-2018 silver proof: Contains 0.07234 oz of silver (90% silver)",
+			},
+		},
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   2019,
+			Composition: MetalComposition{
+				Name:        "Roosevelt Dime (2019+ Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.08149,
+				Purity:      99.9,
+				Description: "2019+ silver proof: Contains 0.08149 oz of silver (99.9% silver)",
+			},
+		},
+	},
+	"Kennedy Half Dollar": {
+		{
+			StrikeTypes: []string{StrikeSilverProof, StrikeSilverUncirculated},
+			StartYear:   1976,
+			EndYear:     1976,
+			Composition: MetalComposition{
+				Name:        "Kennedy Half Dollar (1976-S Bicentennial Silver)",
+				MetalType:   "silver",
+				Weight:      0.14792,
+				Purity:      40,
+				Description: "1976-S Bicentennial collector issue: Contains 0.14792 oz of silver (40% silver)",
+			},
+		},
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   1992,
+			EndYear:     2018,
+			Composition: MetalComposition{
+				Name:        "Kennedy Half Dollar (1992-2018 Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.36169,
+				Purity:      90,
+				Description: "1992-2018 silver proof: Contains 0.36169 oz of silver (90% silver)",
+			},
+		},
+		{
+			StrikeTypes: []string{StrikeSilverProof},
+			StartYear:   2019,
+			Composition: MetalComposition{
+				Name:        "Kennedy Half Dollar (2019+ Silver Proof)",
+				MetalType:   "silver",
+				Weight:      0.40744,
+				Purity:      99.9,
+				Description: "2019+ silver proof: Contains 0.40744 oz of silver (99.9% silver)",
+			},
+		},
+	},
+	"Eisenhower Dollar": {
+		{
+			StrikeTypes: []string{StrikeSilverProof, StrikeSilverUncirculated},
+			StartYear:   1971,
+			EndYear:     1976,
+			Composition: MetalComposition{
+				Name:        "Eisenhower Dollar (1971-1976 Silver)",
+				MetalType:   "silver",
+				Weight:      0.31625,
+				Purity:      40,
+				Description: "1971-1976 40% silver collector issue (S mint only): Contains 0.31625 oz of silver",
+			},
+		},
+		{
+			// Circulation strikes and regular proofs were always clad
+			StrikeTypes: []string{StrikeBusiness, StrikeProof},
+			StartYear:   1971,
+			EndYear:     1978,
+			Composition: MetalComposition{
+				Name:        "Eisenhower Dollar (Copper-Nickel Clad)",
+				MetalType:   "copper",
+				Weight:      0.0,
+				Purity:      0,
+				Description: "Copper-nickel clad, no precious metal content",
+			},
+		},
+	},
+}
+
+// strikeVariant returns the special-strike composition for a coin, if any
+func strikeVariant(coinType string, year int, strikeType string) (MetalComposition, bool) {
+	if strikeType == "" || year == 0 {
+		return MetalComposition{}, false
+	}
+	for _, variant := range StrikeVariants[coinType] {
+		if year < variant.StartYear || (variant.EndYear != 0 && year > variant.EndYear) {
+			continue
+		}
+		for _, s := range variant.StrikeTypes {
+			if s == strikeType {
+				return variant.Composition, true
+			}
+		}
+	}
+	return MetalComposition{}, false
+}
+
+var (
+	silverProofPattern = regexp.MustCompile(`(?i)\bsilver\s+(proof|pr|pf)\b`)
+	smsPattern         = regexp.MustCompile(`(?i)\b(sms|special mint set)\b`)
+	proofPattern       = regexp.MustCompile(`(?i)(\bproof\b|\s(PR|PF)\d+[A-Z+]*$)`)
+	strikeWordsPattern = regexp.MustCompile(`(?i)\b(silver\s+proof|proof|sms|special mint set)\b`)
+)
+
+// InferStrikeType guesses a strike type from a PCGS-style title such as
+// "1999-S Silver Proof Washington Quarter PR69DCAM" or "1966 SMS Kennedy Half"
+func InferStrikeType(coinType string) string {
+	switch {
+	case silverProofPattern.MatchString(coinType):
+		return StrikeSilverProof
+	case smsPattern.MatchString(coinType):
+		return StrikeSMS
+	case proofPattern.MatchString(coinType):
+		return StrikeProof
+	}
+	return ""
+}
+
+// stripStrikeWords removes strike descriptors from a coin name so the series
+// can be matched, e.g. "Silver Proof Washington Quarter" -> "Washington Quarter"
+func stripStrikeWords(coinType string) string {
+	return strings.Join(strings.Fields(strikeWordsPattern.ReplaceAllString(coinType, " ")), " ")
+}
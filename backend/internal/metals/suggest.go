@@ -0,0 +1,126 @@
+package metals
+
+import (
+	"sort"
+	"strings"
+)
+
+// Suggestion is a catalog coin type offered for type-ahead input
+type Suggestion struct {
+	CoinType     string  `json:"coin_type"`
+	MetalType    string  `json:"metal_type"`
+	Description  string  `json:"description"`
+	Denomination string  `json:"denomination"`
+	FaceValue    float64 `json:"face_value"`
+	MatchedAlias string  `json:"matched_alias,omitempty"`
+	UsageCount   int     `json:"usage_count"`
+	Score        float64 `json:"score"`
+}
+
+// Relevance of a catalog name to the typed query
+const (
+	relevanceExact       = 100
+	relevanceAliasExact  = 90
+	relevancePrefix      = 80
+	relevanceWordPrefix  = 60
+	relevanceAliasPrefix = 50
+	relevanceContains    = 40
+
+	// usageWeight is added per prior use of a coin type, capped at maxUsageBoost
+	usageWeight   = 5
+	maxUsageBoost = 50
+)
+
+// SuggestCoinTypes ranks catalog coin types against a partial query, boosted
+// by how often the user has already used each type (usage is keyed by
+// canonical coin type). An empty query ranks by usage alone.
+func SuggestCoinTypes(query string, usage map[string]int, limit int) []Suggestion {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+
+	// Best alias relevance per catalog name
+	aliasScores := map[string]float64{}
+	aliasNames := map[string]string{}
+	if query != "" {
+		for alias, name := range SeriesAliases {
+			var score float64
+			switch {
+			case alias == query:
+				score = relevanceAliasExact
+			case strings.HasPrefix(alias, query):
+				score = relevanceAliasPrefix
+			default:
+				continue
+			}
+			if score > aliasScores[name] || (score == aliasScores[name] && alias < aliasNames[name]) {
+				aliasScores[name] = score
+				aliasNames[name] = alias
+			}
+		}
+	}
+
+	suggestions := []Suggestion{}
+	for lower, name := range catalogNames() {
+		var relevance float64
+		if query != "" {
+			relevance = nameRelevance(lower, query)
+		}
+
+		suggestion := Suggestion{CoinType: name, UsageCount: usage[name]}
+		if aliasScores[name] > relevance {
+			relevance = aliasScores[name]
+			suggestion.MatchedAlias = aliasNames[name]
+		}
+		if query != "" && relevance == 0 {
+			continue
+		}
+		if query == "" && suggestion.UsageCount == 0 {
+			continue
+		}
+
+		boost := float64(suggestion.UsageCount * usageWeight)
+		if boost > maxUsageBoost {
+			boost = maxUsageBoost
+		}
+		suggestion.Score = relevance + boost
+
+		if info, ok := SeriesReference[name]; ok {
+			suggestion.Denomination = info.Denomination
+			suggestion.FaceValue = info.FaceValue
+		}
+		if comp, ok := ResolveComposition(name, 0); ok {
+			suggestion.MetalType = comp.MetalType
+			suggestion.Description = comp.Description
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].CoinType < suggestions[j].CoinType
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+func nameRelevance(name, query string) float64 {
+	switch {
+	case name == query:
+		return relevanceExact
+	case strings.HasPrefix(name, query):
+		return relevancePrefix
+	}
+	for _, word := range strings.Fields(name) {
+		if strings.HasPrefix(strings.TrimLeft(word, "$("), query) {
+			return relevanceWordPrefix
+		}
+	}
+	if strings.Contains(name, query) {
+		return relevanceContains
+	}
+	return 0
+}
@@ -0,0 +1,51 @@
+package metals
+
+import "strings"
+
+// Currency is the ISO 4217 code every amount is stored and priced in.
+// Responses say so explicitly, since a user's settings can restate them in
+// another currency.
+const Currency = "USD"
+
+// NormalizeCurrency upper-cases a currency code and reports whether it looks
+// like an ISO 4217 code: three letters, e.g. "USD" or "cad"
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return code, false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return code, false
+		}
+	}
+	return code, true
+}
+
+// Units weights and spot prices are given in
+const (
+	UnitTroyOunce = "troy_oz"
+	UnitGram      = "g"
+	UnitPound     = "lb"
+)
+
+// GramsPerTroyOunce converts the troy ounces weights are stored in to grams
+const GramsPerTroyOunce = 31.1034768
+
+// PriceUnit returns the unit a metal's spot price is quoted per: troy ounces
+// for precious metals, pounds for copper and nickel
+func PriceUnit(metal string) string {
+	if metal == "copper" || metal == "nickel" {
+		return UnitPound
+	}
+	return UnitTroyOunce
+}
+
+// priceUnits maps each metal to PriceUnit
+func priceUnits() map[string]string {
+	units := make(map[string]string, len(Metals))
+	for _, metal := range Metals {
+		units[metal] = PriceUnit(metal)
+	}
+	return units
+}
@@ -0,0 +1,23 @@
+package metals
+
+import "testing"
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+		ok   bool
+	}{
+		{"USD", "USD", true},
+		{" cad ", "CAD", true},
+		{"US", "US", false},
+		{"US$", "US$", false},
+		{"EURO", "EURO", false},
+	}
+	for _, tt := range tests {
+		got, ok := NormalizeCurrency(tt.code)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v; want %q, %v", tt.code, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -242,20 +242,5 @@ var YearBasedCompositions = []YearBasedComposition{
 
 // GetCompositionByYear looks up composition based on coin type and year
 func GetCompositionByYear(coinType string, year int) (MetalComposition, bool) {
-	// First check year-based compositions
-	for _, ybc := range YearBasedCompositions {
-		if ybc.CoinType == coinType {
-			// Check if year falls in any range
-			for _, yr := range ybc.YearRanges {
-				if year >= yr.StartYear && year <= yr.EndYear {
-					return yr.Composition, true
-				}
-			}
-			// Year doesn't match any range, use default
-			return ybc.DefaultComp, true
-		}
-	}
-
-	// Fall back to static compositions (coins that don't vary by year)
-	return GetComposition(coinType)
+	return ResolveComposition(coinType, year)
 }
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"github.com/evansminotwood/aureus/internal/audit"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	auditUserKey   = "audit_user_id"
+	auditTargetKey = "audit_target_id"
+)
+
+// AuditUser names the user an audited sign-in signed in, since those routes
+// run before there is one
+func AuditUser(c *gin.Context, userID uuid.UUID) {
+	c.Set(auditUserKey, userID)
+}
+
+// AuditTarget names the record an audited request acted on when the route
+// has no :id, e.g. a coin it just created
+func AuditTarget(c *gin.Context, id uuid.UUID) {
+	c.Set(auditTargetKey, id)
+}
+
+// Audit records action in the user's audit log once the handler succeeds,
+// with the client's address, user agent and the API key used, if any. The
+// target is what the handler named with AuditTarget, or else the route's
+// :id. Failed requests change nothing and aren't recorded.
+func Audit(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if status := c.Writer.Status(); status < 200 || status >= 300 {
+			return
+		}
+
+		userID, ok := c.Get("user_id")
+		if !ok {
+			userID, ok = c.Get(auditUserKey)
+		}
+		if !ok {
+			return
+		}
+		entry := models.AuditLog{
+			UserID:    userID.(uuid.UUID),
+			Action:    action,
+			IPAddress: c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		if target, ok := c.Get(auditTargetKey); ok {
+			id := target.(uuid.UUID)
+			entry.TargetID = &id
+		} else if id, err := uuid.Parse(c.Param("id")); err == nil {
+			entry.TargetID = &id
+		}
+		if keyID, ok := c.Get("api_key_id"); ok {
+			id := keyID.(uuid.UUID)
+			entry.APIKeyID = &id
+		}
+		audit.Record(entry)
+	}
+}
@@ -4,12 +4,24 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/evansminotwood/aureus/internal/apikeys"
 	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/emergency"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/sessions"
 	"github.com/gin-gonic/gin"
 )
 
+// AuthRequired rejects requests without a valid bearer token or API key
+// (X-API-Key). In multi-tenant mode it must run after ResolveTenant.
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(apikeys.Header); key != "" {
+			authenticateAPIKey(c, key)
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
@@ -32,8 +44,86 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		if MultiTenant() && !SameTenant(claims.TenantID, TenantIDFrom(c)) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token is not valid for this tenant"})
+			c.Abort()
+			return
+		}
+
+		// Tokens issued to an emergency contact end with their access
+		if claims.EmergencyAccessID != nil && !emergency.Active(*claims.EmergencyAccessID, claims.UserID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Emergency access has ended"})
+			c.Abort()
+			return
+		}
+
+		// Logging out everywhere bumps the version, revoking earlier tokens.
+		// Emergency tokens end with their grant instead.
+		if claims.EmergencyAccessID == nil {
+			if version, err := sessions.TokenVersion(claims.UserID); err != nil || version != claims.TokenVersion {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Revoking a session, or logging it out, ends its access tokens
+		if claims.SessionID != nil {
+			if err := sessions.Seen(*claims.SessionID, claims.UserID, c.ClientIP()); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", *claims.SessionID)
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("scopes", claims.Scopes)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey lets a request through as the user a key belongs to,
+// limited to the key's scopes
+func authenticateAPIKey(c *gin.Context, plain string) {
+	key, user, err := apikeys.Authenticate(plain)
+	// A key without scopes would have full access, which keys never get
+	if err != nil || len(key.Scopes) == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		c.Abort()
+		return
+	}
+
+	if MultiTenant() && !SameTenant(user.TenantID, TenantIDFrom(c)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API key is not valid for this tenant"})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("email", user.Email)
+	c.Set("scopes", key.Scopes)
+	c.Set("api_key_id", key.ID)
+	if key.PortfolioID != nil {
+		c.Set("display_portfolio_id", *key.PortfolioID)
+	}
+	c.Next()
+}
+
+// AdminRequired rejects requests from users without admin rights.
+// Must be used after AuthRequired.
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+
+		var user models.User
+		if err := database.GetDB().First(&user, "id = ?", userID).Error; err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/evansminotwood/aureus/internal/coincode"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CoinCode lets coin routes name the coin in :id by its short code (e.g.
+// AU-000123) as well as its ID. The code is swapped for the coin's ID before
+// the handler runs; a code no coin has is left for the handler to answer
+// 404 like an unknown ID.
+func CoinCode() gin.HandlerFunc {
+	return codeResolver(func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.Coin{})
+	})
+}
+
+// ArchivedCoinCode is CoinCode for archived coins. A regraded coin's
+// archived records share its code, so the code names the latest of them.
+func ArchivedCoinCode() gin.HandlerFunc {
+	return codeResolver(func(db *gorm.DB) *gorm.DB {
+		return db.Model(&models.ArchivedCoin{}).Order("archived_at DESC")
+	})
+}
+
+// TrashedCoinCode is CoinCode for coins in the trash
+func TrashedCoinCode() gin.HandlerFunc {
+	return codeResolver(func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Model(&models.Coin{}).Where("deleted_at IS NOT NULL")
+	})
+}
+
+func codeResolver(query func(db *gorm.DB) *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		number, ok := coincode.Parse(c.Param("id"))
+		if !ok {
+			c.Next()
+			return
+		}
+		var ids []string
+		if err := query(database.GetDB()).Where("number = ?", number).Limit(1).Pluck("id", &ids).Error; err != nil {
+			log.Printf("Failed to look up coin code %s: %v", c.Param("id"), err)
+		}
+		if len(ids) > 0 {
+			for i, param := range c.Params {
+				if param.Key == "id" {
+					c.Params[i].Value = ids[0]
+				}
+			}
+		}
+		c.Next()
+	}
+}
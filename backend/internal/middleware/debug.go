@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/debuglog"
+	"github.com/gin-gonic/gin"
+)
+
+// bodyRecorder copies what a handler writes, up to a limit
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	if room := debuglog.MaxBodyBytes*4 - w.body.Len(); room > 0 {
+		w.body.Write(data[:min(len(data), room)])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// DebugLog records each request and its response in the debug log, with
+// secrets and personal data redacted, while DEBUG_LOGGING is on. Uploads are
+// logged by size only.
+func DebugLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Reading the debug log would otherwise log everything it returned
+		if !debuglog.Enabled() || strings.HasSuffix(c.Request.URL.Path, "/admin/debug-log") {
+			c.Next()
+			return
+		}
+
+		entry := debuglog.Entry{
+			Kind:           debuglog.KindRequest,
+			Time:           time.Now(),
+			Method:         c.Request.Method,
+			URL:            debuglog.RedactURL(c.Request.URL),
+			RequestHeaders: debuglog.RedactHeaders(c.Request.Header),
+		}
+
+		contentType := c.ContentType()
+		if c.Request.Body != nil {
+			if strings.HasPrefix(contentType, "multipart/") {
+				entry.RequestBody = fmt.Sprintf("[%d bytes of %s]", c.Request.ContentLength, contentType)
+			} else {
+				// BodySizeLimit has already capped the size of non-upload bodies
+				data, err := io.ReadAll(c.Request.Body)
+				if err == nil {
+					c.Request.Body = io.NopCloser(bytes.NewReader(data))
+					entry.RequestBody = debuglog.RedactBody(data, contentType)
+				}
+			}
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		entry.Status = c.Writer.Status()
+		entry.DurationMS = float64(time.Since(entry.Time).Microseconds()) / 1000
+		entry.ResponseBody = debuglog.RedactBody(recorder.body.Bytes(), c.Writer.Header().Get("Content-Type"))
+		if userID, ok := c.Get("user_id"); ok {
+			entry.UserID = fmt.Sprint(userID)
+		}
+		if len(c.Errors) > 0 {
+			entry.Error = c.Errors.String()
+		}
+		debuglog.Record(entry)
+	}
+}
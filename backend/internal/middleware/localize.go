@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/settings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// keepCurrencyKey marks a response whose amounts are already in the currency
+// it names
+const keepCurrencyKey = "localize_keep_currency"
+
+// KeepCurrency leaves the response as the handler wrote it, for responses
+// that restate amounts in a currency of their own choosing, e.g. the
+// currency hedging report
+func KeepCurrency(c *gin.Context) {
+	c.Set(keepCurrencyKey, true)
+}
+
+// Localize restates the amounts and weights in JSON responses in the user's
+// currency and weight unit, and turns those in JSON request bodies back into
+// dollars and troy ounces before the handler reads them. Requests of users
+// on the defaults pass through untouched.
+func Localize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := c.Get("user_id")
+		if !ok {
+			c.Next()
+			return
+		}
+		prefs, err := settings.For(userID.(uuid.UUID))
+		if err != nil {
+			log.Printf("Failed to load settings for user %v: %v", userID, err)
+		}
+		localizer, err := settings.NewLocalizer(prefs)
+		if err != nil {
+			log.Printf("No exchange rate for user %v's currency %s, answering in dollars: %v", userID, prefs.Currency, err)
+		}
+		if localizer.Identity() {
+			c.Next()
+			return
+		}
+
+		if c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+				return
+			}
+			// A body that isn't valid JSON goes through as it is, for the
+			// handler to reject
+			if converted, err := localizer.Request(body); err == nil && len(body) > 0 {
+				body = converted
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Request.ContentLength = int64(len(body))
+		}
+
+		writer := &jsonWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffering {
+			return
+		}
+		body := writer.body.Bytes()
+		if !c.GetBool(keepCurrencyKey) {
+			if localized, err := localizer.Response(body); err == nil {
+				body = localized
+			} else {
+				log.Printf("Failed to localize %s %s response: %v", c.Request.Method, c.Request.URL.Path, err)
+			}
+		}
+		c.Writer.Write(body)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/evansminotwood/aureus/internal/clientip"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// IPAllowlist only lets through clients whose address is in the setting
+// key, a comma-separated list of IP addresses and CIDR ranges; others get
+// 403. Unset allows everyone. The address is c.ClientIP(), so behind a
+// reverse proxy TRUSTED_PROXIES has to name it or every request is judged
+// by the proxy's address.
+func IPAllowlist(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value := config.String(key, "")
+		if value == "" {
+			c.Next()
+			return
+		}
+
+		allowed, err := clientip.ParseList(value)
+		if err != nil {
+			log.Printf("%s: %v", key, err)
+		}
+		// A typo fails closed rather than opening the routes to everyone
+		if err != nil || !clientip.Contains(allowed, c.ClientIP()) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access from this address is not allowed", "code": "ip_not_allowed"})
+			return
+		}
+		c.Next()
+	}
+}
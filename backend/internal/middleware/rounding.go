@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/rounding"
+	"github.com/gin-gonic/gin"
+)
+
+// jsonWriter holds back JSON responses so their amounts can be rounded or
+// restated before they're sent. Anything else, like CSV exports, streams
+// through.
+type jsonWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+	decided   bool
+}
+
+func (w *jsonWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *jsonWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// RoundValues rounds the money amounts in JSON responses by the instance's
+// rounding policy, unless VALUE_ROUNDING is off
+func RoundValues() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !rounding.Enabled() {
+			c.Next()
+			return
+		}
+
+		writer := &jsonWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if !writer.buffering {
+			return
+		}
+		body := writer.body.Bytes()
+		if rounded, err := rounding.Apply(body, rounding.Current()); err == nil {
+			body = rounded
+		} else {
+			log.Printf("Failed to round %s %s response: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		c.Writer.Write(body)
+	}
+}
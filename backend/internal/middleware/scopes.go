@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// ScopesFrom returns the scopes of the request's credentials; none is full
+// access. Whatever authenticates a request (a token or an API key, and later
+// shares) sets them, so the checks below apply to all of them alike.
+func ScopesFrom(c *gin.Context) []string {
+	scopes, _ := c.Get("scopes")
+	granted, _ := scopes.([]string)
+	return granted
+}
+
+func rejectScope(c *gin.Context, scope string) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "Token lacks the " + scope + " scope"})
+	c.Abort()
+}
+
+// RequireScope rejects requests whose credentials don't carry scope.
+// Must be used after AuthRequired.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasScope(ScopesFrom(c), scope) {
+			rejectScope(c, scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+// ScopeByMethod requires read for GET and HEAD requests and write for the
+// rest. POST routes whose path ends in one of readOnly only compute or send
+// something and need read as well. Must be used after AuthRequired.
+func ScopeByMethod(read, write string, readOnly ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope := write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead:
+			scope = read
+		case http.MethodPost:
+			for _, suffix := range readOnly {
+				if strings.HasSuffix(c.FullPath(), suffix) {
+					scope = read
+					break
+				}
+			}
+		}
+		if !auth.HasScope(ScopesFrom(c), scope) {
+			rejectScope(c, scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+// FullAccessRequired rejects scoped credentials, for account settings and
+// anything else a limited login shouldn't touch. Must be used after
+// AuthRequired.
+func FullAccessRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.FullAccess(ScopesFrom(c)) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This requires a full access login"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// NoDisplayTokens rejects display tokens, for routes any other token may use
+// but that show more than a wall display needs. Must be used after
+// AuthRequired.
+func NoDisplayTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(ScopesFrom(c), auth.ScopeDisplay) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Display tokens can't use this route"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
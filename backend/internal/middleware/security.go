@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/evansminotwood/aureus/internal/security"
+	"github.com/gin-gonic/gin"
+)
+
+// SecurityHeaders adds the security headers (see security.Headers) to every
+// response. They're read once, when the router is built.
+func SecurityHeaders() gin.HandlerFunc {
+	headers := security.Headers()
+	return func(c *gin.Context) {
+		for name, values := range headers {
+			c.Writer.Header()[name] = values
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const tenantIDKey = "tenant_id"
+
+// tenantSlugPattern matches a valid tenant slug, which must also work as a
+// DNS label
+var tenantSlugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// MultiTenant reports whether the deployment isolates users by tenant
+// (MULTI_TENANT)
+func MultiTenant() bool {
+	return config.Bool("MULTI_TENANT", false)
+}
+
+// TenantHeader is the request header naming the tenant slug (TENANT_HEADER)
+func TenantHeader() string {
+	return config.String("TENANT_HEADER", "X-Tenant")
+}
+
+// ValidTenantSlug reports whether slug can be used as a tenant slug
+func ValidTenantSlug(slug string) bool {
+	return tenantSlugPattern.MatchString(slug)
+}
+
+// tenantSlug returns the tenant named by the tenant header, the ?tenant
+// query parameter (for browser navigations, which can't set headers), or
+// else by the subdomain of TENANT_BASE_DOMAIN the request was sent to, e.g.
+// "coinclub.aureus.example" -> "coinclub"
+func tenantSlug(c *gin.Context) string {
+	if slug := strings.TrimSpace(c.GetHeader(TenantHeader())); slug != "" {
+		return strings.ToLower(slug)
+	}
+	if slug := strings.TrimSpace(c.Query("tenant")); slug != "" {
+		return strings.ToLower(slug)
+	}
+
+	baseDomain := strings.ToLower(strings.Trim(config.String("TENANT_BASE_DOMAIN", ""), "."))
+	if baseDomain == "" {
+		return ""
+	}
+	host := strings.ToLower(c.Request.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if sub, ok := strings.CutSuffix(host, "."+baseDomain); ok && !strings.Contains(sub, ".") {
+		return sub
+	}
+	return ""
+}
+
+// ResolveTenant identifies the tenant of each request in multi-tenant mode
+// and rejects requests that name no known tenant. It does nothing otherwise.
+func ResolveTenant() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !MultiTenant() {
+			c.Next()
+			return
+		}
+
+		slug := tenantSlug(c)
+		if slug == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Tenant required", "code": "tenant_required"})
+			return
+		}
+
+		var tenant models.Tenant
+		if !ValidTenantSlug(slug) || database.GetDB().Where("slug = ?", slug).First(&tenant).Error != nil {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Tenant not found", "code": "tenant_not_found"})
+			return
+		}
+
+		c.Set(tenantIDKey, tenant.ID)
+		c.Next()
+	}
+}
+
+// TenantIDFrom returns the tenant of the current request, or nil outside
+// multi-tenant mode
+func TenantIDFrom(c *gin.Context) *uuid.UUID {
+	if value, ok := c.Get(tenantIDKey); ok {
+		id := value.(uuid.UUID)
+		return &id
+	}
+	return nil
+}
+
+// SameTenant reports whether two optional tenant IDs refer to the same tenant
+func SameTenant(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const apiVersionKey = "api_version"
+
+// APIVersion tags requests with the API version they were routed through so
+// handlers can keep older response shapes for older versions
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(apiVersionKey, version)
+		c.Header("X-API-Version", version)
+		c.Next()
+	}
+}
+
+// APIVersionFrom returns the API version of the current request, e.g. "v1"
+func APIVersionFrom(c *gin.Context) string {
+	return c.GetString(apiVersionKey)
+}
+
+// Deprecation describes how a deprecated route or route group is retired
+type Deprecation struct {
+	// Since is when the deprecation was announced (zero for unspecified)
+	Since time.Time
+	// Sunset is when the route stops working (zero for not yet scheduled)
+	Sunset time.Time
+	// Successor maps the request path to its replacement, if any
+	Successor func(path string) string
+}
+
+// Deprecated marks responses with Deprecation, Sunset (RFC 8594) and
+// successor-version Link headers so clients can detect and migrate off old routes
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d.Since.IsZero() {
+			c.Header("Deprecation", "true")
+		} else {
+			c.Header("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+		}
+		if !d.Sunset.IsZero() {
+			c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != nil {
+			if successor := d.Successor(c.Request.URL.Path); successor != "" {
+				c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			}
+		}
+		c.Next()
+	}
+}
+
+// ReplacePrefix returns a Successor func that swaps one path prefix for another
+func ReplacePrefix(from, to string) func(string) string {
+	return func(path string) string {
+		if !strings.HasPrefix(path, from) {
+			return ""
+		}
+		return to + strings.TrimPrefix(path, from)
+	}
+}
@@ -3,18 +3,52 @@ package models
 import (
 	"time"
 
+	"github.com/evansminotwood/aureus/internal/coincode"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-type User struct {
+// Tenant is a club or shop in a multi-tenant deployment. Users and their
+// data belong to at most one tenant; single-tenant installs have none.
+type Tenant struct {
 	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	Email     string    `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
+	Slug      string    `gorm:"uniqueIndex;not null" json:"slug"` // subdomain or X-Tenant header value
+	Name      string    `gorm:"not null" json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+func (t *Tenant) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+type User struct {
+	ID       uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	Email    string     `gorm:"uniqueIndex;not null" json:"email"`
+	Password string     `gorm:"not null" json:"-"`
+	IsAdmin  bool       `gorm:"default:false" json:"is_admin"`
+	// PCGSAPIKey holds the user's own PCGS key, encrypted at rest
+	PCGSAPIKey string `gorm:"column:pcgs_api_key" json:"-"`
+	// Automatic PCGS value sync: every N days, 0 when off
+	PCGSSyncIntervalDays int        `gorm:"column:pcgs_sync_interval_days;default:0" json:"pcgs_sync_interval_days"`
+	PCGSSyncedAt         *time.Time `gorm:"column:pcgs_synced_at" json:"pcgs_synced_at,omitempty"`
+	// TokenVersion is stamped into the user's tokens; logging out everywhere
+	// bumps it, which invalidates every token issued before
+	TokenVersion int `gorm:"not null;default:0" json:"-"`
+	// DemoExpiresAt is set on throwaway demo accounts, which are deleted
+	// with everything in them once it passes
+	DemoExpiresAt *time.Time `gorm:"index" json:"demo_expires_at,omitempty"`
+	// DemoIPAddress is the address a demo account was created from, which
+	// may only hold a few live ones at a time
+	DemoIPAddress string    `gorm:"index" json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
@@ -22,14 +56,252 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// InviteCode lets someone register while registration is invite-only
+type InviteCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	TenantID  *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	Code      string     `gorm:"uniqueIndex;not null" json:"code"`
+	Note      string     `json:"note"`
+	CreatedBy uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	MaxUses   int        `gorm:"not null;default:1" json:"max_uses"`
+	Uses      int        `gorm:"not null;default:0" json:"uses"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (i *InviteCode) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// EmailChangeRequest is a pending change of a user's email address. Only a
+// hash of the token mailed to the new address is stored.
+type EmailChangeRequest struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	NewEmail  string    `gorm:"not null" json:"new_email"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (r *EmailChangeRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// PasswordResetToken lets a user who forgot their password set a new one.
+// Only a hash of the token mailed to them is stored.
+type PasswordResetToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"user_id"`
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t *PasswordResetToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// OAuthIdentity links an account at an identity provider, such as Google
+// or Apple, to a user, who can then sign in with it. Subject is the
+// provider's stable ID for the account; Email is what it last reported.
+type OAuthIdentity struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_user_provider" json:"user_id"`
+	Provider  string    `gorm:"not null;uniqueIndex:idx_oauth_provider_subject;uniqueIndex:idx_oauth_user_provider" json:"provider"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_oauth_provider_subject" json:"-"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (i *OAuthIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// OAuthLoginCode hands a sign-in with a provider over to the frontend: the
+// callback redirects there with the code, which is traded once for a
+// session. Only a hash of the code is stored.
+type OAuthLoginCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	CodeHash  string    `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (c *OAuthLoginCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// APIKey lets scripts call the API with an X-API-Key header instead of
+// logging in. Only a hash of the key is stored; Prefix is its first
+// characters, so the owner can tell keys apart. Keys always carry scopes.
+// Display tokens are keys with only the display scope, bound to a portfolio.
+type APIKey struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes     []string   `gorm:"type:jsonb;serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"` // nil never expires
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// PortfolioID is the one portfolio a display token shows
+	PortfolioID *uuid.UUID `gorm:"type:uuid;index" json:"portfolio_id,omitempty"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// RefreshToken lets a client get a new access token without the password.
+// Only a hash of the token is stored. Each use rotates it: the token is
+// revoked and replaced by a new one in the same family, so a revoked token
+// coming back means it leaked and the whole family is revoked.
+type RefreshToken struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	FamilyID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"family_id"` // the Session the token belongs to
+	TokenHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	UserAgent    string     `json:"user_agent"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ReplacedByID *uuid.UUID `gorm:"type:uuid" json:"replaced_by_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// Session is one login on one device: the refresh tokens of a family and the
+// access tokens issued with them, which carry its ID. Revoking it signs the
+// device out at once. Device is a readable name made from the user agent,
+// and IPAddress and LastSeenAt are from its latest request.
+type Session struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"` // the FamilyID of its refresh tokens
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	Device     string     `json:"device"`
+	IPAddress  string     `json:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// LoginThrottle counts recent failed sign-ins or registrations for one key,
+// an IP address ("ip:<address>") or an email address ("email:<address>"),
+// and locks the key out once there are too many
+type LoginThrottle struct {
+	Key           string     `gorm:"primaryKey" json:"key"`
+	Failures      int        `gorm:"not null;default:0" json:"failures"`
+	LastFailureAt time.Time  `gorm:"index" json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// LoginFailure is the audit record of one failed sign-in or registration
+type LoginFailure struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Action    string    `gorm:"not null" json:"action"` // login or register
+	Email     string    `gorm:"index" json:"email"`
+	IPAddress string    `gorm:"index" json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	Status    int       `json:"status"`
+	Locked    bool      `json:"locked"` // this failure locked the email or IP out
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (f *LoginFailure) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// AuditLog records one thing done to an account: a sign-in, or a coin,
+// portfolio or PCGS sync started through the API. Only actions that
+// succeeded are recorded.
+type AuditLog struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index:idx_audit_logs_user_created,priority:1" json:"user_id"`
+	Action    string     `gorm:"not null;index" json:"action"`          // e.g. "login", "coin.created"
+	TargetID  *uuid.UUID `gorm:"type:uuid" json:"target_id,omitempty"`  // the coin or portfolio acted on
+	APIKeyID  *uuid.UUID `gorm:"type:uuid" json:"api_key_id,omitempty"` // set when done with an API key
+	IPAddress string     `json:"ip_address"`
+	UserAgent string     `json:"user_agent"`
+	CreatedAt time.Time  `gorm:"index:idx_audit_logs_user_created,priority:2" json:"created_at"`
+}
+
+func (l *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
 type Portfolio struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	UserID      uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Coins       []Coin    `gorm:"foreignKey:PortfolioID" json:"coins,omitempty"`
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID    *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	Name        string     `gorm:"not null" json:"name"`
+	Description string     `json:"description"`
+	// How the portfolio list shows it: a cover image (e.g. from /upload), a
+	// hex color, an icon name and the user's ordering. Notes are Markdown.
+	CoverImageURL string `json:"cover_image_url"`
+	Color         string `json:"color"`
+	Icon          string `json:"icon"`
+	SortOrder     int    `gorm:"not null;default:0;index" json:"sort_order"`
+	Notes         string `gorm:"type:text" json:"notes"`
+	// MonthlyStatement emails the owner a summary of the previous month
+	MonthlyStatement bool       `gorm:"default:false" json:"monthly_statement"`
+	StatementSentAt  *time.Time `json:"statement_sent_at,omitempty"`
+	// StatementStories includes the stories of coins acquired during the
+	// month in its statements
+	StatementStories bool `gorm:"default:false" json:"statement_stories"`
+	// ValuationBasis is what current_value means for the portfolio's coins:
+	// "melt", "numismatic" or "max" (the higher of the two)
+	ValuationBasis string `gorm:"not null;default:'max'" json:"valuation_basis"`
+	// Defaults new coins in the portfolio start with when they don't say:
+	// the face currency of coins whose series doesn't set one, where they're
+	// kept, and whether scheduled PCGS syncs include them
+	DefaultFaceCurrency    string `json:"default_face_currency"`
+	DefaultStorageLocation string `json:"default_storage_location"`
+	DefaultAutoSync        *bool  `gorm:"not null;default:true" json:"default_auto_sync"`
+	// Archived portfolios are kept out of the portfolio list and statements
+	Archived   bool       `gorm:"not null;default:false;index" json:"archived"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	// DeletedAt is set while the portfolio is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Coins     []Coin         `gorm:"foreignKey:PortfolioID" json:"coins,omitempty"`
 }
 
 func (p *Portfolio) BeforeCreate(tx *gorm.DB) error {
@@ -40,27 +312,85 @@ func (p *Portfolio) BeforeCreate(tx *gorm.DB) error {
 }
 
 type Coin struct {
-	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ID uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	// Number is taken from the coin_numbers sequence when the coin is
+	// created and shown as its short Code, e.g. AU-000123. It's unique among
+	// coins; a regraded coin's archived record shares its replacement's.
+	Number          int64      `gorm:"not null;default:nextval('coin_numbers'::regclass)" json:"-"`
+	Code            string     `gorm:"-" json:"code"`
 	PortfolioID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"portfolio_id"`
 	CoinType        string     `json:"coin_type"`
 	Year            int        `json:"year"`
 	MintMark        string     `json:"mint_mark"`
+	StrikeType      string     `json:"strike_type"` // "business", "proof", "sms", "silver_proof", ...
 	Denomination    string     `json:"denomination"`
-	PCGSCertNumber   string     `json:"pcgs_cert_number"`
-	PurchasePrice    float64    `json:"purchase_price"`
-	PurchaseDate     *time.Time `json:"purchase_date"`
-	CurrentValue     float64    `json:"current_value"`
-	NumismaticValue  float64    `json:"numismatic_value"`
-	LastPriceUpdate  *time.Time `json:"last_price_update"`
+	FaceValue       float64    `json:"face_value"`    // face value in FaceCurrency
+	FaceCurrency    string     `json:"face_currency"` // e.g., "USD", "CAD"
+	PCGSCertNumber  string     `json:"pcgs_cert_number"`
+	PurchasePrice   float64    `json:"purchase_price"` // hammer price per coin
+	BuyersPremium   float64    `json:"buyers_premium"` // premium, shipping and tax are for the whole purchase
+	ShippingCost    float64    `json:"shipping_cost"`
+	SalesTax        float64    `json:"sales_tax"`
+	PurchaseDate    *time.Time `json:"purchase_date"`
+	LotID           *uuid.UUID `gorm:"type:uuid;index" json:"lot_id"`           // the group purchase this coin was bought in
+	UpgradedFromID  *uuid.UUID `gorm:"type:uuid;index" json:"upgraded_from_id"` // the archived record of this coin before it was regraded
+	CurrentValue    float64    `json:"current_value"`
+	MeltValue       float64    `json:"melt_value"` // melt value at the last price update
+	NumismaticValue float64    `json:"numismatic_value"`
+	InsuredValue    float64    `json:"insured_value"` // replacement value per coin, for insurance
+	LastPriceUpdate *time.Time `json:"last_price_update"`
+	PCGSSyncedAt    *time.Time `gorm:"column:pcgs_synced_at" json:"pcgs_synced_at"`
+	AutoSync        *bool      `gorm:"not null;default:true" json:"auto_sync"` // false leaves the coin out of scheduled PCGS syncs
+	StorageLocation string     `gorm:"index" json:"storage_location"`          // e.g. "Home safe", "Bank box 12"
 	ImageURL        string     `json:"image_url"`
 	ThumbnailURL    string     `json:"thumbnail_url"`
 	Notes           string     `json:"notes"`
+	Story           string     `gorm:"type:text" json:"story"` // long-form Markdown: how it was acquired, provenance, family history
 	Quantity        int        `gorm:"default:1" json:"quantity"`
 	MetalType       string     `json:"metal_type"`   // e.g., "silver", "gold", "copper"
 	MetalWeight     float64    `json:"metal_weight"` // weight in troy ounces
 	MetalPurity     float64    `json:"metal_purity"` // purity percentage (e.g., 90 for 90%)
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	// Condition of raw coins: problems such as "cleaned" or "holed", eye
+	// appeal and toning descriptors. Problems and poor eye appeal take a
+	// haircut off a raw coin's numismatic value when it is valued.
+	Problems  []string `gorm:"type:jsonb;serializer:json" json:"problems"`
+	EyeAppeal string   `gorm:"index" json:"eye_appeal"`
+	Toning    []string `gorm:"type:jsonb;serializer:json" json:"toning"`
+	// How the metal fields were filled in (catalog match method, "manual" or
+	// "confirmed") and how sure that guess is
+	CompositionSource     string `gorm:"index" json:"composition_source"`
+	CompositionConfidence string `gorm:"index" json:"composition_confidence"`
+	// CertStatus is "suspicious" when the cert number matched the
+	// counterfeit watchlist (CertFlags says why) and "verified" once the
+	// owner checked the slab by hand
+	CertStatus string   `gorm:"index" json:"cert_status"`
+	CertFlags  []string `gorm:"type:jsonb;serializer:json" json:"cert_flags"`
+	// References link to more about the coin, e.g. its PCGS CoinFacts page or
+	// auction archives. Lookups fill in the ones marked auto; the rest were
+	// added by the owner.
+	References []CoinReference `gorm:"type:jsonb;serializer:json" json:"references"`
+	// Series is the catalog series the coin belongs to; mintage is how many
+	// of its year and mint were struck, from PCGS CoinFacts (0 if unknown)
+	Series  string `json:"series"`
+	Mintage int64  `json:"mintage"`
+	// EnrichmentStatus is "pending" while a new coin's cert waits to be
+	// looked up at PCGS, then "done", or "failed" once it couldn't be after
+	// a few attempts. Coins with nothing to look up leave it empty.
+	EnrichmentStatus   string `gorm:"index" json:"enrichment_status,omitempty"`
+	EnrichmentAttempts int    `gorm:"not null;default:0" json:"-"`
+	// Watched coins have their coin alerts evaluated; unwatching pauses them
+	Watched   bool      `gorm:"index" json:"watched"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the coin is in the trash, on its own or with
+	// its portfolio. Archived coins are never trashed.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// Derived from the values above when a coin is returned; never stored.
+	// Premium over melt is per coin, gain/loss covers the whole quantity
+	// against its all-in cost.
+	PremiumOverMelt float64 `gorm:"-" json:"premium_over_melt"`
+	GainLoss        float64 `gorm:"-" json:"gain_loss"`
+	GainLossPercent float64 `gorm:"-" json:"gain_loss_percent"`
 }
 
 func (c *Coin) BeforeCreate(tx *gorm.DB) error {
@@ -70,14 +400,200 @@ func (c *Coin) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+func (c *Coin) AfterCreate(tx *gorm.DB) error {
+	c.Code = coincode.Format(c.Number)
+	return nil
+}
+
+func (c *Coin) AfterFind(tx *gorm.DB) error {
+	c.Code = coincode.Format(c.Number)
+	return nil
+}
+
+// CoinReference is a typed link to literature or a reference site about a
+// coin
+type CoinReference struct {
+	Type  string `json:"type"` // "pcgs_coinfacts", "pcgs_cert", "ngc_coin_explorer", "auction_archive", "literature" or "other"
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Auto  bool   `json:"auto"` // filled in by a lookup and rebuilt when the coin changes
+}
+
+// ArchivedCoin is a coin that was sold or otherwise disposed of. Archived
+// coins live in their own table so the queries behind coin lists and stats
+// only scan coins still held, while historical reports read both. A coin
+// keeps its ID when archived, so its price history and images stay linked.
+type ArchivedCoin struct {
+	Coin
+	Disposition   string    `gorm:"not null;index" json:"disposition"` // "sold", "gifted", "melted", "lost", "regraded" or "other"
+	DisposedAt    time.Time `gorm:"not null;index" json:"disposed_at"`
+	SalePrice     float64   `json:"sale_price"` // total proceeds for all of quantity
+	SaleFees      float64   `json:"sale_fees"`  // commissions, shipping and other selling costs
+	DisposalNotes string    `json:"disposal_notes"`
+	// ReplacedByID is the coin that took over from a regraded coin
+	ReplacedByID *uuid.UUID `gorm:"type:uuid;index" json:"replaced_by_id"`
+	ArchivedAt   time.Time  `json:"archived_at"`
+}
+
+func (ArchivedCoin) TableName() string { return "archived_coins" }
+
+// Lot is a single purchase of several coins at one price. Its totals are
+// split across the coins' purchase price and fees by Allocation.
+type Lot struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name          string     `gorm:"not null" json:"name"`
+	Seller        string     `json:"seller"`
+	InvoiceNumber string     `json:"invoice_number"`
+	PurchaseDate  *time.Time `json:"purchase_date"`
+	TotalPrice    float64    `json:"total_price"` // hammer price for the whole lot
+	BuyersPremium float64    `json:"buyers_premium"`
+	ShippingCost  float64    `json:"shipping_cost"`
+	SalesTax      float64    `json:"sales_tax"`
+	Allocation    string     `gorm:"not null;default:'melt_weight'" json:"allocation"` // "melt_weight" or "manual"
+	Notes         string     `json:"notes"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (l *Lot) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+// Notification tells a user what a background process did, e.g. an alert
+// firing or a scheduled sync finishing
+type Notification struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index:idx_notifications_user_created,priority:1" json:"user_id"`
+	Kind        string     `gorm:"not null" json:"kind"` // "alert", "pcgs_sync", "statement", "transfer", "emergency_access", "member", "spot_prices", "cert_check" or "job"
+	Title       string     `gorm:"not null" json:"title"`
+	Body        string     `json:"body"`
+	PortfolioID *uuid.UUID `gorm:"type:uuid" json:"portfolio_id,omitempty"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `gorm:"index:idx_notifications_user_created,priority:2" json:"created_at"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationSettings holds where a user can be reached on channels beyond
+// in-app notifications and email
+type NotificationSettings struct {
+	UserID         uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	Phone          string    `json:"phone"` // E.164, for SMS
+	TelegramChatID string    `json:"telegram_chat_id"`
+	// Web Push subscription from the browser's PushManager
+	PushEndpoint string    `json:"-"`
+	PushP256dh   string    `json:"-"`
+	PushAuth     string    `json:"-"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserSettings holds how a user wants amounts, weights and dates shown, and
+// the portfolio coins go in when none is given. A user without a row has
+// the defaults: US dollars, troy ounces and ISO dates.
+type UserSettings struct {
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	Currency   string    `gorm:"not null;default:'USD'" json:"currency"`        // ISO 4217 code
+	WeightUnit string    `gorm:"not null;default:'troy_oz'" json:"weight_unit"` // "troy_oz" or "g"
+	DateFormat string    `gorm:"not null;default:'iso'" json:"date_format"`     // "iso", "us" or "eu"
+	// DefaultPortfolioID is where a new coin goes when it doesn't say
+	DefaultPortfolioID *uuid.UUID `gorm:"type:uuid" json:"default_portfolio_id"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// Job is a long operation a user started, e.g. a PCGS sync or an import,
+// running in the background. Clients poll it for progress; once it's done,
+// Result holds what the operation reports.
+type Job struct {
+	ID     uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index:idx_jobs_user_created,priority:1" json:"user_id"`
+	Kind   string    `gorm:"not null;index" json:"kind"`   // e.g. "pcgs_sync", "import"
+	Status string    `gorm:"not null;index" json:"status"` // "queued", "running", "succeeded" or "failed"
+	// Progress: how many of the operation's items are done, out of Total
+	// (0 until it knows)
+	Done       int        `gorm:"not null;default:0" json:"done"`
+	Total      int        `gorm:"not null;default:0" json:"total"`
+	Result     any        `gorm:"type:jsonb;serializer:json" json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `gorm:"index:idx_jobs_user_created,priority:2" json:"created_at"`
+	// UpdatedAt doubles as the running job's heartbeat
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
+
+func (j *Job) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// AuctionComparable is a sold auction lot stored as a price comparable
+type AuctionComparable struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Source    string    `gorm:"not null;uniqueIndex:idx_auction_comparables_lot" json:"source"` // e.g. "heritage"
+	LotID     string    `gorm:"not null;uniqueIndex:idx_auction_comparables_lot" json:"lot_id"`
+	CoinType  string    `gorm:"not null;index" json:"coin_type"` // the coin type it was fetched for
+	Title     string    `json:"title"`
+	Year      int       `json:"year"`
+	MintMark  string    `json:"mint_mark"`
+	Grade     string    `gorm:"index" json:"grade"`
+	Service   string    `json:"service"`
+	Price     float64   `json:"price"`
+	SoldAt    time.Time `gorm:"index" json:"sold_at"`
+	URL       string    `json:"url"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+func (a *AuctionComparable) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// CoinImage is a certification image variant. Archived images are copies
+// kept in image storage, so the coin keeps its photos if the grading
+// service's URLs change; the rest still point at the source.
+type CoinImage struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	CoinID      uuid.UUID `gorm:"type:uuid;not null;index" json:"coin_id"`
+	Position    int       `json:"position"`    // order in the grading service's response
+	Description string    `json:"description"` // e.g. "Obverse", "TrueView"
+	Resolution  string    `json:"resolution"`  // e.g. "Large"
+	Side        string    `json:"side"`        // "obverse", "reverse" or "" when unknown
+	TrueView    bool      `json:"trueview"`
+	SourceURL   string    `json:"source_url"`
+	URL         string    `json:"url"`
+	FileName    string    `json:"-"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (i *CoinImage) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
 type PriceHistory struct {
-	ID               uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
-	CoinID           uuid.UUID  `gorm:"type:uuid;not null;index" json:"coin_id"`
-	MeltValue        float64    `json:"melt_value"`
-	NumismaticValue  float64    `json:"numismatic_value"`
-	PCGSValue        float64    `json:"pcgs_value"`
-	RecordedAt       time.Time  `gorm:"index" json:"recorded_at"`
-	CreatedAt        time.Time  `json:"created_at"`
+	ID              uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid();index:idx_price_histories_coin_recorded,priority:3" json:"id"`
+	CoinID          uuid.UUID `gorm:"type:uuid;not null;index;index:idx_price_histories_coin_recorded,priority:1" json:"coin_id"`
+	MeltValue       float64   `json:"melt_value"`
+	NumismaticValue float64   `json:"numismatic_value"`
+	PCGSValue       float64   `json:"pcgs_value"`
+	RecordedAt      time.Time `gorm:"not null;index;index:idx_price_histories_coin_recorded,priority:2" json:"recorded_at"` // partition key, by month
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 func (p *PriceHistory) BeforeCreate(tx *gorm.DB) error {
@@ -87,10 +603,328 @@ func (p *PriceHistory) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// PortfolioAlert notifies a user when a portfolio's total melt value crosses a threshold
+type PortfolioAlert struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	PortfolioID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"portfolio_id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Condition       string     `gorm:"not null" json:"condition"` // "above" or "below"
+	Threshold       float64    `gorm:"not null" json:"threshold"`
+	Enabled         bool       `gorm:"default:true" json:"enabled"`
+	Channels        []string   `gorm:"type:jsonb;serializer:json" json:"channels"`
+	Triggered       bool       `json:"triggered"` // true while the condition holds, so we only notify on crossing
+	LastValue       float64    `json:"last_value"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (a *PortfolioAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// CoinAlert notifies a user about one watched coin: when its melt or current
+// value per coin crosses a threshold, or whenever its PCGS guide value changes
+type CoinAlert struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	CoinID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"coin_id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Metric          string     `json:"metric"`                    // "melt_value" or "current_value"; empty for pcgs_change
+	Condition       string     `gorm:"not null" json:"condition"` // "above", "below" or "pcgs_change"
+	Threshold       float64    `json:"threshold"`
+	Enabled         bool       `json:"enabled"`
+	Channels        []string   `gorm:"type:jsonb;serializer:json" json:"channels"`
+	Triggered       bool       `json:"triggered"`
+	LastValue       float64    `json:"last_value"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (a *CoinAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// SpotAlert notifies a user when a spot price, or the gold/silver ratio,
+// crosses a threshold or moves by a percentage within a day
+type SpotAlert struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID          uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Metric          string     `gorm:"not null" json:"metric"`    // a metal, or "gold_silver_ratio"
+	Condition       string     `gorm:"not null" json:"condition"` // "above", "below", "rises", "falls" or "moves"
+	Threshold       float64    `gorm:"not null" json:"threshold"` // a price or ratio, or a percentage for rises/falls/moves
+	Enabled         bool       `gorm:"default:true" json:"enabled"`
+	Channels        []string   `gorm:"type:jsonb;serializer:json" json:"channels"`
+	Triggered       bool       `json:"triggered"`
+	LastValue       float64    `json:"last_value"`
+	LastChange      float64    `json:"last_change"` // percent change over the last day
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (a *SpotAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// SpotPriceHistory is a live spot price refresh, kept for a few days so spot
+// alerts can measure daily moves
+type SpotPriceHistory struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Gold       float64   `json:"gold"`
+	Silver     float64   `json:"silver"`
+	Platinum   float64   `json:"platinum"`
+	Palladium  float64   `json:"palladium"`
+	RecordedAt time.Time `gorm:"index" json:"recorded_at"`
+}
+
+func (h *SpotPriceHistory) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
+
+// SpotPriceClose is the spot prices at a market close: the last live refresh
+// before it. Closes are kept for good, one per trading day.
+type SpotPriceClose struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ClosedAt   time.Time `gorm:"uniqueIndex" json:"closed_at"`
+	Gold       float64   `json:"gold"`
+	Silver     float64   `json:"silver"`
+	Platinum   float64   `json:"platinum"`
+	Palladium  float64   `json:"palladium"`
+	RecordedAt time.Time `json:"recorded_at"` // when the prices were fetched
+}
+
+func (c *SpotPriceClose) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// FXRate is an exchange rate recorded on a refresh: how many units of
+// Currency one US dollar bought
+type FXRate struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Currency   string    `gorm:"not null;index:idx_fx_rates_currency_recorded_at" json:"currency"`
+	Rate       float64   `gorm:"not null" json:"rate"`
+	RecordedAt time.Time `gorm:"not null;index:idx_fx_rates_currency_recorded_at" json:"recorded_at"`
+}
+
+func (FXRate) TableName() string { return "fx_rates" }
+
+func (r *FXRate) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// FallbackPrice replaces the built-in price a metal falls back to when no
+// live source quotes it, instance-wide
+type FallbackPrice struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Metal      string     `gorm:"uniqueIndex;not null" json:"metal"`
+	Price      float64    `gorm:"not null" json:"price"`
+	ReviewedAt time.Time  `gorm:"not null" json:"reviewed_at"` // when an admin last checked it against the market
+	ReviewedBy *uuid.UUID `gorm:"type:uuid" json:"reviewed_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (p *FallbackPrice) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// CompositionOverride replaces or adds a catalog composition instance-wide,
+// layered over the compositions built into the release
+type CompositionOverride struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	CoinType      string     `gorm:"uniqueIndex;not null" json:"coin_type"`
+	MetalType     string     `gorm:"not null" json:"metal_type"`
+	Weight        float64    `json:"weight"` // troy ounces of pure metal
+	Purity        float64    `json:"purity"`
+	Description   string     `json:"description"`
+	IsBaseMetal   bool       `json:"is_base_metal"`
+	WeightGrams   float64    `json:"weight_grams"`
+	CopperPercent float64    `json:"copper_percent"`
+	NickelPercent float64    `json:"nickel_percent"`
+	UpdatedBy     *uuid.UUID `gorm:"type:uuid" json:"updated_by"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (o *CompositionOverride) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// CertWatchEntry is a range of cert numbers known to be on counterfeit
+// slabs or otherwise problem holders, e.g. from a grading service's or
+// dealer association's counterfeit database. Coins with a cert in the range
+// are flagged for their owner to verify.
+type CertWatchEntry struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	RangeStart int64      `gorm:"not null;index" json:"range_start"`
+	RangeEnd   int64      `gorm:"not null;index" json:"range_end"`
+	Reason     string     `gorm:"not null" json:"reason"`
+	Source     string     `json:"source"` // where it was reported, e.g. a URL
+	CreatedBy  *uuid.UUID `gorm:"type:uuid" json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (e *CertWatchEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// RegistrySet publishes a portfolio as a set on the instance's public club
+// leaderboard. Its slots are the years StartYear to EndYear of CoinType.
+type RegistrySet struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	PortfolioID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"portfolio_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TenantID    *uuid.UUID `gorm:"type:uuid;index" json:"tenant_id,omitempty"`
+	Title       string     `gorm:"not null" json:"title"`
+	DisplayName string     `gorm:"not null" json:"display_name"` // shown publicly instead of the owner's email
+	CoinType    string     `gorm:"not null;index" json:"coin_type"`
+	StartYear   int        `gorm:"not null" json:"start_year"`
+	EndYear     int        `gorm:"not null" json:"end_year"`
+	Published   bool       `gorm:"not null;default:false;index" json:"published"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (s *RegistrySet) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// CoinTransfer offers a coin to another user on the instance, e.g. a gift
+// within a family or a dealer's handoff to a customer. The coin only moves
+// once the recipient accepts it into one of their portfolios.
+type CoinTransfer struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	CoinID     uuid.UUID `gorm:"type:uuid;not null;index" json:"coin_id"`
+	FromUserID uuid.UUID `gorm:"type:uuid;not null;index" json:"from_user_id"` // the coin's portfolio's creator
+	ToUserID   uuid.UUID `gorm:"type:uuid;not null;index" json:"to_user_id"`
+	Status     string    `gorm:"not null;default:'pending';index" json:"status"` // "pending", "accepted", "declined" or "cancelled"
+	// InitiatedByID is the co-owner who made the offer, when it wasn't the
+	// portfolio's creator
+	InitiatedByID *uuid.UUID `gorm:"type:uuid;index" json:"initiated_by_id,omitempty"`
+	// What moves with the coin: its purchase price, fees and date, its price
+	// history, and its photos
+	KeepCostBasis  bool       `gorm:"not null" json:"keep_cost_basis"`
+	IncludeHistory bool       `gorm:"not null" json:"include_history"`
+	IncludeImages  bool       `gorm:"not null" json:"include_images"`
+	Message        string     `json:"message"`
+	ToPortfolioID  *uuid.UUID `gorm:"type:uuid" json:"to_portfolio_id,omitempty"` // where the recipient put it
+	RespondedAt    *time.Time `json:"responded_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (t *CoinTransfer) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// PortfolioMember shares a portfolio with another account on the instance,
+// e.g. a spouse or business partner. The account that created the portfolio
+// is always its owner; members are invited as another owner, an editor, who
+// manages its coins, or a viewer, who only reads them. The invitation is
+// pending until the member accepts it.
+type PortfolioMember struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	PortfolioID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_portfolio_members_pair" json:"portfolio_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_portfolio_members_pair;index" json:"user_id"`
+	Role        string     `gorm:"not null" json:"role"` // "owner", "editor" or "viewer"
+	InvitedBy   uuid.UUID  `gorm:"type:uuid;not null" json:"invited_by"`
+	AcceptedAt  *time.Time `json:"accepted_at"` // nil while the invitation is pending
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (m *PortfolioMember) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// EmergencyContact designates a second account, e.g. an executor, that can
+// get read-only access to a user's portfolios if the user dies or is locked
+// out. Access needs the contact's request, an admin's approval and then a
+// waiting period during which the owner can still deny it.
+type EmergencyContact struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_emergency_contacts_pair" json:"user_id"`
+	ContactUserID uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_emergency_contacts_pair;index" json:"contact_user_id"`
+	WaitingDays   int        `gorm:"not null" json:"waiting_days"`
+	Status        string     `gorm:"not null;default:'designated';index" json:"status"` // "designated", "requested" or "approved"
+	Reason        string     `json:"reason"`                                            // the contact's explanation of their request
+	RequestedAt   *time.Time `json:"requested_at"`
+	ApprovedAt    *time.Time `json:"approved_at"`
+	ApprovedBy    *uuid.UUID `gorm:"type:uuid" json:"approved_by,omitempty"`
+	AvailableAt   *time.Time `json:"available_at"` // approval plus the waiting period
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (e *EmergencyContact) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
 type PortfolioStats struct {
-	TotalCoins        int64   `json:"total_coins"`
-	TotalValue        float64 `json:"total_value"`
-	TotalPurchaseCost float64 `json:"total_purchase_cost"`
-	TotalGainLoss     float64 `json:"total_gain_loss"`
-	GainLossPercent   float64 `json:"gain_loss_percent"`
+	Currency             string  `json:"currency"` // ISO 4217 code of every amount below
+	TotalCoins           int64   `json:"total_coins"`
+	TotalValue           float64 `json:"total_value"`
+	TotalHammerPrice     float64 `json:"total_hammer_price"`     // purchase prices × quantity
+	TotalAcquisitionFees float64 `json:"total_acquisition_fees"` // buyer's premiums, shipping and tax
+	TotalPurchaseCost    float64 `json:"total_purchase_cost"`    // all-in: hammer price plus fees
+	TotalGainLoss        float64 `json:"total_gain_loss"`
+	GainLossPercent      float64 `json:"gain_loss_percent"`
+	// US face value of all coins, and of circulating (90%/40%/35%) silver coins
+	TotalFaceValue      float64 `json:"total_face_value"`
+	JunkSilverFaceValue float64 `json:"junk_silver_face_value"`
+	// Only set when inflation-adjusted figures are requested
+	InflationAdjusted *RealPerformance `json:"inflation_adjusted,omitempty"`
+}
+
+// RealPerformance restates a purchase cost in today's dollars using the CPI,
+// so gains are measured against what the money would buy now
+type RealPerformance struct {
+	PurchaseCost    float64 `json:"purchase_cost"`
+	GainLoss        float64 `json:"gain_loss"`
+	GainLossPercent float64 `json:"gain_loss_percent"`
+	CPISource       string  `json:"cpi_source"`
+	CPIPeriod       string  `json:"cpi_period"` // latest CPI period used for "today"
 }
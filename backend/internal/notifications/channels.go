@@ -0,0 +1,240 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"github.com/google/uuid"
+)
+
+// Delivery channels besides in-app notifications
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelPush     = "push"
+	ChannelTelegram = "telegram"
+)
+
+// Channel delivers notifications outside the app
+type Channel interface {
+	// Configured reports whether the instance has credentials for the channel
+	Configured() bool
+	// Reachable reports whether the user has set up the channel
+	Reachable(user models.User, settings models.NotificationSettings) bool
+	Send(user models.User, settings models.NotificationSettings, n models.Notification) error
+}
+
+var channels = map[string]Channel{
+	ChannelEmail:    emailChannel{},
+	ChannelSMS:      twilioChannel{},
+	ChannelPush:     webPushChannel{},
+	ChannelTelegram: telegramChannel{},
+}
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// ValidChannel reports whether name is a known channel
+func ValidChannel(name string) bool {
+	_, ok := channels[name]
+	return ok
+}
+
+// ValidPhone reports whether phone is an E.164 number, e.g. +15555550123
+func ValidPhone(phone string) bool {
+	return phonePattern.MatchString(phone)
+}
+
+// Available reports which channels the instance can deliver on
+func Available() map[string]bool {
+	available := make(map[string]bool, len(channels))
+	for name, channel := range channels {
+		available[name] = channel.Configured()
+	}
+	return available
+}
+
+// ChannelNames lists every known channel
+func ChannelNames() []string {
+	names := make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Settings returns the user's notification settings, empty if never saved
+func Settings(userID uuid.UUID) (models.NotificationSettings, error) {
+	settings := models.NotificationSettings{UserID: userID}
+	err := database.GetDB().Where("user_id = ?", userID).Limit(1).Find(&settings).Error
+	return settings, err
+}
+
+// Deliver sends a notification on each of the named channels. Channels the
+// instance or the user hasn't set up are skipped; failures are logged.
+func Deliver(n models.Notification, names []string) {
+	if len(names) == 0 {
+		return
+	}
+
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", n.UserID).Error; err != nil {
+		log.Printf("Failed to load user %s for notification delivery: %v", n.UserID, err)
+		return
+	}
+	settings, err := Settings(n.UserID)
+	if err != nil {
+		log.Printf("Failed to load notification settings for user %s: %v", n.UserID, err)
+		return
+	}
+
+	for _, name := range names {
+		channel, ok := channels[name]
+		switch {
+		case !ok:
+			log.Printf("Notification for user %s: unknown channel %q", n.UserID, name)
+		case !channel.Configured():
+			log.Printf("Notification for user %s: %s is not configured on this instance", n.UserID, name)
+		case !channel.Reachable(user, settings):
+			log.Printf("Notification for user %s: %s is not set up", n.UserID, name)
+		default:
+			if err := channel.Send(user, settings, n); err != nil {
+				log.Printf("Notification for user %s: %s delivery failed: %v", n.UserID, name, err)
+			}
+		}
+	}
+}
+
+// text is the plain-text form of a notification for SMS and chat
+func text(n models.Notification) string {
+	if n.Body == "" {
+		return "Aureus: " + n.Title
+	}
+	return "Aureus: " + n.Title + "\n" + n.Body
+}
+
+// emailChannel mails the notification; without SMTP it is logged like other mail
+type emailChannel struct{}
+
+func (emailChannel) Configured() bool { return true }
+
+func (emailChannel) Reachable(user models.User, _ models.NotificationSettings) bool {
+	return user.Email != ""
+}
+
+func (emailChannel) Send(user models.User, _ models.NotificationSettings, n models.Notification) error {
+	body := n.Body
+	if body != "" {
+		body += "\n\n"
+	}
+	return mail.Send(user.Email, n.Title, body+mail.AppURL()+"/dashboard")
+}
+
+// twilioChannel sends SMS through Twilio's Messages API
+type twilioChannel struct{}
+
+func (twilioChannel) Configured() bool {
+	return config.String("TWILIO_ACCOUNT_SID", "") != "" &&
+		config.String("TWILIO_AUTH_TOKEN", "") != "" &&
+		config.String("TWILIO_FROM_NUMBER", "") != "" &&
+		!config.MockMode()
+}
+
+func (twilioChannel) Reachable(_ models.User, settings models.NotificationSettings) bool {
+	return settings.Phone != ""
+}
+
+func (twilioChannel) Send(_ models.User, settings models.NotificationSettings, n models.Notification) error {
+	sid := config.String("TWILIO_ACCOUNT_SID", "")
+	form := url.Values{
+		"To":   {settings.Phone},
+		"From": {config.String("TWILIO_FROM_NUMBER", "")},
+		"Body": {text(n)},
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		"https://api.twilio.com/2010-04-01/Accounts/"+url.PathEscape(sid)+"/Messages.json",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(sid, config.String("TWILIO_AUTH_TOKEN", ""))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	err = post(req)
+	usage.RecordCall(usage.ServiceTwilio, err)
+	return err
+}
+
+// telegramChannel sends messages from a Telegram bot to the user's chat
+type telegramChannel struct{}
+
+func (telegramChannel) Configured() bool {
+	return config.String("TELEGRAM_BOT_TOKEN", "") != "" && !config.MockMode()
+}
+
+func (telegramChannel) Reachable(_ models.User, settings models.NotificationSettings) bool {
+	return settings.TelegramChatID != ""
+}
+
+func (telegramChannel) Send(_ models.User, settings models.NotificationSettings, n models.Notification) error {
+	payload, err := json.Marshal(map[string]string{
+		"chat_id": settings.TelegramChatID,
+		"text":    text(n),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost,
+		"https://api.telegram.org/bot"+config.String("TELEGRAM_BOT_TOKEN", "")+"/sendMessage",
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	err = post(req)
+	usage.RecordCall(usage.ServiceTelegram, err)
+	return err
+}
+
+// post sends req and turns non-2xx responses into errors
+func post(req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &statusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
+
+type statusError struct {
+	Code int
+	Body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.Code, e.Body)
+}
@@ -0,0 +1,325 @@
+package notifications
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/alerts"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/transfers"
+)
+
+// Notification kinds
+const (
+	KindAlert     = "alert"
+	KindPCGSSync  = "pcgs_sync"
+	KindStatement = "statement"
+	KindTransfer  = "transfer"
+	KindEmergency = "emergency_access"
+	KindMember    = "member"
+	KindSpotPrice = "spot_prices"
+	KindCertCheck = "cert_check"
+	KindJob       = "job"
+)
+
+// jobLabels name each kind of background job in notifications
+var jobLabels = map[string]string{
+	jobs.KindPCGSSync:             "PCGS value sync",
+	jobs.KindImport:               "Import",
+	jobs.KindPriceHistoryBackfill: "Price history backfill",
+	jobs.KindCompositionBackfill:  "Composition backfill",
+	jobs.KindStaleValueRefresh:    "Stale value refresh",
+	jobs.KindStatement:            "Statement",
+}
+
+// Create stores a notification for a user
+func Create(n models.Notification) error {
+	return database.GetDB().Create(&n).Error
+}
+
+func notify(n models.Notification) {
+	if err := Create(n); err != nil {
+		log.Printf("Failed to store %s notification for user %s: %v", n.Kind, n.UserID, err)
+	}
+}
+
+// Subscribe turns background events into in-app notifications
+func Subscribe() {
+	events.Subscribe(events.TypeAlertFired, func(e events.Event) {
+		fired := e.(events.AlertFired)
+
+		name := "Portfolio"
+		var portfolio models.Portfolio
+		if err := database.GetDB().Select("name").First(&portfolio, "id = ?", fired.PortfolioID).Error; err == nil {
+			name = portfolio.Name
+		}
+
+		portfolioID := fired.PortfolioID
+		n := models.Notification{
+			UserID:      fired.UserID,
+			Kind:        KindAlert,
+			Title:       fmt.Sprintf("%s is %s $%.2f", name, fired.Condition, fired.Threshold),
+			Body:        fmt.Sprintf("Melt value reached $%.2f.", fired.Value),
+			PortfolioID: &portfolioID,
+		}
+		notify(n)
+		Deliver(n, fired.Channels)
+	})
+
+	events.Subscribe(events.TypeSpotAlertFired, func(e events.Event) {
+		fired := e.(events.SpotAlertFired)
+
+		name, value, threshold := "", fmt.Sprintf("$%.2f", fired.Value), fmt.Sprintf("$%.2f", fired.Threshold)
+		switch fired.Metric {
+		case alerts.MetricGoldSilverRatio:
+			name, value, threshold = "Gold/silver ratio", fmt.Sprintf("%.1f", fired.Value), fmt.Sprintf("%.1f", fired.Threshold)
+		case alerts.MetricPlatinumGoldRatio:
+			name, value, threshold = "Platinum/gold ratio", fmt.Sprintf("%.3f", fired.Value), fmt.Sprintf("%.3f", fired.Threshold)
+		default:
+			name = strings.ToUpper(fired.Metric[:1]) + fired.Metric[1:]
+		}
+
+		title := fmt.Sprintf("%s is %s %s", name, fired.Condition, threshold)
+		if alerts.IsPercentCondition(fired.Condition) {
+			title = fmt.Sprintf("%s %s %.1f%% in a day", name, fired.Condition, fired.Threshold)
+		}
+
+		n := models.Notification{
+			UserID: fired.UserID,
+			Kind:   KindAlert,
+			Title:  title,
+			Body:   fmt.Sprintf("Now %s, %+.1f%% over the last day.", value, fired.Change),
+		}
+		notify(n)
+		Deliver(n, fired.Channels)
+	})
+
+	events.Subscribe(events.TypeCoinAlertFired, func(e events.Event) {
+		fired := e.(events.CoinAlertFired)
+		label := transfers.Label(fired.Coin)
+
+		n := models.Notification{
+			UserID:      fired.UserID,
+			Kind:        KindAlert,
+			PortfolioID: &fired.Coin.PortfolioID,
+		}
+		if fired.Condition == alerts.ConditionPCGSChange {
+			n.Title = fmt.Sprintf("PCGS guide for your %s changed", label)
+			n.Body = fmt.Sprintf("Now $%.2f, was $%.2f.", fired.Value, fired.Previous)
+		} else {
+			metric := "Melt value"
+			if fired.Metric == alerts.MetricCurrentValue {
+				metric = "Value"
+			}
+			n.Title = fmt.Sprintf("Your %s is %s $%.2f", label, fired.Condition, fired.Threshold)
+			n.Body = fmt.Sprintf("%s reached $%.2f.", metric, fired.Value)
+		}
+		notify(n)
+		Deliver(n, fired.Channels)
+	})
+
+	events.Subscribe(events.TypePCGSSyncCompleted, func(e events.Event) {
+		done := e.(events.PCGSSyncCompleted)
+		if done.Updated == 0 && done.Failed == 0 {
+			return
+		}
+
+		body := fmt.Sprintf("%d of %d coins updated", done.Updated, done.Total)
+		if done.Skipped > 0 {
+			body += fmt.Sprintf(", %d recently synced", done.Skipped)
+		}
+		if done.Failed > 0 {
+			body += fmt.Sprintf(", %d failed", done.Failed)
+		}
+		notify(models.Notification{
+			UserID: done.UserID,
+			Kind:   KindPCGSSync,
+			Title:  "PCGS values synced",
+			Body:   body + ".",
+		})
+	})
+
+	events.Subscribe(events.TypeStatementSent, func(e events.Event) {
+		sent := e.(events.StatementSent)
+		portfolioID := sent.PortfolioID
+		notify(models.Notification{
+			UserID:      sent.UserID,
+			Kind:        KindStatement,
+			Title:       fmt.Sprintf("%s statement for %s", sent.Period, sent.PortfolioName),
+			Body:        "Your monthly statement was emailed.",
+			PortfolioID: &portfolioID,
+		})
+	})
+
+	events.Subscribe(events.TypeCoinTransfer, func(e events.Event) {
+		updated := e.(events.CoinTransferUpdated)
+		t := updated.Transfer
+
+		n := models.Notification{Kind: KindTransfer}
+		switch t.Status {
+		case transfers.StatusPending:
+			n.UserID = t.ToUserID
+			n.Title = fmt.Sprintf("%s wants to give you a %s", updated.FromEmail, updated.CoinLabel)
+			n.Body = "Accept it into one of your portfolios or decline it."
+			if t.Message != "" {
+				n.Body = fmt.Sprintf("%q %s", t.Message, n.Body)
+			}
+		case transfers.StatusAccepted, transfers.StatusDeclined:
+			n.UserID = t.FromUserID
+			n.Title = fmt.Sprintf("%s %s your %s", updated.ToEmail, t.Status, updated.CoinLabel)
+			// The co-owner who made the offer hears back too
+			if t.InitiatedByID != nil {
+				initiated := n
+				initiated.UserID = *t.InitiatedByID
+				notify(initiated)
+			}
+		default:
+			return
+		}
+		notify(n)
+	})
+
+	events.Subscribe(events.TypePortfolioMember, func(e events.Event) {
+		updated := e.(events.PortfolioMemberUpdated)
+		m := updated.Member
+
+		n := models.Notification{UserID: m.UserID, Kind: KindMember}
+		switch updated.Action {
+		case events.MemberInvited:
+			n.Title = fmt.Sprintf("%s invited you to %s", updated.ByEmail, updated.PortfolioName)
+			n.Body = fmt.Sprintf("Accept the invitation to join it as %s, or decline it.", withArticle(m.Role))
+		case events.MemberChanged:
+			n.Title = fmt.Sprintf("You're now %s of %s", withArticle(m.Role), updated.PortfolioName)
+		case events.MemberRemoved:
+			if updated.ByEmail == "" {
+				n.UserID = m.InvitedBy
+				n.Title = fmt.Sprintf("%s left %s", updated.MemberEmail, updated.PortfolioName)
+			} else {
+				n.Title = fmt.Sprintf("%s removed you from %s", updated.ByEmail, updated.PortfolioName)
+			}
+		case events.MemberAccepted, events.MemberDeclined:
+			n.UserID = m.InvitedBy
+			n.Title = fmt.Sprintf("%s %s your invitation to %s", updated.MemberEmail, updated.Action, updated.PortfolioName)
+		default:
+			return
+		}
+		notify(n)
+	})
+
+	// The owner hears about every step towards someone reading their account,
+	// by email too, so they can deny it in time
+	events.Subscribe(events.TypeEmergencyAccess, func(e events.Event) {
+		updated := e.(events.EmergencyAccessUpdated)
+		contact := updated.Contact
+
+		toContact := models.Notification{UserID: contact.ContactUserID, Kind: KindEmergency}
+		toOwner := models.Notification{UserID: contact.UserID, Kind: KindEmergency}
+		switch updated.Action {
+		case events.EmergencyDesignated:
+			toContact.Title = fmt.Sprintf("%s named you their emergency contact", updated.OwnerEmail)
+			toContact.Body = "You can request read-only access to their portfolios if they can no longer manage them."
+			notify(toContact)
+		case events.EmergencyRequested:
+			toOwner.Title = fmt.Sprintf("%s requested emergency access to your account", updated.ContactEmail)
+			toOwner.Body = fmt.Sprintf("Reason: %s. If you didn't expect this, deny it under your emergency contacts.", contact.Reason)
+			notify(toOwner)
+			Deliver(toOwner, []string{ChannelEmail})
+		case events.EmergencyApproved:
+			opens := contact.AvailableAt.Format("January 2, 2006")
+			toOwner.Title = fmt.Sprintf("Emergency access for %s was approved", updated.ContactEmail)
+			toOwner.Body = fmt.Sprintf("They can read your portfolios from %s unless you deny it before then.", opens)
+			notify(toOwner)
+			Deliver(toOwner, []string{ChannelEmail})
+			toContact.Title = fmt.Sprintf("Emergency access to %s's account was approved", updated.OwnerEmail)
+			toContact.Body = fmt.Sprintf("It opens on %s.", opens)
+			notify(toContact)
+		case events.EmergencyDenied:
+			toContact.Title = fmt.Sprintf("Your emergency access request for %s was denied", updated.OwnerEmail)
+			notify(toContact)
+		}
+	})
+
+	// Fallback prices can quietly misvalue every coin of a metal, so admins
+	// hear about it by email too
+	events.Subscribe(events.TypeSpotPricesDegraded, func(e events.Event) {
+		degraded := e.(events.SpotPricesDegraded)
+
+		var admins []models.User
+		if err := database.GetDB().Select("id").Where("is_admin = ?", true).Find(&admins).Error; err != nil {
+			log.Printf("Failed to find admins to warn about fallback spot prices: %v", err)
+			return
+		}
+		for _, admin := range admins {
+			n := models.Notification{
+				UserID: admin.ID,
+				Kind:   KindSpotPrice,
+				Title:  fmt.Sprintf("Spot prices for %s are fallbacks", strings.Join(degraded.Metals, ", ")),
+				Body: fmt.Sprintf("No live source has priced them since %s, so melt values use built-in prices that may be out of date.",
+					degraded.Since.UTC().Format("Jan 2 15:04 MST")),
+			}
+			notify(n)
+			Deliver(n, []string{ChannelEmail})
+		}
+	})
+
+	events.Subscribe(events.TypeCertFlagged, func(e events.Event) {
+		flagged := e.(events.CertFlagged)
+		coin := flagged.Coin
+
+		portfolioID := coin.PortfolioID
+		notify(models.Notification{
+			UserID:      flagged.UserID,
+			Kind:        KindCertCheck,
+			Title:       fmt.Sprintf("Verify cert %s on your %d %s", coin.PCGSCertNumber, coin.Year, coin.CoinType),
+			Body:        strings.Join(coin.CertFlags, ". ") + ". Check the slab against PCGS's cert verification before relying on it.",
+			PortfolioID: &portfolioID,
+		})
+	})
+
+	// Long operations run as jobs; their users have usually moved on by the
+	// time they finish. A sent statement announces itself.
+	events.Subscribe(events.TypeJobFinished, func(e events.Event) {
+		job := e.(events.JobFinished).Job
+		if job.Kind == jobs.KindStatement && job.Status == jobs.StatusSucceeded {
+			return
+		}
+
+		label, ok := jobLabels[job.Kind]
+		if !ok {
+			label = "Background job"
+		}
+		n := models.Notification{UserID: job.UserID, Kind: KindJob, Title: label + " finished"}
+		if job.Total > 0 {
+			n.Body = fmt.Sprintf("%d of %d done.", job.Done, job.Total)
+		}
+		if job.Status == jobs.StatusFailed {
+			n.Title, n.Body = label+" failed", job.Error
+		}
+		notify(n)
+	})
+
+	// Notifications about a deleted portfolio would link nowhere
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Model(&models.Notification{}).
+			Where("portfolio_id = ?", updated.PortfolioID).
+			Update("portfolio_id", nil).Error; err != nil {
+			log.Printf("Failed to unlink notifications for portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+}
+
+// withArticle puts "a" or "an" before a role, as in "an editor"
+func withArticle(role string) string {
+	if strings.ContainsRune("aeiou", rune(role[0])) {
+		return "an " + role
+	}
+	return "a " + role
+}
@@ -0,0 +1,202 @@
+package notifications
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	pushTTL        = 24 * time.Hour
+	pushRecordSize = 4096
+)
+
+// VAPIDPublicKey is the application server key browsers need to subscribe,
+// or "" when Web Push isn't configured
+func VAPIDPublicKey() string {
+	if !(webPushChannel{}).Configured() {
+		return ""
+	}
+	return config.String("VAPID_PUBLIC_KEY", "")
+}
+
+// webPushChannel sends Web Push messages (RFC 8030) with aes128gcm payload
+// encryption (RFC 8291) and VAPID authentication (RFC 8292)
+type webPushChannel struct{}
+
+func (webPushChannel) Configured() bool {
+	return config.String("VAPID_PUBLIC_KEY", "") != "" &&
+		config.String("VAPID_PRIVATE_KEY", "") != "" &&
+		!config.MockMode()
+}
+
+func (webPushChannel) Reachable(_ models.User, settings models.NotificationSettings) bool {
+	return settings.PushEndpoint != "" && settings.PushP256dh != "" && settings.PushAuth != ""
+}
+
+func (webPushChannel) Send(user models.User, settings models.NotificationSettings, n models.Notification) error {
+	payload, err := json.Marshal(map[string]string{
+		"title": n.Title,
+		"body":  n.Body,
+		"kind":  n.Kind,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := encryptPush(payload, settings.PushP256dh, settings.PushAuth)
+	if err != nil {
+		return err
+	}
+	authorization, err := vapidAuthorization(settings.PushEndpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, settings.PushEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprint(int(pushTTL.Seconds())))
+	req.Header.Set("Authorization", authorization)
+
+	err = post(req)
+	usage.RecordCall(usage.ServiceWebPush, err)
+
+	// The browser unsubscribed; stop sending to it
+	var status *statusError
+	if errors.As(err, &status) && (status.Code == http.StatusNotFound || status.Code == http.StatusGone) {
+		database.GetDB().Model(&models.NotificationSettings{}).Where("user_id = ?", user.ID).
+			Updates(map[string]interface{}{"push_endpoint": "", "push_p256dh": "", "push_auth": ""})
+	}
+	return err
+}
+
+// decodeKey decodes the URL-safe base64 used for push keys, padded or not
+func decodeKey(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// encryptPush encrypts payload for a subscription's p256dh public key and
+// auth secret as a single aes128gcm record
+func encryptPush(payload []byte, p256dh, authSecret string) ([]byte, error) {
+	uaPublicBytes, err := decodeKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	auth, err := decodeKey(authSecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	secret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublicBytes...), asPublic...)
+	ikm, err := hkdfRead(secret, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdfRead(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfRead(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 0x02 marks the last (and only) record
+	ciphertext := gcm.Seal(nil, nonce, append(payload, 0x02), nil)
+
+	var body bytes.Buffer
+	body.Write(salt)
+	binary.Write(&body, binary.BigEndian, uint32(pushRecordSize))
+	body.WriteByte(byte(len(asPublic)))
+	body.Write(asPublic)
+	body.Write(ciphertext)
+	return body.Bytes(), nil
+}
+
+func hkdfRead(secret, salt, info []byte, n int) ([]byte, error) {
+	out := make([]byte, n)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// vapidAuthorization signs a VAPID token for the push service at endpoint
+func vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid push endpoint")
+	}
+
+	rawKey, err := decodeKey(config.String("VAPID_PRIVATE_KEY", ""))
+	if err != nil {
+		return "", fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), rawKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid VAPID_PRIVATE_KEY: %w", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": config.String("VAPID_SUBJECT", "mailto:admin@localhost"),
+	}).SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", token, config.String("VAPID_PUBLIC_KEY", "")), nil
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+)
+
+// TestEncryptPushRoundTrip decrypts the payload the way a browser would
+func TestEncryptPushRoundTrip(t *testing.T) {
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	uaPublic := uaPrivate.PublicKey().Bytes()
+
+	payload := []byte(`{"title":"Silver stack is above $5000.00"}`)
+	body, err := encryptPush(payload,
+		base64.RawURLEncoding.EncodeToString(uaPublic),
+		base64.URLEncoding.EncodeToString(auth)) // padded keys are accepted too
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != pushRecordSize {
+		t.Errorf("record size = %d, want %d", rs, pushRecordSize)
+	}
+	keyLen := int(body[20])
+	asPublicBytes := body[21 : 21+keyLen]
+	ciphertext := body[21+keyLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaPublic...), asPublicBytes...)
+	ikm, _ := hkdfRead(secret, auth, keyInfo, 32)
+	cek, _ := hkdfRead(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfRead(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	if last := plaintext[len(plaintext)-1]; last != 0x02 {
+		t.Errorf("padding delimiter = %#x, want 0x02", last)
+	}
+	if got := string(plaintext[:len(plaintext)-1]); got != string(payload) {
+		t.Errorf("payload = %q, want %q", got, payload)
+	}
+}
@@ -0,0 +1,98 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	appleAuthURL  = "https://appleid.apple.com/auth/authorize"
+	appleTokenURL = "https://appleid.apple.com/auth/token"
+	appleIssuer   = "https://appleid.apple.com"
+)
+
+// Apple signs users in with their Apple ID. It needs the Services ID
+// (APPLE_CLIENT_ID), the team ID (APPLE_TEAM_ID) and a Sign in with Apple
+// key (APPLE_KEY_ID, and the .p8 file's PEM contents in APPLE_PRIVATE_KEY).
+type Apple struct {
+	ClientID string
+	TeamID   string
+	KeyID    string
+	Key      *ecdsa.PrivateKey
+	TokenURL string
+}
+
+func newApple() (*Apple, bool) {
+	a := &Apple{
+		ClientID: config.String("APPLE_CLIENT_ID", ""),
+		TeamID:   config.String("APPLE_TEAM_ID", ""),
+		KeyID:    config.String("APPLE_KEY_ID", ""),
+		TokenURL: appleTokenURL,
+	}
+	pem := config.String("APPLE_PRIVATE_KEY", "")
+	if a.ClientID == "" || a.TeamID == "" || a.KeyID == "" || pem == "" {
+		return nil, false
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM([]byte(pem))
+	if err != nil {
+		log.Printf("Sign in with Apple is off: APPLE_PRIVATE_KEY isn't an EC private key: %v", err)
+		return nil, false
+	}
+	a.Key = key
+	return a, true
+}
+
+func (a *Apple) Name() string { return ProviderApple }
+
+// AuthURL asks for the user's email, which makes Apple post the callback
+// as a form rather than redirecting with a query string
+func (a *Apple) AuthURL(state, redirectURI, loginHint string) string {
+	query := url.Values{
+		"client_id":     {a.ClientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"response_mode": {"form_post"},
+		"scope":         {"email"},
+		"state":         {state},
+	}
+	return appleAuthURL + "?" + query.Encode()
+}
+
+// clientSecret is the short-lived JWT Apple takes in place of a static
+// client secret, signed with the team's key
+func (a *Apple) clientSecret() (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.RegisteredClaims{
+		Issuer:    a.TeamID,
+		Subject:   a.ClientID,
+		Audience:  jwt.ClaimStrings{appleIssuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	})
+	token.Header["kid"] = a.KeyID
+	return token.SignedString(a.Key)
+}
+
+func (a *Apple) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	secret, err := a.clientSecret()
+	if err != nil {
+		return Identity{}, err
+	}
+	idToken, err := exchangeCode(ctx, a.TokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {a.ClientID},
+		"client_secret": {secret},
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	return identityFromIDToken(idToken, appleIssuer, a.ClientID)
+}
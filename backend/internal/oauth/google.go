@@ -0,0 +1,62 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleIssuer   = "https://accounts.google.com"
+)
+
+// Google signs users in with their Google account (GOOGLE_CLIENT_ID,
+// GOOGLE_CLIENT_SECRET)
+type Google struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+}
+
+func newGoogle() (*Google, bool) {
+	g := &Google{
+		ClientID:     config.String("GOOGLE_CLIENT_ID", ""),
+		ClientSecret: config.String("GOOGLE_CLIENT_SECRET", ""),
+		TokenURL:     googleTokenURL,
+	}
+	return g, g.ClientID != "" && g.ClientSecret != ""
+}
+
+func (g *Google) Name() string { return ProviderGoogle }
+
+func (g *Google) AuthURL(state, redirectURI, loginHint string) string {
+	query := url.Values{
+		"client_id":     {g.ClientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {"openid email"},
+		"state":         {state},
+		"prompt":        {"select_account"},
+	}
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+	}
+	return googleAuthURL + "?" + query.Encode()
+}
+
+func (g *Google) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	idToken, err := exchangeCode(ctx, g.TokenURL, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {g.ClientID},
+		"client_secret": {g.ClientSecret},
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	return identityFromIDToken(idToken, googleIssuer, g.ClientID)
+}
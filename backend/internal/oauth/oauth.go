@@ -0,0 +1,217 @@
+// Package oauth signs users in with third-party identity providers, Google,
+// Apple and an organization's own OpenID Connect provider, using the OAuth
+// 2.0 authorization code flow with OpenID Connect ID tokens. Providers are
+// enabled by configuring their client credentials.
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported providers
+const (
+	ProviderGoogle = "google"
+	ProviderApple  = "apple"
+	ProviderOIDC   = "oidc"
+)
+
+// MockEmailDomain is the only domain the mock provider signs in, so its
+// identities can't be mistaken for anyone's real account
+const MockEmailDomain = "mock.aureus.test"
+
+// Identity is who a provider says signed in
+type Identity struct {
+	Subject       string // the provider's stable ID for the user
+	Email         string
+	EmailVerified bool
+	// Mock is set on identities from the mock provider, which proves nothing
+	Mock bool
+}
+
+// Provider is an identity provider users can sign in with
+type Provider interface {
+	Name() string
+	// AuthURL is where to send the browser to sign in. The provider sends
+	// it back to redirectURI with state and a code. loginHint, if set,
+	// suggests the account to sign in with. It is empty if the provider
+	// can't be reached.
+	AuthURL(state, redirectURI, loginHint string) string
+	// Exchange trades the code from the callback for who signed in
+	Exchange(ctx context.Context, code, redirectURI string) (Identity, error)
+}
+
+// ErrNoEmail is returned when a provider doesn't share the user's email
+var ErrNoEmail = errors.New("the provider didn't share an email address")
+
+var httpClient = &http.Client{Timeout: 15 * time.Second}
+
+// Enabled returns the providers with client credentials configured, in a
+// stable order. With MOCK_OAUTH every provider is enabled and signs in the
+// MockEmailDomain address the login hint names, without leaving the server.
+func Enabled() []Provider {
+	if config.MockOAuth() {
+		providers := []Provider{mockProvider{ProviderGoogle}, mockProvider{ProviderApple}}
+		if config.String("OIDC_ISSUER", "") != "" {
+			providers = append(providers, mockProvider{ProviderOIDC})
+		}
+		return providers
+	}
+	var providers []Provider
+	if p, ok := newGoogle(); ok {
+		providers = append(providers, p)
+	}
+	if p, ok := newApple(); ok {
+		providers = append(providers, p)
+	}
+	if p, ok := newOIDC(); ok {
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// Label is what sign-in buttons call a provider, e.g. "Google", or
+// OIDC_NAME for the organization's provider
+func Label(name string) string {
+	switch name {
+	case ProviderGoogle:
+		return "Google"
+	case ProviderApple:
+		return "Apple"
+	case ProviderOIDC:
+		return config.String("OIDC_NAME", "Single sign-on")
+	}
+	return name
+}
+
+// Get returns the enabled provider called name
+func Get(name string) (Provider, bool) {
+	for _, p := range Enabled() {
+		if p.Name() == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// exchangeCode posts an authorization code to a token endpoint and returns
+// the ID token it answers with
+func exchangeCode(ctx context.Context, tokenURL string, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("token endpoint returned %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token endpoint returned no ID token")
+	}
+	return token.IDToken, nil
+}
+
+// parseIDToken reads the claims of an ID token. The token came straight
+// from the provider's token endpoint over TLS, which OpenID Connect accepts
+// in place of checking its signature; its issuer, audience and expiry are
+// still checked.
+func parseIDToken(idToken, issuer, audience string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(idToken, claims); err != nil {
+		return nil, fmt.Errorf("malformed ID token: %w", err)
+	}
+
+	validator := jwt.NewValidator(jwt.WithIssuer(issuer), jwt.WithAudience(audience), jwt.WithExpirationRequired())
+	if err := validator.Validate(claims); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	return claims, nil
+}
+
+// identityFromClaims reads who signed in from an ID token's claims, taking
+// the email from emailClaim
+func identityFromClaims(claims jwt.MapClaims, emailClaim string) (Identity, error) {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Identity{}, errors.New("ID token has no subject")
+	}
+	identity := Identity{Subject: subject}
+	identity.Email, _ = claims[emailClaim].(string)
+	// Apple sends email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	return identity, nil
+}
+
+// identityFromIDToken reads who signed in from an ID token
+func identityFromIDToken(idToken, issuer, audience string) (Identity, error) {
+	claims, err := parseIDToken(idToken, issuer, audience)
+	if err != nil {
+		return Identity{}, err
+	}
+	return identityFromClaims(claims, "email")
+}
+
+// mockProvider stands in for a provider with MOCK_OAUTH: its sign-in page is
+// skipped and the login hint, an address at MockEmailDomain, is the email
+// that signs in
+type mockProvider struct {
+	name string
+}
+
+func (p mockProvider) Name() string { return p.name }
+
+func (p mockProvider) AuthURL(state, redirectURI, loginHint string) string {
+	query := url.Values{"state": {state}, "code": {"mock:" + loginHint}}
+	return redirectURI + "?" + query.Encode()
+}
+
+func (p mockProvider) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	email, ok := strings.CutPrefix(code, "mock:")
+	if !ok || email == "" {
+		return Identity{}, errors.New("mock sign-in needs a login_hint")
+	}
+	if !IsMockEmail(email) {
+		return Identity{}, fmt.Errorf("mock sign-in only signs in addresses at %s", MockEmailDomain)
+	}
+	return Identity{Subject: "mock-" + strings.ToLower(email), Email: email, EmailVerified: true, Mock: true}, nil
+}
+
+// IsMockEmail reports whether email is at MockEmailDomain
+func IsMockEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSpace(email)), "@"+MockEmailDomain)
+}
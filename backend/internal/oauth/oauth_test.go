@@ -0,0 +1,199 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// idToken builds an unsigned-looking ID token; signatures aren't checked
+func idToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("provider key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestIdentityFromIDToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	token := idToken(t, jwt.MapClaims{"iss": appleIssuer, "aud": "club.aureus", "exp": exp, "sub": "0001.abc", "email": "a@privaterelay.appleid.com", "email_verified": "true"})
+
+	identity, err := identityFromIDToken(token, appleIssuer, "club.aureus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "0001.abc" || identity.Email != "a@privaterelay.appleid.com" || !identity.EmailVerified {
+		t.Errorf("identity = %+v", identity)
+	}
+
+	if _, err := identityFromIDToken(token, appleIssuer, "someone.else"); err == nil {
+		t.Error("a token for another client should be rejected")
+	}
+	expired := idToken(t, jwt.MapClaims{"iss": appleIssuer, "aud": "club.aureus", "exp": time.Now().Add(-time.Hour).Unix(), "sub": "x"})
+	if _, err := identityFromIDToken(expired, appleIssuer, "club.aureus"); err == nil {
+		t.Error("an expired token should be rejected")
+	}
+}
+
+func TestGoogleExchange(t *testing.T) {
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "the-code" || r.PostFormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": token})
+	}))
+	defer server.Close()
+
+	g := &Google{ClientID: "client", ClientSecret: "secret", TokenURL: server.URL}
+	token = idToken(t, jwt.MapClaims{"iss": googleIssuer, "aud": "client", "exp": time.Now().Add(time.Hour).Unix(), "sub": "1234", "email": "collector@gmail.com", "email_verified": true})
+
+	identity, err := g.Exchange(context.Background(), "the-code", "http://localhost/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "1234" || !identity.EmailVerified {
+		t.Errorf("identity = %+v", identity)
+	}
+	if _, err := g.Exchange(context.Background(), "bad-code", "http://localhost/callback"); err == nil {
+		t.Error("a rejected code should fail")
+	}
+}
+
+func TestOIDCDiscoversEndpointsAndMapsTheEmailClaim(t *testing.T) {
+	var server *httptest.Server
+	var token string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/realms/club/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 server.URL + "/realms/club",
+				"authorization_endpoint": server.URL + "/realms/club/auth",
+				"token_endpoint":         server.URL + "/realms/club/token",
+			})
+		case "/realms/club/token":
+			if r.PostFormValue("code") != "the-code" || r.PostFormValue("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "id_token": token})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	o := &OIDC{Issuer: server.URL + "/realms/club", ClientID: "aureus", ClientSecret: "secret", Scopes: "openid email", EmailClaim: "upn", TrustEmail: true}
+	token = idToken(t, jwt.MapClaims{"iss": o.Issuer, "aud": "aureus", "exp": time.Now().Add(time.Hour).Unix(), "sub": "f3a1", "upn": "jo@club.example"})
+
+	authURL := o.AuthURL("the-state", "http://localhost/callback", "")
+	if want := server.URL + "/realms/club/auth?"; !strings.HasPrefix(authURL, want) {
+		t.Errorf("auth URL = %q, want it under %q", authURL, want)
+	}
+
+	identity, err := o.Exchange(context.Background(), "the-code", "http://localhost/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "f3a1" || identity.Email != "jo@club.example" || !identity.EmailVerified {
+		t.Errorf("identity = %+v", identity)
+	}
+	if _, err := o.Exchange(context.Background(), "bad-code", "http://localhost/callback"); err == nil {
+		t.Error("a rejected code should fail")
+	}
+}
+
+func TestAppleClientSecret(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &Apple{ClientID: "club.aureus", TeamID: "TEAM123", KeyID: "KEY123", Key: key}
+
+	secret, err := a.clientSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := jwt.RegisteredClaims{}
+	parsed, err := jwt.ParseWithClaims(secret, &claims, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Header["kid"] != "KEY123" || claims.Issuer != "TEAM123" || claims.Subject != "club.aureus" {
+		t.Errorf("client secret = %v %+v", parsed.Header, claims)
+	}
+}
+
+func TestMockProviderOnlyOutsideProductionAndForMockAddresses(t *testing.T) {
+	t.Setenv("MOCK_OAUTH", "true")
+	t.Setenv("APP_ENV", "production")
+	if _, ok := Get(ProviderGoogle); ok {
+		t.Fatal("the mock provider is offered in production")
+	}
+
+	t.Setenv("APP_ENV", "development")
+	p, ok := Get(ProviderGoogle)
+	if !ok {
+		t.Fatal("the mock provider isn't offered in development")
+	}
+	if _, err := p.Exchange(context.Background(), "mock:admin@example.com", ""); err == nil {
+		t.Error("mock sign-in accepted a real address")
+	}
+	identity, err := p.Exchange(context.Background(), "mock:Tester@"+MockEmailDomain, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !identity.Mock || identity.Subject != "mock-tester@"+MockEmailDomain {
+		t.Errorf("identity = %+v, want a mock identity", identity)
+	}
+}
+
+func TestStateIsBoundToNonceAndProvider(t *testing.T) {
+	userID := uuid.New()
+	signed, nonce, err := State{Provider: ProviderGoogle, LinkUserID: &userID}.Sign()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := ParseState(signed, nonce, ProviderGoogle)
+	if err != nil || state.LinkUserID == nil || *state.LinkUserID != userID {
+		t.Fatalf("state = %+v, %v", state, err)
+	}
+	if _, err := ParseState(signed, "other-browser", ProviderGoogle); err != ErrInvalidState {
+		t.Error("a state should only be accepted with its nonce")
+	}
+	if _, err := ParseState(signed, nonce, ProviderApple); err != ErrInvalidState {
+		t.Error("a state should only be accepted by its provider")
+	}
+	if _, err := ParseLinkTicket(signed, nonce); err != ErrInvalidState {
+		t.Error("a state shouldn't pass for a link ticket")
+	}
+
+	ticket, linkNonce, err := NewLinkTicket(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ParseLinkTicket(ticket, linkNonce); err != nil || got != userID {
+		t.Errorf("link ticket user = %v, %v", got, err)
+	}
+	for _, other := range []string{"", "other-browser", nonce} {
+		if _, err := ParseLinkTicket(ticket, other); err != ErrInvalidState {
+			t.Errorf("link ticket accepted with nonce %q", other)
+		}
+	}
+}
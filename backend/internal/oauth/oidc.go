@@ -0,0 +1,154 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// discoveryTTL is how long an issuer's discovered endpoints are reused
+const discoveryTTL = time.Hour
+
+// OIDC signs users in with any OpenID Connect provider, so a self-hosted
+// instance can use the organization's existing single sign-on, e.g. Okta,
+// Keycloak, Authentik or Microsoft Entra ID. It needs the issuer URL
+// (OIDC_ISSUER) and a client registered there (OIDC_CLIENT_ID,
+// OIDC_CLIENT_SECRET); its endpoints are discovered from the issuer.
+type OIDC struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       string
+	// EmailClaim is the ID token claim holding the user's email, "email"
+	// unless the provider puts it elsewhere, e.g. "upn"
+	EmailClaim string
+	// TrustEmail treats every email the provider sends as verified, for a
+	// provider that is the organization's directory but doesn't say so
+	TrustEmail bool
+}
+
+// discovery is the part of an issuer's OpenID configuration used here
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	fetchedAt             time.Time
+}
+
+var (
+	discoveryMu sync.Mutex
+	discovered  = map[string]discovery{}
+)
+
+func newOIDC() (*OIDC, bool) {
+	o := &OIDC{
+		Issuer:       strings.TrimRight(config.String("OIDC_ISSUER", ""), "/"),
+		ClientID:     config.String("OIDC_CLIENT_ID", ""),
+		ClientSecret: config.String("OIDC_CLIENT_SECRET", ""),
+		Scopes:       config.String("OIDC_SCOPES", "openid email profile"),
+		EmailClaim:   config.String("OIDC_EMAIL_CLAIM", "email"),
+		TrustEmail:   config.Bool("OIDC_TRUST_EMAIL", false),
+	}
+	return o, o.Issuer != "" && o.ClientID != "" && o.ClientSecret != ""
+}
+
+func (o *OIDC) Name() string { return ProviderOIDC }
+
+// discover returns the issuer's endpoints from its
+// /.well-known/openid-configuration, cached for discoveryTTL
+func (o *OIDC) discover(ctx context.Context) (discovery, error) {
+	discoveryMu.Lock()
+	defer discoveryMu.Unlock()
+
+	if d, ok := discovered[o.Issuer]; ok && time.Since(d.fetchedAt) < discoveryTTL {
+		return d, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return discovery{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return discovery{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return discovery{}, fmt.Errorf("OpenID configuration returned %d", resp.StatusCode)
+	}
+	var d discovery
+	if err := json.NewDecoder(resp.Body).Decode(&d); err != nil {
+		return discovery{}, fmt.Errorf("malformed OpenID configuration: %w", err)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" {
+		return discovery{}, fmt.Errorf("OpenID configuration lacks its endpoints")
+	}
+	// ID tokens are checked against the issuer the provider names itself
+	if d.Issuer == "" {
+		d.Issuer = o.Issuer
+	}
+	d.fetchedAt = time.Now()
+	discovered[o.Issuer] = d
+	return d, nil
+}
+
+func (o *OIDC) AuthURL(state, redirectURI, loginHint string) string {
+	d, err := o.discover(context.Background())
+	if err != nil {
+		log.Printf("Single sign-on with %s is unavailable: %v", o.Issuer, err)
+		return ""
+	}
+	query := url.Values{
+		"client_id":     {o.ClientID},
+		"redirect_uri":  {redirectURI},
+		"response_type": {"code"},
+		"scope":         {o.Scopes},
+		"state":         {state},
+	}
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+	}
+	separator := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return d.AuthorizationEndpoint + separator + query.Encode()
+}
+
+func (o *OIDC) Exchange(ctx context.Context, code, redirectURI string) (Identity, error) {
+	d, err := o.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	idToken, err := exchangeCode(ctx, d.TokenEndpoint, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {o.ClientID},
+		"client_secret": {o.ClientSecret},
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	claims, err := parseIDToken(idToken, d.Issuer, o.ClientID)
+	if err != nil {
+		return Identity{}, err
+	}
+	identity, err := identityFromClaims(claims, o.EmailClaim)
+	if err != nil {
+		return Identity{}, err
+	}
+	if o.TrustEmail && identity.Email != "" {
+		identity.EmailVerified = true
+	}
+	return identity, nil
+}
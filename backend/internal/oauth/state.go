@@ -0,0 +1,103 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// StateTTL is how long a user has to finish signing in with a provider
+const StateTTL = 10 * time.Minute
+
+// ErrInvalidState is returned for a callback whose state wasn't issued to
+// this browser, was tampered with or has expired
+var ErrInvalidState = errors.New("invalid or expired OAuth state")
+
+// State is carried through the provider's sign-in page and back. Its nonce
+// is also kept in a cookie, so a callback is only accepted by the browser
+// that started the sign-in.
+type State struct {
+	Provider   string     `json:"provider"`
+	Nonce      string     `json:"nonce"`
+	TenantID   *uuid.UUID `json:"tenant_id,omitempty"`
+	InviteCode string     `json:"invite_code,omitempty"`
+	// LinkUserID is set when a signed-in user links the provider to their
+	// account rather than signing in with it
+	LinkUserID *uuid.UUID `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Sign fills in a fresh nonce and expiry and signs the state, returning it
+// with the nonce for the cookie
+func (s State) Sign() (string, string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	s.Nonce = hex.EncodeToString(buf)
+	s.RegisteredClaims = jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(StateTTL))}
+	signed, err := auth.SignState(s)
+	return signed, s.Nonce, err
+}
+
+// ParseState verifies a state returned by provider against the nonce from
+// the browser's cookie
+func ParseState(signed, nonce, provider string) (State, error) {
+	var s State
+	if err := auth.ParseState(signed, &s); err != nil {
+		return State{}, ErrInvalidState
+	}
+	if nonce == "" || s.Nonce != nonce || s.Provider != provider {
+		return State{}, ErrInvalidState
+	}
+	return s, nil
+}
+
+// LinkTicket lets a signed-in user's browser start linking a provider to
+// their account, since a browser navigation can't carry their token. Its
+// nonce is also kept in a cookie set on the request for the ticket, so the
+// ticket only works in the browser of the user who asked for it and can't
+// be handed to someone else to link their identity to the wrong account.
+type LinkTicket struct {
+	UserID uuid.UUID `json:"link_user_id"`
+	Nonce  string    `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// LinkTicketTTL is how long a link ticket can be used to start linking
+const LinkTicketTTL = 5 * time.Minute
+
+// NewLinkTicket signs a link ticket for userID, returning it with the nonce
+// for the cookie
+func NewLinkTicket(userID uuid.UUID) (string, string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	nonce := hex.EncodeToString(buf)
+	ticket, err := auth.SignState(LinkTicket{
+		UserID:           userID,
+		Nonce:            nonce,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "link", ExpiresAt: jwt.NewNumericDate(time.Now().Add(LinkTicketTTL))},
+	})
+	return ticket, nonce, err
+}
+
+// ParseLinkTicket returns the user a link ticket was issued to, if nonce
+// from the browser's cookie is the ticket's
+func ParseLinkTicket(ticket, nonce string) (uuid.UUID, error) {
+	var t LinkTicket
+	if err := auth.ParseState(ticket, &t); err != nil || t.Subject != "link" || t.UserID == uuid.Nil {
+		return uuid.Nil, ErrInvalidState
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(t.Nonce), []byte(nonce)) != 1 {
+		return uuid.Nil, ErrInvalidState
+	}
+	return t.UserID, nil
+}
@@ -0,0 +1,124 @@
+package pcgs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Image sides
+const (
+	SideObverse = "obverse"
+	SideReverse = "reverse"
+)
+
+// ImageVariant is an image with what its description says about it
+type ImageVariant struct {
+	URL         string `json:"url"`
+	Side        string `json:"side,omitempty"` // "obverse", "reverse" or "" when unknown
+	TrueView    bool   `json:"trueview"`
+	Resolution  string `json:"resolution"`
+	Description string `json:"description"`
+}
+
+var resolutionNames = map[string]int{
+	"thumbnail": 1,
+	"small":     2,
+	"medium":    3,
+	"large":     4,
+	"xlarge":    5,
+	"original":  6,
+}
+
+var resolutionPixels = regexp.MustCompile(`(\d+)\s*[xX×]\s*(\d+)|(\d+)`)
+
+// resolutionRank orders resolutions: pixel sizes like "1200x1200" by area,
+// named sizes ("Small", "Large") below any pixel size, unknown ones lowest
+func resolutionRank(resolution string) int {
+	if m := resolutionPixels.FindStringSubmatch(resolution); m != nil {
+		if m[1] != "" {
+			w, _ := strconv.Atoi(m[1])
+			h, _ := strconv.Atoi(m[2])
+			return 100 + w*h
+		}
+		n, _ := strconv.Atoi(m[3])
+		return 100 + n*n
+	}
+	key := strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(resolution))
+	return resolutionNames[key]
+}
+
+// Classify reads the side and TrueView flag from an image's description
+func Classify(image ImageDetail) ImageVariant {
+	desc := strings.ToLower(image.Description)
+	variant := ImageVariant{
+		URL:         image.URL,
+		TrueView:    strings.Contains(desc, "trueview") || strings.Contains(desc, "true view"),
+		Resolution:  image.Resolution,
+		Description: image.Description,
+	}
+	switch {
+	case strings.Contains(desc, "obverse") || strings.Contains(desc, "front"):
+		variant.Side = SideObverse
+	case strings.Contains(desc, "reverse") || strings.Contains(desc, "back"):
+		variant.Side = SideReverse
+	}
+	return variant
+}
+
+// Variants classifies every image in the response
+func (p *PCGSImageData) Variants() []ImageVariant {
+	variants := make([]ImageVariant, len(p.Images))
+	for i, image := range p.Images {
+		variants[i] = Classify(image)
+	}
+	return variants
+}
+
+// best returns the highest resolution variant accepted by match, preferring
+// TrueView photography at equal resolution
+func (p *PCGSImageData) best(match func(ImageVariant) bool) *ImageVariant {
+	var best *ImageVariant
+	for _, variant := range p.Variants() {
+		if variant.URL == "" || !match(variant) {
+			continue
+		}
+		if best == nil ||
+			resolutionRank(variant.Resolution) > resolutionRank(best.Resolution) ||
+			(resolutionRank(variant.Resolution) == resolutionRank(best.Resolution) && variant.TrueView && !best.TrueView) {
+			v := variant
+			best = &v
+		}
+	}
+	return best
+}
+
+// Obverse returns the best obverse image, or nil when none is labelled
+func (p *PCGSImageData) Obverse() *ImageVariant {
+	if !p.HasObverseImage && !p.hasSide(SideObverse) {
+		return nil
+	}
+	return p.best(func(v ImageVariant) bool { return v.Side == SideObverse })
+}
+
+// Reverse returns the best reverse image, or nil when none is labelled
+func (p *PCGSImageData) Reverse() *ImageVariant {
+	if !p.HasReverseImage && !p.hasSide(SideReverse) {
+		return nil
+	}
+	return p.best(func(v ImageVariant) bool { return v.Side == SideReverse })
+}
+
+// TrueView returns the highest resolution TrueView image, or nil
+func (p *PCGSImageData) TrueView() *ImageVariant {
+	return p.best(func(v ImageVariant) bool { return v.TrueView })
+}
+
+func (p *PCGSImageData) hasSide(side string) bool {
+	for _, variant := range p.Variants() {
+		if variant.Side == side {
+			return true
+		}
+	}
+	return false
+}
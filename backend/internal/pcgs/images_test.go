@@ -0,0 +1,35 @@
+package pcgs
+
+import "testing"
+
+func TestImageSelectionUsesMetadata(t *testing.T) {
+	data := PCGSImageData{
+		HasObverseImage: true,
+		HasReverseImage: true,
+		Images: []ImageDetail{
+			{URL: "rev-small", Resolution: "Small", Description: "Reverse"},
+			{URL: "trueview", Resolution: "Large", Description: "TrueView"},
+			{URL: "obv-small", Resolution: "Small", Description: "Obverse"},
+			{URL: "obv-large", Resolution: "Large", Description: "Obverse"},
+			{URL: "obv-large-tv", Resolution: "Large", Description: "TrueView Obverse"},
+			{URL: "rev-1200", Resolution: "1200x1200", Description: "Back"},
+		},
+	}
+
+	if got := data.GetFrontImageURL(); got != "obv-large-tv" {
+		t.Errorf("front = %s, want obv-large-tv", got)
+	}
+	if got := data.GetBackImageURL(); got != "rev-1200" {
+		t.Errorf("back = %s, want rev-1200", got)
+	}
+	if got := data.TrueView(); got == nil || got.URL != "trueview" {
+		t.Errorf("TrueView = %v, want trueview", got)
+	}
+}
+
+func TestImageSelectionFallsBackToOrder(t *testing.T) {
+	data := PCGSImageData{Images: []ImageDetail{{URL: "a"}, {URL: "b"}}}
+	if front, back := data.GetFrontImageURL(), data.GetBackImageURL(); front != "a" || back != "b" {
+		t.Errorf("front, back = %s, %s, want a, b", front, back)
+	}
+}
@@ -0,0 +1,67 @@
+package pcgs
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MockAPIKey is used in mock mode when no real key is configured, so the
+// client's key checks pass without a PCGS account
+const MockAPIKey = "mock-pcgs-key"
+
+//go:embed fixtures/*.json
+var fixtureFS embed.FS
+
+// fixtureTransport answers PCGS API requests from the embedded fixtures
+// instead of the network. Unknown cert numbers get the same "invalid request"
+// body the real API returns.
+type fixtureTransport struct{}
+
+func (fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var file, certNo string
+	switch {
+	case strings.Contains(req.URL.Path, "/GetCoinFactsByCertNo/"):
+		file = "fixtures/coinfacts.json"
+		certNo = req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	case strings.HasSuffix(req.URL.Path, "/GetImagesByCertNo"):
+		file = "fixtures/images.json"
+		certNo = req.URL.Query().Get("certNo")
+	default:
+		return fixtureResponse(req, http.StatusNotFound, []byte(`{"Message":"No fixture for this endpoint"}`)), nil
+	}
+
+	data, err := fixtureFS.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, err
+	}
+
+	body, ok := fixtures[certNo]
+	if !ok {
+		body, _ = json.Marshal(map[string]interface{}{
+			"CertNo":         certNo,
+			"IsValidRequest": false,
+			"ServerMessage":  "No data found",
+		})
+	}
+
+	return fixtureResponse(req, http.StatusOK, body), nil
+}
+
+func fixtureResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"regexp"
@@ -13,6 +14,9 @@ import (
 	"time"
 
 	"github.com/chromedp/chromedp"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/debuglog"
+	"github.com/evansminotwood/aureus/internal/usage"
 )
 
 const (
@@ -23,6 +27,9 @@ type PCGSClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	APIKey     string
+	// UsageService is the name calls are counted under; user-supplied keys
+	// have their own quota and are tracked separately from the instance key
+	UsageService string
 }
 
 // CoinFactsResponse represents the response from PCGS GetCoinFactsByCertNo
@@ -76,17 +83,28 @@ type PCGSImageData struct {
 	ServerMessage    string        `json:"ServerMessage"`
 }
 
-// GetFrontImageURL returns the first available image URL (for backwards compatibility)
+// GetFrontImageURL returns the best obverse image, falling back to a
+// TrueView image and then to the first image when none is labelled
 func (p *PCGSImageData) GetFrontImageURL() string {
+	if obverse := p.Obverse(); obverse != nil {
+		return obverse.URL
+	}
+	if trueView := p.TrueView(); trueView != nil {
+		return trueView.URL
+	}
 	if len(p.Images) > 0 {
 		return p.Images[0].URL
 	}
 	return ""
 }
 
-// GetBackImageURL returns the second image URL if available (for backwards compatibility)
+// GetBackImageURL returns the best reverse image, falling back to the second
+// image when none is labelled
 func (p *PCGSImageData) GetBackImageURL() string {
-	if len(p.Images) > 1 {
+	if reverse := p.Reverse(); reverse != nil {
+		return reverse.URL
+	}
+	if len(p.Images) > 1 && p.Images[1].URL != p.GetFrontImageURL() {
 		return p.Images[1].URL
 	}
 	return ""
@@ -94,15 +112,48 @@ func (p *PCGSImageData) GetBackImageURL() string {
 
 // NewPCGSClient creates a new PCGS API client
 func NewPCGSClient() *PCGSClient {
-	apiKey := os.Getenv("PCGS_API_KEY")
-	fmt.Printf("[DEBUG] NewPCGSClient: API key loaded, length=%d\n", len(apiKey))
+	apiKey := config.String("PCGS_API_KEY", "")
+	if apiKey == "" && config.MockMode() {
+		apiKey = MockAPIKey
+	}
+	return &PCGSClient{
+		BaseURL:      PCGSAPIBaseURL,
+		HTTPClient:   newHTTPClient(),
+		APIKey:       apiKey,
+		UsageService: usage.ServicePCGS,
+	}
+}
+
+// NewPCGSClientWithKey creates a PCGS API client using a user's own API key
+func NewPCGSClientWithKey(apiKey string) *PCGSClient {
 	return &PCGSClient{
-		BaseURL:    PCGSAPIBaseURL,
-		HTTPClient: &http.Client{},
-		APIKey:     apiKey,
+		BaseURL:      PCGSAPIBaseURL,
+		HTTPClient:   newHTTPClient(),
+		APIKey:       apiKey,
+		UsageService: usage.ServicePCGSUserKeys,
 	}
 }
 
+// newHTTPClient returns the client used for API calls, which serves local
+// fixtures instead of calling PCGS when MOCK_EXTERNAL_APIS is enabled
+func newHTTPClient() *http.Client {
+	if config.MockMode() {
+		return &http.Client{Transport: debuglog.Transport{Base: fixtureTransport{}}}
+	}
+	return &http.Client{Transport: debuglog.Transport{}}
+}
+
+// do executes a request and records it against the PCGS daily quota
+func (c *PCGSClient) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err == nil && resp.StatusCode != http.StatusOK {
+		usage.RecordCall(c.UsageService, fmt.Errorf("status %d", resp.StatusCode))
+	} else {
+		usage.RecordCall(c.UsageService, err)
+	}
+	return resp, err
+}
+
 // GetCoinDataByCertNumber retrieves coin data using PCGS certification number
 func (c *PCGSClient) GetCoinDataByCertNumber(certNumber string) (*CoinFactsResponse, error) {
 	// Use the correct endpoint from PCGS Swagger documentation
@@ -115,19 +166,16 @@ func (c *PCGSClient) GetCoinDataByCertNumber(certNumber string) (*CoinFactsRespo
 	}
 
 	// Add authorization header with Bearer token (required by PCGS API)
-	fmt.Printf("[DEBUG] GetCoinDataByCertNumber: API key length=%d\n", len(c.APIKey))
 	if c.APIKey != "" {
 		req.Header.Add("Authorization", fmt.Sprintf("bearer %s", c.APIKey))
-		fmt.Printf("[DEBUG] Authorization header added\n")
 	} else {
-		fmt.Printf("[DEBUG] API key is empty!\n")
-		return nil, fmt.Errorf("PCGS API key not configured - please set PCGS_API_KEY environment variable")
+		return nil, fmt.Errorf("PCGS API key not configured - please set PCGS_API_KEY or add your own key in account settings")
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
 
 	// Execute request
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -151,10 +199,8 @@ func (c *PCGSClient) GetCoinDataByCertNumber(certNumber string) (*CoinFactsRespo
 // GetPriceData retrieves pricing data for a coin by PCGS certification number
 // Tries API first, falls back to returning error if API fails
 func (c *PCGSClient) GetPriceData(certNumber string) (*PCGSPriceData, error) {
-	fmt.Printf("[DEBUG] GetPriceData called for cert: %s\n", certNumber)
 	// Try the PCGS API first
 	coinData, err := c.GetCoinDataByCertNumber(certNumber)
-	fmt.Printf("[DEBUG] GetCoinDataByCertNumber returned: err=%v, coinData=%v\n", err, coinData != nil)
 	if err == nil && coinData != nil && coinData.IsValidRequest {
 		// Successfully got data from API
 		return &PCGSPriceData{
@@ -171,7 +217,10 @@ func (c *PCGSClient) GetPriceData(certNumber string) (*PCGSPriceData, error) {
 	}
 
 	// API failed - return helpful error
-	fmt.Printf("PCGS API failed for cert %s: %v\n", certNumber, err)
+	if err == nil && coinData != nil {
+		err = fmt.Errorf("invalid request: %s", coinData.ServerMessage)
+	}
+	log.Printf("PCGS API failed for cert %s: %v", certNumber, err)
 	return nil, fmt.Errorf("PCGS API not available - please enter the value manually or visit https://www.pcgs.com/cert/%s", certNumber)
 }
 
@@ -179,7 +228,6 @@ func (c *PCGSClient) GetPriceData(certNumber string) (*PCGSPriceData, error) {
 func (c *PCGSClient) GetCoinImagesByCertNumber(certNumber string) (*PCGSImageData, error) {
 	// Use the PCGS API endpoint for images with query parameter
 	endpoint := fmt.Sprintf("%s/coindetail/GetImagesByCertNo?certNo=%s", c.BaseURL, certNumber)
-	fmt.Printf("[DEBUG] GetCoinImagesByCertNumber: Calling endpoint: %s\n", endpoint)
 
 	// Create request
 	req, err := http.NewRequest("GET", endpoint, nil)
@@ -191,13 +239,13 @@ func (c *PCGSClient) GetCoinImagesByCertNumber(certNumber string) (*PCGSImageDat
 	if c.APIKey != "" {
 		req.Header.Add("Authorization", fmt.Sprintf("bearer %s", c.APIKey))
 	} else {
-		return nil, fmt.Errorf("PCGS API key not configured - please set PCGS_API_KEY environment variable")
+		return nil, fmt.Errorf("PCGS API key not configured - please set PCGS_API_KEY or add your own key in account settings")
 	}
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
 
 	// Execute request
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
@@ -224,7 +272,7 @@ func (c *PCGSClient) GetCoinImagesByCertNumber(certNumber string) (*PCGSImageDat
 
 // scrapePCGSWebsite scrapes the PCGS cert verification page for coin data using headless Chrome
 func (c *PCGSClient) scrapePCGSWebsite(certNumber string) (*PCGSPriceData, error) {
-	fmt.Printf("Scraping PCGS for cert %s using headless browser...\n", certNumber)
+	debuglog.Printf("Scraping PCGS for cert %s using headless browser", certNumber)
 
 	// Create context with timeout
 	ctx, cancel := chromedp.NewContext(context.Background())
@@ -340,15 +388,14 @@ func (c *PCGSClient) scrapePCGSWebsite(certNumber string) (*PCGSPriceData, error
 		}
 	}
 
-	// Log what we found for debugging
-	fmt.Printf("PCGS scrape result for %s: Title='%s', Grade='%s', Price=$%.2f\n",
+	debuglog.Printf("PCGS scrape result for %s: title %q, grade %q, price $%.2f",
 		certNumber, priceData.CoinTitle, priceData.Grade, priceData.Price)
 
-	// Save HTML to file for debugging if extraction failed
-	if priceData.CoinTitle == "" || priceData.Grade == "" || priceData.Price == 0 {
-		debugFile := fmt.Sprintf("/tmp/pcgs_debug_%s.html", certNumber)
+	// Keep the page when extraction failed, to see what changed on it
+	if debuglog.Enabled() && (priceData.CoinTitle == "" || priceData.Grade == "" || priceData.Price == 0) {
+		debugFile := fmt.Sprintf("%s/pcgs_debug_%s.html", os.TempDir(), certNumber)
 		if err := os.WriteFile(debugFile, []byte(pageHTML), 0644); err == nil {
-			fmt.Printf("Saved HTML to %s for debugging\n", debugFile)
+			debuglog.Printf("Saved PCGS page for cert %s to %s", certNumber, debugFile)
 		}
 	}
 
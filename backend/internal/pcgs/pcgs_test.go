@@ -0,0 +1,98 @@
+package pcgs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fixtureClient answers from the recorded responses in fixtures/
+func fixtureClient() *PCGSClient {
+	return &PCGSClient{
+		BaseURL:      PCGSAPIBaseURL,
+		HTTPClient:   &http.Client{Transport: fixtureTransport{}},
+		APIKey:       MockAPIKey,
+		UsageService: "pcgs-test",
+	}
+}
+
+func TestGetPriceDataFromFixtures(t *testing.T) {
+	tests := []struct {
+		certNo string
+		title  string
+		grade  string
+		price  float64
+		series string
+	}{
+		{"10000001", "1921 Morgan Dollar", "MS65", 185, "Morgan Dollar"},
+		{"10000002", "1921-S Peace Dollar", "MS67", 2750, "Peace Dollar"},
+		{"10000003", "1986 American Gold Eagle (1 oz)", "MS69", 2400, "American Gold Eagle"},
+	}
+
+	client := fixtureClient()
+	for _, tc := range tests {
+		t.Run(tc.certNo, func(t *testing.T) {
+			data, err := client.GetPriceData(tc.certNo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data.CoinTitle != tc.title || data.Grade != tc.grade || data.Price != tc.price || data.SeriesName != tc.series {
+				t.Errorf("GetPriceData(%s) = %+v", tc.certNo, data)
+			}
+		})
+	}
+}
+
+func TestUnknownCertIsInvalid(t *testing.T) {
+	client := fixtureClient()
+
+	facts, err := client.GetCoinDataByCertNumber("99999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if facts.IsValidRequest {
+		t.Error("an unknown cert should come back as an invalid request")
+	}
+	if _, err := client.GetPriceData("99999999"); err == nil {
+		t.Error("GetPriceData should fail for an unknown cert")
+	}
+	if _, err := client.GetCoinImagesByCertNumber("99999999"); err == nil {
+		t.Error("GetCoinImagesByCertNumber should fail for an unknown cert")
+	}
+}
+
+func TestRequestsCarryTheAPIKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		apiKey  string
+		status  int
+		wantErr string
+	}{
+		{"success", "secret", http.StatusOK, ""},
+		{"missing key", "", http.StatusOK, "API key not configured"},
+		{"server error", "secret", http.StatusInternalServerError, "status 500"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "bearer "+tc.apiKey {
+					t.Errorf("Authorization = %q", got)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{"CertNo":"1","IsValidRequest":true}`))
+			}))
+			defer server.Close()
+
+			client := &PCGSClient{BaseURL: server.URL, HTTPClient: server.Client(), APIKey: tc.apiKey, UsageService: "pcgs-test"}
+			_, err := client.GetCoinDataByCertNumber("1")
+			if tc.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
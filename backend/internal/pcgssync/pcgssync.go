@@ -0,0 +1,217 @@
+package pcgssync
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/crypto"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/pcgs"
+	"github.com/evansminotwood/aureus/internal/usage"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+// Intervals users can pick for automatic syncs, in days. 0 turns them off.
+var Intervals = []int{0, 1, 7, 30}
+
+// Options narrows a sync to some of the user's coins
+type Options struct {
+	PortfolioID *uuid.UUID
+	CoinIDs     []uuid.UUID
+	// MaxAge skips coins synced more recently than this
+	MaxAge time.Duration
+	// Background syncs nobody is waiting on stop once the PCGS quota is
+	// nearly used up, leaving the rest of it for interactive requests
+	Background bool
+	// Scheduled syncs leave out coins with auto-sync turned off
+	Scheduled bool
+	// Progress, when set, is told how many of the coins have been dealt
+	// with after each one
+	Progress func(done, total int)
+}
+
+// Result summarizes a sync
+type Result struct {
+	TotalCoins int      `json:"total_coins"`
+	Updated    int      `json:"updated"`
+	Skipped    int      `json:"skipped"`
+	Failed     int      `json:"failed"`
+	Errors     []string `json:"errors,omitempty"`
+	// Throttled is set when a background sync stopped early to save quota;
+	// the coins it didn't get to are counted as skipped
+	Throttled bool `json:"throttled,omitempty"`
+}
+
+// ValidInterval reports whether days is one of the supported schedules
+func ValidInterval(days int) bool {
+	for _, interval := range Intervals {
+		if days == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientForUser returns a PCGS client using the user's own API key when one
+// is stored, falling back to the instance-wide PCGS_API_KEY
+func ClientForUser(userID uuid.UUID) *pcgs.PCGSClient {
+	var user models.User
+	if err := database.GetDB().Select("id", "pcgs_api_key").First(&user, "id = ?", userID).Error; err == nil && user.PCGSAPIKey != "" {
+		apiKey, err := crypto.Decrypt(user.PCGSAPIKey)
+		if err == nil {
+			// Re-encrypt under the current key after a key rotation
+			if crypto.NeedsRotation(user.PCGSAPIKey) {
+				if sealed, err := crypto.Encrypt(apiKey); err == nil {
+					database.GetDB().Model(&user).Update("pcgs_api_key", sealed)
+				}
+			}
+			return pcgs.NewPCGSClientWithKey(apiKey)
+		}
+		log.Printf("Failed to decrypt PCGS key for user %v, using instance key: %v", userID, err)
+	}
+
+	return pcgs.NewPCGSClient()
+}
+
+// Sync refreshes the numismatic value of the user's coins that have a PCGS
+// cert number
+func Sync(userID uuid.UUID, opts Options) (Result, error) {
+	db := database.GetDB()
+	result := Result{}
+
+	query := db.Table("coins").
+		Select("coins.*").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("portfolios.user_id = ? AND coins.pcgs_cert_number != ''", userID)
+	if opts.PortfolioID != nil {
+		query = query.Where("coins.portfolio_id = ?", *opts.PortfolioID)
+	}
+	if len(opts.CoinIDs) > 0 {
+		query = query.Where("coins.id IN ?", opts.CoinIDs)
+	}
+	if opts.Scheduled {
+		query = query.Where("coins.auto_sync")
+	}
+
+	var coins []models.Coin
+	if err := query.Find(&coins).Error; err != nil {
+		return result, err
+	}
+	result.TotalCoins = len(coins)
+
+	portfolioIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		portfolioIDs[i] = coin.PortfolioID
+	}
+	bases, err := valuation.Bases(portfolioIDs)
+	if err != nil {
+		return result, err
+	}
+
+	now := time.Now()
+	pcgsClient := ClientForUser(userID)
+	for i, coin := range coins {
+		if opts.Progress != nil {
+			opts.Progress(i, len(coins))
+		}
+		if opts.Background && usage.Throttled(pcgsClient.UsageService) {
+			result.Throttled = true
+			result.Skipped += len(coins) - i
+			break
+		}
+		if opts.MaxAge > 0 && coin.PCGSSyncedAt != nil && now.Sub(*coin.PCGSSyncedAt) < opts.MaxAge {
+			result.Skipped++
+			continue
+		}
+
+		// Fetch PCGS price data
+		priceData, err := pcgsClient.GetPriceData(coin.PCGSCertNumber)
+		if err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, coin.PCGSCertNumber+": "+err.Error())
+			continue
+		}
+
+		oldCurrentValue, oldNumismaticValue := coin.CurrentValue, coin.NumismaticValue
+		if priceData.Price > 0 {
+			valuation.ApplyNumismaticValue(&coin, priceData.Price, bases[coin.PortfolioID])
+		}
+		syncedAt := time.Now()
+		coin.PCGSSyncedAt = &syncedAt
+
+		if err := db.Save(&coin).Error; err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, coin.PCGSCertNumber+": failed to save")
+			continue
+		}
+		if priceData.Price <= 0 {
+			continue
+		}
+
+		result.Updated++
+		if coin.NumismaticValue != oldNumismaticValue || coin.CurrentValue != oldCurrentValue {
+			events.Publish(events.CoinValued{
+				UserID:             userID,
+				CoinID:             coin.ID,
+				PortfolioID:        coin.PortfolioID,
+				Source:             "pcgs",
+				OldCurrentValue:    oldCurrentValue,
+				NewCurrentValue:    coin.CurrentValue,
+				OldNumismaticValue: oldNumismaticValue,
+				NewNumismaticValue: coin.NumismaticValue,
+			})
+		}
+	}
+	if opts.Progress != nil {
+		opts.Progress(len(coins), len(coins))
+	}
+
+	return result, nil
+}
+
+// SyncDue runs the automatic sync for every user whose schedule is due.
+// Coins already synced within the schedule's interval are skipped.
+func SyncDue(now time.Time) error {
+	var users []models.User
+	if err := database.GetDB().
+		Where("pcgs_sync_interval_days > 0 AND (pcgs_synced_at IS NULL OR pcgs_synced_at < ? - make_interval(days => pcgs_sync_interval_days))", now).
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, user := range users {
+		interval := time.Duration(user.PCGSSyncIntervalDays) * 24 * time.Hour
+		result, err := Sync(user.ID, Options{MaxAge: interval, Background: true, Scheduled: true})
+		if err != nil {
+			log.Printf("PCGS sync for user %s failed: %v", user.ID, err)
+			failed++
+			continue
+		}
+		if result.Throttled {
+			// Left due, so the next run picks up where this one stopped
+			log.Printf("PCGS sync for user %s paused: PCGS quota nearly used up (%d updated, %d left)", user.ID, result.Updated, result.Skipped)
+			continue
+		}
+		if result.Updated > 0 || result.Failed > 0 {
+			log.Printf("PCGS sync for user %s: %d updated, %d skipped, %d failed", user.ID, result.Updated, result.Skipped, result.Failed)
+		}
+		events.Publish(events.PCGSSyncCompleted{
+			UserID:  user.ID,
+			Total:   result.TotalCoins,
+			Updated: result.Updated,
+			Skipped: result.Skipped,
+			Failed:  result.Failed,
+		})
+		database.GetDB().Model(&user).Update("pcgs_synced_at", now)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d PCGS syncs failed", failed, len(users))
+	}
+	return nil
+}
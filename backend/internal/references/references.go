@@ -0,0 +1,176 @@
+// Package references builds the links from a coin to literature and
+// reference sites about it: its PCGS CoinFacts page and cert verification,
+// NGC Coin Explorer and the auction archives where it or coins like it sold.
+// Links built from lookups are marked auto and rebuilt whenever the coin
+// changes; links the owner added are kept as they are.
+package references
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Reference types
+const (
+	TypePCGSCoinFacts   = "pcgs_coinfacts"
+	TypePCGSCert        = "pcgs_cert"
+	TypeNGCCoinExplorer = "ngc_coin_explorer"
+	TypeAuctionArchive  = "auction_archive"
+	TypeLiterature      = "literature"
+	TypeOther           = "other"
+)
+
+// Types are the kinds of reference a coin can link to
+var Types = []string{TypePCGSCoinFacts, TypePCGSCert, TypeNGCCoinExplorer, TypeAuctionArchive, TypeLiterature, TypeOther}
+
+// MaxReferences caps the references the owner can add to a coin
+const MaxReferences = 20
+
+// maxTitleLength caps a reference's title
+const maxTitleLength = 200
+
+const (
+	coinFactsURL        = "https://www.pcgs.com/coinfacts/coin/%s/%s"
+	certURL             = "https://www.pcgs.com/cert/"
+	ngcSearchURL        = "https://www.ngccoin.com/coin-explorer/search/?q="
+	heritageSearchURL   = "https://coins.ha.com/c/search/results.zx?mode=archive&term="
+	greatCollectionsURL = "https://www.greatcollections.com/search?mode=archive&q="
+)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+// coinFactsNumber matches the PCGS number at the end of a CoinFacts link
+var coinFactsNumber = regexp.MustCompile(`/(\d+)$`)
+
+// ValidType reports whether t is one of Types
+func ValidType(t string) bool {
+	return slices.Contains(Types, t)
+}
+
+// describe names a coin the way reference sites are searched, e.g.
+// "1881-S Morgan Dollar"
+func describe(coin models.Coin) string {
+	date := ""
+	if coin.Year != 0 {
+		date = strconv.Itoa(coin.Year)
+		if coin.MintMark != "" {
+			date += "-" + coin.MintMark
+		}
+	}
+	return strings.TrimSpace(date + " " + coin.CoinType)
+}
+
+// Lookup returns the references a coin's details point to. pcgsNumber is the
+// PCGS coin number from a cert lookup, which CoinFacts pages are keyed by;
+// without it there is no CoinFacts link.
+func Lookup(coin models.Coin, pcgsNumber string) []models.CoinReference {
+	refs := []models.CoinReference{}
+	name := describe(coin)
+	if pcgsNumber != "" {
+		slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+		if slug == "" {
+			slug = "coin"
+		}
+		refs = append(refs, models.CoinReference{
+			Type:  TypePCGSCoinFacts,
+			URL:   fmt.Sprintf(coinFactsURL, slug, url.PathEscape(pcgsNumber)),
+			Title: "PCGS CoinFacts #" + pcgsNumber,
+			Auto:  true,
+		})
+	}
+	if coin.PCGSCertNumber != "" {
+		refs = append(refs, models.CoinReference{
+			Type:  TypePCGSCert,
+			URL:   certURL + url.PathEscape(coin.PCGSCertNumber),
+			Title: "PCGS cert verification " + coin.PCGSCertNumber,
+			Auto:  true,
+		})
+	}
+	if coin.CoinType == "" {
+		return refs
+	}
+	query := url.QueryEscape(name)
+	return append(refs,
+		models.CoinReference{Type: TypeNGCCoinExplorer, URL: ngcSearchURL + query, Title: "NGC Coin Explorer: " + name, Auto: true},
+		models.CoinReference{Type: TypeAuctionArchive, URL: heritageSearchURL + query, Title: "Heritage auction archives: " + name, Auto: true},
+		models.CoinReference{Type: TypeAuctionArchive, URL: greatCollectionsURL + query, Title: "GreatCollections auction archives: " + name, Auto: true},
+	)
+}
+
+// PCGSNumber returns the PCGS coin number from a coin's CoinFacts link, so
+// the link can be rebuilt without looking the cert up again
+func PCGSNumber(coin models.Coin) string {
+	for _, ref := range coin.References {
+		if ref.Auto && ref.Type == TypePCGSCoinFacts {
+			if m := coinFactsNumber.FindStringSubmatch(ref.URL); m != nil {
+				return m[1]
+			}
+		}
+	}
+	return ""
+}
+
+// Refresh rebuilds a coin's auto references from its details, keeping the
+// ones its owner added. Auto links the owner already added by hand are left
+// to the owner's copy.
+func Refresh(coin *models.Coin, pcgsNumber string) {
+	refs := []models.CoinReference{}
+	seen := map[string]bool{}
+	for _, ref := range coin.References {
+		if !ref.Auto {
+			refs = append(refs, ref)
+			seen[ref.URL] = true
+		}
+	}
+	for _, ref := range Lookup(*coin, pcgsNumber) {
+		if !seen[ref.URL] {
+			refs = append(refs, ref)
+			seen[ref.URL] = true
+		}
+	}
+	coin.References = refs
+}
+
+// Normalize validates references entered by the owner and returns them
+// trimmed and deduplicated by URL. Entries marked auto are dropped, since
+// lookups manage those.
+func Normalize(refs []models.CoinReference) ([]models.CoinReference, error) {
+	normalized := []models.CoinReference{}
+	seen := map[string]bool{}
+	for _, ref := range refs {
+		if ref.Auto {
+			continue
+		}
+		ref.Type = strings.TrimSpace(ref.Type)
+		if ref.Type == "" {
+			ref.Type = TypeOther
+		}
+		if !ValidType(ref.Type) {
+			return nil, fmt.Errorf("reference type must be one of %s", strings.Join(Types, ", "))
+		}
+		ref.URL = strings.TrimSpace(ref.URL)
+		u, err := url.Parse(ref.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("reference URL %q must be an http or https link", ref.URL)
+		}
+		ref.Title = strings.TrimSpace(ref.Title)
+		if len(ref.Title) > maxTitleLength {
+			return nil, fmt.Errorf("reference titles can be at most %d characters", maxTitleLength)
+		}
+		if seen[ref.URL] {
+			continue
+		}
+		seen[ref.URL] = true
+		normalized = append(normalized, ref)
+	}
+	if len(normalized) > MaxReferences {
+		return nil, fmt.Errorf("a coin can have at most %d references", MaxReferences)
+	}
+	return normalized, nil
+}
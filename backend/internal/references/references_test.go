@@ -0,0 +1,60 @@
+package references
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestRefreshKeepsOwnedReferences(t *testing.T) {
+	book := models.CoinReference{Type: TypeLiterature, URL: "https://example.com/vam-guide", Title: "VAM guide"}
+	coin := models.Coin{CoinType: "Morgan Dollar", Year: 1881, MintMark: "S", PCGSCertNumber: "12345678", References: []models.CoinReference{book}}
+
+	Refresh(&coin, "7126")
+	if len(coin.References) != 6 || coin.References[0] != book {
+		t.Fatalf("references = %+v, want the book followed by five lookups", coin.References)
+	}
+	if got, want := coin.References[1].URL, "https://www.pcgs.com/coinfacts/coin/1881-s-morgan-dollar/7126"; got != want {
+		t.Errorf("CoinFacts link = %q, want %q", got, want)
+	}
+	if got := PCGSNumber(coin); got != "7126" {
+		t.Errorf("PCGSNumber = %q, want 7126", got)
+	}
+
+	// Without a cert the slab's links go, and refreshing twice adds nothing
+	coin.PCGSCertNumber = ""
+	Refresh(&coin, "")
+	Refresh(&coin, "")
+	for _, ref := range coin.References {
+		if ref.Type == TypePCGSCoinFacts || ref.Type == TypePCGSCert {
+			t.Errorf("uncertified coin kept %s link", ref.Type)
+		}
+	}
+	if len(coin.References) != 4 {
+		t.Errorf("got %d references after refreshing, want 4", len(coin.References))
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	refs, err := Normalize([]models.CoinReference{
+		{URL: " https://example.com/a ", Title: " Census "},
+		{Type: TypeAuctionArchive, URL: "https://example.com/a"},
+		{Type: TypePCGSCert, URL: "https://www.pcgs.com/cert/1", Auto: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0].Type != TypeOther || refs[0].URL != "https://example.com/a" || refs[0].Title != "Census" {
+		t.Errorf("Normalize = %+v, want one trimmed reference of type other", refs)
+	}
+
+	for _, bad := range []models.CoinReference{
+		{Type: "blog", URL: "https://example.com"},
+		{URL: "javascript:alert(1)"},
+		{URL: "/relative"},
+	} {
+		if _, err := Normalize([]models.CoinReference{bad}); err == nil {
+			t.Errorf("Normalize(%+v) accepted", bad)
+		}
+	}
+}
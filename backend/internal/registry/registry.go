@@ -0,0 +1,277 @@
+// Package registry ranks the sets users publish to their club's public
+// leaderboard by how complete they are and the condition of their coins. It's
+// off unless PUBLIC_REGISTRY is set.
+package registry
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+)
+
+// MaxSlots caps a set's year range
+const MaxSlots = 250
+
+const defaultCacheTTL = 5 * time.Minute
+
+// ErrUnknownCoinType is returned when a leaderboard is asked for a coin type
+// the catalog doesn't know
+var ErrUnknownCoinType = errors.New("unknown coin type")
+
+// Enabled reports whether the public leaderboard is served (PUBLIC_REGISTRY)
+func Enabled() bool {
+	return config.Bool("PUBLIC_REGISTRY", false)
+}
+
+// CacheTTL is how long a computed leaderboard is served before it's
+// recomputed (REGISTRY_CACHE_TTL, default 5m)
+func CacheTTL() time.Duration {
+	return config.Duration("REGISTRY_CACHE_TTL", defaultCacheTTL)
+}
+
+// Score is how far along a set is. Completion is the percentage of its years
+// held; Quality is the average condition of the best coin in each held year,
+// 100 for certified coins and less for raw coins with problems or poor eye
+// appeal (see valuation.ConditionFactor).
+type Score struct {
+	Slots      int     `json:"slots"`
+	Filled     int     `json:"filled"`
+	Completion float64 `json:"completion"`
+	Quality    float64 `json:"quality"`
+}
+
+// Entry is a published set's place on the leaderboard. It carries nothing
+// about the owner beyond their chosen display name, and no values.
+type Entry struct {
+	Rank        int       `json:"rank"`
+	SetID       uuid.UUID `json:"set_id"`
+	Title       string    `json:"title"`
+	DisplayName string    `json:"display_name"`
+	CoinType    string    `json:"coin_type"`
+	StartYear   int       `json:"start_year"`
+	EndYear     int       `json:"end_year"`
+	Score
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SameSeries reports whether a coin's type is the set's coin type, going
+// through the catalog's aliases when both resolve
+func SameSeries(coinType, setType string) bool {
+	if strings.EqualFold(strings.TrimSpace(coinType), strings.TrimSpace(setType)) {
+		return true
+	}
+	name, _, ok := metals.CanonicalCoinType(coinType)
+	if !ok {
+		return false
+	}
+	setName, _, ok := metals.CanonicalCoinType(setType)
+	return ok && strings.EqualFold(name, setName)
+}
+
+// ScoreSet scores set against the coins of its portfolio
+func ScoreSet(set models.RegistrySet, coins []models.Coin) Score {
+	score := Score{Slots: set.EndYear - set.StartYear + 1}
+	if score.Slots <= 0 {
+		return Score{}
+	}
+
+	best := map[int]float64{}
+	for _, coin := range coins {
+		if coin.Year < set.StartYear || coin.Year > set.EndYear || !SameSeries(coin.CoinType, set.CoinType) {
+			continue
+		}
+		quality := valuation.ConditionFactor(coin)
+		if held, ok := best[coin.Year]; !ok || quality > held {
+			best[coin.Year] = quality
+		}
+	}
+
+	var quality float64
+	for _, q := range best {
+		quality += q
+	}
+	score.Filled = len(best)
+	score.Completion = round(float64(score.Filled) / float64(score.Slots) * 100)
+	if score.Filled > 0 {
+		score.Quality = round(quality / float64(score.Filled) * 100)
+	}
+	return score
+}
+
+// Rank orders entries by completion, then quality, then whoever got there
+// first, and numbers them. Ties share a rank.
+func Rank(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Completion != b.Completion {
+			return a.Completion > b.Completion
+		}
+		if a.Quality != b.Quality {
+			return a.Quality > b.Quality
+		}
+		return a.UpdatedAt.Before(b.UpdatedAt)
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Completion == entries[i-1].Completion && entries[i].Quality == entries[i-1].Quality {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+}
+
+type cachedBoard struct {
+	entries    []Entry
+	computedAt time.Time
+}
+
+var (
+	cacheMu sync.Mutex
+	cache   = map[string]cachedBoard{}
+	// generation counts invalidations, so a board computed from data read
+	// before one isn't cached after it
+	generation uint64
+	computing  singleflight.Group
+)
+
+// Leaderboard returns the ranked published sets of a tenant (nil outside
+// multi-tenant mode), limited to one coin type when coinType isn't empty.
+// The coin type is resolved through the catalog and its aliases, and
+// ErrUnknownCoinType returned when it isn't in it. Results are cached for
+// CacheTTL; concurrent requests for a board that isn't cached share one
+// computation, run outside the cache lock.
+func Leaderboard(tenantID *uuid.UUID, coinType string) ([]Entry, time.Time, error) {
+	if coinType = strings.TrimSpace(coinType); coinType != "" {
+		name, _, ok := metals.CanonicalCoinType(coinType)
+		if !ok {
+			return nil, time.Time{}, ErrUnknownCoinType
+		}
+		coinType = name
+	}
+	key := strings.ToLower(coinType)
+	if tenantID != nil {
+		key = tenantID.String() + "/" + key
+	}
+
+	cacheMu.Lock()
+	board, ok := cache[key]
+	gen := generation
+	cacheMu.Unlock()
+	if ok && time.Since(board.computedAt) < CacheTTL() {
+		return board.entries, board.computedAt, nil
+	}
+
+	computed, err, _ := computing.Do(key, func() (any, error) {
+		entries, err := compute(tenantID, coinType)
+		if err != nil {
+			return nil, err
+		}
+		board := cachedBoard{entries: entries, computedAt: time.Now()}
+		cacheMu.Lock()
+		defer cacheMu.Unlock()
+		if generation == gen {
+			pruneLocked(board.computedAt)
+			cache[key] = board
+		}
+		return board, nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	board = computed.(cachedBoard)
+	return board.entries, board.computedAt, nil
+}
+
+// pruneLocked drops the boards that have expired by now. cacheMu must be
+// held.
+func pruneLocked(now time.Time) {
+	for key, board := range cache {
+		if now.Sub(board.computedAt) >= CacheTTL() {
+			delete(cache, key)
+		}
+	}
+}
+
+// Invalidate drops every cached leaderboard, e.g. after a set is published
+// or withdrawn
+func Invalidate() {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache = map[string]cachedBoard{}
+	generation++
+}
+
+// Subscribe takes deleted portfolios off the registry, and trashed ones off
+// the leaderboard until they're restored
+func Subscribe() {
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action == events.PortfolioTrashed || updated.Action == events.PortfolioRestored {
+			Invalidate()
+			return
+		}
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Where("portfolio_id = ?", updated.PortfolioID).Delete(&models.RegistrySet{}).Error; err != nil {
+			log.Printf("Failed to remove registry set of portfolio %s: %v", updated.PortfolioID, err)
+			return
+		}
+		Invalidate()
+	})
+}
+
+func compute(tenantID *uuid.UUID, coinType string) ([]Entry, error) {
+	db := database.GetDB()
+	query := db.Where("published = ? AND portfolio_id IN (?)", true, db.Model(&models.Portfolio{}).Select("id"))
+	if tenantID != nil {
+		query = query.Where("tenant_id = ?", *tenantID)
+	} else {
+		query = query.Where("tenant_id IS NULL")
+	}
+
+	var sets []models.RegistrySet
+	if err := query.Find(&sets).Error; err != nil {
+		return nil, err
+	}
+
+	entries := []Entry{}
+	for _, set := range sets {
+		if coinType != "" && !SameSeries(set.CoinType, coinType) {
+			continue
+		}
+		var coins []models.Coin
+		if err := db.Where("portfolio_id = ? AND year BETWEEN ? AND ?", set.PortfolioID, set.StartYear, set.EndYear).Find(&coins).Error; err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{
+			SetID:       set.ID,
+			Title:       set.Title,
+			DisplayName: set.DisplayName,
+			CoinType:    set.CoinType,
+			StartYear:   set.StartYear,
+			EndYear:     set.EndYear,
+			Score:       ScoreSet(set, coins),
+			UpdatedAt:   set.UpdatedAt,
+		})
+	}
+	Rank(entries)
+	return entries, nil
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestScoreSet(t *testing.T) {
+	set := models.RegistrySet{CoinType: "Morgan Dollar", StartYear: 1878, EndYear: 1881}
+	coins := []models.Coin{
+		{CoinType: "Morgan Dollar", Year: 1878, PCGSCertNumber: "12345678"},
+		{CoinType: "morgan dollar", Year: 1879, Problems: []string{"cleaned"}}, // 70
+		{CoinType: "Morgan Dollar", Year: 1879, PCGSCertNumber: "23456789"},    // better coin for the same year
+		{CoinType: "Morgan Dollar", Year: 1880, Problems: []string{"holed"}},   // 40
+		{CoinType: "Morgan Dollar", Year: 1921},                                // outside the set
+		{CoinType: "Peace Dollar", Year: 1881},                                 // another series
+	}
+
+	got := ScoreSet(set, coins)
+	want := Score{Slots: 4, Filled: 3, Completion: 75, Quality: 80}
+	if got != want {
+		t.Errorf("ScoreSet = %+v, want %+v", got, want)
+	}
+}
+
+func TestRank(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Title: "half done", Score: Score{Completion: 50, Quality: 100}, UpdatedAt: now},
+		{Title: "complete, raw", Score: Score{Completion: 100, Quality: 80}, UpdatedAt: now},
+		{Title: "complete, certified", Score: Score{Completion: 100, Quality: 100}, UpdatedAt: now},
+		{Title: "half done earlier", Score: Score{Completion: 50, Quality: 100}, UpdatedAt: now.Add(-time.Hour)},
+	}
+	Rank(entries)
+
+	want := []struct {
+		title string
+		rank  int
+	}{{"complete, certified", 1}, {"complete, raw", 2}, {"half done earlier", 3}, {"half done", 3}}
+	for i, w := range want {
+		if entries[i].Title != w.title || entries[i].Rank != w.rank {
+			t.Errorf("entries[%d] = %q rank %d, want %q rank %d", i, entries[i].Title, entries[i].Rank, w.title, w.rank)
+		}
+	}
+}
+
+func TestLeaderboardRejectsUnknownCoinTypes(t *testing.T) {
+	// Rejected before the cache or the database is touched, so made-up types
+	// can't grow the cache
+	if _, _, err := Leaderboard(nil, "no such coin "+time.Now().String()); !errors.Is(err, ErrUnknownCoinType) {
+		t.Errorf("unknown coin type = %v, want ErrUnknownCoinType", err)
+	}
+	if len(cache) != 0 {
+		t.Errorf("cache has %d boards", len(cache))
+	}
+}
@@ -0,0 +1,134 @@
+// Package rounding rounds the money amounts in API responses by one
+// instance-wide policy, so every endpoint reports the same figure for the
+// same value: melt values to cents, numismatic values to cents or, once they
+// are large, to whole dollars. Amounts are stored unrounded.
+package rounding
+
+import (
+	"math"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/jsonrewrite"
+)
+
+// Policy is how many decimals each kind of amount keeps. Negative decimals
+// leave that kind unrounded.
+type Policy struct {
+	MeltDecimals       int
+	ValueDecimals      int
+	LargeValueAbove    float64 // 0 treats no value as large
+	LargeValueDecimals int
+}
+
+// Default rounds melt and other amounts to cents, and numismatic values of
+// $1,000 or more to dollars
+var Default = Policy{MeltDecimals: 2, ValueDecimals: 2, LargeValueAbove: 1000, LargeValueDecimals: 0}
+
+// Keys of the amounts that are numismatic, or can be, and so count as large
+// past LargeValueAbove. Keys containing "melt" are melt values.
+var numismaticKeys = map[string]bool{
+	"numismatic_value": true,
+	"pcgs_value":       true,
+	"pcgs_guide_value": true,
+	"insured_value":    true,
+	"current_value":    true,
+	"total_value":      true,
+	"start_value":      true,
+	"end_value":        true,
+	"basis_value":      true,
+}
+
+// Keys of the other money amounts. Percentages, ratios and ambiguous keys
+// such as "value" are left alone.
+var moneyKeys = map[string]bool{
+	"acquisition_cost":       true,
+	"buyers_premium":         true,
+	"cost":                   true,
+	"cost_basis":             true,
+	"disposal_proceeds":      true,
+	"gain":                   true,
+	"gain_loss":              true,
+	"long_term":              true,
+	"lot_total":              true,
+	"premium_over_melt":      true,
+	"price":                  true,
+	"proceeds":               true,
+	"purchase_cost":          true,
+	"purchase_price":         true,
+	"sale_fees":              true,
+	"sale_price":             true,
+	"sales_tax":              true,
+	"scheduled_total":        true,
+	"shipping_cost":          true,
+	"short_term":             true,
+	"total_acquisition_fees": true,
+	"total_gain_loss":        true,
+	"total_hammer_price":     true,
+	"total_price":            true,
+	"total_purchase_cost":    true,
+	"unscheduled_total":      true,
+}
+
+// Enabled reports whether responses are rounded (VALUE_ROUNDING, default on)
+func Enabled() bool {
+	return config.Bool("VALUE_ROUNDING", true)
+}
+
+// Current returns the instance's policy: Default with MELT_VALUE_DECIMALS,
+// VALUE_DECIMALS, LARGE_VALUE_ABOVE and LARGE_VALUE_DECIMALS overriding it
+func Current() Policy {
+	return Policy{
+		MeltDecimals:       int(config.Int64("MELT_VALUE_DECIMALS", int64(Default.MeltDecimals))),
+		ValueDecimals:      int(config.Int64("VALUE_DECIMALS", int64(Default.ValueDecimals))),
+		LargeValueAbove:    float64(config.Int64("LARGE_VALUE_ABOVE", int64(Default.LargeValueAbove))),
+		LargeValueDecimals: int(config.Int64("LARGE_VALUE_DECIMALS", int64(Default.LargeValueDecimals))),
+	}
+}
+
+// Decimals returns how many decimals the amount under key keeps, or false
+// when it isn't rounded
+func (p Policy) Decimals(key string, value float64) (int, bool) {
+	var decimals int
+	switch {
+	case strings.Contains(key, "melt") && !strings.Contains(key, "percent"):
+		decimals = p.MeltDecimals
+	case numismaticKeys[key]:
+		decimals = p.ValueDecimals
+		if p.LargeValueAbove > 0 && math.Abs(value) >= p.LargeValueAbove {
+			decimals = p.LargeValueDecimals
+		}
+	case moneyKeys[key]:
+		decimals = p.ValueDecimals
+	default:
+		return 0, false
+	}
+	return decimals, decimals >= 0
+}
+
+// IsAmount reports whether the number under key is a money amount, whether
+// or not the policy rounds it
+func IsAmount(key string) bool {
+	_, ok := Policy{}.Decimals(key, 0)
+	return ok
+}
+
+// Round rounds value to decimals places, halves away from zero
+func Round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// Apply rounds the amounts in a JSON document by key, wherever they're
+// nested, leaving everything else including key order as it was
+func Apply(body []byte, p Policy) ([]byte, error) {
+	return jsonrewrite.Rewrite(body, jsonrewrite.Rewriter{
+		Number: func(key string, value float64) (float64, bool) {
+			decimals, ok := p.Decimals(key, value)
+			if !ok {
+				return 0, false
+			}
+			return Round(value, decimals), true
+		},
+	})
+}
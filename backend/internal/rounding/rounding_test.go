@@ -0,0 +1,44 @@
+package rounding
+
+import "testing"
+
+func TestApplyRoundsByKeyAndKeepsTheRest(t *testing.T) {
+	body := `{"id":"c1","melt_value":21.456789,"coins":[{"numismatic_value":1234.56,"current_value":99.999,"quantity":3},` +
+		`{"numismatic_value":12.345,"condition_factor":0.8525,"value":0.3581}],"totals":{"proceeds":70.005,"gain_loss_percent":12.3456},"tags":["a",null,true]}`
+
+	got, err := Apply([]byte(body), Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"c1","melt_value":21.46,"coins":[{"numismatic_value":1235,"current_value":100,"quantity":3},` +
+		`{"numismatic_value":12.35,"condition_factor":0.8525,"value":0.3581}],"totals":{"proceeds":70.01,"gain_loss_percent":12.3456},"tags":["a",null,true]}`
+	if string(got) != want {
+		t.Errorf("Apply =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPolicyDecimals(t *testing.T) {
+	p := Policy{MeltDecimals: 3, ValueDecimals: -1, LargeValueAbove: 0}
+	if d, ok := p.Decimals("scenario_melt_value", 1); !ok || d != 3 {
+		t.Errorf("melt decimals = %d, %v; want 3", d, ok)
+	}
+	if _, ok := p.Decimals("numismatic_value", 5000); ok {
+		t.Error("negative decimals should leave values unrounded")
+	}
+	if _, ok := p.Decimals("day_change", 1.234); ok {
+		t.Error("percent changes aren't money")
+	}
+}
+
+func TestApplyKeepsScalarsAndEscapes(t *testing.T) {
+	// gin escapes HTML in JSON, and so does Apply
+	for _, body := range []string{`[]`, `{}`, `"\u003cb\u003e"`, `[1.23456,{"a":{}}]`} {
+		got, err := Apply([]byte(body), Default)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != body {
+			t.Errorf("Apply(%s) = %s", body, got)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/audit"
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/demo"
+	"github.com/evansminotwood/aureus/internal/enrichment"
+	"github.com/evansminotwood/aureus/internal/fxrates"
+	"github.com/evansminotwood/aureus/internal/jobs"
+	"github.com/evansminotwood/aureus/internal/loginguard"
+	"github.com/evansminotwood/aureus/internal/market"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/pcgssync"
+	"github.com/evansminotwood/aureus/internal/sessions"
+	"github.com/evansminotwood/aureus/internal/spothistory"
+	"github.com/evansminotwood/aureus/internal/statements"
+	"github.com/evansminotwood/aureus/internal/trash"
+)
+
+const (
+	defaultSpotRefreshInterval       = 15 * time.Minute
+	defaultMarketCloseCheckInterval  = 5 * time.Minute
+	defaultFXRefreshInterval         = 6 * time.Hour
+	defaultStatementCheckInterval    = time.Hour
+	defaultPCGSSyncCheckInterval     = time.Hour
+	defaultPartitionCheckInterval    = 24 * time.Hour
+	defaultRefreshTokenPurgeInterval = 24 * time.Hour
+	defaultLoginGuardPurgeInterval   = 24 * time.Hour
+	defaultAuditLogPurgeInterval     = 24 * time.Hour
+	defaultEnrichmentSweepInterval   = 10 * time.Minute
+	defaultDemoPurgeInterval         = time.Hour
+	defaultTrashPurgeInterval        = time.Hour
+	defaultJobSweepInterval          = 5 * time.Minute
+)
+
+// Job is a unit of background work run on a fixed interval
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// JobStatus reports the most recent run of a scheduled job
+type JobStatus struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	Running      bool       `json:"running"`
+	LastRun      *time.Time `json:"last_run,omitempty"`
+	LastDuration string     `json:"last_duration,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+var (
+	statusMu sync.Mutex
+	statuses = make(map[string]*JobStatus)
+)
+
+// DefaultJobs returns the jobs the API server runs in the background
+func DefaultJobs() []Job {
+	return []Job{
+		{
+			Name:     "spot-refresh",
+			Interval: config.Duration("SPOT_REFRESH_INTERVAL", defaultSpotRefreshInterval),
+			Run:      refreshSpotPrices,
+		},
+		{
+			// Keeps the spot prices at each market close, which daily
+			// changes are measured from
+			Name:     "market-close",
+			Interval: config.Duration("MARKET_CLOSE_CHECK_INTERVAL", defaultMarketCloseCheckInterval),
+			Run:      recordMarketClose,
+		},
+		{
+			// Exchange rates for restating values in other currencies
+			Name:     "fx-refresh",
+			Interval: config.Duration("FX_REFRESH_INTERVAL", defaultFXRefreshInterval),
+			Run:      func() error { return fxrates.Refresh(time.Now()) },
+		},
+		{
+			// Statements go out on the first check of each month
+			Name:     "monthly-statements",
+			Interval: config.Duration("STATEMENT_CHECK_INTERVAL", defaultStatementCheckInterval),
+			Run:      func() error { return statements.SendDue(time.Now()) },
+		},
+		{
+			// Users choose how often their coins are synced; this is how
+			// often due schedules are looked for
+			Name:     "pcgs-sync",
+			Interval: config.Duration("PCGS_SYNC_CHECK_INTERVAL", defaultPCGSSyncCheckInterval),
+			Run:      func() error { return pcgssync.SyncDue(time.Now()) },
+		},
+		{
+			// Creates next months' price history partitions ahead of time
+			// and drops expired ones
+			Name:     "price-history-partitions",
+			Interval: config.Duration("PRICE_HISTORY_PARTITION_CHECK_INTERVAL", defaultPartitionCheckInterval),
+			Run:      func() error { return database.MaintainPriceHistoryPartitions(time.Now()) },
+		},
+		{
+			Name:     "refresh-token-purge",
+			Interval: config.Duration("REFRESH_TOKEN_PURGE_INTERVAL", defaultRefreshTokenPurgeInterval),
+			Run:      func() error { return sessions.PurgeExpired(time.Now()) },
+		},
+		{
+			// Drops stale login throttles and old failed attempts
+			Name:     "login-guard-purge",
+			Interval: config.Duration("LOGIN_GUARD_PURGE_INTERVAL", defaultLoginGuardPurgeInterval),
+			Run:      func() error { return loginguard.Purge(time.Now()) },
+		},
+		{
+			// Drops audit log entries past AUDIT_LOG_RETENTION
+			Name:     "audit-log-purge",
+			Interval: config.Duration("AUDIT_LOG_PURGE_INTERVAL", defaultAuditLogPurgeInterval),
+			Run:      func() error { return audit.Purge(time.Now()) },
+		},
+		{
+			// Requeues coins whose cert lookup was cut short by a restart,
+			// a failure or the PCGS quota
+			Name:     "coin-enrichment",
+			Interval: config.Duration("ENRICHMENT_SWEEP_INTERVAL", defaultEnrichmentSweepInterval),
+			Run:      enrichment.Sweep,
+		},
+		{
+			// Deletes demo accounts past DEMO_ACCOUNT_TTL
+			Name:     "demo-purge",
+			Interval: config.Duration("DEMO_PURGE_INTERVAL", defaultDemoPurgeInterval),
+			Run:      func() error { return demo.Purge(time.Now()) },
+		},
+		{
+			// Deletes portfolios and coins in the trash past TRASH_RETENTION
+			Name:     "trash-purge",
+			Interval: config.Duration("TRASH_PURGE_INTERVAL", defaultTrashPurgeInterval),
+			Run:      func() error { return trash.Purge(time.Now()) },
+		},
+		{
+			// Fails background jobs cut short by a restart and drops
+			// finished ones past JOB_RETENTION
+			Name:     "job-sweep",
+			Interval: config.Duration("JOB_SWEEP_INTERVAL", defaultJobSweepInterval),
+			Run:      func() error { return jobs.Sweep(time.Now()) },
+		},
+	}
+}
+
+// Start runs each job on its own ticker until ctx is cancelled
+func Start(ctx context.Context, jobs []Job) {
+	for _, job := range jobs {
+		go runJob(ctx, job)
+	}
+}
+
+func runJob(ctx context.Context, job Job) {
+	log.Printf("Scheduler: starting %s every %s", job.Name, job.Interval)
+
+	statusMu.Lock()
+	statuses[job.Name] = &JobStatus{Name: job.Name, Interval: job.Interval.String()}
+	statusMu.Unlock()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			execute(job)
+		}
+	}
+}
+
+// execute runs a job once and records its outcome
+func execute(job Job) {
+	start := time.Now()
+	setStatus(job.Name, func(s *JobStatus) { s.Running = true })
+
+	err := job.Run()
+	if err != nil {
+		log.Printf("Scheduler: %s failed: %v", job.Name, err)
+	}
+
+	setStatus(job.Name, func(s *JobStatus) {
+		s.Running = false
+		s.LastRun = &start
+		s.LastDuration = time.Since(start).Round(time.Millisecond).String()
+		s.LastError = ""
+		if err != nil {
+			s.LastError = err.Error()
+		}
+	})
+}
+
+func setStatus(name string, update func(*JobStatus)) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	if s, ok := statuses[name]; ok {
+		update(s)
+	}
+}
+
+// Status returns the state of every registered job
+func Status() []JobStatus {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	result := make([]JobStatus, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// QueueDepth returns the number of jobs currently executing
+func QueueDepth() int {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+
+	depth := 0
+	for _, s := range statuses {
+		if s.Running {
+			depth++
+		}
+	}
+	return depth
+}
+
+// refreshSpotPrices refreshes the spot price cache. Subscribers such as
+// alert evaluation and coin revaluation react to the resulting
+// SpotPricesRefreshed event.
+func refreshSpotPrices() error {
+	_, err := metals.RefreshSpotPrices()
+	return err
+}
+
+// recordMarketClose records the spot prices at the last market close. A
+// close with no live refresh before it is skipped; its daily change falls
+// back to the nearest refresh.
+func recordMarketClose() error {
+	err := spothistory.RecordClose(market.Default().LastClose(time.Now()))
+	if errors.Is(err, spothistory.ErrNoClosePrices) {
+		return nil
+	}
+	return err
+}
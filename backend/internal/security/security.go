@@ -0,0 +1,118 @@
+// Package security holds the browser-facing hardening applied to every
+// response: the security headers and the attributes cookies are set with.
+// Both are configured per environment, e.g. HSTS only where the API is
+// served over HTTPS.
+package security
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// DefaultContentSecurityPolicy suits an API answering with JSON and
+// images: nothing a response holds may load or run anything, or be framed
+const DefaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// Headers returns the security headers every response gets, or none with
+// SECURITY_HEADERS=false, e.g. when a reverse proxy sets its own
+func Headers() http.Header {
+	headers := http.Header{}
+	if !config.Bool("SECURITY_HEADERS", true) {
+		return headers
+	}
+
+	headers.Set("X-Content-Type-Options", "nosniff")
+	headers.Set("X-Frame-Options", "DENY")
+	headers.Set("Referrer-Policy", config.String("REFERRER_POLICY", "no-referrer"))
+	headers.Set("Cross-Origin-Opener-Policy", "same-origin")
+	if csp := config.String("CONTENT_SECURITY_POLICY", DefaultContentSecurityPolicy); csp != "off" {
+		headers.Set("Content-Security-Policy", csp)
+	}
+	if hsts := HSTS(config.Duration("HSTS_MAX_AGE", 0), config.Bool("HSTS_INCLUDE_SUBDOMAINS", false)); hsts != "" {
+		headers.Set("Strict-Transport-Security", hsts)
+	}
+	return headers
+}
+
+// HSTS is the Strict-Transport-Security value for maxAge, or "" to send
+// none. It is off by default since browsers then refuse plain HTTP to the
+// host for maxAge, which breaks local development.
+func HSTS(maxAge time.Duration, includeSubdomains bool) string {
+	if maxAge <= 0 {
+		return ""
+	}
+	value := fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return value
+}
+
+// CookiePolicy is the attributes cookies are set with
+type CookiePolicy struct {
+	Secure   bool
+	SameSite http.SameSite
+	Domain   string
+}
+
+// ParseSameSite reads a SameSite setting: strict, lax or none
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+	return 0, fmt.Errorf("%q is not strict, lax or none", value)
+}
+
+// CurrentCookiePolicy reads COOKIE_SECURE (default true; false only for
+// plain HTTP development on another host than localhost), COOKIE_SAMESITE
+// (default strict) and COOKIE_DOMAIN (default the API's host)
+func CurrentCookiePolicy() CookiePolicy {
+	policy := CookiePolicy{
+		Secure:   config.Bool("COOKIE_SECURE", true),
+		SameSite: http.SameSiteStrictMode,
+		Domain:   config.String("COOKIE_DOMAIN", ""),
+	}
+	if sameSite, err := ParseSameSite(config.String("COOKIE_SAMESITE", "strict")); err == nil {
+		policy.SameSite = sameSite
+	}
+	return policy
+}
+
+// Apply sets the policy's attributes on cookie. Cookies are always
+// HttpOnly, since no cookie of the API's is for scripts to read. A cookie
+// that already asks for a SameSite mode keeps it, e.g. one that has to
+// come back on a provider's cross-site POST; SameSite=None cookies are
+// always Secure, as browsers drop them otherwise.
+func (p CookiePolicy) Apply(cookie *http.Cookie) {
+	cookie.HttpOnly = true
+	cookie.Secure = p.Secure
+	if cookie.SameSite == 0 || cookie.SameSite == http.SameSiteDefaultMode {
+		cookie.SameSite = p.SameSite
+	}
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+	if cookie.Domain == "" {
+		cookie.Domain = p.Domain
+	}
+}
+
+// SetCookie sets cookie on the response with the current policy applied
+func SetCookie(w http.ResponseWriter, cookie *http.Cookie) {
+	CurrentCookiePolicy().Apply(cookie)
+	http.SetCookie(w, cookie)
+}
+
+// ClearCookie tells the browser to drop the cookie name set on path
+func ClearCookie(w http.ResponseWriter, name, path string, sameSite http.SameSite) {
+	SetCookie(w, &http.Cookie{Name: name, Path: path, MaxAge: -1, SameSite: sameSite})
+}
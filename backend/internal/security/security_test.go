@@ -0,0 +1,77 @@
+package security
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestHeaders(t *testing.T) {
+	headers := Headers()
+	if headers.Get("X-Content-Type-Options") != "nosniff" || headers.Get("X-Frame-Options") != "DENY" {
+		t.Errorf("default headers = %v", headers)
+	}
+	if headers.Get("Content-Security-Policy") != DefaultContentSecurityPolicy {
+		t.Errorf("CSP = %q, want the default", headers.Get("Content-Security-Policy"))
+	}
+	if headers.Get("Strict-Transport-Security") != "" {
+		t.Error("HSTS is sent without HSTS_MAX_AGE")
+	}
+
+	t.Setenv("CONTENT_SECURITY_POLICY", "off")
+	t.Setenv("HSTS_MAX_AGE", "8760h")
+	t.Setenv("HSTS_INCLUDE_SUBDOMAINS", "true")
+	headers = Headers()
+	if _, ok := headers["Content-Security-Policy"]; ok {
+		t.Error("CSP is sent when turned off")
+	}
+	if got := headers.Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("HSTS = %q", got)
+	}
+
+	t.Setenv("SECURITY_HEADERS", "false")
+	if headers := Headers(); len(headers) != 0 {
+		t.Errorf("headers with SECURITY_HEADERS=false = %v, want none", headers)
+	}
+}
+
+func TestHSTS(t *testing.T) {
+	if got := HSTS(0, true); got != "" {
+		t.Errorf("HSTS(0) = %q, want none", got)
+	}
+	if got := HSTS(time.Hour, false); got != "max-age=3600" {
+		t.Errorf("HSTS(1h) = %q", got)
+	}
+}
+
+func TestParseSameSite(t *testing.T) {
+	for value, want := range map[string]http.SameSite{
+		"strict": http.SameSiteStrictMode,
+		" Lax ":  http.SameSiteLaxMode,
+		"NONE":   http.SameSiteNoneMode,
+	} {
+		if got, err := ParseSameSite(value); err != nil || got != want {
+			t.Errorf("ParseSameSite(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	if _, err := ParseSameSite("sometimes"); err == nil {
+		t.Error("ParseSameSite accepted an unknown mode")
+	}
+}
+
+func TestCookiePolicyApply(t *testing.T) {
+	policy := CookiePolicy{Secure: false, SameSite: http.SameSiteStrictMode, Domain: "example.com"}
+
+	session := &http.Cookie{Name: "session"}
+	policy.Apply(session)
+	if !session.HttpOnly || session.Secure || session.SameSite != http.SameSiteStrictMode || session.Domain != "example.com" {
+		t.Errorf("applied cookie = %+v", session)
+	}
+
+	// A cross-site cookie keeps its mode and stays Secure, or browsers drop it
+	nonce := &http.Cookie{Name: "nonce", SameSite: http.SameSiteNoneMode, Domain: "api.example.com"}
+	policy.Apply(nonce)
+	if !nonce.Secure || nonce.SameSite != http.SameSiteNoneMode || nonce.Domain != "api.example.com" {
+		t.Errorf("applied cross-site cookie = %+v", nonce)
+	}
+}
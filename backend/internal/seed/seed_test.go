@@ -0,0 +1,48 @@
+package seed
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/metals"
+)
+
+func TestGeneratedCoinsArePlausible(t *testing.T) {
+	calc := metals.NewCalculator(metals.SpotPrices{Gold: 2000, Silver: 25, Platinum: 1000, Palladium: 1000, Copper: 4, Nickel: 8})
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	types := catalogTypes()
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		coin := generateCoin(r, types, calc, now)
+		if coin.CurrentValue <= 0 || coin.PurchasePrice <= 0 || coin.Quantity < 1 {
+			t.Fatalf("coin %d = %+v, want positive value, price and quantity", i, coin)
+		}
+		if coin.Year > now.Year() || coin.PurchaseDate.After(now) {
+			t.Fatalf("coin %d is from the future: year %d, bought %s", i, coin.Year, coin.PurchaseDate)
+		}
+		if coin.MetalType == "" {
+			t.Fatalf("coin %d (%s) has no composition", i, coin.CoinType)
+		}
+
+		for _, s := range generateSnapshots(r, coin, 24, now) {
+			if s.RecordedAt.Before(*coin.PurchaseDate) {
+				t.Fatalf("coin %d has a snapshot from before it was bought", i)
+			}
+		}
+	}
+}
+
+func TestSameSeedSameCoins(t *testing.T) {
+	calc := metals.NewCalculator(metals.SpotPrices{Gold: 2000, Silver: 25})
+	now := time.Now()
+	types := catalogTypes()
+
+	a := generateCoin(rand.New(rand.NewSource(42)), types, calc, now)
+	b := generateCoin(rand.New(rand.NewSource(42)), types, calc, now)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed generated %+v and %+v", a, b)
+	}
+}
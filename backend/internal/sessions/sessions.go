@@ -0,0 +1,290 @@
+// Package sessions keeps users signed in past their access token's expiry.
+// A login starts a session and hands out a refresh token alongside the
+// access token; trading it in at /auth/refresh rotates it and issues a new
+// access token in the same session. Access tokens name their session, so
+// logging out or revoking a session from the device list signs that device
+// out at once. Logging out everywhere revokes every session and bumps the
+// user's token version so outstanding scoped tokens stop working too.
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken is returned for a refresh token that is unknown, expired
+// or revoked
+var ErrInvalidToken = errors.New("invalid or expired refresh token")
+
+// ErrSessionRevoked is returned for an access token whose session was
+// revoked or no longer exists
+var ErrSessionRevoked = errors.New("session has been revoked")
+
+// ErrSessionNotFound is returned when revoking a session the user doesn't
+// have, or that already ended
+var ErrSessionNotFound = errors.New("session not found")
+
+// lastSeenPrecision is how stale a session's last_seen_at may get, so
+// requests don't all write to the database
+const lastSeenPrecision = time.Minute
+
+// RefreshTokenTTL is how long a refresh token lasts unused (REFRESH_TOKEN_TTL,
+// default 30 days). Each rotation starts the period again.
+func RefreshTokenTTL() time.Duration {
+	return config.Duration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// create stores a new refresh token in family and returns it with its
+// plaintext, which is only ever handed to the client
+func create(tx *gorm.DB, userID, familyID uuid.UUID, userAgent string, now time.Time) (models.RefreshToken, string, error) {
+	plain, err := newToken()
+	if err != nil {
+		return models.RefreshToken{}, "", err
+	}
+	token := models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(plain),
+		UserAgent: userAgent,
+		ExpiresAt: now.Add(RefreshTokenTTL()),
+	}
+	if err := tx.Create(&token).Error; err != nil {
+		return models.RefreshToken{}, "", err
+	}
+	return token, plain, nil
+}
+
+// Issue starts a new session for userID on the device of userAgent and ip,
+// returning its first refresh token. The token's FamilyID is the session.
+func Issue(userID uuid.UUID, userAgent, ip string) (models.RefreshToken, string, error) {
+	var token models.RefreshToken
+	var plain string
+	now := time.Now()
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			ID:         uuid.New(),
+			UserID:     userID,
+			UserAgent:  userAgent,
+			Device:     Device(userAgent),
+			IPAddress:  ip,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(RefreshTokenTTL()),
+		}
+		if err := tx.Create(&session).Error; err != nil {
+			return err
+		}
+		var err error
+		token, plain, err = create(tx, userID, session.ID, userAgent, now)
+		return err
+	})
+	return token, plain, err
+}
+
+// Rotate trades a refresh token in for a new one in the same family and
+// returns the user it belongs to, recording the device and ip on the
+// session. A token that was already used or revoked revokes its whole
+// family, since whoever presents it isn't the client the replacement went
+// to.
+func Rotate(plain, userAgent, ip string) (models.User, models.RefreshToken, string, error) {
+	var user models.User
+	var next models.RefreshToken
+	var nextPlain string
+	var reused *models.RefreshToken
+	now := time.Now()
+
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		var current models.RefreshToken
+		if err := tx.Where("token_hash = ?", hashToken(plain)).First(&current).Error; err != nil {
+			return ErrInvalidToken
+		}
+		if current.RevokedAt != nil {
+			reused = &current
+			return ErrInvalidToken
+		}
+		if !now.Before(current.ExpiresAt) {
+			return ErrInvalidToken
+		}
+		if err := tx.First(&user, "id = ?", current.UserID).Error; err != nil {
+			return ErrInvalidToken
+		}
+
+		var err error
+		if next, nextPlain, err = create(tx, current.UserID, current.FamilyID, userAgent, now); err != nil {
+			return err
+		}
+		// Only one of two concurrent refreshes with the same token wins
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"revoked_at": now, "replaced_by_id": next.ID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			reused = &current
+			return ErrInvalidToken
+		}
+
+		// Families from before sessions were tracked get one now
+		session := models.Session{ID: current.FamilyID, UserID: current.UserID, CreatedAt: current.CreatedAt}
+		if err := tx.Where("id = ?", current.FamilyID).FirstOrInit(&session).Error; err != nil {
+			return err
+		}
+		session.UserAgent = userAgent
+		session.Device = Device(userAgent)
+		session.IPAddress = ip
+		session.LastSeenAt = now
+		session.ExpiresAt = next.ExpiresAt
+		return tx.Save(&session).Error
+	})
+
+	if reused != nil {
+		log.Printf("Refresh token %s of user %s was reused; revoking its family", reused.ID, reused.UserID)
+		if err := revokeFamily(reused.FamilyID, now); err != nil {
+			log.Printf("Failed to revoke refresh token family %s: %v", reused.FamilyID, err)
+		}
+	}
+	return user, next, nextPlain, err
+}
+
+// revokeFamily ends a session: its refresh tokens and its access tokens
+func revokeFamily(familyID uuid.UUID, now time.Time) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+}
+
+// Revoke ends the session a refresh token belongs to, along with the access
+// tokens issued in it. Unknown tokens are ignored, so logging out twice isn't
+// an error.
+func Revoke(plain string) error {
+	var token models.RefreshToken
+	if err := database.GetDB().Where("token_hash = ?", hashToken(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	return revokeFamily(token.FamilyID, time.Now())
+}
+
+// RevokeSession ends one of userID's sessions, e.g. on a lost phone. Its
+// refresh and access tokens stop working at once.
+func RevokeSession(userID, id uuid.UUID) error {
+	var session models.Session
+	err := database.GetDB().Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return revokeFamily(session.ID, time.Now())
+}
+
+// List returns userID's active sessions, most recently seen first
+func List(userID uuid.UUID) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := database.GetDB().
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Seen checks that the session an access token names is still active and
+// records that it was used, from ip
+func Seen(id, userID uuid.UUID, ip string) error {
+	db := database.GetDB()
+	var session models.Session
+	err := db.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) > lastSeenPrecision || session.IPAddress != ip {
+		db.Model(&session).UpdateColumns(map[string]interface{}{"last_seen_at": now, "ip_address": ip})
+	}
+	return nil
+}
+
+// RevokeAll logs a user out everywhere: every session and refresh token is
+// revoked and the token version bumped, so access and scoped tokens issued
+// so far are rejected
+func RevokeAll(userID uuid.UUID) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).
+			Update("token_version", gorm.Expr("token_version + 1")).Error
+	})
+}
+
+// TokenVersion returns the user's current token version, which access
+// tokens must carry to be accepted
+func TokenVersion(userID uuid.UUID) (int, error) {
+	var user models.User
+	err := database.GetDB().Select("token_version").First(&user, "id = ?", userID).Error
+	return user.TokenVersion, err
+}
+
+// PurgeExpired deletes refresh tokens and sessions that expired before now,
+// revoked or not. Revoked tokens are kept until then so reuse is still
+// detected.
+func PurgeExpired(now time.Time) error {
+	result := database.GetDB().Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Purged %d expired refresh tokens", result.RowsAffected)
+	}
+	// Access tokens naming a purged session are refused, which only cuts
+	// one short when ACCESS_TOKEN_TTL is longer than REFRESH_TOKEN_TTL
+	return database.GetDB().Where("expires_at < ?", now).Delete(&models.Session{}).Error
+}
@@ -0,0 +1,200 @@
+// Package settings holds each user's display preferences and applies them
+// to what the API sends back. Amounts are stored in US dollars and weights
+// in troy ounces; a Localizer restates a response in the user's currency
+// and weight unit on the way out, and turns what they send back into
+// dollars and ounces on the way in, so a value read and saved unchanged
+// stays the same.
+package settings
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/fxrates"
+	"github.com/evansminotwood/aureus/internal/jsonrewrite"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/rounding"
+	"github.com/google/uuid"
+)
+
+// Date formats
+const (
+	DateISO = "iso" // 2026-03-14
+	DateUS  = "us"  // 03/14/2026
+	DateEU  = "eu"  // 14/03/2026
+)
+
+// DateFormats lists every date format
+var DateFormats = []string{DateISO, DateUS, DateEU}
+
+// WeightUnits lists the units weights can be shown in
+var WeightUnits = []string{metals.UnitTroyOunce, metals.UnitGram}
+
+// weightKeys are the weights converted to the user's unit, all stored in
+// troy ounces
+var weightKeys = map[string]bool{
+	"metal_weight": true,
+}
+
+// Defaults returns the settings of a user who hasn't changed any
+func Defaults(userID uuid.UUID) models.UserSettings {
+	return models.UserSettings{
+		UserID:     userID,
+		Currency:   metals.Currency,
+		WeightUnit: metals.UnitTroyOunce,
+		DateFormat: DateISO,
+	}
+}
+
+// For returns the user's settings, or the defaults when they have none
+func For(userID uuid.UUID) (models.UserSettings, error) {
+	settings := Defaults(userID)
+	err := database.GetReadDB().Where("user_id = ?", userID).Limit(1).Find(&settings).Error
+	return settings, err
+}
+
+// ValidWeightUnit reports whether unit is one of WeightUnits
+func ValidWeightUnit(unit string) bool {
+	return slices.Contains(WeightUnits, unit)
+}
+
+// ValidDateFormat reports whether format is one of DateFormats
+func ValidDateFormat(format string) bool {
+	return slices.Contains(DateFormats, format)
+}
+
+// NormalizeCurrency checks a currency can be shown: the dollar, or one an
+// exchange rate has been recorded for
+func NormalizeCurrency(code string) (string, error) {
+	currency, ok := metals.NormalizeCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("currency must be a three-letter currency code like EUR")
+	}
+	if _, err := fxrates.At(currency, time.Now()); err != nil {
+		return "", fmt.Errorf("no exchange rate is recorded for %s; the instance records %s", currency, strings.Join(fxrates.Currencies(), ", "))
+	}
+	return currency, nil
+}
+
+// FormatDate formats a date in format
+func FormatDate(t time.Time, format string) string {
+	switch format {
+	case DateUS:
+		return t.Format("01/02/2006")
+	case DateEU:
+		return t.Format("02/01/2006")
+	}
+	return t.Format("2006-01-02")
+}
+
+// FormatTime formats a time in format, to the minute. ISO keeps RFC 3339,
+// which is what machine-read exports have always had.
+func FormatTime(t time.Time, format string) string {
+	t = t.UTC()
+	switch format {
+	case DateUS, DateEU:
+		return FormatDate(t, format) + t.Format(" 15:04")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Localizer restates amounts and weights in a user's currency and unit
+type Localizer struct {
+	Currency string
+	Rate     float64 // units of Currency per US dollar
+	Grams    bool
+}
+
+// NewLocalizer returns the Localizer for settings at today's exchange rate.
+// When no rate is recorded for the currency any more, amounts stay in
+// dollars, which responses that carry a currency say.
+func NewLocalizer(s models.UserSettings) (Localizer, error) {
+	l := Localizer{Currency: metals.Currency, Rate: 1, Grams: s.WeightUnit == metals.UnitGram}
+	if s.Currency == "" || s.Currency == metals.Currency {
+		return l, nil
+	}
+	rate, err := fxrates.At(s.Currency, time.Now())
+	if err != nil {
+		return l, err
+	}
+	l.Currency, l.Rate = s.Currency, rate
+	return l, nil
+}
+
+// Identity reports whether the Localizer leaves everything as stored
+func (l Localizer) Identity() bool {
+	return l.Rate == 1 && l.Currency == metals.Currency && !l.Grams
+}
+
+// Amount restates a dollar amount in the user's currency
+func (l Localizer) Amount(usd float64) float64 {
+	return usd * l.Rate
+}
+
+// Response restates the amounts and weights in a JSON response, and labels
+// it with the user's currency where it says USD
+func (l Localizer) Response(body []byte) ([]byte, error) {
+	return jsonrewrite.Rewrite(body, jsonrewrite.Rewriter{
+		Number: func(key string, value float64) (float64, bool) {
+			switch {
+			case rounding.IsAmount(key):
+				return value * l.Rate, l.Rate != 1
+			case weightKeys[key]:
+				return value * metals.GramsPerTroyOunce, l.Grams
+			}
+			return 0, false
+		},
+		String: func(key, value string) (string, bool) {
+			return l.Currency, key == "currency" && value == metals.Currency
+		},
+	})
+}
+
+// Request turns the amounts and weights in a JSON request body back into
+// dollars and troy ounces
+func (l Localizer) Request(body []byte) ([]byte, error) {
+	return jsonrewrite.Rewrite(body, jsonrewrite.Rewriter{
+		Number: func(key string, value float64) (float64, bool) {
+			switch {
+			case rounding.IsAmount(key):
+				return value / l.Rate, l.Rate != 1
+			case weightKeys[key]:
+				return value / metals.GramsPerTroyOunce, l.Grams
+			}
+			return 0, false
+		},
+	})
+}
+
+// Subscribe clears a portfolio from the settings naming it as the default
+// when it's deleted, its members' included, or the user stops being a member
+func Subscribe() {
+	events.Subscribe(events.TypePortfolioUpdated, func(e events.Event) {
+		updated := e.(events.PortfolioUpdated)
+		if updated.Action != events.PortfolioDeleted {
+			return
+		}
+		if err := database.GetDB().Model(&models.UserSettings{}).
+			Where("default_portfolio_id = ?", updated.PortfolioID).
+			Update("default_portfolio_id", nil).Error; err != nil {
+			log.Printf("Failed to clear default portfolio %s: %v", updated.PortfolioID, err)
+		}
+	})
+	events.Subscribe(events.TypePortfolioMember, func(e events.Event) {
+		updated := e.(events.PortfolioMemberUpdated)
+		if updated.Action != events.MemberRemoved {
+			return
+		}
+		if err := database.GetDB().Model(&models.UserSettings{}).
+			Where("user_id = ? AND default_portfolio_id = ?", updated.Member.UserID, updated.Member.PortfolioID).
+			Update("default_portfolio_id", nil).Error; err != nil {
+			log.Printf("Failed to clear default portfolio %s: %v", updated.Member.PortfolioID, err)
+		}
+	})
+}
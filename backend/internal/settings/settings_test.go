@@ -0,0 +1,58 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLocalizerRestatesAmountsAndWeights(t *testing.T) {
+	l := Localizer{Currency: "EUR", Rate: 0.5, Grams: true}
+	body := `{"currency":"USD","face_currency":"USD","purchase_price":10,"melt_value":4,"metal_weight":2,"quantity":3,"coins":[{"gain_loss":-6}]}`
+
+	got, err := l.Response([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"currency":"EUR","face_currency":"USD","purchase_price":5,"melt_value":2,"metal_weight":62.2069536,"quantity":3,"coins":[{"gain_loss":-3}]}`
+	if string(got) != want {
+		t.Errorf("Response =\n%s\nwant\n%s", got, want)
+	}
+
+	back, err := l.Request([]byte(`{"purchase_price":5,"metal_weight":62.2069536,"quantity":3}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"purchase_price":10,"metal_weight":2,"quantity":3}`; string(back) != want {
+		t.Errorf("Request = %s, want %s", back, want)
+	}
+}
+
+func TestLocalizerOnTheDefaultsIsIdentity(t *testing.T) {
+	l, err := NewLocalizer(Defaults(uuid.Nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !l.Identity() {
+		t.Errorf("default settings localize: %+v", l)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	at := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	tests := map[string]string{
+		DateISO: "2026-03-14T09:26:53Z",
+		DateUS:  "03/14/2026 09:26",
+		DateEU:  "14/03/2026 09:26",
+		"":      "2026-03-14T09:26:53Z",
+	}
+	for format, want := range tests {
+		if got := FormatTime(at, format); got != want {
+			t.Errorf("FormatTime(%q) = %s, want %s", format, got, want)
+		}
+	}
+	if got := FormatDate(at, DateEU); got != "14/03/2026" {
+		t.Errorf("FormatDate(eu) = %s", got)
+	}
+}
@@ -0,0 +1,71 @@
+package snapshots
+
+import (
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/enrichment"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+)
+
+// Record stores a price history snapshot of a coin's current values
+func Record(coin models.Coin, recordedAt time.Time) (models.PriceHistory, error) {
+	return record(coin, 0, recordedAt) // TODO: Fetch PCGS value if cert number exists
+}
+
+// RecordAcquisition stores a new coin's first snapshot, dated when it was
+// acquired so its price chart starts at the purchase. pcgsValue is the price
+// guide value its cert was enriched with, if any.
+func RecordAcquisition(coin models.Coin, pcgsValue float64) (models.PriceHistory, error) {
+	return record(coin, pcgsValue, valuation.AcquiredAt(coin))
+}
+
+// RecordPCGS stores a snapshot of a coin's current values along with a
+// price guide value just looked up, e.g. for a coin's new cert after it was
+// regraded
+func RecordPCGS(coin models.Coin, pcgsValue float64, recordedAt time.Time) (models.PriceHistory, error) {
+	return record(coin, pcgsValue, recordedAt)
+}
+
+func record(coin models.Coin, pcgsValue float64, recordedAt time.Time) (models.PriceHistory, error) {
+	var meltValue float64
+	if calc, err := metals.CurrentCalculator(); err == nil {
+		meltValue = valuation.CoinMeltValue(coin, calc)
+	}
+
+	history := models.PriceHistory{
+		CoinID:          coin.ID,
+		MeltValue:       meltValue,
+		NumismaticValue: coin.NumismaticValue,
+		PCGSValue:       pcgsValue,
+		RecordedAt:      recordedAt,
+	}
+
+	err := database.GetDB().Create(&history).Error
+	return history, err
+}
+
+// Subscribe records an initial snapshot for every new coin so its price
+// chart starts when it was bought. Coins whose cert is still being looked
+// up get theirs once it has been, with the price guide value found.
+func Subscribe() {
+	events.Subscribe(events.TypeCoinCreated, func(e events.Event) {
+		created := e.(events.CoinCreated)
+		if created.Coin.EnrichmentStatus == enrichment.StatusPending {
+			return
+		}
+		if _, err := RecordAcquisition(created.Coin, 0); err != nil {
+			log.Printf("Failed to record initial snapshot for coin %s: %v", created.Coin.ID, err)
+		}
+	})
+	events.Subscribe(events.TypeCoinEnriched, func(e events.Event) {
+		enriched := e.(events.CoinEnriched)
+		if _, err := RecordAcquisition(enriched.Coin, enriched.PCGSValue); err != nil {
+			log.Printf("Failed to record initial snapshot for coin %s: %v", enriched.Coin.ID, err)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package spothistory
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"gorm.io/gorm"
+)
+
+// Derived metrics, alongside the metals themselves
+const (
+	MetricGoldSilverRatio   = "gold_silver_ratio"
+	MetricPlatinumGoldRatio = "platinum_gold_ratio"
+)
+
+// Retention is how long live refreshes are kept, long enough to measure a
+// month's change
+const Retention = 35 * 24 * time.Hour
+
+// CloseWindow is how long before a close the last live refresh must have
+// been to stand for the close
+const CloseWindow = time.Hour
+
+// ErrNoClosePrices is returned when no live refresh stands for a close, as
+// when the server was down over it
+var ErrNoClosePrices = errors.New("no live spot prices in the hour before the close")
+
+// Value returns a metal's price, or a ratio between two of them, from a set
+// of spot prices keyed by metal
+func Value(metric string, prices map[string]float64) (float64, bool) {
+	ratio := func(numerator, denominator string) (float64, bool) {
+		if prices[numerator] <= 0 || prices[denominator] <= 0 {
+			return 0, false
+		}
+		return prices[numerator] / prices[denominator], true
+	}
+
+	switch metric {
+	case MetricGoldSilverRatio:
+		return ratio("gold", "silver")
+	case MetricPlatinumGoldRatio:
+		return ratio("platinum", "gold")
+	}
+	value, ok := prices[metric]
+	return value, ok && value > 0
+}
+
+// Change is the percent change of a metric between two sets of prices
+func Change(metric string, from, to map[string]float64) (float64, bool) {
+	base, ok := Value(metric, from)
+	if !ok {
+		return 0, false
+	}
+	value, ok := Value(metric, to)
+	if !ok {
+		return 0, false
+	}
+	return (value - base) / base * 100, true
+}
+
+// IndicatorMetrics are the market indicators reported for the dashboard
+var IndicatorMetrics = []string{"gold", "silver", "platinum", "palladium", MetricGoldSilverRatio, MetricPlatinumGoldRatio}
+
+// Indicator is a metric's current value and its percent change over the last
+// day, week and month. A change is nil when history doesn't reach back that far.
+type Indicator struct {
+	Value       float64  `json:"value"`
+	DayChange   *float64 `json:"day_change"`
+	WeekChange  *float64 `json:"week_change"`
+	MonthChange *float64 `json:"month_change"`
+}
+
+// Indicators computes every indicator from current prices and the prices a
+// day, week and month earlier, any of which may be nil
+func Indicators(current, day, week, month map[string]float64) map[string]Indicator {
+	change := func(metric string, from map[string]float64) *float64 {
+		if from == nil {
+			return nil
+		}
+		if pct, ok := Change(metric, from, current); ok {
+			return &pct
+		}
+		return nil
+	}
+
+	indicators := make(map[string]Indicator, len(IndicatorMetrics))
+	for _, metric := range IndicatorMetrics {
+		value, ok := Value(metric, current)
+		if !ok {
+			continue
+		}
+		indicators[metric] = Indicator{
+			Value:       value,
+			DayChange:   change(metric, day),
+			WeekChange:  change(metric, week),
+			MonthChange: change(metric, month),
+		}
+	}
+	return indicators
+}
+
+// PricesAt returns the prices of the most recent refresh at or before t, or
+// nil when none was recorded
+func PricesAt(t time.Time) map[string]float64 {
+	record, err := At(t)
+	if err != nil {
+		return nil
+	}
+	return Prices(record)
+}
+
+// Prices converts a history record to the price map published with
+// SpotPricesRefreshed
+func Prices(h models.SpotPriceHistory) map[string]float64 {
+	return map[string]float64{
+		"gold":      h.Gold,
+		"silver":    h.Silver,
+		"platinum":  h.Platinum,
+		"palladium": h.Palladium,
+	}
+}
+
+// At returns the most recent refresh at or before t
+func At(t time.Time) (models.SpotPriceHistory, error) {
+	var record models.SpotPriceHistory
+	err := database.GetReadDB().Where("recorded_at <= ?", t).Order("recorded_at DESC").First(&record).Error
+	return record, err
+}
+
+// Oldest returns the earliest refresh before t
+func Oldest(t time.Time) (models.SpotPriceHistory, error) {
+	var record models.SpotPriceHistory
+	err := database.GetReadDB().Where("recorded_at < ?", t).Order("recorded_at ASC").First(&record).Error
+	return record, err
+}
+
+// Record stores a live spot price refresh and prunes refreshes older than
+// Retention
+func Record(prices map[string]float64, at time.Time) error {
+	db := database.GetDB()
+
+	record := models.SpotPriceHistory{
+		Gold:       prices["gold"],
+		Silver:     prices["silver"],
+		Platinum:   prices["platinum"],
+		Palladium:  prices["palladium"],
+		RecordedAt: at,
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return err
+	}
+	return db.Where("recorded_at < ?", at.Add(-Retention)).Delete(&models.SpotPriceHistory{}).Error
+}
+
+// CloseAt returns the prices at the close at t: the recorded close, or the
+// last live refresh before it when the close wasn't recorded. It returns nil
+// when neither is known.
+func CloseAt(t time.Time) map[string]float64 {
+	var record models.SpotPriceClose
+	if err := database.GetReadDB().Where("closed_at = ?", t).First(&record).Error; err == nil {
+		return map[string]float64{
+			"gold":      record.Gold,
+			"silver":    record.Silver,
+			"platinum":  record.Platinum,
+			"palladium": record.Palladium,
+		}
+	}
+	return PricesAt(t)
+}
+
+// RecordClose stores the close at closedAt from the last live refresh within
+// CloseWindow before it. A close already recorded is left as it is.
+func RecordClose(closedAt time.Time) error {
+	db := database.GetDB()
+
+	var existing int64
+	if err := db.Model(&models.SpotPriceClose{}).Where("closed_at = ?", closedAt).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	record, err := At(closedAt)
+	if errors.Is(err, gorm.ErrRecordNotFound) || err == nil && record.RecordedAt.Before(closedAt.Add(-CloseWindow)) {
+		return ErrNoClosePrices
+	}
+	if err != nil {
+		return err
+	}
+	return db.Create(&models.SpotPriceClose{
+		ClosedAt:   closedAt,
+		Gold:       record.Gold,
+		Silver:     record.Silver,
+		Platinum:   record.Platinum,
+		Palladium:  record.Palladium,
+		RecordedAt: record.RecordedAt,
+	}).Error
+}
+
+// Subscribe records every live spot price refresh. Fallback prices are
+// hard-coded, so they're not recorded.
+func Subscribe() {
+	events.Subscribe(events.TypeSpotPricesRefreshed, func(e events.Event) {
+		refreshed := e.(events.SpotPricesRefreshed)
+		if refreshed.Fallback {
+			return
+		}
+		at := refreshed.RefreshedAt
+		if at.IsZero() {
+			at = time.Now()
+		}
+		if err := Record(refreshed.Prices, at); err != nil {
+			log.Printf("Failed to record spot prices: %v", err)
+		}
+	})
+}
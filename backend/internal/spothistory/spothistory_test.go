@@ -0,0 +1,38 @@
+package spothistory
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValueRatios(t *testing.T) {
+	prices := map[string]float64{"gold": 2400, "silver": 30, "platinum": 960}
+
+	if ratio, ok := Value(MetricGoldSilverRatio, prices); !ok || ratio != 80 {
+		t.Errorf("gold/silver = %v, %v, want 80", ratio, ok)
+	}
+	if ratio, ok := Value(MetricPlatinumGoldRatio, prices); !ok || ratio != 0.4 {
+		t.Errorf("platinum/gold = %v, %v, want 0.4", ratio, ok)
+	}
+	if _, ok := Value(MetricGoldSilverRatio, map[string]float64{"gold": 2400}); ok {
+		t.Error("ratio without a silver price should be unavailable")
+	}
+}
+
+func TestIndicatorsWithPartialHistory(t *testing.T) {
+	current := map[string]float64{"gold": 2472, "silver": 30, "platinum": 960, "palladium": 1000}
+	day := map[string]float64{"gold": 2400, "silver": 30, "platinum": 960, "palladium": 1000}
+
+	indicators := Indicators(current, day, nil, nil)
+
+	gold := indicators["gold"]
+	if gold.Value != 2472 || gold.DayChange == nil || *gold.DayChange != 3 {
+		t.Errorf("gold = %+v, want 2472 up 3%% on the day", gold)
+	}
+	if gold.WeekChange != nil || gold.MonthChange != nil {
+		t.Error("week and month changes should be nil without history")
+	}
+	if ratio := indicators[MetricGoldSilverRatio]; math.Abs(ratio.Value-82.4) > 1e-9 || math.Abs(*ratio.DayChange-3) > 1e-9 {
+		t.Errorf("gold/silver ratio = %+v, want 82.4 up 3%%", ratio)
+	}
+}
@@ -0,0 +1,135 @@
+package statements
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/markdown"
+)
+
+var funcs = template.FuncMap{
+	"money":   money,
+	"signed":  signed,
+	"percent": func(v float64) string { return fmt.Sprintf("%+.2f%%", v) },
+	"date":    func(st *Statement) string { return st.From.Format("January 2006") },
+	// Stories are the owner's Markdown, rendered with raw HTML escaped
+	"markdown": markdown.Render,
+}
+
+func money(v float64) string {
+	return fmt.Sprintf("$%.2f", v)
+}
+
+// signed formats a change with an explicit sign, e.g. "+$12.50" or "-$3.00"
+func signed(v float64) string {
+	if v >= 0 {
+		return "+" + money(v)
+	}
+	return "-" + money(-v)
+}
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: -apple-system, Helvetica, Arial, sans-serif; color: #0f172a; max-width: 640px; margin: 0 auto;">
+  <h2 style="margin-bottom: 0;">{{.PortfolioName}}</h2>
+  <p style="color: #64748b; margin-top: 4px;">Statement for {{date .}}</p>
+
+  <table style="width: 100%; border-collapse: collapse; margin: 16px 0;">
+    <tr><td>Value at start of month</td><td align="right">{{money .StartValue}}</td></tr>
+    <tr><td>Value at end of month</td><td align="right">{{money .EndValue}}</td></tr>
+    <tr><td><strong>Change</strong></td><td align="right"><strong>{{signed .Change}} ({{percent .ChangePercent}})</strong></td></tr>
+    {{if .Acquisitions}}<tr><td>Coins added this month (cost)</td><td align="right">{{money .AcquisitionCost}}</td></tr>{{end}}
+    {{if .Disposals}}<tr><td>Coins disposed of this month (proceeds)</td><td align="right">{{money .DisposalProceeds}}</td></tr>{{end}}
+  </table>
+
+  {{if .TopMovers}}
+  <h3>Top movers</h3>
+  <table style="width: 100%; border-collapse: collapse;">
+    {{range .TopMovers}}<tr style="border-top: 1px solid #e2e8f0;">
+      <td>{{if .Year}}{{.Year}} {{end}}{{.CoinType}}</td>
+      <td align="right">{{money .EndValue}}</td>
+      <td align="right" style="color: {{if ge .Change 0.0}}#16a34a{{else}}#dc2626{{end}};">{{signed .Change}} ({{percent .ChangePercent}})</td>
+    </tr>{{end}}
+  </table>
+  {{end}}
+
+  {{if .Acquisitions}}
+  <h3>Acquisitions</h3>
+  <table style="width: 100%; border-collapse: collapse;">
+    {{range .Acquisitions}}<tr style="border-top: 1px solid #e2e8f0;">
+      <td>{{.AcquiredAt.Format "Jan 2"}}</td>
+      <td>{{.Quantity}} × {{if .Year}}{{.Year}} {{end}}{{.CoinType}}</td>
+      <td align="right">{{money .Cost}}</td>
+    </tr>{{if .Story}}<tr><td></td><td colspan="2" style="color: #334155; font-size: 14px;">{{markdown .Story}}</td></tr>{{end}}{{end}}
+  </table>
+  {{end}}
+
+  {{if .Disposals}}
+  <h3>Disposals</h3>
+  <table style="width: 100%; border-collapse: collapse;">
+    {{range .Disposals}}<tr style="border-top: 1px solid #e2e8f0;">
+      <td>{{.DisposedAt.Format "Jan 2"}}</td>
+      <td>{{.Quantity}} × {{if .Year}}{{.Year}} {{end}}{{.CoinType}} ({{.Disposition}})</td>
+      <td align="right">{{money .Proceeds}}</td>
+    </tr>{{end}}
+  </table>
+  {{end}}
+
+  <p style="color: #94a3b8; font-size: 12px; margin-top: 24px;">
+    Values are from price snapshots at the start and end of the month.
+    You can turn off monthly statements in the portfolio's settings.
+  </p>
+</body>
+</html>
+`))
+
+// RenderHTML renders a statement as an HTML email body
+func RenderHTML(st *Statement) (string, error) {
+	var b strings.Builder
+	if err := htmlTemplate.Execute(&b, st); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// RenderText renders a statement as plain text
+func RenderText(st *Statement) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: statement for %s\n\n", st.PortfolioName, st.From.Format("January 2006"))
+	fmt.Fprintf(&b, "Value at start of month: %s\n", money(st.StartValue))
+	fmt.Fprintf(&b, "Value at end of month:   %s\n", money(st.EndValue))
+	fmt.Fprintf(&b, "Change:                  %s (%+.2f%%)\n", signed(st.Change), st.ChangePercent)
+
+	if len(st.TopMovers) > 0 {
+		b.WriteString("\nTop movers:\n")
+		for _, m := range st.TopMovers {
+			fmt.Fprintf(&b, "  %s: %s (%+.2f%%)\n", coinName(m.Year, m.CoinType), signed(m.Change), m.ChangePercent)
+		}
+	}
+	if len(st.Acquisitions) > 0 {
+		fmt.Fprintf(&b, "\nAcquisitions (%s):\n", money(st.AcquisitionCost))
+		for _, a := range st.Acquisitions {
+			fmt.Fprintf(&b, "  %s  %d × %s  %s\n", a.AcquiredAt.Format("Jan 2"), a.Quantity, coinName(a.Year, a.CoinType), money(a.Cost))
+			if a.Story != "" {
+				for _, line := range strings.Split(a.Story, "\n") {
+					fmt.Fprintf(&b, "      %s\n", line)
+				}
+			}
+		}
+	}
+	if len(st.Disposals) > 0 {
+		fmt.Fprintf(&b, "\nDisposals (%s):\n", money(st.DisposalProceeds))
+		for _, d := range st.Disposals {
+			fmt.Fprintf(&b, "  %s  %d × %s (%s)  %s\n", d.DisposedAt.Format("Jan 2"), d.Quantity, coinName(d.Year, d.CoinType), d.Disposition, money(d.Proceeds))
+		}
+	}
+	return b.String()
+}
+
+func coinName(year int, coinType string) string {
+	if year == 0 {
+		return coinType
+	}
+	return fmt.Sprintf("%d %s", year, coinType)
+}
@@ -0,0 +1,295 @@
+package statements
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/archive"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/mail"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"github.com/google/uuid"
+)
+
+const topMoverCount = 5
+
+// Acquisition is a coin added to the portfolio during the statement period
+type Acquisition struct {
+	CoinID     uuid.UUID `json:"coin_id"`
+	CoinType   string    `json:"coin_type"`
+	Year       int       `json:"year"`
+	Quantity   int       `json:"quantity"`
+	Cost       float64   `json:"cost"` // all-in: purchase price × quantity plus fees
+	AcquiredAt time.Time `json:"acquired_at"`
+	Story      string    `json:"story,omitempty"` // Markdown; only when the portfolio includes stories
+}
+
+// Mover is a coin held for the whole period and how its value changed
+type Mover struct {
+	CoinID        uuid.UUID `json:"coin_id"`
+	CoinType      string    `json:"coin_type"`
+	Year          int       `json:"year"`
+	StartValue    float64   `json:"start_value"`
+	EndValue      float64   `json:"end_value"`
+	Change        float64   `json:"change"`
+	ChangePercent float64   `json:"change_percent"`
+}
+
+// Disposal is a coin sold or otherwise disposed of during the statement
+// period
+type Disposal struct {
+	CoinID      uuid.UUID `json:"coin_id"`
+	CoinType    string    `json:"coin_type"`
+	Year        int       `json:"year"`
+	Quantity    int       `json:"quantity"`
+	Disposition string    `json:"disposition"`
+	Proceeds    float64   `json:"proceeds"` // sale price less selling fees
+	DisposedAt  time.Time `json:"disposed_at"`
+}
+
+// Statement summarizes a portfolio over one calendar month
+type Statement struct {
+	PortfolioID     uuid.UUID     `json:"portfolio_id"`
+	PortfolioName   string        `json:"portfolio_name"`
+	Period          string        `json:"period"` // e.g. "2025-09"
+	From            time.Time     `json:"from"`
+	To              time.Time     `json:"to"`
+	StartValue      float64       `json:"start_value"`
+	EndValue        float64       `json:"end_value"`
+	Change          float64       `json:"change"`
+	ChangePercent   float64       `json:"change_percent"`
+	AcquisitionCost float64       `json:"acquisition_cost"`
+	CoinCount       int           `json:"coin_count"` // coins held at the end of the month
+	Acquisitions    []Acquisition `json:"acquisitions"`
+	// Coins that left the portfolio count until they were disposed of
+	DisposalProceeds float64    `json:"disposal_proceeds"`
+	Disposals        []Disposal `json:"disposals"`
+	TopMovers        []Mover    `json:"top_movers"`
+}
+
+// MonthStart returns the first instant of the month containing t, in UTC
+func MonthStart(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// snapshotValue values a price history record on the portfolio's basis, the
+// way current_value is derived
+func snapshotValue(coin models.Coin, record models.PriceHistory, basis string) float64 {
+	return valuation.Value(basis, record.MeltValue, valuation.AdjustedNumismaticValue(coin, record.NumismaticValue)) * float64(coin.Quantity)
+}
+
+// latestBefore returns each coin's most recent price history record before t
+func latestBefore(coinIDs []uuid.UUID, t time.Time) (map[uuid.UUID]models.PriceHistory, error) {
+	var records []models.PriceHistory
+	if len(coinIDs) > 0 {
+		if err := database.GetReadDB().Raw(
+			"SELECT DISTINCT ON (coin_id) * FROM price_histories WHERE coin_id IN ? AND recorded_at < ? ORDER BY coin_id, recorded_at DESC",
+			coinIDs, t,
+		).Scan(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	latest := make(map[uuid.UUID]models.PriceHistory, len(records))
+	for _, record := range records {
+		latest[record.CoinID] = record
+	}
+	return latest, nil
+}
+
+// Build summarizes a portfolio over the calendar month starting at month.
+// Values come from price history snapshots at the start and end of the month.
+func Build(portfolio models.Portfolio, month time.Time) (*Statement, error) {
+	from := MonthStart(month)
+	to := from.AddDate(0, 1, 0)
+
+	var coins []models.Coin
+	if err := database.GetReadDB().Where("portfolio_id = ? AND created_at < ?", portfolio.ID, to).Find(&coins).Error; err != nil {
+		return nil, err
+	}
+	archived, err := archive.HeldDuring(portfolio.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived coins disposed of after the month were held throughout it
+	disposedOf := map[uuid.UUID]models.ArchivedCoin{}
+	for _, a := range archived {
+		// A regraded coin's history went to its replacement, which stands
+		// in for it
+		if a.Disposition == archive.DispositionRegraded {
+			continue
+		}
+		coins = append(coins, a.Coin)
+		if a.DisposedAt.Before(to) {
+			disposedOf[a.ID] = a
+		}
+	}
+
+	coinIDs := make([]uuid.UUID, len(coins))
+	for i, coin := range coins {
+		coinIDs[i] = coin.ID
+	}
+	atStart, err := latestBefore(coinIDs, from)
+	if err != nil {
+		return nil, err
+	}
+	atEnd, err := latestBefore(coinIDs, to)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{
+		PortfolioID:   portfolio.ID,
+		PortfolioName: portfolio.Name,
+		Period:        from.Format("2006-01"),
+		From:          from,
+		To:            to,
+		CoinCount:     len(coins) - len(disposedOf),
+		Acquisitions:  []Acquisition{},
+		Disposals:     []Disposal{},
+		TopMovers:     []Mover{},
+	}
+
+	for _, coin := range coins {
+		start, hadStart := atStart[coin.ID]
+		end, hadEnd := atEnd[coin.ID]
+
+		startValue := 0.0
+		if hadStart {
+			startValue = snapshotValue(coin, start, portfolio.ValuationBasis)
+		}
+		endValue := coin.CurrentValue * float64(coin.Quantity)
+		if hadEnd {
+			endValue = snapshotValue(coin, end, portfolio.ValuationBasis)
+		}
+		disposal, disposed := disposedOf[coin.ID]
+		if disposed {
+			endValue = 0
+			d := Disposal{
+				CoinID:      coin.ID,
+				CoinType:    coin.CoinType,
+				Year:        coin.Year,
+				Quantity:    coin.Quantity,
+				Disposition: disposal.Disposition,
+				Proceeds:    disposal.SalePrice - disposal.SaleFees,
+				DisposedAt:  disposal.DisposedAt,
+			}
+			st.Disposals = append(st.Disposals, d)
+			st.DisposalProceeds += d.Proceeds
+		}
+		st.StartValue += startValue
+		st.EndValue += endValue
+
+		// Coins added during the month are listed as acquisitions rather than movers
+		if !coin.CreatedAt.Before(from) {
+			acquisition := Acquisition{
+				CoinID:     coin.ID,
+				CoinType:   coin.CoinType,
+				Year:       coin.Year,
+				Quantity:   coin.Quantity,
+				Cost:       valuation.AllInCost(coin),
+				AcquiredAt: valuation.AcquiredAt(coin),
+			}
+			if portfolio.StatementStories {
+				acquisition.Story = strings.TrimSpace(coin.Story)
+			}
+			st.Acquisitions = append(st.Acquisitions, acquisition)
+			st.AcquisitionCost += acquisition.Cost
+			continue
+		}
+
+		if hadStart && !disposed {
+			mover := Mover{
+				CoinID:     coin.ID,
+				CoinType:   coin.CoinType,
+				Year:       coin.Year,
+				StartValue: startValue,
+				EndValue:   endValue,
+				Change:     endValue - startValue,
+			}
+			if startValue > 0 {
+				mover.ChangePercent = mover.Change / startValue * 100
+			}
+			st.TopMovers = append(st.TopMovers, mover)
+		}
+	}
+
+	st.Change = st.EndValue - st.StartValue
+	if st.StartValue > 0 {
+		st.ChangePercent = st.Change / st.StartValue * 100
+	}
+
+	sort.Slice(st.Acquisitions, func(i, j int) bool { return st.Acquisitions[i].AcquiredAt.Before(st.Acquisitions[j].AcquiredAt) })
+	sort.Slice(st.Disposals, func(i, j int) bool { return st.Disposals[i].DisposedAt.Before(st.Disposals[j].DisposedAt) })
+	sort.SliceStable(st.TopMovers, func(i, j int) bool { return math.Abs(st.TopMovers[i].Change) > math.Abs(st.TopMovers[j].Change) })
+	if len(st.TopMovers) > topMoverCount {
+		st.TopMovers = st.TopMovers[:topMoverCount]
+	}
+	return st, nil
+}
+
+// Send builds the statement for month and emails it to the portfolio's owner
+func Send(portfolio models.Portfolio, month time.Time) error {
+	var user models.User
+	if err := database.GetDB().First(&user, "id = ?", portfolio.UserID).Error; err != nil {
+		return err
+	}
+
+	st, err := Build(portfolio, month)
+	if err != nil {
+		return err
+	}
+	html, err := RenderHTML(st)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Your %s statement for %s", st.From.Format("January 2006"), portfolio.Name)
+	if err := mail.SendHTML(user.Email, subject, RenderText(st), html); err != nil {
+		return err
+	}
+
+	events.Publish(events.StatementSent{
+		UserID:        user.ID,
+		PortfolioID:   portfolio.ID,
+		PortfolioName: portfolio.Name,
+		Period:        st.From.Format("January 2006"),
+	})
+	return nil
+}
+
+// SendDue emails last month's statement for every portfolio that has
+// statements enabled, isn't archived and hasn't been sent one this month
+func SendDue(now time.Time) error {
+	thisMonth := MonthStart(now)
+
+	var portfolios []models.Portfolio
+	if err := database.GetDB().
+		Where("monthly_statement = ? AND NOT archived AND created_at < ? AND (statement_sent_at IS NULL OR statement_sent_at < ?)", true, thisMonth, thisMonth).
+		Find(&portfolios).Error; err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, portfolio := range portfolios {
+		if err := Send(portfolio, thisMonth.AddDate(0, -1, 0)); err != nil {
+			log.Printf("Statement for portfolio %s failed: %v", portfolio.ID, err)
+			failed++
+			continue
+		}
+		database.GetDB().Model(&portfolio).Update("statement_sent_at", now)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d statements failed", failed, len(portfolios))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultUploadDir        = "./uploads"
+	defaultUserStorageQuota = 500 << 20 // 500MB
+)
+
+// UserQuota is the per-user storage limit in bytes from USER_STORAGE_QUOTA;
+// 0 disables it
+func UserQuota() int64 {
+	return config.Bytes("USER_STORAGE_QUOTA", defaultUserStorageQuota)
+}
+
+// LocalStorage stores uploaded files on local disk, grouped by user
+type LocalStorage struct {
+	BaseDir string
+	BaseURL string
+}
+
+// NewLocalStorage creates a storage backend from UPLOAD_DIR and UPLOAD_BASE_URL
+func NewLocalStorage() *LocalStorage {
+	port := config.String("PORT", "8080")
+	baseURL := config.String("UPLOAD_BASE_URL", fmt.Sprintf("http://localhost:%s/uploads", port))
+
+	return &LocalStorage{
+		BaseDir: config.String("UPLOAD_DIR", defaultUploadDir),
+		BaseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Save writes data under the user's directory and returns its public URL
+func (s *LocalStorage) Save(userID uuid.UUID, name string, data []byte) (string, error) {
+	dir := filepath.Join(s.BaseDir, userID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	// Only keep the base name so callers can't escape the user's directory
+	name = filepath.Base(name)
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return s.URL(userID, name), nil
+}
+
+// Delete removes a file from the user's directory. Missing files are not an error.
+func (s *LocalStorage) Delete(userID uuid.UUID, name string) error {
+	err := os.Remove(filepath.Join(s.BaseDir, userID.String(), filepath.Base(name)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteUser removes the user's directory with every file in it
+func (s *LocalStorage) DeleteUser(userID uuid.UUID) error {
+	return os.RemoveAll(filepath.Join(s.BaseDir, userID.String()))
+}
+
+// Move hands a file from one user's directory to another's, e.g. with a
+// transferred coin, and returns its new URL
+func (s *LocalStorage) Move(from, to uuid.UUID, name string) (string, error) {
+	dir := filepath.Join(s.BaseDir, to.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	name = filepath.Base(name)
+	if err := os.Rename(filepath.Join(s.BaseDir, from.String(), name), filepath.Join(dir, name)); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	return s.URL(to, name), nil
+}
+
+// FileName returns the name of the user's file a URL points at, or false
+// when it points elsewhere
+func (s *LocalStorage) FileName(userID uuid.UUID, url string) (string, bool) {
+	name, ok := strings.CutPrefix(url, s.URL(userID, ""))
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// URL returns the public URL for a stored file
+func (s *LocalStorage) URL(userID uuid.UUID, name string) string {
+	return fmt.Sprintf("%s/%s/%s", s.BaseURL, userID.String(), name)
+}
+
+// TotalUsage returns the number of bytes stored across all users
+func (s *LocalStorage) TotalUsage() (int64, error) {
+	return dirSize(s.BaseDir)
+}
+
+// UserUsage returns the number of bytes stored for a single user
+func (s *LocalStorage) UserUsage(userID uuid.UUID) (int64, error) {
+	return dirSize(filepath.Join(s.BaseDir, userID.String()))
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	return size, err
+}
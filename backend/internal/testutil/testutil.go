@@ -0,0 +1,142 @@
+//go:build integration
+
+// Package testutil runs integration tests against a real Postgres, started
+// with testcontainers unless TEST_DATABASE_URL points at one, and seeds
+// fixtures for them.
+package testutil
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/auth"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+const postgresImage = "postgres:16-alpine"
+
+// Main connects to a migrated test database, runs the package's tests and
+// tears the database down again. Call it from TestMain. Without Docker or
+// TEST_DATABASE_URL the tests are skipped rather than failed.
+func Main(m *testing.M) int {
+	// External APIs are served from fixtures and sign-in providers are
+	// faked in every integration test
+	os.Setenv("MOCK_EXTERNAL_APIS", "true")
+	os.Setenv("APP_ENV", "development")
+	os.Setenv("MOCK_OAUTH", "true")
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		var stop func()
+		var err error
+		dsn, stop, err = startPostgres(context.Background())
+		if err != nil {
+			log.Printf("Skipping integration tests, Postgres could not be started: %v", err)
+			return 0
+		}
+		defer stop()
+	}
+
+	os.Setenv("DATABASE_URL", dsn)
+	if err := database.Connect(); err != nil {
+		log.Printf("Failed to connect to the test database: %v", err)
+		return 1
+	}
+	if err := database.Migrate(); err != nil {
+		log.Printf("Failed to migrate the test database: %v", err)
+		return 1
+	}
+
+	return m.Run()
+}
+
+// startPostgres runs a throwaway Postgres container and returns its DSN and a
+// function that removes it. testcontainers panics when it can't find Docker,
+// which is reported as an error instead.
+func startPostgres(ctx context.Context) (dsn string, stop func(), err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+
+	container, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase("aureus_test"),
+		postgres.WithUsername("aureus"),
+		postgres.WithPassword("aureus"),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		return "", nil, err
+	}
+	stop = func() {
+		if err := testcontainers.TerminateContainer(container); err != nil {
+			log.Printf("Failed to stop Postgres: %v", err)
+		}
+	}
+
+	dsn, err = container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	return dsn, stop, nil
+}
+
+// SeedUser creates a user with a unique email and returns it with a bearer
+// token for it
+func SeedUser(t *testing.T) (models.User, string) {
+	t.Helper()
+
+	user := models.User{
+		Email:    fmt.Sprintf("test-%s@example.com", uuid.NewString()[:8]),
+		Password: "not-a-real-hash",
+	}
+	if err := database.GetDB().Create(&user).Error; err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+
+	token, _, err := auth.GenerateToken(user.ID, user.Email, nil, user.TokenVersion)
+	if err != nil {
+		t.Fatalf("token for seeded user: %v", err)
+	}
+	return user, token
+}
+
+// SeedPortfolio creates a portfolio owned by userID
+func SeedPortfolio(t *testing.T, userID uuid.UUID, name string) models.Portfolio {
+	t.Helper()
+
+	portfolio := models.Portfolio{UserID: userID, Name: name}
+	if err := database.GetDB().Create(&portfolio).Error; err != nil {
+		t.Fatalf("seed portfolio: %v", err)
+	}
+	return portfolio
+}
+
+// SeedCoin adds coin to a portfolio, defaulting its quantity to 1 and its
+// purchase date to a year ago
+func SeedCoin(t *testing.T, portfolioID uuid.UUID, coin models.Coin) models.Coin {
+	t.Helper()
+
+	coin.PortfolioID = portfolioID
+	if coin.Quantity == 0 {
+		coin.Quantity = 1
+	}
+	if coin.PurchaseDate == nil {
+		purchased := time.Now().AddDate(-1, 0, 0)
+		coin.PurchaseDate = &purchased
+	}
+	if err := database.GetDB().Create(&coin).Error; err != nil {
+		t.Fatalf("seed coin: %v", err)
+	}
+	return coin
+}
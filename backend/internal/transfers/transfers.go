@@ -0,0 +1,181 @@
+// Package transfers moves coins between users of an instance once the
+// recipient accepts, along with as much of the coin's record as the sender
+// chose to hand over
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/evansminotwood/aureus/internal/storage"
+	"github.com/evansminotwood/aureus/internal/valuation"
+	"gorm.io/gorm"
+)
+
+// Transfer statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDeclined  = "declined"
+	StatusCancelled = "cancelled"
+)
+
+// ErrCoinGone is returned when the sender deleted or moved the coin on while
+// the transfer was pending
+var ErrCoinGone = errors.New("coin is no longer available")
+
+// Label names a coin for messages, e.g. "1921-S Morgan Dollar"
+func Label(coin models.Coin) string {
+	label := coin.CoinType
+	if coin.Year > 0 {
+		year := fmt.Sprint(coin.Year)
+		if coin.MintMark != "" {
+			year += "-" + coin.MintMark
+		}
+		label = year + " " + label
+	}
+	return strings.TrimSpace(label)
+}
+
+// StripCostBasis clears what a coin cost its previous owner, for gifts whose
+// recipient shouldn't see it. The purchase date becomes the transfer date.
+func StripCostBasis(coin *models.Coin, at time.Time) {
+	coin.PurchasePrice = 0
+	coin.BuyersPremium = 0
+	coin.ShippingCost = 0
+	coin.SalesTax = 0
+	coin.PurchaseDate = &at
+}
+
+// Complete moves the transfer's coin into the recipient's portfolio and
+// marks the transfer accepted. The coin leaves the sender's lot, is revalued
+// on the portfolio's basis, and loses its cost basis, price history or images
+// when the sender left them out. Stored images move to the recipient's
+// storage.
+func Complete(transfer *models.CoinTransfer, portfolio models.Portfolio) (models.Coin, error) {
+	db := database.GetDB()
+
+	var coin models.Coin
+	err := db.Where("id = ? AND portfolio_id IN (?)", transfer.CoinID,
+		db.Model(&models.Portfolio{}).Select("id").Where("user_id = ?", transfer.FromUserID)).First(&coin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return coin, ErrCoinGone
+	}
+	if err != nil {
+		return coin, err
+	}
+
+	var images []models.CoinImage
+	if err := db.Where("coin_id = ?", coin.ID).Find(&images).Error; err != nil {
+		return coin, err
+	}
+
+	now := time.Now()
+	store := storage.NewLocalStorage()
+	moved := map[string]string{} // file name -> new URL
+	var dropped []string
+	// rewrite replaces every image URL of the coin
+	rewrite := func(replace func(url string) string) {
+		coin.ImageURL = replace(coin.ImageURL)
+		coin.ThumbnailURL = replace(coin.ThumbnailURL)
+		for i := range images {
+			images[i].URL = replace(images[i].URL)
+		}
+	}
+
+	if transfer.IncludeImages {
+		var moveErr error
+		rewrite(func(url string) string {
+			name, ok := store.FileName(transfer.FromUserID, url)
+			if !ok || moveErr != nil {
+				return url
+			}
+			if movedURL, done := moved[name]; done {
+				return movedURL
+			}
+			movedURL, err := store.Move(transfer.FromUserID, transfer.ToUserID, name)
+			if err != nil {
+				moveErr = err
+				return url
+			}
+			moved[name] = movedURL
+			return movedURL
+		})
+		if moveErr != nil {
+			moveBack(store, transfer, moved)
+			return coin, moveErr
+		}
+	} else {
+		rewrite(func(url string) string {
+			if name, ok := store.FileName(transfer.FromUserID, url); ok {
+				dropped = append(dropped, name)
+			}
+			return ""
+		})
+	}
+
+	if !transfer.KeepCostBasis {
+		StripCostBasis(&coin, now)
+	}
+	coin.LotID = nil
+	coin.PortfolioID = portfolio.ID
+	// The sender's alerts on the coin don't come with it
+	coin.Watched = false
+	valuation.ApplyBasis(&coin, portfolio.ValuationBasis)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if !transfer.IncludeHistory {
+			if err := tx.Where("coin_id = ?", coin.ID).Delete(&models.PriceHistory{}).Error; err != nil {
+				return err
+			}
+		}
+		if !transfer.IncludeImages {
+			if err := tx.Where("coin_id = ?", coin.ID).Delete(&models.CoinImage{}).Error; err != nil {
+				return err
+			}
+		} else {
+			for i := range images {
+				if err := tx.Save(&images[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Where("coin_id = ?", coin.ID).Delete(&models.CoinAlert{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&coin).Error; err != nil {
+			return err
+		}
+
+		portfolioID := portfolio.ID
+		transfer.Status = StatusAccepted
+		transfer.ToPortfolioID = &portfolioID
+		transfer.RespondedAt = &now
+		return tx.Save(transfer).Error
+	})
+	if err != nil {
+		moveBack(store, transfer, moved)
+		return coin, err
+	}
+
+	for _, name := range dropped {
+		if err := store.Delete(transfer.FromUserID, name); err != nil {
+			log.Printf("Failed to delete image %s of transferred coin %s: %v", name, coin.ID, err)
+		}
+	}
+	return coin, nil
+}
+
+// moveBack returns images to the sender after a failed transfer
+func moveBack(store *storage.LocalStorage, transfer *models.CoinTransfer, moved map[string]string) {
+	for name := range moved {
+		if _, err := store.Move(transfer.ToUserID, transfer.FromUserID, name); err != nil {
+			log.Printf("Failed to move image %s back to user %s: %v", name, transfer.FromUserID, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package transfers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestLabel(t *testing.T) {
+	tests := []struct {
+		coin models.Coin
+		want string
+	}{
+		{models.Coin{CoinType: "Morgan Dollar", Year: 1921, MintMark: "S"}, "1921-S Morgan Dollar"},
+		{models.Coin{CoinType: "Morgan Dollar", Year: 1921}, "1921 Morgan Dollar"},
+		{models.Coin{CoinType: "American Silver Eagle"}, "American Silver Eagle"},
+	}
+	for _, tt := range tests {
+		if got := Label(tt.coin); got != tt.want {
+			t.Errorf("Label(%+v) = %q, want %q", tt.coin, got, tt.want)
+		}
+	}
+}
+
+func TestStripCostBasis(t *testing.T) {
+	bought := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	coin := models.Coin{PurchasePrice: 40, BuyersPremium: 8, ShippingCost: 5, SalesTax: 3, PurchaseDate: &bought, NumismaticValue: 90}
+	now := time.Now()
+	StripCostBasis(&coin, now)
+
+	if coin.PurchasePrice != 0 || coin.BuyersPremium != 0 || coin.ShippingCost != 0 || coin.SalesTax != 0 {
+		t.Errorf("cost basis left after stripping: %+v", coin)
+	}
+	if coin.PurchaseDate == nil || !coin.PurchaseDate.Equal(now) {
+		t.Errorf("purchase date = %v, want the transfer date", coin.PurchaseDate)
+	}
+	if coin.NumismaticValue != 90 {
+		t.Errorf("numismatic value = %.2f, want it kept", coin.NumismaticValue)
+	}
+}
@@ -0,0 +1,163 @@
+// Package trash keeps deleted portfolios and coins for a while before they
+// go for good, so a deletion made by mistake can be undone. Deleting a
+// portfolio trashes its coins at the same moment, and restoring it brings
+// back exactly those coins; coins deleted on their own before stay in the
+// trash. Nothing is purged until its retention runs out or the owner
+// empties it, and only purging publishes the deleted events whose
+// subscribers clean up members, alerts, tokens and images.
+package trash
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultRetention = 30 * 24 * time.Hour
+
+// ErrPortfolioTrashed is returned when restoring a coin whose portfolio is
+// in the trash too
+var ErrPortfolioTrashed = errors.New("the coin's portfolio is in the trash, restore the portfolio first")
+
+// Retention is how long deleted portfolios and coins stay in the trash
+// (TRASH_RETENTION, default 30 days)
+func Retention() time.Duration {
+	return config.Duration("TRASH_RETENTION", defaultRetention)
+}
+
+// PurgeAt returns when something trashed at deletedAt will be purged
+func PurgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(Retention())
+}
+
+// TrashPortfolio moves a portfolio and the coins it holds to the trash
+func TrashPortfolio(portfolio models.Portfolio, at time.Time) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Coin{}).Where("portfolio_id = ?", portfolio.ID).
+			UpdateColumn("deleted_at", at).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Portfolio{}).Where("id = ?", portfolio.ID).
+			UpdateColumn("deleted_at", at).Error
+	})
+}
+
+// RestorePortfolio takes a portfolio out of the trash with the coins that
+// were trashed with it
+func RestorePortfolio(portfolio models.Portfolio) error {
+	return database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Coin{}).
+			Where("portfolio_id = ? AND deleted_at = ?", portfolio.ID, portfolio.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.Portfolio{}).Where("id = ?", portfolio.ID).
+			UpdateColumn("deleted_at", nil).Error
+	})
+}
+
+// RestoreCoin takes a coin out of the trash, unless its portfolio is in
+// the trash too
+func RestoreCoin(coin models.Coin) error {
+	var live int64
+	if err := database.GetDB().Model(&models.Portfolio{}).Where("id = ?", coin.PortfolioID).Count(&live).Error; err != nil {
+		return err
+	}
+	if live == 0 {
+		return ErrPortfolioTrashed
+	}
+	return database.GetDB().Unscoped().Model(&models.Coin{}).Where("id = ?", coin.ID).
+		UpdateColumn("deleted_at", nil).Error
+}
+
+// PurgePortfolio deletes a trashed portfolio for good, with every coin in
+// it and their price history
+func PurgePortfolio(portfolio models.Portfolio) error {
+	var coins []models.Coin
+	if err := database.GetDB().Unscoped().Where("portfolio_id = ?", portfolio.ID).Find(&coins).Error; err != nil {
+		return err
+	}
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		coinIDs := tx.Unscoped().Model(&models.Coin{}).Select("id").Where("portfolio_id = ?", portfolio.ID)
+		if err := tx.Where("coin_id IN (?)", coinIDs).Delete(&models.PriceHistory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("portfolio_id = ?", portfolio.ID).Delete(&models.Coin{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Portfolio{}, "id = ?", portfolio.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, coin := range coins {
+		events.Publish(events.CoinDeleted{UserID: portfolio.UserID, Coin: coin})
+	}
+	events.Publish(events.PortfolioUpdated{
+		UserID:      portfolio.UserID,
+		PortfolioID: portfolio.ID,
+		Action:      events.PortfolioDeleted,
+	})
+	return nil
+}
+
+// PurgeCoin deletes a trashed coin for good, with its price history.
+// ownerID is the user who owns its portfolio.
+func PurgeCoin(coin models.Coin, ownerID uuid.UUID) error {
+	err := database.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("coin_id = ?", coin.ID).Delete(&models.PriceHistory{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.Coin{}, "id = ?", coin.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	events.Publish(events.CoinDeleted{UserID: ownerID, Coin: coin})
+	return nil
+}
+
+// Purge deletes the portfolios and coins that have been in the trash
+// longer than the retention
+func Purge(now time.Time) error {
+	cutoff := now.Add(-Retention())
+
+	var portfolios []models.Portfolio
+	if err := database.GetDB().Unscoped().Where("deleted_at < ?", cutoff).Find(&portfolios).Error; err != nil {
+		return err
+	}
+	for _, portfolio := range portfolios {
+		if err := PurgePortfolio(portfolio); err != nil {
+			return err
+		}
+	}
+
+	var coins []struct {
+		models.Coin `gorm:"embedded"`
+		OwnerID     uuid.UUID
+	}
+	if err := database.GetDB().Unscoped().Table("coins").Select("coins.*, portfolios.user_id AS owner_id").
+		Joins("JOIN portfolios ON coins.portfolio_id = portfolios.id").
+		Where("coins.deleted_at < ?", cutoff).
+		Scan(&coins).Error; err != nil {
+		return err
+	}
+	for _, coin := range coins {
+		if err := PurgeCoin(coin.Coin, coin.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	if len(portfolios) > 0 || len(coins) > 0 {
+		log.Printf("Purged %d portfolios and %d coins from the trash", len(portfolios), len(coins))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+)
+
+// metric is one Prometheus metric family, valued per service
+type metric struct {
+	name, kind, help string
+	value            func(u ServiceUsage) (float64, bool)
+}
+
+var metrics = []metric{
+	{"aureus_external_api_calls_total", "counter", "Calls to an external service since startup.",
+		func(u ServiceUsage) (float64, bool) { return float64(u.TotalCalls), true }},
+	{"aureus_external_api_calls_today", "gauge", "Calls to an external service in the current UTC day.",
+		func(u ServiceUsage) (float64, bool) { return float64(u.CallsToday), true }},
+	{"aureus_external_api_errors_today", "gauge", "Failed calls to an external service in the current UTC day.",
+		func(u ServiceUsage) (float64, bool) { return float64(u.ErrorsToday), true }},
+	{"aureus_external_api_daily_quota", "gauge", "Daily call quota of an external service.",
+		func(u ServiceUsage) (float64, bool) { return float64(u.DailyQuota), u.DailyQuota > 0 }},
+	{"aureus_external_api_quota_remaining", "gauge", "Calls left in today's quota.",
+		func(u ServiceUsage) (float64, bool) {
+			if u.QuotaRemaining == nil {
+				return 0, false
+			}
+			return float64(*u.QuotaRemaining), true
+		}},
+	{"aureus_external_api_quota_used_ratio", "gauge", "Share of today's quota used, from 0 to 1.",
+		func(u ServiceUsage) (float64, bool) { return u.QuotaUsedPct / 100, u.DailyQuota > 0 }},
+	{"aureus_external_api_projected_calls_today", "gauge", "Calls the UTC day will end on at today's rate so far.",
+		func(u ServiceUsage) (float64, bool) { return float64(u.ProjectedCalls), u.DailyQuota > 0 }},
+	{"aureus_external_api_throttled", "gauge", "1 while background jobs are holding off a service to save its quota.",
+		func(u ServiceUsage) (float64, bool) {
+			if u.Throttled {
+				return 1, u.DailyQuota > 0
+			}
+			return 0, u.DailyQuota > 0
+		}},
+}
+
+// WritePrometheus writes external API usage in the Prometheus text
+// exposition format
+func WritePrometheus(w io.Writer) error {
+	snapshot := Snapshot()
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind); err != nil {
+			return err
+		}
+		for _, u := range snapshot {
+			if value, ok := m.value(u); ok {
+				if _, err := fmt.Fprintf(w, "%s{service=%q} %g\n", m.name, u.Service, value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "# HELP aureus_external_api_throttle_ratio Share of a daily quota after which background jobs stop calling a service.\n"+
+		"# TYPE aureus_external_api_throttle_ratio gauge\naureus_external_api_throttle_ratio %g\n", ThrottlePercent()/100)
+	return err
+}
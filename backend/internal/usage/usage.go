@@ -0,0 +1,202 @@
+package usage
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+)
+
+// External services whose calls are counted
+const (
+	ServicePCGS             = "pcgs"
+	ServicePCGSUserKeys     = "pcgs-user-keys"
+	ServiceGoldPrice        = "goldprice.org"
+	ServiceMetalsLive       = "metals.live"
+	ServiceBaseMetals       = "base-metals"
+	ServiceImageService     = "image-service"
+	ServiceFRED             = "fred"
+	ServiceFXRates          = "fx-rates"
+	ServiceHIBP             = "haveibeenpwned"
+	ServiceTwilio           = "twilio"
+	ServiceTelegram         = "telegram"
+	ServiceWebPush          = "web-push"
+	ServiceHeritage         = "heritage"
+	ServiceGreatCollections = "greatcollections"
+	ServiceSendGrid         = "sendgrid"
+)
+
+// defaultQuotas are the documented daily call limits; 0 means unlimited
+var defaultQuotas = map[string]int64{
+	ServicePCGS: 1000,
+}
+
+// Share of a daily quota at which a warning is logged, once per level per day
+var warnLevels = []float64{80, 95, 100}
+
+// ServiceUsage reports calls made to an external service in the current UTC day
+type ServiceUsage struct {
+	Service      string  `json:"service"`
+	CallsToday   int64   `json:"calls_today"`
+	ErrorsToday  int64   `json:"errors_today"`
+	TotalCalls   int64   `json:"total_calls"`
+	DailyQuota   int64   `json:"daily_quota,omitempty"`
+	QuotaUsedPct float64 `json:"quota_used_percent,omitempty"`
+	// Calls left today, and how many calls the day will end on at the rate
+	// so far. Background jobs stop using a throttled service until midnight.
+	QuotaRemaining *int64 `json:"quota_remaining,omitempty"`
+	ProjectedCalls int64  `json:"projected_calls_today,omitempty"`
+	Throttled      bool   `json:"throttled,omitempty"`
+}
+
+type counter struct {
+	day         string
+	callsToday  int64
+	errorsToday int64
+	totalCalls  int64
+	warnedLevel float64 // highest warnLevels entry logged today
+}
+
+var (
+	mu       sync.Mutex
+	counters = make(map[string]*counter)
+)
+
+func today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// RecordCall counts one call to an external service. Counters are kept in
+// memory and reset at UTC midnight, matching how upstream quotas reset.
+func RecordCall(service string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := counters[service]
+	if !ok {
+		c = &counter{}
+		counters[service] = c
+	}
+
+	day := today()
+	if c.day != day {
+		c.day = day
+		c.callsToday = 0
+		c.errorsToday = 0
+		c.warnedLevel = 0
+	}
+
+	c.callsToday++
+	c.totalCalls++
+	if err != nil {
+		c.errorsToday++
+	}
+
+	if quota := Quota(service); quota > 0 {
+		used := float64(c.callsToday) / float64(quota) * 100
+		for _, level := range warnLevels {
+			if used >= level && level > c.warnedLevel {
+				c.warnedLevel = level
+				log.Printf("⚠ %s has used %d of its %d daily calls (%.0f%%)", service, c.callsToday, quota, used)
+			}
+		}
+	}
+}
+
+// Quota returns the daily quota for a service, overridable with
+// <SERVICE>_DAILY_QUOTA (e.g. PCGS_DAILY_QUOTA)
+func Quota(service string) int64 {
+	envKey := map[string]string{
+		ServicePCGS: "PCGS_DAILY_QUOTA",
+	}[service]
+
+	if envKey != "" {
+		if value := config.Int64(envKey, -1); value >= 0 {
+			return value
+		}
+	}
+	return defaultQuotas[service]
+}
+
+// ThrottlePercent is the share of a daily quota after which background jobs
+// stop calling a service (QUOTA_THROTTLE_PERCENT, default 90), leaving the
+// rest for requests users are waiting on
+func ThrottlePercent() float64 {
+	pct := float64(config.Int64("QUOTA_THROTTLE_PERCENT", 90))
+	if pct <= 0 || pct > 100 {
+		return 90
+	}
+	return pct
+}
+
+// Throttled reports whether background jobs should stop calling service for
+// the rest of the UTC day
+func Throttled(service string) bool {
+	quota := Quota(service)
+	if quota <= 0 {
+		return false
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	c, ok := counters[service]
+	if !ok || c.day != today() {
+		return false
+	}
+	return float64(c.callsToday) >= float64(quota)*ThrottlePercent()/100
+}
+
+// projectCalls extrapolates calls made so far today to the whole UTC day
+func projectCalls(calls int64, now time.Time) int64 {
+	now = now.UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	elapsed := now.Sub(midnight)
+	// Too early in the day to extrapolate from
+	if elapsed < time.Hour {
+		elapsed = time.Hour
+	}
+	return int64(float64(calls) * float64(24*time.Hour) / float64(elapsed))
+}
+
+// Snapshot returns usage for every service called since startup, plus any
+// service with a quota so it's visible before the first call
+func Snapshot() []ServiceUsage {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	day := today()
+	throttleAt := ThrottlePercent()
+	services := make(map[string]bool)
+	for service := range counters {
+		services[service] = true
+	}
+	for service := range defaultQuotas {
+		services[service] = true
+	}
+
+	result := make([]ServiceUsage, 0, len(services))
+	for service := range services {
+		u := ServiceUsage{Service: service, DailyQuota: Quota(service)}
+		if c, ok := counters[service]; ok {
+			u.TotalCalls = c.totalCalls
+			if c.day == day {
+				u.CallsToday = c.callsToday
+				u.ErrorsToday = c.errorsToday
+			}
+		}
+		if u.DailyQuota > 0 {
+			u.QuotaUsedPct = float64(u.CallsToday) / float64(u.DailyQuota) * 100
+			remaining := max(u.DailyQuota-u.CallsToday, 0)
+			u.QuotaRemaining = &remaining
+			u.ProjectedCalls = projectCalls(u.CallsToday, now)
+			u.Throttled = u.QuotaUsedPct >= throttleAt
+		}
+		result = append(result, u)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Service < result[j].Service })
+	return result
+}
@@ -0,0 +1,56 @@
+package usage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProjectCalls(t *testing.T) {
+	noon := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if got := projectCalls(300, noon); got != 600 {
+		t.Errorf("300 calls by noon projects to %d, want 600", got)
+	}
+	// Early in the day the first hour stands in for the elapsed time
+	if got := projectCalls(10, noon.Add(-11*time.Hour-50*time.Minute)); got != 240 {
+		t.Errorf("10 calls in the first minutes projects to %d, want 240", got)
+	}
+}
+
+func TestThrottledAndMetrics(t *testing.T) {
+	t.Setenv("PCGS_DAILY_QUOTA", "10")
+	t.Setenv("QUOTA_THROTTLE_PERCENT", "80")
+	mu.Lock()
+	delete(counters, ServicePCGS)
+	mu.Unlock()
+
+	for i := 0; i < 7; i++ {
+		RecordCall(ServicePCGS, nil)
+	}
+	if Throttled(ServicePCGS) {
+		t.Error("7 of 10 calls should not be throttled at 80%")
+	}
+	RecordCall(ServicePCGS, nil)
+	if !Throttled(ServicePCGS) {
+		t.Error("8 of 10 calls should be throttled at 80%")
+	}
+	if Throttled(ServicePCGSUserKeys) {
+		t.Error("services without a quota are never throttled")
+	}
+
+	var out bytes.Buffer
+	if err := WritePrometheus(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`aureus_external_api_calls_today{service="pcgs"} 8`,
+		`aureus_external_api_quota_remaining{service="pcgs"} 2`,
+		`aureus_external_api_throttled{service="pcgs"} 1`,
+		`aureus_external_api_throttle_ratio 0.8`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("metrics missing %q:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package valuation
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+// Problems a raw coin can have, in display order
+var Problems = []string{"cleaned", "scratched", "holed", "bent", "corroded", "rim_damage", "environmental_damage", "repaired"}
+
+// Eye appeal levels, worst to best
+var EyeAppeals = []string{"poor", "below_average", "average", "above_average", "exceptional"}
+
+const (
+	maxToningDescriptors      = 10
+	maxToningDescriptorLength = 32
+)
+
+// defaultHaircuts are the percentages taken off a raw coin's numismatic
+// value, keyed by problem or "eye_appeal_<level>"
+var defaultHaircuts = map[string]float64{
+	"cleaned":                  30,
+	"scratched":                15,
+	"holed":                    60,
+	"bent":                     40,
+	"corroded":                 50,
+	"rim_damage":               20,
+	"environmental_damage":     35,
+	"repaired":                 40,
+	"eye_appeal_poor":          15,
+	"eye_appeal_below_average": 5,
+}
+
+var (
+	haircutsOnce sync.Once
+	haircuts     map[string]float64
+)
+
+// Haircuts returns the condition haircuts in percent. CONDITION_HAIRCUTS
+// overrides individual defaults, e.g. "cleaned=25,holed=70,eye_appeal_poor=0".
+func Haircuts() map[string]float64 {
+	haircutsOnce.Do(func() {
+		haircuts = parseHaircuts(config.String("CONDITION_HAIRCUTS", ""))
+	})
+	return haircuts
+}
+
+func parseHaircuts(spec string) map[string]float64 {
+	parsed := make(map[string]float64, len(defaultHaircuts))
+	for key, pct := range defaultHaircuts {
+		parsed[key] = pct
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		pct, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if _, known := defaultHaircuts[key]; !ok || !known || err != nil || pct < 0 || pct > 100 {
+			log.Printf("⚠ Ignoring CONDITION_HAIRCUTS entry %q", entry)
+			continue
+		}
+		parsed[key] = pct
+	}
+	return parsed
+}
+
+// ConditionFactor is the share of its problem-free numismatic value a coin is
+// worth given its problems and eye appeal. Haircuts compound, so a cleaned and
+// scratched coin keeps 70% × 85% by default. Graded coins are always 1: their
+// grade (or details grade) already prices in their condition.
+func ConditionFactor(coin models.Coin) float64 {
+	return conditionFactor(coin, Haircuts())
+}
+
+func conditionFactor(coin models.Coin, cuts map[string]float64) float64 {
+	if coin.PCGSCertNumber != "" {
+		return 1
+	}
+	factor := 1.0
+	for _, problem := range coin.Problems {
+		factor *= 1 - cuts[problem]/100
+	}
+	if coin.EyeAppeal != "" {
+		factor *= 1 - cuts["eye_appeal_"+coin.EyeAppeal]/100
+	}
+	return factor
+}
+
+// AdjustedNumismaticValue applies a coin's condition haircut to a numismatic
+// value, such as its stored numismatic_value or one from a price snapshot
+func AdjustedNumismaticValue(coin models.Coin, numismaticValue float64) float64 {
+	return numismaticValue * ConditionFactor(coin)
+}
+
+// NormalizeCondition validates a coin's condition fields and normalizes them
+// in place: problems are deduplicated into display order, and toning
+// descriptors are lowercased and deduplicated
+func NormalizeCondition(coin *models.Coin) error {
+	if len(coin.Problems) > 0 {
+		given := make(map[string]bool, len(coin.Problems))
+		for _, problem := range coin.Problems {
+			problem = strings.ToLower(strings.TrimSpace(problem))
+			if !slices.Contains(Problems, problem) {
+				return fmt.Errorf("unknown problem %q, must be one of %v", problem, Problems)
+			}
+			given[problem] = true
+		}
+		coin.Problems = coin.Problems[:0]
+		for _, problem := range Problems {
+			if given[problem] {
+				coin.Problems = append(coin.Problems, problem)
+			}
+		}
+	}
+
+	coin.EyeAppeal = strings.ToLower(strings.TrimSpace(coin.EyeAppeal))
+	if coin.EyeAppeal != "" && !slices.Contains(EyeAppeals, coin.EyeAppeal) {
+		return fmt.Errorf("eye_appeal must be one of %v", EyeAppeals)
+	}
+
+	if len(coin.Toning) > 0 {
+		toning := make([]string, 0, len(coin.Toning))
+		for _, descriptor := range coin.Toning {
+			descriptor = strings.ToLower(strings.TrimSpace(descriptor))
+			if descriptor == "" || slices.Contains(toning, descriptor) {
+				continue
+			}
+			if len(descriptor) > maxToningDescriptorLength {
+				return fmt.Errorf("toning descriptors must be at most %d characters", maxToningDescriptorLength)
+			}
+			toning = append(toning, descriptor)
+		}
+		if len(toning) > maxToningDescriptors {
+			return fmt.Errorf("at most %d toning descriptors are allowed", maxToningDescriptors)
+		}
+		coin.Toning = toning
+	}
+	return nil
+}
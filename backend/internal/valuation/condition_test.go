@@ -0,0 +1,43 @@
+package valuation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestConditionFactorCompounds(t *testing.T) {
+	cuts := parseHaircuts("scratched=20, holed=oops, unknown=5")
+	if cuts["scratched"] != 20 || cuts["holed"] != defaultHaircuts["holed"] {
+		t.Fatalf("parseHaircuts overrides = %v", cuts)
+	}
+
+	raw := models.Coin{Problems: []string{"cleaned", "scratched"}, EyeAppeal: "poor"}
+	if got, want := conditionFactor(raw, cuts), 0.7*0.8*0.85; math.Abs(got-want) > 1e-9 {
+		t.Errorf("raw coin factor = %v, want %v", got, want)
+	}
+
+	graded := raw
+	graded.PCGSCertNumber = "12345678"
+	if got := conditionFactor(graded, cuts); got != 1 {
+		t.Errorf("graded coin factor = %v, want 1", got)
+	}
+}
+
+func TestNormalizeCondition(t *testing.T) {
+	coin := models.Coin{Problems: []string{"Holed", "cleaned", "holed"}, EyeAppeal: " Average ", Toning: []string{"Rainbow", "rainbow", " "}}
+	if err := NormalizeCondition(&coin); err != nil {
+		t.Fatal(err)
+	}
+	if len(coin.Problems) != 2 || coin.Problems[0] != "cleaned" || coin.Problems[1] != "holed" {
+		t.Errorf("problems = %v, want [cleaned holed]", coin.Problems)
+	}
+	if coin.EyeAppeal != "average" || len(coin.Toning) != 1 || coin.Toning[0] != "rainbow" {
+		t.Errorf("eye appeal %q, toning %v", coin.EyeAppeal, coin.Toning)
+	}
+
+	if err := NormalizeCondition(&models.Coin{Problems: []string{"dented"}}); err == nil {
+		t.Error("unknown problem should be rejected")
+	}
+}
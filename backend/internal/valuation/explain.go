@@ -0,0 +1,179 @@
+package valuation
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// Sources of a coin's metal content
+const (
+	MetalSourceCatalog = "catalog" // stored metal fields match the composition catalog
+	MetalSourceManual  = "manual"  // stored metal fields differ from the catalog
+	MetalSourceNone    = "none"    // no metal content known
+)
+
+// SpotPriceUsed is the spot price a melt value was calculated with
+type SpotPriceUsed struct {
+	Metal     string    `json:"metal"`
+	Price     float64   `json:"price"`
+	Unit      string    `json:"unit"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Explanation describes how a coin's current_value was derived
+type Explanation struct {
+	CoinID              uuid.UUID                `json:"coin_id"`
+	CoinType            string                   `json:"coin_type"`
+	Year                int                      `json:"year"`
+	StrikeType          string                   `json:"strike_type"`
+	Quantity            int                      `json:"quantity"`
+	CurrentValue        float64                  `json:"current_value"`
+	LastPriceUpdate     *time.Time               `json:"last_price_update"`
+	Composition         *metals.CompositionMatch `json:"composition"`
+	MetalSource         string                   `json:"metal_source"`
+	MetalType           string                   `json:"metal_type"`
+	MetalWeight         float64                  `json:"metal_weight"`
+	MetalPurity         float64                  `json:"metal_purity"`
+	SpotPrices          []SpotPriceUsed          `json:"spot_prices"`
+	Calculation         string                   `json:"calculation"`
+	CalculatedMeltValue float64                  `json:"calculated_melt_value"`
+	PCGSCertNumber      string                   `json:"pcgs_cert_number,omitempty"`
+	PCGSGuideValue      float64                  `json:"pcgs_guide_value,omitempty"`
+	ConditionFactor     float64                  `json:"condition_factor"` // share of the numismatic value kept after condition haircuts
+	ValuationBasis      string                   `json:"valuation_basis"`
+	BasisValue          float64                  `json:"basis_value"` // current_value expected on the basis at today's prices
+	Overridden          bool                     `json:"overridden"`
+	Notes               []string                 `json:"notes"`
+}
+
+// valueTolerance absorbs rounding when comparing stored and recalculated values
+const valueTolerance = 0.01
+
+// Explain reconstructs how a coin's current value was calculated on its
+// portfolio's basis: which catalog composition its type resolved to, the spot
+// prices and purity math used, and whether the stored value has since been
+// overridden or gone stale
+func Explain(coin models.Coin, calc *metals.Calculator, basis string) Explanation {
+	exp := Explanation{
+		CoinID:          coin.ID,
+		CoinType:        coin.CoinType,
+		Year:            coin.Year,
+		StrikeType:      coin.StrikeType,
+		Quantity:        coin.Quantity,
+		CurrentValue:    coin.CurrentValue,
+		LastPriceUpdate: coin.LastPriceUpdate,
+		MetalSource:     MetalSourceNone,
+		MetalType:       coin.MetalType,
+		MetalWeight:     coin.MetalWeight,
+		MetalPurity:     coin.MetalPurity,
+		PCGSCertNumber:  coin.PCGSCertNumber,
+		ValuationBasis:  basis,
+		Notes:           []string{},
+	}
+
+	match, matched := metals.MatchStrikeComposition(coin.CoinType, coin.Year, coin.StrikeType)
+	if matched {
+		exp.Composition = &match
+		switch match.Method {
+		case metals.MatchNormalized:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("Coin type %q was matched to %q after removing the year and grade", coin.CoinType, match.MatchedName))
+		case metals.MatchStrike:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("The %s strike of %s has its own composition", strings.ReplaceAll(coin.StrikeType, "_", " "), match.MatchedName))
+		case metals.MatchAlias:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("Coin type %q is a known nickname for %q", coin.CoinType, match.MatchedName))
+		case metals.MatchYearDefault:
+			exp.Notes = append(exp.Notes, fmt.Sprintf("Year %d is not in any special composition range for %s, so its standard composition was used", coin.Year, match.MatchedName))
+		}
+	} else {
+		exp.Notes = append(exp.Notes, "Coin type was not found in the composition catalog")
+	}
+
+	comp := match.Composition
+	switch {
+	case matched && comp.IsBaseMetal && (coin.MetalType == "" || coin.MetalType == comp.MetalType) && coin.MetalWeight == 0:
+		// Base metal coins store no troy ounce weight; their value comes from the catalog's gram weight
+		exp.MetalSource = MetalSourceCatalog
+		exp.CalculatedMeltValue = calc.BaseMeltValue(comp.WeightGrams, comp.CopperPercent, comp.NickelPercent)
+		exp.SpotPrices = []SpotPriceUsed{
+			spotPrice("copper", calc.Prices),
+			spotPrice("nickel", calc.Prices),
+		}
+		exp.Calculation = fmt.Sprintf("%.2f g / %.3f g/lb × (%.0f%% × $%.2f/lb copper + %.0f%% × $%.2f/lb nickel) = $%.4f",
+			comp.WeightGrams, metals.GramsPerPound, comp.CopperPercent, calc.Prices.Copper, comp.NickelPercent, calc.Prices.Nickel, exp.CalculatedMeltValue)
+
+	case coin.MetalType != "" && coin.MetalWeight > 0 && coin.MetalPurity > 0:
+		exp.MetalSource = MetalSourceManual
+		if matched && coin.MetalType == comp.MetalType && coin.MetalWeight == comp.Weight && coin.MetalPurity == comp.Purity {
+			exp.MetalSource = MetalSourceCatalog
+		}
+		exp.CalculatedMeltValue = CoinMeltValue(coin, calc)
+		exp.SpotPrices = []SpotPriceUsed{spotPrice(coin.MetalType, calc.Prices)}
+		exp.Calculation = fmt.Sprintf("%.5f oz × %.2f%% × $%.2f/oz %s = $%.4f",
+			coin.MetalWeight, coin.MetalPurity, exp.SpotPrices[0].Price, coin.MetalType, exp.CalculatedMeltValue)
+		if exp.MetalSource == MetalSourceManual {
+			exp.Notes = append(exp.Notes, "Metal type, weight or purity were entered manually and differ from the catalog")
+		}
+
+	default:
+		exp.SpotPrices = []SpotPriceUsed{}
+		exp.Notes = append(exp.Notes, "No metal content is known, so no melt value can be calculated")
+	}
+
+	if coin.PCGSCertNumber != "" {
+		exp.PCGSGuideValue = coin.NumismaticValue
+		if coin.NumismaticValue > 0 {
+			exp.Notes = append(exp.Notes, "The PCGS price guide value is stored as numismatic_value")
+		}
+	}
+
+	exp.ConditionFactor = ConditionFactor(coin)
+	if exp.ConditionFactor < 1 {
+		exp.Notes = append(exp.Notes, fmt.Sprintf("The coin's problems and eye appeal reduce its numismatic value to %.0f%%", exp.ConditionFactor*100))
+	}
+
+	switch basis {
+	case BasisMelt:
+		exp.Notes = append(exp.Notes, "The portfolio is valued at melt, so current_value follows the melt value and falls back to the numismatic value when no metal content is known")
+	case BasisNumismatic:
+		exp.Notes = append(exp.Notes, "The portfolio is valued at numismatic value, so current_value follows it and falls back to melt when no numismatic value is known")
+	default:
+		exp.Notes = append(exp.Notes, "The portfolio is valued at the higher of melt and numismatic value")
+	}
+
+	exp.BasisValue = Value(basis, exp.CalculatedMeltValue, AdjustedNumismaticValue(coin, coin.NumismaticValue))
+	if coin.CurrentValue > 0 && math.Abs(coin.CurrentValue-exp.BasisValue) > valueTolerance {
+		exp.Overridden = true
+		if exp.BasisValue == 0 {
+			exp.Notes = append(exp.Notes, "current_value was entered manually")
+		} else {
+			exp.Notes = append(exp.Notes, "current_value differs from its value at today's spot prices: it was entered manually or spot prices have moved since the last price update")
+		}
+	}
+
+	return exp
+}
+
+func spotPrice(metal string, prices metals.SpotPrices) SpotPriceUsed {
+	used := SpotPriceUsed{Metal: metal, Unit: "USD/oz", UpdatedAt: prices.UpdatedAt}
+	switch metal {
+	case "gold":
+		used.Price = prices.Gold
+	case "silver":
+		used.Price = prices.Silver
+	case "platinum":
+		used.Price = prices.Platinum
+	case "palladium":
+		used.Price = prices.Palladium
+	case "copper":
+		used.Price, used.Unit = prices.Copper, "USD/lb"
+	case "nickel":
+		used.Price, used.Unit = prices.Nickel, "USD/lb"
+	}
+	return used
+}
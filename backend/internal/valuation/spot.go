@@ -0,0 +1,109 @@
+package valuation
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/config"
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/events"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// revalueBatchSize is how many coins are revalued per query
+const revalueBatchSize = 500
+
+// revalueMu keeps refreshes from revaluing the same coins at once
+var revalueMu sync.Mutex
+
+// SpotRevalueEnabled reports whether stored coin values follow spot prices
+// as they refresh (SPOT_REVALUE_COINS, default on)
+func SpotRevalueEnabled() bool {
+	return config.Bool("SPOT_REVALUE_COINS", true)
+}
+
+// RevalueMetals recomputes the stored melt_value, and current_value on each
+// portfolio's basis, of the coins made of metalTypes at current spot prices.
+// Stats, lists and reports total the stored values, so they reflect a price
+// move right away instead of when each coin is next refreshed. It returns
+// how many coins changed.
+func RevalueMetals(metalTypes []string) (int, error) {
+	if len(metalTypes) == 0 {
+		return 0, nil
+	}
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		return 0, err
+	}
+
+	revalueMu.Lock()
+	defer revalueMu.Unlock()
+
+	now := time.Now()
+	changed := 0
+	var coins []models.Coin
+	err = database.GetDB().
+		Where("metal_type IN ? AND metal_weight > 0 AND metal_purity > 0", metalTypes).
+		FindInBatches(&coins, revalueBatchSize, func(tx *gorm.DB, batch int) error {
+			portfolioIDs := make([]uuid.UUID, len(coins))
+			for i, coin := range coins {
+				portfolioIDs[i] = coin.PortfolioID
+			}
+			bases, err := Bases(portfolioIDs)
+			if err != nil {
+				return err
+			}
+
+			for _, coin := range coins {
+				meltValue := CoinMeltValue(coin, calc)
+				if meltValue <= 0 {
+					continue
+				}
+				before := coin
+				ApplyMeltValue(&coin, meltValue, bases[coin.PortfolioID])
+				if sameCents(coin.MeltValue, before.MeltValue) && sameCents(coin.CurrentValue, before.CurrentValue) {
+					continue
+				}
+				if err := database.GetDB().Model(&coin).Updates(map[string]interface{}{
+					"melt_value":        coin.MeltValue,
+					"current_value":     coin.CurrentValue,
+					"last_price_update": now,
+				}).Error; err != nil {
+					return err
+				}
+				changed++
+			}
+			return nil
+		}).Error
+	return changed, err
+}
+
+// sameCents reports whether two amounts round to the same cent
+func sameCents(a, b float64) bool {
+	return math.Round(a*100) == math.Round(b*100)
+}
+
+// Subscribe revalues the stored values of coins whose metal's spot price
+// moved whenever spot prices are refreshed. Spot-driven changes don't
+// publish CoinValued: alerts are evaluated on the refresh itself.
+func Subscribe() {
+	events.Subscribe(events.TypeSpotPricesRefreshed, func(e events.Event) {
+		refreshed := e.(events.SpotPricesRefreshed)
+		if !SpotRevalueEnabled() || len(refreshed.Moved) == 0 {
+			return
+		}
+		changed, err := RevalueMetals(refreshed.Moved)
+		if err != nil {
+			log.Printf("Failed to revalue coins after the spot price refresh: %v", err)
+			return
+		}
+		if changed > 0 {
+			log.Printf("Revalued %d coins after %v spot prices moved", changed, refreshed.Moved)
+		}
+	})
+}
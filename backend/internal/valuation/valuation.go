@@ -0,0 +1,204 @@
+package valuation
+
+import (
+	"math"
+	"time"
+
+	"github.com/evansminotwood/aureus/internal/database"
+	"github.com/evansminotwood/aureus/internal/metals"
+	"github.com/evansminotwood/aureus/internal/models"
+	"github.com/google/uuid"
+)
+
+// CoinMeltValue returns the melt value of a single coin (not multiplied by
+// quantity), or 0 when its metal content is unknown
+func CoinMeltValue(coin models.Coin, calc *metals.Calculator) float64 {
+	if coin.MetalType == "" || coin.MetalWeight <= 0 || coin.MetalPurity <= 0 {
+		return 0
+	}
+	meltValue, err := calc.MeltValue(coin.MetalType, coin.MetalWeight, coin.MetalPurity)
+	if err != nil {
+		return 0
+	}
+	return meltValue
+}
+
+// Valuation bases a portfolio's coins can be valued on
+const (
+	BasisMelt       = "melt"       // metal content at spot prices
+	BasisNumismatic = "numismatic" // grade-based market value, e.g. the PCGS guide
+	BasisMax        = "max"        // whichever of the two is higher
+)
+
+// DefaultBasis values coins at the higher of melt and numismatic value
+const DefaultBasis = BasisMax
+
+// ValidBasis reports whether basis is a known valuation basis
+func ValidBasis(basis string) bool {
+	switch basis {
+	case BasisMelt, BasisNumismatic, BasisMax:
+		return true
+	}
+	return false
+}
+
+// Value values a single coin on basis. A coin missing the value its basis
+// asks for falls back to the other one; 0 means neither is known.
+func Value(basis string, meltValue, numismaticValue float64) float64 {
+	switch basis {
+	case BasisMelt:
+		if meltValue > 0 {
+			return meltValue
+		}
+		return numismaticValue
+	case BasisNumismatic:
+		if numismaticValue > 0 {
+			return numismaticValue
+		}
+		return meltValue
+	default:
+		return math.Max(meltValue, numismaticValue)
+	}
+}
+
+// ApplyBasis sets a coin's current_value from its melt and numismatic values
+// on basis, after any condition haircut. Coins with neither keep their
+// current_value, which was entered manually.
+func ApplyBasis(coin *models.Coin, basis string) {
+	if value := Value(basis, coin.MeltValue, AdjustedNumismaticValue(*coin, coin.NumismaticValue)); value > 0 {
+		coin.CurrentValue = value
+	}
+}
+
+// ApplyMeltValue records a freshly calculated melt value on a coin and
+// revalues it on its portfolio's basis
+func ApplyMeltValue(coin *models.Coin, meltValue float64, basis string) {
+	coin.MeltValue = meltValue
+	ApplyBasis(coin, basis)
+}
+
+// ApplyNumismaticValue records a grade-based numismatic value on a coin and
+// revalues it on its portfolio's basis
+func ApplyNumismaticValue(coin *models.Coin, numismaticValue float64, basis string) {
+	coin.NumismaticValue = numismaticValue
+	ApplyBasis(coin, basis)
+}
+
+// Bases returns the valuation basis of each of the given portfolios
+func Bases(portfolioIDs []uuid.UUID) (map[uuid.UUID]string, error) {
+	var portfolios []models.Portfolio
+	if len(portfolioIDs) > 0 {
+		if err := database.GetDB().Select("id", "valuation_basis").Where("id IN ?", portfolioIDs).Find(&portfolios).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	bases := make(map[uuid.UUID]string, len(portfolios))
+	for _, portfolio := range portfolios {
+		bases[portfolio.ID] = portfolio.ValuationBasis
+	}
+	return bases, nil
+}
+
+// Rebase revalues every coin in a portfolio on a new basis and returns how
+// many coins' current_value changed
+func Rebase(portfolioID uuid.UUID, basis string) (int, error) {
+	var coins []models.Coin
+	if err := database.GetDB().Where("portfolio_id = ?", portfolioID).Find(&coins).Error; err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for _, coin := range coins {
+		before := coin.CurrentValue
+		ApplyBasis(&coin, basis)
+		if coin.CurrentValue == before {
+			continue
+		}
+		if err := database.GetDB().Model(&coin).Update("current_value", coin.CurrentValue).Error; err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// RefreshMeltValues sets melt_value on coins to their melt value at current
+// spot prices for display, then fills in their derived fields. Coins without
+// precious metal content, and all coins when spot prices are unavailable,
+// keep their stored melt_value.
+func RefreshMeltValues(coins []models.Coin) {
+	defer ApplyDerived(coins)
+
+	calc, err := metals.CurrentCalculator()
+	if err != nil {
+		return
+	}
+	for i := range coins {
+		if meltValue := CoinMeltValue(coins[i], calc); meltValue > 0 {
+			coins[i].MeltValue = meltValue
+		}
+	}
+}
+
+// Derive sets a coin's premium over melt and its gain or loss from its
+// values and cost, so every client and export does the math the same way.
+// Coins without a melt value have no premium over it, and the gain is only
+// a percentage of a known cost, as in portfolio stats.
+func Derive(coin *models.Coin) {
+	coin.PremiumOverMelt = 0
+	if coin.MeltValue > 0 {
+		coin.PremiumOverMelt = coin.CurrentValue - coin.MeltValue
+	}
+
+	cost := AllInCost(*coin)
+	coin.GainLoss = coin.CurrentValue*float64(coin.Quantity) - cost
+	coin.GainLossPercent = 0
+	if cost > 0 {
+		coin.GainLossPercent = coin.GainLoss / cost * 100
+	}
+}
+
+// ApplyDerived runs Derive on each of coins
+func ApplyDerived(coins []models.Coin) {
+	for i := range coins {
+		Derive(&coins[i])
+	}
+}
+
+// PortfolioMeltValue sums the melt value of every coin in a portfolio
+func PortfolioMeltValue(portfolioID uuid.UUID, calc *metals.Calculator) (float64, error) {
+	var coins []models.Coin
+	if err := database.GetDB().
+		Where("portfolio_id = ? AND metal_type != '' AND metal_weight > 0 AND metal_purity > 0", portfolioID).
+		Find(&coins).Error; err != nil {
+		return 0, err
+	}
+
+	var total float64
+	for _, coin := range coins {
+		total += CoinMeltValue(coin, calc) * float64(coin.Quantity)
+	}
+	return total, nil
+}
+
+// AcquisitionFees is what was paid for a coin's purchase on top of the
+// hammer price: buyer's premium, shipping and sales tax
+func AcquisitionFees(coin models.Coin) float64 {
+	return coin.BuyersPremium + coin.ShippingCost + coin.SalesTax
+}
+
+// AllInCost is a coin's cost basis: the hammer price of every coin in the
+// purchase plus its fees
+func AllInCost(coin models.Coin) float64 {
+	return coin.PurchasePrice*float64(coin.Quantity) + AcquisitionFees(coin)
+}
+
+// AcquiredAt is when a coin was bought: its purchase date, or when it was
+// added if no purchase date was entered
+func AcquiredAt(coin models.Coin) time.Time {
+	if coin.PurchaseDate != nil {
+		return *coin.PurchaseDate
+	}
+	return coin.CreatedAt
+}
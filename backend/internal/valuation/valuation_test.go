@@ -0,0 +1,69 @@
+package valuation
+
+import (
+	"testing"
+
+	"github.com/evansminotwood/aureus/internal/models"
+)
+
+func TestValueOnBasis(t *testing.T) {
+	tests := []struct {
+		basis                  string
+		melt, numismatic, want float64
+	}{
+		{BasisMelt, 30, 95, 30},
+		{BasisMelt, 0, 95, 95},
+		{BasisNumismatic, 30, 95, 95},
+		{BasisNumismatic, 30, 0, 30},
+		{BasisMax, 30, 95, 95},
+		{BasisMax, 2400, 2100, 2400},
+		{BasisMax, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Value(tt.basis, tt.melt, tt.numismatic); got != tt.want {
+			t.Errorf("Value(%s, %v, %v) = %v, want %v", tt.basis, tt.melt, tt.numismatic, got, tt.want)
+		}
+	}
+}
+
+func TestApplyBasisKeepsManualValue(t *testing.T) {
+	coin := models.Coin{CurrentValue: 500}
+	ApplyBasis(&coin, BasisMelt)
+	if coin.CurrentValue != 500 {
+		t.Errorf("current_value = %v, want manual 500 kept", coin.CurrentValue)
+	}
+
+	ApplyNumismaticValue(&coin, 650, BasisMelt)
+	if coin.CurrentValue != 650 {
+		t.Errorf("current_value = %v, want numismatic fallback 650", coin.CurrentValue)
+	}
+	ApplyMeltValue(&coin, 40, BasisMelt)
+	if coin.CurrentValue != 40 {
+		t.Errorf("current_value = %v, want melt 40", coin.CurrentValue)
+	}
+}
+
+func TestAllInCost(t *testing.T) {
+	coin := models.Coin{PurchasePrice: 40, Quantity: 5, BuyersPremium: 40, ShippingCost: 12.5, SalesTax: 7.5}
+	if got := AllInCost(coin); got != 260 {
+		t.Errorf("AllInCost = %v, want 260", got)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	coin := models.Coin{CurrentValue: 60, MeltValue: 25, PurchasePrice: 40, Quantity: 5, BuyersPremium: 40, ShippingCost: 12.5, SalesTax: 7.5}
+	Derive(&coin)
+	if coin.PremiumOverMelt != 35 {
+		t.Errorf("premium over melt = %v, want 35", coin.PremiumOverMelt)
+	}
+	if coin.GainLoss != 40 || coin.GainLossPercent != 40.0/260*100 {
+		t.Errorf("gain/loss = %v (%v%%), want 40 on a 260 cost", coin.GainLoss, coin.GainLossPercent)
+	}
+
+	uncosted := models.Coin{CurrentValue: 60, Quantity: 1}
+	Derive(&uncosted)
+	if uncosted.PremiumOverMelt != 0 || uncosted.GainLoss != 60 || uncosted.GainLossPercent != 0 {
+		t.Errorf("coin without melt value or cost derived %+v", uncosted)
+	}
+}
@@ -0,0 +1,312 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Register creates an account and authenticates the client as the new user
+func (c *Client) Register(ctx context.Context, email, password string) (*AuthResponse, error) {
+	return c.authenticate(ctx, "/auth/register", email, password)
+}
+
+// Login authenticates the client; later calls send the returned token
+func (c *Client) Login(ctx context.Context, email, password string) (*AuthResponse, error) {
+	return c.authenticate(ctx, "/auth/login", email, password)
+}
+
+// Demo signs in to a new throwaway account holding a sample collection, on
+// instances running in demo mode. The account is deleted at the user's
+// DemoExpiresAt.
+func (c *Client) Demo(ctx context.Context) (*AuthResponse, error) {
+	var out AuthResponse
+	if _, err := c.do(ctx, http.MethodPost, "/auth/demo", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	c.SetToken(out.Token)
+	return &out, nil
+}
+
+func (c *Client) authenticate(ctx context.Context, path, email, password string) (*AuthResponse, error) {
+	in := map[string]string{"email": email, "password": password}
+	var out AuthResponse
+	if _, err := c.do(ctx, http.MethodPost, path, nil, in, &out); err != nil {
+		return nil, err
+	}
+	c.SetToken(out.Token)
+	return &out, nil
+}
+
+// ForgotPassword mails a password reset link to email, if it has an account
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	in := map[string]string{"email": email}
+	_, err := c.do(ctx, http.MethodPost, "/auth/forgot-password", nil, in, nil)
+	return err
+}
+
+// ResetPassword sets a new password with the token from a reset link. Every
+// session of the account is logged out, so log in again afterwards.
+func (c *Client) ResetPassword(ctx context.Context, token, password string) error {
+	in := map[string]string{"token": token, "password": password}
+	_, err := c.do(ctx, http.MethodPost, "/auth/reset-password", nil, in, nil)
+	return err
+}
+
+// Refresh trades a refresh token for a new access token, which the client
+// switches to, and a new refresh token to use next time. Each refresh token
+// works once.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	in := map[string]string{"refresh_token": refreshToken}
+	var out AuthResponse
+	if _, err := c.do(ctx, http.MethodPost, "/auth/refresh", nil, in, &out); err != nil {
+		return nil, err
+	}
+	c.SetToken(out.Token)
+	return &out, nil
+}
+
+// Logout revokes the session of refreshToken
+func (c *Client) Logout(ctx context.Context, refreshToken string) error {
+	in := map[string]string{"refresh_token": refreshToken}
+	_, err := c.do(ctx, http.MethodPost, "/auth/logout", nil, in, nil)
+	return err
+}
+
+// LogoutEverywhere revokes every session of the account and every token
+// issued to it so far, including the client's own
+func (c *Client) LogoutEverywhere(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/auth/logout-everywhere", nil, nil, nil)
+	return err
+}
+
+// DeleteAccount closes the account and deletes everything in it. password
+// confirms it; accounts without a password confirm with their email instead.
+func (c *Client) DeleteAccount(ctx context.Context, password, email string) error {
+	in := map[string]string{"password": password, "email": email}
+	_, err := c.do(ctx, http.MethodDelete, "/auth/me", nil, in, nil)
+	return err
+}
+
+// ExportAccount downloads everything the account owns as one JSON document
+func (c *Client) ExportAccount(ctx context.Context) (json.RawMessage, error) {
+	var out json.RawMessage
+	if _, err := c.do(ctx, http.MethodGet, "/auth/me/export", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Sessions lists the devices the account is signed in on, most recently
+// seen first
+func (c *Client) Sessions(ctx context.Context) ([]Session, error) {
+	var out []Session
+	if _, err := c.do(ctx, http.MethodGet, "/auth/sessions", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeSession signs one device out; its tokens stop working at once
+func (c *Client) RevokeSession(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/auth/sessions/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// AuditLogFilter narrows AuditLog; zero fields don't filter
+type AuditLogFilter struct {
+	Action string // e.g. "login" or "coin.deleted"
+	From   time.Time
+	To     time.Time
+	After  string // the previous page's Next
+	Limit  int    // the server's default page size when 0
+}
+
+// AuditLogPage is one page of the audit log, newest first. Next is where
+// the following page starts, empty on the last one.
+type AuditLogPage struct {
+	Entries []AuditLogEntry
+	Next    string
+}
+
+// AuditLog returns a page of what was done to the account: sign-ins, coin
+// and portfolio changes and PCGS syncs
+func (c *Client) AuditLog(ctx context.Context, filter AuditLogFilter) (*AuditLogPage, error) {
+	query := url.Values{}
+	if filter.Action != "" {
+		query.Set("action", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+	if filter.After != "" {
+		query.Set("after", filter.After)
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+
+	page := &AuditLogPage{}
+	resp, err := c.do(ctx, http.MethodGet, "/audit-log", query, nil, &page.Entries)
+	if err != nil {
+		return nil, err
+	}
+	page.Next = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
+// OAuthProviders lists the providers users can sign in with ("google",
+// "apple", and "oidc" for the organization's single sign-on)
+func (c *Client) OAuthProviders(ctx context.Context) ([]string, error) {
+	var out struct {
+		Providers []string `json:"providers"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/auth/oauth/providers", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Providers, nil
+}
+
+// ExchangeOAuthCode trades the login code a provider sign-in ends with for a
+// session, and authenticates the client with it. Each code works once.
+func (c *Client) ExchangeOAuthCode(ctx context.Context, code string) (*AuthResponse, error) {
+	in := map[string]string{"code": code}
+	var out AuthResponse
+	if _, err := c.do(ctx, http.MethodPost, "/auth/oauth/exchange", nil, in, &out); err != nil {
+		return nil, err
+	}
+	c.SetToken(out.Token)
+	return &out, nil
+}
+
+// OAuthIdentities lists the providers linked to the account
+func (c *Client) OAuthIdentities(ctx context.Context) ([]OAuthIdentity, error) {
+	var out []OAuthIdentity
+	if _, err := c.do(ctx, http.MethodGet, "/auth/oauth/identities", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LinkOAuth returns a URL that links provider to the account once opened in
+// a browser and signed in to there
+func (c *Client) LinkOAuth(ctx context.Context, provider string) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/auth/oauth/"+provider+"/link", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// UnlinkOAuth unlinks provider from the account. It fails when the provider
+// is the only way left to sign in.
+func (c *Client) UnlinkOAuth(ctx context.Context, provider string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/auth/oauth/"+provider, nil, nil, nil)
+	return err
+}
+
+// Me returns the authenticated user
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	var out User
+	if _, err := c.do(ctx, http.MethodGet, "/auth/me", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Settings returns how the user's amounts, weights and dates are shown, and
+// their default portfolio
+func (c *Client) Settings(ctx context.Context) (*Settings, error) {
+	var out Settings
+	if _, err := c.do(ctx, http.MethodGet, "/settings", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateSettings changes the settings given. Responses about portfolios,
+// coins, lots and reports then come in the new currency and weight unit,
+// and amounts sent to them are read in it.
+func (c *Client) UpdateSettings(ctx context.Context, in SettingsInput) (*Settings, error) {
+	var out Settings
+	if _, err := c.do(ctx, http.MethodPut, "/settings", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ChangeEmail mails a verification link to newEmail. The account's email
+// only changes once ConfirmEmailChange is called with the link's token.
+func (c *Client) ChangeEmail(ctx context.Context, newEmail, password string) error {
+	in := map[string]string{"new_email": newEmail, "password": password}
+	_, err := c.do(ctx, http.MethodPost, "/auth/change-email", nil, in, nil)
+	return err
+}
+
+// ConfirmEmailChange switches the account to its verified new email
+func (c *Client) ConfirmEmailChange(ctx context.Context, token string) (*User, error) {
+	in := map[string]string{"token": token}
+	var out User
+	if _, err := c.do(ctx, http.MethodPost, "/auth/change-email/confirm", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateScopedToken issues a token limited to scopes ("coins:read",
+// "coins:write", "reports:read" or "admin") that expires after expiresInDays,
+// or 30 days when 0. The client keeps using its own token.
+func (c *Client) CreateScopedToken(ctx context.Context, scopes []string, expiresInDays int) (*ScopedToken, error) {
+	in := map[string]any{"scopes": scopes, "expires_in_days": expiresInDays}
+	var out ScopedToken
+	if _, err := c.do(ctx, http.MethodPost, "/auth/tokens", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// APIKeys lists the account's API keys, newest first
+func (c *Client) APIKeys(ctx context.Context) ([]APIKey, error) {
+	var out []APIKey
+	if _, err := c.do(ctx, http.MethodGet, "/auth/api-keys", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateAPIKey creates an API key limited to scopes that expires after
+// expiresInDays, or never when 0. The returned Key is the only copy.
+func (c *Client) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresInDays int) (*APIKey, error) {
+	in := map[string]any{"name": name, "scopes": scopes, "expires_in_days": expiresInDays}
+	var out APIKey
+	if _, err := c.do(ctx, http.MethodPost, "/auth/api-keys", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateDisplayToken creates a token for an always-on display that can only
+// read portfolioID's total value and spot prices, with Display. It expires
+// after expiresInDays, or never when 0, and is revoked with DeleteAPIKey.
+func (c *Client) CreateDisplayToken(ctx context.Context, name, portfolioID string, expiresInDays int) (*APIKey, error) {
+	in := map[string]any{"name": name, "portfolio_id": portfolioID, "expires_in_days": expiresInDays}
+	var out APIKey
+	if _, err := c.do(ctx, http.MethodPost, "/auth/display-tokens", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAPIKey revokes an API key
+func (c *Client) DeleteAPIKey(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/auth/api-keys/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
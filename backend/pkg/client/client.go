@@ -0,0 +1,222 @@
+// Package client is a Go client for the Aureus API.
+//
+//	c := client.New("http://localhost:8080")
+//	if _, err := c.Login(ctx, "me@example.com", "secret"); err != nil {
+//		return err
+//	}
+//	portfolios, err := c.ListPortfolios(ctx)
+//
+// Scripts can skip logging in with an API key:
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(os.Getenv("AUREUS_API_KEY")))
+//
+// Every method returns an *APIError when the server answers with a non-2xx
+// status.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPrefix is the versioned path every request is sent under
+const APIPrefix = "/api/v1"
+
+const defaultTimeout = 30 * time.Second
+
+// Client calls the Aureus API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+
+	apiKey string
+
+	mu    sync.RWMutex
+	token string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken authenticates requests with an existing JWT instead of logging in
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithAPIKey authenticates requests with an API key (X-API-Key) instead of
+// a JWT, for scripts. The key's scopes limit what the client can do.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: defaultTimeout},
+		userAgent:  "aureus-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the JWT the client authenticates with, if any
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the JWT the client authenticates with
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+// APIError is a non-2xx response from the API
+type APIError struct {
+	StatusCode int
+	Message    string // the "error" field of the response body
+	Code       string // machine-readable code, when the server sends one
+	Body       []byte
+	RetryAfter time.Duration // from the Retry-After header of a 429
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("aureus: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("aureus: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is a 401 from the API, e.g. because the
+// token is missing or has expired
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsTooManyAttempts reports whether err is a 429 from the API because the
+// email or IP address is locked out after failed logins; the APIError's
+// RetryAfter says for how long
+func IsTooManyAttempts(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// rawBody is a request body sent as is rather than encoded as JSON
+type rawBody struct {
+	contentType string
+	r           io.Reader
+}
+
+// do sends a request to path (relative to APIPrefix), encoding in as JSON
+// unless it is a rawBody, and decodes a JSON response into out, which may be
+// nil. It returns the raw response so callers can read headers; its body is
+// already closed.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) (*http.Response, error) {
+	endpoint := c.baseURL + APIPrefix + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	contentType := "application/json"
+	if raw, ok := in.(rawBody); ok {
+		body, contentType = raw.r, raw.contentType
+	} else if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("aureus: encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("aureus: creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	} else if token := c.Token(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("aureus: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, fmt.Errorf("aureus: reading response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: data}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			apiErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		var errBody struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		if json.Unmarshal(data, &errBody) == nil {
+			apiErr.Message, apiErr.Code = errBody.Error, errBody.Code
+		}
+		return resp, apiErr
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp, fmt.Errorf("aureus: decoding response: %w", err)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestClientAuthPagingAndErrors(t *testing.T) {
+	coins := make([]Coin, 250)
+	for i := range coins {
+		coins[i].ID = strconv.Itoa(i)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(AuthResponse{Token: "jwt", User: User{Email: "me@example.com"}})
+	})
+	mux.HandleFunc("GET /api/v1/portfolios/p1/coins", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer jwt" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Authorization header required"})
+			return
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(offset+limit, len(coins))
+		w.Header().Set("X-Total-Count", strconv.Itoa(len(coins)))
+		json.NewEncoder(w).Encode(coins[offset:end])
+	})
+	mux.HandleFunc("GET /api/v1/coins/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Coin not found"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	c := New(server.URL)
+
+	if _, err := c.ListPortfolioCoins(ctx, "p1", 0, 10); !IsUnauthorized(err) {
+		t.Fatalf("expected 401 before login, got %v", err)
+	}
+
+	if _, err := c.Login(ctx, "me@example.com", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Token() != "jwt" {
+		t.Fatalf("token = %q, want jwt", c.Token())
+	}
+
+	count := 0
+	for coin, err := range c.AllPortfolioCoins(ctx, "p1") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coin.ID != strconv.Itoa(count) {
+			t.Fatalf("coin %d has ID %q", count, coin.ID)
+		}
+		count++
+	}
+	if count != len(coins) {
+		t.Fatalf("iterated %d coins, want %d", count, len(coins))
+	}
+
+	_, err := c.GetCoin(ctx, "missing")
+	if !IsNotFound(err) {
+		t.Fatalf("expected 404, got %v", err)
+	}
+	if apiErr := err.(*APIError); apiErr.Message != "Coin not found" {
+		t.Fatalf("message = %q", apiErr.Message)
+	}
+}
+
+func TestClientSendsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "aur_key" || r.Header.Get("Authorization") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid or expired API key"})
+			return
+		}
+		json.NewEncoder(w).Encode(Coin{ID: "c1"})
+	}))
+	defer server.Close()
+
+	c := New(server.URL, WithAPIKey("aur_key"))
+	coin, err := c.GetCoin(context.Background(), "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coin.ID != "c1" {
+		t.Errorf("coin = %q, want c1", coin.ID)
+	}
+}
+
+func TestImportCoinsWaitsForTheJob(t *testing.T) {
+	polls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/portfolios/p1/import", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/api/v1/jobs/j1")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(Job{ID: "j1", Kind: "import", Status: JobQueued})
+	})
+	mux.HandleFunc("GET /api/v1/jobs/j1", func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls == 1 {
+			w.Header().Set("Retry-After", "1")
+			json.NewEncoder(w).Encode(Job{ID: "j1", Kind: "import", Status: JobRunning, Done: 1, Total: 2})
+			return
+		}
+		json.NewEncoder(w).Encode(Job{ID: "j1", Kind: "import", Status: JobSucceeded, Done: 2, Total: 2,
+			Result: json.RawMessage(`{"created":2}`)})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	result, err := New(server.URL).ImportCoins(context.Background(), "p1", strings.NewReader("year\n1921\n1922\n"), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Created != 2 || polls != 2 {
+		t.Errorf("created = %d after %d polls, want 2 after 2", result.Created, polls)
+	}
+}
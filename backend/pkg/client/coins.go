@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPageSize is the page size AllPortfolioCoins fetches with
+const DefaultPageSize = 100
+
+// CoinPage is one page of a portfolio's coins
+type CoinPage struct {
+	Coins  []Coin
+	Total  int // number of coins in the portfolio
+	Offset int
+}
+
+// HasMore reports whether there are coins after this page
+func (p *CoinPage) HasMore() bool {
+	return p.Offset+len(p.Coins) < p.Total
+}
+
+// GetCoin returns a coin, with its melt value at current spot prices
+func (c *Client) GetCoin(ctx context.Context, id string) (*Coin, error) {
+	var out Coin
+	if _, err := c.do(ctx, http.MethodGet, "/coins/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateCoin adds a coin to in.PortfolioID, or to the default portfolio in
+// Settings when it's empty. Metal composition, denomination and face value
+// are filled in from the catalog when left empty.
+func (c *Client) CreateCoin(ctx context.Context, in CoinInput) (*Coin, error) {
+	var out Coin
+	if _, err := c.do(ctx, http.MethodPost, "/coins", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CoinStory is a coin's story as written, in Markdown, and rendered as HTML
+// that is safe to embed in a page
+type CoinStory struct {
+	Story string `json:"story"`
+	HTML  string `json:"html"`
+}
+
+// GetCoinStory returns a coin's story
+func (c *Client) GetCoinStory(ctx context.Context, id string) (*CoinStory, error) {
+	var out CoinStory
+	if _, err := c.do(ctx, http.MethodGet, "/coins/"+url.PathEscape(id)+"/story", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateCoin updates a coin. Mint mark, denomination and notes are always
+// replaced, so send them along with any other change.
+func (c *Client) UpdateCoin(ctx context.Context, id string, in CoinInput) (*Coin, error) {
+	var out Coin
+	if _, err := c.do(ctx, http.MethodPut, "/coins/"+url.PathEscape(id), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportCoins adds the coins of a CSV with a header row to a portfolio.
+// onDuplicate (OnDuplicateSkip, OnDuplicateUpdate or OnDuplicateDuplicate)
+// decides what happens to rows whose cert number is already in the
+// collection; with dryRun nothing is saved. The server imports the rows in
+// a job, which ImportCoins waits for.
+func (c *Client) ImportCoins(ctx context.Context, portfolioID string, csv io.Reader, onDuplicate string, dryRun bool) (*ImportResult, error) {
+	return c.ImportCoinsMapped(ctx, portfolioID, csv, nil, onDuplicate, dryRun)
+}
+
+// ImportCoinsMapped is ImportCoins with the columns chosen by mapping, from
+// header to field or "" to leave the column out, e.g. an ImportPreview's
+// Mapping as corrected by the user
+func (c *Client) ImportCoinsMapped(ctx context.Context, portfolioID string, csv io.Reader, mapping map[string]string, onDuplicate string, dryRun bool) (*ImportResult, error) {
+	query := url.Values{"dry_run": {strconv.FormatBool(dryRun)}}
+	if onDuplicate != "" {
+		query.Set("on_duplicate", onDuplicate)
+	}
+	if mapping != nil {
+		data, err := json.Marshal(mapping)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("mapping", string(data))
+	}
+	var out ImportResult
+	path := "/portfolios/" + url.PathEscape(portfolioID) + "/import"
+	if err := c.runJob(ctx, http.MethodPost, path, query, rawBody{contentType: "text/csv", r: csv}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PreviewImport reads a CSV without importing it and suggests the field each
+// column holds, with sampleRows rows (0 for the default)
+func (c *Client) PreviewImport(ctx context.Context, csv io.Reader, sampleRows int) (*ImportPreview, error) {
+	var query url.Values
+	if sampleRows > 0 {
+		query = url.Values{"sample_rows": {strconv.Itoa(sampleRows)}}
+	}
+	var out ImportPreview
+	if _, err := c.do(ctx, http.MethodPost, "/import/preview", query, rawBody{contentType: "text/csv", r: csv}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SplitCoin moves quantities of a coin into new rows, one per entry, and
+// returns the coin and the new rows. Fees are divided by quantity.
+func (c *Client) SplitCoin(ctx context.Context, id string, quantities []int) (*Coin, []Coin, error) {
+	var out struct {
+		Coin     Coin   `json:"coin"`
+		NewCoins []Coin `json:"new_coins"`
+	}
+	body := map[string][]int{"quantities": quantities}
+	if _, err := c.do(ctx, http.MethodPost, "/coins/"+url.PathEscape(id)+"/split", nil, body, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out.Coin, out.NewCoins, nil
+}
+
+// MergeCoins folds identical coin rows into id and returns the merged coin.
+// The other rows are deleted.
+func (c *Client) MergeCoins(ctx context.Context, id string, coinIDs []string) (*Coin, error) {
+	var out Coin
+	body := map[string][]string{"coin_ids": coinIDs}
+	if _, err := c.do(ctx, http.MethodPost, "/coins/"+url.PathEscape(id)+"/merge", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpgradeCoin records that a coin was regraded into a new slab. It returns
+// the replacement coin, which keeps the cost basis and price history, and the
+// archived record of the coin under its old cert.
+func (c *Client) UpgradeCoin(ctx context.Context, id string, in UpgradeInput) (*Coin, *ArchivedCoin, error) {
+	var out struct {
+		Coin     Coin         `json:"coin"`
+		Previous ArchivedCoin `json:"previous"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, "/coins/"+url.PathEscape(id)+"/upgrade", nil, in, &out); err != nil {
+		return nil, nil, err
+	}
+	return &out.Coin, &out.Previous, nil
+}
+
+// CoinUpgrades returns the archived records a coin was regraded from, most
+// recent first
+func (c *Client) CoinUpgrades(ctx context.Context, id string) ([]ArchivedCoin, error) {
+	var out []ArchivedCoin
+	if _, err := c.do(ctx, http.MethodGet, "/coins/"+url.PathEscape(id)+"/upgrades", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteCoin moves a coin to the trash
+func (c *Client) DeleteCoin(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/coins/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ListPortfolioCoins returns up to limit coins of a portfolio starting at
+// offset, oldest first
+func (c *Client) ListPortfolioCoins(ctx context.Context, portfolioID string, offset, limit int) (*CoinPage, error) {
+	query := url.Values{
+		"offset": {strconv.Itoa(offset)},
+		"limit":  {strconv.Itoa(limit)},
+	}
+	var coins []Coin
+	resp, err := c.do(ctx, http.MethodGet, "/portfolios/"+url.PathEscape(portfolioID)+"/coins", query, nil, &coins)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CoinPage{Coins: coins, Offset: offset}
+	if total, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil {
+		page.Total = total
+	} else {
+		// Servers without paging return every coin at once
+		page.Total = offset + len(coins)
+	}
+	return page, nil
+}
+
+// AllPortfolioCoins iterates over every coin in a portfolio, fetching pages of
+// DefaultPageSize as needed. Iteration stops at the first error.
+//
+//	for coin, err := range c.AllPortfolioCoins(ctx, portfolioID) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Client) AllPortfolioCoins(ctx context.Context, portfolioID string) iter.Seq2[Coin, error] {
+	return func(yield func(Coin, error) bool) {
+		offset := 0
+		for {
+			page, err := c.ListPortfolioCoins(ctx, portfolioID, offset, DefaultPageSize)
+			if err != nil {
+				yield(Coin{}, err)
+				return
+			}
+			for _, coin := range page.Coins {
+				if !yield(coin, nil) {
+					return
+				}
+			}
+			if !page.HasMore() || len(page.Coins) == 0 {
+				return
+			}
+			offset += len(page.Coins)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// defaultPollInterval is how often WaitJob polls when the server doesn't say
+const defaultPollInterval = 2 * time.Second
+
+// GetJob returns a job with its progress, and its result once it has finished
+func (c *Client) GetJob(ctx context.Context, id string) (*Job, error) {
+	job, _, err := c.getJob(ctx, id)
+	return job, err
+}
+
+// ListJobs returns the user's jobs, newest first, optionally only those of
+// kind or with status
+func (c *Client) ListJobs(ctx context.Context, kind, status string) ([]Job, error) {
+	query := url.Values{}
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var out []Job
+	if _, err := c.do(ctx, http.MethodGet, "/jobs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WaitJob polls a job until it has finished and returns it. A job that failed
+// is returned along with a *JobError.
+func (c *Client) WaitJob(ctx context.Context, id string) (*Job, error) {
+	for {
+		job, wait, err := c.getJob(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if job.Finished() {
+			if job.Status == JobFailed {
+				return job, &JobError{Job: job}
+			}
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// getJob returns a job and how long to wait before polling it again
+func (c *Client) getJob(ctx context.Context, id string) (*Job, time.Duration, error) {
+	var out Job
+	resp, err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(id), nil, nil, &out)
+	if err != nil {
+		return nil, 0, err
+	}
+	wait := defaultPollInterval
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		wait = time.Duration(seconds) * time.Second
+	}
+	return &out, wait, nil
+}
+
+// runJob sends a request that starts a job, waits for the job to finish and
+// decodes its result into out, which may be nil
+func (c *Client) runJob(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var started Job
+	if _, err := c.do(ctx, method, path, query, in, &started); err != nil {
+		return err
+	}
+	job, err := c.WaitJob(ctx, started.ID)
+	if err != nil {
+		return err
+	}
+	if out != nil && len(job.Result) > 0 {
+		if err := json.Unmarshal(job.Result, out); err != nil {
+			return fmt.Errorf("aureus: decoding job result: %w", err)
+		}
+	}
+	return nil
+}
+
+// JobError is a job that failed
+type JobError struct {
+	Job *Job
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("aureus: %s job %s failed: %s", e.Job.Kind, e.Job.ID, e.Job.Error)
+}
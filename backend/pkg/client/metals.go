@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetSpotPrices returns the current metal spot prices
+func (c *Client) GetSpotPrices(ctx context.Context) (*SpotPrices, error) {
+	var out SpotPrices
+	if _, err := c.do(ctx, http.MethodGet, "/metals/spot-prices", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDisplay returns what a display token shows: its portfolio's total value
+// and spot prices. Clients with a full access login pass the portfolio to
+// preview; display tokens pass "".
+func (c *Client) GetDisplay(ctx context.Context, portfolioID string) (*Display, error) {
+	var query url.Values
+	if portfolioID != "" {
+		query = url.Values{"portfolio_id": {portfolioID}}
+	}
+	var out Display
+	if _, err := c.do(ctx, http.MethodGet, "/display", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CalculateMeltValue returns the melt value of weight troy ounces of metalType
+// at purity percent, at current spot prices
+func (c *Client) CalculateMeltValue(ctx context.Context, metalType string, weight, purity float64) (*MeltValue, error) {
+	in := map[string]any{"metal_type": metalType, "weight": weight, "purity": purity}
+	var out MeltValue
+	if _, err := c.do(ctx, http.MethodPost, "/metals/melt-value", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
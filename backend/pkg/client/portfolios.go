@@ -0,0 +1,178 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListPortfolios returns the user's portfolios, then those shared with them,
+// with their coin counts, total values and the user's role
+func (c *Client) ListPortfolios(ctx context.Context) ([]Portfolio, error) {
+	var out []Portfolio
+	if _, err := c.do(ctx, http.MethodGet, "/portfolios", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListArchivedPortfolios returns the archived portfolios the user owns or
+// is a member of
+func (c *Client) ListArchivedPortfolios(ctx context.Context) ([]Portfolio, error) {
+	var out []Portfolio
+	if _, err := c.do(ctx, http.MethodGet, "/portfolios", url.Values{"archived": {"true"}}, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPortfolio returns a portfolio and all of its coins
+func (c *Client) GetPortfolio(ctx context.Context, id string) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodGet, "/portfolios/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreatePortfolio creates a portfolio
+func (c *Client) CreatePortfolio(ctx context.Context, in PortfolioInput) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePortfolio renames a portfolio and replaces its description
+func (c *Client) UpdatePortfolio(ctx context.Context, id string, in PortfolioInput) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodPut, "/portfolios/"+url.PathEscape(id), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReorderPortfolios sets the order of the portfolio list and returns the
+// reordered list. Portfolios left out keep their order after the listed ones.
+func (c *Client) ReorderPortfolios(ctx context.Context, ids []string) ([]Portfolio, error) {
+	in := map[string][]string{"portfolio_ids": ids}
+	var out []Portfolio
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios/reorder", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ArchivePortfolio takes a portfolio out of the portfolio list
+func (c *Client) ArchivePortfolio(ctx context.Context, id string) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios/"+url.PathEscape(id)+"/archive", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UnarchivePortfolio puts an archived portfolio back in the portfolio list
+func (c *Client) UnarchivePortfolio(ctx context.Context, id string) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios/"+url.PathEscape(id)+"/unarchive", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePortfolio moves a portfolio and its coins to the trash
+func (c *Client) DeletePortfolio(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/portfolios/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// GetPortfolioStats returns value and gain/loss totals for a portfolio
+func (c *Client) GetPortfolioStats(ctx context.Context, id string) (*PortfolioStats, error) {
+	var out PortfolioStats
+	if _, err := c.do(ctx, http.MethodGet, "/portfolios/"+url.PathEscape(id)+"/stats", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StatsBatch is returned by GetPortfolioStatsBatch. NotFound lists requested
+// IDs of portfolios the user can't see.
+type StatsBatch struct {
+	Stats    map[string]PortfolioStats `json:"stats"`
+	NotFound []string                  `json:"not_found"`
+}
+
+// GetPortfolioStatsBatch returns stats for up to 100 portfolios in one call
+func (c *Client) GetPortfolioStatsBatch(ctx context.Context, ids []string) (*StatsBatch, error) {
+	in := map[string][]string{"portfolio_ids": ids}
+	var out StatsBatch
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios/stats-batch", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPortfolioMembers returns the members of a portfolio and its pending
+// invitations
+func (c *Client) ListPortfolioMembers(ctx context.Context, portfolioID string) ([]PortfolioMember, error) {
+	var out []PortfolioMember
+	if _, err := c.do(ctx, http.MethodGet, "/portfolios/"+url.PathEscape(portfolioID)+"/members", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// InvitePortfolioMember invites a user on the instance to a portfolio as an
+// "owner", "editor" or "viewer"
+func (c *Client) InvitePortfolioMember(ctx context.Context, portfolioID, email, role string) (*PortfolioMember, error) {
+	in := map[string]string{"email": email, "role": role}
+	var out PortfolioMember
+	if _, err := c.do(ctx, http.MethodPost, "/portfolios/"+url.PathEscape(portfolioID)+"/members", nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdatePortfolioMember gives a member another role
+func (c *Client) UpdatePortfolioMember(ctx context.Context, portfolioID, memberID, role string) (*PortfolioMember, error) {
+	in := map[string]string{"role": role}
+	var out PortfolioMember
+	if _, err := c.do(ctx, http.MethodPut, "/portfolios/"+url.PathEscape(portfolioID)+"/members/"+url.PathEscape(memberID), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RemovePortfolioMember removes a member or withdraws an invitation. Members
+// can remove themselves to leave.
+func (c *Client) RemovePortfolioMember(ctx context.Context, portfolioID, memberID string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/portfolios/"+url.PathEscape(portfolioID)+"/members/"+url.PathEscape(memberID), nil, nil, nil)
+	return err
+}
+
+// ListPortfolioInvitations returns the invitations to the user they haven't
+// answered
+func (c *Client) ListPortfolioInvitations(ctx context.Context) ([]PortfolioMember, error) {
+	var out []PortfolioMember
+	if _, err := c.do(ctx, http.MethodGet, "/portfolio-invitations", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AcceptPortfolioInvitation joins the portfolio an invitation is for
+func (c *Client) AcceptPortfolioInvitation(ctx context.Context, id string) (*PortfolioMember, error) {
+	var out PortfolioMember
+	if _, err := c.do(ctx, http.MethodPost, "/portfolio-invitations/"+url.PathEscape(id)+"/accept", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeclinePortfolioInvitation turns an invitation down
+func (c *Client) DeclinePortfolioInvitation(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/portfolio-invitations/"+url.PathEscape(id)+"/decline", nil, nil, nil)
+	return err
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// GetTrash returns the user's deleted portfolios and coins
+func (c *Client) GetTrash(ctx context.Context) (*Trash, error) {
+	var out Trash
+	if _, err := c.do(ctx, http.MethodGet, "/trash", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RestorePortfolio takes a portfolio out of the trash with the coins deleted
+// along with it
+func (c *Client) RestorePortfolio(ctx context.Context, id string) (*Portfolio, error) {
+	var out Portfolio
+	if _, err := c.do(ctx, http.MethodPost, "/trash/portfolios/"+url.PathEscape(id)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PurgePortfolio deletes a portfolio in the trash and its coins for good
+func (c *Client) PurgePortfolio(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/trash/portfolios/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// RestoreCoin puts a deleted coin back in its portfolio. It fails with 409
+// while the portfolio is in the trash too.
+func (c *Client) RestoreCoin(ctx context.Context, id string) (*Coin, error) {
+	var out Coin
+	if _, err := c.do(ctx, http.MethodPost, "/trash/coins/"+url.PathEscape(id)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PurgeCoin deletes a coin in the trash for good
+func (c *Client) PurgeCoin(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/trash/coins/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
@@ -0,0 +1,467 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// User is an Aureus account
+type User struct {
+	ID      string `json:"id"`
+	Email   string `json:"email"`
+	IsAdmin bool   `json:"is_admin"`
+	// DemoExpiresAt is when a demo account is deleted; nil on others
+	DemoExpiresAt *time.Time `json:"demo_expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// AuthResponse is returned by Register, Login, Demo and Refresh
+type AuthResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+}
+
+// OAuthIdentity is a sign-in provider linked to the account
+type OAuthIdentity struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScopedToken is a token limited to some scopes, from CreateScopedToken
+type ScopedToken struct {
+	Token     string    `json:"token"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// APIKey is a key scripts authenticate with. Key is only set on the
+// response from CreateAPIKey.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// PortfolioID is only set on display tokens, the portfolio they show
+	PortfolioID string `json:"portfolio_id,omitempty"`
+}
+
+// Display is what a display token can read: one portfolio's total value
+// and spot prices
+type Display struct {
+	PortfolioName string     `json:"portfolio_name"`
+	Currency      string     `json:"currency"`
+	TotalValue    float64    `json:"total_value"`
+	SpotPrices    SpotPrices `json:"spot_prices"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Session is a device the account is signed in on. Current marks the
+// client's own session.
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+// AuditLogEntry is one thing done to the account: a sign-in, a coin or
+// portfolio change, or a PCGS sync
+type AuditLogEntry struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	TargetID  string    `json:"target_id,omitempty"`
+	APIKeyID  string    `json:"api_key_id,omitempty"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Settings is how the user's amounts, weights and dates are shown
+type Settings struct {
+	Currency           string    `json:"currency"`    // ISO 4217 code
+	WeightUnit         string    `json:"weight_unit"` // "troy_oz" or "g"
+	DateFormat         string    `json:"date_format"` // "iso", "us" or "eu"
+	DefaultPortfolioID *string   `json:"default_portfolio_id"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// SettingsInput changes the settings given; nil fields are left unchanged
+// and an empty DefaultPortfolioID clears it
+type SettingsInput struct {
+	Currency           *string `json:"currency,omitempty"`
+	WeightUnit         *string `json:"weight_unit,omitempty"`
+	DateFormat         *string `json:"date_format,omitempty"`
+	DefaultPortfolioID *string `json:"default_portfolio_id,omitempty"`
+}
+
+// Portfolio is a named collection of coins. Coins is only filled in by
+// GetPortfolio; CoinCount and TotalValue only by ListPortfolios.
+type Portfolio struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// How the portfolio list shows it; notes are Markdown
+	CoverImageURL string `json:"cover_image_url"`
+	Color         string `json:"color"`
+	Icon          string `json:"icon"`
+	SortOrder     int    `json:"sort_order"`
+	Notes         string `json:"notes"`
+	// MonthlyStatement emails the owner a summary of each month, with the
+	// stories of coins acquired that month when StatementStories is set
+	MonthlyStatement bool   `json:"monthly_statement"`
+	StatementStories bool   `json:"statement_stories"`
+	ValuationBasis   string `json:"valuation_basis"`
+	// Defaults new coins start with when they leave the field out
+	DefaultFaceCurrency    string    `json:"default_face_currency"`
+	DefaultStorageLocation string    `json:"default_storage_location"`
+	DefaultAutoSync        bool      `json:"default_auto_sync"`
+	CreatedAt              time.Time `json:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at"`
+	Coins                  []Coin    `json:"coins,omitempty"`
+	CoinCount              int       `json:"coin_count,omitempty"`
+	TotalValue             float64   `json:"total_value,omitempty"`
+	// Role is the user's role on the portfolio: "owner" for their own,
+	// otherwise the role they were invited as
+	Role string `json:"role,omitempty"`
+	// Archived portfolios are only listed by ListArchivedPortfolios
+	Archived   bool       `json:"archived"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+// PortfolioMember is a user a portfolio is shared with, or invited to it.
+// AcceptedAt is nil while the invitation is pending.
+type PortfolioMember struct {
+	ID             string     `json:"id"`
+	PortfolioID    string     `json:"portfolio_id"`
+	PortfolioName  string     `json:"portfolio_name"`
+	UserID         string     `json:"user_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"` // "owner", "editor" or "viewer"
+	InvitedBy      string     `json:"invited_by"`
+	InvitedByEmail string     `json:"invited_by_email"`
+	AcceptedAt     *time.Time `json:"accepted_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PortfolioInput creates or updates a portfolio. MonthlyStatement and
+// StatementStories are only applied on update; they, the appearance fields
+// and the coin defaults are left unchanged when nil.
+type PortfolioInput struct {
+	Name                   string  `json:"name"`
+	Description            string  `json:"description"`
+	MonthlyStatement       *bool   `json:"monthly_statement,omitempty"`
+	StatementStories       *bool   `json:"statement_stories,omitempty"`
+	CoverImageURL          *string `json:"cover_image_url,omitempty"`
+	Color                  *string `json:"color,omitempty"`
+	Icon                   *string `json:"icon,omitempty"`
+	Notes                  *string `json:"notes,omitempty"`
+	DefaultFaceCurrency    *string `json:"default_face_currency,omitempty"`
+	DefaultStorageLocation *string `json:"default_storage_location,omitempty"`
+	DefaultAutoSync        *bool   `json:"default_auto_sync,omitempty"`
+}
+
+// PortfolioStats summarizes the value of a portfolio
+type PortfolioStats struct {
+	Currency             string  `json:"currency"` // ISO 4217, e.g. "USD"
+	TotalCoins           int64   `json:"total_coins"`
+	TotalValue           float64 `json:"total_value"`
+	TotalHammerPrice     float64 `json:"total_hammer_price"`
+	TotalAcquisitionFees float64 `json:"total_acquisition_fees"`
+	TotalPurchaseCost    float64 `json:"total_purchase_cost"`
+	TotalGainLoss        float64 `json:"total_gain_loss"`
+	GainLossPercent      float64 `json:"gain_loss_percent"`
+	TotalFaceValue       float64 `json:"total_face_value"`
+	JunkSilverFaceValue  float64 `json:"junk_silver_face_value"`
+}
+
+// Coin is a coin in a portfolio
+type Coin struct {
+	ID                    string     `json:"id"`
+	Code                  string     `json:"code"` // short code, e.g. AU-000123; usable in place of ID
+	PortfolioID           string     `json:"portfolio_id"`
+	CoinType              string     `json:"coin_type"`
+	Year                  int        `json:"year"`
+	MintMark              string     `json:"mint_mark"`
+	StrikeType            string     `json:"strike_type"`
+	Denomination          string     `json:"denomination"`
+	FaceValue             float64    `json:"face_value"`
+	FaceCurrency          string     `json:"face_currency"`
+	Series                string     `json:"series"`
+	Mintage               int64      `json:"mintage"`
+	PCGSCertNumber        string     `json:"pcgs_cert_number"`
+	EnrichmentStatus      string     `json:"enrichment_status"` // pending while the cert is looked up at PCGS
+	PurchasePrice         float64    `json:"purchase_price"`
+	BuyersPremium         float64    `json:"buyers_premium"`
+	ShippingCost          float64    `json:"shipping_cost"`
+	SalesTax              float64    `json:"sales_tax"`
+	PurchaseDate          *time.Time `json:"purchase_date"`
+	LotID                 *string    `json:"lot_id"`
+	UpgradedFromID        *string    `json:"upgraded_from_id"` // archived record it was regraded from
+	CurrentValue          float64    `json:"current_value"`
+	MeltValue             float64    `json:"melt_value"`
+	NumismaticValue       float64    `json:"numismatic_value"`
+	InsuredValue          float64    `json:"insured_value"`
+	LastPriceUpdate       *time.Time `json:"last_price_update"`
+	AutoSync              bool       `json:"auto_sync"` // false leaves it out of scheduled PCGS syncs
+	StorageLocation       string     `json:"storage_location"`
+	ImageURL              string     `json:"image_url"`
+	ThumbnailURL          string     `json:"thumbnail_url"`
+	Notes                 string     `json:"notes"`
+	Story                 string     `json:"story"` // Markdown
+	Quantity              int        `json:"quantity"`
+	MetalType             string     `json:"metal_type"`
+	MetalWeight           float64    `json:"metal_weight"`
+	MetalPurity           float64    `json:"metal_purity"`
+	Problems              []string   `json:"problems"`
+	EyeAppeal             string     `json:"eye_appeal"`
+	Toning                []string   `json:"toning"`
+	CompositionSource     string     `json:"composition_source"`
+	CompositionConfidence string     `json:"composition_confidence"`
+	CertStatus            string     `json:"cert_status"`
+	CertFlags             []string   `json:"cert_flags"`
+	Watched               bool       `json:"watched"`
+	PremiumOverMelt       float64    `json:"premium_over_melt"`
+	GainLoss              float64    `json:"gain_loss"`
+	GainLossPercent       float64    `json:"gain_loss_percent"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+
+	// Links about the coin; lookups fill in the auto ones
+	References []Reference `json:"references"`
+}
+
+// ArchivedCoin is a coin that left its portfolio, e.g. sold or regraded
+type ArchivedCoin struct {
+	Coin
+	Disposition   string    `json:"disposition"`
+	DisposedAt    time.Time `json:"disposed_at"`
+	SalePrice     float64   `json:"sale_price"`
+	SaleFees      float64   `json:"sale_fees"`
+	DisposalNotes string    `json:"disposal_notes"`
+	ReplacedByID  *string   `json:"replaced_by_id"` // the coin that replaced a regraded one
+	ArchivedAt    time.Time `json:"archived_at"`
+}
+
+// Trash is the user's deleted portfolios and coins, kept for Retention
+// before they're deleted for good. Coins deleted with their portfolio are
+// counted in its CoinCount rather than listed.
+type Trash struct {
+	Portfolios []TrashedPortfolio `json:"portfolios"`
+	Coins      []TrashedCoin      `json:"coins"`
+	Retention  string             `json:"retention"`
+}
+
+// TrashedPortfolio is a portfolio in the trash
+type TrashedPortfolio struct {
+	Portfolio
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedCoin is a coin deleted on its own
+type TrashedCoin struct {
+	Coin
+	PortfolioName string    `json:"portfolio_name"`
+	DeletedAt     time.Time `json:"deleted_at"`
+	PurgeAt       time.Time `json:"purge_at"`
+}
+
+// UpgradeInput records a coin's regrade or crossover into a new slab
+type UpgradeInput struct {
+	PCGSCertNumber  string     `json:"pcgs_cert_number"`
+	NumismaticValue float64    `json:"numismatic_value,omitempty"` // looked up by the new cert when 0
+	RegradedAt      *time.Time `json:"regraded_at,omitempty"`
+	Notes           string     `json:"notes,omitempty"`
+}
+
+// CoinInput creates or updates a coin. Zero fields are left for the server to
+// fill in (on create) or left unchanged (on update).
+type CoinInput struct {
+	PortfolioID     string     `json:"portfolio_id,omitempty"`
+	CoinType        string     `json:"coin_type,omitempty"`
+	Year            int        `json:"year,omitempty"`
+	MintMark        string     `json:"mint_mark,omitempty"`
+	StrikeType      string     `json:"strike_type,omitempty"`
+	Denomination    string     `json:"denomination,omitempty"`
+	FaceValue       float64    `json:"face_value,omitempty"`
+	FaceCurrency    string     `json:"face_currency,omitempty"`
+	PCGSCertNumber  string     `json:"pcgs_cert_number,omitempty"`
+	PurchasePrice   float64    `json:"purchase_price,omitempty"`
+	BuyersPremium   float64    `json:"buyers_premium,omitempty"`
+	ShippingCost    float64    `json:"shipping_cost,omitempty"`
+	SalesTax        float64    `json:"sales_tax,omitempty"`
+	PurchaseDate    *time.Time `json:"purchase_date,omitempty"`
+	CurrentValue    float64    `json:"current_value,omitempty"`
+	NumismaticValue float64    `json:"numismatic_value,omitempty"`
+	InsuredValue    float64    `json:"insured_value,omitempty"`
+	ImageURL        string     `json:"image_url,omitempty"`
+	ThumbnailURL    string     `json:"thumbnail_url,omitempty"`
+	Notes           string     `json:"notes,omitempty"`
+	Story           *string    `json:"story,omitempty"` // Markdown; nil leaves it unchanged on update, "" clears it
+	Quantity        int        `json:"quantity,omitempty"`
+	MetalType       string     `json:"metal_type,omitempty"`
+	MetalWeight     float64    `json:"metal_weight,omitempty"`
+	MetalPurity     float64    `json:"metal_purity,omitempty"`
+	Problems        []string   `json:"problems,omitempty"`
+	EyeAppeal       string     `json:"eye_appeal,omitempty"`
+	Toning          []string   `json:"toning,omitempty"`
+	Watched         *bool      `json:"watched,omitempty"`
+	// Left nil on create, these come from the portfolio's defaults
+	StorageLocation *string `json:"storage_location,omitempty"`
+	AutoSync        *bool   `json:"auto_sync,omitempty"`
+	// The links added by hand; nil leaves them unchanged on update
+	References []Reference `json:"references,omitempty"`
+}
+
+// Reference is a typed link about a coin, e.g. "pcgs_coinfacts",
+// "auction_archive" or "literature"
+type Reference struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Auto  bool   `json:"auto,omitempty"` // filled in by a lookup; ignored when sent
+}
+
+// What an import does with rows whose cert number is already in the collection
+const (
+	OnDuplicateSkip      = "skip"
+	OnDuplicateUpdate    = "update"
+	OnDuplicateDuplicate = "duplicate"
+)
+
+// ImportResult reports what a CSV import did with each row
+type ImportResult struct {
+	OnDuplicate    string            `json:"on_duplicate"`
+	DryRun         bool              `json:"dry_run"`
+	Created        int               `json:"created"`
+	Updated        int               `json:"updated"`
+	Skipped        int               `json:"skipped"`
+	Failed         int               `json:"failed"`
+	IgnoredColumns []string          `json:"ignored_columns"`
+	Rows           []ImportRowResult `json:"rows"`
+}
+
+// ImportRowResult is what an import did with one row: "created", "updated",
+// "skipped" or "failed". Duplicates name the existing coin, or the earlier
+// row, with the same cert number.
+type ImportRowResult struct {
+	Line            int    `json:"line"`
+	Status          string `json:"status"`
+	CoinID          string `json:"coin_id,omitempty"`
+	PCGSCertNumber  string `json:"pcgs_cert_number,omitempty"`
+	DuplicateOf     string `json:"duplicate_of,omitempty"`
+	DuplicateOfLine int    `json:"duplicate_of_line,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// ImportPreview is what a CSV holds, with the field each column most likely
+// holds. Mapping is the suggestions in the form ImportCoinsMapped takes.
+type ImportPreview struct {
+	Columns        []ImportPreviewColumn `json:"columns"`
+	SampleRows     [][]string            `json:"sample_rows"`
+	RowCount       int                   `json:"row_count"`
+	Fields         []string              `json:"fields"`
+	RequiredFields []string              `json:"required_fields"`
+	Mapping        map[string]string     `json:"mapping"`
+}
+
+// ImportPreviewColumn is one column of a previewed CSV. Match says how the
+// field was found: "header", "alias", "similar" or "content".
+type ImportPreviewColumn struct {
+	Index          int      `json:"index"`
+	Header         string   `json:"header"`
+	Samples        []string `json:"samples"`
+	SuggestedField string   `json:"suggested_field"`
+	Confidence     float64  `json:"confidence"`
+	Match          string   `json:"match"`
+}
+
+// SpotPrices are precious metal prices in USD per troy ounce, and base metal
+// prices in USD per pound
+type SpotPrices struct {
+	Gold      float64   `json:"gold"`
+	Silver    float64   `json:"silver"`
+	Platinum  float64   `json:"platinum"`
+	Palladium float64   `json:"palladium"`
+	Copper    float64   `json:"copper"`
+	Nickel    float64   `json:"nickel"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Degraded is set when FallbackMetals are priced from built-in fallbacks
+	Degraded       bool     `json:"degraded"`
+	FallbackMetals []string `json:"fallback_metals,omitempty"`
+	// Currency is the ISO 4217 code of the prices, and Units the unit each
+	// metal is priced per ("troy_oz" or "lb")
+	Currency string            `json:"currency"`
+	Units    map[string]string `json:"units"`
+	// Market is whether the metals market is trading
+	Market *MarketStatus `json:"market,omitempty"`
+}
+
+// MarketStatus is whether the metals market is open, with its last close and
+// when it next opens (while closed) or closes (while open)
+type MarketStatus struct {
+	Open      bool       `json:"open"`
+	Timezone  string     `json:"timezone"`
+	LastClose time.Time  `json:"last_close"`
+	NextOpen  *time.Time `json:"next_open,omitempty"`
+	NextClose *time.Time `json:"next_close,omitempty"`
+}
+
+// MeltValue is the result of a melt value calculation
+type MeltValue struct {
+	MeltValue float64 `json:"melt_value"`
+	Currency  string  `json:"currency"`
+	MetalType string  `json:"metal_type"`
+	Weight    float64 `json:"weight"`
+	Unit      string  `json:"unit"` // of Weight, "troy_oz"
+	Purity    float64 `json:"purity"`
+}
+
+// Job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// Job is a long operation, such as an import, running on the server. Result
+// holds what the operation reports once it has finished.
+type Job struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Status     string          `json:"status"`
+	Done       int             `json:"done"`
+	Total      int             `json:"total"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// Finished reports whether the job has succeeded or failed
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
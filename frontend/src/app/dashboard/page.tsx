@@ -14,6 +14,7 @@ import { UploadImageDialog } from '@/components/upload-image-dialog'
 import { EditCoinDialog } from '@/components/edit-coin-dialog'
 import { PortfolioStatsDialog } from '@/components/portfolio-stats-dialog'
 import { SettingsDialog } from '@/components/settings-dialog'
+import { NotificationsMenu } from '@/components/notifications-menu'
 import { PCGSPriceDisplay } from '@/components/pcgs-price-display'
 import { CoinDetailDialog } from '@/components/coin-detail-dialog'
 import { ImageZoomDialog } from '@/components/image-zoom-dialog'
@@ -43,7 +44,7 @@ import {
 } from 'lucide-react'
 
 export default function DashboardPage() {
-  const { user, logout, isAuthenticated } = useAuth()
+  const { user, logout, isAuthenticated, loading: authLoading } = useAuth()
   const router = useRouter()
   const [portfolios, setPortfolios] = useState<Portfolio[]>([])
   const [selectedPortfolio, setSelectedPortfolio] = useState<string | null>(null)
@@ -51,12 +52,31 @@ export default function DashboardPage() {
   const [loading, setLoading] = useState(true)
 
   useEffect(() => {
+    if (authLoading) return
     if (!isAuthenticated) {
       router.push('/login')
       return
     }
     loadPortfolios()
-  }, [isAuthenticated, router])
+  }, [authLoading, isAuthenticated, router])
+
+  // Linking a sign-in provider from Settings comes back here
+  useEffect(() => {
+    const params = new URLSearchParams(window.location.search)
+    const linked = params.get('oauth_linked')
+    const failed = params.get('oauth_error')
+    if (!linked && !failed) return
+    window.history.replaceState(null, '', '/dashboard')
+    if (linked) {
+      alert(`Linked your ${linked === 'apple' ? 'Apple' : 'Google'} account`)
+    } else if (failed === 'already_linked') {
+      alert('That account is already linked to another user')
+    } else if (failed === 'provider_already_linked') {
+      alert('You already linked a different account at that provider. Unlink it first.')
+    } else {
+      alert('Failed to link the account')
+    }
+  }, [])
 
   useEffect(() => {
     if (selectedPortfolio) {
@@ -113,6 +133,7 @@ export default function DashboardPage() {
             </div>
           </div>
           <div className="flex items-center gap-4">
+            <NotificationsMenu />
             <SettingsDialog />
             <Button variant="ghost" size="icon" onClick={logout}>
               <LogOut className="w-5 h-5" />
@@ -0,0 +1,84 @@
+'use client'
+
+import { useState } from 'react'
+import Link from 'next/link'
+import { authAPI } from '@/lib/api'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+
+export default function ForgotPasswordPage() {
+  const [email, setEmail] = useState('')
+  const [error, setError] = useState('')
+  const [sent, setSent] = useState(false)
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setError('')
+    setLoading(true)
+
+    try {
+      await authAPI.forgotPassword(email)
+      setSent(true)
+    } catch (err: any) {
+      setError(err.response?.data?.error || 'Failed to send reset link')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="min-h-screen bg-gradient-to-b from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <div className="flex items-center justify-center mb-4">
+            <div className="w-12 h-12 rounded-full bg-amber-500 flex items-center justify-center">
+              <span className="text-white font-bold text-2xl">A</span>
+            </div>
+          </div>
+          <CardTitle className="text-2xl text-center">Forgot password</CardTitle>
+          <CardDescription className="text-center">
+            {sent
+              ? `If an account exists for ${email}, we sent it a link to reset the password. It works for an hour.`
+              : "Enter your email and we'll send you a link to reset your password"}
+          </CardDescription>
+        </CardHeader>
+        <CardContent>
+          {!sent && (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {error && (
+                <div className="p-3 text-sm text-red-600 bg-red-50 rounded-md">
+                  {error}
+                </div>
+              )}
+
+              <div className="space-y-2">
+                <Label htmlFor="email">Email</Label>
+                <Input
+                  id="email"
+                  type="email"
+                  placeholder="you@example.com"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                />
+              </div>
+
+              <Button type="submit" className="w-full" disabled={loading}>
+                {loading ? 'Sending...' : 'Send reset link'}
+              </Button>
+            </form>
+          )}
+
+          <div className="mt-4 text-center">
+            <Link href="/login" className="text-sm text-slate-600 hover:text-slate-900">
+              ← Back to sign in
+            </Link>
+          </div>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
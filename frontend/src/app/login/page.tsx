@@ -1,20 +1,53 @@
 'use client'
 
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import Link from 'next/link'
 import { useAuth } from '@/lib/auth-context'
+import { authAPI, authErrorMessage, OAuthProvider } from '@/lib/api'
 import { Button } from '@/components/ui/button'
 import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
 
+const defaultProviderLabels: Record<OAuthProvider, string> = {
+  google: 'Google',
+  apple: 'Apple',
+  oidc: 'Single sign-on',
+}
+
+// Why a provider sign-in came back to /login?oauth_error=
+const oauthErrors: Record<string, string> = {
+  cancelled: 'Sign-in was cancelled',
+  invalid_state: 'Your sign-in expired. Please try again.',
+  provider_error: 'The provider could not sign you in. Please try again.',
+  email_required: 'The provider did not share your email address',
+  email_unverified: 'An account uses this email. Sign in with your password and link the provider from Settings.',
+  wrong_tenant: 'This account belongs to a different organization',
+  registration_disabled: 'Registration is closed',
+  invite_required: 'An invite code is required to register',
+  invalid_invite: 'That invite code is invalid or used up',
+  provider_unavailable: 'Single sign-on is unavailable right now. Please try again later.',
+  mock_identity: 'Mock sign-in only works for test accounts',
+}
+
 export default function LoginPage() {
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
   const [error, setError] = useState('')
   const [loading, setLoading] = useState(false)
+  const [providers, setProviders] = useState<OAuthProvider[]>([])
+  const [providerLabels, setProviderLabels] = useState(defaultProviderLabels)
+  const [passwordLogin, setPasswordLogin] = useState(true)
   const { login } = useAuth()
 
+  useEffect(() => {
+    const code = new URLSearchParams(window.location.search).get('oauth_error')
+    if (code) setError(oauthErrors[code] || 'Failed to sign in')
+    authAPI.getOAuthProviders().then(setProviders).catch(() => {})
+    authAPI.getPasswordLogin().then(setPasswordLogin).catch(() => {})
+    authAPI.getOAuthProviderLabels().then((labels) => setProviderLabels({ ...defaultProviderLabels, ...labels })).catch(() => {})
+  }, [])
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     setError('')
@@ -23,7 +56,7 @@ export default function LoginPage() {
     try {
       await login(email, password)
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to login')
+      setError(authErrorMessage(err, 'Failed to login'))
     } finally {
       setLoading(false)
     }
@@ -44,40 +77,58 @@ export default function LoginPage() {
           </CardDescription>
         </CardHeader>
         <CardContent>
-          <form onSubmit={handleSubmit} className="space-y-4">
-            {error && (
-              <div className="p-3 text-sm text-red-600 bg-red-50 rounded-md">
-                {error}
-              </div>
-            )}
-            
-            <div className="space-y-2">
-              <Label htmlFor="email">Email</Label>
-              <Input
-                id="email"
-                type="email"
-                placeholder="you@example.com"
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                required
-              />
+          {error && (
+            <div className="mb-4 p-3 text-sm text-red-600 bg-red-50 rounded-md">
+              {error}
             </div>
+          )}
 
-            <div className="space-y-2">
-              <Label htmlFor="password">Password</Label>
-              <Input
-                id="password"
-                type="password"
-                value={password}
-                onChange={(e) => setPassword(e.target.value)}
-                required
-              />
-            </div>
+          {passwordLogin && (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              <div className="space-y-2">
+                <Label htmlFor="email">Email</Label>
+                <Input
+                  id="email"
+                  type="email"
+                  placeholder="you@example.com"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  required
+                />
+              </div>
+
+              <div className="space-y-2">
+                <div className="flex items-center justify-between">
+                  <Label htmlFor="password">Password</Label>
+                  <Link href="/forgot-password" className="text-sm text-amber-600 hover:text-amber-500">
+                    Forgot password?
+                  </Link>
+                </div>
+                <Input
+                  id="password"
+                  type="password"
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                />
+              </div>
 
-            <Button type="submit" className="w-full" disabled={loading}>
-              {loading ? 'Signing in...' : 'Sign in'}
-            </Button>
-          </form>
+              <Button type="submit" className="w-full" disabled={loading}>
+                {loading ? 'Signing in...' : 'Sign in'}
+              </Button>
+            </form>
+          )}
+
+          {providers.length > 0 && (
+            <div className="mt-4 space-y-2">
+              {passwordLogin && <div className="text-center text-xs uppercase text-slate-500">Or</div>}
+              {providers.map((provider) => (
+                <Button key={provider} variant="outline" className="w-full" asChild>
+                  <a href={authAPI.oauthStartUrl(provider)}>Continue with {providerLabels[provider]}</a>
+                </Button>
+              ))}
+            </div>
+          )}
 
           <div className="mt-4 text-center text-sm">
             Don't have an account?{' '}
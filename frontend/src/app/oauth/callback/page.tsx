@@ -0,0 +1,51 @@
+'use client'
+
+import { useEffect, useRef, useState } from 'react'
+import Link from 'next/link'
+import { useAuth } from '@/lib/auth-context'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+
+// Providers send the browser here after signing in, with a one-time code
+// that's traded for a session
+export default function OAuthCallbackPage() {
+  const [error, setError] = useState('')
+  const { loginWithOAuthCode } = useAuth()
+  // The code works once, so only exchange it once
+  const exchanged = useRef(false)
+
+  useEffect(() => {
+    if (exchanged.current) return
+    exchanged.current = true
+    const code = new URLSearchParams(window.location.search).get('code')
+    if (!code) {
+      setError('This sign-in link is missing its code')
+      return
+    }
+    loginWithOAuthCode(code).catch((err: any) => setError(err.response?.data?.error || 'Failed to sign in'))
+  }, [loginWithOAuthCode])
+
+  return (
+    <div className="min-h-screen bg-gradient-to-b from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <div className="flex items-center justify-center mb-4">
+            <div className="w-12 h-12 rounded-full bg-amber-500 flex items-center justify-center">
+              <span className="text-white font-bold text-2xl">A</span>
+            </div>
+          </div>
+          <CardTitle className="text-2xl text-center">{error ? 'Sign-in failed' : 'Signing you in...'}</CardTitle>
+          {error && <CardDescription className="text-center">{error}</CardDescription>}
+        </CardHeader>
+        {error && (
+          <CardContent>
+            <div className="text-center">
+              <Link href="/login" className="text-sm text-amber-600 hover:text-amber-500 font-medium">
+                ← Back to sign in
+              </Link>
+            </div>
+          </CardContent>
+        )}
+      </Card>
+    </div>
+  )
+}
@@ -1,8 +1,9 @@
 'use client'
 
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import Link from 'next/link'
 import { useAuth } from '@/lib/auth-context'
+import { authAPI, authErrorMessage, RegistrationMode } from '@/lib/api'
 import { Button } from '@/components/ui/button'
 import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
@@ -12,10 +13,23 @@ export default function RegisterPage() {
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
+  const [inviteCode, setInviteCode] = useState('')
+  const [mode, setMode] = useState<RegistrationMode>('open')
+  const [minLength, setMinLength] = useState(8)
   const [error, setError] = useState('')
   const [loading, setLoading] = useState(false)
   const { register } = useAuth()
 
+  useEffect(() => {
+    authAPI.getRegistrationMode().then(setMode).catch(() => {})
+    authAPI.getPasswordPolicy().then((policy) => setMinLength(policy.min_length)).catch(() => {})
+    // Invite links look like /register?invite=K7QF-2M9X-PA4T-HW3C
+    const invite = new URLSearchParams(window.location.search).get('invite')
+    if (invite) {
+      setInviteCode(invite)
+    }
+  }, [])
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault()
     setError('')
@@ -25,17 +39,17 @@ export default function RegisterPage() {
       return
     }
 
-    if (password.length < 6) {
-      setError('Password must be at least 6 characters')
+    if (password.length < minLength) {
+      setError(`Password must be at least ${minLength} characters`)
       return
     }
 
     setLoading(true)
 
     try {
-      await register(email, password)
+      await register(email, password, inviteCode || undefined)
     } catch (err: any) {
-      setError(err.response?.data?.error || 'Failed to create account')
+      setError(authErrorMessage(err, 'Failed to create account'))
     } finally {
       setLoading(false)
     }
@@ -52,7 +66,11 @@ export default function RegisterPage() {
           </div>
           <CardTitle className="text-2xl text-center">Create an account</CardTitle>
           <CardDescription className="text-center">
-            Enter your email below to create your account
+            {mode === 'disabled'
+              ? 'Registration is closed on this instance'
+              : mode === 'invite'
+                ? 'An invite code is required to create an account'
+                : 'Enter your email below to create your account'}
           </CardDescription>
         </CardHeader>
         <CardContent>
@@ -80,7 +98,7 @@ export default function RegisterPage() {
               <Input
                 id="password"
                 type="password"
-                placeholder="At least 6 characters"
+                placeholder={`At least ${minLength} characters`}
                 value={password}
                 onChange={(e) => setPassword(e.target.value)}
                 required
@@ -98,6 +116,19 @@ export default function RegisterPage() {
               />
             </div>
 
+            {mode === 'invite' && (
+              <div className="space-y-2">
+                <Label htmlFor="inviteCode">Invite Code</Label>
+                <Input
+                  id="inviteCode"
+                  placeholder="XXXX-XXXX-XXXX-XXXX"
+                  value={inviteCode}
+                  onChange={(e) => setInviteCode(e.target.value)}
+                  required
+                />
+              </div>
+            )}
+
             <Button type="submit" className="w-full" disabled={loading}>
               {loading ? 'Creating account...' : 'Create account'}
             </Button>
@@ -0,0 +1,111 @@
+'use client'
+
+import { useEffect, useState } from 'react'
+import Link from 'next/link'
+import { authAPI } from '@/lib/api'
+import { Button } from '@/components/ui/button'
+import { Input } from '@/components/ui/input'
+import { Label } from '@/components/ui/label'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+
+export default function ResetPasswordPage() {
+  const [token, setToken] = useState<string | null>(null)
+  const [password, setPassword] = useState('')
+  const [confirm, setConfirm] = useState('')
+  const [error, setError] = useState('')
+  const [done, setDone] = useState(false)
+  const [loading, setLoading] = useState(false)
+  const [minLength, setMinLength] = useState(8)
+
+  useEffect(() => {
+    const token = new URLSearchParams(window.location.search).get('token')
+    setToken(token)
+    if (!token) setError('This reset link is missing its token')
+    authAPI.getPasswordPolicy().then((policy) => setMinLength(policy.min_length)).catch(() => {})
+  }, [])
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!token) return
+    if (password !== confirm) {
+      setError('Passwords do not match')
+      return
+    }
+    setError('')
+    setLoading(true)
+
+    try {
+      await authAPI.resetPassword(token, password)
+      setDone(true)
+    } catch (err: any) {
+      setError(err.response?.data?.error || 'Failed to reset password')
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div className="min-h-screen bg-gradient-to-b from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <div className="flex items-center justify-center mb-4">
+            <div className="w-12 h-12 rounded-full bg-amber-500 flex items-center justify-center">
+              <span className="text-white font-bold text-2xl">A</span>
+            </div>
+          </div>
+          <CardTitle className="text-2xl text-center">{done ? 'Password changed' : 'Choose a new password'}</CardTitle>
+          <CardDescription className="text-center">
+            {done
+              ? 'Your password was changed and every device was signed out. Sign in with your new password.'
+              : 'Enter a new password for your account'}
+          </CardDescription>
+        </CardHeader>
+        <CardContent>
+          {!done && (
+            <form onSubmit={handleSubmit} className="space-y-4">
+              {error && (
+                <div className="p-3 text-sm text-red-600 bg-red-50 rounded-md">
+                  {error}
+                </div>
+              )}
+
+              <div className="space-y-2">
+                <Label htmlFor="password">New password</Label>
+                <Input
+                  id="password"
+                  type="password"
+                  minLength={minLength}
+                  value={password}
+                  onChange={(e) => setPassword(e.target.value)}
+                  required
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="confirm">Confirm new password</Label>
+                <Input
+                  id="confirm"
+                  type="password"
+                  minLength={minLength}
+                  value={confirm}
+                  onChange={(e) => setConfirm(e.target.value)}
+                  required
+                />
+              </div>
+
+              <Button type="submit" className="w-full" disabled={loading || !token}>
+                {loading ? 'Saving...' : 'Set new password'}
+              </Button>
+            </form>
+          )}
+
+          <div className="mt-4 text-center">
+            <Link href="/login" className="text-sm text-amber-600 hover:text-amber-500 font-medium">
+              {done ? 'Sign in' : '← Back to sign in'}
+            </Link>
+          </div>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
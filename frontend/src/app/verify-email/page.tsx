@@ -0,0 +1,60 @@
+'use client'
+
+import { useEffect, useRef, useState } from 'react'
+import Link from 'next/link'
+import { authAPI } from '@/lib/api'
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+
+export default function VerifyEmailPage() {
+  const [status, setStatus] = useState<'verifying' | 'done' | 'error'>('verifying')
+  const [message, setMessage] = useState('')
+  const started = useRef(false)
+
+  useEffect(() => {
+    // Tokens are single use, so don't confirm twice in strict mode
+    if (started.current) return
+    started.current = true
+
+    const token = new URLSearchParams(window.location.search).get('token')
+    if (!token) {
+      setStatus('error')
+      setMessage('This verification link is missing its token')
+      return
+    }
+
+    authAPI.confirmEmailChange(token)
+      .then((user) => {
+        setStatus('done')
+        setMessage(`Your email address is now ${user.email}`)
+      })
+      .catch((err: any) => {
+        setStatus('error')
+        setMessage(err.response?.data?.error || 'Failed to verify email address')
+      })
+  }, [])
+
+  return (
+    <div className="min-h-screen bg-gradient-to-b from-slate-50 to-slate-100 flex items-center justify-center p-4">
+      <Card className="w-full max-w-md">
+        <CardHeader className="space-y-1">
+          <div className="flex items-center justify-center mb-4">
+            <div className="w-12 h-12 rounded-full bg-amber-500 flex items-center justify-center">
+              <span className="text-white font-bold text-2xl">A</span>
+            </div>
+          </div>
+          <CardTitle className="text-2xl text-center">
+            {status === 'verifying' ? 'Verifying email...' : status === 'done' ? 'Email changed' : 'Verification failed'}
+          </CardTitle>
+          <CardDescription className="text-center">{message}</CardDescription>
+        </CardHeader>
+        <CardContent>
+          <div className="text-center">
+            <Link href="/dashboard" className="text-sm text-amber-600 hover:text-amber-500 font-medium">
+              Go to dashboard
+            </Link>
+          </div>
+        </CardContent>
+      </Card>
+    </div>
+  )
+}
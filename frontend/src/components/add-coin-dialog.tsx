@@ -1,7 +1,7 @@
 'use client'
 
 import { useState, useEffect } from 'react'
-import { coinAPI, metalsAPI, pcgsAPI } from '@/lib/api'
+import { catalogAPI, coinAPI, metalsAPI, pcgsAPI, CoinTypeSuggestion, STRIKE_TYPES, StrikeType } from '@/lib/api'
 import {
   Dialog,
   DialogContent,
@@ -28,14 +28,20 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
   const [error, setError] = useState('')
   const [fetchingPcgs, setFetchingPcgs] = useState(false)
   const [pcgsWarning, setPcgsWarning] = useState('')
+  const [typeSuggestions, setTypeSuggestions] = useState<CoinTypeSuggestion[]>([])
 
   const [formData, setFormData] = useState({
     coin_type: '',
     year: '',
     mint_mark: '',
+    strike_type: '',
     denomination: '',
     pcgs_cert_number: '',
     purchase_price: '',
+    buyers_premium: '',
+    shipping_cost: '',
+    sales_tax: '',
+    insured_value: '',
     current_value: '',
     numismatic_value: '',
     notes: '',
@@ -101,6 +107,21 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
     return () => clearTimeout(timer)
   }, [formData.coin_type])
 
+  // Type-ahead suggestions for the coin type field
+  useEffect(() => {
+    if (!open) return
+
+    const timer = setTimeout(async () => {
+      try {
+        setTypeSuggestions(await catalogAPI.suggest(formData.coin_type))
+      } catch (err) {
+        setTypeSuggestions([])
+      }
+    }, 200)
+
+    return () => clearTimeout(timer)
+  }, [formData.coin_type, open])
+
   // Auto-fetch PCGS data when cert number changes
   useEffect(() => {
     if (!formData.pcgs_cert_number) return
@@ -175,7 +196,7 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
     return () => clearTimeout(timer)
   }, [formData.pcgs_cert_number])
 
-  const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
+  const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement | HTMLSelectElement>) => {
     setFormData(prev => ({
       ...prev,
       [e.target.name]: e.target.value
@@ -193,9 +214,14 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
         coin_type: formData.coin_type,
         year: formData.year ? parseInt(formData.year) : undefined,
         mint_mark: formData.mint_mark || undefined,
+        strike_type: (formData.strike_type || undefined) as StrikeType | undefined,
         denomination: formData.denomination || undefined,
         pcgs_cert_number: formData.pcgs_cert_number || undefined,
         purchase_price: formData.purchase_price ? parseFloat(formData.purchase_price) : undefined,
+        buyers_premium: formData.buyers_premium ? parseFloat(formData.buyers_premium) : undefined,
+        shipping_cost: formData.shipping_cost ? parseFloat(formData.shipping_cost) : undefined,
+        sales_tax: formData.sales_tax ? parseFloat(formData.sales_tax) : undefined,
+        insured_value: formData.insured_value ? parseFloat(formData.insured_value) : undefined,
         current_value: formData.current_value ? parseFloat(formData.current_value) : undefined,
         numismatic_value: formData.numismatic_value ? parseFloat(formData.numismatic_value) : undefined,
         notes: formData.notes || undefined,
@@ -213,9 +239,19 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
         coin_type: '',
         year: '',
         mint_mark: '',
+        strike_type: '',
         denomination: '',
         pcgs_cert_number: '',
         purchase_price: '',
+        buyers_premium: '',
+        shipping_cost: '',
+        sales_tax: '',
+        insured_value: '',
+    insured_value: '',
+    buyers_premium: '',
+    shipping_cost: '',
+    sales_tax: '',
+    insured_value: '',
         current_value: '',
         numismatic_value: '',
         notes: '',
@@ -297,8 +333,18 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
                 placeholder="e.g., Morgan Dollar, Peace Dollar"
                 value={formData.coin_type}
                 onChange={handleChange}
+                list="coin_type_suggestions"
+                autoComplete="off"
                 required
               />
+              <datalist id="coin_type_suggestions">
+                {typeSuggestions.map((suggestion) => (
+                  <option key={suggestion.coin_type} value={suggestion.coin_type}>
+                    {suggestion.matched_alias ? `${suggestion.matched_alias} → ` : ''}
+                    {suggestion.description}
+                  </option>
+                ))}
+              </datalist>
               <p className="text-xs text-slate-500">Common types: Morgan Dollar, Peace Dollar, Walking Liberty Half Dollar</p>
             </div>
 
@@ -325,6 +371,22 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
               />
             </div>
 
+            <div className="space-y-2">
+              <Label htmlFor="strike_type">Strike Type (Optional)</Label>
+              <select
+                id="strike_type"
+                name="strike_type"
+                value={formData.strike_type}
+                onChange={handleChange}
+                className="flex h-10 w-full rounded-md border border-input bg-background px-3 py-2 text-sm"
+              >
+                <option value="">Unknown</option>
+                {STRIKE_TYPES.map((strike) => (
+                  <option key={strike.value} value={strike.value}>{strike.label}</option>
+                ))}
+              </select>
+            </div>
+
             <div className="space-y-2">
               <Label htmlFor="denomination">Denomination (Optional)</Label>
               <Input
@@ -361,6 +423,62 @@ export function AddCoinDialog({ portfolioId, onSuccess, trigger }: AddCoinDialog
               />
             </div>
 
+            <div className="space-y-2">
+              <Label htmlFor="buyers_premium">Buyer's Premium ($) (Optional)</Label>
+              <Input
+                id="buyers_premium"
+                name="buyers_premium"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.buyers_premium}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="shipping_cost">Shipping ($) (Optional)</Label>
+              <Input
+                id="shipping_cost"
+                name="shipping_cost"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.shipping_cost}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="sales_tax">Sales Tax ($) (Optional)</Label>
+              <Input
+                id="sales_tax"
+                name="sales_tax"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.sales_tax}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="insured_value">Insured Value ($) (Optional)</Label>
+              <Input
+                id="insured_value"
+                name="insured_value"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="Replacement value per coin"
+                value={formData.insured_value}
+                onChange={handleChange}
+              />
+            </div>
+
             <div className="space-y-2">
               <Label htmlFor="current_value">Current Value ($) (Optional)</Label>
               <Input
@@ -23,6 +23,8 @@ interface CoinDetailDialogProps {
 
 export function CoinDetailDialog({ coin, trigger }: CoinDetailDialogProps) {
   const [open, setOpen] = useState(false)
+  // Coins saved before melt_value was recorded only have current_value
+  const meltValue = coin.melt_value || coin.current_value
 
   return (
     <Dialog open={open} onOpenChange={setOpen}>
@@ -110,13 +112,13 @@ export function CoinDetailDialog({ coin, trigger }: CoinDetailDialogProps) {
               </div>
             )}
 
-            {coin.current_value > 0 && (
+            {meltValue > 0 && (
               <div className="p-4 bg-amber-50 rounded-lg">
                 <div className="flex items-center gap-2 mb-2">
                   <Scale className="w-4 h-4 text-amber-600" />
                   <span className="text-sm text-amber-600">Melt Value</span>
                 </div>
-                <p className="text-2xl font-bold text-amber-600">${coin.current_value.toFixed(2)}</p>
+                <p className="text-2xl font-bold text-amber-600">${meltValue.toFixed(2)}</p>
                 {coin.metal_type && (
                   <p className="text-xs text-amber-600 mt-1">
                     {coin.metal_weight > 0
@@ -174,6 +176,27 @@ export function CoinDetailDialog({ coin, trigger }: CoinDetailDialogProps) {
             </div>
           )}
 
+          {/* References */}
+          {coin.references && coin.references.length > 0 && (
+            <div className="p-4 bg-slate-50 rounded-lg">
+              <h3 className="font-semibold mb-2">References</h3>
+              <ul className="space-y-1">
+                {coin.references.map((ref) => (
+                  <li key={ref.url}>
+                    <a
+                      href={ref.url}
+                      target="_blank"
+                      rel="noopener noreferrer"
+                      className="text-sm text-blue-600 hover:underline"
+                    >
+                      {ref.title || ref.url}
+                    </a>
+                  </li>
+                ))}
+              </ul>
+            </div>
+          )}
+
           {/* Price History Chart */}
           <CoinPriceChart coinId={coin.id} coinName={coin.coin_type} />
 
@@ -3,7 +3,8 @@
 import { useEffect, useState } from 'react'
 import { LineChart, Line, XAxis, YAxis, CartesianGrid, Tooltip, Legend, ResponsiveContainer } from 'recharts'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
-import { Loader2 } from 'lucide-react'
+import { Button } from '@/components/ui/button'
+import { Download, Loader2 } from 'lucide-react'
 import axios from 'axios'
 
 interface PriceHistory {
@@ -51,6 +52,32 @@ export function CoinPriceChart({ coinId, coinName }: CoinPriceChartProps) {
     }
   }
 
+  const exportCSV = async () => {
+    try {
+      const token = localStorage.getItem('token')
+      const response = await axios.get(
+        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/coins/${coinId}/price-history/export?format=csv`,
+        {
+          headers: {
+            'Authorization': `Bearer ${token}`
+          },
+          responseType: 'blob'
+        }
+      )
+      const url = URL.createObjectURL(response.data)
+      const link = document.createElement('a')
+      link.setAttribute('href', url)
+      link.setAttribute('download', `${coinName.replace(/[^a-z0-9]+/gi, '-').toLowerCase()}-price-history.csv`)
+      document.body.appendChild(link)
+      link.click()
+      document.body.removeChild(link)
+      URL.revokeObjectURL(url)
+    } catch (err: any) {
+      console.error('Failed to export price history:', err)
+      alert('Failed to export price history')
+    }
+  }
+
   if (loading) {
     return (
       <Card>
@@ -107,11 +134,17 @@ export function CoinPriceChart({ coinId, coinName }: CoinPriceChartProps) {
 
   return (
     <Card>
-      <CardHeader>
-        <CardTitle>Price History</CardTitle>
-        <CardDescription>
-          Historical value tracking for {coinName} ({priceHistory.length} data points)
-        </CardDescription>
+      <CardHeader className="flex flex-row items-start justify-between space-y-0">
+        <div className="space-y-1.5">
+          <CardTitle>Price History</CardTitle>
+          <CardDescription>
+            Historical value tracking for {coinName} ({priceHistory.length} data points)
+          </CardDescription>
+        </div>
+        <Button variant="outline" size="sm" onClick={exportCSV}>
+          <Download className="w-4 h-4 mr-2" />
+          CSV
+        </Button>
       </CardHeader>
       <CardContent>
         <ResponsiveContainer width="100%" height={300}>
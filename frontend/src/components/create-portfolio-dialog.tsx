@@ -24,6 +24,9 @@ export function CreatePortfolioDialog({ onSuccess }: CreatePortfolioDialogProps)
   const [open, setOpen] = useState(false)
   const [name, setName] = useState('')
   const [description, setDescription] = useState('')
+  const [faceCurrency, setFaceCurrency] = useState('')
+  const [storageLocation, setStorageLocation] = useState('')
+  const [autoSync, setAutoSync] = useState(true)
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState('')
 
@@ -33,9 +36,16 @@ export function CreatePortfolioDialog({ onSuccess }: CreatePortfolioDialogProps)
     setLoading(true)
 
     try {
-      await portfolioAPI.create(name, description)
+      await portfolioAPI.create(name, description, {
+        default_face_currency: faceCurrency,
+        default_storage_location: storageLocation,
+        default_auto_sync: autoSync,
+      })
       setName('')
       setDescription('')
+      setFaceCurrency('')
+      setStorageLocation('')
+      setAutoSync(true)
       setOpen(false)
       onSuccess()
     } catch (err: any) {
@@ -88,6 +98,31 @@ export function CreatePortfolioDialog({ onSuccess }: CreatePortfolioDialogProps)
             />
           </div>
 
+          <div className="space-y-2">
+            <Label>Defaults for New Coins</Label>
+            <div className="grid grid-cols-2 gap-2">
+              <Input
+                placeholder="Face currency, e.g. CAD"
+                maxLength={3}
+                value={faceCurrency}
+                onChange={(e) => setFaceCurrency(e.target.value.toUpperCase())}
+              />
+              <Input
+                placeholder="Storage location"
+                value={storageLocation}
+                onChange={(e) => setStorageLocation(e.target.value)}
+              />
+            </div>
+            <label className="flex items-center gap-2 text-sm text-slate-600">
+              <input
+                type="checkbox"
+                checked={autoSync}
+                onChange={(e) => setAutoSync(e.target.checked)}
+              />
+              Include new coins in scheduled PCGS syncs
+            </label>
+          </div>
+
           <div className="flex justify-end gap-2">
             <Button
               type="button"
@@ -1,7 +1,7 @@
 'use client'
 
 import { useState, useEffect } from 'react'
-import { coinAPI, pcgsAPI, portfolioAPI, Coin, Portfolio } from '@/lib/api'
+import { coinAPI, pcgsAPI, portfolioAPI, Coin, Portfolio, STRIKE_TYPES, StrikeType } from '@/lib/api'
 import {
   Dialog,
   DialogContent,
@@ -43,9 +43,14 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
     coin_type: '',
     year: '',
     mint_mark: '',
+    strike_type: '',
     denomination: '',
     pcgs_cert_number: '',
     purchase_price: '',
+    buyers_premium: '',
+    shipping_cost: '',
+    sales_tax: '',
+    insured_value: '',
     current_value: '',
     numismatic_value: '',
     notes: '',
@@ -83,9 +88,14 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
         coin_type: coin.coin_type || '',
         year: coin.year ? String(coin.year) : '',
         mint_mark: coin.mint_mark || '',
+        strike_type: coin.strike_type || '',
         denomination: coin.denomination || '',
         pcgs_cert_number: coin.pcgs_cert_number || '',
         purchase_price: coin.purchase_price ? String(coin.purchase_price) : '',
+        buyers_premium: coin.buyers_premium ? String(coin.buyers_premium) : '',
+        shipping_cost: coin.shipping_cost ? String(coin.shipping_cost) : '',
+        sales_tax: coin.sales_tax ? String(coin.sales_tax) : '',
+        insured_value: coin.insured_value ? String(coin.insured_value) : '',
         current_value: coin.current_value ? String(coin.current_value) : '',
         numismatic_value: coin.numismatic_value ? String(coin.numismatic_value) : '',
         notes: coin.notes || '',
@@ -149,7 +159,7 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
     return () => clearTimeout(timer)
   }, [formData.pcgs_cert_number, previousCertNumber])
 
-  const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
+  const handleChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement | HTMLSelectElement>) => {
     setFormData(prev => ({
       ...prev,
       [e.target.name]: e.target.value
@@ -167,9 +177,14 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
         coin_type: formData.coin_type,
         year: formData.year ? parseInt(formData.year) : undefined,
         mint_mark: formData.mint_mark || undefined,
+        strike_type: (formData.strike_type || undefined) as StrikeType | undefined,
         denomination: formData.denomination || undefined,
         pcgs_cert_number: formData.pcgs_cert_number || undefined,
         purchase_price: formData.purchase_price ? parseFloat(formData.purchase_price) : undefined,
+        buyers_premium: formData.buyers_premium ? parseFloat(formData.buyers_premium) : 0,
+        shipping_cost: formData.shipping_cost ? parseFloat(formData.shipping_cost) : 0,
+        sales_tax: formData.sales_tax ? parseFloat(formData.sales_tax) : 0,
+        insured_value: formData.insured_value ? parseFloat(formData.insured_value) : 0,
         current_value: formData.current_value ? parseFloat(formData.current_value) : undefined,
         numismatic_value: formData.numismatic_value ? parseFloat(formData.numismatic_value) : undefined,
         notes: formData.notes || undefined,
@@ -297,6 +312,22 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
               />
             </div>
 
+            <div className="space-y-2">
+              <Label htmlFor="strike_type">Strike Type (Optional)</Label>
+              <select
+                id="strike_type"
+                name="strike_type"
+                value={formData.strike_type}
+                onChange={handleChange}
+                className="flex h-10 w-full rounded-md border border-input bg-background px-3 py-2 text-sm"
+              >
+                <option value="">Unknown</option>
+                {STRIKE_TYPES.map((strike) => (
+                  <option key={strike.value} value={strike.value}>{strike.label}</option>
+                ))}
+              </select>
+            </div>
+
             <div className="space-y-2">
               <Label htmlFor="denomination">Denomination (Optional)</Label>
               <Input
@@ -333,6 +364,62 @@ export function EditCoinDialog({ coin, onSuccess, trigger }: EditCoinDialogProps
               />
             </div>
 
+            <div className="space-y-2">
+              <Label htmlFor="buyers_premium">Buyer's Premium ($) (Optional)</Label>
+              <Input
+                id="buyers_premium"
+                name="buyers_premium"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.buyers_premium}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="shipping_cost">Shipping ($) (Optional)</Label>
+              <Input
+                id="shipping_cost"
+                name="shipping_cost"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.shipping_cost}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="sales_tax">Sales Tax ($) (Optional)</Label>
+              <Input
+                id="sales_tax"
+                name="sales_tax"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="0.00"
+                value={formData.sales_tax}
+                onChange={handleChange}
+              />
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="insured_value">Insured Value ($) (Optional)</Label>
+              <Input
+                id="insured_value"
+                name="insured_value"
+                type="number"
+                step="0.01"
+                min="0"
+                placeholder="Replacement value per coin"
+                value={formData.insured_value}
+                onChange={handleChange}
+              />
+            </div>
+
             <div className="space-y-2">
               <Label htmlFor="current_value">Current Value ($) (Optional)</Label>
               <Input
@@ -0,0 +1,103 @@
+'use client'
+
+import { useCallback, useEffect, useState } from 'react'
+import { notificationAPI, type Notification } from '@/lib/api'
+import {
+  DropdownMenu,
+  DropdownMenuContent,
+  DropdownMenuItem,
+  DropdownMenuLabel,
+  DropdownMenuSeparator,
+  DropdownMenuTrigger,
+} from '@/components/ui/dropdown-menu'
+import { Button } from '@/components/ui/button'
+import { Bell } from 'lucide-react'
+
+const POLL_INTERVAL = 60_000
+
+export function NotificationsMenu() {
+  const [notifications, setNotifications] = useState<Notification[]>([])
+  const [unread, setUnread] = useState(0)
+
+  const load = useCallback(async () => {
+    try {
+      const data = await notificationAPI.list()
+      setNotifications(data.notifications)
+      setUnread(data.unread)
+    } catch (error) {
+      console.error('Failed to load notifications:', error)
+    }
+  }, [])
+
+  useEffect(() => {
+    load()
+    const timer = setInterval(load, POLL_INTERVAL)
+    return () => clearInterval(timer)
+  }, [load])
+
+  const markRead = async (notification: Notification) => {
+    if (notification.read_at) return
+    try {
+      const updated = await notificationAPI.markRead(notification.id)
+      setNotifications((current) => current.map((n) => (n.id === updated.id ? updated : n)))
+      setUnread((count) => Math.max(0, count - 1))
+    } catch (error) {
+      console.error('Failed to mark notification read:', error)
+    }
+  }
+
+  const markAllRead = async () => {
+    try {
+      await notificationAPI.markAllRead()
+      await load()
+    } catch (error) {
+      console.error('Failed to mark notifications read:', error)
+    }
+  }
+
+  return (
+    <DropdownMenu>
+      <DropdownMenuTrigger asChild>
+        <Button variant="ghost" size="icon" className="relative">
+          <Bell className="w-5 h-5" />
+          {unread > 0 && (
+            <span className="absolute -top-1 -right-1 min-w-5 h-5 px-1 rounded-full bg-amber-500 text-white text-xs flex items-center justify-center">
+              {unread > 99 ? '99+' : unread}
+            </span>
+          )}
+        </Button>
+      </DropdownMenuTrigger>
+      <DropdownMenuContent align="end" className="w-80">
+        <DropdownMenuLabel className="flex items-center justify-between">
+          Notifications
+          {unread > 0 && (
+            <button className="text-xs font-normal text-amber-600 hover:underline" onClick={markAllRead}>
+              Mark all read
+            </button>
+          )}
+        </DropdownMenuLabel>
+        <DropdownMenuSeparator />
+        {notifications.length === 0 ? (
+          <p className="px-2 py-6 text-center text-sm text-slate-500">Nothing yet</p>
+        ) : (
+          notifications.map((notification) => (
+            <DropdownMenuItem
+              key={notification.id}
+              className="flex flex-col items-start gap-0.5"
+              onSelect={(event) => {
+                event.preventDefault()
+                markRead(notification)
+              }}
+            >
+              <span className={notification.read_at ? 'text-slate-600' : 'font-medium text-slate-900'}>
+                {notification.title}
+              </span>
+              {notification.body && <span className="text-xs text-slate-500">{notification.body}</span>}
+              <span className="text-xs text-slate-400">{new Date(notification.created_at).toLocaleString()}</span>
+            </DropdownMenuItem>
+          ))
+        )}
+      </DropdownMenuContent>
+    </DropdownMenu>
+  )
+}
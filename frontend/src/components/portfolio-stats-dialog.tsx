@@ -113,6 +113,11 @@ export function PortfolioStatsDialog({ portfolioId, portfolioName, trigger }: Po
                     <DollarSign className="w-6 h-6 text-slate-400" />
                     <span className="text-4xl font-bold">${stats.total_purchase_cost.toFixed(2)}</span>
                   </div>
+                  {stats.total_acquisition_fees > 0 && (
+                    <p className="text-xs text-slate-500 mt-1">
+                      ${stats.total_hammer_price.toFixed(2)} hammer + ${stats.total_acquisition_fees.toFixed(2)} premium, shipping &amp; tax
+                    </p>
+                  )}
                 </CardContent>
               </Card>
 
@@ -196,6 +201,16 @@ export function PortfolioStatsDialog({ portfolioId, portfolioName, trigger }: Po
                     ${stats.total_coins > 0 ? (stats.total_value / stats.total_coins).toFixed(2) : '0.00'}
                   </span>
                 </div>
+                <div className="flex justify-between items-center">
+                  <span className="text-slate-600">Total Face Value:</span>
+                  <span className="font-bold text-2xl">${stats.total_face_value.toFixed(2)}</span>
+                </div>
+                {stats.junk_silver_face_value > 0 && (
+                  <div className="flex justify-between items-center">
+                    <span className="text-slate-600">Junk Silver Face Value:</span>
+                    <span className="font-bold text-2xl">${stats.junk_silver_face_value.toFixed(2)}</span>
+                  </div>
+                )}
                 <div className="flex justify-between items-center pt-4 border-t-2">
                   <span className="text-slate-600 font-semibold">Return on Investment:</span>
                   <span className={`font-bold text-3xl ${stats.gain_loss_percent >= 0 ? 'text-green-600' : 'text-red-600'}`}>
@@ -1,6 +1,6 @@
 'use client'
 
-import { useState } from 'react'
+import { useEffect, useState } from 'react'
 import { useAuth } from '@/lib/auth-context'
 import {
   Dialog,
@@ -12,12 +12,18 @@ import {
 } from '@/components/ui/dialog'
 import { Button } from '@/components/ui/button'
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card'
+import { Input } from '@/components/ui/input'
 import { Label } from '@/components/ui/label'
-import { Settings, User, Mail, Shield, LogOut, Download, Upload, RefreshCw } from 'lucide-react'
-import { portfolioAPI, coinAPI, metalsAPI } from '@/lib/api'
+import { Settings, User, Mail, Shield, LogOut, Download, Upload, RefreshCw, Link2, KeyRound } from 'lucide-react'
+import { portfolioAPI, coinAPI, metalsAPI, authAPI, APIKey, OAuthIdentity, OAuthProvider } from '@/lib/api'
 import { exportAllPortfoliosToCSV } from '@/lib/export'
 import { ImportCoinsSettings } from '@/components/import-coins-settings'
-import axios from 'axios'
+
+const defaultProviderLabels: Record<OAuthProvider, string> = {
+  google: 'Google',
+  apple: 'Apple',
+  oidc: 'Single sign-on',
+}
 
 interface SettingsDialogProps {
   trigger?: React.ReactNode
@@ -28,8 +34,74 @@ export function SettingsDialog({ trigger }: SettingsDialogProps) {
   const [exporting, setExporting] = useState(false)
   const [backfilling, setBackfilling] = useState(false)
   const [syncingPcgs, setSyncingPcgs] = useState(false)
+  const [changingEmail, setChangingEmail] = useState(false)
+  const [newEmail, setNewEmail] = useState('')
+  const [emailPassword, setEmailPassword] = useState('')
+  const [emailMessage, setEmailMessage] = useState('')
+  const [providers, setProviders] = useState<OAuthProvider[]>([])
+  const [providerLabels, setProviderLabels] = useState(defaultProviderLabels)
+  const [identities, setIdentities] = useState<OAuthIdentity[]>([])
+  const [oauthMessage, setOauthMessage] = useState('')
+  const [apiKeys, setApiKeys] = useState<APIKey[]>([])
+  const [apiKeyName, setApiKeyName] = useState('')
+  const [apiKeyWrite, setApiKeyWrite] = useState(false)
+  const [newApiKey, setNewApiKey] = useState('')
+  const [apiKeyMessage, setApiKeyMessage] = useState('')
   const { user, logout } = useAuth()
 
+  useEffect(() => {
+    if (!open) return
+    authAPI.getOAuthProviders().then(setProviders).catch(() => {})
+    authAPI.getOAuthProviderLabels().then((labels) => setProviderLabels({ ...defaultProviderLabels, ...labels })).catch(() => {})
+    authAPI.getOAuthIdentities().then(setIdentities).catch(() => {})
+    authAPI.getAPIKeys().then(setApiKeys).catch(() => {})
+  }, [open])
+
+  const createApiKey = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      setApiKeyMessage('')
+      const scopes = apiKeyWrite ? ['coins:write', 'reports:read'] : ['coins:read', 'reports:read']
+      const created = await authAPI.createAPIKey(apiKeyName, scopes)
+      const { key, ...stored } = created
+      setApiKeys([stored, ...apiKeys])
+      setNewApiKey(key)
+      setApiKeyName('')
+    } catch (error: any) {
+      setApiKeyMessage(error.response?.data?.error || 'Failed to create API key')
+    }
+  }
+
+  const revokeApiKey = async (id: string) => {
+    if (!confirm('Revoke this API key? Scripts using it will stop working.')) return
+    try {
+      setApiKeyMessage('')
+      await authAPI.deleteAPIKey(id)
+      setApiKeys(apiKeys.filter((k) => k.id !== id))
+    } catch (error: any) {
+      setApiKeyMessage(error.response?.data?.error || 'Failed to revoke API key')
+    }
+  }
+
+  const linkProvider = async (provider: OAuthProvider) => {
+    try {
+      setOauthMessage('')
+      window.location.href = await authAPI.linkOAuth(provider)
+    } catch (error: any) {
+      setOauthMessage(error.response?.data?.error || 'Failed to link account')
+    }
+  }
+
+  const unlinkProvider = async (provider: OAuthProvider) => {
+    try {
+      setOauthMessage('')
+      await authAPI.unlinkOAuth(provider)
+      setIdentities(identities.filter((i) => i.provider !== provider))
+    } catch (error: any) {
+      setOauthMessage(error.response?.data?.error || 'Failed to unlink account')
+    }
+  }
+
   const exportToCSV = async () => {
     try {
       setExporting(true)
@@ -113,22 +185,32 @@ export function SettingsDialog({ trigger }: SettingsDialogProps) {
     }
   }
 
+  const changeEmail = async (e: React.FormEvent) => {
+    e.preventDefault()
+    try {
+      setChangingEmail(true)
+      setEmailMessage('')
+
+      await authAPI.changeEmail(newEmail, emailPassword)
+
+      setEmailMessage(`We sent a verification link to ${newEmail}. Your email changes once you open it.`)
+      setNewEmail('')
+      setEmailPassword('')
+    } catch (error: any) {
+      console.error('Email change failed:', error)
+      setEmailMessage(error.response?.data?.error || 'Failed to change email')
+    } finally {
+      setChangingEmail(false)
+    }
+  }
+
   const backfillMetalComposition = async () => {
     try {
       setBackfilling(true)
 
-      const token = localStorage.getItem('token')
-      const response = await axios.post(
-        `${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/metals/backfill-composition`,
-        {},
-        {
-          headers: {
-            'Authorization': `Bearer ${token}`
-          }
-        }
-      )
+      const response = await metalsAPI.backfillComposition()
 
-      alert(`Successfully updated ${response.data.updated} of ${response.data.total_coins} coins with metal composition data`)
+      alert(`Successfully updated ${response.updated} of ${response.total_coins} coins with metal composition data`)
 
       // Reload the page to show updated melt values
       window.location.reload()
@@ -204,6 +286,29 @@ export function SettingsDialog({ trigger }: SettingsDialogProps) {
                   <span className="text-sm">{user?.email}</span>
                 </div>
               </div>
+              <form onSubmit={changeEmail} className="space-y-2">
+                <Label className="text-sm text-slate-600">Change Email</Label>
+                <Input
+                  type="email"
+                  placeholder="New email address"
+                  value={newEmail}
+                  onChange={(e) => setNewEmail(e.target.value)}
+                  required
+                />
+                <Input
+                  type="password"
+                  placeholder="Current password"
+                  value={emailPassword}
+                  onChange={(e) => setEmailPassword(e.target.value)}
+                  required
+                />
+                <Button type="submit" variant="outline" size="sm" disabled={changingEmail}>
+                  {changingEmail ? 'Sending...' : 'Send Verification Link'}
+                </Button>
+                {emailMessage && (
+                  <p className="text-xs text-slate-500 px-1">{emailMessage}</p>
+                )}
+              </form>
               <div className="space-y-2">
                 <Label className="text-sm text-slate-600">Account Created</Label>
                 <div className="flex items-center gap-2 p-3 bg-slate-50 rounded-md">
@@ -216,6 +321,102 @@ export function SettingsDialog({ trigger }: SettingsDialogProps) {
             </CardContent>
           </Card>
 
+          {/* Sign-in Methods */}
+          {providers.length > 0 && (
+            <Card>
+              <CardHeader>
+                <CardTitle className="text-base flex items-center gap-2">
+                  <Link2 className="w-4 h-4" />
+                  Sign-in Methods
+                </CardTitle>
+                <CardDescription className="text-sm">
+                  Link an account to sign in without your password
+                </CardDescription>
+              </CardHeader>
+              <CardContent className="space-y-3">
+                {providers.map((provider) => {
+                  const identity = identities.find((i) => i.provider === provider)
+                  return (
+                    <div key={provider} className="flex items-center justify-between">
+                      <div>
+                        <Label>{providerLabels[provider]}</Label>
+                        <p className="text-sm text-slate-600">{identity ? identity.email || 'Linked' : 'Not linked'}</p>
+                      </div>
+                      {identity ? (
+                        <Button variant="outline" size="sm" onClick={() => unlinkProvider(provider)}>
+                          Unlink
+                        </Button>
+                      ) : (
+                        <Button variant="outline" size="sm" onClick={() => linkProvider(provider)}>
+                          Link
+                        </Button>
+                      )}
+                    </div>
+                  )
+                })}
+                {oauthMessage && (
+                  <p className="text-xs text-slate-500 px-1">{oauthMessage}</p>
+                )}
+              </CardContent>
+            </Card>
+          )}
+
+          {/* API Keys */}
+          <Card>
+            <CardHeader>
+              <CardTitle className="text-base flex items-center gap-2">
+                <KeyRound className="w-4 h-4" />
+                API Keys
+              </CardTitle>
+              <CardDescription className="text-sm">
+                Let scripts use the API by sending a key in the X-API-Key header
+              </CardDescription>
+            </CardHeader>
+            <CardContent className="space-y-3">
+              {apiKeys.map((key) => (
+                <div key={key.id} className="flex items-center justify-between">
+                  <div>
+                    <Label>{key.name}</Label>
+                    <p className="text-sm text-slate-600">
+                      {key.prefix}… · {key.scopes.includes('coins:write') ? 'Read & write' : 'Read only'} ·{' '}
+                      {key.last_used_at ? `Last used ${new Date(key.last_used_at).toLocaleDateString()}` : 'Never used'}
+                    </p>
+                  </div>
+                  <Button variant="outline" size="sm" onClick={() => revokeApiKey(key.id)}>
+                    Revoke
+                  </Button>
+                </div>
+              ))}
+              <form onSubmit={createApiKey} className="space-y-2">
+                <Input
+                  placeholder="Key name, e.g. Nightly import"
+                  value={apiKeyName}
+                  onChange={(e) => setApiKeyName(e.target.value)}
+                  maxLength={100}
+                  required
+                />
+                <div className="flex items-center justify-between">
+                  <label className="flex items-center gap-2 text-sm text-slate-600">
+                    <input type="checkbox" checked={apiKeyWrite} onChange={(e) => setApiKeyWrite(e.target.checked)} />
+                    Allow changes to coins and portfolios
+                  </label>
+                  <Button type="submit" variant="outline" size="sm">
+                    Create Key
+                  </Button>
+                </div>
+              </form>
+              {newApiKey && (
+                <div className="space-y-1">
+                  <p className="text-xs text-slate-500 px-1">Copy this key now; it won&apos;t be shown again.</p>
+                  <code className="block p-3 bg-slate-50 rounded-md text-xs break-all">{newApiKey}</code>
+                </div>
+              )}
+              {apiKeyMessage && (
+                <p className="text-xs text-slate-500 px-1">{apiKeyMessage}</p>
+              )}
+            </CardContent>
+          </Card>
+
           {/* Preferences */}
           <Card>
             <CardHeader>
@@ -1,12 +1,15 @@
 import axios from 'axios'
 
 const API_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'
+// Tenant slug for multi-tenant deployments that don't resolve tenants by subdomain
+const TENANT = process.env.NEXT_PUBLIC_TENANT
 
 // Create axios instance
 const api = axios.create({
   baseURL: API_URL,
   headers: {
     'Content-Type': 'application/json',
+    ...(TENANT ? { 'X-Tenant': TENANT } : {}),
   },
 })
 
@@ -19,56 +22,261 @@ api.interceptors.request.use((config) => {
   return config
 })
 
+// Store a login's access and refresh tokens
+const saveSession = (data: { token: string, refresh_token: string }) => {
+  localStorage.setItem('token', data.token)
+  localStorage.setItem('refresh_token', data.refresh_token)
+}
+
+// Refresh tokens work once, so concurrent 401s share one refresh
+let refreshing: Promise<string | null> | null = null
+
+const refreshSession = (): Promise<string | null> => {
+  const refreshToken = localStorage.getItem('refresh_token')
+  if (!refreshToken) return Promise.resolve(null)
+  refreshing ??= axios
+    .post(`${API_URL}/api/v1/auth/refresh`, { refresh_token: refreshToken }, {
+      headers: TENANT ? { 'X-Tenant': TENANT } : {},
+    })
+    .then(({ data }) => {
+      saveSession(data)
+      return data.token as string
+    })
+    .catch(() => {
+      localStorage.removeItem('token')
+      localStorage.removeItem('refresh_token')
+      return null
+    })
+    .finally(() => {
+      refreshing = null
+    })
+  return refreshing
+}
+
+// Retry a request once with a refreshed token when the access token expired
+api.interceptors.response.use(undefined, async (error) => {
+  const config = error.config
+  if (error.response?.status !== 401 || !config || config._retried) {
+    return Promise.reject(error)
+  }
+  const token = await refreshSession()
+  if (!token) return Promise.reject(error)
+  config._retried = true
+  config.headers.Authorization = `Bearer ${token}`
+  return api(config)
+})
+
 // Types
 export interface User {
   id: string
+  tenant_id?: string
   email: string
+  // When a demo account is deleted; absent on other accounts
+  demo_expires_at?: string
   created_at: string
   updated_at: string
 }
 
+export type RegistrationMode = 'open' | 'invite' | 'disabled'
+
+// What a new password has to satisfy
+export interface PasswordPolicy {
+  min_length: number
+  min_classes: number // of lowercase, uppercase, digits and symbols
+  breach_check: boolean
+}
+
+export type ValuationBasis = 'melt' | 'numismatic' | 'max'
+
 export interface Portfolio {
   id: string
   user_id: string
   name: string
   description: string
+  monthly_statement: boolean
+  statement_stories: boolean
+  statement_sent_at?: string
+  valuation_basis: ValuationBasis
+  default_face_currency: string
+  default_storage_location: string
+  default_auto_sync: boolean
+  cover_image_url: string
+  color: string
+  icon: string
+  sort_order: number
+  notes: string
   created_at: string
   updated_at: string
   coin_count?: number
   total_value?: number
   coins?: Coin[]
+  // The user's role: 'owner' for their own portfolios
+  role?: PortfolioRole
+  // Archived portfolios are left out of getAll
+  archived: boolean
+  archived_at?: string
+}
+
+// Deleted portfolios and coins, until they're purged at purge_at. Coins
+// deleted with their portfolio are counted in its coin_count.
+export interface TrashedPortfolio extends Portfolio {
+  deleted_at: string
+  purge_at: string
 }
 
+export interface TrashedCoin extends Coin {
+  portfolio_name: string
+  deleted_at: string
+  purge_at: string
+}
+
+export interface Trash {
+  portfolios: TrashedPortfolio[]
+  coins: TrashedCoin[]
+  retention: string
+}
+
+export type PortfolioRole = 'owner' | 'editor' | 'viewer'
+
+// Someone a portfolio is shared with; accepted_at is null while the
+// invitation is pending
+export interface PortfolioMember {
+  id: string
+  portfolio_id: string
+  portfolio_name: string
+  user_id: string
+  email: string
+  role: PortfolioRole
+  invited_by: string
+  invited_by_email: string
+  accepted_at: string | null
+  created_at: string
+  updated_at: string
+}
+
+// What new coins in a portfolio start with when they leave a field out
+export type PortfolioCoinDefaults = Partial<Pick<Portfolio, 'default_face_currency' | 'default_storage_location' | 'default_auto_sync'>>
+
 export interface Coin {
   id: string
+  // Short code, e.g. AU-000123, for labels; coin endpoints take it in place of id
+  code: string
   portfolio_id: string
   coin_type: string
   year: number
   mint_mark: string
+  strike_type: StrikeType | ''
   denomination: string
+  face_value: number
+  face_currency: string
+  series: string
+  mintage: number
   pcgs_cert_number: string
+  enrichment_status?: 'pending' | 'done' | 'failed'
   purchase_price: number
+  buyers_premium: number
+  shipping_cost: number
+  sales_tax: number
   purchase_date: string
+  lot_id: string | null
+  upgraded_from_id: string | null
   current_value: number
+  melt_value: number
   numismatic_value: number
+  insured_value: number
   last_price_update: string
+  auto_sync: boolean
+  storage_location: string
   image_url: string
   thumbnail_url: string
   notes: string
+  story: string // Markdown; render story HTML from coinAPI.getStory
   quantity: number
   metal_type: string
   metal_weight: number
   metal_purity: number
+  problems: CoinProblem[] | null
+  eye_appeal: EyeAppeal | ''
+  toning: string[] | null
+  cert_status: 'suspicious' | 'verified' | ''
+  cert_flags: string[] | null
+  references: CoinReference[] | null
+  watched: boolean
+  premium_over_melt: number
+  gain_loss: number
+  gain_loss_percent: number
   created_at: string
   updated_at: string
+  images?: CoinImage[]
+}
+
+export type CoinReferenceType =
+  | 'pcgs_coinfacts'
+  | 'pcgs_cert'
+  | 'ngc_coin_explorer'
+  | 'auction_archive'
+  | 'literature'
+  | 'other'
+
+export interface CoinReference {
+  type: CoinReferenceType
+  url: string
+  title: string
+  auto: boolean
 }
 
+export interface CoinImage {
+  id: string
+  position: number
+  description: string
+  resolution: string
+  side: 'obverse' | 'reverse' | ''
+  trueview: boolean
+  source_url: string
+  url: string
+  size: number
+}
+
+export type CoinProblem =
+  | 'cleaned'
+  | 'scratched'
+  | 'holed'
+  | 'bent'
+  | 'corroded'
+  | 'rim_damage'
+  | 'environmental_damage'
+  | 'repaired'
+
+export type EyeAppeal = 'poor' | 'below_average' | 'average' | 'above_average' | 'exceptional'
+
+export type StrikeType = 'business' | 'proof' | 'sms' | 'silver_proof' | 'silver_uncirculated'
+
+export const STRIKE_TYPES: { value: StrikeType; label: string }[] = [
+  { value: 'business', label: 'Business strike' },
+  { value: 'proof', label: 'Proof' },
+  { value: 'sms', label: 'Special Mint Set (SMS)' },
+  { value: 'silver_proof', label: 'Silver proof' },
+  { value: 'silver_uncirculated', label: 'Silver uncirculated' },
+]
+
 export interface PortfolioStats {
+  currency: string
   total_coins: number
   total_value: number
+  total_hammer_price: number
+  total_acquisition_fees: number
   total_purchase_cost: number
   total_gain_loss: number
   gain_loss_percent: number
+  total_face_value: number
+  junk_silver_face_value: number
+  inflation_adjusted?: {
+    purchase_cost: number
+    gain_loss: number
+    gain_loss_percent: number
+    cpi_source: 'bls-annual' | 'fred'
+    cpi_period: string
+  }
 }
 
 export interface PCGSPriceData {
@@ -106,6 +314,35 @@ export interface SpotPrices {
   platinum: number
   palladium: number
   updated_at: string
+  degraded: boolean
+  fallback_metals?: string[]
+  currency: string
+  units: Record<string, 'troy_oz' | 'lb'>
+  // Only on /metals/spot-prices
+  market?: MarketStatus
+}
+
+export interface MarketStatus {
+  open: boolean
+  timezone: string
+  last_close: string
+  next_open?: string
+  next_close?: string
+}
+
+export interface MarketIndicator {
+  value: number
+  day_change: number | null
+  week_change: number | null
+  month_change: number | null
+}
+
+export interface MarketIndicators {
+  indicators: Partial<Record<'gold' | 'silver' | 'platinum' | 'palladium' | 'gold_silver_ratio' | 'platinum_gold_ratio', MarketIndicator>>
+  updated_at: string
+  market: MarketStatus
+  // The market close day_change is measured from
+  day_change_from: string
 }
 
 export interface MetalComposition {
@@ -116,34 +353,483 @@ export interface MetalComposition {
   Description: string
 }
 
+export interface CoinTypeResolution {
+  query: string
+  coin_type: string
+  name_match: 'exact' | 'alias' | 'normalized'
+  composition: MetalComposition
+  method: string
+  confidence: 'high' | 'medium' | 'low'
+}
+
+export interface CoinTypeSuggestion {
+  coin_type: string
+  metal_type: string
+  description: string
+  denomination: string
+  face_value: number
+  matched_alias?: string
+  usage_count: number
+  score: number
+}
+
+export interface Notification {
+  id: string
+  kind: 'alert' | 'pcgs_sync' | 'statement' | 'transfer' | 'emergency_access' | 'member' | 'spot_prices' | 'cert_check' | 'job'
+  title: string
+  body: string
+  portfolio_id?: string
+  read_at: string | null
+  created_at: string
+}
+
+export type JobStatus = 'queued' | 'running' | 'succeeded' | 'failed'
+
+// A long operation running on the server; result is what it reports once
+// it has finished
+export interface Job<T = unknown> {
+  id: string
+  kind: string
+  status: JobStatus
+  done: number
+  total: number
+  result?: T
+  error?: string
+  started_at?: string
+  finished_at?: string
+  created_at: string
+}
+
+export type ImportOnDuplicate = 'skip' | 'update' | 'duplicate'
+
+export interface ImportRowResult {
+  line: number
+  status: 'created' | 'updated' | 'skipped' | 'failed'
+  coin_id?: string
+  pcgs_cert_number?: string
+  duplicate_of?: string
+  duplicate_of_line?: number
+  error?: string
+}
+
+export interface ImportResult {
+  on_duplicate: ImportOnDuplicate
+  dry_run: boolean
+  created: number
+  updated: number
+  skipped: number
+  failed: number
+  ignored_columns: string[]
+  rows: ImportRowResult[]
+}
+
+export interface ImportPreviewColumn {
+  index: number
+  header: string
+  samples: string[]
+  suggested_field: string // '' when no field fits
+  confidence: number // 0 to 1
+  match?: 'header' | 'alias' | 'similar' | 'content'
+}
+
+// What a CSV holds, to pick each column's field before importing it
+export interface ImportPreview {
+  columns: ImportPreviewColumn[]
+  sample_rows: string[][]
+  row_count: number
+  fields: string[]
+  required_fields: string[]
+  mapping: Record<string, string> // header to field, '' to leave it out
+}
+
+export interface StaleCoin {
+  coin_id: string
+  portfolio_id: string
+  portfolio_name: string
+  coin_type: string
+  year: number
+  pcgs_cert_number?: string
+  current_value: number
+  numismatic_value: number
+  last_price_update: string | null
+  pcgs_synced_at: string | null
+  stale: ('current_value' | 'numismatic_value')[]
+}
+
+export type LotAllocation = 'melt_weight' | 'manual'
+
+export interface Lot {
+  id: string
+  user_id: string
+  name: string
+  seller: string
+  invoice_number: string
+  purchase_date: string | null
+  total_price: number
+  buyers_premium: number
+  shipping_cost: number
+  sales_tax: number
+  allocation: LotAllocation
+  notes: string
+  created_at: string
+  updated_at: string
+}
+
+export interface LotSummary extends Lot {
+  coin_count: number
+  reconciliation: {
+    lot_total: number
+    allocated: number
+    difference: number
+    balanced: boolean
+  }
+}
+
+export interface LotDetail extends LotSummary {
+  coins: Coin[]
+}
+
+export interface LotInput {
+  name?: string
+  seller?: string
+  invoice_number?: string
+  purchase_date?: string
+  total_price?: number
+  buyers_premium?: number
+  shipping_cost?: number
+  sales_tax?: number
+  allocation?: LotAllocation
+  notes?: string
+  coin_ids?: string[]
+  amounts?: Record<string, number>
+}
+
+export interface ScheduledItem {
+  coin_id: string
+  code: string
+  portfolio_id: string
+  portfolio_name: string
+  coin_type: string
+  year: number
+  mint_mark: string
+  pcgs_cert_number?: string
+  quantity: number
+  insured_value: number
+  total_value: number
+  estimated: boolean
+}
+
+// A value restated in a currency at the start and end of a period, with its
+// change split between the metal and the exchange rate
+export interface HedgingAttribution {
+  start_value: number
+  end_value: number
+  change: number
+  metal_effect: number
+  fx_effect: number
+}
+
+export interface HedgingCoin extends HedgingAttribution {
+  coin_id: string
+  portfolio_id: string
+  coin_type: string
+  year: number
+  metal_type: string
+  quantity: number
+}
+
+export interface HedgingCurrency extends HedgingAttribution {
+  currency: string
+  start_rate: number
+  end_rate: number
+  coins: HedgingCoin[]
+}
+
+export interface CurrencyHedgingReport {
+  from: string
+  to: string
+  spot_from: string
+  currencies: HedgingCurrency[]
+  missing_rates: string[]
+}
+
 export interface AuthResponse {
   token: string
+  expires_at: string
+  refresh_token: string
+  refresh_expires_at: string
   user: User
 }
 
+export type OAuthProvider = 'google' | 'apple' | 'oidc' // oidc is the organization's single sign-on
+
+export interface OAuthIdentity {
+  id: string
+  user_id: string
+  provider: OAuthProvider
+  email: string
+  created_at: string
+  updated_at: string
+}
+
+export interface APIKey {
+  id: string
+  user_id: string
+  name: string
+  prefix: string
+  scopes: string[]
+  expires_at: string | null
+  last_used_at: string | null
+  created_at: string
+  updated_at: string
+  portfolio_id?: string // display tokens only
+}
+
+// A device the account is signed in on
+export interface Session {
+  id: string
+  user_agent: string
+  device: string
+  ip_address: string
+  last_seen_at: string
+  expires_at: string
+  created_at: string
+  current: boolean
+}
+
+// One thing done to the account, e.g. a login or a coin deleted
+export interface AuditLogEntry {
+  id: string
+  action: string
+  target_id?: string
+  api_key_id?: string
+  ip_address: string
+  user_agent: string
+  created_at: string
+}
+
+export interface AuditLogPage {
+  entries: AuditLogEntry[]
+  next?: string // pass as after for the following page
+}
+
+// How amounts, weights and dates are shown. Portfolio, coin, lot and report
+// responses come in currency and weight_unit, and are sent back in them.
+export interface UserSettings {
+  currency: string
+  weight_unit: 'troy_oz' | 'g'
+  date_format: 'iso' | 'us' | 'eu'
+  default_portfolio_id: string | null
+  updated_at: string
+}
+
+// What a display token shows: one portfolio's total and spot prices
+export interface DisplayView {
+  portfolio_name: string
+  currency: string
+  total_value: number
+  spot_prices: SpotPrices
+  updated_at: string
+}
+
+// The message for a failed login or registration, saying how long to wait
+// when too many attempts have locked it out
+export function authErrorMessage(error: any, fallback: string): string {
+  const data = error.response?.data
+  if (error.response?.status === 429 && data?.retry_after) {
+    const minutes = Math.ceil(data.retry_after / 60)
+    return `Too many failed attempts. Try again in ${minutes} minute${minutes === 1 ? '' : 's'}.`
+  }
+  return data?.error || fallback
+}
+
 // Auth API
 export const authAPI = {
-  register: async (email: string, password: string): Promise<AuthResponse> => {
-    const { data } = await api.post('/api/auth/register', { email, password })
-    localStorage.setItem('token', data.token)
+  register: async (email: string, password: string, inviteCode?: string): Promise<AuthResponse> => {
+    const { data } = await api.post('/api/v1/auth/register', { email, password, invite_code: inviteCode })
+    saveSession(data)
     return data
   },
 
+  getRegistrationMode: async (): Promise<RegistrationMode> => {
+    const { data } = await api.get('/api/v1/auth/registration')
+    return data.mode
+  },
+
+  // False when the instance signs in with single sign-on only
+  getPasswordLogin: async (): Promise<boolean> => {
+    const { data } = await api.get('/api/v1/auth/registration')
+    return data.password_login ?? true
+  },
+
+  getPasswordPolicy: async (): Promise<PasswordPolicy> => {
+    const { data } = await api.get('/api/v1/auth/registration')
+    return data.password_policy
+  },
+
   login: async (email: string, password: string): Promise<AuthResponse> => {
-    const { data } = await api.post('/api/auth/login', { email, password })
-    localStorage.setItem('token', data.token)
+    const { data } = await api.post('/api/v1/auth/login', { email, password })
+    saveSession(data)
+    return data
+  },
+
+  // True when the instance offers demo accounts
+  getDemoEnabled: async (): Promise<boolean> => {
+    const { data } = await api.get('/api/v1/auth/registration')
+    return data.demo ?? false
+  },
+
+  // Signs in to a new throwaway account holding a sample collection
+  demo: async (): Promise<AuthResponse> => {
+    const { data } = await api.post('/api/v1/auth/demo')
+    saveSession(data)
     return data
   },
 
   logout: () => {
+    const refreshToken = localStorage.getItem('refresh_token')
+    if (refreshToken) {
+      api.post('/api/v1/auth/logout', { refresh_token: refreshToken }).catch(() => {})
+    }
     localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+  },
+
+  // Signs out every device, this one included
+  logoutEverywhere: async (): Promise<void> => {
+    await api.post('/api/v1/auth/logout-everywhere')
+    localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+  },
+
+  getSessions: async (): Promise<Session[]> => {
+    const { data } = await api.get('/api/v1/auth/sessions')
+    return data
+  },
+
+  // Signs the device out; its tokens stop working at once
+  revokeSession: async (id: string): Promise<void> => {
+    await api.delete(`/api/v1/auth/sessions/${id}`)
+  },
+
+  // Newest first; from and to are dates (YYYY-MM-DD) or ISO times
+  getAuditLog: async (params: { action?: string; from?: string; to?: string; after?: string; limit?: number } = {}): Promise<AuditLogPage> => {
+    const response = await api.get('/api/v1/audit-log', { params })
+    return { entries: response.data, next: response.headers['x-next-cursor'] || undefined }
+  },
+
+  getSettings: async (): Promise<UserSettings> => {
+    const { data } = await api.get('/api/v1/settings')
+    return data
+  },
+
+  // default_portfolio_id '' clears it
+  updateSettings: async (settings: Partial<Omit<UserSettings, 'updated_at'>>): Promise<UserSettings> => {
+    const { data } = await api.put('/api/v1/settings', settings)
+    return data
   },
 
   getCurrentUser: async (): Promise<User> => {
-    const { data } = await api.get('/api/auth/me')
+    const { data } = await api.get('/api/v1/auth/me')
+    return data
+  },
+
+  // Accounts without a password confirm with their email instead
+  deleteAccount: async (confirm: { password?: string; email?: string }): Promise<void> => {
+    await api.delete('/api/v1/auth/me', { data: confirm })
+    localStorage.removeItem('token')
+    localStorage.removeItem('refresh_token')
+  },
+
+  // Everything the account owns, as the JSON file to save
+  exportAccount: async (): Promise<Blob> => {
+    const { data } = await api.get('/api/v1/auth/me/export', { responseType: 'blob' })
+    return data
+  },
+
+  // The email only changes once the link mailed to newEmail is opened
+  changeEmail: async (newEmail: string, password: string): Promise<void> => {
+    await api.post('/api/v1/auth/change-email', { new_email: newEmail, password })
+  },
+
+  confirmEmailChange: async (token: string): Promise<User> => {
+    const { data } = await api.post('/api/v1/auth/change-email/confirm', { token })
+    return data
+  },
+
+  forgotPassword: async (email: string): Promise<void> => {
+    await api.post('/api/v1/auth/forgot-password', { email })
+  },
+
+  resetPassword: async (token: string, password: string): Promise<void> => {
+    await api.post('/api/v1/auth/reset-password', { token, password })
+  },
+
+  getOAuthProviders: async (): Promise<OAuthProvider[]> => {
+    const { data } = await api.get('/api/v1/auth/oauth/providers')
+    return data.providers ?? []
+  },
+
+  // What to call each provider on its button, e.g. the organization's name
+  // for its single sign-on
+  getOAuthProviderLabels: async (): Promise<Partial<Record<OAuthProvider, string>>> => {
+    const { data } = await api.get('/api/v1/auth/oauth/providers')
+    return data.labels ?? {}
+  },
+
+  // Where to send the browser to sign in with a provider. It comes back to
+  // /oauth/callback with a code for exchangeOAuthCode.
+  oauthStartUrl: (provider: OAuthProvider, inviteCode?: string): string => {
+    const query = new URLSearchParams()
+    if (inviteCode) query.set('invite_code', inviteCode)
+    if (TENANT) query.set('tenant', TENANT)
+    const qs = query.toString()
+    return `${API_URL}/api/v1/auth/oauth/${provider}/start${qs ? `?${qs}` : ''}`
+  },
+
+  exchangeOAuthCode: async (code: string): Promise<AuthResponse> => {
+    const { data } = await api.post('/api/v1/auth/oauth/exchange', { code })
+    saveSession(data)
+    return data
+  },
+
+  getOAuthIdentities: async (): Promise<OAuthIdentity[]> => {
+    const { data } = await api.get('/api/v1/auth/oauth/identities')
+    return data
+  },
+
+  // Returns the URL to open to link provider to the signed-in account. It
+  // only works in this browser, which gets a cookie binding it here.
+  linkOAuth: async (provider: OAuthProvider): Promise<string> => {
+    const { data } = await api.post(`/api/v1/auth/oauth/${provider}/link`, null, { withCredentials: true })
+    return data.url
+  },
+
+  unlinkOAuth: async (provider: OAuthProvider): Promise<void> => {
+    await api.delete(`/api/v1/auth/oauth/${provider}`)
+  },
+
+  getAPIKeys: async (): Promise<APIKey[]> => {
+    const { data } = await api.get('/api/v1/auth/api-keys')
     return data
   },
 
+  // The returned key is only ever shown once
+  createAPIKey: async (name: string, scopes: string[], expiresInDays = 0): Promise<APIKey & { key: string }> => {
+    const { data } = await api.post('/api/v1/auth/api-keys', { name, scopes, expires_in_days: expiresInDays })
+    return data
+  },
+
+  // A key for a wall display that only reads the portfolio's total; shown once
+  createDisplayToken: async (name: string, portfolioId: string, expiresInDays = 0): Promise<APIKey & { key: string }> => {
+    const { data } = await api.post('/api/v1/auth/display-tokens', { name, portfolio_id: portfolioId, expires_in_days: expiresInDays })
+    return data
+  },
+
+  deleteAPIKey: async (id: string): Promise<void> => {
+    await api.delete(`/api/v1/auth/api-keys/${id}`)
+  },
+
   isAuthenticated: (): boolean => {
     return !!localStorage.getItem('token')
   },
@@ -152,36 +838,112 @@ export const authAPI = {
 // Portfolio API
 export const portfolioAPI = {
   getAll: async (): Promise<Portfolio[]> => {
-    const { data } = await api.get('/api/portfolios')
+    const { data } = await api.get('/api/v1/portfolios')
+    return data
+  },
+
+  getArchived: async (): Promise<Portfolio[]> => {
+    const { data } = await api.get('/api/v1/portfolios', { params: { archived: true } })
     return data
   },
 
   getById: async (id: string): Promise<Portfolio> => {
-    const { data } = await api.get(`/api/portfolios/${id}`)
+    const { data } = await api.get(`/api/v1/portfolios/${id}`)
     return data
   },
 
-  create: async (name: string, description: string): Promise<Portfolio> => {
-    const { data } = await api.post('/api/portfolios', { name, description })
+  create: async (name: string, description: string, defaults: PortfolioCoinDefaults = {}): Promise<Portfolio> => {
+    const { data } = await api.post('/api/v1/portfolios', { name, description, ...defaults })
     return data
   },
 
   update: async (id: string, name: string, description: string): Promise<Portfolio> => {
-    const { data } = await api.put(`/api/portfolios/${id}`, { name, description })
+    const { data } = await api.put(`/api/v1/portfolios/${id}`, { name, description })
+    return data
+  },
+
+  setMonthlyStatement: async (portfolio: Portfolio, enabled: boolean): Promise<Portfolio> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolio.id}`, {
+      name: portfolio.name,
+      description: portfolio.description,
+      monthly_statement: enabled,
+    })
+    return data
+  },
+
+  setStatementStories: async (portfolio: Portfolio, enabled: boolean): Promise<Portfolio> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolio.id}`, {
+      name: portfolio.name,
+      description: portfolio.description,
+      statement_stories: enabled,
+    })
+    return data
+  },
+
+  setValuationBasis: async (portfolio: Portfolio, basis: ValuationBasis): Promise<Portfolio> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolio.id}`, {
+      name: portfolio.name,
+      description: portfolio.description,
+      valuation_basis: basis,
+    })
+    return data
+  },
+
+  setAppearance: async (
+    portfolio: Portfolio,
+    appearance: Partial<Pick<Portfolio, 'cover_image_url' | 'color' | 'icon' | 'notes'>>
+  ): Promise<Portfolio> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolio.id}`, {
+      name: portfolio.name,
+      description: portfolio.description,
+      ...appearance,
+    })
+    return data
+  },
+
+  // Only affects coins added afterwards
+  setCoinDefaults: async (portfolio: Portfolio, defaults: PortfolioCoinDefaults): Promise<Portfolio> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolio.id}`, {
+      name: portfolio.name,
+      description: portfolio.description,
+      ...defaults,
+    })
+    return data
+  },
+
+  reorder: async (portfolioIds: string[]): Promise<Portfolio[]> => {
+    const { data } = await api.post('/api/v1/portfolios/reorder', { portfolio_ids: portfolioIds })
+    return data
+  },
+
+  archive: async (id: string): Promise<Portfolio> => {
+    const { data } = await api.post(`/api/v1/portfolios/${id}/archive`)
     return data
   },
 
+  unarchive: async (id: string): Promise<Portfolio> => {
+    const { data } = await api.post(`/api/v1/portfolios/${id}/unarchive`)
+    return data
+  },
+
+  // Moves the portfolio and its coins to the trash
   delete: async (id: string): Promise<void> => {
-    await api.delete(`/api/portfolios/${id}`)
+    await api.delete(`/api/v1/portfolios/${id}`)
   },
 
-  getStats: async (id: string): Promise<PortfolioStats> => {
-    const { data } = await api.get(`/api/portfolios/${id}/stats`)
+  getStatsBatch: async (ids: string[]): Promise<Record<string, PortfolioStats>> => {
+    const { data } = await api.post('/api/v1/portfolios/stats-batch', { portfolio_ids: ids })
+    return data.stats
+  },
+
+  // realTerms adds CPI-adjusted gains in inflation_adjusted
+  getStats: async (id: string, realTerms = false): Promise<PortfolioStats> => {
+    const { data } = await api.get(`/api/v1/portfolios/${id}/stats`, { params: realTerms ? { real: true } : undefined })
     return data
   },
 
   getCoins: async (id: string): Promise<Coin[]> => {
-    const { data } = await api.get(`/api/portfolios/${id}/coins`)
+    const { data } = await api.get(`/api/v1/portfolios/${id}/coins`)
     return data
   },
 }
@@ -189,56 +951,237 @@ export const portfolioAPI = {
 // Coin API
 export const coinAPI = {
   create: async (coin: {
-    portfolio_id: string
+    portfolio_id?: string // the default portfolio in settings when left out
     coin_type: string
     year?: number
     mint_mark?: string
+    strike_type?: StrikeType
     denomination?: string
+    face_currency?: string
     pcgs_cert_number?: string
     purchase_price?: number
+    buyers_premium?: number
+    shipping_cost?: number
+    sales_tax?: number
+    purchase_date?: string
     current_value?: number
     numismatic_value?: number
+    insured_value?: number
     image_url?: string
     thumbnail_url?: string
     notes?: string
+    story?: string
     quantity?: number
     metal_type?: string
     metal_weight?: number
     metal_purity?: number
+    problems?: CoinProblem[]
+    eye_appeal?: EyeAppeal
+    toning?: string[]
+    references?: Pick<CoinReference, 'type' | 'url' | 'title'>[]
+    storage_location?: string
+    auto_sync?: boolean
   }): Promise<Coin> => {
-    const { data } = await api.post('/api/coins', coin)
+    const { data } = await api.post('/api/v1/coins', coin)
     return data
   },
 
   getById: async (id: string): Promise<Coin> => {
-    const { data } = await api.get(`/api/coins/${id}`)
+    const { data } = await api.get(`/api/v1/coins/${id}`)
+    return data
+  },
+
+  // html is the story rendered by the server with any HTML in it escaped,
+  // safe for dangerouslySetInnerHTML
+  getStory: async (id: string): Promise<{ story: string; html: string }> => {
+    const { data } = await api.get(`/api/v1/coins/${id}/story`)
     return data
   },
 
   getByPortfolio: async (portfolioId: string): Promise<Coin[]> => {
-    const { data } = await api.get(`/api/portfolios/${portfolioId}/coins`)
+    const { data } = await api.get(`/api/v1/portfolios/${portfolioId}/coins`)
     return data
   },
 
   update: async (id: string, updates: Partial<Coin>): Promise<Coin> => {
-    const { data } = await api.put(`/api/coins/${id}`, updates)
+    const { data } = await api.put(`/api/v1/coins/${id}`, updates)
     return data
   },
 
   delete: async (id: string): Promise<void> => {
-    await api.delete(`/api/coins/${id}`)
+    await api.delete(`/api/v1/coins/${id}`)
   },
 
-  syncPcgsValues: async (): Promise<{
-    message: string
+  split: async (id: string, quantities: number[]): Promise<{ coin: Coin; new_coins: Coin[] }> => {
+    const { data } = await api.post(`/api/v1/coins/${id}/split`, { quantities })
+    return data
+  },
+
+  merge: async (id: string, coinIds: string[]): Promise<Coin> => {
+    const { data } = await api.post(`/api/v1/coins/${id}/merge`, { coin_ids: coinIds })
+    return data
+  },
+
+  upgrade: async (
+    id: string,
+    upgrade: { pcgs_cert_number: string; numismatic_value?: number; regraded_at?: string; notes?: string }
+  ): Promise<{ coin: Coin; previous: Coin & { disposition: string; disposed_at: string } }> => {
+    const { data } = await api.post(`/api/v1/coins/${id}/upgrade`, upgrade)
+    return data
+  },
+
+  importCsv: async (
+    portfolioId: string,
+    file: File,
+    options: {
+      onDuplicate?: ImportOnDuplicate
+      dryRun?: boolean
+      mapping?: Record<string, string>
+      onProgress?: (job: Job) => void
+    } = {}
+  ): Promise<ImportResult> => {
+    const formData = new FormData()
+    formData.append('file', file)
+    if (options.mapping) {
+      formData.append('mapping', JSON.stringify(options.mapping))
+    }
+    const { data } = await api.post(`/api/v1/portfolios/${portfolioId}/import`, formData, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      params: { on_duplicate: options.onDuplicate, dry_run: options.dryRun },
+    })
+    return jobAPI.wait<ImportResult>(data.id, options.onProgress)
+  },
+
+  // Suggests each column's field; send the mapping back with importCsv
+  previewCsv: async (file: File, sampleRows?: number): Promise<ImportPreview> => {
+    const formData = new FormData()
+    formData.append('file', file)
+    const { data } = await api.post('/api/v1/import/preview', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      params: { sample_rows: sampleRows },
+    })
+    return data
+  },
+
+  syncPcgsValues: async (options: {
+    portfolioId?: string
+    coinIds?: string[]
+    maxAgeDays?: number
+    onProgress?: (job: Job) => void
+  } = {}): Promise<{
     total_coins: number
     updated: number
+    skipped: number
     failed: number
     errors?: string[]
   }> => {
-    const { data } = await api.post('/api/coins/sync-pcgs-values')
+    const { data } = await api.post('/api/v1/coins/sync-pcgs-values', null, {
+      params: {
+        portfolio_id: options.portfolioId,
+        coin_ids: options.coinIds?.join(','),
+        max_age_days: options.maxAgeDays,
+      },
+    })
+    return jobAPI.wait(data.id, options.onProgress)
+  },
+}
+
+// Members API: sharing portfolios with other users, and the invitations
+// to the user's account
+export const memberAPI = {
+  list: async (portfolioId: string): Promise<PortfolioMember[]> => {
+    const { data } = await api.get(`/api/v1/portfolios/${portfolioId}/members`)
+    return data
+  },
+
+  invite: async (portfolioId: string, email: string, role: PortfolioRole): Promise<PortfolioMember> => {
+    const { data } = await api.post(`/api/v1/portfolios/${portfolioId}/members`, { email, role })
+    return data
+  },
+
+  setRole: async (portfolioId: string, memberId: string, role: PortfolioRole): Promise<PortfolioMember> => {
+    const { data } = await api.put(`/api/v1/portfolios/${portfolioId}/members/${memberId}`, { role })
     return data
   },
+
+  // Also how a member leaves, with their own membership
+  remove: async (portfolioId: string, memberId: string): Promise<void> => {
+    await api.delete(`/api/v1/portfolios/${portfolioId}/members/${memberId}`)
+  },
+
+  invitations: async (): Promise<PortfolioMember[]> => {
+    const { data } = await api.get('/api/v1/portfolio-invitations')
+    return data
+  },
+
+  accept: async (id: string): Promise<PortfolioMember> => {
+    const { data } = await api.post(`/api/v1/portfolio-invitations/${id}/accept`)
+    return data
+  },
+
+  decline: async (id: string): Promise<void> => {
+    await api.post(`/api/v1/portfolio-invitations/${id}/decline`)
+  },
+}
+
+// Trash API: deleted portfolios and coins can be restored until they're
+// purged
+export const trashAPI = {
+  get: async (): Promise<Trash> => {
+    const { data } = await api.get('/api/v1/trash')
+    return data
+  },
+
+  restorePortfolio: async (id: string): Promise<Portfolio> => {
+    const { data } = await api.post(`/api/v1/trash/portfolios/${id}/restore`)
+    return data
+  },
+
+  purgePortfolio: async (id: string): Promise<void> => {
+    await api.delete(`/api/v1/trash/portfolios/${id}`)
+  },
+
+  // Fails with 409 while the coin's portfolio is in the trash too
+  restoreCoin: async (id: string): Promise<Coin> => {
+    const { data } = await api.post(`/api/v1/trash/coins/${id}/restore`)
+    return data
+  },
+
+  purgeCoin: async (id: string): Promise<void> => {
+    await api.delete(`/api/v1/trash/coins/${id}`)
+  },
+}
+
+// Jobs API: long operations answer 202 with a job, polled here until it
+// has finished
+export const jobAPI = {
+  list: async (params: { kind?: string; status?: JobStatus } = {}): Promise<Job[]> => {
+    const { data } = await api.get('/api/v1/jobs', { params })
+    return data
+  },
+
+  get: async <T = unknown>(id: string): Promise<Job<T>> => {
+    const { data } = await api.get(`/api/v1/jobs/${id}`)
+    return data
+  },
+
+  // Resolves with the job's result once it has succeeded, and rejects with
+  // its error if it failed
+  wait: async <T = any>(id: string, onProgress?: (job: Job) => void): Promise<T> => {
+    for (;;) {
+      const response = await api.get(`/api/v1/jobs/${id}`)
+      const job: Job<T> = response.data
+      onProgress?.(job)
+      if (job.status === 'succeeded') {
+        return job.result as T
+      }
+      if (job.status === 'failed') {
+        throw Object.assign(new Error(job.error || 'Job failed'), { response: { data: { error: job.error } } })
+      }
+      const seconds = Number(response.headers['retry-after']) || 2
+      await new Promise((resolve) => setTimeout(resolve, seconds * 1000))
+    }
+  },
 }
 
 // PCGS API with session-based caching
@@ -258,7 +1201,7 @@ export const pcgsAPI = {
       return cached.data
     }
 
-    const { data } = await api.get(`/api/pcgs/price?cert_number=${certNumber}`)
+    const { data } = await api.get(`/api/v1/pcgs/price?cert_number=${certNumber}`)
 
     // Store in cache
     pcgsCache.price.set(certNumber, { data, timestamp: Date.now() })
@@ -273,7 +1216,7 @@ export const pcgsAPI = {
       return cached.data
     }
 
-    const { data } = await api.get(`/api/pcgs/images?cert_number=${certNumber}`)
+    const { data } = await api.get(`/api/v1/pcgs/images?cert_number=${certNumber}`)
 
     // Store in cache
     pcgsCache.images.set(certNumber, { data, timestamp: Date.now() })
@@ -288,25 +1231,154 @@ export const pcgsAPI = {
   },
 }
 
+// Notifications API
+export const notificationAPI = {
+  list: async (unreadOnly = false): Promise<{ notifications: Notification[]; unread: number }> => {
+    const { data } = await api.get('/api/v1/notifications', { params: unreadOnly ? { unread: true } : {} })
+    return data
+  },
+
+  markRead: async (id: string): Promise<Notification> => {
+    const { data } = await api.post(`/api/v1/notifications/${id}/read`)
+    return data
+  },
+
+  markAllRead: async (): Promise<void> => {
+    await api.post('/api/v1/notifications/read-all')
+  },
+}
+
+// Lots API
+export const lotAPI = {
+  list: async (): Promise<LotSummary[]> => {
+    const { data } = await api.get('/api/v1/lots')
+    return data
+  },
+
+  get: async (id: string): Promise<LotDetail> => {
+    const { data } = await api.get(`/api/v1/lots/${id}`)
+    return data
+  },
+
+  create: async (lot: LotInput & { name: string }): Promise<LotDetail> => {
+    const { data } = await api.post('/api/v1/lots', lot)
+    return data
+  },
+
+  update: async (id: string, lot: LotInput): Promise<LotDetail> => {
+    const { data } = await api.put(`/api/v1/lots/${id}`, lot)
+    return data
+  },
+
+  delete: async (id: string): Promise<void> => {
+    await api.delete(`/api/v1/lots/${id}`)
+  },
+}
+
+// Reports API
+export const reportAPI = {
+  staleValues: async (olderThan = '30d', portfolioId?: string): Promise<{ total: number; coins: StaleCoin[] }> => {
+    const { data } = await api.get('/api/v1/reports/stale-values', {
+      params: { older_than: olderThan, ...(portfolioId ? { portfolio_id: portfolioId } : {}) },
+    })
+    return data
+  },
+
+  // Resolves once the refresh has finished; with nothing stale there's no job
+  refreshStaleValues: async (olderThan = '30d', portfolioId?: string): Promise<{ melt_refreshed: number; pcgs?: unknown }> => {
+    const response = await api.post('/api/v1/reports/stale-values/refresh', null, {
+      params: { older_than: olderThan, ...(portfolioId ? { portfolio_id: portfolioId } : {}) },
+    })
+    if (response.status !== 202) {
+      return { melt_refreshed: 0 }
+    }
+    return jobAPI.wait(response.data.id)
+  },
+
+  scheduledItems: async (threshold?: number): Promise<{
+    threshold: number
+    scheduled: ScheduledItem[]
+    scheduled_count: number
+    scheduled_total: number
+    unscheduled_count: number
+    unscheduled_total: number
+    total: number
+  }> => {
+    const { data } = await api.get('/api/v1/reports/scheduled-items', {
+      params: threshold !== undefined ? { threshold } : {},
+    })
+    return data
+  },
+
+  // Coins are valued in their face currency unless currency is given
+  currencyHedging: async (period = '30d', currency?: string, portfolioId?: string): Promise<CurrencyHedgingReport> => {
+    const { data } = await api.get('/api/v1/reports/currency-hedging', {
+      params: { period, ...(currency ? { currency } : {}), ...(portfolioId ? { portfolio_id: portfolioId } : {}) },
+    })
+    return data
+  },
+}
+
+// Catalog API
+export const catalogAPI = {
+  suggest: async (query: string, limit = 10): Promise<CoinTypeSuggestion[]> => {
+    const { data } = await api.get('/api/v1/catalog/suggest', { params: { q: query, limit } })
+    return data.suggestions
+  },
+}
+
 // Metals API
 export const metalsAPI = {
   getSpotPrices: async (): Promise<SpotPrices> => {
-    const { data } = await api.get('/api/metals/spot-prices')
+    const { data } = await api.get('/api/v1/metals/spot-prices')
+    return data
+  },
+
+  // A wall display passes its display token; the owner previews a portfolio
+  getDisplay: async (displayToken?: string, portfolioId?: string): Promise<DisplayView> => {
+    const { data } = await api.get('/api/v1/display', {
+      params: portfolioId ? { portfolio_id: portfolioId } : undefined,
+      headers: displayToken ? { 'X-API-Key': displayToken } : undefined,
+    })
+    return data
+  },
+
+  getIndicators: async (): Promise<MarketIndicators> => {
+    const { data } = await api.get('/api/v1/metals/indicators')
     return data
   },
 
   getCompositions: async (): Promise<Record<string, MetalComposition>> => {
-    const { data } = await api.get('/api/metals/compositions')
+    const { data } = await api.get('/api/v1/metals/compositions')
     return data
   },
 
   getComposition: async (coinType: string): Promise<MetalComposition> => {
-    const { data } = await api.get(`/api/metals/composition?coin_type=${coinType}`)
+    const { data } = await api.get(`/api/v1/metals/composition?coin_type=${coinType}`)
+    return data
+  },
+
+  resolveCoinType: async (query: string, year?: number): Promise<CoinTypeResolution> => {
+    const { data } = await api.get('/api/v1/metals/resolve', { params: { q: query, year } })
     return data
   },
 
+  // Resolves once the backfill job has finished
+  backfillComposition: async (options: { dryRun?: boolean; overwrite?: boolean } = {}): Promise<{
+    message: string
+    dry_run: boolean
+    total_coins: number
+    updated: number
+    failed: number
+  }> => {
+    const { data } = await api.post('/api/v1/metals/backfill-composition', null, {
+      params: { dry_run: options.dryRun, overwrite: options.overwrite },
+    })
+    return jobAPI.wait(data.id)
+  },
+
   calculateMeltValue: async (metalType: string, weight: number, purity: number): Promise<{ melt_value: number }> => {
-    const { data } = await api.post('/api/metals/melt-value', {
+    const { data } = await api.post('/api/v1/metals/melt-value', {
       metal_type: metalType,
       weight,
       purity,
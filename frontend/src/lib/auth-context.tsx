@@ -8,7 +8,8 @@ interface AuthContextType {
   user: User | null
   loading: boolean
   login: (email: string, password: string) => Promise<void>
-  register: (email: string, password: string) => Promise<void>
+  register: (email: string, password: string, inviteCode?: string) => Promise<void>
+  loginWithOAuthCode: (code: string) => Promise<void>
   logout: () => void
   isAuthenticated: boolean
 }
@@ -49,9 +50,9 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     }
   }
 
-  const register = async (email: string, password: string) => {
+  const register = async (email: string, password: string, inviteCode?: string) => {
     try {
-      const response = await authAPI.register(email, password)
+      const response = await authAPI.register(email, password, inviteCode)
       setUser(response.user)
       router.push('/dashboard')
     } catch (error) {
@@ -60,6 +61,13 @@ export function AuthProvider({ children }: { children: ReactNode }) {
     }
   }
 
+  // Finishes a provider sign-in with the code it came back with
+  const loginWithOAuthCode = async (code: string) => {
+    const response = await authAPI.exchangeOAuthCode(code)
+    setUser(response.user)
+    router.push('/dashboard')
+  }
+
   const logout = () => {
     authAPI.logout()
     setUser(null)
@@ -73,6 +81,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
         loading,
         login,
         register,
+        loginWithOAuthCode,
         logout,
         isAuthenticated: !!user,
       }}
@@ -61,12 +61,17 @@ function fileToDataUrl(file: File): Promise<string> {
 
 /**
  * Upload image via backend endpoint (for production use)
+ * @param file - The image file to upload
+ * @param autoCrop - Ask the backend to crop the coin and normalize the background
  */
-export async function uploadCoinImageViaBackend(file: File): Promise<UploadResult> {
+export async function uploadCoinImageViaBackend(file: File, autoCrop?: boolean): Promise<UploadResult> {
   const formData = new FormData()
   formData.append('file', file)
+  if (autoCrop !== undefined) {
+    formData.append('auto_crop', String(autoCrop))
+  }
 
-  const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/upload`, {
+  const response = await fetch(`${process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080'}/api/v1/upload`, {
     method: 'POST',
     headers: {
       'Authorization': `Bearer ${localStorage.getItem('token')}`,
@@ -6,7 +6,8 @@ import cv2
 import numpy as np
 import pytesseract
 import re
-from typing import List, Dict, Any
+import base64
+from typing import List, Dict, Any, Optional, Tuple
 import logging
 
 from app.coin_classifier import classify_coin_hybrid, classify_coin_with_clip, get_coin_details
@@ -39,12 +40,14 @@ async def root():
             "OCR-based text extraction",
             "Circle detection for multi-coin analysis",
             "Year and denomination detection",
-            "Value estimation"
+            "Value estimation",
+            "Auto-crop and background normalization for gallery images"
         ],
         "endpoints": {
             "/health": "Health check",
             "/identify": "Identify a single coin (ML + OCR)",
             "/analyze": "Analyze image with multiple coins",
+            "/process": "Auto-crop a coin photo and normalize its background",
         },
         "ml_model": "OpenAI CLIP (ViT-B/32)",
         "documentation": "/docs"
@@ -373,6 +376,119 @@ async def identify_coin(file: UploadFile = File(...)) -> Dict[str, Any]:
         logger.error(f"Error identifying coin: {str(e)}")
         raise HTTPException(status_code=500, detail=f"Identification failed: {str(e)}")
 
+def find_primary_coin(image: np.ndarray) -> Optional[Tuple[int, int, int]]:
+    """
+    Find the most prominent circular coin in a photo.
+    Radius bounds scale with the image so both close-ups and slab shots work.
+    """
+    gray = cv2.cvtColor(image, cv2.COLOR_BGR2GRAY)
+    blurred = cv2.GaussianBlur(gray, (9, 9), 2)
+
+    short_side = min(gray.shape[:2])
+    circles = cv2.HoughCircles(
+        blurred,
+        cv2.HOUGH_GRADIENT,
+        dp=1.2,
+        minDist=short_side // 2,
+        param1=150,
+        param2=60,
+        minRadius=short_side // 6,
+        maxRadius=short_side // 2
+    )
+
+    if circles is None:
+        return None
+
+    # Prefer the largest detected circle - secondary hits are usually lettering or rims
+    x, y, r = max(circles[0], key=lambda c: c[2])
+    return int(x), int(y), int(r)
+
+def normalize_coin_image(image: np.ndarray, x: int, y: int, radius: int, size: int, background: Tuple[int, int, int]) -> np.ndarray:
+    """
+    Crop a square around the coin, replace everything outside the coin with a flat
+    background color and resize to a fixed square so gallery thumbnails line up.
+    """
+    padding = int(radius * 0.05)
+    half = radius + padding
+
+    # Pad the source so crops near the image edge keep the coin centered
+    bordered = cv2.copyMakeBorder(image, half, half, half, half, cv2.BORDER_CONSTANT, value=background)
+    cx, cy = x + half, y + half
+    crop = bordered[cy - half:cy + half, cx - half:cx + half].copy()
+
+    mask = np.zeros(crop.shape[:2], dtype=np.uint8)
+    cv2.circle(mask, (half, half), radius, 255, -1)
+    # Feather the edge slightly so the rim doesn't look jagged after resizing
+    mask = cv2.GaussianBlur(mask, (5, 5), 0)
+
+    alpha = (mask.astype(np.float32) / 255.0)[..., None]
+    backdrop = np.full(crop.shape, background, dtype=np.float32)
+    result = crop.astype(np.float32) * alpha + backdrop * (1.0 - alpha)
+
+    return cv2.resize(result.astype(np.uint8), (size, size), interpolation=cv2.INTER_AREA)
+
+def encode_png(image: np.ndarray) -> str:
+    ok, buffer = cv2.imencode(".png", image)
+    if not ok:
+        raise ValueError("failed to encode image")
+    return base64.b64encode(buffer.tobytes()).decode("ascii")
+
+def parse_hex_color(value: str) -> Tuple[int, int, int]:
+    """Parse an RRGGBB hex string into an OpenCV BGR tuple"""
+    value = value.lstrip("#")
+    if not re.fullmatch(r"[0-9a-fA-F]{6}", value):
+        raise ValueError(f"invalid background color: {value}")
+    r, g, b = int(value[0:2], 16), int(value[2:4], 16), int(value[4:6], 16)
+    return (b, g, r)
+
+@app.post("/process")
+async def process_coin_image(
+    file: UploadFile = File(...),
+    size: int = 1024,
+    thumbnail_size: int = 256,
+    background: str = "ffffff",
+) -> Dict[str, Any]:
+    """
+    Detect the coin in an uploaded photo, crop to it and normalize the background.
+    Returns base64 PNGs for the full-size image and a thumbnail. When no coin can
+    be found, detected is false and the caller should keep the original image.
+    """
+    try:
+        bg = parse_hex_color(background)
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=str(e))
+
+    if size < 64 or size > 4096 or thumbnail_size < 32 or thumbnail_size > size:
+        raise HTTPException(status_code=400, detail="Invalid size parameters")
+
+    try:
+        contents = await file.read()
+        image = Image.open(io.BytesIO(contents)).convert("RGB")
+        img_cv = cv2.cvtColor(np.array(image), cv2.COLOR_RGB2BGR)
+
+        coin = find_primary_coin(img_cv)
+        if coin is None:
+            logger.info("Process: no coin detected, leaving image untouched")
+            return {"success": True, "detected": False}
+
+        x, y, radius = coin
+        processed = normalize_coin_image(img_cv, x, y, radius, size, bg)
+        thumbnail = cv2.resize(processed, (thumbnail_size, thumbnail_size), interpolation=cv2.INTER_AREA)
+
+        logger.info(f"Process: cropped coin at ({x}, {y}) r={radius}")
+        return {
+            "success": True,
+            "detected": True,
+            "position": {"x": x, "y": y, "radius": radius},
+            "content_type": "image/png",
+            "image": encode_png(processed),
+            "thumbnail": encode_png(thumbnail),
+        }
+
+    except Exception as e:
+        logger.error(f"Error processing image: {str(e)}")
+        raise HTTPException(status_code=500, detail=f"Processing failed: {str(e)}")
+
 if __name__ == "__main__":
     import uvicorn
     uvicorn.run(app, host="0.0.0.0", port=8000)
\ No newline at end of file